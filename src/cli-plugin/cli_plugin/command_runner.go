@@ -9,7 +9,11 @@ import (
 	"lib/policy_client"
 	"log"
 	"policy-server/models"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"code.cloudfoundry.org/cli/plugin"
 )
@@ -76,7 +80,7 @@ func (r *CommandRunner) List() (string, error) {
 
 	buffer := &bytes.Buffer{}
 	tabWriter := tabwriter.NewWriter(buffer, 0, 8, 2, '\t', tabwriter.FilterHTML)
-	fmt.Fprintf(tabWriter, r.Styler.AddStyle("Source\tDestination\tProtocol\tPort\n", "bold"))
+	fmt.Fprintf(tabWriter, r.Styler.AddStyle("Source\tDestination\tProtocol\tPort\tICMP Type\tICMP Code\tDescription\tLabels\tExpires\n", "bold"))
 
 	for _, policy := range policies {
 		srcName := ""
@@ -90,11 +94,16 @@ func (r *CommandRunner) List() (string, error) {
 			}
 		}
 		if srcName != "" && dstName != "" {
-			fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%d\n",
+			fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 				r.Styler.AddStyle(srcName, "cyan"),
 				r.Styler.AddStyle(dstName, "cyan"),
 				policy.Destination.Protocol,
-				policy.Destination.Port,
+				formatPort(policy.Destination),
+				formatICMP(policy.Destination.Protocol, policy.Destination.ICMPType),
+				formatICMP(policy.Destination.Protocol, policy.Destination.ICMPCode),
+				policy.Description,
+				formatLabels(policy.Labels),
+				formatExpiry(policy.ExpiresAt),
 			)
 		}
 	}
@@ -109,6 +118,47 @@ func (r *CommandRunner) List() (string, error) {
 	return string(outBytes), nil
 }
 
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// formatPort shows a dash for the protocols that are not limited to a port.
+func formatPort(destination models.Destination) string {
+	if destination.Protocol == models.ProtocolICMP || destination.Protocol == models.ProtocolAll {
+		return "-"
+	}
+	return strconv.Itoa(destination.Port)
+}
+
+// formatICMP shows the ICMP type or code of an icmp policy, which allows any
+// of them when unset, and a dash for the other protocols.
+func formatICMP(protocol string, value *int) string {
+	if protocol != models.ProtocolICMP {
+		return "-"
+	}
+	if value == nil {
+		return "any"
+	}
+	return strconv.Itoa(*value)
+}
+
+func formatExpiry(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	return expiresAt.UTC().Format(time.RFC3339)
+}
+
 func (r *CommandRunner) Allow() (string, error) {
 	err := validateUsage(r.CliConnection, r.Args)
 	if err != nil {
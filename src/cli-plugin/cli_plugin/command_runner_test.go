@@ -7,6 +7,7 @@ import (
 	"lib/fakes"
 	"log"
 	"policy-server/models"
+	"time"
 
 	"code.cloudfoundry.org/cli/plugin/models"
 	"code.cloudfoundry.org/cli/plugin/pluginfakes"
@@ -78,7 +79,69 @@ var _ = Describe("CommandRunner", func() {
 				Expect(policyClient.GetPoliciesArgsForCall(0)).To(Equal("some-token"))
 				Expect(fakeCliConnection.GetAppsCallCount()).To(Equal(1))
 
-				Expect(output).To(Equal("<BOLD>Source\t\tDestination\tProtocol\tPort\n<RESET><CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\ttcp\t\t9999\n"))
+				Expect(output).To(Equal("<BOLD>Source\t\tDestination\tProtocol\tPort\tICMP Type\tICMP Code\tDescription\tLabels\tExpires\n<RESET><CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\ttcp\t\t9999\t-\t\t-\t\t\t\t\t\n"))
+			})
+		})
+
+		Context("when a policy has a description and labels", func() {
+			BeforeEach(func() {
+				policyClient.GetPoliciesReturns([]models.Policy{{
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Port: 9999, Protocol: "tcp"},
+					Description: "some description",
+					Labels:      map[string]string{"team": "payments", "env": "prod"},
+				}}, nil)
+			})
+
+			It("shows them with the labels sorted by key", func() {
+				output, err := runner.List()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(output).To(Equal("<BOLD>Source\t\tDestination\tProtocol\tPort\tICMP Type\tICMP Code\tDescription\t\tLabels\t\t\tExpires\n<RESET><CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\ttcp\t\t9999\t-\t\t-\t\tsome description\tenv=prod,team=payments\t\n"))
+			})
+		})
+
+		Context("when a policy has an expiry", func() {
+			BeforeEach(func() {
+				expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+				policyClient.GetPoliciesReturns([]models.Policy{{
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Port: 9999, Protocol: "tcp"},
+					ExpiresAt:   &expiresAt,
+				}}, nil)
+			})
+
+			It("shows it", func() {
+				output, err := runner.List()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(output).To(Equal("<BOLD>Source\t\tDestination\tProtocol\tPort\tICMP Type\tICMP Code\tDescription\tLabels\tExpires\n<RESET><CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\ttcp\t\t9999\t-\t\t-\t\t\t\t\t2030-01-02T03:04:05Z\n"))
+			})
+		})
+
+		Context("when policies are for icmp or all protocols", func() {
+			BeforeEach(func() {
+				echoRequest, codeZero := 8, 0
+				policyClient.GetPoliciesReturns([]models.Policy{{
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "icmp", ICMPType: &echoRequest, ICMPCode: &codeZero},
+				}, {
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "icmp"},
+				}, {
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "all"},
+				}}, nil)
+			})
+
+			It("shows the icmp type and code instead of a port", func() {
+				output, err := runner.List()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(output).To(Equal("<BOLD>Source\t\tDestination\tProtocol\tPort\tICMP Type\tICMP Code\tDescription\tLabels\tExpires\n<RESET>" +
+					"<CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\ticmp\t\t-\t8\t\t0\t\t\t\t\t\n" +
+					"<CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\ticmp\t\t-\tany\t\tany\t\t\t\t\t\n" +
+					"<CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\tall\t\t-\t-\t\t-\t\t\t\t\t\n"))
 			})
 		})
 
@@ -94,7 +157,7 @@ var _ = Describe("CommandRunner", func() {
 				Expect(policyClient.GetPoliciesArgsForCall(0)).To(Equal("some-token"))
 				Expect(fakeCliConnection.GetAppsCallCount()).To(Equal(1))
 
-				Expect(output).To(Equal("<BOLD>Source\tDestination\tProtocol\tPort\n<RESET>"))
+				Expect(output).To(Equal("<BOLD>Source\tDestination\tProtocol\tPort\tICMP Type\tICMP Code\tDescription\tLabels\tExpires\n<RESET>"))
 			})
 		})
 
@@ -114,7 +177,7 @@ var _ = Describe("CommandRunner", func() {
 				Expect(policyClient.GetPoliciesArgsForCall(0)).To(Equal("some-token"))
 				Expect(fakeCliConnection.GetAppsCallCount()).To(Equal(1))
 
-				Expect(output).To(Equal("<BOLD>Source\tDestination\tProtocol\tPort\n<RESET>"))
+				Expect(output).To(Equal("<BOLD>Source\tDestination\tProtocol\tPort\tICMP Type\tICMP Code\tDescription\tLabels\tExpires\n<RESET>"))
 			})
 		})
 
@@ -177,7 +240,7 @@ var _ = Describe("CommandRunner", func() {
 			It("filters the call to the policy server", func() {
 				output, err := runner.List()
 				Expect(err).NotTo(HaveOccurred())
-				Expect(output).To(Equal("<BOLD>Source\t\tDestination\tProtocol\tPort\n<RESET><CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\ttcp\t\t9999\n"))
+				Expect(output).To(Equal("<BOLD>Source\t\tDestination\tProtocol\tPort\tICMP Type\tICMP Code\tDescription\tLabels\tExpires\n<RESET><CLR_C>some-app<RESET>\t<CLR_C>some-other-app<RESET>\ttcp\t\t9999\t-\t\t-\t\t\t\t\t\n"))
 
 				Expect(fakeCliConnection.GetAppCallCount()).To(Equal(1))
 				Expect(fakeCliConnection.GetAppArgsForCall(0)).To(Equal("some-app"))
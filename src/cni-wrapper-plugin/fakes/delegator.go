@@ -35,6 +35,18 @@ type Delegator struct {
 	delegateDelReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DelegateCheckStub        func(delegatePlugin string, netconf []byte) error
+	delegateCheckMutex       sync.RWMutex
+	delegateCheckArgsForCall []struct {
+		delegatePlugin string
+		netconf        []byte
+	}
+	delegateCheckReturns struct {
+		result1 error
+	}
+	delegateCheckReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -150,6 +162,60 @@ func (fake *Delegator) DelegateDelReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *Delegator) DelegateCheck(delegatePlugin string, netconf []byte) error {
+	var netconfCopy []byte
+	if netconf != nil {
+		netconfCopy = make([]byte, len(netconf))
+		copy(netconfCopy, netconf)
+	}
+	fake.delegateCheckMutex.Lock()
+	ret, specificReturn := fake.delegateCheckReturnsOnCall[len(fake.delegateCheckArgsForCall)]
+	fake.delegateCheckArgsForCall = append(fake.delegateCheckArgsForCall, struct {
+		delegatePlugin string
+		netconf        []byte
+	}{delegatePlugin, netconfCopy})
+	fake.recordInvocation("DelegateCheck", []interface{}{delegatePlugin, netconfCopy})
+	fake.delegateCheckMutex.Unlock()
+	if fake.DelegateCheckStub != nil {
+		return fake.DelegateCheckStub(delegatePlugin, netconf)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.delegateCheckReturns.result1
+}
+
+func (fake *Delegator) DelegateCheckCallCount() int {
+	fake.delegateCheckMutex.RLock()
+	defer fake.delegateCheckMutex.RUnlock()
+	return len(fake.delegateCheckArgsForCall)
+}
+
+func (fake *Delegator) DelegateCheckArgsForCall(i int) (string, []byte) {
+	fake.delegateCheckMutex.RLock()
+	defer fake.delegateCheckMutex.RUnlock()
+	return fake.delegateCheckArgsForCall[i].delegatePlugin, fake.delegateCheckArgsForCall[i].netconf
+}
+
+func (fake *Delegator) DelegateCheckReturns(result1 error) {
+	fake.DelegateCheckStub = nil
+	fake.delegateCheckReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *Delegator) DelegateCheckReturnsOnCall(i int, result1 error) {
+	fake.DelegateCheckStub = nil
+	if fake.delegateCheckReturnsOnCall == nil {
+		fake.delegateCheckReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.delegateCheckReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *Delegator) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -157,7 +223,13 @@ func (fake *Delegator) Invocations() map[string][][]interface{} {
 	defer fake.delegateAddMutex.RUnlock()
 	fake.delegateDelMutex.RLock()
 	defer fake.delegateDelMutex.RUnlock()
-	return fake.invocations
+	fake.delegateCheckMutex.RLock()
+	defer fake.delegateCheckMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
 }
 
 func (fake *Delegator) recordInvocation(key string, args []interface{}) {
@@ -5,20 +5,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"lib/datastore"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	"code.cloudfoundry.org/garden"
+	"code.cloudfoundry.org/lager/lagertest"
 
 	noop_debug "github.com/containernetworking/cni/plugins/test/noop/debug"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gexec"
 	"github.com/pivotal-cf-experimental/gomegamatchers"
+	"github.com/tedsuo/ifrit"
 	"github.com/vishvananda/netlink"
 )
 
@@ -42,6 +47,7 @@ var _ = Describe("CniWrapperPlugin", func() {
 		debug                   *noop_debug.Debug
 		healthCheckServer       *httptest.Server
 		healthCheckReturnStatus int
+		healthCheckFailures     int32
 		inputStruct             InputStruct
 		containerID             string
 		netinChainName          string
@@ -96,7 +102,12 @@ var _ = Describe("CniWrapperPlugin", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		healthCheckReturnStatus = http.StatusOK
+		healthCheckFailures = 0
 		healthCheckServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&healthCheckFailures, -1) >= 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
 			w.WriteHeader(healthCheckReturnStatus)
 		}))
 
@@ -148,7 +159,7 @@ var _ = Describe("CniWrapperPlugin", func() {
 				VTEPName:                 "some-device",
 				IPTablesDeniedLogsPerSec: 5,
 				RuntimeConfig: lib.RuntimeConfig{
-					PortMappings: []garden.NetIn{
+					PortMappings: []lib.NetIn{
 						{
 							HostPort:      1000,
 							ContainerPort: 1001,
@@ -293,6 +304,63 @@ var _ = Describe("CniWrapperPlugin", func() {
 		})
 	})
 
+	Describe("datastore notifications", func() {
+		var (
+			socketDir string
+			changes   chan struct{}
+			listener  ifrit.Process
+		)
+
+		BeforeEach(func() {
+			var err error
+			socketDir, err = ioutil.TempDir("", "datastore-notify")
+			Expect(err).NotTo(HaveOccurred())
+
+			changes = make(chan struct{}, 1)
+			listener = ifrit.Invoke(&datastore.Listener{
+				SocketPath: filepath.Join(socketDir, "datastore.sock"),
+				Changes:    changes,
+				Logger:     lagertest.NewTestLogger("test"),
+			})
+
+			inputStruct.WrapperConfig.DatastoreNotifySocket = filepath.Join(socketDir, "datastore.sock")
+			input = GetInput(inputStruct)
+			cmd = cniCommand("ADD", input)
+		})
+
+		AfterEach(func() {
+			listener.Signal(os.Interrupt)
+			Eventually(listener.Wait()).Should(Receive())
+			os.RemoveAll(socketDir)
+		})
+
+		It("notifies the listener when the container is added and deleted", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Eventually(changes).Should(Receive())
+
+			session, err = gexec.Start(cniCommand("DEL", input), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			Eventually(changes).Should(Receive())
+		})
+
+		Context("when nothing listens on the socket", func() {
+			BeforeEach(func() {
+				listener.Signal(os.Interrupt)
+				Eventually(listener.Wait()).Should(Receive())
+			})
+
+			It("still sets up the container", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0))
+				Expect(session.Err.Contents()).NotTo(ContainSubstring("notify datastore change"))
+			})
+		})
+	})
+
 	Describe("iptables lifecycle", func() {
 		It("adds and removes ip masquerade rules with the lifetime of the container", func() {
 			By("calling ADD")
@@ -501,7 +569,7 @@ var _ = Describe("CniWrapperPlugin", func() {
 
 			Context("when a port mapping with hostport 0 is given", func() {
 				BeforeEach(func() {
-					inputStruct.WrapperConfig.RuntimeConfig.PortMappings = []garden.NetIn{
+					inputStruct.WrapperConfig.RuntimeConfig.PortMappings = []lib.NetIn{
 						{
 							HostPort:      0,
 							ContainerPort: 1001,
@@ -691,28 +759,40 @@ var _ = Describe("CniWrapperPlugin", func() {
 		Context("When the health check call returns an error", func() {
 			BeforeEach(func() {
 				healthCheckServer.Close()
+				inputStruct.WrapperConfig.HealthCheckBackoffMs = 50
+				inputStruct.WrapperConfig.HealthCheckDeadlineMs = 300
+				input = GetInput(inputStruct)
+
+				cmd = cniCommand("ADD", input)
 			})
 
-			It("wraps and returns the error", func() {
+			It("retries until the deadline and returns the reason", func() {
 				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 				Expect(err).NotTo(HaveOccurred())
-				Eventually(session).Should(gexec.Exit(1))
+				Eventually(session, "5s").Should(gexec.Exit(1))
 				var errData map[string]interface{}
 				Expect(json.Unmarshal(session.Out.Contents(), &errData)).To(Succeed())
 				Expect(errData["code"]).To(BeEquivalentTo(100))
 				Expect(errData["msg"]).To(ContainSubstring("could not call health check: Get http"))
+				Expect(errData["msg"]).To(HaveSuffix("after 3 attempts"))
+				Expect(errData["details"]).To(Equal(healthCheckServer.URL + " did not become healthy within 300ms"))
 			})
 		})
 
 		Context("When the health check returns a non-200 status code", func() {
 			BeforeEach(func() {
 				healthCheckReturnStatus = 503
+				inputStruct.WrapperConfig.HealthCheckBackoffMs = 50
+				inputStruct.WrapperConfig.HealthCheckDeadlineMs = 300
+				input = GetInput(inputStruct)
+
+				cmd = cniCommand("ADD", input)
 			})
 
 			It("wraps and returns the error", func() {
 				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 				Expect(err).NotTo(HaveOccurred())
-				Eventually(session).Should(gexec.Exit(1))
+				Eventually(session, "5s").Should(gexec.Exit(1))
 				var errData map[string]interface{}
 				Expect(json.Unmarshal(session.Out.Contents(), &errData)).To(Succeed())
 				Expect(errData["code"]).To(BeEquivalentTo(100))
@@ -720,6 +800,23 @@ var _ = Describe("CniWrapperPlugin", func() {
 			})
 		})
 
+		Context("When the health check recovers before the deadline", func() {
+			BeforeEach(func() {
+				healthCheckFailures = 2
+				inputStruct.WrapperConfig.HealthCheckBackoffMs = 50
+				input = GetInput(inputStruct)
+
+				cmd = cniCommand("ADD", input)
+			})
+
+			It("retries and sets up the container", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session, "5s").Should(gexec.Exit(0))
+				Expect(atomic.LoadInt32(&healthCheckFailures)).To(BeNumerically("<", 0))
+			})
+		})
+
 		Context("When the delegate plugin returns an error", func() {
 			BeforeEach(func() {
 				debug.ReportError = "banana"
@@ -778,6 +875,116 @@ var _ = Describe("CniWrapperPlugin", func() {
 
 				Expect(AllIPTablesRules("nat")).NotTo(ContainElement("-A POSTROUTING -s 1.2.3.4/32 ! -o some-device -j MASQUERADE"))
 			})
+
+			It("calls the delegate DEL", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1))
+
+				debug, err := noop_debug.ReadDebug(debugFileName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(debug.Command).To(Equal("DEL"))
+			})
+		})
+
+		Describe("rolling back a failed ADD", func() {
+			expectRolledBack := func() {
+				By("checking that the delegate DEL was called")
+				debug, err := noop_debug.ReadDebug(debugFileName)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(debug.Command).To(Equal("DEL"))
+
+				By("checking that the datastore entry was removed")
+				stateFileBytes, err := ioutil.ReadFile(datastorePath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(stateFileBytes)).NotTo(ContainSubstring(containerID))
+
+				By("checking that the chains were removed")
+				for _, table := range []string{"filter", "nat", "mangle"} {
+					tableRules := AllIPTablesRules(table)
+					Expect(tableRules).NotTo(ContainElement(ContainSubstring(netinChainName)))
+					Expect(tableRules).NotTo(ContainElement(ContainSubstring(netoutChainName)))
+					Expect(tableRules).NotTo(ContainElement(ContainSubstring(inputChainName)))
+					Expect(tableRules).NotTo(ContainElement(ContainSubstring(overlayChainName)))
+				}
+
+				By("checking that the ip masquerade rule was removed")
+				Expect(AllIPTablesRules("nat")).NotTo(ContainElement("-A POSTROUTING -s 1.2.3.4/32 ! -o some-device -j MASQUERADE"))
+			}
+
+			runFailingAdd := func(expectedMessage string) {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session, "5s").Should(gexec.Exit(1))
+
+				var errData map[string]interface{}
+				Expect(json.Unmarshal(session.Out.Contents(), &errData)).To(Succeed())
+				Expect(errData["msg"]).To(ContainSubstring(expectedMessage))
+			}
+
+			Context("when initializing the netout chains fails", func() {
+				BeforeEach(func() {
+					session, err := gexec.Start(exec.Command("iptables", "-w", "-t", "filter", "-N", netoutChainName), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(session).Should(gexec.Exit(0))
+				})
+
+				It("undoes the delegate ADD and the datastore entry", func() {
+					runFailingAdd("initialize net out: ")
+					expectRolledBack()
+				})
+			})
+
+			Context("when initializing the netin chains fails", func() {
+				BeforeEach(func() {
+					session, err := gexec.Start(exec.Command("iptables", "-w", "-t", "nat", "-N", netinChainName), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(session).Should(gexec.Exit(0))
+				})
+
+				It("undoes the netout chains", func() {
+					runFailingAdd("initialize net in: ")
+					expectRolledBack()
+				})
+			})
+
+			Context("when adding a port mapping fails", func() {
+				BeforeEach(func() {
+					inputStruct.WrapperConfig.InstanceAddress = "10.244.2.3/99"
+					input = GetInput(inputStruct)
+
+					cmd = cniCommand("ADD", input)
+				})
+
+				It("undoes the netin chains", func() {
+					runFailingAdd("adding netin rule: ")
+					expectRolledBack()
+				})
+			})
+
+			Context("when inserting the egress rules fails", func() {
+				BeforeEach(func() {
+					inputStruct.WrapperConfig.RuntimeConfig.NetOutRules = []garden.NetOutRule{
+						{
+							Protocol: garden.ProtocolAll,
+							Networks: []garden.IPRange{
+								{
+									Start: net.ParseIP("1.1.1.1"),
+									End:   net.ParseIP("fd00::1"),
+								},
+							},
+						},
+					}
+					input = GetInput(inputStruct)
+
+					cmd = cniCommand("ADD", input)
+				})
+
+				It("undoes every completed step", func() {
+					runFailingAdd("bulk insert: ")
+					expectRolledBack()
+				})
+			})
 		})
 	})
 
@@ -848,4 +1055,177 @@ var _ = Describe("CniWrapperPlugin", func() {
 		})
 
 	})
+
+	Context("When call with command CHECK", func() {
+		var checkInput = func(cniVersion string, prevResult []byte) string {
+			var checkConfig map[string]interface{}
+			Expect(json.Unmarshal([]byte(input), &checkConfig)).To(Succeed())
+
+			checkConfig["cniVersion"] = cniVersion
+			checkConfig["prevResult"] = json.RawMessage(prevResult)
+
+			checkBytes, err := json.Marshal(checkConfig)
+			Expect(err).NotTo(HaveOccurred())
+			return string(checkBytes)
+		}
+
+		var addResult []byte
+
+		BeforeEach(func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+			addResult = session.Out.Contents()
+
+			cmd = cniCommand("CHECK", checkInput("0.4.0", addResult))
+		})
+
+		It("succeeds when nothing has drifted", func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+		})
+
+		Context("when the ip masquerade rule has been removed", func() {
+			BeforeEach(func() {
+				session, err := gexec.Start(exec.Command("iptables", "-w", "-t", "nat", "-D", "POSTROUTING", "-s", "1.2.3.4/32", "!", "-o", "some-device", "-j", "MASQUERADE"), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(0))
+			})
+
+			It("reports the drift", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1))
+
+				var errData map[string]interface{}
+				Expect(json.Unmarshal(session.Out.Contents(), &errData)).To(Succeed())
+				Expect(errData["code"]).To(BeEquivalentTo(100))
+				Expect(errData["msg"]).To(ContainSubstring("container some-container-id-that-is-long has drifted"))
+				Expect(errData["msg"]).To(ContainSubstring("missing ip masq rule for 1.2.3.4"))
+			})
+		})
+
+		Context("when the netout chain has been removed", func() {
+			BeforeEach(func() {
+				for _, args := range [][]string{
+					{"-D", "FORWARD", "-s", "1.2.3.4/32", "-o", defaultIface.Name, "-j", netoutChainName},
+					{"-F", netoutChainName},
+					{"-X", netoutChainName},
+				} {
+					session, err := gexec.Start(exec.Command("iptables", append([]string{"-w", "-t", "filter"}, args...)...), GinkgoWriter, GinkgoWriter)
+					Expect(err).NotTo(HaveOccurred())
+					Eventually(session).Should(gexec.Exit(0))
+				}
+			})
+
+			It("reports the drift", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1))
+				Expect(string(session.Out.Contents())).To(ContainSubstring("missing chain " + netoutChainName))
+			})
+		})
+
+		Context("when the prevResult has an address that is not in the datastore", func() {
+			BeforeEach(func() {
+				cmd = cniCommand("CHECK", checkInput("0.4.0", []byte(`{ "cniVersion": "0.4.0", "ips": [{ "version": "4", "address": "1.2.3.5/32" }], "dns":{} }`)))
+			})
+
+			It("reports the drift", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1))
+				Expect(string(session.Out.Contents())).To(ContainSubstring("store: ip 1.2.3.5 of prevResult not found"))
+			})
+		})
+
+		Context("when the config is older than cniVersion 0.4.0", func() {
+			BeforeEach(func() {
+				cmd = cniCommand("CHECK", checkInput("0.3.1", addResult))
+			})
+
+			It("is rejected by the version negotiation", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1))
+				Expect(string(session.Out.Contents())).To(ContainSubstring("config version does not allow CHECK"))
+			})
+		})
+
+		Context("when the container is not in the datastore", func() {
+			BeforeEach(func() {
+				cmd.Env[1] = "CNI_CONTAINERID=some-other-container-id"
+			})
+
+			It("reports the drift", func() {
+				session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1))
+				Expect(string(session.Out.Contents())).To(ContainSubstring("store: no entry for container some-other-container-id"))
+			})
+		})
+	})
+
+	Context("When call with command GC", func() {
+		var gcInput = func(validContainerIDs ...string) string {
+			var gcConfig map[string]interface{}
+			Expect(json.Unmarshal([]byte(input), &gcConfig)).To(Succeed())
+
+			attachments := []map[string]string{}
+			for _, id := range validContainerIDs {
+				attachments = append(attachments, map[string]string{"containerID": id, "ifname": "some-eth0"})
+			}
+			gcConfig["cni.dev/valid-attachments"] = attachments
+
+			gcBytes, err := json.Marshal(gcConfig)
+			Expect(err).NotTo(HaveOccurred())
+			return string(gcBytes)
+		}
+
+		BeforeEach(func() {
+			session, err := gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+		})
+
+		It("removes the state of containers that are no longer attached", func() {
+			session, err := gexec.Start(cniCommand("GC", gcInput("some-other-container-id")), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			stateFileBytes, err := ioutil.ReadFile(datastorePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(stateFileBytes)).NotTo(ContainSubstring("1.2.3.4"))
+
+			Expect(AllIPTablesRules("nat")).NotTo(ContainElement("-A POSTROUTING -s 1.2.3.4/32 ! -o some-device -j MASQUERADE"))
+			Expect(AllIPTablesRules("nat")).NotTo(ContainElement(ContainSubstring(netinChainName)))
+			Expect(AllIPTablesRules("filter")).NotTo(ContainElement(ContainSubstring(netoutChainName)))
+			Expect(AllIPTablesRules("filter")).NotTo(ContainElement(ContainSubstring(inputChainName)))
+			Expect(AllIPTablesRules("filter")).NotTo(ContainElement(ContainSubstring(overlayChainName)))
+		})
+
+		It("leaves the state of attached containers alone", func() {
+			session, err := gexec.Start(cniCommand("GC", gcInput(containerID)), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session).Should(gexec.Exit(0))
+
+			stateFileBytes, err := ioutil.ReadFile(datastorePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(stateFileBytes)).To(ContainSubstring("1.2.3.4"))
+			Expect(AllIPTablesRules("nat")).To(ContainElement("-A POSTROUTING -s 1.2.3.4/32 ! -o some-device -j MASQUERADE"))
+			Expect(AllIPTablesRules("filter")).To(ContainElement("-N " + netoutChainName))
+		})
+
+		Context("when the valid attachments are missing", func() {
+			It("refuses to remove anything", func() {
+				session, err := gexec.Start(cniCommand("GC", input), GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(session).Should(gexec.Exit(1))
+				Expect(string(session.Out.Contents())).To(ContainSubstring("missing cni.dev/valid-attachments"))
+
+				Expect(AllIPTablesRules("filter")).To(ContainElement("-N " + netoutChainName))
+			})
+		})
+	})
 })
@@ -1,6 +1,7 @@
 package legacynet
 
 import (
+	"cni-wrapper-plugin/lib"
 	"fmt"
 	"lib/rules"
 	"net"
@@ -55,8 +56,26 @@ func (m *NetIn) Cleanup(containerHandle string) error {
 	return result
 }
 
-func (m *NetIn) AddRule(containerHandle string,
-	hostPort, containerPort int, hostIP, containerIP string) error {
+func (m *NetIn) Check(containerHandle string) error {
+	chain := m.ChainNamer.Prefix(prefixNetIn, containerHandle)
+
+	return checkChains(m.IPTables, []fullRule{
+		{
+			Table:       "nat",
+			ParentChain: "PREROUTING",
+			Chain:       chain,
+		},
+		{
+			Table:       "mangle",
+			ParentChain: "PREROUTING",
+			Chain:       chain,
+		},
+	})
+}
+
+// AddRule forwards the host ports of netIn on hostIP to the container, for
+// each of its protocols.
+func (m *NetIn) AddRule(containerHandle string, netIn lib.NetIn, hostIP, containerIP string) error {
 	chain := m.ChainNamer.Prefix(prefixNetIn, containerHandle)
 
 	parsedIP := net.ParseIP(hostIP)
@@ -69,22 +88,28 @@ func (m *NetIn) AddRule(containerHandle string,
 		return fmt.Errorf("invalid ip: %s", containerIP)
 	}
 
+	hostPort, hostPortEnd := int(netIn.HostPort), netIn.HostPortEnd()
+	forwardingRules := []rules.IPTablesRule{}
+	markRules := []rules.IPTablesRule{}
+	for _, protocol := range netIn.Protocols() {
+		forwardingRules = append(forwardingRules,
+			rules.NewPortForwardingRule(protocol, hostPort, hostPortEnd, int(netIn.ContainerPort), hostIP, containerIP))
+		markRules = append(markRules,
+			rules.NewIngressMarkRule(m.HostInterfaceName, protocol, hostPort, hostPortEnd, hostIP, m.IngressTag))
+	}
+
 	args := []fullRule{
 		{
 			Table:       "nat",
 			ParentChain: "PREROUTING",
 			Chain:       chain,
-			Rules: []rules.IPTablesRule{
-				rules.NewPortForwardingRule(hostPort, containerPort, hostIP, containerIP),
-			},
+			Rules:       forwardingRules,
 		},
 		{
 			Table:       "mangle",
 			ParentChain: "PREROUTING",
 			Chain:       chain,
-			Rules: []rules.IPTablesRule{
-				rules.NewIngressMarkRule(m.HostInterfaceName, hostPort, hostIP, m.IngressTag),
-			},
+			Rules:       markRules,
 		},
 	}
 
@@ -110,6 +135,30 @@ func initChains(iptables rules.IPTablesAdapter, args []fullRule) error {
 	return nil
 }
 
+func checkChains(iptables rules.IPTablesAdapter, args []fullRule) error {
+	var result error
+	for _, arg := range args {
+		if _, err := iptables.List(arg.Table, arg.Chain); err != nil {
+			result = multierror.Append(result, fmt.Errorf("missing chain %s in table %s: %s", arg.Chain, arg.Table, err))
+			continue
+		}
+
+		if arg.ParentChain == "" {
+			continue
+		}
+
+		jumpRule := append(rules.IPTablesRule{}, arg.JumpConditions...)
+		jumpRule = append(jumpRule, "--jump", arg.Chain)
+		exists, err := iptables.Exists(arg.Table, arg.ParentChain, jumpRule)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("checking jump to %s: %s", arg.Chain, err))
+		} else if !exists {
+			result = multierror.Append(result, fmt.Errorf("missing jump from %s to %s in table %s", arg.ParentChain, arg.Chain, arg.Table))
+		}
+	}
+	return result
+}
+
 func applyRules(iptables rules.IPTablesAdapter, args []fullRule) error {
 	for _, arg := range args {
 		err := iptables.BulkAppend(arg.Table, arg.Chain, arg.Rules...)
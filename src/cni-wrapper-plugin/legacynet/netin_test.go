@@ -3,6 +3,7 @@ package legacynet_test
 import (
 	"cni-wrapper-plugin/fakes"
 	"cni-wrapper-plugin/legacynet"
+	"cni-wrapper-plugin/lib"
 	"errors"
 
 	lib_fakes "lib/fakes"
@@ -196,9 +197,78 @@ var _ = Describe("Netin", func() {
 		})
 	})
 
+	Describe("Check", func() {
+		BeforeEach(func() {
+			ipTables.ExistsReturns(true, nil)
+		})
+
+		It("checks that the chain and the jump to it exist in both tables", func() {
+			err := netIn.Check("some-container-handle")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ipTables.ListCallCount()).To(Equal(2))
+			table, chain := ipTables.ListArgsForCall(0)
+			Expect(table).To(Equal("nat"))
+			Expect(chain).To(Equal("some-chain-name"))
+			table, chain = ipTables.ListArgsForCall(1)
+			Expect(table).To(Equal("mangle"))
+			Expect(chain).To(Equal("some-chain-name"))
+
+			Expect(ipTables.ExistsCallCount()).To(Equal(2))
+			table, chain, rulespec := ipTables.ExistsArgsForCall(0)
+			Expect(table).To(Equal("nat"))
+			Expect(chain).To(Equal("PREROUTING"))
+			Expect(rulespec).To(Equal(rules.IPTablesRule{"--jump", "some-chain-name"}))
+			table, chain, rulespec = ipTables.ExistsArgsForCall(1)
+			Expect(table).To(Equal("mangle"))
+			Expect(chain).To(Equal("PREROUTING"))
+			Expect(rulespec).To(Equal(rules.IPTablesRule{"--jump", "some-chain-name"}))
+		})
+
+		Context("when a chain is missing", func() {
+			BeforeEach(func() {
+				ipTables.ListStub = func(table, chain string) ([]string, error) {
+					if table == "mangle" {
+						return nil, errors.New("no chain by that name")
+					}
+					return nil, nil
+				}
+			})
+
+			It("reports the missing chain", func() {
+				err := netIn.Check("some-container-handle")
+				Expect(err).To(MatchError(ContainSubstring("missing chain some-chain-name in table mangle: no chain by that name")))
+				Expect(ipTables.ExistsCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when a jump is missing", func() {
+			BeforeEach(func() {
+				ipTables.ExistsReturns(false, nil)
+			})
+
+			It("reports every missing jump", func() {
+				err := netIn.Check("some-container-handle")
+				Expect(err).To(MatchError(ContainSubstring("missing jump from PREROUTING to some-chain-name in table nat")))
+				Expect(err).To(MatchError(ContainSubstring("missing jump from PREROUTING to some-chain-name in table mangle")))
+			})
+		})
+
+		Context("when checking a jump fails", func() {
+			BeforeEach(func() {
+				ipTables.ExistsReturns(false, errors.New("potato"))
+			})
+
+			It("returns the error", func() {
+				err := netIn.Check("some-container-handle")
+				Expect(err).To(MatchError(ContainSubstring("checking jump to some-chain-name: potato")))
+			})
+		})
+	})
+
 	Describe("AddRule", func() {
 		It("creates and enforces a portforwarding and mark rule", func() {
-			err := netIn.AddRule("some-container-handle", 1111, 2222, "1.2.3.4", "5.6.7.8")
+			err := netIn.AddRule("some-container-handle", lib.NetIn{HostPort: 1111, ContainerPort: 2222}, "1.2.3.4", "5.6.7.8")
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(chainNamer.PrefixCallCount()).To(Equal(1))
@@ -228,26 +298,71 @@ var _ = Describe("Netin", func() {
 			}}))
 		})
 
+		It("forwards a range of host ports for each protocol", func() {
+			err := netIn.AddRule("some-container-handle", lib.NetIn{
+				HostPort:      1111,
+				HostPortCount: 10,
+				ContainerPort: 2222,
+				Protocol:      "tcp+udp",
+			}, "1.2.3.4", "5.6.7.8")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ipTables.BulkAppendCallCount()).To(Equal(2))
+			table, _, rulespec := ipTables.BulkAppendArgsForCall(0)
+			Expect(table).To(Equal("nat"))
+			Expect(rulespec).To(Equal([]rules.IPTablesRule{
+				{
+					"-d", "1.2.3.4", "-p", "tcp",
+					"-m", "tcp", "--dport", "1111:1120",
+					"--jump", "DNAT",
+					"--to-destination", "5.6.7.8:2222",
+				},
+				{
+					"-d", "1.2.3.4", "-p", "udp",
+					"-m", "udp", "--dport", "1111:1120",
+					"--jump", "DNAT",
+					"--to-destination", "5.6.7.8:2222",
+				},
+			}))
+
+			table, _, rulespec = ipTables.BulkAppendArgsForCall(1)
+			Expect(table).To(Equal("mangle"))
+			Expect(rulespec).To(Equal([]rules.IPTablesRule{
+				{
+					"-i", "some-eth0", "-d", "1.2.3.4", "-p", "tcp",
+					"-m", "tcp", "--dport", "1111:1120",
+					"--jump", "MARK",
+					"--set-mark", "0xFEEDBEEF",
+				},
+				{
+					"-i", "some-eth0", "-d", "1.2.3.4", "-p", "udp",
+					"-m", "udp", "--dport", "1111:1120",
+					"--jump", "MARK",
+					"--set-mark", "0xFEEDBEEF",
+				},
+			}))
+		})
+
 		Context("when writing the netin rule fails", func() {
 			BeforeEach(func() {
 				ipTables.BulkAppendReturns(errors.New("blue potato"))
 			})
 			It("returns an error", func() {
-				err := netIn.AddRule("some-container-handle", 1111, 2222, "1.2.3.4", "5.6.7.8")
+				err := netIn.AddRule("some-container-handle", lib.NetIn{HostPort: 1111, ContainerPort: 2222}, "1.2.3.4", "5.6.7.8")
 				Expect(err).To(MatchError("appending rule: blue potato"))
 			})
 		})
 
 		Context("when the host ip is invalid", func() {
 			It("returns an error", func() {
-				err := netIn.AddRule("some-container-handle", 1111, 2222, "banana", "5.6.7.8")
+				err := netIn.AddRule("some-container-handle", lib.NetIn{HostPort: 1111, ContainerPort: 2222}, "banana", "5.6.7.8")
 				Expect(err).To(MatchError("invalid ip: banana"))
 			})
 		})
 
 		Context("when the container ip is invalid", func() {
 			It("returns an error", func() {
-				err := netIn.AddRule("some-container-handle", 1111, 2222, "5.6.7.8", "banana")
+				err := netIn.AddRule("some-container-handle", lib.NetIn{HostPort: 1111, ContainerPort: 2222}, "5.6.7.8", "banana")
 				Expect(err).To(MatchError("invalid ip: banana"))
 			})
 		})
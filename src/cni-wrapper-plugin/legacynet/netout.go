@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"lib/rules"
 	"net"
+	"strings"
 
 	multierror "github.com/hashicorp/go-multierror"
 
@@ -16,6 +17,10 @@ const prefixNetOut = "netout"
 const prefixOverlay = "overlay"
 const suffixNetOutLog = "log"
 
+// prefixEgress starts the names of the chains vxlan-policy-agent attaches to
+// netout chains to enforce egress policies.
+const prefixEgress = "egress--"
+
 //go:generate counterfeiter -o ../fakes/net_out_rule_converter.go --fake-name NetOutRuleConverter . netOutRuleConverter
 type netOutRuleConverter interface {
 	Convert(rule garden.NetOutRule, logChainName string, logging bool) []rules.IPTablesRule
@@ -32,6 +37,11 @@ type NetOut struct {
 	VTEPName          string
 	HostInterfaceName string
 	DeniedLogsPerSec  int
+
+	// IPv6 is set when IPTables writes the ip6tables tables. The chains of a
+	// dual-stack container are then set up once per family, each with the
+	// net-out networks and DNS servers of its family.
+	IPv6 bool
 }
 
 type fullRule struct {
@@ -76,10 +86,7 @@ func (m *NetOut) Initialize(containerHandle string, containerIP net.IP, dnsServe
 				"-s", containerIP.String(),
 				"-o", m.HostInterfaceName,
 			},
-			Rules: []rules.IPTablesRule{
-				rules.NewNetOutRelatedEstablishedRule(),
-				rules.NewNetOutDefaultRejectRule(),
-			},
+			Rules: m.defaultNetOutRules(containerHandle),
 		},
 		{
 			Table:       "filter",
@@ -102,14 +109,6 @@ func (m *NetOut) Initialize(containerHandle string, containerIP net.IP, dnsServe
 		},
 	}
 
-	if m.ASGLogging {
-		args[1].Rules = []rules.IPTablesRule{
-			rules.NewNetOutRelatedEstablishedRule(),
-			rules.NewNetOutDefaultRejectLogRule(containerHandle, m.DeniedLogsPerSec),
-			rules.NewNetOutDefaultRejectRule(),
-		}
-	}
-
 	if m.C2CLogging {
 		args[2].Rules = []rules.IPTablesRule{
 			rules.NewOverlayAllowEgress(m.VTEPName, containerIP.String()),
@@ -120,6 +119,7 @@ func (m *NetOut) Initialize(containerHandle string, containerIP net.IP, dnsServe
 		}
 	}
 
+	dnsServers = m.familyAddresses(dnsServers)
 	if len(dnsServers) > 0 {
 		args[0].Rules = []rules.IPTablesRule{
 			rules.NewInputRelatedEstablishedRule(),
@@ -131,6 +131,10 @@ func (m *NetOut) Initialize(containerHandle string, containerIP net.IP, dnsServe
 		args[0].Rules = append(args[0].Rules, rules.NewInputDefaultRejectRule())
 	}
 
+	for i := range args {
+		args[i].Rules = m.forFamily(args[i].Rules)
+	}
+
 	err = initChains(m.IPTables, args)
 	if err != nil {
 		return err
@@ -139,16 +143,83 @@ func (m *NetOut) Initialize(containerHandle string, containerIP net.IP, dnsServe
 	return applyRules(m.IPTables, args)
 }
 
-func (m *NetOut) Cleanup(containerHandle, containerIP string) error {
+// defaultNetOutRules end every netout chain, after the ASG rules and the
+// jumps to egress policy chains.
+func (m *NetOut) defaultNetOutRules(containerHandle string) []rules.IPTablesRule {
+	if m.ASGLogging {
+		return m.forFamily([]rules.IPTablesRule{
+			rules.NewNetOutRelatedEstablishedRule(),
+			rules.NewNetOutDefaultRejectLogRule(containerHandle, m.DeniedLogsPerSec),
+			rules.NewNetOutDefaultRejectRule(),
+		})
+	}
+	return m.forFamily([]rules.IPTablesRule{
+		rules.NewNetOutRelatedEstablishedRule(),
+		rules.NewNetOutDefaultRejectRule(),
+	})
+}
+
+// forFamily converts rules built for iptables when writing the ip6tables
+// tables.
+func (m *NetOut) forFamily(ruleSpec []rules.IPTablesRule) []rules.IPTablesRule {
+	if !m.IPv6 {
+		return ruleSpec
+	}
+	converted := []rules.IPTablesRule{}
+	for _, rule := range ruleSpec {
+		converted = append(converted, rules.ToIPv6(rule))
+	}
+	return converted
+}
+
+func (m *NetOut) familyAddresses(addresses []string) []string {
+	var family []string
+	for _, address := range addresses {
+		if rules.IsIPv6(address) == m.IPv6 {
+			family = append(family, address)
+		}
+	}
+	return family
+}
+
+// familyNetOutRules drops the networks of the other IP family from the rules,
+// and the rules left without networks.
+func (m *NetOut) familyNetOutRules(netOutRules []garden.NetOutRule) []garden.NetOutRule {
+	var family []garden.NetOutRule
+	for _, rule := range netOutRules {
+		if len(rule.Networks) > 0 {
+			var networks []garden.IPRange
+			for _, network := range rule.Networks {
+				if rules.IsIPv6(network.Start.String()) == m.IPv6 {
+					networks = append(networks, network)
+				}
+			}
+			if len(networks) == 0 {
+				continue
+			}
+			rule.Networks = networks
+		}
+		family = append(family, rule)
+	}
+	return family
+}
+
+func (m *NetOut) convert(netOutRules []garden.NetOutRule, logChain string) []rules.IPTablesRule {
+	return m.forFamily(m.Converter.BulkConvert(m.familyNetOutRules(netOutRules), logChain, m.ASGLogging))
+}
+
+// chains are the chains Initialize created for the container, in the order
+// they are cleaned up.
+func (m *NetOut) chains(containerHandle, containerIP string) ([]fullRule, error) {
 	overlayChain := m.ChainNamer.Prefix(prefixOverlay, containerHandle)
 	forwardChain := m.ChainNamer.Prefix(prefixNetOut, containerHandle)
 	inputChain := m.ChainNamer.Prefix(prefixInput, containerHandle)
 	logChain, err := m.ChainNamer.Postfix(forwardChain, suffixNetOutLog)
 	if err != nil {
-		return fmt.Errorf("getting chain name: %s", err)
+		return nil, fmt.Errorf("getting chain name: %s", err)
 	}
 
-	args := []fullRule{
+	return []fullRule{
 		{
 			Table:       "filter",
 			ParentChain: "FORWARD",
@@ -176,9 +247,73 @@ func (m *NetOut) Cleanup(containerHandle, containerIP string) error {
 			ParentChain: "",
 			Chain:       logChain,
 		},
+	}, nil
+}
+
+func (m *NetOut) Cleanup(containerHandle, containerIP string) error {
+	args, err := m.chains(containerHandle, containerIP)
+	if err != nil {
+		return err
+	}
+	forwardChain := args[1].Chain
+
+	var result error
+	egressChains, err := m.egressChains(forwardChain)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	for _, egressChain := range egressChains {
+		args = append(args, fullRule{Table: "filter", Chain: egressChain})
 	}
 
-	return cleanupChains(args, m.IPTables)
+	if err := cleanupChains(args, m.IPTables); err != nil {
+		result = multierror.Append(result, err)
+	}
+	return result
+}
+
+// Check reports the chains Initialize created for the container that are
+// missing or no longer jumped to from their parent chain.
+func (m *NetOut) Check(containerHandle, containerIP string) error {
+	args, err := m.chains(containerHandle, containerIP)
+	if err != nil {
+		return err
+	}
+	return checkChains(m.IPTables, args)
+}
+
+// egressChains finds the egress chains jumped to from the container's netout
+// chain, which must be deleted once the netout chain no longer refers to them.
+func (m *NetOut) egressChains(forwardChain string) ([]string, error) {
+	ruleList, err := m.IPTables.List("filter", forwardChain)
+	if err != nil {
+		return nil, fmt.Errorf("list rules: %s", err)
+	}
+	return findEgressChains(ruleList), nil
+}
+
+func findEgressChains(ruleList []string) []string {
+	var egressChains []string
+	for _, rule := range ruleList {
+		if chain := egressJumpTarget(rule); chain != "" {
+			egressChains = append(egressChains, chain)
+		}
+	}
+	return egressChains
+}
+
+func isEgressJump(rule string) bool {
+	return egressJumpTarget(rule) != ""
+}
+
+func egressJumpTarget(rule string) string {
+	fields := strings.Fields(rule)
+	for i := 0; i < len(fields)-1; i++ {
+		if fields[i] == "-j" && strings.HasPrefix(fields[i+1], prefixEgress) {
+			return fields[i+1]
+		}
+	}
+	return ""
 }
 
 func cleanupChains(args []fullRule, iptables rules.IPTablesAdapter) error {
@@ -198,7 +333,10 @@ func (m *NetOut) InsertRule(containerHandle string, rule garden.NetOutRule) erro
 		return fmt.Errorf("getting chain name: %s", err)
 	}
 
-	ruleSpec := m.Converter.Convert(rule, logChain, m.ASGLogging)
+	var ruleSpec []rules.IPTablesRule
+	for _, familyRule := range m.familyNetOutRules([]garden.NetOutRule{rule}) {
+		ruleSpec = m.forFamily(m.Converter.Convert(familyRule, logChain, m.ASGLogging))
+	}
 	err = m.IPTables.BulkInsert("filter", chain, 1, ruleSpec...)
 	if err != nil {
 		return fmt.Errorf("inserting net-out rule: %s", err)
@@ -214,7 +352,7 @@ func (m *NetOut) BulkInsertRules(containerHandle string, netOutRules []garden.Ne
 		return fmt.Errorf("getting chain name: %s", err)
 	}
 
-	ruleSpec := m.Converter.BulkConvert(netOutRules, logChain, m.ASGLogging)
+	ruleSpec := m.convert(netOutRules, logChain)
 	err = m.IPTables.BulkInsert("filter", chain, 1, ruleSpec...)
 	if err != nil {
 		return fmt.Errorf("bulk inserting net-out rules: %s", err)
@@ -222,3 +360,25 @@ func (m *NetOut) BulkInsertRules(containerHandle string, netOutRules []garden.Ne
 
 	return nil
 }
+
+// BulkReplaceRules atomically replaces the ASG rules of a running container's
+// netout chain. Jumps to egress policy chains are kept so that policies
+// enforced by vxlan-policy-agent survive the update, including jumps it
+// inserts while the rules are being replaced.
+func (m *NetOut) BulkReplaceRules(containerHandle string, netOutRules []garden.NetOutRule) error {
+	chain := m.ChainNamer.Prefix(prefixNetOut, containerHandle)
+	logChain, err := m.ChainNamer.Postfix(chain, suffixNetOutLog)
+	if err != nil {
+		return fmt.Errorf("getting chain name: %s", err)
+	}
+
+	ruleSpec := m.convert(netOutRules, logChain)
+	ruleSpec = append(ruleSpec, m.defaultNetOutRules(containerHandle)...)
+
+	err = m.IPTables.BulkReplace("filter", chain, isEgressJump, ruleSpec...)
+	if err != nil {
+		return fmt.Errorf("replacing net-out rules: %s", err)
+	}
+
+	return nil
+}
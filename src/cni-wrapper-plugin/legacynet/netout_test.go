@@ -279,6 +279,51 @@ var _ = Describe("Netout", func() {
 
 			})
 		})
+
+		Context("when writing the IPv6 tables", func() {
+			BeforeEach(func() {
+				netOut.IPv6 = true
+			})
+
+			It("jumps to the chains for the IPv6 address and rejects with icmp6", func() {
+				err := netOut.Initialize("some-container-handle", net.ParseIP("fd00::5"), nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, _, _, rulespec := ipTables.BulkInsertArgsForCall(1)
+				Expect(rulespec).To(Equal([]rules.IPTablesRule{{"-s", "fd00::5", "-o", "some-device", "--jump", "netout-some-container-handle"}}))
+
+				_, chain, rulespec := ipTables.BulkAppendArgsForCall(1)
+				Expect(chain).To(Equal("netout-some-container-handle"))
+				Expect(rulespec).To(Equal([]rules.IPTablesRule{
+					{"-m", "state", "--state", "RELATED,ESTABLISHED",
+						"--jump", "ACCEPT"},
+					{"--jump", "REJECT",
+						"--reject-with", "icmp6-port-unreachable"},
+				}))
+
+				_, _, rulespec = ipTables.BulkAppendArgsForCall(2)
+				Expect(rulespec[len(rulespec)-1]).To(Equal(rules.IPTablesRule{
+					"-d", "fd00::5",
+					"--jump", "REJECT",
+					"--reject-with", "icmp6-port-unreachable",
+				}))
+			})
+
+			It("only allows the IPv6 dns servers", func() {
+				err := netOut.Initialize("some-container-handle", net.ParseIP("fd00::5"), []string{"8.8.4.4", "fe80::53"})
+				Expect(err).NotTo(HaveOccurred())
+
+				_, _, rulespec := ipTables.BulkAppendArgsForCall(0)
+				Expect(rulespec).To(Equal([]rules.IPTablesRule{
+					{"-m", "state", "--state", "RELATED,ESTABLISHED",
+						"--jump", "ACCEPT"},
+					{"-p", "tcp", "-d", "fe80::53", "--destination-port", "53", "--jump", "ACCEPT"},
+					{"-p", "udp", "-d", "fe80::53", "--destination-port", "53", "--jump", "ACCEPT"},
+					{"--jump", "REJECT",
+						"--reject-with", "icmp6-port-unreachable"},
+				}))
+			})
+		})
 	})
 
 	Describe("Cleanup", func() {
@@ -367,6 +412,44 @@ var _ = Describe("Netout", func() {
 
 		})
 
+		Context("when the policy agent attached egress chains to the netout chain", func() {
+			BeforeEach(func() {
+				ipTables.ListReturns([]string{
+					"-N netout-some-container-handle",
+					"-A netout-some-container-handle -j egress--01234567891500000000",
+					"-A netout-some-container-handle -m state --state RELATED,ESTABLISHED -j ACCEPT",
+				}, nil)
+			})
+
+			It("deletes the egress chains after the netout chain", func() {
+				err := netOut.Cleanup("some-container-handle", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				table, chain := ipTables.ListArgsForCall(0)
+				Expect(table).To(Equal("filter"))
+				Expect(chain).To(Equal("netout-some-container-handle"))
+
+				Expect(ipTables.ClearChainCallCount()).To(Equal(5))
+				_, chain = ipTables.ClearChainArgsForCall(4)
+				Expect(chain).To(Equal("egress--01234567891500000000"))
+
+				Expect(ipTables.DeleteChainCallCount()).To(Equal(5))
+				_, chain = ipTables.DeleteChainArgsForCall(4)
+				Expect(chain).To(Equal("egress--01234567891500000000"))
+			})
+		})
+
+		Context("when listing the netout chain fails", func() {
+			BeforeEach(func() {
+				ipTables.ListReturns(nil, errors.New("russet potato"))
+			})
+			It("still cleans up the container chains and returns the error", func() {
+				err := netOut.Cleanup("some-container-handle", "")
+				Expect(err).To(MatchError(ContainSubstring("list rules: russet potato")))
+				Expect(ipTables.DeleteChainCallCount()).To(Equal(4))
+			})
+		})
+
 		Context("when the chain namer fails", func() {
 			BeforeEach(func() {
 				chainNamer.PostfixReturns("", errors.New("banana"))
@@ -422,6 +505,76 @@ var _ = Describe("Netout", func() {
 		})
 	})
 
+	Describe("Check", func() {
+		BeforeEach(func() {
+			ipTables.ExistsReturns(true, nil)
+		})
+
+		It("checks the chains created by Initialize and the jumps to them", func() {
+			err := netOut.Check("some-container-handle", "5.6.7.8")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ipTables.ListCallCount()).To(Equal(4))
+			var listed []string
+			for i := 0; i < 4; i++ {
+				table, chain := ipTables.ListArgsForCall(i)
+				Expect(table).To(Equal("filter"))
+				listed = append(listed, chain)
+			}
+			Expect(listed).To(ConsistOf(
+				"overlay-some-container-handle",
+				"netout-some-container-handle",
+				"input-some-container-handle",
+				"some-other-chain-name",
+			))
+
+			Expect(ipTables.ExistsCallCount()).To(Equal(3))
+			table, chain, rulespec := ipTables.ExistsArgsForCall(0)
+			Expect(table).To(Equal("filter"))
+			Expect(chain).To(Equal("FORWARD"))
+			Expect(rulespec).To(Equal(rules.IPTablesRule{"--jump", "overlay-some-container-handle"}))
+			table, chain, rulespec = ipTables.ExistsArgsForCall(1)
+			Expect(table).To(Equal("filter"))
+			Expect(chain).To(Equal("FORWARD"))
+			Expect(rulespec).To(Equal(rules.IPTablesRule{"-s", "5.6.7.8", "-o", "some-device", "--jump", "netout-some-container-handle"}))
+			table, chain, rulespec = ipTables.ExistsArgsForCall(2)
+			Expect(table).To(Equal("filter"))
+			Expect(chain).To(Equal("INPUT"))
+			Expect(rulespec).To(Equal(rules.IPTablesRule{"-s", "5.6.7.8", "--jump", "input-some-container-handle"}))
+		})
+
+		Context("when chains and jumps have drifted", func() {
+			BeforeEach(func() {
+				ipTables.ListStub = func(table, chain string) ([]string, error) {
+					if chain == "some-other-chain-name" {
+						return nil, errors.New("no chain by that name")
+					}
+					return nil, nil
+				}
+				ipTables.ExistsStub = func(table, chain string, rulespec rules.IPTablesRule) (bool, error) {
+					return chain != "INPUT", nil
+				}
+			})
+
+			It("reports all of the drift", func() {
+				err := netOut.Check("some-container-handle", "5.6.7.8")
+				Expect(err).To(MatchError(ContainSubstring("missing chain some-other-chain-name in table filter: no chain by that name")))
+				Expect(err).To(MatchError(ContainSubstring("missing jump from INPUT to input-some-container-handle in table filter")))
+			})
+		})
+
+		Context("when the chain namer fails", func() {
+			BeforeEach(func() {
+				chainNamer.PostfixReturns("", errors.New("banana"))
+			})
+
+			It("returns the error", func() {
+				err := netOut.Check("some-container-handle", "5.6.7.8")
+				Expect(err).To(MatchError("getting chain name: banana"))
+			})
+		})
+	})
+
 	Describe("InsertRule", func() {
 		var (
 			netOutRule     garden.NetOutRule
@@ -510,6 +663,61 @@ var _ = Describe("Netout", func() {
 				Expect(logging).To(Equal(true))
 			})
 		})
+
+		Context("when the rule has networks of both IP families", func() {
+			BeforeEach(func() {
+				netOutRule.Networks = append(netOutRule.Networks, garden.IPRange{
+					Start: net.ParseIP("2001:db8::"), End: net.ParseIP("2001:db8::ffff"),
+				})
+			})
+
+			It("only converts the IPv4 networks", func() {
+				err := netOut.InsertRule("some-container-handle", netOutRule)
+				Expect(err).NotTo(HaveOccurred())
+
+				rule, _, _ := converter.ConvertArgsForCall(0)
+				Expect(rule.Networks).To(Equal([]garden.IPRange{
+					{Start: net.ParseIP("1.1.1.1"), End: net.ParseIP("2.2.2.2")},
+					{Start: net.ParseIP("3.3.3.3"), End: net.ParseIP("4.4.4.4")},
+				}))
+			})
+
+			Context("when writing the IPv6 tables", func() {
+				BeforeEach(func() {
+					netOut.IPv6 = true
+					converter.ConvertReturns([]rules.IPTablesRule{
+						{"-p", "icmp", "-m", "icmp", "--icmp-type", "128/0", "--jump", "ACCEPT"},
+					})
+				})
+
+				It("only converts the IPv6 networks, with icmp6 matches", func() {
+					err := netOut.InsertRule("some-container-handle", netOutRule)
+					Expect(err).NotTo(HaveOccurred())
+
+					rule, _, _ := converter.ConvertArgsForCall(0)
+					Expect(rule.Networks).To(Equal([]garden.IPRange{
+						{Start: net.ParseIP("2001:db8::"), End: net.ParseIP("2001:db8::ffff")},
+					}))
+
+					_, _, _, rulespec := ipTables.BulkInsertArgsForCall(0)
+					Expect(rulespec).To(Equal([]rules.IPTablesRule{
+						{"-p", "icmpv6", "-m", "icmp6", "--icmpv6-type", "128/0", "--jump", "ACCEPT"},
+					}))
+				})
+			})
+		})
+
+		Context("when writing the IPv6 tables and the rule only has IPv4 networks", func() {
+			BeforeEach(func() {
+				netOut.IPv6 = true
+			})
+
+			It("does not convert the rule", func() {
+				err := netOut.InsertRule("some-container-handle", netOutRule)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(converter.ConvertCallCount()).To(Equal(0))
+			})
+		})
 	})
 
 	Describe("BulkInsertRules", func() {
@@ -593,4 +801,87 @@ var _ = Describe("Netout", func() {
 			})
 		})
 	})
+
+	Describe("BulkReplaceRules", func() {
+		var (
+			netOutRules  []garden.NetOutRule
+			genericRules []rules.IPTablesRule
+		)
+
+		BeforeEach(func() {
+			netOutRules = []garden.NetOutRule{{Protocol: garden.ProtocolTCP}}
+			genericRules = []rules.IPTablesRule{
+				rules.IPTablesRule{"rule1"},
+				rules.IPTablesRule{"rule2"},
+			}
+			converter.BulkConvertReturns(genericRules)
+		})
+
+		It("replaces the rules of the netout chain, keeping the egress policy jumps", func() {
+			err := netOut.BulkReplaceRules("some-container-handle", netOutRules)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(converter.BulkConvertCallCount()).To(Equal(1))
+			convertedRules, logChainName, logging := converter.BulkConvertArgsForCall(0)
+			Expect(convertedRules).To(Equal(netOutRules))
+			Expect(logChainName).To(Equal("some-other-chain-name"))
+			Expect(logging).To(BeFalse())
+
+			Expect(ipTables.BulkReplaceCallCount()).To(Equal(1))
+			table, chain, keep, rulespec := ipTables.BulkReplaceArgsForCall(0)
+			Expect(table).To(Equal("filter"))
+			Expect(chain).To(Equal("netout-some-container-handle"))
+			Expect(rulespec).To(Equal([]rules.IPTablesRule{
+				{"rule1"},
+				{"rule2"},
+				rules.NewNetOutRelatedEstablishedRule(),
+				rules.NewNetOutDefaultRejectRule(),
+			}))
+
+			Expect(keep("-A netout-some-container-handle -j egress--01234567891500000000")).To(BeTrue())
+			Expect(keep("-A netout-some-container-handle rule0")).To(BeFalse())
+			Expect(keep("-A netout-some-container-handle -j netout-some-container-handle--log")).To(BeFalse())
+		})
+
+		Context("when the global logging is enabled", func() {
+			BeforeEach(func() {
+				netOut.ASGLogging = true
+			})
+
+			It("converts the rules with logging and logs denied packets", func() {
+				err := netOut.BulkReplaceRules("some-container-handle", netOutRules)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, _, logging := converter.BulkConvertArgsForCall(0)
+				Expect(logging).To(BeTrue())
+
+				_, _, _, rulespec := ipTables.BulkReplaceArgsForCall(0)
+				Expect(rulespec[len(rulespec)-3:]).To(Equal([]rules.IPTablesRule{
+					rules.NewNetOutRelatedEstablishedRule(),
+					rules.NewNetOutDefaultRejectLogRule("some-container-handle", 3),
+					rules.NewNetOutDefaultRejectRule(),
+				}))
+			})
+		})
+
+		Context("when the chain namer fails", func() {
+			BeforeEach(func() {
+				chainNamer.PostfixReturns("", errors.New("banana"))
+			})
+			It("returns the error", func() {
+				err := netOut.BulkReplaceRules("some-container-handle", netOutRules)
+				Expect(err).To(MatchError("getting chain name: banana"))
+			})
+		})
+
+		Context("when bulk replace fails", func() {
+			BeforeEach(func() {
+				ipTables.BulkReplaceReturns(errors.New("potato"))
+			})
+			It("returns an error", func() {
+				err := netOut.BulkReplaceRules("some-container-handle", netOutRules)
+				Expect(err).To(MatchError("replacing net-out rules: potato"))
+			})
+		})
+	})
 })
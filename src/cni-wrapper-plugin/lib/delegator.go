@@ -1,6 +1,9 @@
 package lib
 
 import (
+	"os"
+	"path/filepath"
+
 	"github.com/containernetworking/cni/pkg/invoke"
 	"github.com/containernetworking/cni/pkg/types"
 )
@@ -9,6 +12,7 @@ import (
 type Delegator interface {
 	DelegateAdd(delegatePlugin string, netconf []byte) (types.Result, error)
 	DelegateDel(delegatePlugin string, netconf []byte) error
+	DelegateCheck(delegatePlugin string, netconf []byte) error
 }
 
 type delegator struct{}
@@ -21,4 +25,15 @@ func (*delegator) DelegateDel(delegatePlugin string, netconf []byte) error {
 	return invoke.DelegateDel(delegatePlugin, netconf)
 }
 
+// DelegateCheck passes the CHECK command in the plugin's environment on to the
+// delegate, the same way invoke.DelegateDel passes on DEL.
+func (*delegator) DelegateCheck(delegatePlugin string, netconf []byte) error {
+	pluginPath, err := invoke.FindInPath(delegatePlugin, filepath.SplitList(os.Getenv("CNI_PATH")))
+	if err != nil {
+		return err
+	}
+
+	return invoke.ExecPluginWithoutResult(pluginPath, netconf, invoke.ArgsFromEnv())
+}
+
 func NewDelegator() Delegator { return &delegator{} }
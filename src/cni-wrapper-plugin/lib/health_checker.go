@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+//go:generate counterfeiter -o ../fakes/http_client.go --fake-name HTTPClient . httpClient
+type httpClient interface {
+	Get(url string) (*http.Response, error)
+}
+
+//go:generate counterfeiter -o ../fakes/clock.go --fake-name Clock . clock
+type clock interface {
+	Now() time.Time
+	Sleep(time.Duration)
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// HealthCheckError is the reason the last attempt of a health check failed.
+type HealthCheckError struct {
+	Reason   string
+	Attempts int
+}
+
+func (e *HealthCheckError) Error() string {
+	return fmt.Sprintf("%s after %d attempts", e.Reason, e.Attempts)
+}
+
+// HealthChecker calls a health check URL until it returns 200, doubling
+// Backoff between attempts, and gives up once another attempt would start
+// after Deadline. The Client sets the timeout of each attempt.
+type HealthChecker struct {
+	Client   httpClient
+	Clock    clock
+	Backoff  time.Duration
+	Deadline time.Duration
+}
+
+func (h *HealthChecker) Check(url string) error {
+	deadline := h.Clock.Now().Add(h.Deadline)
+	backoff := h.Backoff
+	for attempt := 1; ; attempt++ {
+		reason := h.check(url)
+		if reason == "" {
+			return nil
+		}
+		if h.Clock.Now().Add(backoff).After(deadline) {
+			return &HealthCheckError{Reason: reason, Attempts: attempt}
+		}
+		h.Clock.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (h *HealthChecker) check(url string) string {
+	resp, err := h.Client.Get(url)
+	if err != nil {
+		return fmt.Sprintf("could not call health check: %s", err)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("health check failed with %d", resp.StatusCode)
+	}
+	return ""
+}
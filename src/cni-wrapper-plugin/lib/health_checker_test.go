@@ -0,0 +1,106 @@
+package lib_test
+
+import (
+	"bytes"
+	"cni-wrapper-plugin/fakes"
+	"cni-wrapper-plugin/lib"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HealthChecker", func() {
+	var (
+		client        *fakes.HTTPClient
+		clock         *fakes.Clock
+		now           time.Time
+		healthChecker *lib.HealthChecker
+	)
+
+	response := func(statusCode int) *http.Response {
+		return &http.Response{
+			StatusCode: statusCode,
+			Body:       ioutil.NopCloser(&bytes.Buffer{}),
+		}
+	}
+
+	BeforeEach(func() {
+		client = &fakes.HTTPClient{}
+		client.GetReturns(response(http.StatusOK), nil)
+
+		now = time.Unix(1000, 0)
+		clock = &fakes.Clock{}
+		clock.NowStub = func() time.Time {
+			return now
+		}
+		clock.SleepStub = func(d time.Duration) {
+			now = now.Add(d)
+		}
+
+		healthChecker = &lib.HealthChecker{
+			Client:   client,
+			Clock:    clock,
+			Backoff:  100 * time.Millisecond,
+			Deadline: time.Second,
+		}
+	})
+
+	It("calls the health check url", func() {
+		Expect(healthChecker.Check("http://some-url")).To(Succeed())
+
+		Expect(client.GetCallCount()).To(Equal(1))
+		Expect(client.GetArgsForCall(0)).To(Equal("http://some-url"))
+		Expect(clock.SleepCallCount()).To(Equal(0))
+	})
+
+	Context("when the health check recovers", func() {
+		BeforeEach(func() {
+			client.GetReturnsOnCall(0, nil, errors.New("connection refused"))
+			client.GetReturnsOnCall(1, response(http.StatusServiceUnavailable), nil)
+			client.GetReturnsOnCall(2, response(http.StatusOK), nil)
+		})
+
+		It("retries with a doubling backoff", func() {
+			Expect(healthChecker.Check("http://some-url")).To(Succeed())
+
+			Expect(client.GetCallCount()).To(Equal(3))
+			Expect(clock.SleepCallCount()).To(Equal(2))
+			Expect(clock.SleepArgsForCall(0)).To(Equal(100 * time.Millisecond))
+			Expect(clock.SleepArgsForCall(1)).To(Equal(200 * time.Millisecond))
+		})
+	})
+
+	Context("when the health check keeps failing", func() {
+		BeforeEach(func() {
+			client.GetReturns(response(http.StatusServiceUnavailable), nil)
+		})
+
+		It("gives up at the deadline with the reason of the last attempt", func() {
+			err := healthChecker.Check("http://some-url")
+			Expect(err).To(MatchError("health check failed with 503 after 4 attempts"))
+
+			healthCheckErr, ok := err.(*lib.HealthCheckError)
+			Expect(ok).To(BeTrue())
+			Expect(healthCheckErr.Reason).To(Equal("health check failed with 503"))
+			Expect(healthCheckErr.Attempts).To(Equal(4))
+			Expect(now).To(Equal(time.Unix(1000, 0).Add(700 * time.Millisecond)))
+		})
+	})
+
+	Context("when the health check cannot be called", func() {
+		BeforeEach(func() {
+			client.GetReturns(nil, errors.New("connection refused"))
+			healthChecker.Deadline = 0
+		})
+
+		It("returns the error", func() {
+			err := healthChecker.Check("http://some-url")
+			Expect(err).To(MatchError("could not call health check: connection refused after 1 attempts"))
+			Expect(clock.SleepCallCount()).To(Equal(0))
+		})
+	})
+})
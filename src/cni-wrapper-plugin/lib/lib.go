@@ -3,7 +3,12 @@ package lib
 import (
 	"encoding/json"
 	"fmt"
+	"lib/datastore"
+	"lib/filelock"
 	"lib/rules"
+	"strconv"
+	"strings"
+	"time"
 
 	"code.cloudfoundry.org/garden"
 
@@ -12,15 +17,27 @@ import (
 )
 
 type RuntimeConfig struct {
-	PortMappings []garden.NetIn      `json:"portMappings"`
+	PortMappings []NetIn             `json:"portMappings"`
 	NetOutRules  []garden.NetOutRule `json:"netOutRules"`
 }
 
+const (
+	defaultHealthCheckTimeoutMs  = 1000
+	defaultHealthCheckBackoffMs  = 100
+	defaultHealthCheckDeadlineMs = 5000
+)
+
 type WrapperConfig struct {
 	Datastore                string                 `json:"datastore"`
+	DatastoreBackend         string                 `json:"datastore_backend"`
+	DatastoreNotifySocket    string                 `json:"datastore_notify_socket"`
 	IPTablesLockFile         string                 `json:"iptables_lock_file"`
+	IPTablesLockTimeoutMs    int                    `json:"iptables_lock_timeout_ms"`
 	Delegate                 map[string]interface{} `json:"delegate"`
 	HealthCheckURL           string                 `json:"health_check_url"`
+	HealthCheckTimeoutMs     int                    `json:"health_check_timeout_ms"`
+	HealthCheckBackoffMs     int                    `json:"health_check_backoff_ms"`
+	HealthCheckDeadlineMs    int                    `json:"health_check_deadline_ms"`
 	InstanceAddress          string                 `json:"instance_address"`
 	DNSServers               []string               `json:"dns_servers"`
 	IPTablesASGLogging       bool                   `json:"iptables_asg_logging"`
@@ -41,14 +58,44 @@ func LoadWrapperConfig(bytes []byte) (*WrapperConfig, error) {
 		return nil, fmt.Errorf("missing datastore path")
 	}
 
+	if !datastore.ValidBackend(n.DatastoreBackend) {
+		return nil, fmt.Errorf("invalid datastore backend: %s", n.DatastoreBackend)
+	}
+
 	if n.IPTablesLockFile == "" {
 		return nil, fmt.Errorf("missing iptables lock file path")
 	}
 
+	// without a timeout the iptables lock is waited for forever
+	if n.IPTablesLockTimeoutMs < 0 {
+		return nil, fmt.Errorf("invalid iptables lock timeout")
+	}
+
 	if n.HealthCheckURL == "" {
 		return nil, fmt.Errorf("missing health check url")
 	}
 
+	if n.HealthCheckTimeoutMs < 0 {
+		return nil, fmt.Errorf("invalid health check timeout")
+	}
+	if n.HealthCheckTimeoutMs == 0 {
+		n.HealthCheckTimeoutMs = defaultHealthCheckTimeoutMs
+	}
+
+	if n.HealthCheckBackoffMs < 0 {
+		return nil, fmt.Errorf("invalid health check backoff")
+	}
+	if n.HealthCheckBackoffMs == 0 {
+		n.HealthCheckBackoffMs = defaultHealthCheckBackoffMs
+	}
+
+	if n.HealthCheckDeadlineMs < 0 {
+		return nil, fmt.Errorf("invalid health check deadline")
+	}
+	if n.HealthCheckDeadlineMs == 0 {
+		n.HealthCheckDeadlineMs = defaultHealthCheckDeadlineMs
+	}
+
 	if n.InstanceAddress == "" {
 		return nil, fmt.Errorf("missing instance address")
 	}
@@ -72,9 +119,32 @@ func LoadWrapperConfig(bytes []byte) (*WrapperConfig, error) {
 	return n, nil
 }
 
+// IPTablesFileLocker locks the iptables lock file shared with the
+// vxlan-policy-agent, recording this process as its holder, so that a process
+// timing out on the lock can tell what is holding it up.
+func (n *WrapperConfig) IPTablesFileLocker() filelock.FileLocker {
+	return filelock.NewLockerWithOptions(n.IPTablesLockFile, filelock.Options{
+		Timeout:      time.Duration(n.IPTablesLockTimeoutMs) * time.Millisecond,
+		RecordHolder: true,
+	})
+}
+
 type PluginController struct {
 	Delegator Delegator
 	IPTables  rules.IPTablesAdapter
+	// IP6Tables is nil on cells without ip6tables
+	IP6Tables rules.IPTablesAdapter
+}
+
+// IPTablesFor returns the tables of the IP family of ip.
+func (c *PluginController) IPTablesFor(ip string) (rules.IPTablesAdapter, error) {
+	if !rules.IsIPv6(ip) {
+		return c.IPTables, nil
+	}
+	if c.IP6Tables == nil {
+		return nil, fmt.Errorf("ip6tables unavailable for %s", ip)
+	}
+	return c.IP6Tables, nil
 }
 
 func getDelegateParams(netconf map[string]interface{}) (string, []byte, error) {
@@ -109,10 +179,47 @@ func (c *PluginController) DelegateDel(netconf map[string]interface{}) error {
 	return c.Delegator.DelegateDel(delegateType, netconfBytes)
 }
 
+// DelegateCheck runs CHECK on the delegate. Delegates configured with a
+// cniVersion before 0.4.0 do not support CHECK and are not checked.
+func (c *PluginController) DelegateCheck(netconf map[string]interface{}) error {
+	delegateType, netconfBytes, err := getDelegateParams(netconf)
+	if err != nil {
+		return err
+	}
+
+	cniVersion, _ := netconf["cniVersion"].(string)
+	if !supportsCheck(cniVersion) {
+		return nil
+	}
+
+	return c.Delegator.DelegateCheck(delegateType, netconfBytes)
+}
+
+func supportsCheck(cniVersion string) bool {
+	parts := strings.Split(cniVersion, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return major > 0 || minor >= 4
+}
+
 func (c *PluginController) AddIPMasq(ip, deviceName string) error {
 	rule := rules.NewDefaultEgressRule(ip, deviceName)
 
-	if err := c.IPTables.BulkAppend("nat", "POSTROUTING", rule); err != nil {
+	ipt, err := c.IPTablesFor(ip)
+	if err != nil {
+		return err
+	}
+
+	if err := ipt.BulkAppend("nat", "POSTROUTING", rule); err != nil {
 		return err
 	}
 
@@ -122,9 +229,33 @@ func (c *PluginController) AddIPMasq(ip, deviceName string) error {
 func (c *PluginController) DelIPMasq(ip, deviceName string) error {
 	rule := rules.NewDefaultEgressRule(ip, deviceName)
 
-	if err := c.IPTables.Delete("nat", "POSTROUTING", rule); err != nil {
+	ipt, err := c.IPTablesFor(ip)
+	if err != nil {
+		return err
+	}
+
+	if err := ipt.Delete("nat", "POSTROUTING", rule); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (c *PluginController) CheckIPMasq(ip, deviceName string) error {
+	rule := rules.NewDefaultEgressRule(ip, deviceName)
+
+	ipt, err := c.IPTablesFor(ip)
+	if err != nil {
+		return err
+	}
+
+	exists, err := ipt.Exists("nat", "POSTROUTING", rule)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("missing ip masq rule for %s", ip)
+	}
+
+	return nil
+}
@@ -4,8 +4,16 @@ import (
 	"cni-wrapper-plugin/fakes"
 	"cni-wrapper-plugin/lib"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	libfakes "lib/fakes"
+	"lib/filelock"
+	"lib/rules"
 	"net"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/cni/pkg/types/020"
@@ -45,12 +53,34 @@ var _ = Describe("LoadWrapperConfig", func() {
 				"some":       "info",
 			},
 			HealthCheckURL:           "http://127.0.0.1:10007",
+			HealthCheckTimeoutMs:     1000,
+			HealthCheckBackoffMs:     100,
+			HealthCheckDeadlineMs:    5000,
 			IngressTag:               "ffaa0000",
 			VTEPName:                 "some-device",
 			IPTablesDeniedLogsPerSec: 2,
 		}))
 	})
 
+	Context("when the health check settings are set", func() {
+		BeforeEach(func() {
+			var inputData map[string]interface{}
+			Expect(json.Unmarshal(input, &inputData)).To(Succeed())
+			inputData["health_check_timeout_ms"] = 200
+			inputData["health_check_backoff_ms"] = 50
+			inputData["health_check_deadline_ms"] = 10000
+			input, _ = json.Marshal(inputData)
+		})
+
+		It("uses them instead of the defaults", func() {
+			conf, err := lib.LoadWrapperConfig(input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conf.HealthCheckTimeoutMs).To(Equal(200))
+			Expect(conf.HealthCheckBackoffMs).To(Equal(50))
+			Expect(conf.HealthCheckDeadlineMs).To(Equal(10000))
+		})
+	})
+
 	Context("When the stdin is not a valid json", func() {
 		BeforeEach(func() {
 			input = []byte("}{")
@@ -127,9 +157,56 @@ var _ = Describe("LoadWrapperConfig", func() {
 		Expect(err).To(MatchError(errMessage))
 	},
 		Entry("denied logs per sec", "iptables_denied_logs_per_sec", -1, "invalid denied logs per sec"),
+		Entry("datastore backend", "datastore_backend", "banana", "invalid datastore backend: banana"),
+		Entry("iptables lock timeout", "iptables_lock_timeout_ms", -1, "invalid iptables lock timeout"),
+		Entry("health check timeout", "health_check_timeout_ms", -1, "invalid health check timeout"),
+		Entry("health check backoff", "health_check_backoff_ms", -1, "invalid health check backoff"),
+		Entry("health check deadline", "health_check_deadline_ms", -1, "invalid health check deadline"),
 	)
 })
 
+var _ = Describe("IPTablesFileLocker", func() {
+	var (
+		dir  string
+		conf *lib.WrapperConfig
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		conf = &lib.WrapperConfig{
+			IPTablesLockFile:      filepath.Join(dir, "iptables.lock"),
+			IPTablesLockTimeoutMs: 50,
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("records this process as the holder of the lock", func() {
+		file, err := conf.IPTablesFileLocker().Open()
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		holder, err := filelock.ReadHolder(filelock.HolderFileFor(conf.IPTablesLockFile))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(holder.PID).To(Equal(os.Getpid()))
+	})
+
+	It("gives up waiting for the lock after the timeout", func() {
+		file, err := conf.IPTablesFileLocker().Open()
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		_, err = conf.IPTablesFileLocker().Open()
+		Expect(err).To(BeAssignableToTypeOf(&filelock.TimeoutError{}))
+		Expect(err.(*filelock.TimeoutError).Timeout).To(Equal(50 * time.Millisecond))
+	})
+})
+
 var _ = Describe("DelegateAdd", func() {
 	var (
 		input            map[string]interface{}
@@ -268,3 +345,165 @@ var _ = Describe("DelegateDel", func() {
 		})
 	})
 })
+
+var _ = Describe("DelegateCheck", func() {
+	var (
+		input            map[string]interface{}
+		pluginController *lib.PluginController
+		fakeDelegator    *fakes.Delegator
+	)
+
+	BeforeEach(func() {
+		fakeDelegator = &fakes.Delegator{}
+		pluginController = &lib.PluginController{
+			Delegator: fakeDelegator,
+		}
+
+		input = map[string]interface{}{
+			"type":       "something",
+			"cniVersion": "0.4.0",
+		}
+	})
+
+	It("should call the plugin specified by the type", func() {
+		err := pluginController.DelegateCheck(input)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(fakeDelegator.DelegateCheckCallCount()).To(Equal(1))
+		delegatePlugin, netconf := fakeDelegator.DelegateCheckArgsForCall(0)
+		Expect(delegatePlugin).To(Equal("something"))
+		Expect(netconf).To(MatchJSON(`{"type": "something", "cniVersion": "0.4.0"}`))
+	})
+
+	DescribeTable("delegates that do not support CHECK", func(cniVersion string) {
+		input["cniVersion"] = cniVersion
+		err := pluginController.DelegateCheck(input)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeDelegator.DelegateCheckCallCount()).To(Equal(0))
+	},
+		Entry("0.3.1", "0.3.1"),
+		Entry("0.2.0", "0.2.0"),
+		Entry("missing", ""),
+	)
+
+	Context("when the delegator returns an error", func() {
+		BeforeEach(func() {
+			fakeDelegator.DelegateCheckReturns(fmt.Errorf("patato"))
+		})
+
+		It("should return the error", func() {
+			err := pluginController.DelegateCheck(input)
+			Expect(err).To(MatchError("patato"))
+		})
+	})
+
+	Context("when the input type is missing", func() {
+		BeforeEach(func() {
+			delete(input, "type")
+		})
+
+		It("should return a useful error", func() {
+			err := pluginController.DelegateCheck(input)
+			Expect(err).To(MatchError("delegate config is missing type"))
+		})
+	})
+})
+
+var _ = Describe("CheckIPMasq", func() {
+	var (
+		pluginController *lib.PluginController
+		ipTables         *libfakes.IPTablesAdapter
+	)
+
+	BeforeEach(func() {
+		ipTables = &libfakes.IPTablesAdapter{}
+		ipTables.ExistsReturns(true, nil)
+		pluginController = &lib.PluginController{
+			IPTables: ipTables,
+		}
+	})
+
+	It("checks for the masquerade rule of the container", func() {
+		err := pluginController.CheckIPMasq("1.2.3.4", "some-device")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ipTables.ExistsCallCount()).To(Equal(1))
+		table, chain, rulespec := ipTables.ExistsArgsForCall(0)
+		Expect(table).To(Equal("nat"))
+		Expect(chain).To(Equal("POSTROUTING"))
+		Expect(rulespec).To(Equal(rules.NewDefaultEgressRule("1.2.3.4", "some-device")))
+	})
+
+	Context("when the rule is missing", func() {
+		BeforeEach(func() {
+			ipTables.ExistsReturns(false, nil)
+		})
+
+		It("reports it", func() {
+			err := pluginController.CheckIPMasq("1.2.3.4", "some-device")
+			Expect(err).To(MatchError("missing ip masq rule for 1.2.3.4"))
+		})
+	})
+
+	Context("when checking the rule fails", func() {
+		BeforeEach(func() {
+			ipTables.ExistsReturns(false, errors.New("banana"))
+		})
+
+		It("returns the error", func() {
+			err := pluginController.CheckIPMasq("1.2.3.4", "some-device")
+			Expect(err).To(MatchError("banana"))
+		})
+	})
+})
+
+var _ = Describe("IP masquerade rules", func() {
+	var (
+		pluginController *lib.PluginController
+		ipTables         *libfakes.IPTablesAdapter
+		ip6Tables        *libfakes.IPTablesAdapter
+	)
+
+	BeforeEach(func() {
+		ipTables = &libfakes.IPTablesAdapter{}
+		ip6Tables = &libfakes.IPTablesAdapter{}
+		pluginController = &lib.PluginController{
+			IPTables:  ipTables,
+			IP6Tables: ip6Tables,
+		}
+	})
+
+	It("writes the rules of IPv4 addresses to iptables", func() {
+		Expect(pluginController.AddIPMasq("1.2.3.4", "some-device")).To(Succeed())
+		Expect(pluginController.DelIPMasq("1.2.3.4", "some-device")).To(Succeed())
+
+		Expect(ipTables.BulkAppendCallCount()).To(Equal(1))
+		Expect(ipTables.DeleteCallCount()).To(Equal(1))
+		Expect(ip6Tables.BulkAppendCallCount()).To(Equal(0))
+		Expect(ip6Tables.DeleteCallCount()).To(Equal(0))
+	})
+
+	It("writes the rules of IPv6 addresses to ip6tables", func() {
+		Expect(pluginController.AddIPMasq("fd00::2", "some-device")).To(Succeed())
+		Expect(pluginController.DelIPMasq("fd00::2", "some-device")).To(Succeed())
+
+		Expect(ip6Tables.BulkAppendCallCount()).To(Equal(1))
+		table, chain, rulespec := ip6Tables.BulkAppendArgsForCall(0)
+		Expect(table).To(Equal("nat"))
+		Expect(chain).To(Equal("POSTROUTING"))
+		Expect(rulespec).To(Equal([]rules.IPTablesRule{rules.NewDefaultEgressRule("fd00::2", "some-device")}))
+		Expect(ip6Tables.DeleteCallCount()).To(Equal(1))
+		Expect(ipTables.BulkAppendCallCount()).To(Equal(0))
+	})
+
+	Context("when ip6tables is unavailable", func() {
+		BeforeEach(func() {
+			pluginController.IP6Tables = nil
+		})
+
+		It("returns an error for IPv6 addresses", func() {
+			err := pluginController.AddIPMasq("fd00::2", "some-device")
+			Expect(err).To(MatchError("ip6tables unavailable for fd00::2"))
+		})
+	})
+})
@@ -0,0 +1,66 @@
+package lib
+
+import "fmt"
+
+const (
+	ProtocolTCP    = "tcp"
+	ProtocolUDP    = "udp"
+	ProtocolTCPUDP = "tcp+udp"
+)
+
+const maxPort = 65535
+
+// NetIn is a garden.NetIn that can also forward UDP, and forward a range of
+// host ports to the same container port.
+type NetIn struct {
+	HostPort      uint32 `json:"host_port"`
+	ContainerPort uint32 `json:"container_port"`
+	// HostPortCount is how many consecutive host ports from HostPort are
+	// forwarded. Zero forwards HostPort only.
+	HostPortCount uint32 `json:"host_port_count,omitempty"`
+	// Protocol is tcp, udp or tcp+udp. Empty is tcp.
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// Count is the number of host ports forwarded.
+func (n NetIn) Count() int {
+	if n.HostPortCount == 0 {
+		return 1
+	}
+	return int(n.HostPortCount)
+}
+
+// HostPortEnd is the last host port forwarded.
+func (n NetIn) HostPortEnd() int {
+	return int(n.HostPort) + n.Count() - 1
+}
+
+// Protocols are the protocols forwarded, each of which needs its own rules.
+func (n NetIn) Protocols() []string {
+	switch n.Protocol {
+	case ProtocolUDP:
+		return []string{ProtocolUDP}
+	case ProtocolTCPUDP:
+		return []string{ProtocolTCP, ProtocolUDP}
+	default:
+		return []string{ProtocolTCP}
+	}
+}
+
+// Validate checks the protocol and ports. A HostPort of zero is valid, since
+// the port allocator fills it in.
+func (n NetIn) Validate() error {
+	switch n.Protocol {
+	case "", ProtocolTCP, ProtocolUDP, ProtocolTCPUDP:
+	default:
+		return fmt.Errorf("invalid protocol: %s", n.Protocol)
+	}
+
+	if n.ContainerPort > maxPort {
+		return fmt.Errorf("invalid container port: %d", n.ContainerPort)
+	}
+	if n.HostPortCount > maxPort || n.HostPortEnd() > maxPort {
+		return fmt.Errorf("invalid host ports: %d-%d", n.HostPort, n.HostPortEnd())
+	}
+	return nil
+}
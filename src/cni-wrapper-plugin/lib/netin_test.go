@@ -0,0 +1,46 @@
+package lib_test
+
+import (
+	"cni-wrapper-plugin/lib"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NetIn", func() {
+	It("forwards a single tcp host port by default", func() {
+		netIn := lib.NetIn{HostPort: 1000, ContainerPort: 8080}
+		Expect(netIn.Validate()).To(Succeed())
+		Expect(netIn.Count()).To(Equal(1))
+		Expect(netIn.HostPortEnd()).To(Equal(1000))
+		Expect(netIn.Protocols()).To(Equal([]string{"tcp"}))
+	})
+
+	It("forwards a range of host ports", func() {
+		netIn := lib.NetIn{HostPort: 1000, ContainerPort: 8080, HostPortCount: 10}
+		Expect(netIn.Validate()).To(Succeed())
+		Expect(netIn.Count()).To(Equal(10))
+		Expect(netIn.HostPortEnd()).To(Equal(1009))
+	})
+
+	DescribeTable("protocols",
+		func(protocol string, expected []string) {
+			netIn := lib.NetIn{HostPort: 1000, ContainerPort: 8080, Protocol: protocol}
+			Expect(netIn.Validate()).To(Succeed())
+			Expect(netIn.Protocols()).To(Equal(expected))
+		},
+		Entry("tcp", "tcp", []string{"tcp"}),
+		Entry("udp", "udp", []string{"udp"}),
+		Entry("tcp and udp", "tcp+udp", []string{"tcp", "udp"}),
+	)
+
+	DescribeTable("invalid mappings",
+		func(netIn lib.NetIn, errorMsg string) {
+			Expect(netIn.Validate()).To(MatchError(errorMsg))
+		},
+		Entry("unknown protocol", lib.NetIn{HostPort: 1000, Protocol: "icmp"}, "invalid protocol: icmp"),
+		Entry("container port out of range", lib.NetIn{HostPort: 1000, ContainerPort: 70000}, "invalid container port: 70000"),
+		Entry("host ports out of range", lib.NetIn{HostPort: 65530, HostPortCount: 10}, "invalid host ports: 65530-65539"),
+	)
+})
@@ -0,0 +1,37 @@
+package lib
+
+import (
+	"fmt"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+type undoStep struct {
+	name string
+	undo func() error
+}
+
+// Rollback records how to undo each completed step of a container setup, so
+// that a failed ADD leaves nothing behind.
+type Rollback struct {
+	steps []undoStep
+}
+
+// Add records the undo of a step once the step has succeeded.
+func (r *Rollback) Add(name string, undo func() error) {
+	r.steps = append(r.steps, undoStep{name: name, undo: undo})
+}
+
+// Run undoes the recorded steps, most recent first, carrying on past errors
+// so that as much as possible is undone.
+func (r *Rollback) Run() error {
+	var result error
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+		if err := step.undo(); err != nil {
+			result = multierror.Append(result, fmt.Errorf("undo %s: %s", step.name, err))
+		}
+	}
+	r.steps = nil
+	return result
+}
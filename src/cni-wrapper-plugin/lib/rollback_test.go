@@ -0,0 +1,61 @@
+package lib_test
+
+import (
+	"cni-wrapper-plugin/lib"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rollback", func() {
+	var (
+		rollback *lib.Rollback
+		undone   []string
+	)
+
+	BeforeEach(func() {
+		rollback = &lib.Rollback{}
+		undone = []string{}
+	})
+
+	undo := func(name string, err error) func() error {
+		return func() error {
+			undone = append(undone, name)
+			return err
+		}
+	}
+
+	It("undoes the steps in reverse order", func() {
+		rollback.Add("first", undo("first", nil))
+		rollback.Add("second", undo("second", nil))
+		rollback.Add("third", undo("third", nil))
+
+		Expect(rollback.Run()).To(Succeed())
+		Expect(undone).To(Equal([]string{"third", "second", "first"}))
+	})
+
+	It("only undoes the steps once", func() {
+		rollback.Add("first", undo("first", nil))
+
+		Expect(rollback.Run()).To(Succeed())
+		Expect(rollback.Run()).To(Succeed())
+		Expect(undone).To(Equal([]string{"first"}))
+	})
+
+	Context("when undoing a step fails", func() {
+		BeforeEach(func() {
+			rollback.Add("first", undo("first", nil))
+			rollback.Add("second", undo("second", errors.New("banana")))
+			rollback.Add("third", undo("third", errors.New("kiwi")))
+		})
+
+		It("undoes the remaining steps and returns every error", func() {
+			err := rollback.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("undo third: kiwi"))
+			Expect(err.Error()).To(ContainSubstring("undo second: banana"))
+			Expect(undone).To(Equal([]string{"third", "second", "first"}))
+		})
+	})
+})
@@ -8,57 +8,117 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"lib/datastore"
-	"lib/filelock"
 	"lib/rules"
-	"lib/serial"
 	"net"
 	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/cni/pkg/types/current"
 	"github.com/containernetworking/cni/pkg/version"
 	"github.com/coreos/go-iptables/iptables"
+	multierror "github.com/hashicorp/go-multierror"
 )
 
-func cmdAdd(args *skel.CmdArgs) error {
+func cmdAdd(args *skel.CmdArgs) (err error) {
 	n, err := lib.LoadWrapperConfig(args.StdinData)
 	if err != nil {
 		return err
 	}
 
-	client := http.DefaultClient
-	resp, err := client.Get(n.HealthCheckURL)
-	if err != nil {
-		return fmt.Errorf("could not call health check: %s", err)
+	// Validate dns
+	var localDNSServers []string
+	for _, entry := range n.DNSServers {
+		dnsIP := net.ParseIP(entry)
+		if dnsIP == nil {
+			return fmt.Errorf(`invalid DNS server "%s", must be valid IP address`, entry)
+		} else if dnsIP.IsLinkLocalUnicast() {
+			localDNSServers = append(localDNSServers, entry)
+		}
 	}
-	if resp.StatusCode != http.StatusOK {
-		return errors.New(fmt.Sprintf("health check failed with %d", resp.StatusCode))
+
+	portMappings := n.RuntimeConfig.PortMappings
+	for _, netIn := range portMappings {
+		if netIn.HostPort <= 0 {
+			return fmt.Errorf("cannot allocate port %d", netIn.HostPort)
+		}
+		if err := netIn.Validate(); err != nil {
+			return fmt.Errorf("invalid port mapping: %s", err)
+		}
+	}
+
+	healthChecker := &lib.HealthChecker{
+		Client:   &http.Client{Timeout: time.Duration(n.HealthCheckTimeoutMs) * time.Millisecond},
+		Clock:    lib.SystemClock{},
+		Backoff:  time.Duration(n.HealthCheckBackoffMs) * time.Millisecond,
+		Deadline: time.Duration(n.HealthCheckDeadlineMs) * time.Millisecond,
+	}
+	if err := healthChecker.Check(n.HealthCheckURL); err != nil {
+		// garden shows the error message to users, so say what was unhealthy
+		return &types.Error{
+			Code:    100,
+			Msg:     err.Error(),
+			Details: fmt.Sprintf("%s did not become healthy within %dms", n.HealthCheckURL, n.HealthCheckDeadlineMs),
+		}
+	}
+
+	pluginController, err := newPluginController(n)
+	if err != nil {
+		return err
 	}
 
-	pluginController, err := newPluginController(n.IPTablesLockFile)
+	defaultIfaceName, err := defaultInterfaceName()
 	if err != nil {
 		return err
 	}
 
+	// Undo every completed step if a later one fails, so that garden can
+	// retry creating the container from a clean slate
+	rollback := &lib.Rollback{}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rollbackErr := rollback.Run(); rollbackErr != nil {
+			fmt.Fprintf(os.Stderr, "rolling back: %s", rollbackErr)
+		}
+	}()
+
 	result, err := pluginController.DelegateAdd(n.Delegate)
 	if err != nil {
 		return fmt.Errorf("delegate call: %s", err)
 	}
+	rollback.Add("delegate call", func() error {
+		return pluginController.DelegateDel(n.Delegate)
+	})
 
 	result030, err := current.NewResultFromResult(result)
 	if err != nil {
 		return fmt.Errorf("converting result from delegate plugin: %s", err) // not tested
 	}
 
-	containerIP := result030.IPs[0].Address.IP
+	var containerIPs []string
+	for _, ipConfig := range result030.IPs {
+		containerIPs = append(containerIPs, ipConfig.Address.IP.String())
+	}
+	if len(containerIPs) == 0 {
+		return errors.New("delegate call: no ip allocated")
+	}
+	containerIP := datastore.PrimaryIP(containerIPs)
+
+	if len(portMappings) > 0 && rules.IsIPv6(containerIP) {
+		return fmt.Errorf("cannot map port %d without an IPv4 address", portMappings[0].HostPort)
+	}
 
 	// Add container metadata info
-	store := &datastore.Store{
-		Serializer: &serial.Serial{},
-		Locker:     filelock.NewLocker(n.Datastore),
+	store, err := datastore.New(n.DatastoreBackend, n.Datastore, os.Stderr)
+	if err != nil {
+		return err // not tested, the backend is validated with the config
 	}
 
 	var cniAddData struct {
@@ -68,102 +128,103 @@ func cmdAdd(args *skel.CmdArgs) error {
 		panic(err) // not tested, this should be impossible
 	}
 
-	if err := store.Add(args.ContainerID, containerIP.String(), cniAddData.Metadata); err != nil {
-		storeErr := fmt.Errorf("store add: %s", err)
-		fmt.Fprintf(os.Stderr, "%s", storeErr)
-		fmt.Fprintf(os.Stderr, "cleaning up from error")
-		err = pluginController.DelIPMasq(containerIP.String(), n.VTEPName)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "during cleanup: removing IP masq: %s", err)
-		}
-
-		return storeErr
+	if err := store.Add(args.ContainerID, containerIPs, cniAddData.Metadata); err != nil {
+		return fmt.Errorf("store add: %s", err)
 	}
-
-	// Initialize dns
-	var localDNSServers []string
-	for _, entry := range n.DNSServers {
-		dnsIP := net.ParseIP(entry)
-		if dnsIP == nil {
-			return fmt.Errorf(`invalid DNS server "%s", must be valid IP address`, entry)
-		} else if dnsIP.IsLinkLocalUnicast() {
-			localDNSServers = append(localDNSServers, entry)
+	rollback.Add("store add", func() error {
+		_, err := store.Delete(args.ContainerID)
+		return err
+	})
+
+	// Initialize NetOut, once for each IP family of the container
+	var netOutProviders []*legacynet.NetOut
+	initializedFamilies := map[bool]bool{}
+	for _, ip := range containerIPs {
+		if initializedFamilies[rules.IsIPv6(ip)] {
+			continue
 		}
-	}
-
-	defaultInterface := discover.DefaultInterface{
-		NetlinkAdapter: &adapter.NetlinkAdapter{},
-		NetAdapter:     &adapter.NetAdapter{},
-	}
-	defaultIfaceName, err := defaultInterface.Name()
-	if err != nil {
-		return fmt.Errorf("discover default interface name: %s", err) // not tested
-	}
+		initializedFamilies[rules.IsIPv6(ip)] = true
 
-	// Initialize NetOut
-	netOutProvider := legacynet.NetOut{
-		ChainNamer: &legacynet.ChainNamer{
-			MaxLength: 28,
-		},
-		IPTables:          pluginController.IPTables,
-		Converter:         &legacynet.NetOutRuleConverter{Logger: os.Stderr},
-		ASGLogging:        n.IPTablesASGLogging,
-		C2CLogging:        n.IPTablesC2CLogging,
-		DeniedLogsPerSec:  n.IPTablesDeniedLogsPerSec,
-		IngressTag:        n.IngressTag,
-		VTEPName:          n.VTEPName,
-		HostInterfaceName: defaultIfaceName,
-	}
-	if err := netOutProvider.Initialize(args.ContainerID, containerIP, localDNSServers); err != nil {
-		return fmt.Errorf("initialize net out: %s", err)
+		ip := ip
+		netOutProvider, err := newNetOutProvider(n, pluginController, defaultIfaceName, ip)
+		if err != nil {
+			return fmt.Errorf("initialize net out: %s", err)
+		}
+		// a partial Initialize can leave chains behind, so always clean up
+		rollback.Add("initialize net out", func() error {
+			return netOutProvider.Cleanup(args.ContainerID, ip)
+		})
+		if err := netOutProvider.Initialize(args.ContainerID, net.ParseIP(ip), localDNSServers); err != nil {
+			return fmt.Errorf("initialize net out: %s", err)
+		}
+		netOutProviders = append(netOutProviders, netOutProvider)
 	}
 
 	// Initialize NetIn
-	netinProvider := legacynet.NetIn{
-		ChainNamer: &legacynet.ChainNamer{
-			MaxLength: 28,
-		},
-		IPTables:          pluginController.IPTables,
-		IngressTag:        n.IngressTag,
-		HostInterfaceName: defaultIfaceName,
+	netinProvider := newNetInProvider(n, pluginController, defaultIfaceName)
+	rollback.Add("initialize net in", func() error {
+		return netinProvider.Cleanup(args.ContainerID)
+	})
+	if err := netinProvider.Initialize(args.ContainerID); err != nil {
+		return fmt.Errorf("initialize net in: %s", err)
 	}
-	err = netinProvider.Initialize(args.ContainerID)
 
-	// Create port mappings
-	portMappings := n.RuntimeConfig.PortMappings
+	// Create port mappings, which forward from the IPv4 instance address.
+	// The rules are in the netin chains, so they are undone with them.
 	for _, netIn := range portMappings {
-		if netIn.HostPort <= 0 {
-			return fmt.Errorf("cannot allocate port %d", netIn.HostPort)
-		}
-		if err := netinProvider.AddRule(args.ContainerID, int(netIn.HostPort), int(netIn.ContainerPort), n.InstanceAddress, containerIP.String()); err != nil {
+		if err := netinProvider.AddRule(args.ContainerID, netIn, n.InstanceAddress, containerIP); err != nil {
 			return fmt.Errorf("adding netin rule: %s", err)
 		}
 	}
 
-	// Create egress rules
+	// Create egress rules, which are undone with the netout chains
 	netOutRules := n.RuntimeConfig.NetOutRules
-	if err := netOutProvider.BulkInsertRules(args.ContainerID, netOutRules); err != nil {
-		return fmt.Errorf("bulk insert: %s", err) // not tested
+	for _, netOutProvider := range netOutProviders {
+		if err := netOutProvider.BulkInsertRules(args.ContainerID, netOutRules); err != nil {
+			return fmt.Errorf("bulk insert: %s", err)
+		}
 	}
 
-	err = pluginController.AddIPMasq(containerIP.String(), n.VTEPName)
-	if err != nil {
-		return fmt.Errorf("error setting up default ip masq rule: %s", err)
+	for _, ip := range containerIPs {
+		ip := ip
+		if err := pluginController.AddIPMasq(ip, n.VTEPName); err != nil {
+			return fmt.Errorf("error setting up default ip masq rule: %s", err)
+		}
+		rollback.Add("ip masq", func() error {
+			return pluginController.DelIPMasq(ip, n.VTEPName)
+		})
 	}
 
+	// the policy agent plans for the container once everything it builds on
+	// is in place
+	notifyDatastoreChange(n, datastore.ActionAdd, args.ContainerID)
+
 	result030.DNS.Nameservers = n.DNSServers
 	return result030.Print()
 }
 
+// notifyDatastoreChange lets the policy agent update its rules without
+// waiting for its next poll.
+func notifyDatastoreChange(n *lib.WrapperConfig, action, handle string) {
+	if n.DatastoreNotifySocket == "" {
+		return
+	}
+
+	notifier := &datastore.Notifier{SocketPath: n.DatastoreNotifySocket}
+	if err := notifier.Notify(datastore.Event{Action: action, Handle: handle}); err != nil {
+		fmt.Fprintf(os.Stderr, "notify datastore change: %s\n", err)
+	}
+}
+
 func cmdDel(args *skel.CmdArgs) error {
 	n, err := lib.LoadWrapperConfig(args.StdinData)
 	if err != nil {
 		return err
 	}
 
-	store := &datastore.Store{
-		Serializer: &serial.Serial{},
-		Locker:     filelock.NewLocker(n.Datastore),
+	store, err := datastore.New(n.DatastoreBackend, n.Datastore, os.Stderr)
+	if err != nil {
+		return err // not tested, the backend is validated with the config
 	}
 
 	container, err := store.Delete(args.ContainerID)
@@ -171,7 +232,7 @@ func cmdDel(args *skel.CmdArgs) error {
 		fmt.Fprintf(os.Stderr, "store delete: %s", err)
 	}
 
-	pluginController, err := newPluginController(n.IPTablesLockFile)
+	pluginController, err := newPluginController(n)
 	if err != nil {
 		return err
 	}
@@ -180,56 +241,271 @@ func cmdDel(args *skel.CmdArgs) error {
 		fmt.Fprintf(os.Stderr, "delegate delete: %s", err)
 	}
 
-	netInProvider := legacynet.NetIn{
-		ChainNamer: &legacynet.ChainNamer{
-			MaxLength: 28,
-		},
-		IPTables:   pluginController.IPTables,
-		IngressTag: n.IngressTag,
+	defaultIfaceName, err := defaultInterfaceName()
+	if err != nil {
+		return err
 	}
 
-	if err = netInProvider.Cleanup(args.ContainerID); err != nil {
-		fmt.Fprintf(os.Stderr, "net in cleanup: %s", err)
+	if err = cleanupContainer(n, pluginController, defaultIfaceName, args.ContainerID, container.AllIPs()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err)
 	}
 
-	defaultInterface := discover.DefaultInterface{
-		NetlinkAdapter: &adapter.NetlinkAdapter{},
-		NetAdapter:     &adapter.NetAdapter{},
+	notifyDatastoreChange(n, datastore.ActionDelete, args.ContainerID)
+
+	return nil
+}
+
+// cmdCheck reports any drift of the container's state from what cmdAdd set
+// up: its datastore entry, chains, IP masquerade rule and delegate state.
+// skel only dispatches CHECK for configs of cniVersion 0.4.0 or later, which
+// carry the result of ADD as prevResult.
+func cmdCheck(args *skel.CmdArgs) error {
+	n, err := lib.LoadWrapperConfig(args.StdinData)
+	if err != nil {
+		return err
 	}
-	defaultIfaceName, err := defaultInterface.Name()
+
+	netConf := &types.NetConf{}
+	if err := json.Unmarshal(args.StdinData, netConf); err != nil {
+		return fmt.Errorf("loading prevResult: %s", err)
+	}
+	rawPrevResult := netConf.RawPrevResult
+	if err := version.ParsePrevResult(netConf); err != nil {
+		return fmt.Errorf("parsing prevResult: %s", err)
+	}
+	if netConf.PrevResult == nil {
+		return errors.New("missing prevResult")
+	}
+	prevResult, err := current.NewResultFromResult(netConf.PrevResult)
 	if err != nil {
-		return fmt.Errorf("discover default interface name: %s", err) // not tested
+		return fmt.Errorf("converting prevResult: %s", err)
+	}
+
+	store, err := datastore.New(n.DatastoreBackend, n.Datastore, os.Stderr)
+	if err != nil {
+		return err // not tested, the backend is validated with the config
+	}
+
+	containers, err := store.ReadAll()
+	if err != nil {
+		return fmt.Errorf("store read: %s", err)
+	}
+	container, ok := containers[args.ContainerID]
+	if !ok {
+		return fmt.Errorf("store: no entry for container %s", args.ContainerID)
 	}
 
-	netOutProvider := legacynet.NetOut{
+	pluginController, err := newPluginController(n)
+	if err != nil {
+		return err
+	}
+
+	defaultIfaceName, err := defaultInterfaceName()
+	if err != nil {
+		return err
+	}
+
+	var result error
+	storedIPs := make(map[string]struct{})
+	for _, ip := range container.AllIPs() {
+		storedIPs[ip] = struct{}{}
+	}
+	for _, ipConfig := range prevResult.IPs {
+		if _, ok := storedIPs[ipConfig.Address.IP.String()]; !ok {
+			result = multierror.Append(result, fmt.Errorf("store: ip %s of prevResult not found", ipConfig.Address.IP))
+		}
+	}
+
+	// the delegate checks against the same result that ADD returned
+	delegate := make(map[string]interface{}, len(n.Delegate)+1)
+	for key, value := range n.Delegate {
+		delegate[key] = value
+	}
+	delegate["prevResult"] = rawPrevResult
+	if err := pluginController.DelegateCheck(delegate); err != nil {
+		result = multierror.Append(result, fmt.Errorf("delegate check: %s", err))
+	}
+
+	netInProvider := newNetInProvider(n, pluginController, defaultIfaceName)
+	if err := netInProvider.Check(args.ContainerID); err != nil {
+		result = multierror.Append(result, fmt.Errorf("net in: %s", err))
+	}
+
+	for _, ip := range container.AllIPs() {
+		netOutProvider, err := newNetOutProvider(n, pluginController, defaultIfaceName, ip)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("net out: %s", err))
+			continue
+		}
+		if err := netOutProvider.Check(args.ContainerID, ip); err != nil {
+			result = multierror.Append(result, fmt.Errorf("net out: %s", err))
+		}
+
+		if err := pluginController.CheckIPMasq(ip, n.VTEPName); err != nil {
+			result = multierror.Append(result, fmt.Errorf("ip masq: %s", err))
+		}
+	}
+
+	if result != nil {
+		return fmt.Errorf("container %s has drifted: %s", args.ContainerID, result)
+	}
+	return nil
+}
+
+// cmdGC removes the datastore entries, chains and IP masquerade rules of
+// containers that are not in the runtime's list of valid attachments.
+func cmdGC(args *skel.CmdArgs) error {
+	n, err := lib.LoadWrapperConfig(args.StdinData)
+	if err != nil {
+		return err
+	}
+
+	var gcData struct {
+		ValidAttachments *[]struct {
+			ContainerID string `json:"containerID"`
+		} `json:"cni.dev/valid-attachments"`
+	}
+	if err := json.Unmarshal(args.StdinData, &gcData); err != nil {
+		return fmt.Errorf("loading valid attachments: %s", err)
+	}
+	if gcData.ValidAttachments == nil {
+		// without the list every container would look stale
+		return errors.New("missing cni.dev/valid-attachments")
+	}
+
+	validHandles := make(map[string]struct{})
+	for _, attachment := range *gcData.ValidAttachments {
+		validHandles[attachment.ContainerID] = struct{}{}
+	}
+
+	store, err := datastore.New(n.DatastoreBackend, n.Datastore, os.Stderr)
+	if err != nil {
+		return err // not tested, the backend is validated with the config
+	}
+
+	containers, err := store.ReadAll()
+	if err != nil {
+		return fmt.Errorf("store read: %s", err)
+	}
+
+	pluginController, err := newPluginController(n)
+	if err != nil {
+		return err
+	}
+
+	defaultIfaceName, err := defaultInterfaceName()
+	if err != nil {
+		return err
+	}
+
+	var result error
+	for handle, container := range containers {
+		if _, ok := validHandles[handle]; ok {
+			continue
+		}
+
+		if _, err := store.Delete(handle); err != nil {
+			result = multierror.Append(result, fmt.Errorf("store delete %s: %s", handle, err))
+		}
+
+		if err := cleanupContainer(n, pluginController, defaultIfaceName, handle, container.AllIPs()); err != nil {
+			result = multierror.Append(result, err)
+		}
+
+		notifyDatastoreChange(n, datastore.ActionDelete, handle)
+	}
+
+	return result
+}
+
+// cleanupContainer removes the chains and IP masquerade rules of a container,
+// carrying on past errors so that as much as possible is removed.
+func cleanupContainer(n *lib.WrapperConfig, pluginController *lib.PluginController, defaultIfaceName, handle string, containerIPs []string) error {
+	var result error
+
+	netInProvider := newNetInProvider(n, pluginController, defaultIfaceName)
+	if err := netInProvider.Cleanup(handle); err != nil {
+		result = multierror.Append(result, fmt.Errorf("net in cleanup: %s", err))
+	}
+
+	if len(containerIPs) == 0 {
+		// without a datastore entry the IPv4 chains can still be removed by
+		// their names
+		containerIPs = []string{""}
+	}
+
+	for _, containerIP := range containerIPs {
+		netOutProvider, err := newNetOutProvider(n, pluginController, defaultIfaceName, containerIP)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("net out cleanup: %s", err))
+			continue
+		}
+		if err := netOutProvider.Cleanup(handle, containerIP); err != nil {
+			result = multierror.Append(result, fmt.Errorf("net out cleanup: %s", err))
+		}
+
+		if err := pluginController.DelIPMasq(containerIP, n.VTEPName); err != nil {
+			result = multierror.Append(result, fmt.Errorf("removing IP masq: %s", err))
+		}
+	}
+
+	return result
+}
+
+func newNetInProvider(n *lib.WrapperConfig, pluginController *lib.PluginController, defaultIfaceName string) *legacynet.NetIn {
+	return &legacynet.NetIn{
 		ChainNamer: &legacynet.ChainNamer{
 			MaxLength: 28,
 		},
 		IPTables:          pluginController.IPTables,
-		Converter:         &legacynet.NetOutRuleConverter{Logger: os.Stderr},
+		IngressTag:        n.IngressTag,
 		HostInterfaceName: defaultIfaceName,
 	}
+}
 
-	if err = netOutProvider.Cleanup(args.ContainerID, container.IP); err != nil {
-		fmt.Fprintf(os.Stderr, "net out cleanup: %s", err)
+// newNetOutProvider writes the chains of the IP family of containerIP.
+func newNetOutProvider(n *lib.WrapperConfig, pluginController *lib.PluginController, defaultIfaceName, containerIP string) (*legacynet.NetOut, error) {
+	ipt, err := pluginController.IPTablesFor(containerIP)
+	if err != nil {
+		return nil, err
 	}
 
-	err = pluginController.DelIPMasq(container.IP, n.VTEPName)
+	return &legacynet.NetOut{
+		ChainNamer: &legacynet.ChainNamer{
+			MaxLength: 28,
+		},
+		IPTables:          ipt,
+		IPv6:              rules.IsIPv6(containerIP),
+		Converter:         &legacynet.NetOutRuleConverter{Logger: os.Stderr},
+		ASGLogging:        n.IPTablesASGLogging,
+		C2CLogging:        n.IPTablesC2CLogging,
+		DeniedLogsPerSec:  n.IPTablesDeniedLogsPerSec,
+		IngressTag:        n.IngressTag,
+		VTEPName:          n.VTEPName,
+		HostInterfaceName: defaultIfaceName,
+	}, nil
+}
+
+func defaultInterfaceName() (string, error) {
+	defaultInterface := discover.DefaultInterface{
+		NetlinkAdapter: &adapter.NetlinkAdapter{},
+		NetAdapter:     &adapter.NetAdapter{},
+	}
+	defaultIfaceName, err := defaultInterface.Name()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "removing IP masq: %s", err)
+		return "", fmt.Errorf("discover default interface name: %s", err) // not tested
 	}
-
-	return nil
+	return defaultIfaceName, nil
 }
 
-func newPluginController(iptablesLockFile string) (*lib.PluginController, error) {
+func newPluginController(n *lib.WrapperConfig) (*lib.PluginController, error) {
 	ipt, err := iptables.New()
 	if err != nil {
 		return nil, err
 	}
 
 	iptLocker := &rules.IPTablesLocker{
-		FileLocker: filelock.NewLocker(iptablesLockFile),
+		FileLocker: n.IPTablesFileLocker(),
 		Mutex:      &sync.Mutex{},
 	}
 	restorer := &rules.Restorer{}
@@ -243,11 +519,54 @@ func newPluginController(iptablesLockFile string) (*lib.PluginController, error)
 		Delegator: lib.NewDelegator(),
 		IPTables:  lockedIPTables,
 	}
+
+	// cells without ip6tables can only run IPv4 containers
+	ip6t, err := iptables.NewWithProtocol(iptables.ProtocolIPv6)
+	if err == nil {
+		pluginController.IP6Tables = &rules.LockedIPTables{
+			IPTables: ip6t,
+			Locker:   iptLocker,
+			Restorer: &rules.Restorer{IPv6: true},
+		}
+	}
+
 	return pluginController, nil
 }
 
+// pluginMain runs the commands that skel.PluginMain does not dispatch, with
+// the same arguments and error reporting. There is no version negotiation.
+func pluginMain(cmd func(*skel.CmdArgs) error) {
+	stdinData, err := ioutil.ReadAll(os.Stdin)
+	if err == nil {
+		err = cmd(&skel.CmdArgs{
+			ContainerID: os.Getenv("CNI_CONTAINERID"),
+			Netns:       os.Getenv("CNI_NETNS"),
+			IfName:      os.Getenv("CNI_IFNAME"),
+			Args:        os.Getenv("CNI_ARGS"),
+			Path:        os.Getenv("CNI_PATH"),
+			StdinData:   stdinData,
+		})
+	}
+
+	if err != nil {
+		if e, ok := err.(*types.Error); ok {
+			e.Print()
+		} else {
+			(&types.Error{Code: 100, Msg: err.Error()}).Print()
+		}
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
 func main() {
-	supportedVersions := []string{"0.3.1"}
+	supportedVersions := []string{"0.3.1", "0.4.0"}
+
+	// GC is a custom command of this plugin rather than part of the CNI spec
+	// versions it supports, so skel does not know about it
+	if os.Getenv("CNI_COMMAND") == "GC" {
+		pluginMain(cmdGC)
+	}
 
-	skel.PluginMain(cmdAdd, cmdDel, version.PluginSupports(supportedVersions...))
+	skel.PluginMain(cmdAdd, cmdCheck, cmdDel, version.PluginSupports(supportedVersions...), "cni-wrapper-plugin")
 }
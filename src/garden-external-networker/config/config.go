@@ -3,18 +3,23 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"garden-external-networker/port_allocator"
 	"io/ioutil"
 	"os"
 )
 
 type Config struct {
-	CniPluginDir  string `json:"cni_plugin_dir"`
-	CniConfigDir  string `json:"cni_config_dir"`
-	BindMountDir  string `json:"bind_mount_dir"`
-	StateFilePath string `json:"state_file"`
-	StartPort     int    `json:"start_port"`
-	TotalPorts    int    `json:"total_ports"`
-	LogPrefix     string `json:"log_prefix"`
+	CniPluginDir          string `json:"cni_plugin_dir"`
+	CniConfigDir          string `json:"cni_config_dir"`
+	BindMountDir          string `json:"bind_mount_dir"`
+	StateFilePath         string `json:"state_file"`
+	StartPort             int    `json:"start_port"`
+	TotalPorts            int    `json:"total_ports"`
+	PortStrategy          string `json:"port_allocation_strategy"`
+	PortQuarantineSeconds int    `json:"port_quarantine_seconds"`
+	GardenProtocol        string `json:"garden_protocol"`
+	GardenAddress         string `json:"garden_address"`
+	LogPrefix             string `json:"log_prefix"`
 }
 
 func New(configFilePath string) (Config, error) {
@@ -58,6 +63,14 @@ func New(configFilePath string) (Config, error) {
 		return cfg, fmt.Errorf("missing required config 'total_ports'")
 	}
 
+	if !port_allocator.ValidStrategy(cfg.PortStrategy) {
+		return cfg, fmt.Errorf("invalid config 'port_allocation_strategy': %s", cfg.PortStrategy)
+	}
+
+	if cfg.PortQuarantineSeconds < 0 {
+		return cfg, fmt.Errorf("invalid config 'port_quarantine_seconds': %d", cfg.PortQuarantineSeconds)
+	}
+
 	if cfg.LogPrefix == "" {
 		return cfg, fmt.Errorf("missing required config 'log_prefix'")
 	}
@@ -33,6 +33,10 @@ var _ = Describe("Config", func() {
 					"state_file": "some/path",
 					"start_port": 1234,
 					"total_ports": 56,
+					"port_allocation_strategy": "round-robin",
+					"port_quarantine_seconds": 120,
+					"garden_protocol": "unix",
+					"garden_address": "/some/garden.sock",
 					"log_prefix": "prefix"
 				}`)
 				c, err := config.New(file.Name())
@@ -43,6 +47,10 @@ var _ = Describe("Config", func() {
 				Expect(c.StateFilePath).To(Equal("some/path"))
 				Expect(c.StartPort).To(Equal(1234))
 				Expect(c.TotalPorts).To(Equal(56))
+				Expect(c.PortStrategy).To(Equal("round-robin"))
+				Expect(c.PortQuarantineSeconds).To(Equal(120))
+				Expect(c.GardenProtocol).To(Equal("unix"))
+				Expect(c.GardenAddress).To(Equal("/some/garden.sock"))
 				Expect(c.LogPrefix).To(Equal("prefix"))
 			})
 		})
@@ -101,5 +109,26 @@ var _ = Describe("Config", func() {
 			Entry("missing total ports", "total_ports"),
 			Entry("missing log prefix", "log_prefix"),
 		)
+
+		DescribeTable("when config file has an invalid member",
+			func(member string, value interface{}, errorMsg string) {
+				allData := map[string]interface{}{
+					"cni_plugin_dir": "/some/plugin/dir",
+					"cni_config_dir": "/some/config/dir",
+					"bind_mount_dir": "/some/mount/dir",
+					"state_file":     "/some/state/file",
+					"start_port":     50000,
+					"total_ports":    10000,
+					"log_prefix":     "prefix",
+				}
+				allData[member] = value
+				Expect(json.NewEncoder(file).Encode(allData)).To(Succeed())
+
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError(errorMsg))
+			},
+			Entry("unknown port allocation strategy", "port_allocation_strategy", "banana", "invalid config 'port_allocation_strategy': banana"),
+			Entry("negative port quarantine", "port_quarantine_seconds", -1, "invalid config 'port_quarantine_seconds': -1"),
+		)
 	})
 })
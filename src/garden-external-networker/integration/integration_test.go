@@ -15,6 +15,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
 )
 
@@ -227,7 +228,7 @@ var _ = Describe("Garden External Networker", func() {
 			"properties": {
 				"garden.network.container-ip": "169.254.1.2",
 				"garden.network.host-ip": "255.255.255.255",
-				"garden.network.mapped-ports": "[{\"HostPort\":12345,\"ContainerPort\":7000},{\"HostPort\":60000,\"ContainerPort\":7000}]"
+				"garden.network.mapped-ports": "[{\"HostPort\":12345,\"ContainerPort\":7000,\"Protocol\":\"tcp\"},{\"HostPort\":60000,\"ContainerPort\":7000,\"Protocol\":\"tcp\"}]"
 			},
 			"dns_servers": [
 				"1.2.3.4"
@@ -292,6 +293,51 @@ var _ = Describe("Garden External Networker", func() {
 		runAndWait(downCommand2)
 	})
 
+	It("reports the ports allocated to containers", func() {
+		runAndWait(upCommand)
+
+		portsCommand := exec.Command(paths.PathToAdapter,
+			"--action", "ports",
+			"--configFile", fakeConfigFilePath,
+		)
+		portsSession := runAndWait(portsCommand)
+		Expect(portsSession.Out.Contents()).To(MatchJSON(fmt.Sprintf(`{
+			"start_port": 60000,
+			"capacity": 56,
+			"acquired": 1,
+			"quarantined": 0,
+			"free": 55,
+			"allocations": { %q: [ 60000 ] }
+		}`, containerHandle)))
+
+		runAndWait(downCommand)
+
+		portsSession = runAndWait(exec.Command(paths.PathToAdapter,
+			"--action", "ports",
+			"--configFile", fakeConfigFilePath,
+		))
+		Expect(portsSession.Out.Contents()).To(MatchJSON(`{
+			"start_port": 60000,
+			"capacity": 56,
+			"acquired": 0,
+			"quarantined": 0,
+			"free": 56,
+			"allocations": {}
+		}`))
+	})
+
+	Context("when reconciling ports without a garden address", func() {
+		It("should return a useful error", func() {
+			session, err := gexec.Start(exec.Command(paths.PathToAdapter,
+				"--action", "reconcile-ports",
+				"--configFile", fakeConfigFilePath,
+			), GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session, "5s").Should(gexec.Exit(1))
+			Expect(session.Err).To(gbytes.Say("reconcile-ports requires garden_protocol and garden_address"))
+		})
+	})
+
 	Context("when the CNI plugin result DNS servers list is empty", func() {
 		BeforeEach(func() {
 			upCommand.Env = append(upCommand.Env, "FAKE_CNI_DEBUG=no_dns_result")
@@ -305,7 +351,7 @@ var _ = Describe("Garden External Networker", func() {
 			"properties": {
 				"garden.network.container-ip": "169.254.1.2",
 				"garden.network.host-ip": "255.255.255.255",
-				"garden.network.mapped-ports": "[{\"HostPort\":12345,\"ContainerPort\":7000},{\"HostPort\":60000,\"ContainerPort\":7000}]"
+				"garden.network.mapped-ports": "[{\"HostPort\":12345,\"ContainerPort\":7000,\"Protocol\":\"tcp\"},{\"HostPort\":60000,\"ContainerPort\":7000,\"Protocol\":\"tcp\"}]"
 			}
 		}`))
 
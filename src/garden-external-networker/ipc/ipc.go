@@ -4,16 +4,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"garden-external-networker/manager"
+	"garden-external-networker/port_allocator"
 	"io"
 )
 
 type Mux struct {
-	Up   func(handle string, inputs manager.UpInputs) (*manager.UpOutputs, error)
-	Down func(handle string) error
+	Up             func(handle string, inputs manager.UpInputs) (*manager.UpOutputs, error)
+	Down           func(handle string) error
+	NetOut         func(handle string, inputs manager.NetOutInputs) error
+	BulkNetOut     func(handle string, inputs manager.BulkNetOutInputs) error
+	Ports          func() (*port_allocator.PoolStatus, error)
+	ReconcilePorts func() (*manager.ReconcilePortsOutputs, error)
+}
+
+// NeedsHandle reports whether an action is about a single container. The
+// other actions are run by operators rather than Garden.
+func NeedsHandle(action string) bool {
+	return action != "ports" && action != "reconcile-ports"
 }
 
 func (m *Mux) Handle(action string, handle string, stdin io.Reader, stdout io.Writer) error {
-	if handle == "" {
+	if handle == "" && NeedsHandle(action) {
 		return fmt.Errorf("missing handle")
 	}
 
@@ -35,6 +46,38 @@ func (m *Mux) Handle(action string, handle string, stdin io.Reader, stdout io.Wr
 		if err != nil {
 			return err
 		}
+	case "net-out":
+		var inputs manager.NetOutInputs
+		if err := json.NewDecoder(stdin).Decode(&inputs); err != nil {
+			return err
+		}
+		if err := m.NetOut(handle, inputs); err != nil {
+			return err
+		}
+	case "bulk-net-out":
+		var inputs manager.BulkNetOutInputs
+		if err := json.NewDecoder(stdin).Decode(&inputs); err != nil {
+			return err
+		}
+		if err := m.BulkNetOut(handle, inputs); err != nil {
+			return err
+		}
+	case "ports":
+		outputs, err := m.Ports()
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(stdout).Encode(outputs); err != nil {
+			return err
+		}
+	case "reconcile-ports":
+		outputs, err := m.ReconcilePorts()
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(stdout).Encode(outputs); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unrecognized action: %s", action)
 	}
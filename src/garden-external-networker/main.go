@@ -1,6 +1,9 @@
 package main
 
 import (
+	"cni-wrapper-plugin/legacynet"
+	"cni-wrapper-plugin/lib"
+	"errors"
 	"flag"
 	"fmt"
 	"garden-external-networker/bindmount"
@@ -10,9 +13,19 @@ import (
 	"garden-external-networker/manager"
 	"garden-external-networker/port_allocator"
 	"io"
+	"lib/datastore"
 	"lib/filelock"
+	"lib/rules"
 	"lib/serial"
+	"math/rand"
 	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/garden/client"
+	"code.cloudfoundry.org/garden/client/connection"
+	"github.com/containernetworking/cni/libcni"
+	"github.com/coreos/go-iptables/iptables"
 )
 
 var (
@@ -49,7 +62,7 @@ func parseArgs(allArgs []string) error {
 		return fmt.Errorf("unexpected extra args: %+v", flagSet.Args())
 	}
 
-	if handle == "" {
+	if handle == "" && ipc.NeedsHandle(action) {
 		return fmt.Errorf("missing required flag 'handle'")
 	}
 
@@ -98,9 +111,13 @@ func mainWithError(logger io.Writer) error {
 	mounter := &bindmount.Mounter{}
 
 	locker := filelock.NewLocker(cfg.StateFilePath)
+	// the random port allocation strategy must not repeat itself every run
+	rand.Seed(time.Now().UnixNano())
 	tracker := &port_allocator.Tracker{
-		StartPort: cfg.StartPort,
-		Capacity:  cfg.TotalPorts,
+		StartPort:  cfg.StartPort,
+		Capacity:   cfg.TotalPorts,
+		Strategy:   cfg.PortStrategy,
+		Quarantine: time.Duration(cfg.PortQuarantineSeconds) * time.Second,
 	}
 	serializer := &serial.Serial{}
 	portAllocator := &port_allocator.PortAllocator{
@@ -117,10 +134,105 @@ func mainWithError(logger io.Writer) error {
 		PortAllocator: portAllocator,
 	}
 
+	if action == "net-out" || action == "bulk-net-out" {
+		netOutProviders, err := newNetOutProviders(networks, handle)
+		if err != nil {
+			return fmt.Errorf("load net-out provider: %s", err)
+		}
+		for _, netOutProvider := range netOutProviders {
+			manager.NetOutProviders = append(manager.NetOutProviders, netOutProvider)
+		}
+	}
+
+	if action == "reconcile-ports" {
+		if cfg.GardenProtocol == "" || cfg.GardenAddress == "" {
+			return errors.New("reconcile-ports requires garden_protocol and garden_address")
+		}
+		manager.GardenClient = client.New(connection.New(cfg.GardenProtocol, cfg.GardenAddress))
+	}
+
 	mux := ipc.Mux{
-		Up:   manager.Up,
-		Down: manager.Down,
+		Up:             manager.Up,
+		Down:           manager.Down,
+		NetOut:         manager.NetOut,
+		BulkNetOut:     manager.BulkNetOut,
+		Ports:          manager.Ports,
+		ReconcilePorts: manager.ReconcilePorts,
 	}
 
 	return mux.Handle(action, handle, os.Stdin, os.Stdout)
 }
+
+// newNetOutProviders write the netout chains of a running container using the
+// config of the cni-wrapper-plugin network, which created those chains. There
+// is one provider for each IP family of the container in the datastore.
+func newNetOutProviders(networks []*libcni.NetworkConfig, containerHandle string) ([]*legacynet.NetOut, error) {
+	for _, network := range networks {
+		if network.Network.Type != "cni-wrapper-plugin" {
+			continue
+		}
+
+		wrapperConfig, err := lib.LoadWrapperConfig(network.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err := datastore.New(wrapperConfig.DatastoreBackend, wrapperConfig.Datastore, os.Stderr)
+		if err != nil {
+			return nil, err // not tested
+		}
+		containers, err := store.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("read datastore: %s", err)
+		}
+
+		// containers missing from the datastore only have IPv4 chains
+		families := map[bool]bool{false: true}
+		if container, ok := containers[containerHandle]; ok {
+			families = map[bool]bool{}
+			for _, ip := range container.AllIPs() {
+				families[rules.IsIPv6(ip)] = true
+			}
+		}
+
+		iptLocker := &rules.IPTablesLocker{
+			FileLocker: wrapperConfig.IPTablesFileLocker(),
+			Mutex:      &sync.Mutex{},
+		}
+
+		var netOutProviders []*legacynet.NetOut
+		for _, ipv6 := range []bool{false, true} {
+			if !families[ipv6] {
+				continue
+			}
+
+			protocol := iptables.ProtocolIPv4
+			if ipv6 {
+				protocol = iptables.ProtocolIPv6
+			}
+			ipt, err := iptables.NewWithProtocol(protocol)
+			if err != nil {
+				return nil, err // not tested
+			}
+
+			netOutProviders = append(netOutProviders, &legacynet.NetOut{
+				ChainNamer: &legacynet.ChainNamer{
+					MaxLength: 28,
+				},
+				IPTables: &rules.LockedIPTables{
+					IPTables: ipt,
+					Locker:   iptLocker,
+					Restorer: &rules.Restorer{IPv6: ipv6},
+				},
+				IPv6:             ipv6,
+				Converter:        &legacynet.NetOutRuleConverter{Logger: os.Stderr},
+				ASGLogging:       wrapperConfig.IPTablesASGLogging,
+				DeniedLogsPerSec: wrapperConfig.IPTablesDeniedLogsPerSec,
+			})
+		}
+
+		return netOutProviders, nil
+	}
+
+	return nil, errors.New("no cni-wrapper-plugin network configured")
+}
@@ -1,15 +1,18 @@
 package manager
 
 import (
+	"cni-wrapper-plugin/lib"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"garden-external-networker/port_allocator"
 	"io"
 	"path/filepath"
 
 	"code.cloudfoundry.org/garden"
 	"github.com/containernetworking/cni/pkg/types"
-	"github.com/containernetworking/cni/pkg/types/020"
+	"github.com/containernetworking/cni/pkg/types/current"
+	multierror "github.com/hashicorp/go-multierror"
 )
 
 //go:generate counterfeiter -o ../fakes/cniController.go --fake-name CNIController . cniController
@@ -26,8 +29,21 @@ type mounter interface {
 
 //go:generate counterfeiter -o ../fakes/portAllocator.go --fake-name PortAllocator . portAllocator
 type portAllocator interface {
-	AllocatePort(handle string, port int) (int, error)
+	AllocatePorts(handle string, port, count int) (int, error)
 	ReleaseAllPorts(handle string) error
+	AllocatedHandles() ([]string, error)
+	Status() (port_allocator.PoolStatus, error)
+}
+
+//go:generate counterfeiter -o ../fakes/gardenClient.go --fake-name GardenClient . gardenClient
+type gardenClient interface {
+	Containers(garden.Properties) ([]garden.Container, error)
+}
+
+//go:generate counterfeiter -o ../fakes/netOutProvider.go --fake-name NetOutProvider . netOutProvider
+type netOutProvider interface {
+	InsertRule(containerHandle string, rule garden.NetOutRule) error
+	BulkReplaceRules(containerHandle string, netOutRules []garden.NetOutRule) error
 }
 
 type Manager struct {
@@ -36,23 +52,46 @@ type Manager struct {
 	Mounter       mounter
 	BindMountRoot string
 	PortAllocator portAllocator
+	// NetOutProviders write the netout chains of a container, one for each
+	// of its IP families.
+	NetOutProviders []netOutProvider
+	// GardenClient is only needed to reconcile ports.
+	GardenClient gardenClient
 }
 
 type UpInputs struct {
 	Pid        int
 	Properties map[string]interface{}
 	NetOut     []garden.NetOutRule `json:"netout_rules"`
-	NetIn      []garden.NetIn      `json:"netin"`
+	NetIn      []lib.NetIn         `json:"netin"`
 }
 type UpOutputs struct {
 	Properties struct {
 		ContainerIP      string `json:"garden.network.container-ip"`
+		ContainerIPv6    string `json:"garden.network.container-ipv6,omitempty"`
 		DeprecatedHostIP string `json:"garden.network.host-ip"`
 		MappedPorts      string `json:"garden.network.mapped-ports"`
 	} `json:"properties"`
 	DNSServers []string `json:"dns_servers,omitempty"`
 }
 
+// PortMapping is a garden.PortMapping with the protocol of the mapping and,
+// for a range of host ports, their count.
+type PortMapping struct {
+	HostPort      uint32
+	ContainerPort uint32
+	HostPortCount uint32 `json:",omitempty"`
+	Protocol      string
+}
+
+type NetOutInputs struct {
+	NetOutRule garden.NetOutRule `json:"netout_rule"`
+}
+
+type BulkNetOutInputs struct {
+	NetOutRules []garden.NetOutRule `json:"netout_rules"`
+}
+
 func (m *Manager) Up(containerHandle string, inputs UpInputs) (*UpOutputs, error) {
 	if inputs.Pid == 0 {
 		return nil, errors.New("up missing pid")
@@ -69,19 +108,29 @@ func (m *Manager) Up(containerHandle string, inputs UpInputs) (*UpOutputs, error
 		return nil, fmt.Errorf("failed mounting %s to %s: %s", procNsPath, bindMountPath, err)
 	}
 
-	mappedPorts := []garden.PortMapping{}
+	mappedPorts := []PortMapping{}
 	for i := range inputs.NetIn {
+		if err := inputs.NetIn[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid netin: %s", err)
+		}
+
 		if inputs.NetIn[i].HostPort == 0 {
-			hostPort, err := m.PortAllocator.AllocatePort(containerHandle, int(inputs.NetIn[i].HostPort))
+			hostPort, err := m.PortAllocator.AllocatePorts(containerHandle, 0, inputs.NetIn[i].Count())
 			if err != nil {
 				return nil, fmt.Errorf("allocating port: %s", err)
 			}
 			inputs.NetIn[i].HostPort = uint32(hostPort)
 		}
 
-		mappedPorts = append(mappedPorts, garden.PortMapping{
+		protocol := inputs.NetIn[i].Protocol
+		if protocol == "" {
+			protocol = lib.ProtocolTCP
+		}
+		mappedPorts = append(mappedPorts, PortMapping{
 			HostPort:      inputs.NetIn[i].HostPort,
 			ContainerPort: inputs.NetIn[i].ContainerPort,
+			HostPortCount: inputs.NetIn[i].HostPortCount,
+			Protocol:      protocol,
 		})
 	}
 
@@ -102,18 +151,37 @@ func (m *Manager) Up(containerHandle string, inputs UpInputs) (*UpOutputs, error
 		return nil, errors.New("cni up failed: no ip allocated")
 	}
 
-	result020, err := result.GetAsVersion("0.2.0")
+	result030, err := current.NewResultFromResult(result)
 	if err != nil {
 		return nil, fmt.Errorf("cni plugin result version incompatible: %s", err) // not tested
 	}
 
-	containerIP := result020.(*types020.Result).IP4.IP.IP
+	var containerIPv4, containerIPv6 string
+	for _, ipConfig := range result030.IPs {
+		ip := ipConfig.Address.IP
+		if ip.To4() != nil {
+			if containerIPv4 == "" {
+				containerIPv4 = ip.String()
+			}
+		} else if containerIPv6 == "" {
+			containerIPv6 = ip.String()
+		}
+	}
+
+	if containerIPv4 == "" && containerIPv6 == "" {
+		return nil, errors.New("cni up failed: no ip allocated")
+	}
 
 	outputs := UpOutputs{}
 	outputs.Properties.MappedPorts = toJson(mappedPorts)
-	outputs.Properties.ContainerIP = containerIP.String()
+	outputs.Properties.ContainerIP = containerIPv4
+	if containerIPv4 == "" {
+		// IPv6-only containers still need a container-ip for garden
+		outputs.Properties.ContainerIP = containerIPv6
+	}
+	outputs.Properties.ContainerIPv6 = containerIPv6
 	outputs.Properties.DeprecatedHostIP = "255.255.255.255"
-	outputs.DNSServers = result020.(*types020.Result).DNS.Nameservers
+	outputs.DNSServers = result030.DNS.Nameservers
 	return &outputs, nil
 }
 
@@ -140,7 +208,92 @@ func (m *Manager) Down(containerHandle string) error {
 	return nil
 }
 
-func toJson(mappedPorts []garden.PortMapping) string {
+// NetOut adds a rule to the netout chain of a running container.
+func (m *Manager) NetOut(containerHandle string, inputs NetOutInputs) error {
+	if containerHandle == "" {
+		return errors.New("net-out missing container handle")
+	}
+
+	for _, netOutProvider := range m.NetOutProviders {
+		if err := netOutProvider.InsertRule(containerHandle, inputs.NetOutRule); err != nil {
+			return fmt.Errorf("insert net-out rule: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// BulkNetOut replaces the netout rules of a running container, e.g. when its
+// application security groups change.
+func (m *Manager) BulkNetOut(containerHandle string, inputs BulkNetOutInputs) error {
+	if containerHandle == "" {
+		return errors.New("bulk-net-out missing container handle")
+	}
+
+	for _, netOutProvider := range m.NetOutProviders {
+		if err := netOutProvider.BulkReplaceRules(containerHandle, inputs.NetOutRules); err != nil {
+			return fmt.Errorf("replace net-out rules: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// Ports describes the host ports allocated to containers.
+func (m *Manager) Ports() (*port_allocator.PoolStatus, error) {
+	status, err := m.PortAllocator.Status()
+	if err != nil {
+		return nil, fmt.Errorf("port allocator status: %s", err)
+	}
+	return &status, nil
+}
+
+type ReconcilePortsOutputs struct {
+	ReleasedHandles []string `json:"released_handles"`
+}
+
+// ReconcilePorts releases the host ports of handles that Garden does not know
+// about, e.g. when releasing them failed in Down. The allocations are read
+// before the containers are listed, so a container that is being created is
+// already known to Garden by the time its ports are looked at.
+func (m *Manager) ReconcilePorts() (*ReconcilePortsOutputs, error) {
+	if m.GardenClient == nil {
+		return nil, errors.New("reconcile-ports requires a garden client")
+	}
+
+	allocatedHandles, err := m.PortAllocator.AllocatedHandles()
+	if err != nil {
+		return nil, fmt.Errorf("list port allocations: %s", err)
+	}
+
+	containers, err := m.GardenClient.Containers(garden.Properties{})
+	if err != nil {
+		return nil, fmt.Errorf("list garden containers: %s", err)
+	}
+	liveHandles := make(map[string]struct{})
+	for _, container := range containers {
+		liveHandles[container.Handle()] = struct{}{}
+	}
+
+	outputs := &ReconcilePortsOutputs{ReleasedHandles: []string{}}
+	var result error
+	for _, handle := range allocatedHandles {
+		if _, ok := liveHandles[handle]; ok {
+			continue
+		}
+		if err := m.PortAllocator.ReleaseAllPorts(handle); err != nil {
+			result = multierror.Append(result, fmt.Errorf("release ports of %s: %s", handle, err))
+			continue
+		}
+		outputs.ReleasedHandles = append(outputs.ReleasedHandles, handle)
+	}
+	if result != nil {
+		return nil, result
+	}
+	return outputs, nil
+}
+
+func toJson(mappedPorts []PortMapping) string {
 	bytes, err := json.Marshal(mappedPorts)
 	if err != nil {
 		panic(err) // untested, should never happen
@@ -8,12 +8,16 @@ import (
 	"path/filepath"
 
 	"code.cloudfoundry.org/garden"
+	"code.cloudfoundry.org/garden/gardenfakes"
 
+	"cni-wrapper-plugin/lib"
 	"garden-external-networker/fakes"
 	"garden-external-networker/manager"
+	"garden-external-networker/port_allocator"
 
 	"github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/cni/pkg/types/020"
+	"github.com/containernetworking/cni/pkg/types/current"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -28,7 +32,8 @@ var _ = Describe("Manager", func() {
 		expectedMetadata      map[string]interface{}
 		expectedLegacyNetConf map[string]interface{}
 		portAllocator         *fakes.PortAllocator
-		netInRules            []garden.NetIn
+		netOutProvider        *fakes.NetOutProvider
+		netInRules            []lib.NetIn
 		netOutRules           []garden.NetOutRule
 		logger                *bytes.Buffer
 		containerHandle       string
@@ -40,6 +45,7 @@ var _ = Describe("Manager", func() {
 		mounter = &fakes.Mounter{}
 		cniController = &fakes.CNIController{}
 		portAllocator = &fakes.PortAllocator{}
+		netOutProvider = &fakes.NetOutProvider{}
 
 		cniController.UpReturns(&types020.Result{
 			IP4: &types020.IPConfig{
@@ -59,8 +65,9 @@ var _ = Describe("Manager", func() {
 			BindMountRoot: "some/fake/path",
 			PortAllocator: portAllocator,
 		}
+		mgr.NetOutProviders = append(mgr.NetOutProviders, netOutProvider)
 
-		netInRules = []garden.NetIn{
+		netInRules = []lib.NetIn{
 			{
 				HostPort:      12345,
 				ContainerPort: 7000,
@@ -118,6 +125,63 @@ var _ = Describe("Manager", func() {
 			Expect(out.Properties.DeprecatedHostIP).To(Equal("255.255.255.255"))
 		})
 
+		Context("when the container is dual-stack", func() {
+			BeforeEach(func() {
+				cniController.UpReturns(&current.Result{
+					IPs: []*current.IPConfig{
+						{
+							Version: "6",
+							Address: net.IPNet{IP: net.ParseIP("fd00::2"), Mask: net.CIDRMask(64, 128)},
+						},
+						{
+							Version: "4",
+							Address: net.IPNet{IP: net.ParseIP("169.254.1.2"), Mask: net.CIDRMask(24, 32)},
+						},
+					},
+				}, nil)
+			})
+
+			It("returns the IPv4 address as the container ip and the IPv6 address separately", func() {
+				out, err := mgr.Up(containerHandle, upInputs)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(out.Properties.ContainerIP).To(Equal("169.254.1.2"))
+				Expect(out.Properties.ContainerIPv6).To(Equal("fd00::2"))
+			})
+		})
+
+		Context("when the container is IPv6-only", func() {
+			BeforeEach(func() {
+				cniController.UpReturns(&current.Result{
+					IPs: []*current.IPConfig{
+						{
+							Version: "6",
+							Address: net.IPNet{IP: net.ParseIP("fd00::2"), Mask: net.CIDRMask(64, 128)},
+						},
+					},
+				}, nil)
+			})
+
+			It("returns the IPv6 address as the container ip", func() {
+				out, err := mgr.Up(containerHandle, upInputs)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(out.Properties.ContainerIP).To(Equal("fd00::2"))
+				Expect(out.Properties.ContainerIPv6).To(Equal("fd00::2"))
+			})
+		})
+
+		Context("when the CNI result has no ips", func() {
+			BeforeEach(func() {
+				cniController.UpReturns(&current.Result{}, nil)
+			})
+
+			It("returns an error", func() {
+				_, err := mgr.Up(containerHandle, upInputs)
+				Expect(err).To(MatchError("cni up failed: no ip allocated"))
+			})
+		})
+
 		It("should return the DNS nameservers info as a separate key in the up ouput", func() {
 			out, err := mgr.Up(containerHandle, upInputs)
 			Expect(err).NotTo(HaveOccurred())
@@ -142,56 +206,104 @@ var _ = Describe("Manager", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(out.Properties.MappedPorts).To(MatchJSON(`[
-				{"HostPort": 12345, "ContainerPort": 7000},
-				{"HostPort": 23456, "ContainerPort": 7001}
+				{"HostPort": 12345, "ContainerPort": 7000, "Protocol": "tcp"},
+				{"HostPort": 23456, "ContainerPort": 7001, "Protocol": "tcp"}
 			]`))
 		})
 
+		Context("when the netin maps udp or a range of host ports", func() {
+			BeforeEach(func() {
+				netInRules = []lib.NetIn{
+					{
+						HostPort:      0,
+						HostPortCount: 10,
+						ContainerPort: 7000,
+						Protocol:      "tcp+udp",
+					},
+				}
+				upInputs.NetIn = netInRules
+				portAllocator.AllocatePortsReturns(1234, nil)
+			})
+
+			It("allocates the whole range and reflects it in the mapped ports", func() {
+				out, err := mgr.Up(containerHandle, upInputs)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(portAllocator.AllocatePortsCallCount()).To(Equal(1))
+				_, _, count := portAllocator.AllocatePortsArgsForCall(0)
+				Expect(count).To(Equal(10))
+
+				Expect(out.Properties.MappedPorts).To(MatchJSON(`[
+					{"HostPort": 1234, "ContainerPort": 7000, "HostPortCount": 10, "Protocol": "tcp+udp"}
+				]`))
+			})
+		})
+
+		Context("when a netin is invalid", func() {
+			BeforeEach(func() {
+				upInputs.NetIn = []lib.NetIn{
+					{
+						HostPort:      8080,
+						ContainerPort: 7000,
+						Protocol:      "sctp",
+					},
+				}
+			})
+
+			It("returns an error without allocating ports", func() {
+				_, err := mgr.Up(containerHandle, upInputs)
+				Expect(err).To(MatchError("invalid netin: invalid protocol: sctp"))
+				Expect(portAllocator.AllocatePortsCallCount()).To(Equal(0))
+				Expect(cniController.UpCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("when the host port is 0", func() {
 			BeforeEach(func() {
-				netInRules = []garden.NetIn{
+				netInRules = []lib.NetIn{
 					{
 						HostPort:      0,
 						ContainerPort: 7000,
 					},
 				}
 				upInputs.NetIn = netInRules
-				portAllocator.AllocatePortReturns(1234, nil)
+				portAllocator.AllocatePortsReturns(1234, nil)
 			})
 			It("allocates a port", func() {
 				out, err := mgr.Up(containerHandle, upInputs)
 
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(portAllocator.AllocatePortCallCount()).To(Equal(1))
-				handle, port := portAllocator.AllocatePortArgsForCall(0)
+				Expect(portAllocator.AllocatePortsCallCount()).To(Equal(1))
+				handle, port, count := portAllocator.AllocatePortsArgsForCall(0)
 				Expect(handle).To(Equal("some-container-handle"))
 				Expect(port).To(Equal(0))
+				Expect(count).To(Equal(1))
 
 				Expect(cniController.UpCallCount()).To(Equal(1))
 				_, handle, _, legacyNetConf := cniController.UpArgsForCall(0)
 				Expect(handle).To(Equal(containerHandle))
-				Expect(legacyNetConf).To(HaveKeyWithValue("portMappings", []garden.NetIn{
+				Expect(legacyNetConf).To(HaveKeyWithValue("portMappings", []lib.NetIn{
 					{
 						HostPort:      1234,
 						ContainerPort: 7000,
 					},
 				}))
 
-				Expect(out.Properties.MappedPorts).To(MatchJSON(`[{"HostPort": 1234, "ContainerPort": 7000}]`))
+				Expect(out.Properties.MappedPorts).To(MatchJSON(`[{"HostPort": 1234, "ContainerPort": 7000, "Protocol": "tcp"}]`))
 			})
 		})
 
 		Context("when the port allocation fails", func() {
 			BeforeEach(func() {
-				netInRules = []garden.NetIn{
+				netInRules = []lib.NetIn{
 					{
 						HostPort:      0,
 						ContainerPort: 7000,
 					},
 				}
 				upInputs.NetIn = netInRules
-				portAllocator.AllocatePortReturns(0, errors.New("banana"))
+				portAllocator.AllocatePortsReturns(0, errors.New("banana"))
 			})
 			It("returns an error", func() {
 				_, err := mgr.Up(containerHandle, upInputs)
@@ -327,4 +439,165 @@ var _ = Describe("Manager", func() {
 		})
 
 	})
+
+	Describe("NetOut", func() {
+		It("inserts the rule into the container's netout chain", func() {
+			err := mgr.NetOut(containerHandle, manager.NetOutInputs{NetOutRule: netOutRules[0]})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(netOutProvider.InsertRuleCallCount()).To(Equal(1))
+			handle, rule := netOutProvider.InsertRuleArgsForCall(0)
+			Expect(handle).To(Equal(containerHandle))
+			Expect(rule).To(Equal(netOutRules[0]))
+		})
+
+		Context("when the container has chains for both IP families", func() {
+			var ipv6NetOutProvider *fakes.NetOutProvider
+
+			BeforeEach(func() {
+				ipv6NetOutProvider = &fakes.NetOutProvider{}
+				mgr.NetOutProviders = append(mgr.NetOutProviders, ipv6NetOutProvider)
+			})
+
+			It("inserts the rule into each netout chain", func() {
+				err := mgr.NetOut(containerHandle, manager.NetOutInputs{NetOutRule: netOutRules[0]})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(netOutProvider.InsertRuleCallCount()).To(Equal(1))
+				Expect(ipv6NetOutProvider.InsertRuleCallCount()).To(Equal(1))
+				handle, rule := ipv6NetOutProvider.InsertRuleArgsForCall(0)
+				Expect(handle).To(Equal(containerHandle))
+				Expect(rule).To(Equal(netOutRules[0]))
+			})
+		})
+
+		Context("when missing args", func() {
+			It("should return a friendly error", func() {
+				err := mgr.NetOut("", manager.NetOutInputs{})
+				Expect(err).To(MatchError("net-out missing container handle"))
+			})
+		})
+
+		Context("when inserting the rule fails", func() {
+			It("should return the error", func() {
+				netOutProvider.InsertRuleReturns(errors.New("bang"))
+				err := mgr.NetOut(containerHandle, manager.NetOutInputs{NetOutRule: netOutRules[0]})
+				Expect(err).To(MatchError("insert net-out rule: bang"))
+			})
+		})
+	})
+
+	Describe("BulkNetOut", func() {
+		It("replaces the rules of the container's netout chain", func() {
+			err := mgr.BulkNetOut(containerHandle, manager.BulkNetOutInputs{NetOutRules: netOutRules})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(netOutProvider.BulkReplaceRulesCallCount()).To(Equal(1))
+			handle, rules := netOutProvider.BulkReplaceRulesArgsForCall(0)
+			Expect(handle).To(Equal(containerHandle))
+			Expect(rules).To(Equal(netOutRules))
+		})
+
+		Context("when missing args", func() {
+			It("should return a friendly error", func() {
+				err := mgr.BulkNetOut("", manager.BulkNetOutInputs{})
+				Expect(err).To(MatchError("bulk-net-out missing container handle"))
+			})
+		})
+
+		Context("when replacing the rules fails", func() {
+			It("should return the error", func() {
+				netOutProvider.BulkReplaceRulesReturns(errors.New("bang"))
+				err := mgr.BulkNetOut(containerHandle, manager.BulkNetOutInputs{NetOutRules: netOutRules})
+				Expect(err).To(MatchError("replace net-out rules: bang"))
+			})
+		})
+	})
+
+	Describe("Ports", func() {
+		It("returns the status of the port pool", func() {
+			status := port_allocator.PoolStatus{Capacity: 10, Acquired: 1, Free: 9}
+			portAllocator.StatusReturns(status, nil)
+
+			outputs, err := mgr.Ports()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outputs).To(Equal(&status))
+		})
+
+		Context("when the status cannot be read", func() {
+			It("should return the error", func() {
+				portAllocator.StatusReturns(port_allocator.PoolStatus{}, errors.New("potato"))
+				_, err := mgr.Ports()
+				Expect(err).To(MatchError("port allocator status: potato"))
+			})
+		})
+	})
+
+	Describe("ReconcilePorts", func() {
+		var gardenClient *fakes.GardenClient
+
+		BeforeEach(func() {
+			gardenClient = &fakes.GardenClient{}
+			mgr.GardenClient = gardenClient
+
+			liveContainer := &gardenfakes.FakeContainer{}
+			liveContainer.HandleReturns("live-handle")
+			gardenClient.ContainersReturns([]garden.Container{liveContainer}, nil)
+			portAllocator.AllocatedHandlesReturns([]string{"dead-handle", "live-handle"}, nil)
+		})
+
+		It("releases the ports of handles that garden does not know about", func() {
+			outputs, err := mgr.ReconcilePorts()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outputs.ReleasedHandles).To(Equal([]string{"dead-handle"}))
+
+			Expect(portAllocator.ReleaseAllPortsCallCount()).To(Equal(1))
+			Expect(portAllocator.ReleaseAllPortsArgsForCall(0)).To(Equal("dead-handle"))
+		})
+
+		It("reads the allocations before listing the containers", func() {
+			gardenClient.ContainersStub = func(garden.Properties) ([]garden.Container, error) {
+				Expect(portAllocator.AllocatedHandlesCallCount()).To(Equal(1))
+				return nil, nil
+			}
+			_, err := mgr.ReconcilePorts()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when there is no garden client", func() {
+			It("should return a friendly error", func() {
+				mgr.GardenClient = nil
+				_, err := mgr.ReconcilePorts()
+				Expect(err).To(MatchError("reconcile-ports requires a garden client"))
+			})
+		})
+
+		Context("when the allocations cannot be listed", func() {
+			It("should return the error", func() {
+				portAllocator.AllocatedHandlesReturns(nil, errors.New("potato"))
+				_, err := mgr.ReconcilePorts()
+				Expect(err).To(MatchError("list port allocations: potato"))
+			})
+		})
+
+		Context("when the garden containers cannot be listed", func() {
+			It("should return the error and release nothing", func() {
+				gardenClient.ContainersReturns(nil, errors.New("banana"))
+				_, err := mgr.ReconcilePorts()
+				Expect(err).To(MatchError("list garden containers: banana"))
+				Expect(portAllocator.ReleaseAllPortsCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when releasing ports fails", func() {
+			It("releases the other handles and returns the errors", func() {
+				portAllocator.AllocatedHandlesReturns([]string{"dead-handle-1", "dead-handle-2"}, nil)
+				portAllocator.ReleaseAllPortsReturnsOnCall(0, errors.New("potato"))
+
+				_, err := mgr.ReconcilePorts()
+				Expect(err).To(MatchError(ContainSubstring("release ports of dead-handle-1: potato")))
+				Expect(portAllocator.ReleaseAllPortsCallCount()).To(Equal(2))
+			})
+		})
+	})
 })
@@ -3,47 +3,118 @@ package port_allocator
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
 )
 
 var ErrorPortPoolExhausted = errors.New("port pool exhausted")
 
 type Pool struct {
 	AcquiredPorts map[int]string
+	// ReleasedPorts maps released ports to the time they were released,
+	// until their quarantine is over.
+	ReleasedPorts map[int]time.Time
+	// Cursor is the port at which the round-robin strategy looks for the next
+	// free port.
+	Cursor int
+	// StartPort and Capacity are the range of the tracker that last wrote the
+	// pool, so that readers without the configuration of the range, like the
+	// vxlan-policy-agent, can report on it.
+	StartPort int
+	Capacity  int
+
+	// taken holds the acquired and quarantined ports of the range starting
+	// at takenStart. It is built on the first acquisition after the pool is
+	// decoded and then kept up to date as ports are acquired and come out of
+	// quarantine, so that later acquisitions do not walk every port again.
+	taken      *portSet
+	takenStart int
+}
+
+type poolJSON struct {
+	AcquiredPorts map[string][]int     `json:"acquired_ports"`
+	ReleasedPorts map[string]time.Time `json:"released_ports,omitempty"`
+	Cursor        int                  `json:"cursor,omitempty"`
+	StartPort     int                  `json:"start_port,omitempty"`
+	Capacity      int                  `json:"capacity,omitempty"`
 }
 
 func (p *Pool) MarshalJSON() ([]byte, error) {
-	var jsonData struct {
-		AcquiredPorts map[string][]int `json:"acquired_ports"`
-	}
+	var jsonData poolJSON
 	jsonData.AcquiredPorts = make(map[string][]int)
-
 	for port, handle := range p.AcquiredPorts {
 		jsonData.AcquiredPorts[handle] = append(jsonData.AcquiredPorts[handle], port)
 	}
+	if len(p.ReleasedPorts) > 0 {
+		jsonData.ReleasedPorts = make(map[string]time.Time)
+		for port, releasedAt := range p.ReleasedPorts {
+			jsonData.ReleasedPorts[strconv.Itoa(port)] = releasedAt
+		}
+	}
+	jsonData.Cursor = p.Cursor
+	jsonData.StartPort = p.StartPort
+	jsonData.Capacity = p.Capacity
 	return json.Marshal(jsonData)
 }
 
 func (p *Pool) UnmarshalJSON(bytes []byte) error {
-	var jsonData struct {
-		AcquiredPorts map[string][]int `json:"acquired_ports"`
-	}
+	var jsonData poolJSON
 	err := json.Unmarshal(bytes, &jsonData)
 	if err != nil {
 		return err
 	}
-
 	p.AcquiredPorts = make(map[int]string)
 	for handle, ports := range jsonData.AcquiredPorts {
 		for _, port := range ports {
 			p.AcquiredPorts[port] = handle
 		}
 	}
+	p.ReleasedPorts = make(map[int]time.Time)
+	for portString, releasedAt := range jsonData.ReleasedPorts {
+		port, err := strconv.Atoi(portString)
+		if err != nil {
+			return fmt.Errorf("invalid released port: %s", portString)
+		}
+		p.ReleasedPorts[port] = releasedAt
+	}
+	p.Cursor = jsonData.Cursor
+	p.StartPort = jsonData.StartPort
+	p.Capacity = jsonData.Capacity
+	p.taken = nil
 	return nil
 }
 
+const (
+	StrategyLowestFree = "lowest-free"
+	StrategyRoundRobin = "round-robin"
+	StrategyRandom     = "random"
+)
+
+// ValidStrategy reports whether strategy is known. The empty strategy is the
+// lowest-free one.
+func ValidStrategy(strategy string) bool {
+	switch strategy {
+	case "", StrategyLowestFree, StrategyRoundRobin, StrategyRandom:
+		return true
+	}
+	return false
+}
+
+// Tracker acquires ports of its range for containers. A Tracker without a
+// range only releases ports, and reports on the range recorded in the pool.
 type Tracker struct {
 	StartPort int
 	Capacity  int
+	// Strategy picks where to look for a free port: the lowest free port,
+	// the next free port after the last one acquired, or a free port at
+	// random. It defaults to the lowest free port.
+	Strategy string
+	// Quarantine is how long a released port is not acquired again, so that
+	// clients with stale connections to it do not reach another container.
+	Quarantine time.Duration
 }
 
 func (t *Tracker) InRange(port int) bool {
@@ -51,30 +122,164 @@ func (t *Tracker) InRange(port int) bool {
 }
 
 func (t *Tracker) AcquireOne(pool *Pool, handler string) (int, error) {
+	return t.AcquireRange(pool, handler, 1)
+}
+
+// AcquireRange acquires count consecutive ports and returns the first of them.
+func (t *Tracker) AcquireRange(pool *Pool, handler string, count int) (int, error) {
+	if count < 1 {
+		return -1, fmt.Errorf("invalid port count: %d", count)
+	}
 	if pool.AcquiredPorts == nil {
 		pool.AcquiredPorts = make(map[int]string)
 	}
+	taken := t.takenPorts(pool)
+	t.endQuarantine(pool)
+
+	offset, ok := taken.nextFreeRun(t.startOffset(pool), count)
+	if !ok {
+		return -1, ErrorPortPoolExhausted
+	}
+
+	port := t.StartPort + offset
+	for i := 0; i < count; i++ {
+		pool.AcquiredPorts[port+i] = handler
+		taken.add(offset + i)
+	}
+	t.recordRange(pool)
+	if t.Strategy == StrategyRoundRobin {
+		pool.Cursor = t.StartPort + (offset+count)%t.Capacity
+	}
+	return port, nil
+}
+
+// takenPorts returns the set of acquired and quarantined ports of the range of
+// the tracker, building it only when the pool does not hold one for that range.
+func (t *Tracker) takenPorts(pool *Pool) *portSet {
+	if pool.taken != nil && pool.takenStart == t.StartPort && pool.taken.capacity == t.Capacity {
+		return pool.taken
+	}
+
+	taken := newPortSet(t.Capacity)
+	for port := range pool.AcquiredPorts {
+		if t.InRange(port) {
+			taken.add(port - t.StartPort)
+		}
+	}
+	for port := range pool.ReleasedPorts {
+		if t.InRange(port) {
+			taken.add(port - t.StartPort)
+		}
+	}
+	pool.taken = taken
+	pool.takenStart = t.StartPort
+	return taken
+}
+
+func (t *Tracker) startOffset(pool *Pool) int {
+	switch t.Strategy {
+	case StrategyRoundRobin:
+		if t.InRange(pool.Cursor) {
+			return pool.Cursor - t.StartPort
+		}
+	case StrategyRandom:
+		if t.Capacity > 0 {
+			return rand.Intn(t.Capacity)
+		}
+	}
+	return 0
+}
+
+// recordRange records the range of the tracker in the pool. A tracker without
+// a range leaves the recorded one alone.
+func (t *Tracker) recordRange(pool *Pool) {
+	if t.Capacity == 0 {
+		return
+	}
+	pool.StartPort = t.StartPort
+	pool.Capacity = t.Capacity
+}
 
-	for i := 0; i < t.Capacity; i++ {
-		candidatePort := t.StartPort + i
-		if !contains(pool.AcquiredPorts, candidatePort) {
-			pool.AcquiredPorts[candidatePort] = handler
-			return candidatePort, nil
+// endQuarantine forgets the released ports whose quarantine is over, and
+// frees them in the taken ports of the pool.
+func (t *Tracker) endQuarantine(pool *Pool) {
+	now := time.Now()
+	for port, releasedAt := range pool.ReleasedPorts {
+		if now.Sub(releasedAt) < t.Quarantine {
+			continue
+		}
+		delete(pool.ReleasedPorts, port)
+		if _, acquired := pool.AcquiredPorts[port]; !acquired && pool.taken != nil && t.InRange(port) {
+			pool.taken.remove(port - t.StartPort)
 		}
 	}
-	return -1, ErrorPortPoolExhausted
+}
+
+// PoolStatus describes the ports of a pool in the range of a tracker.
+type PoolStatus struct {
+	StartPort   int              `json:"start_port"`
+	Capacity    int              `json:"capacity"`
+	Acquired    int              `json:"acquired"`
+	Quarantined int              `json:"quarantined"`
+	Free        int              `json:"free"`
+	Allocations map[string][]int `json:"allocations"`
+}
+
+// Status counts the ports of the range that are acquired, quarantined or
+// free. Allocations lists the ports of every handle, including the ports
+// outside the range that containers asked for explicitly. A tracker without a
+// range counts the ports of the range recorded in the pool, which is empty
+// until the pool has been written by a tracker with a range.
+func (t *Tracker) Status(pool *Pool) PoolStatus {
+	if t.Capacity == 0 {
+		t = &Tracker{
+			StartPort:  pool.StartPort,
+			Capacity:   pool.Capacity,
+			Quarantine: t.Quarantine,
+		}
+	}
+
+	status := PoolStatus{
+		StartPort:   t.StartPort,
+		Capacity:    t.Capacity,
+		Allocations: make(map[string][]int),
+	}
+	for port, handle := range pool.AcquiredPorts {
+		status.Allocations[handle] = append(status.Allocations[handle], port)
+		if t.InRange(port) {
+			status.Acquired++
+		}
+	}
+	for _, ports := range status.Allocations {
+		sort.Ints(ports)
+	}
+
+	now := time.Now()
+	for port, releasedAt := range pool.ReleasedPorts {
+		if t.InRange(port) && now.Sub(releasedAt) < t.Quarantine {
+			if _, acquired := pool.AcquiredPorts[port]; !acquired {
+				status.Quarantined++
+			}
+		}
+	}
+
+	status.Free = t.Capacity - status.Acquired - status.Quarantined
+	return status
 }
 
 func (t *Tracker) ReleaseAll(pool *Pool, handle string) error {
+	t.recordRange(pool)
+	if pool.ReleasedPorts == nil {
+		pool.ReleasedPorts = make(map[int]time.Time)
+	}
+	now := time.Now()
 	for port, h := range pool.AcquiredPorts {
 		if h == handle {
 			delete(pool.AcquiredPorts, port)
+			// the quarantine is up to the tracker acquiring the port again,
+			// so the port stays taken until then
+			pool.ReleasedPorts[port] = now
 		}
 	}
 	return nil
 }
-
-func contains(list map[int]string, candidate int) bool {
-	_, ok := list[candidate]
-	return ok
-}
@@ -3,6 +3,7 @@ package port_allocator_test
 import (
 	"encoding/json"
 	"garden-external-networker/port_allocator"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -30,6 +31,13 @@ var _ = Describe("Tracker", func() {
 			Expect(pool.AcquiredPorts).To(Equal(map[int]string{newPort: "some-handle"}))
 		})
 
+		It("records the range in the pool", func() {
+			_, err := tracker.AcquireOne(pool, "some-handle")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pool.StartPort).To(Equal(100))
+			Expect(pool.Capacity).To(Equal(10))
+		})
+
 		Context("when acquiring multiple ports", func() {
 			It("gives unique ports", func() {
 				firstPort, err := tracker.AcquireOne(pool, "some-handle")
@@ -88,6 +96,270 @@ var _ = Describe("Tracker", func() {
 
 				Expect(runtime.Seconds()).To(BeNumerically("<", 5), "Acquiring a port shouldn't take too long.")
 			}, 10)
+
+			Measure("should notice that a full pool is exhausted quickly", func(b Benchmarker) {
+				tracker.Capacity = 4000
+				for i := 0; i < 4000; i++ {
+					_, err := tracker.AcquireOne(pool, "some-handle")
+					Expect(err).NotTo(HaveOccurred())
+				}
+
+				runtime := b.Time("runtime", func() {
+					for i := 0; i < 1000; i++ {
+						_, err := tracker.AcquireOne(pool, "some-handle")
+						Expect(err).To(Equal(port_allocator.ErrorPortPoolExhausted))
+					}
+				})
+				Expect(runtime.Seconds()).To(BeNumerically("<", 5), "Failing to acquire a port shouldn't take too long.")
+			}, 10)
+		})
+	})
+
+	Describe("AcquireRange", func() {
+		It("reserves consecutive ports and returns the first", func() {
+			port, err := tracker.AcquireRange(pool, "some-handle", 3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(port).To(Equal(100))
+			Expect(pool.AcquiredPorts).To(Equal(map[int]string{
+				100: "some-handle",
+				101: "some-handle",
+				102: "some-handle",
+			}))
+		})
+
+		Context("when the free ports are not consecutive", func() {
+			BeforeEach(func() {
+				pool.AcquiredPorts = map[int]string{
+					101: "some-handle",
+					104: "some-handle",
+				}
+			})
+
+			It("skips the gaps that are too small", func() {
+				port, err := tracker.AcquireRange(pool, "some-handle2", 3)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(port).To(Equal(105))
+			})
+		})
+
+		Context("when the only free run would wrap around the range", func() {
+			BeforeEach(func() {
+				tracker.Strategy = port_allocator.StrategyRoundRobin
+				pool.Cursor = 109
+				pool.AcquiredPorts = map[int]string{
+					102: "some-handle",
+					103: "some-handle",
+					104: "some-handle",
+					105: "some-handle",
+					106: "some-handle",
+					107: "some-handle",
+				}
+			})
+
+			It("looks for a run from the start of the range", func() {
+				port, err := tracker.AcquireRange(pool, "some-handle2", 2)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(port).To(Equal(100))
+				Expect(pool.Cursor).To(Equal(102))
+			})
+		})
+
+		Context("when there is no run of free ports long enough", func() {
+			BeforeEach(func() {
+				pool.AcquiredPorts = map[int]string{
+					102: "some-handle",
+					105: "some-handle",
+					108: "some-handle",
+				}
+			})
+
+			It("returns a useful error", func() {
+				_, err := tracker.AcquireRange(pool, "some-handle", 3)
+				Expect(err).To(Equal(port_allocator.ErrorPortPoolExhausted))
+				Expect(pool.AcquiredPorts).To(HaveLen(3))
+			})
+		})
+
+		Context("when the count is not positive", func() {
+			It("returns an error", func() {
+				_, err := tracker.AcquireRange(pool, "some-handle", 0)
+				Expect(err).To(MatchError("invalid port count: 0"))
+			})
+		})
+	})
+
+	Describe("strategies", func() {
+		Context("when the strategy is round-robin", func() {
+			BeforeEach(func() {
+				tracker.Strategy = port_allocator.StrategyRoundRobin
+			})
+
+			It("does not hand out a released port before the others", func() {
+				first, err := tracker.AcquireOne(pool, "some-handle")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tracker.ReleaseAll(pool, "some-handle")).To(Succeed())
+
+				second, err := tracker.AcquireOne(pool, "some-handle2")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(first).To(Equal(100))
+				Expect(second).To(Equal(101))
+				Expect(pool.Cursor).To(Equal(102))
+			})
+
+			It("wraps around at the end of the range", func() {
+				pool.Cursor = 109
+				pool.AcquiredPorts = map[int]string{100: "some-handle"}
+
+				port, err := tracker.AcquireOne(pool, "some-handle")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(port).To(Equal(109))
+
+				port, err = tracker.AcquireOne(pool, "some-handle")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(port).To(Equal(101))
+			})
+
+			It("starts at the beginning of the range when the cursor is outside of it", func() {
+				pool.Cursor = 5000
+
+				port, err := tracker.AcquireOne(pool, "some-handle")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(port).To(Equal(100))
+			})
+		})
+
+		Context("when the strategy is random", func() {
+			BeforeEach(func() {
+				tracker.Strategy = port_allocator.StrategyRandom
+			})
+
+			It("hands out every port of the range once", func() {
+				ports := map[int]bool{}
+				for i := 0; i < tracker.Capacity; i++ {
+					port, err := tracker.AcquireOne(pool, "some-handle")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(port).To(BeInRange(100, 110))
+					ports[port] = true
+				}
+				Expect(ports).To(HaveLen(tracker.Capacity))
+
+				_, err := tracker.AcquireOne(pool, "some-handle")
+				Expect(err).To(Equal(port_allocator.ErrorPortPoolExhausted))
+			})
+		})
+
+		It("knows the valid strategies", func() {
+			Expect(port_allocator.ValidStrategy("")).To(BeTrue())
+			Expect(port_allocator.ValidStrategy("lowest-free")).To(BeTrue())
+			Expect(port_allocator.ValidStrategy("round-robin")).To(BeTrue())
+			Expect(port_allocator.ValidStrategy("random")).To(BeTrue())
+			Expect(port_allocator.ValidStrategy("banana")).To(BeFalse())
+		})
+	})
+
+	Describe("quarantine", func() {
+		BeforeEach(func() {
+			tracker.Quarantine = time.Hour
+			tracker.Capacity = 2
+		})
+
+		It("records when ports are released", func() {
+			pool.AcquiredPorts = map[int]string{100: "some-handle"}
+			Expect(tracker.ReleaseAll(pool, "some-handle")).To(Succeed())
+
+			Expect(pool.AcquiredPorts).To(BeEmpty())
+			Expect(pool.ReleasedPorts).To(HaveKey(100))
+			Expect(pool.ReleasedPorts[100]).To(BeTemporally("~", time.Now(), time.Second))
+		})
+
+		It("does not hand out ports released during the quarantine", func() {
+			pool.ReleasedPorts = map[int]time.Time{100: time.Now().Add(-time.Minute)}
+
+			port, err := tracker.AcquireOne(pool, "some-handle")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(port).To(Equal(101))
+
+			_, err = tracker.AcquireOne(pool, "some-handle")
+			Expect(err).To(Equal(port_allocator.ErrorPortPoolExhausted))
+		})
+
+		It("hands out ports again once their quarantine is over", func() {
+			pool.ReleasedPorts = map[int]time.Time{100: time.Now().Add(-2 * time.Hour)}
+
+			port, err := tracker.AcquireOne(pool, "some-handle")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(port).To(Equal(100))
+			Expect(pool.ReleasedPorts).To(BeEmpty())
+		})
+
+		It("hands out ports whose quarantine ends between acquisitions", func() {
+			tracker.Quarantine = 50 * time.Millisecond
+			_, err := tracker.AcquireOne(pool, "some-handle")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = tracker.AcquireOne(pool, "some-handle2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tracker.ReleaseAll(pool, "some-handle")).To(Succeed())
+
+			_, err = tracker.AcquireOne(pool, "some-handle3")
+			Expect(err).To(Equal(port_allocator.ErrorPortPoolExhausted))
+
+			Eventually(func() error {
+				_, err := tracker.AcquireOne(pool, "some-handle3")
+				return err
+			}).Should(Succeed())
+			Expect(pool.AcquiredPorts).To(Equal(map[int]string{100: "some-handle3", 101: "some-handle2"}))
+		})
+	})
+
+	Describe("Status", func() {
+		BeforeEach(func() {
+			tracker.Quarantine = time.Hour
+			pool.AcquiredPorts = map[int]string{
+				101:  "some-handle",
+				100:  "some-handle",
+				102:  "some-handle2",
+				5000: "some-handle2",
+			}
+			pool.ReleasedPorts = map[int]time.Time{
+				103: time.Now().Add(-time.Minute),
+				104: time.Now().Add(-2 * time.Hour),
+			}
+		})
+
+		It("counts the acquired, quarantined and free ports of the range", func() {
+			Expect(tracker.Status(pool)).To(Equal(port_allocator.PoolStatus{
+				StartPort:   100,
+				Capacity:    10,
+				Acquired:    3,
+				Quarantined: 1,
+				Free:        6,
+				Allocations: map[string][]int{
+					"some-handle":  {100, 101},
+					"some-handle2": {102, 5000},
+				},
+			}))
+		})
+
+		Context("when the tracker has no range", func() {
+			BeforeEach(func() {
+				tracker = &port_allocator.Tracker{}
+			})
+
+			It("counts the ports of the range recorded in the pool", func() {
+				pool.StartPort = 100
+				pool.Capacity = 20
+
+				status := tracker.Status(pool)
+				Expect(status.StartPort).To(Equal(100))
+				Expect(status.Capacity).To(Equal(20))
+				Expect(status.Acquired).To(Equal(3))
+			})
+
+			It("reports an empty range until one is recorded", func() {
+				status := tracker.Status(pool)
+				Expect(status.Capacity).To(Equal(0))
+				Expect(status.Acquired).To(Equal(0))
+			})
 		})
 	})
 
@@ -107,6 +379,48 @@ var _ = Describe("Tracker", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(reacquired).To(Equal(100))
 		})
+
+		It("keeps track of the taken ports when trackers with different ranges share the pool", func() {
+			other := &port_allocator.Tracker{StartPort: 105, Capacity: 10}
+			for i := 0; i < 5; i++ {
+				_, err := other.AcquireOne(pool, "other-handle")
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			for i := 0; i < 5; i++ {
+				port, err := tracker.AcquireOne(pool, "some-handle")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(port).To(Equal(100 + i))
+			}
+			_, err := tracker.AcquireOne(pool, "some-handle")
+			Expect(err).To(Equal(port_allocator.ErrorPortPoolExhausted))
+
+			port, err := other.AcquireOne(pool, "other-handle")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(port).To(Equal(110))
+		})
+
+		It("keeps track of the taken ports when the pool is decoded again", func() {
+			_, err := tracker.AcquireOne(pool, "some-handle")
+			Expect(err).NotTo(HaveOccurred())
+
+			bytes, err := json.Marshal(pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(bytes, pool)).To(Succeed())
+
+			port, err := tracker.AcquireOne(pool, "some-handle")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(port).To(Equal(101))
+		})
+
+		It("keeps the recorded range when released by a tracker without one", func() {
+			_, err := tracker.AcquireOne(pool, "some-handle")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect((&port_allocator.Tracker{}).ReleaseAll(pool, "some-handle")).To(Succeed())
+			Expect(pool.StartPort).To(Equal(100))
+			Expect(pool.Capacity).To(Equal(10))
+		})
 	})
 
 	Describe("InRange", func() {
@@ -136,6 +450,47 @@ var _ = Describe("Tracker", func() {
 			Expect(newPool.AcquiredPorts).To(Equal(pool.AcquiredPorts))
 		})
 
+		It("round-trips the quarantined ports and the cursor", func() {
+			releasedAt := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+			pool.AcquiredPorts = map[int]string{42: "some-handle"}
+			pool.ReleasedPorts = map[int]time.Time{105: releasedAt}
+			pool.Cursor = 106
+
+			bytes, err := json.Marshal(pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bytes).To(MatchJSON(`{
+				"acquired_ports": { "some-handle": [ 42 ] },
+				"released_ports": { "105": "2017-06-01T12:00:00Z" },
+				"cursor": 106
+			}`))
+
+			var newPool port_allocator.Pool
+			Expect(json.Unmarshal(bytes, &newPool)).To(Succeed())
+			Expect(newPool.ReleasedPorts).To(HaveLen(1))
+			Expect(newPool.ReleasedPorts[105].Equal(releasedAt)).To(BeTrue())
+			Expect(newPool.Cursor).To(Equal(106))
+		})
+
+		It("round-trips the recorded range", func() {
+			pool.StartPort = 61000
+			pool.Capacity = 5000
+
+			bytes, err := json.Marshal(pool)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bytes).To(MatchJSON(`{ "acquired_ports": {}, "start_port": 61000, "capacity": 5000 }`))
+
+			var newPool port_allocator.Pool
+			Expect(json.Unmarshal(bytes, &newPool)).To(Succeed())
+			Expect(newPool.StartPort).To(Equal(61000))
+			Expect(newPool.Capacity).To(Equal(5000))
+		})
+
+		It("returns an error when a released port is not a number", func() {
+			var newPool port_allocator.Pool
+			err := json.Unmarshal([]byte(`{ "released_ports": { "banana": "2017-06-01T12:00:00Z" } }`), &newPool)
+			Expect(err).To(MatchError("invalid released port: banana"))
+		})
+
 		It("marshals as a map from container handle to list of allocated ports", func() {
 			pool.AcquiredPorts = map[int]string{
 				42:  "some-handle",
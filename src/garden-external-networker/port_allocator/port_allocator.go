@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"lib/filelock"
 	"lib/serial"
+	"sort"
 )
 
 //go:generate counterfeiter -o ../fakes/tracker.go --fake-name Tracker . tracker
 type tracker interface {
 	AcquireOne(pool *Pool, handle string) (int, error)
+	AcquireRange(pool *Pool, handle string, count int) (int, error)
 	ReleaseAll(pool *Pool, handle string) error
 	InRange(port int) bool
+	Status(pool *Pool) PoolStatus
 }
 
 type PortAllocator struct {
@@ -21,12 +24,20 @@ type PortAllocator struct {
 }
 
 func (p *PortAllocator) AllocatePort(handle string, port int) (int, error) {
+	return p.AllocatePorts(handle, port, 1)
+}
+
+// AllocatePorts allocates count consecutive ports and returns the first of
+// them. A port that is given is used as is, as long as the range it starts
+// does not overlap the allocation range.
+func (p *PortAllocator) AllocatePorts(handle string, port, count int) (int, error) {
 	if port != 0 {
-		if p.Tracker.InRange(port) {
-			return -1, errors.New("cannot specify port from allocation range")
-		} else {
-			return port, nil
+		for i := 0; i < count; i++ {
+			if p.Tracker.InRange(port + i) {
+				return -1, errors.New("cannot specify port from allocation range")
+			}
 		}
+		return port, nil
 	}
 
 	file, err := p.Locker.Open()
@@ -41,7 +52,7 @@ func (p *PortAllocator) AllocatePort(handle string, port int) (int, error) {
 		return -1, fmt.Errorf("decoding state file: %s", err)
 	}
 
-	newPort, err := p.Tracker.AcquireOne(pool, handle)
+	newPort, err := p.Tracker.AcquireRange(pool, handle, count)
 	if err != nil {
 		return -1, fmt.Errorf("acquire port: %s", err)
 	}
@@ -78,3 +89,48 @@ func (p *PortAllocator) ReleaseAllPorts(handle string) error {
 
 	return nil
 }
+
+// AllocatedHandles lists the handles that hold at least one port in the pool.
+func (p *PortAllocator) AllocatedHandles() ([]string, error) {
+	file, err := p.Locker.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open lock: %s", err)
+	}
+	defer file.Close() // defer not tested
+
+	pool := &Pool{}
+	err = p.Serializer.DecodeAll(file, pool)
+	if err != nil {
+		return nil, fmt.Errorf("decoding state file: %s", err)
+	}
+
+	seen := make(map[string]struct{})
+	handles := []string{}
+	for _, handle := range pool.AcquiredPorts {
+		if _, ok := seen[handle]; ok {
+			continue
+		}
+		seen[handle] = struct{}{}
+		handles = append(handles, handle)
+	}
+	sort.Strings(handles)
+
+	return handles, nil
+}
+
+// Status describes the ports of the pool.
+func (p *PortAllocator) Status() (PoolStatus, error) {
+	file, err := p.Locker.Open()
+	if err != nil {
+		return PoolStatus{}, fmt.Errorf("open lock: %s", err)
+	}
+	defer file.Close() // defer not tested
+
+	pool := &Pool{}
+	err = p.Serializer.DecodeAll(file, pool)
+	if err != nil {
+		return PoolStatus{}, fmt.Errorf("decoding state file: %s", err)
+	}
+
+	return p.Tracker.Status(pool), nil
+}
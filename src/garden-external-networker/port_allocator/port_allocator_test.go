@@ -4,6 +4,7 @@ import (
 	"errors"
 	"garden-external-networker/fakes"
 	"garden-external-networker/port_allocator"
+	"io"
 	"io/ioutil"
 	libfakes "lib/fakes"
 	"os"
@@ -25,7 +26,7 @@ var _ = Describe("PortAllocator", func() {
 		tracker = &fakes.Tracker{}
 		locker = &libfakes.FileLocker{}
 		serializer.DecodeAllReturns(nil)
-		tracker.AcquireOneReturns(111, nil)
+		tracker.AcquireRangeReturns(111, nil)
 
 		portAllocator = &port_allocator.PortAllocator{
 			Tracker:    tracker,
@@ -54,12 +55,13 @@ var _ = Describe("PortAllocator", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(serializer.DecodeAllCallCount()).To(Equal(1))
-				Expect(tracker.AcquireOneCallCount()).To(Equal(1))
+				Expect(tracker.AcquireRangeCallCount()).To(Equal(1))
 
 				_, pool := serializer.DecodeAllArgsForCall(0)
-				receivedPool, receivedHandle := tracker.AcquireOneArgsForCall(0)
+				receivedPool, receivedHandle, count := tracker.AcquireRangeArgsForCall(0)
 				Expect(receivedPool).To(Equal(pool))
 				Expect(receivedHandle).To(Equal("some-handle"))
+				Expect(count).To(Equal(1))
 			})
 		})
 
@@ -71,7 +73,7 @@ var _ = Describe("PortAllocator", func() {
 				port, err := portAllocator.AllocatePort("some-handle", 42)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(tracker.AcquireOneCallCount()).To(Equal(0))
+				Expect(tracker.AcquireRangeCallCount()).To(Equal(0))
 				Expect(port).To(Equal(42))
 			})
 		})
@@ -140,7 +142,7 @@ var _ = Describe("PortAllocator", func() {
 
 		Context("when the tracker cannot acquire a port", func() {
 			BeforeEach(func() {
-				tracker.AcquireOneReturns(0, errors.New("turnip"))
+				tracker.AcquireRangeReturns(0, errors.New("turnip"))
 			})
 			It("wraps and returns the error", func() {
 				_, err := portAllocator.AllocatePort("some-handle", 0)
@@ -159,6 +161,34 @@ var _ = Describe("PortAllocator", func() {
 		})
 	})
 
+	Describe("AllocatePorts", func() {
+		Context("when the passed in port is 0", func() {
+			It("acquires a range of ports from the pool", func() {
+				port, err := portAllocator.AllocatePorts("some-handle", 0, 5)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(port).To(Equal(111))
+
+				Expect(tracker.AcquireRangeCallCount()).To(Equal(1))
+				_, receivedHandle, count := tracker.AcquireRangeArgsForCall(0)
+				Expect(receivedHandle).To(Equal("some-handle"))
+				Expect(count).To(Equal(5))
+			})
+		})
+
+		Context("when the passed in ports run into the allocation range", func() {
+			BeforeEach(func() {
+				tracker.InRangeStub = func(port int) bool {
+					return port == 46
+				}
+			})
+			It("returns an error", func() {
+				_, err := portAllocator.AllocatePorts("some-handle", 42, 5)
+				Expect(err).To(MatchError(errors.New("cannot specify port from allocation range")))
+				Expect(tracker.AcquireRangeCallCount()).To(Equal(0))
+			})
+		})
+	})
+
 	Describe("ReleaseAllPorts", func() {
 		It("deserializes the pool from the locked file", func() {
 			err := portAllocator.ReleaseAllPorts("some-handle")
@@ -237,4 +267,95 @@ var _ = Describe("PortAllocator", func() {
 		})
 
 	})
+
+	Describe("AllocatedHandles", func() {
+		BeforeEach(func() {
+			serializer.DecodeAllStub = func(file io.ReadSeeker, outData interface{}) error {
+				pool := outData.(*port_allocator.Pool)
+				pool.AcquiredPorts = map[int]string{
+					111: "some-handle",
+					112: "some-other-handle",
+					113: "some-handle",
+				}
+				return nil
+			}
+		})
+
+		It("returns each handle that holds a port once", func() {
+			handles, err := portAllocator.AllocatedHandles()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(handles).To(Equal([]string{"some-handle", "some-other-handle"}))
+
+			file, _ := serializer.DecodeAllArgsForCall(0)
+			Expect(file).To(Equal(lockedFile))
+		})
+
+		It("does not rewrite the state file", func() {
+			_, err := portAllocator.AllocatedHandles()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(serializer.EncodeAndOverwriteCallCount()).To(Equal(0))
+		})
+
+		Context("when the locker fails to open the file", func() {
+			BeforeEach(func() {
+				locker.OpenReturns(nil, errors.New("potato"))
+			})
+			It("wraps and returns the error", func() {
+				_, err := portAllocator.AllocatedHandles()
+				Expect(err).To(MatchError("open lock: potato"))
+			})
+		})
+
+		Context("when the serializer fails to decode", func() {
+			BeforeEach(func() {
+				serializer.DecodeAllStub = nil
+				serializer.DecodeAllReturns(errors.New("potato"))
+			})
+			It("wraps and returns the error", func() {
+				_, err := portAllocator.AllocatedHandles()
+				Expect(err).To(MatchError("decoding state file: potato"))
+			})
+		})
+	})
+
+	Describe("Status", func() {
+		BeforeEach(func() {
+			serializer.DecodeAllStub = func(file io.ReadSeeker, outData interface{}) error {
+				pool := outData.(*port_allocator.Pool)
+				pool.AcquiredPorts = map[int]string{111: "some-handle"}
+				return nil
+			}
+			tracker.StatusReturns(port_allocator.PoolStatus{Capacity: 10, Acquired: 1, Free: 9})
+		})
+
+		It("returns the status of the pool from the tracker", func() {
+			status, err := portAllocator.Status()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(port_allocator.PoolStatus{Capacity: 10, Acquired: 1, Free: 9}))
+
+			Expect(tracker.StatusArgsForCall(0).AcquiredPorts).To(Equal(map[int]string{111: "some-handle"}))
+			Expect(serializer.EncodeAndOverwriteCallCount()).To(Equal(0))
+		})
+
+		Context("when the locker fails to open the file", func() {
+			BeforeEach(func() {
+				locker.OpenReturns(nil, errors.New("potato"))
+			})
+			It("wraps and returns the error", func() {
+				_, err := portAllocator.Status()
+				Expect(err).To(MatchError("open lock: potato"))
+			})
+		})
+
+		Context("when the serializer fails to decode", func() {
+			BeforeEach(func() {
+				serializer.DecodeAllStub = nil
+				serializer.DecodeAllReturns(errors.New("potato"))
+			})
+			It("wraps and returns the error", func() {
+				_, err := portAllocator.Status()
+				Expect(err).To(MatchError("decoding state file: potato"))
+			})
+		})
+	})
 })
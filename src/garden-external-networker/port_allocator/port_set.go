@@ -0,0 +1,102 @@
+package port_allocator
+
+const wordSize = 64
+
+// portSet is a bitmap of the taken offsets of a port range. Looking for a
+// free port skips a word of taken ports at a time, and a full range is
+// recognized without looking at all.
+type portSet struct {
+	words    []uint64
+	capacity int
+	taken    int
+}
+
+func newPortSet(capacity int) *portSet {
+	s := &portSet{
+		words:    make([]uint64, (capacity+wordSize-1)/wordSize),
+		capacity: capacity,
+	}
+	// the bits past the end of the range are never free
+	if extra := capacity % wordSize; extra != 0 {
+		s.words[len(s.words)-1] = ^uint64(0) << uint(extra)
+	}
+	return s
+}
+
+func (s *portSet) add(offset int) {
+	bit := uint64(1) << uint(offset%wordSize)
+	if s.words[offset/wordSize]&bit != 0 {
+		return
+	}
+	s.words[offset/wordSize] |= bit
+	s.taken++
+}
+
+func (s *portSet) remove(offset int) {
+	bit := uint64(1) << uint(offset%wordSize)
+	if s.words[offset/wordSize]&bit == 0 {
+		return
+	}
+	s.words[offset/wordSize] &^= bit
+	s.taken--
+}
+
+// nextFree returns the first free offset at or after start, wrapping around
+// at the end of the range.
+func (s *portSet) nextFree(start int) (int, bool) {
+	if s.taken >= s.capacity {
+		return -1, false
+	}
+
+	w := start / wordSize
+	// the bits before start are looked at last, after wrapping around
+	word := s.words[w] | (uint64(1)<<uint(start%wordSize) - 1)
+	for i := 0; i <= len(s.words); i++ {
+		if word != ^uint64(0) {
+			return w*wordSize + firstZero(word), true
+		}
+		w = (w + 1) % len(s.words)
+		word = s.words[w]
+	}
+	return -1, false // not tested, a range that is not full has a free bit
+}
+
+// nextFreeRun returns the first offset at or after start that begins count
+// free offsets, wrapping around at the end of the range. A run does not wrap
+// around itself, since its ports must be consecutive.
+func (s *portSet) nextFreeRun(start, count int) (int, bool) {
+	if count == 1 {
+		return s.nextFree(start)
+	}
+	if s.capacity-s.taken < count {
+		return -1, false
+	}
+
+	for _, bounds := range [][2]int{{start, s.capacity}, {0, start}} {
+		run := 0
+		for offset := bounds[0]; offset < bounds[1]+count-1 && offset < s.capacity; offset++ {
+			if s.has(offset) {
+				run = 0
+				continue
+			}
+			run++
+			if run == count {
+				return offset - count + 1, true
+			}
+		}
+	}
+	return -1, false
+}
+
+func (s *portSet) has(offset int) bool {
+	return s.words[offset/wordSize]&(uint64(1)<<uint(offset%wordSize)) != 0
+}
+
+func firstZero(word uint64) int {
+	i := 0
+	for word&1 != 0 {
+		word >>= 1
+		i++
+	}
+	return i
+}
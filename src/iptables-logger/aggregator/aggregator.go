@@ -0,0 +1,293 @@
+package aggregator
+
+import (
+	"iptables-logger/merger"
+	"iptables-logger/repository"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	ModeRaw       = "raw"
+	ModeAggregate = "aggregate"
+)
+
+// ValidMode reports whether mode is known. The empty mode is the raw one.
+func ValidMode(mode string) bool {
+	switch mode {
+	case "", ModeRaw, ModeAggregate:
+		return true
+	}
+	return false
+}
+
+//go:generate counterfeiter -o ../fakes/event_sink.go --fake-name EventSink . eventSink
+type eventSink interface {
+	Write(merger.IPTablesLogData) error
+}
+
+// Tuple identifies the flows that are counted together. The app GUIDs are
+// empty when the app is not known, e.g. the remote app of events that are
+// not enriched. Ingress flows from an unknown app are told apart by their
+// SourceIP instead.
+type Tuple struct {
+	Direction          string
+	Allowed            bool
+	SourceAppGUID      string
+	SourceIP           string
+	DestinationAppGUID string
+	Protocol           string
+	Port               int
+}
+
+// Talker is a source app, or the source IP when the app is not known, and
+// the number of flows it started in a window.
+type Talker struct {
+	SourceAppGUID string `json:"source_app_guid,omitempty"`
+	SourceIP      string `json:"source_ip,omitempty"`
+	Flows         int    `json:"flows"`
+}
+
+type flows struct {
+	count     int
+	host      string
+	container repository.Container
+}
+
+// Aggregator counts the events written to it per tuple, and writes a summary
+// event for each tuple to its Sinks at the end of every Window. It ranks the
+// source apps by their flows in the window, keeping the TopN of them.
+type Aggregator struct {
+	Sinks  []eventSink
+	Logger lager.Logger
+	Window time.Duration
+	TopN   int
+	Now    func() time.Time
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	flows       map[Tuple]*flows
+	topTalkers  []Talker
+}
+
+func (a *Aggregator) now() time.Time {
+	if a.Now == nil {
+		return time.Now()
+	}
+	return a.Now()
+}
+
+func (a *Aggregator) Write(data merger.IPTablesLogData) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.flows == nil {
+		a.flows = make(map[Tuple]*flows)
+	}
+	if a.windowStart.IsZero() {
+		a.windowStart = a.now()
+	}
+	tuple := tupleOf(data.Event)
+	f, ok := a.flows[tuple]
+	if !ok {
+		f = &flows{
+			host: data.Event.Host,
+			// the containers of an app are counted together
+			container: repository.Container{
+				AppID:   data.Event.Container.AppID,
+				SpaceID: data.Event.Container.SpaceID,
+				OrgID:   data.Event.Container.OrgID,
+			},
+		}
+		a.flows[tuple] = f
+	}
+	f.count++
+	return nil
+}
+
+func tupleOf(event merger.Event) Tuple {
+	var remoteAppGUID string
+	if event.Remote != nil {
+		remoteAppGUID = event.Remote.AppGUID
+	}
+	tuple := Tuple{
+		Direction:          event.Direction,
+		Allowed:            event.Allowed,
+		SourceAppGUID:      event.Container.AppID,
+		DestinationAppGUID: remoteAppGUID,
+		Protocol:           event.Protocol,
+		Port:               event.DestinationPort,
+	}
+	if event.Direction == "ingress" {
+		tuple.SourceAppGUID, tuple.DestinationAppGUID = remoteAppGUID, event.Container.AppID
+		if remoteAppGUID == "" {
+			tuple.SourceIP = event.SourceIP
+		}
+	}
+	return tuple
+}
+
+func (t Tuple) less(other Tuple) bool {
+	if t.SourceAppGUID != other.SourceAppGUID {
+		return t.SourceAppGUID < other.SourceAppGUID
+	}
+	if t.SourceIP != other.SourceIP {
+		return t.SourceIP < other.SourceIP
+	}
+	if t.DestinationAppGUID != other.DestinationAppGUID {
+		return t.DestinationAppGUID < other.DestinationAppGUID
+	}
+	if t.Direction != other.Direction {
+		return t.Direction < other.Direction
+	}
+	if t.Protocol != other.Protocol {
+		return t.Protocol < other.Protocol
+	}
+	if t.Port != other.Port {
+		return t.Port < other.Port
+	}
+	return t.Allowed && !other.Allowed
+}
+
+func (a *Aggregator) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	a.mutex.Lock()
+	a.windowStart = a.now()
+	a.mutex.Unlock()
+
+	ticker := time.NewTicker(a.Window)
+	defer ticker.Stop()
+
+	close(ready)
+	for {
+		select {
+		case <-signals:
+			a.Flush()
+			return nil
+		case <-ticker.C:
+			a.Flush()
+		}
+	}
+}
+
+// Flush writes a summary event for each tuple counted since the last flush,
+// busiest first, and ranks the source apps of the window.
+func (a *Aggregator) Flush() {
+	a.mutex.Lock()
+	counted := a.flows
+	windowStart, windowEnd := a.windowStart, a.now()
+	a.flows = make(map[Tuple]*flows)
+	a.windowStart = windowEnd
+	a.topTalkers = rankTalkers(counted, a.TopN)
+	topTalkers := a.topTalkers
+	a.mutex.Unlock()
+
+	tuples := make([]Tuple, 0, len(counted))
+	for tuple := range counted {
+		tuples = append(tuples, tuple)
+	}
+	sort.Slice(tuples, func(i, j int) bool {
+		if counted[tuples[i]].count != counted[tuples[j]].count {
+			return counted[tuples[i]].count > counted[tuples[j]].count
+		}
+		return tuples[i].less(tuples[j])
+	})
+
+	for _, tuple := range tuples {
+		summary := newSummary(tuple, counted[tuple], windowStart, windowEnd)
+		for _, sink := range a.Sinks {
+			if err := sink.Write(summary); err != nil {
+				a.Logger.Error("write-summary", err)
+			}
+		}
+	}
+
+	if len(topTalkers) > 0 {
+		a.Logger.Info("top-talkers", lager.Data{"talkers": topTalkers})
+	}
+}
+
+// TopTalkers are the busiest source apps of the last window, busiest first.
+func (a *Aggregator) TopTalkers() []Talker {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.topTalkers
+}
+
+func rankTalkers(counted map[Tuple]*flows, n int) []Talker {
+	bySource := make(map[Talker]int)
+	for tuple, f := range counted {
+		if tuple.SourceAppGUID != "" || tuple.SourceIP != "" {
+			bySource[Talker{SourceAppGUID: tuple.SourceAppGUID, SourceIP: tuple.SourceIP}] += f.count
+		}
+	}
+
+	talkers := make([]Talker, 0, len(bySource))
+	for source, count := range bySource {
+		source.Flows = count
+		talkers = append(talkers, source)
+	}
+	sort.Slice(talkers, func(i, j int) bool {
+		if talkers[i].Flows != talkers[j].Flows {
+			return talkers[i].Flows > talkers[j].Flows
+		}
+		if talkers[i].SourceAppGUID != talkers[j].SourceAppGUID {
+			return talkers[i].SourceAppGUID < talkers[j].SourceAppGUID
+		}
+		return talkers[i].SourceIP < talkers[j].SourceIP
+	})
+	if len(talkers) > n {
+		talkers = talkers[:n]
+	}
+	return talkers
+}
+
+func newSummary(tuple Tuple, f *flows, windowStart, windowEnd time.Time) merger.IPTablesLogData {
+	message := tuple.Direction
+	if tuple.Allowed {
+		message += "-allowed-summary"
+	} else {
+		message += "-denied-summary"
+	}
+
+	event := merger.Event{
+		Timestamp:       windowEnd,
+		Host:            f.host,
+		Name:            message,
+		Direction:       tuple.Direction,
+		Allowed:         tuple.Allowed,
+		Protocol:        tuple.Protocol,
+		DestinationPort: tuple.Port,
+		Container:       f.container,
+		Count:           f.count,
+		WindowStart:     &windowStart,
+	}
+	remoteAppGUID := tuple.DestinationAppGUID
+	if tuple.Direction == "ingress" {
+		remoteAppGUID = tuple.SourceAppGUID
+	}
+	if remoteAppGUID != "" || tuple.SourceIP != "" {
+		event.Remote = &merger.Remote{IP: tuple.SourceIP, AppGUID: remoteAppGUID}
+	}
+
+	data := lager.Data{
+		"source_app_guid":      tuple.SourceAppGUID,
+		"destination_app_guid": tuple.DestinationAppGUID,
+		"protocol":             tuple.Protocol,
+		"dst_port":             tuple.Port,
+		"count":                f.count,
+		"window_start":         windowStart,
+		"window_end":           windowEnd,
+	}
+	if tuple.SourceIP != "" {
+		data["src_ip"] = tuple.SourceIP
+	}
+	return merger.IPTablesLogData{
+		Message: message,
+		Data:    data,
+		Event:   event,
+	}
+}
@@ -0,0 +1,229 @@
+package aggregator_test
+
+import (
+	"errors"
+	"iptables-logger/aggregator"
+	"iptables-logger/fakes"
+	"iptables-logger/merger"
+	"iptables-logger/repository"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Aggregator", func() {
+	var (
+		flowAggregator *aggregator.Aggregator
+		fakeSink       *fakes.EventSink
+		logger         *lagertest.TestLogger
+		windowStart    time.Time
+		now            time.Time
+	)
+
+	newData := func(direction, remoteAppGUID, localAppGUID string, port int) merger.IPTablesLogData {
+		return merger.IPTablesLogData{
+			Message: direction + "-allowed",
+			Event: merger.Event{
+				Host:            "some-host",
+				Name:            direction + "-allowed",
+				Direction:       direction,
+				Allowed:         true,
+				Protocol:        "TCP",
+				SourceIP:        "10.255.15.7",
+				SourcePort:      60012,
+				DestinationIP:   "10.255.15.13",
+				DestinationPort: port,
+				Remote:          &merger.Remote{IP: "10.255.15.7", AppGUID: remoteAppGUID},
+				Container: repository.Container{
+					Handle:  "some-handle",
+					AppID:   localAppGUID,
+					SpaceID: "some-space-guid",
+					OrgID:   "some-org-guid",
+				},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		fakeSink = &fakes.EventSink{}
+		logger = lagertest.NewTestLogger("test")
+		windowStart = time.Date(2017, time.May, 3, 23, 35, 0, 0, time.UTC)
+		now = windowStart
+
+		flowAggregator = &aggregator.Aggregator{
+			Logger: logger,
+			Window: time.Minute,
+			TopN:   2,
+			Now:    func() time.Time { return now },
+		}
+		flowAggregator.Sinks = append(flowAggregator.Sinks, fakeSink)
+	})
+
+	Describe("Flush", func() {
+		BeforeEach(func() {
+			for i := 0; i < 3; i++ {
+				Expect(flowAggregator.Write(newData("ingress", "some-source-app-guid", "some-app-guid", 8080))).To(Succeed())
+			}
+			other := newData("ingress", "some-other-source-app-guid", "some-app-guid", 8080)
+			other.Event.Container.Handle = "some-other-handle"
+			Expect(flowAggregator.Write(other)).To(Succeed())
+			Expect(flowAggregator.Write(newData("egress", "", "some-app-guid", 443))).To(Succeed())
+			Expect(flowAggregator.Write(newData("egress", "", "some-app-guid", 443))).To(Succeed())
+
+			now = windowStart.Add(time.Minute)
+		})
+
+		It("writes a summary event for each tuple, busiest first", func() {
+			flowAggregator.Flush()
+
+			Expect(fakeSink.WriteCallCount()).To(Equal(3))
+			summary := fakeSink.WriteArgsForCall(0)
+			Expect(summary.Message).To(Equal("ingress-allowed-summary"))
+			Expect(summary.Data).To(Equal(lager.Data{
+				"source_app_guid":      "some-source-app-guid",
+				"destination_app_guid": "some-app-guid",
+				"protocol":             "TCP",
+				"dst_port":             8080,
+				"count":                3,
+				"window_start":         windowStart,
+				"window_end":           now,
+			}))
+			Expect(summary.Event).To(Equal(merger.Event{
+				Timestamp:       now,
+				Host:            "some-host",
+				Name:            "ingress-allowed-summary",
+				Direction:       "ingress",
+				Allowed:         true,
+				Protocol:        "TCP",
+				DestinationPort: 8080,
+				Container: repository.Container{
+					AppID:   "some-app-guid",
+					SpaceID: "some-space-guid",
+					OrgID:   "some-org-guid",
+				},
+				Remote:      &merger.Remote{AppGUID: "some-source-app-guid"},
+				Count:       3,
+				WindowStart: &windowStart,
+			}))
+
+			egress := fakeSink.WriteArgsForCall(1)
+			Expect(egress.Message).To(Equal("egress-allowed-summary"))
+			Expect(egress.Event.Count).To(Equal(2))
+			Expect(egress.Event.Remote).To(BeNil())
+			Expect(egress.Data).To(HaveKeyWithValue("source_app_guid", "some-app-guid"))
+			Expect(egress.Data).To(HaveKeyWithValue("destination_app_guid", ""))
+
+			Expect(fakeSink.WriteArgsForCall(2).Event.Count).To(Equal(1))
+		})
+
+		It("counts the denied flows of a tuple apart from the allowed ones", func() {
+			denied := newData("ingress", "some-source-app-guid", "some-app-guid", 8080)
+			denied.Event.Allowed = false
+			Expect(flowAggregator.Write(denied)).To(Succeed())
+
+			flowAggregator.Flush()
+
+			Expect(fakeSink.WriteCallCount()).To(Equal(4))
+			Expect(fakeSink.WriteArgsForCall(3).Message).To(Equal("ingress-denied-summary"))
+		})
+
+		It("ranks the source apps by their flows, keeping the top n", func() {
+			flowAggregator.Flush()
+
+			Expect(flowAggregator.TopTalkers()).To(Equal([]aggregator.Talker{
+				{SourceAppGUID: "some-source-app-guid", Flows: 3},
+				{SourceAppGUID: "some-app-guid", Flows: 2},
+			}))
+			Expect(logger).To(gbytes.Say("top-talkers.*some-source-app-guid"))
+		})
+
+		Context("when the source app of an ingress flow is not known", func() {
+			BeforeEach(func() {
+				unknown := newData("ingress", "", "some-app-guid", 8080)
+				unknown.Event.SourceIP = "10.0.16.5"
+				for i := 0; i < 4; i++ {
+					Expect(flowAggregator.Write(unknown)).To(Succeed())
+				}
+				unknown.Event.SourceIP = "10.0.16.6"
+				Expect(flowAggregator.Write(unknown)).To(Succeed())
+			})
+
+			It("counts the flows per source IP instead", func() {
+				flowAggregator.Flush()
+
+				Expect(fakeSink.WriteCallCount()).To(Equal(5))
+				summary := fakeSink.WriteArgsForCall(0)
+				Expect(summary.Event.Count).To(Equal(4))
+				Expect(summary.Event.Remote).To(Equal(&merger.Remote{IP: "10.0.16.5"}))
+				Expect(summary.Data).To(HaveKeyWithValue("source_app_guid", ""))
+				Expect(summary.Data).To(HaveKeyWithValue("src_ip", "10.0.16.5"))
+			})
+
+			It("ranks the source IPs with the source apps", func() {
+				flowAggregator.Flush()
+
+				Expect(flowAggregator.TopTalkers()).To(Equal([]aggregator.Talker{
+					{SourceIP: "10.0.16.5", Flows: 4},
+					{SourceAppGUID: "some-source-app-guid", Flows: 3},
+				}))
+			})
+		})
+
+		It("starts a new window", func() {
+			flowAggregator.Flush()
+			now = now.Add(time.Minute)
+			flowAggregator.Flush()
+
+			Expect(fakeSink.WriteCallCount()).To(Equal(3))
+			Expect(flowAggregator.TopTalkers()).To(BeEmpty())
+		})
+
+		Context("when writing a summary fails", func() {
+			BeforeEach(func() {
+				fakeSink.WriteReturns(errors.New("banana"))
+			})
+
+			It("logs the error and writes the other summaries", func() {
+				flowAggregator.Flush()
+
+				Expect(fakeSink.WriteCallCount()).To(Equal(3))
+				Expect(logger).To(gbytes.Say("write-summary.*banana"))
+			})
+		})
+	})
+
+	Describe("Run", func() {
+		BeforeEach(func() {
+			flowAggregator.Window = 10 * time.Millisecond
+			flowAggregator.Now = nil
+		})
+
+		It("flushes at the end of each window and when it is signalled", func() {
+			process := ifrit.Invoke(flowAggregator)
+
+			Expect(flowAggregator.Write(newData("ingress", "some-source-app-guid", "some-app-guid", 8080))).To(Succeed())
+			Eventually(fakeSink.WriteCallCount).Should(Equal(1))
+
+			Expect(flowAggregator.Write(newData("ingress", "some-source-app-guid", "some-app-guid", 8080))).To(Succeed())
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+			Expect(fakeSink.WriteCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("ValidMode", func() {
+		It("allows the raw and aggregate modes", func() {
+			Expect(aggregator.ValidMode("")).To(BeTrue())
+			Expect(aggregator.ValidMode("raw")).To(BeTrue())
+			Expect(aggregator.ValidMode("aggregate")).To(BeTrue())
+			Expect(aggregator.ValidMode("banana")).To(BeFalse())
+		})
+	})
+})
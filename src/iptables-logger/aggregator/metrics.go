@@ -0,0 +1,34 @@
+package aggregator
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/cf-networking-helpers/metrics"
+)
+
+//go:generate counterfeiter -o ../fakes/talker_reporter.go --fake-name TalkerReporter . talkerReporter
+type talkerReporter interface {
+	TopTalkers() []Talker
+}
+
+// NewTopTalkerSources are the flows of the n busiest source apps of the last
+// window, as topTalker1Flows to topTalker<n>Flows. The apps themselves are
+// logged as top-talkers at the end of each window.
+func NewTopTalkerSources(reporter talkerReporter, n int) []metrics.MetricSource {
+	sources := make([]metrics.MetricSource, n)
+	for i := range sources {
+		rank := i
+		sources[i] = metrics.MetricSource{
+			Name: fmt.Sprintf("topTalker%dFlows", rank+1),
+			Unit: "",
+			Getter: func() (float64, error) {
+				talkers := reporter.TopTalkers()
+				if rank >= len(talkers) {
+					return 0, nil
+				}
+				return float64(talkers[rank].Flows), nil
+			},
+		}
+	}
+	return sources
+}
@@ -0,0 +1,36 @@
+package aggregator_test
+
+import (
+	"iptables-logger/aggregator"
+	"iptables-logger/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	var reporter *fakes.TalkerReporter
+
+	BeforeEach(func() {
+		reporter = &fakes.TalkerReporter{}
+		reporter.TopTalkersReturns([]aggregator.Talker{
+			{SourceAppGUID: "some-app-guid", Flows: 42},
+			{SourceAppGUID: "some-other-app-guid", Flows: 7},
+		})
+	})
+
+	It("reports the flows of each rank of the top talkers", func() {
+		values := map[string]float64{}
+		for _, source := range aggregator.NewTopTalkerSources(reporter, 3) {
+			value, err := source.Getter()
+			Expect(err).NotTo(HaveOccurred())
+			values[source.Name] = value
+		}
+
+		Expect(values).To(Equal(map[string]float64{
+			"topTalker1Flows": 42,
+			"topTalker2Flows": 7,
+			"topTalker3Flows": 0,
+		}))
+	})
+})
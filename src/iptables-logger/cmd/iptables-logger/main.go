@@ -3,27 +3,40 @@ package main
 import (
 	"flag"
 	"fmt"
+	"iptables-logger/aggregator"
 	"iptables-logger/config"
+	"iptables-logger/enricher"
 	"iptables-logger/merger"
+	"iptables-logger/output"
 	"iptables-logger/parser"
 	"iptables-logger/repository"
 	"iptables-logger/runner"
 	"lib/datastore"
 	"lib/filelock"
+	"lib/policy_client"
 	"lib/serial"
 	"log"
+	"net/http"
 	"os"
+	"time"
 
+	"github.com/cloudfoundry/dropsonde"
 	"github.com/hpcloud/tail"
 	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/grouper"
+	"github.com/tedsuo/ifrit/sigmon"
 
 	"iptables-logger/rotatablesink"
 
+	"code.cloudfoundry.org/cf-networking-helpers/metrics"
+	"code.cloudfoundry.org/cf-networking-helpers/mutualtls"
 	"code.cloudfoundry.org/lager"
 )
 
 var (
-	logPrefix = "cfnetworking"
+	logPrefix       = "cfnetworking"
+	dropsondeOrigin = "iptables-logger"
+	emitInterval    = 30 * time.Second
 )
 
 func main() {
@@ -57,43 +70,146 @@ func main() {
 	}
 
 	kernelLogParser := &parser.KernelLogParser{}
-	store := &datastore.Store{
-		Serializer: &serial.Serial{},
-		Locker:     filelock.NewCacheFileLock(filelock.NewLocker(conf.ContainerMetadataFile), conf.ContainerMetadataFile),
-	}
-	containerRepo := &repository.ContainerRepo{
-		Store: store,
+	containerRepo := &repository.ContainerRepo{}
+	switch conf.ContainerMetadataBackend {
+	case datastore.BackendBolt:
+		store, err := datastore.New(conf.ContainerMetadataBackend, conf.ContainerMetadataFile, os.Stderr)
+		if err != nil {
+			logger.Fatal("datastore-new", err) // not tested
+		}
+		containerRepo.Store = store
+		containerRepo.Index = store.(*datastore.BoltStore)
+	default:
+		containerRepo.Store = &datastore.Store{
+			Path:       conf.ContainerMetadataFile,
+			Serializer: &serial.Serial{},
+			Locker:     filelock.NewCacheFileLock(filelock.NewLocker(conf.ContainerMetadataFile), conf.ContainerMetadataFile),
+			BackupFile: datastore.BackupFileFor(conf.ContainerMetadataFile),
+			Logger:     os.Stderr,
+		}
 	}
 	logMerger := &merger.Merger{
 		ContainerRepo: containerRepo,
 	}
-	iptablesLogger := lager.NewLogger(fmt.Sprintf("%s.iptables", logPrefix))
-	outputLogFile, err := os.OpenFile(conf.OutputLogFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if conf.PolicyServerURL != "" {
+		clientTLSConfig, err := mutualtls.NewClientTLSConfig(conf.ClientCertFile, conf.ClientKeyFile, conf.CACertFile)
+		if err != nil {
+			logger.Fatal("mutual-tls-config", err)
+		}
+		httpClient := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: clientTLSConfig,
+			},
+			Timeout: 5 * time.Second,
+		}
+		logMerger.Enricher = &enricher.Enricher{
+			PolicyClient:  policy_client.NewInternal(logger.Session("policy-client"), httpClient, conf.PolicyServerURL),
+			ContainerRepo: containerRepo,
+			Logger:        logger.Session("enricher"),
+			CacheTTL:      time.Duration(conf.EnrichmentCacheTTLSeconds) * time.Second,
+		}
+	}
+	runner := &runner.Runner{
+		Lines:  t.Lines,
+		Parser: kernelLogParser,
+		Logger: logger,
+		Merger: logMerger,
+	}
+	sinks := []eventSink{newFileSink(conf.OutputLogFile, conf.OutputFormat, logger)}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Fatal("hostname", err) // not tested
+	}
+	// the network outputs are sent to in the background, starting before the
+	// aggregator and the runner and stopping after them
+	members := grouper.Members{}
+	for i, o := range conf.Outputs {
+		format := o.Format
+		if format == "" {
+			format = output.DefaultFormat(o.Type)
+		}
+
+		var networkSink eventSink
+		switch o.Type {
+		case output.TypeFile:
+			sinks = append(sinks, newFileSink(o.Path, format, logger))
+		case output.TypeSyslog:
+			networkSink = output.NewSyslogSink(o.Network, o.Address, format, hostname)
+		case output.TypeForwarder:
+			networkSink = output.NewForwarderSink(o.Network, o.Address, format)
+		}
+		if networkSink != nil {
+			name := fmt.Sprintf("output-%d", i)
+			queueSink := output.NewQueueSink(networkSink, output.DefaultQueueSize, logger.Session(name, lager.Data{"address": o.Address}))
+			sinks = append(sinks, queueSink)
+			members = append(members, grouper.Member{name, queueSink})
+		}
+	}
+
+	// the aggregator starts before the runner and stops after it, so that
+	// it summarises the flows of the last window
+	if conf.Mode == aggregator.ModeAggregate {
+		flowAggregator := &aggregator.Aggregator{
+			Logger: logger.Session("aggregator"),
+			Window: time.Duration(conf.AggregationWindowSeconds) * time.Second,
+			TopN:   conf.TopTalkers,
+		}
+		for _, sink := range sinks {
+			flowAggregator.Sinks = append(flowAggregator.Sinks, sink)
+		}
+		runner.Sinks = append(runner.Sinks, flowAggregator)
+		members = append(members, grouper.Member{"aggregator", flowAggregator})
+
+		if conf.MetronAddress != "" {
+			err = dropsonde.Initialize(conf.MetronAddress, dropsondeOrigin)
+			if err != nil {
+				logger.Fatal("initialize-dropsonde", err) // not tested
+			}
+			metricsEmitter := metrics.NewMetricsEmitter(logger, emitInterval, aggregator.NewTopTalkerSources(flowAggregator, conf.TopTalkers)...)
+			members = append(members, grouper.Member{"metrics_emitter", metricsEmitter})
+		}
+	} else {
+		for _, sink := range sinks {
+			runner.Sinks = append(runner.Sinks, sink)
+		}
+	}
+	members = append(members, grouper.Member{"runner", runner})
+
+	monitor := ifrit.Invoke(sigmon.New(grouper.NewOrdered(os.Interrupt, members)))
+	err = <-monitor.Wait()
+	if err != nil {
+		logger.Fatal("ifrit monitor", err)
+	}
+}
+
+type eventSink interface {
+	Write(merger.IPTablesLogData) error
+}
+
+// newFileSink writes to the file at path, following it when it is rotated.
+func newFileSink(path, format string, logger lager.Logger) eventSink {
+	outputLogFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
 		logger.Fatal("open-output-log-file", err)
 	}
 
-	iptablesSink, err := rotatablesink.NewRotatableSink(
+	fileSink, err := rotatablesink.NewRotatableSink(
 		outputLogFile.Name(),
 		lager.DEBUG,
 		rotatablesink.DefaultFileWriterFunc(rotatablesink.DefaultFileWriter),
 		rotatablesink.DefaultDestinationFileInfo{},
 		logger,
 	)
-
 	if err != nil {
 		logger.Fatal("rotatable-sink", err)
 	}
-	iptablesLogger.RegisterSink(iptablesSink)
 
-	runner := &runner.Runner{
-		Lines:          t.Lines,
-		Parser:         kernelLogParser,
-		Logger:         logger,
-		Merger:         logMerger,
-		IPTablesLogger: iptablesLogger,
+	if format == output.FormatJSON || format == output.FormatCEF {
+		return &output.WriterSink{Writer: fileSink, Format: format}
 	}
 
-	monitor := ifrit.Invoke(runner)
-	<-monitor.Wait()
+	iptablesLogger := lager.NewLogger(fmt.Sprintf("%s.iptables", logPrefix))
+	iptablesLogger.RegisterSink(fileSink)
+	return &output.LagerSink{Logger: iptablesLogger}
 }
@@ -2,17 +2,67 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"iptables-logger/aggregator"
+	"iptables-logger/output"
+	"lib/datastore"
 	"os"
 
 	validator "gopkg.in/validator.v2"
 )
 
 type Config struct {
-	KernelLogFile         string `json:"kernel_log_file" validate:"nonzero"`
-	ContainerMetadataFile string `json:"container_metadata_file" validate:"nonzero"`
-	OutputLogFile         string `json:"output_log_file" validate:"nonzero"`
+	KernelLogFile            string `json:"kernel_log_file" validate:"nonzero"`
+	ContainerMetadataFile    string `json:"container_metadata_file" validate:"nonzero"`
+	ContainerMetadataBackend string `json:"container_metadata_backend"`
+	OutputLogFile            string `json:"output_log_file" validate:"nonzero"`
+	// OutputFormat is the format of the OutputLogFile, lager by default.
+	OutputFormat string `json:"output_format"`
+	// Outputs are written to as well as the OutputLogFile.
+	Outputs []Output `json:"outputs"`
+	// PolicyServerURL is the internal API of the policy server. When it is
+	// set, events are enriched with the app on the other side of the packet
+	// and the policy that allowed it.
+	PolicyServerURL string `json:"policy_server_url"`
+	CACertFile      string `json:"ca_cert_file"`
+	ClientCertFile  string `json:"client_cert_file"`
+	ClientKeyFile   string `json:"client_key_file"`
+	// EnrichmentCacheTTLSeconds is how long the tags and policies read from
+	// the policy server are kept, 60 seconds by default.
+	EnrichmentCacheTTLSeconds int `json:"enrichment_cache_ttl_seconds"`
+	// Mode is raw, writing an event for every logged packet, or aggregate,
+	// writing a summary event for each tuple of flows at the end of every
+	// window of AggregationWindowSeconds. Without a PolicyServerURL the app
+	// of an ingress packet from another cell is not known, and its flows are
+	// counted per source IP instead.
+	Mode                     string `json:"mode"`
+	AggregationWindowSeconds int    `json:"aggregation_window_seconds"`
+	// TopTalkers is how many of the busiest source apps of each window are
+	// emitted as metrics to the MetronAddress in the aggregate mode.
+	TopTalkers    int    `json:"top_talkers"`
+	MetronAddress string `json:"metron_address"`
+}
+
+const (
+	DefaultEnrichmentCacheTTLSeconds = 60
+	DefaultAggregationWindowSeconds  = 60
+	DefaultTopTalkers                = 10
+)
+
+// Output is a file, a syslog server or a forwarder that sends each event as
+// a line over tcp or udp.
+type Output struct {
+	Type   string `json:"type"`
+	Format string `json:"format"`
+	// Path is the path of a file output.
+	Path string `json:"path"`
+	// Network and Address are where a syslog or forwarder output sends
+	// events to, e.g. "unixgram" and "/dev/log", or "tcp" and
+	// "10.0.0.5:5514".
+	Network string `json:"network"`
+	Address string `json:"address"`
 }
 
 func New(path string) (*Config, error) {
@@ -34,5 +84,72 @@ func New(path string) (*Config, error) {
 		return &cfg, fmt.Errorf("invalid config: %s", err)
 	}
 
+	if !datastore.ValidBackend(cfg.ContainerMetadataBackend) {
+		return &cfg, fmt.Errorf("invalid config: invalid container_metadata_backend: %s", cfg.ContainerMetadataBackend)
+	}
+
+	if !output.ValidFormat(output.TypeFile, cfg.OutputFormat) {
+		return &cfg, fmt.Errorf("invalid config: invalid output_format: %s", cfg.OutputFormat)
+	}
+
+	for i, o := range cfg.Outputs {
+		if err := o.validate(); err != nil {
+			return &cfg, fmt.Errorf("invalid config: outputs[%d]: %s", i, err)
+		}
+	}
+
+	if cfg.PolicyServerURL != "" {
+		if cfg.CACertFile == "" || cfg.ClientCertFile == "" || cfg.ClientKeyFile == "" {
+			return &cfg, errors.New("invalid config: policy_server_url requires ca_cert_file, client_cert_file and client_key_file")
+		}
+	}
+
+	if cfg.EnrichmentCacheTTLSeconds < 0 {
+		return &cfg, fmt.Errorf("invalid config: invalid enrichment_cache_ttl_seconds: %d", cfg.EnrichmentCacheTTLSeconds)
+	}
+	if cfg.EnrichmentCacheTTLSeconds == 0 {
+		cfg.EnrichmentCacheTTLSeconds = DefaultEnrichmentCacheTTLSeconds
+	}
+
+	if !aggregator.ValidMode(cfg.Mode) {
+		return &cfg, fmt.Errorf("invalid config: invalid mode: %s", cfg.Mode)
+	}
+	if cfg.AggregationWindowSeconds < 0 {
+		return &cfg, fmt.Errorf("invalid config: invalid aggregation_window_seconds: %d", cfg.AggregationWindowSeconds)
+	}
+	if cfg.AggregationWindowSeconds == 0 {
+		cfg.AggregationWindowSeconds = DefaultAggregationWindowSeconds
+	}
+	if cfg.TopTalkers < 0 {
+		return &cfg, fmt.Errorf("invalid config: invalid top_talkers: %d", cfg.TopTalkers)
+	}
+	if cfg.TopTalkers == 0 {
+		cfg.TopTalkers = DefaultTopTalkers
+	}
+
 	return &cfg, nil
 }
+
+func (o Output) validate() error {
+	if !output.ValidType(o.Type) {
+		return fmt.Errorf("invalid type: %s", o.Type)
+	}
+	if !output.ValidFormat(o.Type, o.Format) {
+		return fmt.Errorf("invalid format for %s: %s", o.Type, o.Format)
+	}
+
+	if o.Type == output.TypeFile {
+		if o.Path == "" {
+			return errors.New("missing path")
+		}
+		return nil
+	}
+
+	if !output.ValidNetwork(o.Type, o.Network) {
+		return fmt.Errorf("invalid network for %s: %s", o.Type, o.Network)
+	}
+	if o.Address == "" {
+		return errors.New("missing address")
+	}
+	return nil
+}
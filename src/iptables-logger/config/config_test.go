@@ -29,6 +29,7 @@ var _ = Describe("Config", func() {
 				file.WriteString(`{
 					"kernel_log_file": "/var/log/kern.log",
 					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"container_metadata_backend": "bolt",
 					"output_log_file": "/var/vcap/sys/log/iptables-logger"
 				}`)
 			})
@@ -37,6 +38,7 @@ var _ = Describe("Config", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(c.KernelLogFile).To(Equal("/var/log/kern.log"))
 				Expect(c.ContainerMetadataFile).To(Equal("/var/vcap/data/container-metadata/store.json"))
+				Expect(c.ContainerMetadataBackend).To(Equal("bolt"))
 				Expect(c.OutputLogFile).To(Equal("/var/vcap/sys/log/iptables-logger"))
 			})
 		})
@@ -56,6 +58,179 @@ var _ = Describe("Config", func() {
 			})
 		})
 
+		Context("when the container metadata backend is unknown", func() {
+			It("returns the error", func() {
+				file.WriteString(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"container_metadata_backend": "banana",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger"
+				}`)
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError("invalid config: invalid container_metadata_backend: banana"))
+			})
+		})
+
+		Context("when outputs are configured", func() {
+			BeforeEach(func() {
+				file.WriteString(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger",
+					"output_format": "json",
+					"outputs": [
+						{"type": "file", "format": "cef", "path": "/var/vcap/sys/log/iptables-logger/iptables.cef"},
+						{"type": "syslog", "network": "unixgram", "address": "/dev/log"},
+						{"type": "forwarder", "format": "json", "network": "tcp", "address": "10.0.0.5:5514"}
+					]
+				}`)
+			})
+			It("returns the config", func() {
+				c, err := config.New(file.Name())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(c.OutputFormat).To(Equal("json"))
+				Expect(c.Outputs).To(Equal([]config.Output{
+					{Type: "file", Format: "cef", Path: "/var/vcap/sys/log/iptables-logger/iptables.cef"},
+					{Type: "syslog", Network: "unixgram", Address: "/dev/log"},
+					{Type: "forwarder", Format: "json", Network: "tcp", Address: "10.0.0.5:5514"},
+				}))
+			})
+		})
+
+		DescribeTable("when an output is invalid",
+			func(outputs, errorMsg string) {
+				file.WriteString(fmt.Sprintf(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger",
+					"outputs": %s
+				}`, outputs))
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError(errorMsg))
+			},
+			Entry("unknown type", `[{"type": "banana"}]`, "invalid config: outputs[0]: invalid type: banana"),
+			Entry("lager over the network", `[{"type": "forwarder", "format": "lager", "network": "tcp", "address": "a:1"}]`, "invalid config: outputs[0]: invalid format for forwarder: lager"),
+			Entry("file without a path", `[{"type": "file"}]`, "invalid config: outputs[0]: missing path"),
+			Entry("forwarder over a unix socket", `[{"type": "forwarder", "network": "unix", "address": "/some/sock"}]`, "invalid config: outputs[0]: invalid network for forwarder: unix"),
+			Entry("syslog without an address", `[{"type": "syslog", "network": "udp"}]`, "invalid config: outputs[0]: missing address"),
+		)
+
+		Context("when the policy server is configured", func() {
+			BeforeEach(func() {
+				file.WriteString(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger",
+					"policy_server_url": "https://policy-server.service.cf.internal:4003",
+					"ca_cert_file": "/some/ca.crt",
+					"client_cert_file": "/some/client.crt",
+					"client_key_file": "/some/client.key",
+					"enrichment_cache_ttl_seconds": 30
+				}`)
+			})
+			It("returns the config", func() {
+				c, err := config.New(file.Name())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(c.PolicyServerURL).To(Equal("https://policy-server.service.cf.internal:4003"))
+				Expect(c.CACertFile).To(Equal("/some/ca.crt"))
+				Expect(c.ClientCertFile).To(Equal("/some/client.crt"))
+				Expect(c.ClientKeyFile).To(Equal("/some/client.key"))
+				Expect(c.EnrichmentCacheTTLSeconds).To(Equal(30))
+			})
+		})
+
+		Context("when the enrichment cache ttl and the aggregation are not set", func() {
+			It("defaults them", func() {
+				file.WriteString(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger"
+				}`)
+				c, err := config.New(file.Name())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(c.EnrichmentCacheTTLSeconds).To(Equal(60))
+				Expect(c.Mode).To(Equal(""))
+				Expect(c.AggregationWindowSeconds).To(Equal(60))
+				Expect(c.TopTalkers).To(Equal(10))
+			})
+		})
+
+		Context("when the aggregate mode is configured", func() {
+			It("returns the config", func() {
+				file.WriteString(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger",
+					"mode": "aggregate",
+					"aggregation_window_seconds": 300,
+					"top_talkers": 3,
+					"metron_address": "127.0.0.1:3457"
+				}`)
+				c, err := config.New(file.Name())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(c.Mode).To(Equal("aggregate"))
+				Expect(c.AggregationWindowSeconds).To(Equal(300))
+				Expect(c.TopTalkers).To(Equal(3))
+				Expect(c.MetronAddress).To(Equal("127.0.0.1:3457"))
+			})
+		})
+
+		DescribeTable("when the aggregation is invalid",
+			func(member, errorMsg string) {
+				file.WriteString(fmt.Sprintf(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger",
+					%s
+				}`, member))
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError(errorMsg))
+			},
+			Entry("unknown mode", `"mode": "banana"`, "invalid config: invalid mode: banana"),
+			Entry("negative window", `"aggregation_window_seconds": -1`, "invalid config: invalid aggregation_window_seconds: -1"),
+			Entry("negative top talkers", `"top_talkers": -1`, "invalid config: invalid top_talkers: -1"),
+		)
+
+		Context("when the policy server is configured without client certs", func() {
+			It("returns the error", func() {
+				file.WriteString(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger",
+					"policy_server_url": "https://policy-server.service.cf.internal:4003",
+					"ca_cert_file": "/some/ca.crt"
+				}`)
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError("invalid config: policy_server_url requires ca_cert_file, client_cert_file and client_key_file"))
+			})
+		})
+
+		Context("when the enrichment cache ttl is negative", func() {
+			It("returns the error", func() {
+				file.WriteString(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger",
+					"enrichment_cache_ttl_seconds": -1
+				}`)
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError("invalid config: invalid enrichment_cache_ttl_seconds: -1"))
+			})
+		})
+
+		Context("when the output format is unknown", func() {
+			It("returns the error", func() {
+				file.WriteString(`{
+					"kernel_log_file": "/var/log/kern.log",
+					"container_metadata_file": "/var/vcap/data/container-metadata/store.json",
+					"output_log_file": "/var/vcap/sys/log/iptables-logger",
+					"output_format": "xml"
+				}`)
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError("invalid config: invalid output_format: xml"))
+			})
+		})
+
 		Context("when config file contents blank", func() {
 			It("returns the error", func() {
 				_, err = config.New(file.Name())
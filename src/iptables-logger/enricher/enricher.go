@@ -0,0 +1,223 @@
+package enricher
+
+import (
+	"fmt"
+	"iptables-logger/merger"
+	"iptables-logger/repository"
+	"policy-server/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o ../fakes/policy_client.go --fake-name PolicyClient . policyClient
+type policyClient interface {
+	GetTags() ([]models.Tag, error)
+	GetPoliciesByID(ids ...string) ([]models.Policy, error)
+}
+
+//go:generate counterfeiter -o ../fakes/container_repo.go --fake-name ContainerRepo . containerRepo
+type containerRepo interface {
+	GetByIP(string) (repository.Container, error)
+}
+
+// Enricher adds the app on the other side of a packet, and the policy that
+// allowed it, to events. The tags and policies are read from the policy
+// server and kept for CacheTTL. When the policy server cannot be reached
+// the events are written without them.
+type Enricher struct {
+	PolicyClient  policyClient
+	ContainerRepo containerRepo
+	Logger        lager.Logger
+	CacheTTL      time.Duration
+	Now           func() time.Time
+
+	mutex    sync.Mutex
+	tags     map[uint64]models.Tag
+	tagsRead time.Time
+	policies map[string]cachedPolicies
+}
+
+type cachedPolicies struct {
+	policies []models.Policy
+	read     time.Time
+}
+
+func (e *Enricher) Enrich(event *merger.Event) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	remote := &merger.Remote{}
+	var tagValue string
+	if event.Direction == "ingress" {
+		remote.IP = event.SourceIP
+		tagValue = sourceTag(event.Mark, event.LogPrefix)
+	} else {
+		remote.IP = event.DestinationIP
+	}
+
+	if tagValue != "" {
+		tag, found, err := e.tagByValue(tagValue)
+		if err != nil {
+			e.Logger.Error("enrich-event", err)
+		} else if found {
+			remote.Tag = tag.Tag
+			remote.AppGUID = tag.ID
+		}
+	}
+
+	if remote.AppGUID == "" {
+		container, err := e.ContainerRepo.GetByIP(remote.IP)
+		if err != nil {
+			e.Logger.Error("enrich-event", fmt.Errorf("get container by ip: %s", err))
+		} else {
+			remote.AppGUID = container.AppID
+		}
+	}
+	event.Remote = remote
+
+	if !event.Allowed || remote.AppGUID == "" || event.Container.AppID == "" {
+		return
+	}
+
+	sourceID, destinationID := remote.AppGUID, event.Container.AppID
+	if event.Direction != "ingress" {
+		sourceID, destinationID = event.Container.AppID, remote.AppGUID
+	}
+
+	policy, err := e.matchPolicy(sourceID, destinationID, event)
+	if err != nil {
+		e.Logger.Error("enrich-event", err)
+		return
+	}
+	event.Policy = policy
+}
+
+// sourceTag is the tag of the app that sent an ingress packet. The packet is
+// marked with it, and the rules that allow it log it after "OK_".
+func sourceTag(mark, logPrefix string) string {
+	if mark != "" {
+		return mark
+	}
+	parts := strings.Split(logPrefix, "_")
+	if len(parts) > 2 && parts[0] == "OK" {
+		return parts[1]
+	}
+	return ""
+}
+
+func (e *Enricher) now() time.Time {
+	if e.Now == nil {
+		return time.Now()
+	}
+	return e.Now()
+}
+
+func (e *Enricher) tagByValue(value string) (models.Tag, bool, error) {
+	key, err := strconv.ParseUint(strings.TrimPrefix(value, "0x"), 16, 64)
+	if err != nil || key == 0 {
+		return models.Tag{}, false, nil
+	}
+
+	now := e.now()
+	if e.tagsRead.IsZero() || now.Sub(e.tagsRead) >= e.CacheTTL {
+		// a failed read keeps the tags that were read before until the
+		// next refresh, rather than asking the policy server for every packet
+		e.tagsRead = now
+		tags, err := e.PolicyClient.GetTags()
+		if err != nil {
+			return models.Tag{}, false, fmt.Errorf("get tags: %s", err)
+		}
+		e.tags = make(map[uint64]models.Tag)
+		for _, tag := range tags {
+			tagKey, err := strconv.ParseUint(tag.Tag, 16, 64)
+			if err != nil {
+				continue
+			}
+			e.tags[tagKey] = tag
+		}
+	}
+
+	tag, found := e.tags[key]
+	return tag, found, nil
+}
+
+func (e *Enricher) policiesTo(destinationID string) ([]models.Policy, error) {
+	now := e.now()
+	cached, ok := e.policies[destinationID]
+	if ok && now.Sub(cached.read) < e.CacheTTL {
+		return cached.policies, nil
+	}
+
+	if e.policies == nil {
+		e.policies = make(map[string]cachedPolicies)
+	}
+	policies, err := e.PolicyClient.GetPoliciesByID(destinationID)
+	if err != nil {
+		cached.read = now
+		e.policies[destinationID] = cached
+		return nil, fmt.Errorf("get policies by id: %s", err)
+	}
+	e.policies[destinationID] = cachedPolicies{policies: policies, read: now}
+	return policies, nil
+}
+
+func (e *Enricher) matchPolicy(sourceID, destinationID string, event *merger.Event) (*merger.PolicyTuple, error) {
+	policies, err := e.policiesTo(destinationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := e.now()
+	for _, policy := range policies {
+		if policy.Source.ID != sourceID || policy.Destination.ID != destinationID || policy.Expired(now) {
+			continue
+		}
+		if startPort, endPort, ok := matchDestination(policy.Destination, event); ok {
+			return &merger.PolicyTuple{
+				SourceAppGUID:      sourceID,
+				DestinationAppGUID: destinationID,
+				Protocol:           policy.Destination.Protocol,
+				StartPort:          startPort,
+				EndPort:            endPort,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// matchDestination reports whether the destination of a policy allows the
+// packet of the event, and the ports it allows if it is a tcp or udp one.
+func matchDestination(destination models.Destination, event *merger.Event) (int, int, bool) {
+	protocol := strings.ToLower(event.Protocol)
+	switch destination.Protocol {
+	case models.ProtocolAll:
+		return 0, 0, true
+	case models.ProtocolICMP:
+		if protocol != models.ProtocolICMP {
+			return 0, 0, false
+		}
+		if destination.ICMPType != nil && *destination.ICMPType != event.ICMPType {
+			return 0, 0, false
+		}
+		if destination.ICMPCode != nil && *destination.ICMPCode != event.ICMPCode {
+			return 0, 0, false
+		}
+		return 0, 0, true
+	}
+
+	if destination.Protocol != protocol {
+		return 0, 0, false
+	}
+	startPort, endPort := destination.Ports.Start, destination.Ports.End
+	if startPort == 0 {
+		startPort, endPort = destination.Port, destination.Port
+	}
+	if event.DestinationPort < startPort || event.DestinationPort > endPort {
+		return 0, 0, false
+	}
+	return startPort, endPort, true
+}
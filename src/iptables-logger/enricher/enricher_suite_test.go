@@ -0,0 +1,13 @@
+package enricher_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestEnricher(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Enricher Suite")
+}
@@ -0,0 +1,336 @@
+package enricher_test
+
+import (
+	"errors"
+	"iptables-logger/enricher"
+	"iptables-logger/fakes"
+	"iptables-logger/merger"
+	"iptables-logger/repository"
+	"policy-server/models"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Enricher", func() {
+	var (
+		eventEnricher     *enricher.Enricher
+		fakePolicyClient  *fakes.PolicyClient
+		fakeContainerRepo *fakes.ContainerRepo
+		logger            *lagertest.TestLogger
+		now               time.Time
+		event             *merger.Event
+	)
+
+	BeforeEach(func() {
+		fakePolicyClient = &fakes.PolicyClient{}
+		fakeContainerRepo = &fakes.ContainerRepo{}
+		logger = lagertest.NewTestLogger("test")
+		now = time.Date(2017, time.May, 3, 23, 35, 7, 0, time.UTC)
+
+		eventEnricher = &enricher.Enricher{
+			PolicyClient:  fakePolicyClient,
+			ContainerRepo: fakeContainerRepo,
+			Logger:        logger,
+			CacheTTL:      time.Minute,
+			Now:           func() time.Time { return now },
+		}
+
+		fakePolicyClient.GetTagsReturns([]models.Tag{
+			{ID: "some-source-app-guid", Tag: "0002"},
+			{ID: "some-app-guid", Tag: "0003"},
+		}, nil)
+		fakePolicyClient.GetPoliciesByIDReturns([]models.Policy{
+			{
+				Source: models.Source{ID: "some-other-app-guid"},
+				Destination: models.Destination{
+					ID:       "some-app-guid",
+					Protocol: "tcp",
+					Ports:    models.Ports{Start: 8080, End: 8080},
+				},
+			},
+			{
+				Source: models.Source{ID: "some-source-app-guid"},
+				Destination: models.Destination{
+					ID:       "some-app-guid",
+					Protocol: "udp",
+					Ports:    models.Ports{Start: 8080, End: 8080},
+				},
+			},
+			{
+				Source: models.Source{ID: "some-source-app-guid"},
+				Destination: models.Destination{
+					ID:       "some-app-guid",
+					Protocol: "tcp",
+					Ports:    models.Ports{Start: 8000, End: 9000},
+				},
+			},
+		}, nil)
+
+		event = &merger.Event{
+			Direction:       "ingress",
+			Allowed:         true,
+			Protocol:        "TCP",
+			SourceIP:        "10.255.15.7",
+			SourcePort:      60012,
+			DestinationIP:   "10.255.15.13",
+			DestinationPort: 8080,
+			Mark:            "0x2",
+			LogPrefix:       "OK_0002_e9e8959f-3828-4136-8",
+			Container:       repository.Container{AppID: "some-app-guid"},
+		}
+	})
+
+	Context("when the packet is ingress", func() {
+		It("adds the source app from the tag of the mark and the policy that allowed it", func() {
+			eventEnricher.Enrich(event)
+
+			Expect(event.Remote).To(Equal(&merger.Remote{
+				IP:      "10.255.15.7",
+				Tag:     "0002",
+				AppGUID: "some-source-app-guid",
+			}))
+			Expect(event.Policy).To(Equal(&merger.PolicyTuple{
+				SourceAppGUID:      "some-source-app-guid",
+				DestinationAppGUID: "some-app-guid",
+				Protocol:           "tcp",
+				StartPort:          8000,
+				EndPort:            9000,
+			}))
+
+			Expect(fakePolicyClient.GetPoliciesByIDCallCount()).To(Equal(1))
+			Expect(fakePolicyClient.GetPoliciesByIDArgsForCall(0)).To(Equal([]string{"some-app-guid"}))
+			Expect(fakeContainerRepo.GetByIPCallCount()).To(Equal(0))
+		})
+
+		Context("when the packet has no mark", func() {
+			BeforeEach(func() {
+				event.Mark = ""
+			})
+
+			It("reads the tag from the log prefix", func() {
+				eventEnricher.Enrich(event)
+
+				Expect(event.Remote.AppGUID).To(Equal("some-source-app-guid"))
+			})
+		})
+
+		Context("when the packet was denied", func() {
+			BeforeEach(func() {
+				event.Allowed = false
+			})
+
+			It("adds the source app but no policy", func() {
+				eventEnricher.Enrich(event)
+
+				Expect(event.Remote.AppGUID).To(Equal("some-source-app-guid"))
+				Expect(event.Policy).To(BeNil())
+				Expect(fakePolicyClient.GetPoliciesByIDCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when no policy allows the port", func() {
+			BeforeEach(func() {
+				event.DestinationPort = 22
+			})
+
+			It("adds no policy", func() {
+				eventEnricher.Enrich(event)
+
+				Expect(event.Policy).To(BeNil())
+			})
+		})
+
+		Context("when the matching policy has expired", func() {
+			BeforeEach(func() {
+				expiresAt := now.Add(-time.Second)
+				fakePolicyClient.GetPoliciesByIDReturns([]models.Policy{{
+					Source:      models.Source{ID: "some-source-app-guid"},
+					Destination: models.Destination{ID: "some-app-guid", Protocol: "all"},
+					ExpiresAt:   &expiresAt,
+				}}, nil)
+			})
+
+			It("adds no policy", func() {
+				eventEnricher.Enrich(event)
+
+				Expect(event.Policy).To(BeNil())
+			})
+		})
+
+		Context("when the tag is unknown", func() {
+			BeforeEach(func() {
+				event.Mark = "0x9"
+				fakeContainerRepo.GetByIPReturns(repository.Container{AppID: "some-local-app-guid"}, nil)
+			})
+
+			It("looks up the source in the containers of the cell", func() {
+				eventEnricher.Enrich(event)
+
+				Expect(fakeContainerRepo.GetByIPCallCount()).To(Equal(1))
+				Expect(fakeContainerRepo.GetByIPArgsForCall(0)).To(Equal("10.255.15.7"))
+				Expect(event.Remote).To(Equal(&merger.Remote{
+					IP:      "10.255.15.7",
+					AppGUID: "some-local-app-guid",
+				}))
+			})
+		})
+	})
+
+	Context("when the packet is egress", func() {
+		BeforeEach(func() {
+			event.Direction = "egress"
+			event.SourceIP = "10.255.15.13"
+			event.DestinationIP = "10.255.15.7"
+			event.Mark = "0x3"
+			event.LogPrefix = "OK_e9e8959f-3828-4136-8"
+			event.Container = repository.Container{AppID: "some-source-app-guid"}
+
+			fakeContainerRepo.GetByIPReturns(repository.Container{AppID: "some-app-guid"}, nil)
+		})
+
+		It("adds the destination app and the policy that allowed it", func() {
+			eventEnricher.Enrich(event)
+
+			Expect(fakeContainerRepo.GetByIPArgsForCall(0)).To(Equal("10.255.15.7"))
+			Expect(event.Remote).To(Equal(&merger.Remote{
+				IP:      "10.255.15.7",
+				AppGUID: "some-app-guid",
+			}))
+			Expect(event.Policy).To(Equal(&merger.PolicyTuple{
+				SourceAppGUID:      "some-source-app-guid",
+				DestinationAppGUID: "some-app-guid",
+				Protocol:           "tcp",
+				StartPort:          8000,
+				EndPort:            9000,
+			}))
+			Expect(fakePolicyClient.GetTagsCallCount()).To(Equal(0))
+		})
+
+		Context("when the destination is not an app", func() {
+			BeforeEach(func() {
+				fakeContainerRepo.GetByIPReturns(repository.Container{}, nil)
+			})
+
+			It("adds only the destination ip", func() {
+				eventEnricher.Enrich(event)
+
+				Expect(event.Remote).To(Equal(&merger.Remote{IP: "10.255.15.7"}))
+				Expect(event.Policy).To(BeNil())
+			})
+		})
+	})
+
+	Context("when the packet is icmp", func() {
+		var icmpType, icmpCode int
+
+		BeforeEach(func() {
+			icmpType, icmpCode = 8, 0
+			event.Protocol = "ICMP"
+			event.DestinationPort = 0
+			event.ICMPType = 8
+			fakePolicyClient.GetPoliciesByIDReturns([]models.Policy{{
+				Source: models.Source{ID: "some-source-app-guid"},
+				Destination: models.Destination{
+					ID:       "some-app-guid",
+					Protocol: "icmp",
+					ICMPType: &icmpType,
+					ICMPCode: &icmpCode,
+				},
+			}}, nil)
+		})
+
+		It("matches the icmp type and code of the policy", func() {
+			eventEnricher.Enrich(event)
+
+			Expect(event.Policy).To(Equal(&merger.PolicyTuple{
+				SourceAppGUID:      "some-source-app-guid",
+				DestinationAppGUID: "some-app-guid",
+				Protocol:           "icmp",
+			}))
+
+			event.Policy = nil
+			event.ICMPType = 0
+			eventEnricher.Enrich(event)
+			Expect(event.Policy).To(BeNil())
+		})
+	})
+
+	Describe("caching", func() {
+		It("reads the tags and policies again once they are older than the cache ttl", func() {
+			eventEnricher.Enrich(event)
+			eventEnricher.Enrich(event)
+			Expect(fakePolicyClient.GetTagsCallCount()).To(Equal(1))
+			Expect(fakePolicyClient.GetPoliciesByIDCallCount()).To(Equal(1))
+
+			now = now.Add(time.Minute)
+			eventEnricher.Enrich(event)
+			Expect(fakePolicyClient.GetTagsCallCount()).To(Equal(2))
+			Expect(fakePolicyClient.GetPoliciesByIDCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when getting the tags fails", func() {
+		BeforeEach(func() {
+			fakePolicyClient.GetTagsReturns(nil, errors.New("banana"))
+		})
+
+		It("logs the error and does not ask again until the cache ttl is over", func() {
+			eventEnricher.Enrich(event)
+
+			Expect(logger).To(gbytes.Say("enrich-event.*get tags: banana"))
+			Expect(event.Remote).To(Equal(&merger.Remote{IP: "10.255.15.7"}))
+
+			eventEnricher.Enrich(event)
+			Expect(fakePolicyClient.GetTagsCallCount()).To(Equal(1))
+		})
+
+		Context("when tags were read before", func() {
+			BeforeEach(func() {
+				fakePolicyClient.GetTagsReturnsOnCall(0, []models.Tag{{ID: "some-source-app-guid", Tag: "0002"}}, nil)
+			})
+
+			It("keeps using them", func() {
+				eventEnricher.Enrich(event)
+				now = now.Add(time.Minute)
+				eventEnricher.Enrich(event)
+
+				Expect(fakePolicyClient.GetTagsCallCount()).To(Equal(2))
+				Expect(event.Remote.AppGUID).To(Equal("some-source-app-guid"))
+			})
+		})
+	})
+
+	Context("when getting the policies fails", func() {
+		BeforeEach(func() {
+			fakePolicyClient.GetPoliciesByIDReturns(nil, errors.New("banana"))
+		})
+
+		It("logs the error and adds no policy", func() {
+			eventEnricher.Enrich(event)
+
+			Expect(logger).To(gbytes.Say("enrich-event.*get policies by id: banana"))
+			Expect(event.Remote.AppGUID).To(Equal("some-source-app-guid"))
+			Expect(event.Policy).To(BeNil())
+		})
+	})
+
+	Context("when looking up the container fails", func() {
+		BeforeEach(func() {
+			event.Mark = ""
+			event.LogPrefix = "OK_e9e8959f-3828-4136-8"
+			fakeContainerRepo.GetByIPReturns(repository.Container{}, errors.New("banana"))
+		})
+
+		It("logs the error", func() {
+			eventEnricher.Enrich(event)
+
+			Expect(logger).To(gbytes.Say("enrich-event.*get container by ip: banana"))
+			Expect(event.Remote).To(Equal(&merger.Remote{IP: "10.255.15.7"}))
+		})
+	})
+})
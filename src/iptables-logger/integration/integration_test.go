@@ -8,6 +8,7 @@ import (
 	"lib/datastore"
 	"lib/filelock"
 	"lib/serial"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -115,6 +116,7 @@ var _ = Describe("Integration", func() {
 		Expect(err).NotTo(HaveOccurred())
 
 		store = &datastore.Store{
+			Path:       containerMetadataFile.Name(),
 			Serializer: &serial.Serial{},
 			Locker:     filelock.NewLocker(containerMetadataFile.Name()),
 		}
@@ -182,6 +184,112 @@ var _ = Describe("Integration", func() {
 		Eventually(ReadLines, "5s").Should(ContainElement(MatchJSON(EGRESS_DENIED_JSON)))
 	})
 
+	Context("when the output is json and a forwarder is configured", func() {
+		var listener net.PacketConn
+
+		BeforeEach(func() {
+			session.Interrupt()
+			Eventually(session, DEFAULT_TIMEOUT).Should(gexec.Exit())
+
+			var err error
+			listener, err = net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+
+			conf.OutputFormat = "json"
+			conf.Outputs = []config.Output{
+				{Type: "forwarder", Network: "udp", Address: listener.LocalAddr().String()},
+			}
+			configFilePath = WriteConfigFile(conf)
+
+			cmd := exec.Command(binaryPath, "-config-file", configFilePath)
+			session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			listener.Close()
+		})
+
+		It("writes the events in the documented schema to the file and the forwarder", func() {
+			expectedEvent := `{
+				"timestamp": "some-timestamp",
+				"kernel_uptime_seconds": 100471.222018,
+				"host": "localhost",
+				"event": "egress-allowed",
+				"direction": "egress",
+				"allowed": true,
+				"protocol": "UDP",
+				"src_ip": "10.255.0.1",
+				"src_port": 36556,
+				"dst_ip": "10.10.10.10",
+				"dst_port": 11111,
+				"icmp_type": 0,
+				"icmp_code": 0,
+				"mark": "0x1",
+				"log_prefix": "OK_container-handle-1-longer",
+				"container": {
+					"container_id": "container-handle-1-longer-than-29-chars",
+					"app_guid": "app_id_1",
+					"space_guid": "space_id_1",
+					"organization_guid": "organization_id_1"
+				}
+			}`
+
+			go AddToKernelLog(EGRESS_ALLOWD_KERNEL_LOG, kernelLogFile)
+			Eventually(outputFile).Should(BeAnExistingFile())
+			Eventually(ReadLines, "5s").Should(ContainElement(MatchJSON(expectedEvent)))
+
+			buffer := make([]byte, 4096)
+			listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := listener.ReadFrom(buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			var forwarded map[string]interface{}
+			Expect(json.Unmarshal(buffer[:n], &forwarded)).To(Succeed())
+			Expect(forwarded).To(HaveKeyWithValue("event", "egress-allowed"))
+			Expect(forwarded).To(HaveKeyWithValue("timestamp", MatchRegexp(`^\d{4}-06-28T18:21:24`)))
+		})
+	})
+
+	Context("when the mode is aggregate", func() {
+		BeforeEach(func() {
+			session.Interrupt()
+			Eventually(session, DEFAULT_TIMEOUT).Should(gexec.Exit())
+
+			conf.OutputFormat = "json"
+			conf.Mode = "aggregate"
+			conf.AggregationWindowSeconds = 1
+			configFilePath = WriteConfigFile(conf)
+
+			var err error
+			cmd := exec.Command(binaryPath, "-config-file", configFilePath)
+			session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("writes a summary event for the flows of each window", func() {
+			go AddToKernelLog(EGRESS_ALLOWD_KERNEL_LOG+EGRESS_ALLOWD_KERNEL_LOG, kernelLogFile)
+			Eventually(outputFile).Should(BeAnExistingFile())
+
+			Eventually(func() []map[string]interface{} {
+				events := []map[string]interface{}{}
+				for _, line := range ReadLines() {
+					var event map[string]interface{}
+					Expect(json.Unmarshal([]byte(line), &event)).To(Succeed())
+					events = append(events, event)
+				}
+				return events
+			}, "5s").Should(ContainElement(SatisfyAll(
+				HaveKeyWithValue("event", "egress-allowed-summary"),
+				HaveKeyWithValue("count", BeNumerically("==", 2)),
+				HaveKeyWithValue("protocol", "UDP"),
+				HaveKeyWithValue("dst_port", BeNumerically("==", 11111)),
+				HaveKeyWithValue("window_start", Not(BeEmpty())),
+			)))
+			Expect(ReadOutput()).NotTo(ContainSubstring(`"event":"egress-allowed"`))
+		})
+	})
+
 	Context("when source file is rotated", func() {
 		It("logs data about packets", func() {
 			By("logging successful egress packets")
@@ -268,7 +376,7 @@ var _ = Describe("Integration", func() {
 })
 
 func AddToContainerMetadata(store *datastore.Store, containerID, containerIP string, metadata map[string]interface{}) {
-	err := store.Add(containerID, containerIP, metadata)
+	err := store.Add(containerID, []string{containerIP}, metadata)
 	Expect(err).NotTo(HaveOccurred())
 }
 func AddToKernelLog(line string, w io.Writer) {
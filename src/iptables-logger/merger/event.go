@@ -0,0 +1,78 @@
+package merger
+
+import (
+	"iptables-logger/parser"
+	"iptables-logger/repository"
+	"time"
+)
+
+// Event is the schema of the json, cef and syslog outputs. Its field names
+// are stable: fields are added to it, but never renamed or removed. See
+// docs/configuration.md for a description of each field.
+type Event struct {
+	Timestamp       time.Time            `json:"timestamp"`
+	KernelUptime    float64              `json:"kernel_uptime_seconds"`
+	Host            string               `json:"host"`
+	Name            string               `json:"event"`
+	Direction       string               `json:"direction"`
+	Allowed         bool                 `json:"allowed"`
+	Protocol        string               `json:"protocol"`
+	SourceIP        string               `json:"src_ip"`
+	SourcePort      int                  `json:"src_port"`
+	DestinationIP   string               `json:"dst_ip"`
+	DestinationPort int                  `json:"dst_port"`
+	ICMPType        int                  `json:"icmp_type"`
+	ICMPCode        int                  `json:"icmp_code"`
+	Mark            string               `json:"mark"`
+	LogPrefix       string               `json:"log_prefix"`
+	Container       repository.Container `json:"container"`
+	// Remote and Policy are only set when events are enriched with the
+	// policy server's data.
+	Remote *Remote      `json:"remote,omitempty"`
+	Policy *PolicyTuple `json:"policy,omitempty"`
+	// Count and WindowStart are only set on the summary events of the
+	// aggregate mode: Count flows of the same tuple were logged between
+	// WindowStart and Timestamp.
+	Count       int        `json:"count,omitempty"`
+	WindowStart *time.Time `json:"window_start,omitempty"`
+}
+
+// Remote is the other side of the packet: the source of an ingress packet,
+// the destination of an egress packet. Summary events have no IP.
+type Remote struct {
+	IP string `json:"ip,omitempty"`
+	// Tag is the mark of an ingress packet from another app, which is the
+	// tag of that app.
+	Tag     string `json:"tag,omitempty"`
+	AppGUID string `json:"app_guid,omitempty"`
+}
+
+// PolicyTuple is the policy that allowed the packet.
+type PolicyTuple struct {
+	SourceAppGUID      string `json:"source_app_guid"`
+	DestinationAppGUID string `json:"destination_app_guid"`
+	Protocol           string `json:"protocol"`
+	StartPort          int    `json:"start_port,omitempty"`
+	EndPort            int    `json:"end_port,omitempty"`
+}
+
+func newEvent(name string, parsedData parser.ParsedData, container repository.Container) Event {
+	return Event{
+		Timestamp:       parsedData.Timestamp,
+		KernelUptime:    parsedData.KernelUptime,
+		Host:            parsedData.Host,
+		Name:            name,
+		Direction:       parsedData.Direction,
+		Allowed:         parsedData.Allowed,
+		Protocol:        parsedData.Protocol,
+		SourceIP:        parsedData.SourceIP,
+		SourcePort:      parsedData.SourcePort,
+		DestinationIP:   parsedData.DestinationIP,
+		DestinationPort: parsedData.DestinationPort,
+		ICMPType:        parsedData.ICMPType,
+		ICMPCode:        parsedData.ICMPCode,
+		Mark:            parsedData.Mark,
+		LogPrefix:       parsedData.LogPrefix,
+		Container:       container,
+	}
+}
@@ -13,13 +13,24 @@ type containerRepo interface {
 	GetByIP(string) (repository.Container, error)
 }
 
+//go:generate counterfeiter -o fakes/eventEnricher.go --fake-name EventEnricher . eventEnricher
+type eventEnricher interface {
+	Enrich(*Event)
+}
+
 type IPTablesLogData struct {
 	Message string
 	Data    lager.Data
+	// Event holds the same data as Message and Data, for the outputs that
+	// do not write lager lines.
+	Event Event
 }
 
 type Merger struct {
 	ContainerRepo containerRepo
+	// Enricher adds the remote app and the matching policy to events. It is
+	// optional.
+	Enricher eventEnricher
 }
 
 func (m *Merger) Merge(parsedData parser.ParsedData) (IPTablesLogData, error) {
@@ -43,11 +54,25 @@ func (m *Merger) Merge(parsedData parser.ParsedData) (IPTablesLogData, error) {
 	if err != nil {
 		return IPTablesLogData{}, fmt.Errorf("get container by ip: %s", err)
 	}
+
+	data := lager.Data{
+		key:      containerData,
+		"packet": parsedData,
+	}
+	event := newEvent(message, parsedData, containerData)
+	if m.Enricher != nil {
+		m.Enricher.Enrich(&event)
+		if event.Remote != nil {
+			data["remote"] = event.Remote
+		}
+		if event.Policy != nil {
+			data["policy"] = event.Policy
+		}
+	}
+
 	return IPTablesLogData{
 		Message: message,
-		Data: lager.Data{
-			key:      containerData,
-			"packet": parsedData,
-		},
+		Data:    data,
+		Event:   event,
 	}, nil
 }
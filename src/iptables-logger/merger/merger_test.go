@@ -6,6 +6,7 @@ import (
 	"iptables-logger/merger/fakes"
 	"iptables-logger/parser"
 	"iptables-logger/repository"
+	"time"
 
 	"code.cloudfoundry.org/lager"
 
@@ -56,12 +57,81 @@ var _ = Describe("Merger", func() {
 		Expect(fakeContainerRepo.GetByIPCallCount()).To(Equal(1))
 		Expect(fakeContainerRepo.GetByIPArgsForCall(0)).To(Equal("5.6.7.8"))
 
-		Expect(merged).To(Equal(merger.IPTablesLogData{
-			Message: "ingress-allowed",
-			Data:    lager.Data{"destination": container, "packet": parsedData},
+		Expect(merged.Message).To(Equal("ingress-allowed"))
+		Expect(merged.Data).To(Equal(lager.Data{"destination": container, "packet": parsedData}))
+	})
+
+	It("describes the packet and its container in an event", func() {
+		parsedData.Timestamp = time.Date(2017, time.May, 3, 23, 35, 7, 0, time.UTC)
+		parsedData.Host = "some-host"
+		parsedData.KernelUptime = 87981.320056
+
+		merged, err := logMerger.Merge(parsedData)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(merged.Event).To(Equal(merger.Event{
+			Timestamp:       time.Date(2017, time.May, 3, 23, 35, 7, 0, time.UTC),
+			KernelUptime:    87981.320056,
+			Host:            "some-host",
+			Name:            "ingress-allowed",
+			Direction:       "ingress",
+			Allowed:         true,
+			Protocol:        "some-proto",
+			SourceIP:        "1.2.3.4",
+			SourcePort:      1234,
+			DestinationIP:   "5.6.7.8",
+			DestinationPort: 9999,
+			ICMPType:        42,
+			ICMPCode:        13,
+			Mark:            "some-mark",
+			Container:       container,
 		}))
 	})
 
+	Context("when there is an enricher", func() {
+		var fakeEnricher *fakes.EventEnricher
+
+		BeforeEach(func() {
+			fakeEnricher = &fakes.EventEnricher{}
+			fakeEnricher.EnrichStub = func(event *merger.Event) {
+				event.Remote = &merger.Remote{IP: event.SourceIP, AppGUID: "some-source-app-guid"}
+				event.Policy = &merger.PolicyTuple{
+					SourceAppGUID:      "some-source-app-guid",
+					DestinationAppGUID: "some-app-id",
+					Protocol:           "tcp",
+					StartPort:          9999,
+					EndPort:            9999,
+				}
+			}
+			logMerger.Enricher = fakeEnricher
+		})
+
+		It("enriches the event and adds the remote app and the policy to the data", func() {
+			merged, err := logMerger.Merge(parsedData)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeEnricher.EnrichCallCount()).To(Equal(1))
+			Expect(fakeEnricher.EnrichArgsForCall(0).Container).To(Equal(container))
+
+			remote := &merger.Remote{IP: "1.2.3.4", AppGUID: "some-source-app-guid"}
+			policy := &merger.PolicyTuple{
+				SourceAppGUID:      "some-source-app-guid",
+				DestinationAppGUID: "some-app-id",
+				Protocol:           "tcp",
+				StartPort:          9999,
+				EndPort:            9999,
+			}
+			Expect(merged.Event.Remote).To(Equal(remote))
+			Expect(merged.Event.Policy).To(Equal(policy))
+			Expect(merged.Data).To(Equal(lager.Data{
+				"destination": container,
+				"packet":      parsedData,
+				"remote":      remote,
+				"policy":      policy,
+			}))
+		})
+	})
+
 	Context("when the data is for an egress packet", func() {
 		BeforeEach(func() {
 			parsedData.Direction = "egress"
@@ -73,10 +143,8 @@ var _ = Describe("Merger", func() {
 			Expect(fakeContainerRepo.GetByIPCallCount()).To(Equal(1))
 			Expect(fakeContainerRepo.GetByIPArgsForCall(0)).To(Equal("1.2.3.4"))
 
-			Expect(merged).To(Equal(merger.IPTablesLogData{
-				Message: "egress-allowed",
-				Data:    lager.Data{"source": container, "packet": parsedData},
-			}))
+			Expect(merged.Message).To(Equal("egress-allowed"))
+			Expect(merged.Data).To(Equal(lager.Data{"source": container, "packet": parsedData}))
 		})
 	})
 
@@ -91,10 +159,8 @@ var _ = Describe("Merger", func() {
 			Expect(fakeContainerRepo.GetByIPCallCount()).To(Equal(1))
 			Expect(fakeContainerRepo.GetByIPArgsForCall(0)).To(Equal("5.6.7.8"))
 
-			Expect(merged).To(Equal(merger.IPTablesLogData{
-				Message: "ingress-denied",
-				Data:    lager.Data{"destination": container, "packet": parsedData},
-			}))
+			Expect(merged.Message).To(Equal("ingress-denied"))
+			Expect(merged.Data).To(Equal(lager.Data{"destination": container, "packet": parsedData}))
 		})
 	})
 
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"fmt"
+	"iptables-logger/merger"
+	"strings"
+)
+
+const (
+	cefVendor  = "Cloud Foundry"
+	cefProduct = "iptables-logger"
+	cefVersion = "1"
+
+	cefSeverityAllowed = 3
+	cefSeverityDenied  = 6
+)
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// formatCEF encodes the event in the ArcSight Common Event Format. The
+// container is in the custom string fields cs1 to cs4, labelled with the
+// names of the json fields. Summary events have their count in cnt and no
+// source or destination IP.
+func formatCEF(event merger.Event) []byte {
+	severity, action, direction := cefSeverityAllowed, "allowed", "1"
+	if !event.Allowed {
+		severity, action = cefSeverityDenied, "denied"
+	}
+	if event.Direction == "ingress" {
+		direction = "0"
+	}
+
+	extension := [][2]string{
+		{"rt", fmt.Sprintf("%d", event.Timestamp.UnixNano()/1e6)},
+		{"dvchost", event.Host},
+		{"act", action},
+		{"deviceDirection", direction},
+		{"proto", event.Protocol},
+	}
+	if event.SourceIP != "" {
+		extension = append(extension, [2]string{"src", event.SourceIP})
+	}
+	if event.SourcePort != 0 {
+		extension = append(extension, [2]string{"spt", fmt.Sprintf("%d", event.SourcePort)})
+	}
+	if event.DestinationIP != "" {
+		extension = append(extension, [2]string{"dst", event.DestinationIP})
+	}
+	if event.DestinationPort != 0 {
+		extension = append(extension, [2]string{"dpt", fmt.Sprintf("%d", event.DestinationPort)})
+	}
+	if event.Protocol == "ICMP" {
+		extension = append(extension,
+			[2]string{"cn1Label", "icmp_type"}, [2]string{"cn1", fmt.Sprintf("%d", event.ICMPType)},
+			[2]string{"cn2Label", "icmp_code"}, [2]string{"cn2", fmt.Sprintf("%d", event.ICMPCode)},
+		)
+	}
+	if event.Count != 0 {
+		extension = append(extension, [2]string{"cnt", fmt.Sprintf("%d", event.Count)})
+	}
+	extension = append(extension,
+		[2]string{"cs1Label", "container_id"}, [2]string{"cs1", event.Container.Handle},
+		[2]string{"cs2Label", "app_guid"}, [2]string{"cs2", event.Container.AppID},
+		[2]string{"cs3Label", "space_guid"}, [2]string{"cs3", event.Container.SpaceID},
+		[2]string{"cs4Label", "organization_guid"}, [2]string{"cs4", event.Container.OrgID},
+	)
+
+	pairs := make([]string, len(extension))
+	for i, pair := range extension {
+		pairs[i] = pair[0] + "=" + cefExtensionEscaper.Replace(pair[1])
+	}
+
+	header := []string{
+		"CEF:0",
+		cefHeaderEscaper.Replace(cefVendor),
+		cefHeaderEscaper.Replace(cefProduct),
+		cefHeaderEscaper.Replace(cefVersion),
+		cefHeaderEscaper.Replace(event.Name),
+		cefHeaderEscaper.Replace(event.Name),
+		fmt.Sprintf("%d", severity),
+	}
+	return []byte(strings.Join(header, "|") + "|" + strings.Join(pairs, " "))
+}
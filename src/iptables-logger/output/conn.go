@@ -0,0 +1,59 @@
+package output
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds dialing and each write of the network outputs.
+const DefaultTimeout = 5 * time.Second
+
+// Conn is a network connection that is dialed on the first write, and
+// dialed again on the write after one fails. Dialing and each write may take
+// up to Timeout, so the network outputs are written through a QueueSink.
+type Conn struct {
+	Network string
+	Address string
+	Timeout time.Duration
+
+	mutex sync.Mutex
+	conn  net.Conn
+}
+
+func (c *Conn) Write(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		conn, err := net.DialTimeout(c.Network, c.Address, c.Timeout)
+		if err != nil {
+			return 0, fmt.Errorf("dial: %s", err)
+		}
+		c.conn = conn
+	}
+
+	if c.Timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.Timeout))
+	}
+	n, err := c.conn.Write(p)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return n, err
+	}
+	return n, nil
+}
+
+func (c *Conn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
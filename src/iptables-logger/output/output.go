@@ -0,0 +1,118 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"iptables-logger/merger"
+
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	TypeFile      = "file"
+	TypeSyslog    = "syslog"
+	TypeForwarder = "forwarder"
+)
+
+const (
+	FormatLager = "lager"
+	FormatJSON  = "json"
+	FormatCEF   = "cef"
+)
+
+// ValidType reports whether outputType is known.
+func ValidType(outputType string) bool {
+	switch outputType {
+	case TypeFile, TypeSyslog, TypeForwarder:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidFormat reports whether an output of outputType can write format. The
+// empty format is the default of the output type. Only files are written
+// as lager lines.
+func ValidFormat(outputType, format string) bool {
+	switch format {
+	case "", FormatJSON, FormatCEF:
+		return true
+	case FormatLager:
+		return outputType == TypeFile
+	default:
+		return false
+	}
+}
+
+// DefaultFormat is the format of an output of outputType that sets none.
+func DefaultFormat(outputType string) string {
+	if outputType == TypeFile {
+		return FormatLager
+	}
+	return FormatJSON
+}
+
+// ValidNetwork reports whether an output of outputType can send over
+// network.
+func ValidNetwork(outputType, network string) bool {
+	switch outputType {
+	case TypeSyslog:
+		return network == "udp" || network == "tcp" || network == "unix" || network == "unixgram"
+	case TypeForwarder:
+		return network == "udp" || network == "tcp"
+	default:
+		return false
+	}
+}
+
+// Format encodes the event as a single line, without a newline.
+func Format(format string, event merger.Event) ([]byte, error) {
+	switch format {
+	case FormatCEF:
+		return formatCEF(event), nil
+	case FormatJSON:
+		return json.Marshal(event)
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// LagerSink writes events as lager lines, like the iptables-logger always
+// has.
+type LagerSink struct {
+	Logger lager.Logger
+}
+
+func (s *LagerSink) Write(data merger.IPTablesLogData) error {
+	s.Logger.Info(data.Message, data.Data)
+	return nil
+}
+
+// WriterSink writes each event as a line in Format. With a Conn as its
+// Writer it forwards the events over the network.
+type WriterSink struct {
+	Writer io.Writer
+	Format string
+}
+
+// NewForwarderSink returns a sink that sends the events to address, one per
+// line, or one per datagram over udp.
+func NewForwarderSink(network, address, format string) *WriterSink {
+	return &WriterSink{
+		Writer: &Conn{Network: network, Address: address, Timeout: DefaultTimeout},
+		Format: format,
+	}
+}
+
+func (s *WriterSink) Write(data merger.IPTablesLogData) error {
+	line, err := Format(s.Format, data.Event)
+	if err != nil {
+		return fmt.Errorf("format event: %s", err)
+	}
+
+	if _, err := s.Writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write event: %s", err)
+	}
+	return nil
+}
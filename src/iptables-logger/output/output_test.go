@@ -0,0 +1,227 @@
+package output_test
+
+import (
+	"bytes"
+	"errors"
+	"iptables-logger/merger"
+	"iptables-logger/output"
+	"iptables-logger/repository"
+	"net"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Output", func() {
+	var (
+		event merger.Event
+		data  merger.IPTablesLogData
+	)
+
+	BeforeEach(func() {
+		event = merger.Event{
+			Timestamp:       time.Date(2017, time.May, 3, 23, 35, 7, 123456000, time.UTC),
+			KernelUptime:    87981.320056,
+			Host:            "some-host",
+			Name:            "ingress-allowed",
+			Direction:       "ingress",
+			Allowed:         true,
+			Protocol:        "TCP",
+			SourceIP:        "10.255.15.7",
+			SourcePort:      60012,
+			DestinationIP:   "10.255.15.13",
+			DestinationPort: 8080,
+			Mark:            "0x2",
+			Container: repository.Container{
+				Handle:  "some-handle",
+				AppID:   "some-app-guid",
+				SpaceID: "some-space-guid",
+				OrgID:   "some-org-guid",
+			},
+		}
+		data = merger.IPTablesLogData{
+			Message: "ingress-allowed",
+			Data:    lager.Data{"foo": "bar"},
+			Event:   event,
+		}
+	})
+
+	Describe("Format", func() {
+		Context("when the format is json", func() {
+			It("encodes the event with the documented field names", func() {
+				line, err := output.Format(output.FormatJSON, event)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(line).To(MatchJSON(`{
+					"timestamp": "2017-05-03T23:35:07.123456Z",
+					"kernel_uptime_seconds": 87981.320056,
+					"host": "some-host",
+					"event": "ingress-allowed",
+					"direction": "ingress",
+					"allowed": true,
+					"protocol": "TCP",
+					"src_ip": "10.255.15.7",
+					"src_port": 60012,
+					"dst_ip": "10.255.15.13",
+					"dst_port": 8080,
+					"icmp_type": 0,
+					"icmp_code": 0,
+					"mark": "0x2",
+					"container": {
+						"container_id": "some-handle",
+						"app_guid": "some-app-guid",
+						"space_guid": "some-space-guid",
+						"organization_guid": "some-org-guid"
+					}
+				}`))
+			})
+		})
+
+		Context("when the format is cef", func() {
+			It("encodes the event as a CEF line", func() {
+				line, err := output.Format(output.FormatCEF, event)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(line)).To(Equal("CEF:0|Cloud Foundry|iptables-logger|1|ingress-allowed|ingress-allowed|3|" +
+					"rt=1493854507123 dvchost=some-host act=allowed deviceDirection=0 proto=TCP " +
+					"src=10.255.15.7 spt=60012 dst=10.255.15.13 dpt=8080 " +
+					"cs1Label=container_id cs1=some-handle cs2Label=app_guid cs2=some-app-guid " +
+					"cs3Label=space_guid cs3=some-space-guid cs4Label=organization_guid cs4=some-org-guid"))
+			})
+
+			It("escapes the extension values", func() {
+				event.Container.Handle = `some=handle\`
+				line, err := output.Format(output.FormatCEF, event)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(line)).To(ContainSubstring(`cs1=some\=handle\\ `))
+			})
+
+			Context("when the packet is a denied icmp packet", func() {
+				BeforeEach(func() {
+					event.Name = "egress-denied"
+					event.Direction = "egress"
+					event.Allowed = false
+					event.Protocol = "ICMP"
+					event.SourcePort = 0
+					event.DestinationPort = 0
+					event.ICMPType = 8
+				})
+
+				It("has no ports, but the icmp type and code", func() {
+					line, err := output.Format(output.FormatCEF, event)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(line)).To(HavePrefix("CEF:0|Cloud Foundry|iptables-logger|1|egress-denied|egress-denied|6|"))
+					Expect(string(line)).To(ContainSubstring("act=denied deviceDirection=1 proto=ICMP src=10.255.15.7 dst=10.255.15.13 cn1Label=icmp_type cn1=8 cn2Label=icmp_code cn2=0 "))
+				})
+			})
+
+			Context("when the event is a summary", func() {
+				BeforeEach(func() {
+					event.Name = "ingress-allowed-summary"
+					event.SourceIP = ""
+					event.SourcePort = 0
+					event.DestinationIP = ""
+					event.Count = 42
+				})
+
+				It("has the count, but no ips", func() {
+					line, err := output.Format(output.FormatCEF, event)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(line)).To(ContainSubstring("proto=TCP dpt=8080 cnt=42 cs1Label=container_id "))
+				})
+			})
+		})
+
+		Context("when the format is unknown", func() {
+			It("returns an error", func() {
+				_, err := output.Format(output.FormatLager, event)
+				Expect(err).To(MatchError("unknown format: lager"))
+			})
+		})
+	})
+
+	Describe("ValidFormat", func() {
+		It("only allows lager lines in files", func() {
+			Expect(output.ValidFormat(output.TypeFile, output.FormatLager)).To(BeTrue())
+			Expect(output.ValidFormat(output.TypeSyslog, output.FormatLager)).To(BeFalse())
+			Expect(output.ValidFormat(output.TypeForwarder, output.FormatCEF)).To(BeTrue())
+			Expect(output.ValidFormat(output.TypeFile, "xml")).To(BeFalse())
+		})
+	})
+
+	Describe("LagerSink", func() {
+		It("logs the message and data at info level", func() {
+			logger := lagertest.NewTestLogger("iptables")
+			sink := &output.LagerSink{Logger: logger}
+
+			Expect(sink.Write(data)).To(Succeed())
+
+			Expect(logger.Logs()).To(HaveLen(1))
+			Expect(logger.Logs()[0].Message).To(Equal("iptables.ingress-allowed"))
+			Expect(logger.Logs()[0].LogLevel).To(Equal(lager.INFO))
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue("foo", "bar"))
+		})
+	})
+
+	Describe("WriterSink", func() {
+		It("writes the event as a line", func() {
+			buffer := &bytes.Buffer{}
+			sink := &output.WriterSink{Writer: buffer, Format: output.FormatCEF}
+
+			Expect(sink.Write(data)).To(Succeed())
+			Expect(buffer.String()).To(HavePrefix("CEF:0|"))
+			Expect(buffer.String()).To(HaveSuffix("cs4=some-org-guid\n"))
+		})
+
+		Context("when writing fails", func() {
+			It("returns an error", func() {
+				sink := &output.WriterSink{Writer: &failingWriter{}, Format: output.FormatJSON}
+				Expect(sink.Write(data)).To(MatchError("write event: banana"))
+			})
+		})
+	})
+
+	Describe("NewForwarderSink", func() {
+		It("sends each event as a datagram over udp", func() {
+			listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			defer listener.Close()
+
+			sink := output.NewForwarderSink("udp", listener.LocalAddr().String(), output.FormatJSON)
+			Expect(sink.Write(data)).To(Succeed())
+
+			buffer := make([]byte, 4096)
+			listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+			n, _, err := listener.ReadFrom(buffer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(buffer[:n])).To(HaveSuffix("}\n"))
+			Expect(string(buffer[:n])).To(ContainSubstring(`"event":"ingress-allowed"`))
+		})
+
+		Context("when nothing listens at the address", func() {
+			It("returns an error and dials again on the next event", func() {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				Expect(err).NotTo(HaveOccurred())
+				address := listener.Addr().String()
+				Expect(listener.Close()).To(Succeed())
+
+				sink := output.NewForwarderSink("tcp", address, output.FormatJSON)
+				Expect(sink.Write(data)).To(MatchError(ContainSubstring("write event: dial:")))
+
+				listener, err = net.Listen("tcp", address)
+				Expect(err).NotTo(HaveOccurred())
+				defer listener.Close()
+
+				Expect(sink.Write(data)).To(Succeed())
+			})
+		})
+	})
+})
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("banana")
+}
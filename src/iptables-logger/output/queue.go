@@ -0,0 +1,102 @@
+package output
+
+import (
+	"iptables-logger/merger"
+	"os"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// DefaultQueueSize bounds the events waiting to be sent to a network output.
+const DefaultQueueSize = 1024
+
+type eventSink interface {
+	Write(merger.IPTablesLogData) error
+}
+
+// QueueSink writes the events to Sink in the background, so that a slow or
+// unreachable output does not hold up the kernel log or the other outputs.
+// An event written while the queue is full is dropped and counted.
+type QueueSink struct {
+	Sink   eventSink
+	Logger lager.Logger
+
+	queue   chan merger.IPTablesLogData
+	dropped uint64
+}
+
+func NewQueueSink(sink eventSink, size int, logger lager.Logger) *QueueSink {
+	return &QueueSink{
+		Sink:   sink,
+		Logger: logger,
+		queue:  make(chan merger.IPTablesLogData, size),
+	}
+}
+
+// Write queues the event. It never blocks and never fails: errors sending
+// the event are logged by Run.
+func (q *QueueSink) Write(data merger.IPTablesLogData) error {
+	select {
+	case q.queue <- data:
+	default:
+		atomic.AddUint64(&q.dropped, 1)
+	}
+	return nil
+}
+
+// Dropped is the number of events dropped because the queue was full.
+func (q *QueueSink) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// Run sends the queued events until it is signalled. It then sends the
+// events still queued, until one fails.
+func (q *QueueSink) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+	var reported uint64
+	for {
+		// a signal takes precedence over the queued events
+		select {
+		case <-signals:
+			q.drain()
+			return nil
+		default:
+		}
+
+		select {
+		case <-signals:
+			q.drain()
+			return nil
+		case data := <-q.queue:
+			q.send(data)
+			if dropped := q.Dropped(); dropped != reported {
+				q.Logger.Info("dropped-events", lager.Data{"dropped": dropped})
+				reported = dropped
+			}
+		}
+	}
+}
+
+func (q *QueueSink) send(data merger.IPTablesLogData) bool {
+	if err := q.Sink.Write(data); err != nil {
+		q.Logger.Error("write-event", err)
+		return false
+	}
+	return true
+}
+
+func (q *QueueSink) drain() {
+	for {
+		select {
+		case data := <-q.queue:
+			if !q.send(data) {
+				atomic.AddUint64(&q.dropped, uint64(len(q.queue)))
+				q.Logger.Info("dropped-events", lager.Data{"dropped": q.Dropped()})
+				return
+			}
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package output_test
+
+import (
+	"errors"
+	"iptables-logger/fakes"
+	"iptables-logger/merger"
+	"iptables-logger/output"
+	"os"
+
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("QueueSink", func() {
+	var (
+		fakeSink  *fakes.EventSink
+		logger    *lagertest.TestLogger
+		queueSink *output.QueueSink
+		unblock   chan struct{}
+		data      merger.IPTablesLogData
+	)
+
+	BeforeEach(func() {
+		fakeSink = &fakes.EventSink{}
+		logger = lagertest.NewTestLogger("test")
+		queueSink = output.NewQueueSink(fakeSink, 2, logger)
+		unblock = make(chan struct{})
+		data = merger.IPTablesLogData{Message: "some-message"}
+	})
+
+	It("sends the events in the background", func() {
+		fakeSink.WriteStub = func(merger.IPTablesLogData) error {
+			<-unblock
+			return nil
+		}
+		process := ifrit.Invoke(queueSink)
+		defer process.Signal(os.Interrupt)
+
+		Expect(queueSink.Write(data)).To(Succeed())
+		Expect(queueSink.Write(data)).To(Succeed())
+		Eventually(fakeSink.WriteCallCount).Should(Equal(1))
+
+		close(unblock)
+		Eventually(fakeSink.WriteCallCount).Should(Equal(2))
+		Expect(fakeSink.WriteArgsForCall(1)).To(Equal(data))
+	})
+
+	It("drops and counts the events written while the queue is full", func() {
+		for i := 0; i < 5; i++ {
+			Expect(queueSink.Write(data)).To(Succeed())
+		}
+		Expect(queueSink.Dropped()).To(Equal(uint64(3)))
+
+		process := ifrit.Invoke(queueSink)
+		defer process.Signal(os.Interrupt)
+
+		Eventually(fakeSink.WriteCallCount).Should(Equal(2))
+		Eventually(logger).Should(gbytes.Say(`dropped-events.*"dropped":3`))
+	})
+
+	It("logs the events that fail to be sent", func() {
+		fakeSink.WriteReturns(errors.New("banana"))
+		process := ifrit.Invoke(queueSink)
+		defer process.Signal(os.Interrupt)
+
+		Expect(queueSink.Write(data)).To(Succeed())
+		Eventually(logger).Should(gbytes.Say("write-event.*banana"))
+	})
+
+	Context("when it is signalled", func() {
+		It("sends the events still queued until one fails", func() {
+			queueSink = output.NewQueueSink(fakeSink, 3, logger)
+			Expect(queueSink.Write(data)).To(Succeed())
+			Expect(queueSink.Write(data)).To(Succeed())
+			Expect(queueSink.Write(data)).To(Succeed())
+			fakeSink.WriteReturnsOnCall(1, errors.New("banana"))
+
+			signals := make(chan os.Signal, 1)
+			signals <- os.Interrupt
+			Expect(queueSink.Run(signals, make(chan struct{}))).To(Succeed())
+
+			Expect(fakeSink.WriteCallCount()).To(Equal(2))
+			Expect(queueSink.Dropped()).To(Equal(uint64(1)))
+		})
+	})
+})
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"iptables-logger/merger"
+)
+
+const (
+	syslogFacilityLocal0  = 16
+	syslogSeverityWarning = 4
+	syslogSeverityInfo    = 6
+	syslogTimestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+	syslogNilValue        = "-"
+	syslogAppName         = "iptables-logger"
+
+	syslogMaxHostname = 255
+	syslogMaxAppName  = 48
+	syslogMaxMsgID    = 32
+)
+
+// SyslogSink sends each event as an RFC 5424 syslog message, whose message
+// is the event in Format. Denied packets are logged as warnings.
+type SyslogSink struct {
+	Writer io.Writer
+	Format string
+	// OctetCounting frames the messages with their length, as RFC 6587
+	// describes for stream transports. Datagrams are not framed.
+	OctetCounting bool
+	// Hostname is used for the events that have no host of their own.
+	Hostname string
+	AppName  string
+}
+
+// NewSyslogSink returns a sink that sends the events to the syslog server
+// listening on address.
+func NewSyslogSink(network, address, format, hostname string) *SyslogSink {
+	return &SyslogSink{
+		Writer:        &Conn{Network: network, Address: address, Timeout: DefaultTimeout},
+		Format:        format,
+		OctetCounting: network == "tcp" || network == "unix",
+		Hostname:      hostname,
+		AppName:       syslogAppName,
+	}
+}
+
+func (s *SyslogSink) Write(data merger.IPTablesLogData) error {
+	event := data.Event
+	body, err := Format(s.Format, event)
+	if err != nil {
+		return fmt.Errorf("format event: %s", err)
+	}
+
+	severity := syslogSeverityInfo
+	if !event.Allowed {
+		severity = syslogSeverityWarning
+	}
+
+	hostname := event.Host
+	if hostname == "" {
+		hostname = s.Hostname
+	}
+
+	message := fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		syslogFacilityLocal0*8+severity,
+		syslogTimestamp(event),
+		syslogHeaderField(hostname, syslogMaxHostname),
+		syslogHeaderField(s.AppName, syslogMaxAppName),
+		syslogNilValue,
+		syslogHeaderField(event.Name, syslogMaxMsgID),
+		syslogNilValue,
+		body,
+	)
+	if s.OctetCounting {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+
+	if _, err := s.Writer.Write([]byte(message)); err != nil {
+		return fmt.Errorf("write event: %s", err)
+	}
+	return nil
+}
+
+func syslogTimestamp(event merger.Event) string {
+	if event.Timestamp.IsZero() {
+		return syslogNilValue
+	}
+	return event.Timestamp.Format(syslogTimestampFormat)
+}
+
+// syslogHeaderField truncates a header field to the length RFC 5424 allows,
+// and stands in the nil value for an empty one.
+func syslogHeaderField(value string, maxLength int) string {
+	if value == "" {
+		return syslogNilValue
+	}
+	if len(value) > maxLength {
+		return value[:maxLength]
+	}
+	return value
+}
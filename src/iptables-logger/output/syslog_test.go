@@ -0,0 +1,86 @@
+package output_test
+
+import (
+	"bytes"
+	"iptables-logger/merger"
+	"iptables-logger/output"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SyslogSink", func() {
+	var (
+		buffer *bytes.Buffer
+		sink   *output.SyslogSink
+		data   merger.IPTablesLogData
+	)
+
+	BeforeEach(func() {
+		buffer = &bytes.Buffer{}
+		sink = &output.SyslogSink{
+			Writer:   buffer,
+			Format:   output.FormatCEF,
+			Hostname: "some-cell",
+			AppName:  "iptables-logger",
+		}
+		data = merger.IPTablesLogData{
+			Event: merger.Event{
+				Timestamp: time.Date(2017, time.May, 3, 23, 35, 7, 123456000, time.UTC),
+				Host:      "some-host",
+				Name:      "egress-allowed",
+				Direction: "egress",
+				Allowed:   true,
+				Protocol:  "TCP",
+			},
+		}
+	})
+
+	It("writes an RFC 5424 message with the event as its message", func() {
+		Expect(sink.Write(data)).To(Succeed())
+		Expect(buffer.String()).To(HavePrefix("<134>1 2017-05-03T23:35:07.123456Z some-host iptables-logger - egress-allowed - CEF:0|"))
+		Expect(buffer.String()).NotTo(HaveSuffix("\n"))
+	})
+
+	Context("when the packet was denied", func() {
+		BeforeEach(func() {
+			data.Event.Allowed = false
+		})
+
+		It("logs it as a warning", func() {
+			Expect(sink.Write(data)).To(Succeed())
+			Expect(buffer.String()).To(HavePrefix("<132>1 "))
+		})
+	})
+
+	Context("when the event has no host", func() {
+		BeforeEach(func() {
+			data.Event.Host = ""
+		})
+
+		It("uses the hostname of the sink", func() {
+			Expect(sink.Write(data)).To(Succeed())
+			Expect(buffer.String()).To(HavePrefix("<134>1 2017-05-03T23:35:07.123456Z some-cell iptables-logger "))
+		})
+	})
+
+	Context("when the messages are octet counted", func() {
+		BeforeEach(func() {
+			sink.OctetCounting = true
+		})
+
+		It("prefixes each message with its length", func() {
+			Expect(sink.Write(data)).To(Succeed())
+
+			frame := strings.SplitN(buffer.String(), " ", 2)
+			Expect(frame).To(HaveLen(2))
+			length, err := strconv.Atoi(frame[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(frame[1]).To(HavePrefix("<134>1 "))
+			Expect(len(frame[1])).To(Equal(length))
+		})
+	})
+})
@@ -3,6 +3,7 @@ package parser
 import (
 	"strconv"
 	"strings"
+	"time"
 )
 
 type ParsedData struct {
@@ -16,9 +17,21 @@ type ParsedData struct {
 	Mark            string `json:"mark"`
 	ICMPType        int    `json:"icmp_type"`
 	ICMPCode        int    `json:"icmp_code"`
+
+	// Timestamp, Host and KernelUptime come from the prefix of the line, and
+	// LogPrefix is the prefix of the iptables rule that logged it. They are
+	// not part of the packet.
+	Timestamp    time.Time `json:"-"`
+	Host         string    `json:"-"`
+	KernelUptime float64   `json:"-"`
+	LogPrefix    string    `json:"-"`
 }
 
 type KernelLogParser struct {
+	// Now is the time the line is read, which completes the syslog timestamp
+	// and stands in for a timestamp that cannot be parsed. It defaults to
+	// time.Now.
+	Now func() time.Time
 }
 
 func (k *KernelLogParser) IsIPTablesLogData(line string) bool {
@@ -31,8 +44,12 @@ func (k *KernelLogParser) Parse(line string) ParsedData {
 	}
 
 	data := map[string]string{}
+	var logPrefix string
 	words := strings.Fields(line)
 	for _, word := range words {
+		if logPrefix == "" && (strings.HasPrefix(word, "OK_") || strings.HasPrefix(word, "DENY_")) {
+			logPrefix = word
+		}
 		if equalSignIndex := strings.Index(word, "="); equalSignIndex > -1 {
 			key := word[:equalSignIndex]
 			value := word[equalSignIndex+1:]
@@ -79,5 +96,62 @@ func (k *KernelLogParser) Parse(line string) ParsedData {
 		ICMPType:        icmpType,
 		ICMPCode:        icmpCode,
 	}
+	parsed.Timestamp, parsed.Host = k.parseTimestamp(words)
+	parsed.KernelUptime = parseKernelUptime(line)
+	parsed.LogPrefix = logPrefix
 	return parsed
 }
+
+// parseTimestamp reads the timestamp and host that syslog writes before the
+// kernel message, either in the traditional "May  3 23:34:07 host" format or
+// in the RFC 3339 format of rsyslog's high precision timestamps.
+func (k *KernelLogParser) parseTimestamp(words []string) (time.Time, string) {
+	now := k.now()
+
+	if len(words) >= 2 {
+		if timestamp, err := time.Parse(time.RFC3339Nano, words[0]); err == nil {
+			return timestamp, words[1]
+		}
+	}
+
+	if len(words) >= 4 {
+		stamp := strings.Join(words[:3], " ")
+		if timestamp, err := time.ParseInLocation(time.Stamp, stamp, now.Location()); err == nil {
+			// the syslog timestamp has no year, and a line from the end of
+			// December may be read in January
+			timestamp = timestamp.AddDate(now.Year(), 0, 0)
+			if timestamp.Sub(now) > 24*time.Hour {
+				timestamp = timestamp.AddDate(-1, 0, 0)
+			}
+			return timestamp, words[3]
+		}
+	}
+
+	return now, ""
+}
+
+func (k *KernelLogParser) now() time.Time {
+	if k.Now == nil {
+		return time.Now()
+	}
+	return k.Now()
+}
+
+// parseKernelUptime reads the seconds since boot, e.g. "[87921.493829]", that
+// the kernel puts before its message.
+func parseKernelUptime(line string) float64 {
+	start := strings.Index(line, "kernel: [")
+	if start == -1 {
+		return 0
+	}
+	start += len("kernel: [")
+	end := strings.Index(line[start:], "]")
+	if end == -1 {
+		return 0
+	}
+	uptime, err := strconv.ParseFloat(strings.TrimSpace(line[start:start+end]), 64)
+	if err != nil {
+		return 0
+	}
+	return uptime
+}
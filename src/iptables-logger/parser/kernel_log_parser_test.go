@@ -2,6 +2,7 @@ package parser_test
 
 import (
 	"iptables-logger/parser"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -37,7 +38,7 @@ var _ = Describe("KernelLogParser", func() {
 
 	Describe("Parsing different kernel log messages for TCP or UDP", func() {
 		It("ingress allowed", func() {
-			Expect(kernelLogParser.Parse(ingressAllowedTCP)).To(Equal(
+			Expect(packetOf(kernelLogParser.Parse(ingressAllowedTCP))).To(Equal(
 				parser.ParsedData{
 					Direction:       "ingress",
 					Allowed:         true,
@@ -54,7 +55,7 @@ var _ = Describe("KernelLogParser", func() {
 		})
 
 		It("ingress denied", func() {
-			Expect(kernelLogParser.Parse(ingressDeniedTCP)).To(Equal(
+			Expect(packetOf(kernelLogParser.Parse(ingressDeniedTCP))).To(Equal(
 				parser.ParsedData{
 					Direction:       "ingress",
 					Allowed:         false,
@@ -71,7 +72,7 @@ var _ = Describe("KernelLogParser", func() {
 		})
 
 		It("egress allowed", func() {
-			Expect(kernelLogParser.Parse(egressAllowedUDP)).To(Equal(
+			Expect(packetOf(kernelLogParser.Parse(egressAllowedUDP))).To(Equal(
 				parser.ParsedData{
 					Direction:       "egress",
 					Allowed:         true,
@@ -88,7 +89,7 @@ var _ = Describe("KernelLogParser", func() {
 		})
 
 		It("egress denied", func() {
-			Expect(kernelLogParser.Parse(egressDeniedTCP)).To(Equal(
+			Expect(packetOf(kernelLogParser.Parse(egressDeniedTCP))).To(Equal(
 				parser.ParsedData{
 					Direction:       "egress",
 					Allowed:         false,
@@ -105,7 +106,7 @@ var _ = Describe("KernelLogParser", func() {
 		})
 		Describe("Parsing log messages for ICMP", func() {
 			It("egress denied", func() {
-				Expect(kernelLogParser.Parse(egressDeniedICMP)).To(Equal(
+				Expect(packetOf(kernelLogParser.Parse(egressDeniedICMP))).To(Equal(
 					parser.ParsedData{
 						Direction:       "egress",
 						Allowed:         false,
@@ -127,4 +128,63 @@ var _ = Describe("KernelLogParser", func() {
 			})
 		})
 	})
+
+	Describe("Parsing the prefix of the line", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Date(2017, time.June, 30, 12, 0, 0, 0, time.UTC)
+			kernelLogParser.Now = func() time.Time { return now }
+		})
+
+		It("reads the syslog timestamp, the host and the kernel uptime", func() {
+			parsed := kernelLogParser.Parse(ingressAllowedTCP)
+			Expect(parsed.Timestamp).To(Equal(time.Date(2017, time.May, 3, 23, 35, 7, 0, time.UTC)))
+			Expect(parsed.Host).To(Equal("localhost"))
+			Expect(parsed.KernelUptime).To(Equal(87981.320056))
+		})
+
+		It("reads the log prefix of the iptables rule", func() {
+			Expect(kernelLogParser.Parse(ingressAllowedTCP).LogPrefix).To(Equal("OK_0002_e9e8959f-3828-4136-8"))
+			Expect(kernelLogParser.Parse(ingressDeniedTCP).LogPrefix).To(Equal("DENY_C2C_cb40f81e-52ce-41c5-"))
+		})
+
+		Context("when the syslog timestamp is from the end of the previous year", func() {
+			BeforeEach(func() {
+				now = time.Date(2018, time.January, 1, 0, 0, 10, 0, time.UTC)
+			})
+
+			It("uses the previous year", func() {
+				parsed := kernelLogParser.Parse("Dec 31 23:59:58 localhost kernel: [1.5] OK_0002_e9e8959f-3828-4136-8 SRC=10.255.15.7")
+				Expect(parsed.Timestamp).To(Equal(time.Date(2017, time.December, 31, 23, 59, 58, 0, time.UTC)))
+			})
+		})
+
+		Context("when the timestamp is in the RFC 3339 format", func() {
+			It("reads it", func() {
+				parsed := kernelLogParser.Parse("2017-05-03T23:35:07.123456+01:00 some-cell kernel: [   12.5] OK_0002_e9e8959f-3828-4136-8 SRC=10.255.15.7")
+				Expect(parsed.Timestamp.Equal(time.Date(2017, time.May, 3, 22, 35, 7, 123456000, time.UTC))).To(BeTrue())
+				Expect(parsed.Host).To(Equal("some-cell"))
+				Expect(parsed.KernelUptime).To(Equal(12.5))
+			})
+		})
+
+		Context("when the line has no timestamp", func() {
+			It("uses the time the line is read", func() {
+				parsed := kernelLogParser.Parse("OK_0002_e9e8959f-3828-4136-8 SRC=10.255.15.7")
+				Expect(parsed.Timestamp).To(Equal(now))
+				Expect(parsed.Host).To(BeEmpty())
+				Expect(parsed.KernelUptime).To(BeZero())
+			})
+		})
+	})
 })
+
+// packetOf clears the fields that come from the prefix of the line.
+func packetOf(parsed parser.ParsedData) parser.ParsedData {
+	parsed.Timestamp = time.Time{}
+	parsed.Host = ""
+	parsed.KernelUptime = 0
+	parsed.LogPrefix = ""
+	return parsed
+}
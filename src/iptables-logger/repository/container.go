@@ -12,38 +12,70 @@ type Container struct {
 	OrgID   string `json:"organization_guid"`
 }
 
+//go:generate counterfeiter -o ../fakes/container_index.go --fake-name ContainerIndex . containerIndex
+type containerIndex interface {
+	GetByIP(ip string) (datastore.Container, bool, error)
+}
+
 type ContainerRepo struct {
 	Store datastore.Datastore
+	// Index looks up containers without reading the whole Store. It is nil
+	// when the Store has no index by IP.
+	Index containerIndex
 }
 
 func (c *ContainerRepo) GetByIP(ip string) (Container, error) {
+	if c.Index != nil {
+		container, found, err := c.Index.GetByIP(ip)
+		if err != nil {
+			return Container{}, fmt.Errorf("get by ip: %s", err)
+		}
+		if !found {
+			return Container{}, nil
+		}
+		return newContainer(container), nil
+	}
+
 	containers, err := c.Store.ReadAll()
 	if err != nil {
 		return Container{}, fmt.Errorf("read all: %s", err)
 	}
 
 	for _, container := range containers {
-		if container.IP == ip {
-			appID, ok := container.Metadata["app_id"].(string)
-			if !ok {
-				appID = ""
-			}
-			spaceID, ok := container.Metadata["space_id"].(string)
-			if !ok {
-				spaceID = ""
-			}
-			orgID, ok := container.Metadata["org_id"].(string)
-			if !ok {
-				orgID = ""
-			}
-			return Container{
-				Handle:  container.Handle,
-				AppID:   appID,
-				SpaceID: spaceID,
-				OrgID:   orgID,
-			}, nil
+		if hasIP(container, ip) {
+			return newContainer(container), nil
 		}
 	}
 
 	return Container{}, nil
 }
+
+func newContainer(container datastore.Container) Container {
+	appID, ok := container.Metadata["app_id"].(string)
+	if !ok {
+		appID = ""
+	}
+	spaceID, ok := container.Metadata["space_id"].(string)
+	if !ok {
+		spaceID = ""
+	}
+	orgID, ok := container.Metadata["org_id"].(string)
+	if !ok {
+		orgID = ""
+	}
+	return Container{
+		Handle:  container.Handle,
+		AppID:   appID,
+		SpaceID: spaceID,
+		OrgID:   orgID,
+	}
+}
+
+func hasIP(container datastore.Container, ip string) bool {
+	for _, containerIP := range container.AllIPs() {
+		if containerIP == ip {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package repository_test
 
 import (
 	"errors"
+	iptablesfakes "iptables-logger/fakes"
 	"iptables-logger/repository"
 	"lib/datastore"
 	"lib/fakes"
@@ -37,6 +38,14 @@ var _ = Describe("Container", func() {
 				IP:       "ip-2",
 				Metadata: map[string]interface{}{},
 			},
+			"handle-3": {
+				Handle: "handle-3",
+				IP:     "10.255.1.3",
+				IPs:    []string{"10.255.1.3", "fd00::3"},
+				Metadata: map[string]interface{}{
+					"app_id": "app-3",
+				},
+			},
 		}
 
 		fakeStore.ReadAllReturns(containers, nil)
@@ -67,6 +76,16 @@ var _ = Describe("Container", func() {
 			}))
 		})
 
+		It("looks up dual-stack containers by any of their ips", func() {
+			container, err := repo.GetByIP("fd00::3")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(container).To(Equal(repository.Container{
+				Handle: "handle-3",
+				AppID:  "app-3",
+			}))
+		})
+
 		Context("when unable to read from datastore", func() {
 			BeforeEach(func() {
 				fakeStore.ReadAllReturns(nil, errors.New("apple"))
@@ -87,5 +106,48 @@ var _ = Describe("Container", func() {
 				Expect(container.OrgID).To(BeEmpty())
 			})
 		})
+
+		Context("when the store has an index by ip", func() {
+			var fakeIndex *iptablesfakes.ContainerIndex
+
+			BeforeEach(func() {
+				fakeIndex = &iptablesfakes.ContainerIndex{}
+				fakeIndex.GetByIPReturns(datastore.Container{
+					Handle: "handle-4",
+					IP:     "ip-4",
+					Metadata: map[string]interface{}{
+						"app_id": "app-4",
+					},
+				}, true, nil)
+				repo.Index = fakeIndex
+			})
+
+			It("looks up the container from the index without reading the store", func() {
+				container, err := repo.GetByIP("ip-4")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(container).To(Equal(repository.Container{
+					Handle: "handle-4",
+					AppID:  "app-4",
+				}))
+
+				Expect(fakeIndex.GetByIPArgsForCall(0)).To(Equal("ip-4"))
+				Expect(fakeStore.ReadAllCallCount()).To(Equal(0))
+			})
+
+			It("returns an empty container when the ip is not indexed", func() {
+				fakeIndex.GetByIPReturns(datastore.Container{}, false, nil)
+
+				container, err := repo.GetByIP("ip-5")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(container).To(Equal(repository.Container{}))
+			})
+
+			It("returns an error when the index cannot be read", func() {
+				fakeIndex.GetByIPReturns(datastore.Container{}, false, errors.New("apple"))
+
+				_, err := repo.GetByIP("ip-4")
+				Expect(err).To(MatchError("get by ip: apple"))
+			})
+		})
 	})
 })
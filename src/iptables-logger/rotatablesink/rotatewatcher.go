@@ -16,6 +16,7 @@ type RotatableSink struct {
 	minLogLevel         lager.LogLevel
 	WriterFactory       FileWriterFactory
 	writerSink          lager.Sink
+	writer              io.Writer
 	writeL              *sync.Mutex
 	DestinationFileInfo DestinationFileInfo
 }
@@ -26,6 +27,14 @@ func (rs *RotatableSink) Log(logFmt lager.LogFormat) {
 	rs.writerSink.Log(logFmt)
 }
 
+// Write writes to the file as it is currently, so that outputs other than
+// lager lines also follow the file when it is rotated.
+func (rs *RotatableSink) Write(p []byte) (int, error) {
+	rs.writeL.Lock()
+	defer rs.writeL.Unlock()
+	return rs.writer.Write(p)
+}
+
 func NewRotatableSink(fileToWatch string, logLevel lager.LogLevel, fileWriterFactory FileWriterFactory, destinationFileInfo DestinationFileInfo, componentLogger lager.Logger) (*RotatableSink, error) {
 	var err error
 	rotatableSink := &RotatableSink{
@@ -97,6 +106,7 @@ func (rs *RotatableSink) rotateFileSink() error {
 	if err != nil {
 		return fmt.Errorf("create file writer: %s", err)
 	}
+	rs.writer = outputLogFile
 	rs.writerSink = lager.NewWriterSink(outputLogFile, rs.minLogLevel)
 	return nil
 }
@@ -189,6 +189,17 @@ var _ = Describe("Rotatewatcher", func() {
 
 	})
 
+	Describe("Write", func() {
+		It("writes to output log file as is", func() {
+			_, err := rotatableSink.Write([]byte("some-line\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(fileToWatch.Name())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("some-line\n"))
+		})
+	})
+
 	Describe("FileWriterFactory", func() {
 		It("should return a writer that can write to a file", func() {
 			writer, err := rotatablesink.DefaultFileWriter(fileToWatch.Name())
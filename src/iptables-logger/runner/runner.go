@@ -20,12 +20,18 @@ type kernelLogParser interface {
 	Parse(line string) parser.ParsedData
 }
 
+//go:generate counterfeiter -o fakes/event_sink.go --fake-name EventSink . eventSink
+type eventSink interface {
+	Write(merger.IPTablesLogData) error
+}
+
 type Runner struct {
-	Lines          chan *tail.Line
-	Parser         kernelLogParser
-	Merger         logMerger
-	Logger         lager.Logger
-	IPTablesLogger lager.Logger
+	Lines  chan *tail.Line
+	Parser kernelLogParser
+	Merger logMerger
+	Logger lager.Logger
+	// Sinks are the outputs that every event is written to.
+	Sinks []eventSink
 }
 
 func (r *Runner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
@@ -48,7 +54,11 @@ func (r *Runner) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 					r.Logger.Error("merge-kernel-logs", err)
 					continue
 				}
-				r.IPTablesLogger.Info(merged.Message, merged.Data)
+				for _, sink := range r.Sinks {
+					if err := sink.Write(merged); err != nil {
+						r.Logger.Error("write-event", err)
+					}
+				}
 			}
 		}
 	}
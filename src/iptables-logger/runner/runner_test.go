@@ -3,6 +3,7 @@ package runner_test
 import (
 	"errors"
 	"iptables-logger/merger"
+	"iptables-logger/output"
 	"iptables-logger/parser"
 	"iptables-logger/runner"
 	"iptables-logger/runner/fakes"
@@ -26,6 +27,7 @@ var _ = Describe("Runner", func() {
 		fakeMerger     *fakes.LogMerger
 		logger         *lagertest.TestLogger
 		iptablesLogger *lagertest.TestLogger
+		fakeSink       *fakes.EventSink
 		logRunner      *runner.Runner
 		logRunnerProc  ifrit.Process
 	)
@@ -36,14 +38,15 @@ var _ = Describe("Runner", func() {
 		fakeMerger = &fakes.LogMerger{}
 		logger = lagertest.NewTestLogger("test")
 		iptablesLogger = lagertest.NewTestLogger("iptables-test")
+		fakeSink = &fakes.EventSink{}
 
 		logRunner = &runner.Runner{
-			Lines:          lines,
-			Parser:         fakeParser,
-			Merger:         fakeMerger,
-			Logger:         logger,
-			IPTablesLogger: iptablesLogger,
+			Lines:  lines,
+			Parser: fakeParser,
+			Merger: fakeMerger,
+			Logger: logger,
 		}
+		logRunner.Sinks = append(logRunner.Sinks, &output.LagerSink{Logger: iptablesLogger}, fakeSink)
 	})
 
 	AfterEach(func() {
@@ -107,6 +110,36 @@ var _ = Describe("Runner", func() {
 		})
 	})
 
+	Context("when writing an event to a sink fails", func() {
+		BeforeEach(func() {
+			fakeParser.IsIPTablesLogDataReturns(true)
+			fakeMerger.MergeReturns(merger.IPTablesLogData{
+				Message: "some-message",
+				Data:    lager.Data{"foo": "bar"},
+			}, nil)
+			fakeSink.WriteReturns(errors.New("banana"))
+		})
+
+		It("logs the error and still writes the event to the other sinks", func() {
+			logRunnerProc = ifrit.Invoke(logRunner)
+			go func() {
+				lines <- &tail.Line{
+					Text: "some-line",
+				}
+			}()
+
+			Eventually(logger.Logs).Should(HaveLen(2))
+			Expect(logger.Logs()[1]).To(SatisfyAll(
+				LogsWith(lager.ERROR, "test.write-event"),
+				HaveLogData(HaveKeyWithValue("error", "banana")),
+			))
+
+			Expect(iptablesLogger.Logs()).To(HaveLen(1))
+			Expect(fakeSink.WriteCallCount()).To(Equal(1))
+			Expect(fakeSink.WriteArgsForCall(0).Message).To(Equal("some-message"))
+		})
+	})
+
 	Context("when the kernel log gets a non-iptables message", func() {
 		BeforeEach(func() {
 			fakeParser.IsIPTablesLogDataReturns(false)
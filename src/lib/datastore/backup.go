@@ -0,0 +1,76 @@
+package datastore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// backup is the last good copy of the data file, used when the data file
+// cannot be decoded. Every write first replaces the data file and only then
+// the backup, both atomically.
+type backup struct {
+	// Version counts the writes of the data file.
+	Version int `json:"version"`
+	// Recoveries counts how often the data file was restored from the backup.
+	Recoveries int             `json:"recoveries"`
+	SHA256     string          `json:"sha256"`
+	Containers json.RawMessage `json:"containers"`
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readBackup returns a nil backup when there is none yet.
+func readBackup(path string) (*backup, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b := &backup{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("decoding backup: %s", err)
+	}
+	if checksum(b.Containers) != b.SHA256 {
+		return nil, fmt.Errorf("backup checksum mismatch")
+	}
+	return b, nil
+}
+
+func (b *backup) containers() (map[string]Container, error) {
+	pool := make(map[string]Container)
+	if err := json.Unmarshal(b.Containers, &pool); err != nil {
+		return nil, fmt.Errorf("decoding backup containers: %s", err) // not tested
+	}
+	return pool, nil
+}
+
+// writeBackup replaces the backup, so that a crash leaves either the old or the
+// new backup in place.
+func writeBackup(path string, b *backup, pool map[string]Container) error {
+	containers, err := json.Marshal(pool)
+	if err != nil {
+		return err // not tested
+	}
+	b.Containers = containers
+	b.SHA256 = checksum(containers)
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err // not tested
+	}
+
+	return replaceFile(path, func(tmp *os.File) error {
+		_, err := tmp.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,253 @@
+package datastore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+)
+
+var (
+	containersBucket = []byte("containers")
+	ipsBucket        = []byte("ips")
+)
+
+var errNotCreated = errors.New("database not created")
+
+// BoltFileFor is where the bolt database replacing the JSON data file at path
+// is kept.
+func BoltFileFor(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".db"
+}
+
+// DefaultBoltTimeout is how long to wait for another process using the
+// database.
+const DefaultBoltTimeout = 10 * time.Second
+
+// BoltStore keeps each container under its handle in a bolt database, with
+// an index of the handles by IP. The database is only open for the duration
+// of each call, since bolt locks it for as long as it is open and it is
+// shared by several processes.
+type BoltStore struct {
+	Path string
+	// Timeout bounds the wait for the database lock. Zero waits forever.
+	Timeout time.Duration
+	// MigrateFrom is imported when the database is created, so that switching
+	// backends keeps the existing entries. It may be nil.
+	MigrateFrom Datastore
+	// Logger reports the migration. It may be nil.
+	Logger io.Writer
+}
+
+func (s *BoltStore) Add(handle string, ips []string, metadata map[string]interface{}) error {
+	if err := validate(handle, ips); err != nil {
+		return err
+	}
+
+	container := Container{
+		Handle:   handle,
+		IP:       PrimaryIP(ips),
+		IPs:      ips,
+		Metadata: metadata,
+	}
+
+	return s.update(func(tx *bolt.Tx) error {
+		if _, err := removeContainer(tx, handle); err != nil {
+			return err
+		}
+		return putContainer(tx, container)
+	})
+}
+
+func (s *BoltStore) Delete(handle string) (Container, error) {
+	deleted := Container{}
+	if handle == "" {
+		return deleted, fmt.Errorf("invalid handle")
+	}
+
+	err := s.update(func(tx *bolt.Tx) error {
+		var err error
+		deleted, err = removeContainer(tx, handle)
+		return err
+	})
+	return deleted, err
+}
+
+func (s *BoltStore) ReadAll() (map[string]Container, error) {
+	pool := make(map[string]Container)
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(containersBucket).ForEach(func(_, v []byte) error {
+			container := Container{}
+			if err := json.Unmarshal(v, &container); err != nil {
+				return fmt.Errorf("decoding container: %s", err)
+			}
+			pool[container.Handle] = container
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// GetByIP looks up the container with the address ip, without reading the
+// other containers.
+func (s *BoltStore) GetByIP(ip string) (Container, bool, error) {
+	container := Container{}
+	found := false
+	err := s.view(func(tx *bolt.Tx) error {
+		handle := tx.Bucket(ipsBucket).Get([]byte(ip))
+		if handle == nil {
+			return nil
+		}
+		var err error
+		container, found, err = getContainer(tx, string(handle))
+		return err
+	})
+	return container, found, err
+}
+
+func getContainer(tx *bolt.Tx, handle string) (Container, bool, error) {
+	container := Container{}
+	data := tx.Bucket(containersBucket).Get([]byte(handle))
+	if data == nil {
+		return container, false, nil
+	}
+	if err := json.Unmarshal(data, &container); err != nil {
+		return container, false, fmt.Errorf("decoding container: %s", err)
+	}
+	return container, true, nil
+}
+
+func putContainer(tx *bolt.Tx, container Container) error {
+	data, err := json.Marshal(container)
+	if err != nil {
+		return fmt.Errorf("encoding container: %s", err) // not tested
+	}
+	if err := tx.Bucket(containersBucket).Put([]byte(container.Handle), data); err != nil {
+		return err // not tested
+	}
+
+	ips := tx.Bucket(ipsBucket)
+	for _, ip := range container.AllIPs() {
+		if err := ips.Put([]byte(ip), []byte(container.Handle)); err != nil {
+			return err // not tested
+		}
+	}
+	return nil
+}
+
+// removeContainer deletes a container and the entries of the IP index that
+// still point to it.
+func removeContainer(tx *bolt.Tx, handle string) (Container, error) {
+	container, found, err := getContainer(tx, handle)
+	if err != nil || !found {
+		return container, err
+	}
+
+	ips := tx.Bucket(ipsBucket)
+	for _, ip := range container.AllIPs() {
+		if string(ips.Get([]byte(ip))) != handle {
+			continue
+		}
+		if err := ips.Delete([]byte(ip)); err != nil {
+			return container, err // not tested
+		}
+	}
+	return container, tx.Bucket(containersBucket).Delete([]byte(handle))
+}
+
+func (s *BoltStore) open(readOnly bool) (*bolt.DB, error) {
+	if !readOnly {
+		if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+			return nil, err
+		}
+	}
+	return bolt.Open(s.Path, 0600, &bolt.Options{
+		Timeout:  s.Timeout,
+		ReadOnly: readOnly,
+	})
+}
+
+func (s *BoltStore) update(fn func(*bolt.Tx) error) error {
+	db, err := s.open(false)
+	if err != nil {
+		return fmt.Errorf("open database: %s", err)
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bolt.Tx) error {
+		if err := s.create(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// view reads from the database, creating it first when it does not exist yet
+// so that the migrated entries are read.
+func (s *BoltStore) view(fn func(*bolt.Tx) error) error {
+	err := s.viewExisting(fn)
+	if err != errNotCreated {
+		return err
+	}
+	return s.update(fn)
+}
+
+func (s *BoltStore) viewExisting(fn func(*bolt.Tx) error) error {
+	if _, err := os.Stat(s.Path); os.IsNotExist(err) {
+		return errNotCreated
+	}
+
+	db, err := s.open(true)
+	if err != nil {
+		return fmt.Errorf("open database: %s", err)
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(containersBucket) == nil || tx.Bucket(ipsBucket) == nil {
+			return errNotCreated
+		}
+		return fn(tx)
+	})
+}
+
+// create makes the buckets and imports the entries of MigrateFrom, once.
+func (s *BoltStore) create(tx *bolt.Tx) error {
+	if tx.Bucket(containersBucket) != nil {
+		return nil
+	}
+
+	if _, err := tx.CreateBucket(containersBucket); err != nil {
+		return err // not tested
+	}
+	if _, err := tx.CreateBucketIfNotExists(ipsBucket); err != nil {
+		return err // not tested
+	}
+
+	if s.MigrateFrom == nil {
+		return nil
+	}
+
+	pool, err := s.MigrateFrom.ReadAll()
+	if err != nil {
+		return fmt.Errorf("migrate: %s", err)
+	}
+	for _, container := range pool {
+		if err := putContainer(tx, container); err != nil {
+			return fmt.Errorf("migrate: %s", err) // not tested
+		}
+	}
+	if s.Logger != nil {
+		fmt.Fprintf(s.Logger, "datastore: migrated %d entries to %s\n", len(pool), s.Path)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package datastore_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"lib/datastore"
+	"lib/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("BoltStore", func() {
+	var (
+		dir      string
+		store    *datastore.BoltStore
+		metadata map[string]interface{}
+		logger   *gbytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		metadata = map[string]interface{}{
+			"app_id": "some-appid",
+		}
+		logger = gbytes.NewBuffer()
+
+		store = &datastore.BoltStore{
+			Path:    filepath.Join(dir, "store.db"),
+			Timeout: time.Second,
+			Logger:  logger,
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("when empty", func() {
+		It("returns an empty map", func() {
+			data, err := store.ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(BeEmpty())
+		})
+	})
+
+	It("adds, reads and deletes entries", func() {
+		for i := 0; i < 250; i++ {
+			handle := fmt.Sprintf("handle-%d", i)
+			ip := fmt.Sprintf("10.255.%d.%d", i/200, i%200+1)
+			Expect(store.Add(handle, []string{ip}, metadata)).To(Succeed())
+		}
+
+		data, err := store.ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(HaveLen(250))
+		Expect(data["handle-7"]).To(Equal(datastore.Container{
+			Handle:   "handle-7",
+			IP:       "10.255.0.8",
+			IPs:      []string{"10.255.0.8"},
+			Metadata: metadata,
+		}))
+
+		deleted, err := store.Delete("handle-7")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted.Handle).To(Equal("handle-7"))
+
+		data, err = store.ReadAll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(HaveLen(249))
+		Expect(data).NotTo(HaveKey("handle-7"))
+	})
+
+	It("returns an empty container when deleting a missing handle", func() {
+		deleted, err := store.Delete("missing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal(datastore.Container{}))
+	})
+
+	It("validates the entries like the JSON store", func() {
+		Expect(store.Add("", []string{"10.255.0.1"}, metadata)).To(MatchError("invalid handle"))
+		Expect(store.Add("handle", []string{"banana"}, metadata)).To(MatchError("invalid ip: banana"))
+		_, err := store.Delete("")
+		Expect(err).To(MatchError("invalid handle"))
+	})
+
+	Describe("GetByIP", func() {
+		BeforeEach(func() {
+			Expect(store.Add("handle-1", []string{"10.255.0.1"}, metadata)).To(Succeed())
+			Expect(store.Add("handle-2", []string{"10.255.0.2", "fd00::2"}, metadata)).To(Succeed())
+		})
+
+		It("looks up containers by any of their ips", func() {
+			container, found, err := store.GetByIP("fd00::2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(container.Handle).To(Equal("handle-2"))
+
+			container, found, err = store.GetByIP("10.255.0.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(container.Handle).To(Equal("handle-1"))
+		})
+
+		It("does not find unknown ips", func() {
+			_, found, err := store.GetByIP("10.255.0.99")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("drops the ips of deleted containers from the index", func() {
+			_, err := store.Delete("handle-2")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, found, err := store.GetByIP("fd00::2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("replaces the ips of a container that is added again", func() {
+			Expect(store.Add("handle-2", []string{"10.255.0.3"}, metadata)).To(Succeed())
+
+			_, found, err := store.GetByIP("10.255.0.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeFalse())
+
+			container, found, err := store.GetByIP("10.255.0.3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(container.Handle).To(Equal("handle-2"))
+		})
+
+		It("keeps the ip of a container that reused the address of a deleted one", func() {
+			Expect(store.Add("handle-3", []string{"10.255.0.1"}, metadata)).To(Succeed())
+			_, err := store.Delete("handle-1")
+			Expect(err).NotTo(HaveOccurred())
+
+			container, found, err := store.GetByIP("10.255.0.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(container.Handle).To(Equal("handle-3"))
+		})
+	})
+
+	Context("when migrating from another datastore", func() {
+		var jsonStore *fakes.Datastore
+
+		BeforeEach(func() {
+			jsonStore = &fakes.Datastore{}
+			jsonStore.ReadAllReturns(map[string]datastore.Container{
+				"old-handle": {
+					Handle:   "old-handle",
+					IP:       "10.255.0.9",
+					Metadata: metadata,
+				},
+			}, nil)
+			store.MigrateFrom = jsonStore
+		})
+
+		It("imports its entries when the database is created", func() {
+			data, err := store.ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(HaveKey("old-handle"))
+			Expect(logger).To(gbytes.Say("datastore: migrated 1 entries to .*store.db"))
+
+			container, found, err := store.GetByIP("10.255.0.9")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(container.Handle).To(Equal("old-handle"))
+		})
+
+		It("only imports them once", func() {
+			_, err := store.Delete("old-handle")
+			Expect(err).NotTo(HaveOccurred())
+
+			data, err := store.ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(BeEmpty())
+			Expect(jsonStore.ReadAllCallCount()).To(Equal(1))
+		})
+
+		Context("when the other datastore cannot be read", func() {
+			BeforeEach(func() {
+				jsonStore.ReadAllReturns(nil, fmt.Errorf("banana"))
+			})
+
+			It("fails without creating the database, so that the migration is retried", func() {
+				err := store.Add("handle", []string{"10.255.0.1"}, metadata)
+				Expect(err).To(MatchError("migrate: banana"))
+
+				jsonStore.ReadAllReturns(nil, nil)
+				data, err := store.ReadAll()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(data).To(BeEmpty())
+				Expect(jsonStore.ReadAllCallCount()).To(Equal(2))
+			})
+		})
+	})
+
+	Describe("New", func() {
+		var jsonPath string
+
+		BeforeEach(func() {
+			jsonPath = filepath.Join(dir, "store.json")
+		})
+
+		It("returns the JSON store by default", func() {
+			store, err := datastore.New("", jsonPath, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store).To(BeAssignableToTypeOf(&datastore.Store{}))
+		})
+
+		It("migrates the JSON data file into the bolt store", func() {
+			jsonStore, err := datastore.New(datastore.BackendJSON, jsonPath, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jsonStore.Add("some-handle", []string{"10.255.0.1"}, metadata)).To(Succeed())
+
+			boltStore, err := datastore.New(datastore.BackendBolt, jsonPath, logger)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(boltStore.(*datastore.BoltStore).Path).To(Equal(filepath.Join(dir, "store.db")))
+
+			data, err := boltStore.ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(HaveKey("some-handle"))
+		})
+
+		It("rejects unknown backends", func() {
+			_, err := datastore.New("banana", jsonPath, logger)
+			Expect(err).To(MatchError("unknown datastore backend: banana"))
+		})
+	})
+})
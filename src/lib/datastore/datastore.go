@@ -1,43 +1,132 @@
 package datastore
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"lib/filelock"
 	"lib/serial"
 	"net"
+	"os"
+	"path/filepath"
 )
 
 //go:generate counterfeiter -o ../fakes/datastore.go --fake-name Datastore . Datastore
 type Datastore interface {
-	Add(handle, ip string, metadata map[string]interface{}) error
+	Add(handle string, ips []string, metadata map[string]interface{}) error
 	Delete(handle string) (Container, error)
 	ReadAll() (map[string]Container, error)
 }
 
+// Container is the entry of a container. IP is its primary address, the first
+// IPv4 one if it has any, and IPs holds every address of a dual-stack container.
 type Container struct {
 	Handle   string                 `json:"handle"`
 	IP       string                 `json:"ip"`
+	IPs      []string               `json:"ips,omitempty"`
 	Metadata map[string]interface{} `json:"metadata"`
 }
 
+// AllIPs returns every address of the container, including for entries
+// written before IPs was added.
+func (c Container) AllIPs() []string {
+	if len(c.IPs) > 0 {
+		return c.IPs
+	}
+	if c.IP == "" {
+		return nil
+	}
+	return []string{c.IP}
+}
+
+// BackupFileFor is where the backup of the data file at path is kept.
+func BackupFileFor(path string) string {
+	return path + ".bak"
+}
+
+const (
+	BackendJSON = "json"
+	BackendBolt = "bolt"
+)
+
+// ValidBackend reports whether backend names a datastore backend. The empty
+// backend is the JSON one.
+func ValidBackend(backend string) bool {
+	return backend == "" || backend == BackendJSON || backend == BackendBolt
+}
+
+// New returns the datastore of the backend for the JSON data file at path.
+// The bolt backend keeps its database next to it and imports the data file
+// the first time the database is created.
+func New(backend, path string, logger io.Writer) (Datastore, error) {
+	jsonStore := &Store{
+		Path:       path,
+		Serializer: &serial.Serial{},
+		Locker:     filelock.NewLocker(path),
+		BackupFile: BackupFileFor(path),
+		Logger:     logger,
+	}
+
+	switch backend {
+	case "", BackendJSON:
+		return jsonStore, nil
+	case BackendBolt:
+		return &BoltStore{
+			Path:        BoltFileFor(path),
+			Timeout:     DefaultBoltTimeout,
+			MigrateFrom: jsonStore,
+			Logger:      logger,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown datastore backend: %s", backend)
+	}
+}
+
 type Store struct {
+	// Path is the data file. Writes replace it with a rename, so that a crash
+	// leaves either the old or the new data file in place.
+	Path       string
 	Serializer serial.Serializer
 	Locker     filelock.FileLocker
+	// BackupFile keeps the last good copy of the data file, which is used
+	// when the data file cannot be decoded, e.g. after a crash while it was
+	// being written. Without it there is no recovery.
+	BackupFile string
+	// Logger reports recoveries from the backup file. It may be nil.
+	Logger io.Writer
 }
 
-func validate(handle, ip string) error {
+func validate(handle string, ips []string) error {
 	if handle == "" {
 		return fmt.Errorf("invalid handle")
 	}
 
-	if net.ParseIP(ip) == nil {
-		return fmt.Errorf("invalid ip: %v", ip)
+	if len(ips) == 0 {
+		return fmt.Errorf("missing ip")
+	}
+
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid ip: %v", ip)
+		}
 	}
 	return nil
 }
 
-func (c *Store) Add(handle, ip string, metadata map[string]interface{}) error {
-	if err := validate(handle, ip); err != nil {
+// PrimaryIP picks the first IPv4 address of a container, or its first address
+// when it has no IPv4 one.
+func PrimaryIP(ips []string) string {
+	for _, ip := range ips {
+		if net.ParseIP(ip).To4() != nil {
+			return ip
+		}
+	}
+	return ips[0]
+}
+
+func (c *Store) Add(handle string, ips []string, metadata map[string]interface{}) error {
+	if err := validate(handle, ips); err != nil {
 		return err
 	}
 
@@ -47,24 +136,19 @@ func (c *Store) Add(handle, ip string, metadata map[string]interface{}) error {
 	}
 	defer file.Close()
 
-	pool := make(map[string]Container)
-	err = c.Serializer.DecodeAll(file, &pool)
+	pool, state, err := c.load(file, true)
 	if err != nil {
-		return fmt.Errorf("decoding file: %s", err)
+		return err
 	}
 
 	pool[handle] = Container{
 		Handle:   handle,
-		IP:       ip,
+		IP:       PrimaryIP(ips),
+		IPs:      ips,
 		Metadata: metadata,
 	}
 
-	err = c.Serializer.EncodeAndOverwrite(file, pool)
-	if err != nil {
-		return fmt.Errorf("encode and overwrite: %s", err)
-	}
-
-	return nil
+	return c.write(pool, state)
 }
 
 func (c *Store) Delete(handle string) (Container, error) {
@@ -79,21 +163,16 @@ func (c *Store) Delete(handle string) (Container, error) {
 	}
 	defer file.Close()
 
-	pool := make(map[string]Container)
-	err = c.Serializer.DecodeAll(file, &pool)
+	pool, state, err := c.load(file, true)
 	if err != nil {
-		return deleted, fmt.Errorf("decoding file: %s", err)
+		return deleted, err
 	}
 
 	deleted = pool[handle]
 
 	delete(pool, handle)
 
-	err = c.Serializer.EncodeAndOverwrite(file, pool)
-	if err != nil {
-		return deleted, fmt.Errorf("encode and overwrite: %s", err)
-	}
-	return deleted, nil
+	return deleted, c.write(pool, state)
 }
 
 func (c *Store) ReadAll() (map[string]Container, error) {
@@ -103,10 +182,157 @@ func (c *Store) ReadAll() (map[string]Container, error) {
 	}
 	defer file.Close()
 
+	// the data file is repaired by the next Add or Delete, since some
+	// lockers only allow reading
+	pool, _, err := c.load(file, false)
+	return pool, err
+}
+
+// Recoveries is how often the data file has been restored from the backup
+// file, by any process using the store.
+func (c *Store) Recoveries() (int, error) {
+	if c.BackupFile == "" {
+		return 0, nil
+	}
+
+	b, err := readBackup(c.BackupFile)
+	if err != nil {
+		return 0, fmt.Errorf("read backup: %s", err)
+	}
+	if b == nil {
+		return 0, nil
+	}
+	return b.Recoveries, nil
+}
+
+var errEmptyFile = errors.New("empty file")
+
+// storeState is what the next write of the data file builds on.
+type storeState struct {
+	backup    *backup
+	recovered bool
+}
+
+// load decodes the data file, falling back to the backup file when it is
+// corrupt. The backup is only read when it is needed, either for that or
+// because the caller is about to write.
+func (c *Store) load(file filelock.LockedFile, writing bool) (map[string]Container, storeState, error) {
+	state := storeState{}
+
 	pool := make(map[string]Container)
-	err = c.Serializer.DecodeAll(file, &pool)
+	decodeErr := c.Serializer.DecodeAll(file, &pool)
+	if c.BackupFile == "" {
+		if decodeErr != nil {
+			return nil, state, fmt.Errorf("decoding file: %s", decodeErr)
+		}
+		return pool, state, nil
+	}
+
+	if decodeErr == nil && len(pool) == 0 {
+		// a crash right after truncating the data file leaves it empty,
+		// which decodes without error
+		if size, err := file.Seek(0, io.SeekEnd); err == nil && size == 0 {
+			decodeErr = errEmptyFile
+		}
+	}
+	if decodeErr == nil && !writing {
+		return pool, state, nil
+	}
+
+	b, err := readBackup(c.BackupFile)
+	if err != nil {
+		// a corrupt backup must not stop the data file from being used, and
+		// is replaced by the next write
+		c.log("datastore: ignoring backup %s: %s", c.BackupFile, err)
+		b = nil
+	}
+	state.backup = b
+
+	switch {
+	case decodeErr == nil:
+		return pool, state, nil
+	case b == nil && decodeErr == errEmptyFile:
+		// a new data file
+		return pool, state, nil
+	case b == nil:
+		return nil, state, fmt.Errorf("decoding file: %s", decodeErr)
+	}
+
+	pool, err = b.containers()
+	if err != nil {
+		return nil, state, fmt.Errorf("decoding file: %s", decodeErr) // not tested
+	}
+	c.log("datastore: recovered %d entries from backup %s version %d after decoding failed: %s", len(pool), c.BackupFile, b.Version, decodeErr)
+	state.recovered = true
+	return pool, state, nil
+}
+
+// write replaces the data file and only then refreshes the backup, so that the
+// backup never holds entries the data file does not.
+func (c *Store) write(pool map[string]Container, state storeState) error {
+	err := replaceFile(c.Path, func(tmp *os.File) error {
+		if err := c.Serializer.EncodeAndOverwrite(tmp, pool); err != nil {
+			return fmt.Errorf("encode and overwrite: %s", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("decoding file: %s", err)
+		return err
+	}
+
+	if c.BackupFile != "" {
+		next := &backup{}
+		if state.backup != nil {
+			next.Version = state.backup.Version
+			next.Recoveries = state.backup.Recoveries
+		}
+		next.Version++
+		if state.recovered {
+			next.Recoveries++
+		}
+
+		if err := writeBackup(c.BackupFile, next, pool); err != nil {
+			return fmt.Errorf("write backup: %s", err)
+		}
+	}
+	return nil
+}
+
+// replaceFile calls write with a temporary file next to path, syncs it and
+// renames it over path. Processes waiting for the lock of the old file notice
+// the rename, see filelock.
+func replaceFile(path string, write func(*os.File) error) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err // not tested
+	}
+	if err := tmp.Close(); err != nil {
+		return err // not tested
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err // not tested
+	}
+
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+func (c *Store) log(format string, args ...interface{}) {
+	if c.Logger != nil {
+		fmt.Fprintf(c.Logger, format+"\n", args...)
 	}
-	return pool, nil
 }
@@ -1,9 +1,11 @@
 package datastore_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
 	"sync/atomic"
 
 	"code.cloudfoundry.org/cf-networking-helpers/testsupport"
@@ -14,15 +16,18 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("Datastore Lifecycle", func() {
 	var (
-		handle   string
-		ip       string
-		store    *datastore.Store
-		metadata map[string]interface{}
-		filepath string
+		handle     string
+		ip         string
+		store      *datastore.Store
+		metadata   map[string]interface{}
+		filepath   string
+		backupPath string
+		logger     *gbytes.Buffer
 	)
 
 	BeforeEach(func() {
@@ -39,18 +44,24 @@ var _ = Describe("Datastore Lifecycle", func() {
 		file, err := ioutil.TempFile("", "")
 		Expect(err).NotTo(HaveOccurred())
 		filepath = file.Name()
+		backupPath = filepath + ".bak"
+		logger = gbytes.NewBuffer()
 
 		locker := filelock.NewLocker(filepath)
 		serializer := &serial.Serial{}
 
 		store = &datastore.Store{
+			Path:       filepath,
 			Serializer: serializer,
 			Locker:     locker,
+			BackupFile: backupPath,
+			Logger:     logger,
 		}
 	})
 
 	AfterEach(func() {
 		os.Remove(filepath)
+		os.Remove(backupPath)
 	})
 
 	Context("when empty", func() {
@@ -64,7 +75,7 @@ var _ = Describe("Datastore Lifecycle", func() {
 	Context("when adding", func() {
 		It("can add entry to datastore", func() {
 			By("adding an entry to store")
-			err := store.Add(handle, ip, metadata)
+			err := store.Add(handle, []string{ip}, metadata)
 			Expect(err).NotTo(HaveOccurred())
 
 			By("verify entry is in store")
@@ -83,7 +94,7 @@ var _ = Describe("Datastore Lifecycle", func() {
 			By("adding an entries to store")
 			for i := 0; i < total; i++ {
 				id := fmt.Sprintf("%s-%d", handle, i)
-				err := store.Add(id, ip, metadata)
+				err := store.Add(id, []string{ip}, metadata)
 				Expect(err).NotTo(HaveOccurred())
 			}
 
@@ -97,7 +108,7 @@ var _ = Describe("Datastore Lifecycle", func() {
 	Context("when removing", func() {
 		It("can add entry and remove an entry from datastore", func() {
 			By("adding an entry to store")
-			err := store.Add(handle, ip, metadata)
+			err := store.Add(handle, []string{ip}, metadata)
 			Expect(err).NotTo(HaveOccurred())
 
 			By("verify entry is in store")
@@ -123,7 +134,7 @@ var _ = Describe("Datastore Lifecycle", func() {
 			By("adding an entries to store")
 			for i := 0; i < total; i++ {
 				id := fmt.Sprintf("%s-%d", handle, i)
-				err := store.Add(id, ip, metadata)
+				err := store.Add(id, []string{ip}, metadata)
 				Expect(err).NotTo(HaveOccurred())
 			}
 
@@ -147,6 +158,28 @@ var _ = Describe("Datastore Lifecycle", func() {
 		})
 	})
 
+	Context("when writing", func() {
+		It("replaces the data file instead of overwriting it in place", func() {
+			Expect(ioutil.WriteFile(filepath, []byte(`{}`), 0600)).To(Succeed())
+			oldFile, err := os.Open(filepath)
+			Expect(err).NotTo(HaveOccurred())
+			defer oldFile.Close()
+
+			Expect(store.Add(handle, []string{ip}, metadata)).To(Succeed())
+
+			Expect(ioutil.ReadAll(oldFile)).To(Equal([]byte(`{}`)))
+			contents, err := ioutil.ReadFile(filepath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring(handle))
+
+			entries, err := ioutil.ReadDir(path.Dir(filepath))
+			Expect(err).NotTo(HaveOccurred())
+			for _, entry := range entries {
+				Expect(entry.Name()).NotTo(HavePrefix(path.Base(filepath) + ".tmp"))
+			}
+		})
+	})
+
 	Context("when adding and deleting concurrently", func() {
 		It("remains consistent", func() {
 
@@ -167,7 +200,7 @@ var _ = Describe("Datastore Lifecycle", func() {
 				parallelRunner.RunOnSlice(containerHandles, func(containerHandle interface{}) {
 					p := containerHandle.(string)
 					func(id string) {
-						err := store.Add(id, ip, metadata)
+						err := store.Add(id, []string{ip}, metadata)
 						Expect(err).NotTo(HaveOccurred())
 					}(p)
 					toRead <- p
@@ -206,4 +239,112 @@ var _ = Describe("Datastore Lifecycle", func() {
 
 		})
 	})
+
+	Context("when the data file is corrupted", func() {
+		BeforeEach(func() {
+			Expect(store.Add(handle, []string{ip}, metadata)).To(Succeed())
+			Expect(store.Add("other-handle", []string{"192.168.0.101"}, nil)).To(Succeed())
+		})
+
+		Context("because a write was cut short", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(filepath, []byte(`{"some-handle":{"hand`), 0600)).To(Succeed())
+			})
+
+			It("reads the entries from the backup and logs the recovery", func() {
+				data, err := store.ReadAll()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(data).To(HaveLen(2))
+				Expect(data[handle].IP).To(Equal(ip))
+
+				Expect(logger).To(gbytes.Say("datastore: recovered 2 entries from backup .*version 2 after decoding failed"))
+			})
+
+			It("repairs the data file on the next write and counts the recovery", func() {
+				recoveries, err := store.Recoveries()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(recoveries).To(Equal(0))
+
+				_, err = store.Delete("other-handle")
+				Expect(err).NotTo(HaveOccurred())
+
+				recoveries, err = store.Recoveries()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(recoveries).To(Equal(1))
+
+				var onDisk map[string]datastore.Container
+				contents, err := ioutil.ReadFile(filepath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(json.Unmarshal(contents, &onDisk)).To(Succeed())
+				Expect(onDisk).To(HaveLen(1))
+				Expect(onDisk).To(HaveKey(handle))
+			})
+		})
+
+		Context("because it was truncated", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(filepath, []byte{}, 0600)).To(Succeed())
+			})
+
+			It("reads the entries from the backup", func() {
+				data, err := store.ReadAll()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(data).To(HaveLen(2))
+			})
+		})
+
+		Context("and there is no backup", func() {
+			BeforeEach(func() {
+				Expect(ioutil.WriteFile(filepath, []byte(`{"some-handle":{"hand`), 0600)).To(Succeed())
+				Expect(os.Remove(backupPath)).To(Succeed())
+			})
+
+			It("returns the decoding error", func() {
+				_, err := store.ReadAll()
+				Expect(err).To(MatchError(HavePrefix("decoding file: ")))
+			})
+		})
+	})
+
+	Context("when the backup is corrupted", func() {
+		BeforeEach(func() {
+			Expect(store.Add(handle, []string{ip}, metadata)).To(Succeed())
+
+			backup, err := ioutil.ReadFile(backupPath)
+			Expect(err).NotTo(HaveOccurred())
+			var contents map[string]interface{}
+			Expect(json.Unmarshal(backup, &contents)).To(Succeed())
+			contents["sha256"] = "bad-checksum"
+			backup, err = json.Marshal(contents)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(backupPath, backup, 0600)).To(Succeed())
+		})
+
+		It("uses the data file and replaces the backup on the next write", func() {
+			data, err := store.ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(HaveKey(handle))
+			Expect(logger).To(gbytes.Say("datastore: ignoring backup .*: backup checksum mismatch"))
+
+			Expect(store.Add("other-handle", []string{"192.168.0.101"}, nil)).To(Succeed())
+			_, err = store.Recoveries()
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Context("when the data file predates the backup", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(filepath, []byte(`{"some-handle":{"handle":"some-handle","ip":"192.168.0.100","metadata":null}}`), 0600)).To(Succeed())
+		})
+
+		It("reads it and starts a backup on the next write", func() {
+			data, err := store.ReadAll()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(HaveKey(handle))
+			Expect(backupPath).NotTo(BeAnExistingFile())
+
+			Expect(store.Add("other-handle", []string{"192.168.0.101"}, nil)).To(Succeed())
+			Expect(backupPath).To(BeAnExistingFile())
+		})
+	})
 })
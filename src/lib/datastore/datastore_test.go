@@ -2,7 +2,9 @@ package datastore_test
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"lib/datastore"
 	libfakes "lib/fakes"
@@ -21,6 +23,7 @@ var _ = Describe("Datastore", func() {
 		serializer *libfakes.Serializer
 		locker     *libfakes.FileLocker
 		lockedFile *os.File
+		dataDir    string
 	)
 
 	BeforeEach(func() {
@@ -36,7 +39,12 @@ var _ = Describe("Datastore", func() {
 			"randomKey":     "randomValue",
 		}
 
+		var err error
+		dataDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
 		store = &datastore.Store{
+			Path:       filepath.Join(dataDir, "store.json"),
 			Serializer: serializer,
 			Locker:     locker,
 		}
@@ -45,9 +53,13 @@ var _ = Describe("Datastore", func() {
 		locker.OpenReturns(lockedFile, nil)
 	})
 
+	AfterEach(func() {
+		Expect(os.RemoveAll(dataDir)).To(Succeed())
+	})
+
 	Context("when adding an entry to store", func() {
 		It("deserializes the data from the file", func() {
-			err := store.Add(handle, ip, metadata)
+			err := store.Add(handle, []string{ip}, metadata)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(serializer.DecodeAllCallCount()).To(Equal(1))
@@ -61,22 +73,47 @@ var _ = Describe("Datastore", func() {
 				handle: datastore.Container{
 					Handle:   handle,
 					IP:       ip,
+					IPs:      []string{ip},
 					Metadata: metadata,
 				},
 			}
 			Expect(actual).To(Equal(expected))
 		})
 
+		Context("when the container has more than one ip", func() {
+			It("stores every ip and picks the IPv4 one as the primary ip", func() {
+				err := store.Add(handle, []string{"fd00::100", ip}, metadata)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, actual := serializer.EncodeAndOverwriteArgsForCall(0)
+				Expect(actual).To(Equal(map[string]datastore.Container{
+					handle: datastore.Container{
+						Handle:   handle,
+						IP:       ip,
+						IPs:      []string{"fd00::100", ip},
+						Metadata: metadata,
+					},
+				}))
+			})
+		})
+
+		Context("when no ip is given", func() {
+			It("returns an error", func() {
+				err := store.Add(handle, nil, metadata)
+				Expect(err).To(MatchError("missing ip"))
+			})
+		})
+
 		Context("when handle is not valid", func() {
 			It("wraps and returns the error", func() {
-				err := store.Add("", ip, metadata)
+				err := store.Add("", []string{ip}, metadata)
 				Expect(err).To(MatchError("invalid handle"))
 			})
 		})
 
 		Context("when input IP is not valid", func() {
 			It("wraps and returns the error", func() {
-				err := store.Add(handle, "invalid-ip", metadata)
+				err := store.Add(handle, []string{ip, "invalid-ip"}, metadata)
 				Expect(err).To(MatchError("invalid ip: invalid-ip"))
 			})
 		})
@@ -86,7 +123,7 @@ var _ = Describe("Datastore", func() {
 				locker.OpenReturns(nil, errors.New("potato"))
 			})
 			It("wraps and returns the error", func() {
-				err := store.Add(handle, ip, metadata)
+				err := store.Add(handle, []string{ip}, metadata)
 				Expect(err).To(MatchError("open lock: potato"))
 			})
 		})
@@ -96,7 +133,7 @@ var _ = Describe("Datastore", func() {
 				serializer.DecodeAllReturns(errors.New("potato"))
 			})
 			It("wraps and returns the error", func() {
-				err := store.Add(handle, ip, metadata)
+				err := store.Add(handle, []string{ip}, metadata)
 				Expect(err).To(MatchError("decoding file: potato"))
 			})
 		})
@@ -104,11 +141,17 @@ var _ = Describe("Datastore", func() {
 		Context("when serializer fails to encode", func() {
 			BeforeEach(func() {
 				serializer.EncodeAndOverwriteReturns(errors.New("potato"))
+				store.BackupFile = filepath.Join(dataDir, "store.json.bak")
 			})
 			It("wraps and returns the error", func() {
-				err := store.Add(handle, ip, metadata)
+				err := store.Add(handle, []string{ip}, metadata)
 				Expect(err).To(MatchError("encode and overwrite: potato"))
 			})
+			It("leaves the data file and the backup alone", func() {
+				store.Add(handle, []string{ip}, metadata)
+				Expect(filepath.Join(dataDir, "store.json")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(dataDir, "store.json.bak")).NotTo(BeAnExistingFile())
+			})
 		})
 
 	})
@@ -201,4 +244,18 @@ var _ = Describe("Datastore", func() {
 			})
 		})
 	})
+
+	Describe("AllIPs", func() {
+		It("returns every ip of the container", func() {
+			container := datastore.Container{IP: "10.255.1.2", IPs: []string{"10.255.1.2", "fd00::2"}}
+			Expect(container.AllIPs()).To(Equal([]string{"10.255.1.2", "fd00::2"}))
+		})
+
+		Context("when the entry predates multiple ips", func() {
+			It("returns the ip", func() {
+				container := datastore.Container{IP: "10.255.1.2"}
+				Expect(container.AllIPs()).To(Equal([]string{"10.255.1.2"}))
+			})
+		})
+	})
 })
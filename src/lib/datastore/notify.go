@@ -0,0 +1,139 @@
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	ActionAdd    = "add"
+	ActionDelete = "delete"
+)
+
+// Event describes a change of the datastore.
+type Event struct {
+	Action string `json:"action"`
+	Handle string `json:"handle"`
+}
+
+const notifyTimeout = 100 * time.Millisecond
+
+// Notifier tells the Listener on SocketPath about changes of the datastore,
+// so that it does not have to wait for its next poll to see them.
+type Notifier struct {
+	SocketPath string
+}
+
+// Notify sends the event without waiting for it to be handled. When nothing
+// listens on the socket there is no one to notify, so that is not an error.
+func (n *Notifier) Notify(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err // not tested
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: n.SocketPath, Net: "unixgram"})
+	if err != nil {
+		if noListener(err) {
+			return nil
+		}
+		return fmt.Errorf("dial: %s", err)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(notifyTimeout))
+	if _, err := conn.Write(data); err != nil {
+		if noListener(err) {
+			return nil // not tested, the listener went away after the dial
+		}
+		return fmt.Errorf("write: %s", err)
+	}
+	return nil
+}
+
+func noListener(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	if !ok {
+		return false // not tested
+	}
+	syscallErr, ok := opErr.Err.(*os.SyscallError)
+	if !ok {
+		return false // not tested
+	}
+	return syscallErr.Err == syscall.ENOENT || syscallErr.Err == syscall.ECONNREFUSED
+}
+
+// Listener receives the events of Notifiers on SocketPath and signals
+// Changes. Changes should be buffered, since events that arrive while a
+// change is pending are merged into it.
+type Listener struct {
+	SocketPath string
+	Changes    chan<- struct{}
+	Logger     lager.Logger
+}
+
+func (l *Listener) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	if err := os.MkdirAll(filepath.Dir(l.SocketPath), 0700); err != nil {
+		return fmt.Errorf("create socket dir: %s", err)
+	}
+	// a socket left behind by a previous run cannot be listened on again
+	if err := os.Remove(l.SocketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove stale socket: %s", err) // not tested
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: l.SocketPath, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("listen: %s", err)
+	}
+	defer os.Remove(l.SocketPath)
+	if err := os.Chmod(l.SocketPath, 0600); err != nil {
+		conn.Close()
+		return fmt.Errorf("chmod socket: %s", err) // not tested
+	}
+
+	close(ready)
+
+	readErrs := make(chan error, 1)
+	go func() {
+		readErrs <- l.read(conn)
+	}()
+
+	select {
+	case <-signals:
+		conn.Close()
+		<-readErrs
+		return nil
+	case err := <-readErrs:
+		conn.Close()
+		return fmt.Errorf("read: %s", err) // not tested
+	}
+}
+
+func (l *Listener) read(conn *net.UnixConn) error {
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		event := Event{}
+		if err := json.Unmarshal(buf[:n], &event); err != nil {
+			l.Logger.Error("decode-event", err)
+			continue
+		}
+		l.Logger.Debug("datastore-changed", lager.Data{"action": event.Action, "handle": event.Handle})
+
+		select {
+		case l.Changes <- struct{}{}:
+		default:
+		}
+	}
+}
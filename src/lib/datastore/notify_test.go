@@ -0,0 +1,142 @@
+package datastore_test
+
+import (
+	"io/ioutil"
+	"lib/datastore"
+	"net"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Notifier and Listener", func() {
+	var (
+		dir        string
+		socketPath string
+		changes    chan struct{}
+		logger     *lagertest.TestLogger
+		listener   *datastore.Listener
+		notifier   *datastore.Notifier
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		socketPath = filepath.Join(dir, "run", "datastore.sock")
+
+		changes = make(chan struct{}, 1)
+		logger = lagertest.NewTestLogger("test")
+		listener = &datastore.Listener{
+			SocketPath: socketPath,
+			Changes:    changes,
+			Logger:     logger,
+		}
+		notifier = &datastore.Notifier{
+			SocketPath: socketPath,
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("when the listener is running", func() {
+		var process ifrit.Process
+
+		BeforeEach(func() {
+			process = ifrit.Invoke(listener)
+		})
+
+		AfterEach(func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		It("signals the changes the notifier sends", func() {
+			Expect(notifier.Notify(datastore.Event{Action: datastore.ActionAdd, Handle: "some-handle"})).To(Succeed())
+
+			Eventually(changes).Should(Receive())
+			Expect(logger).To(gbytes.Say("datastore-changed.*add.*some-handle"))
+		})
+
+		It("merges changes that arrive while one is pending", func() {
+			Expect(notifier.Notify(datastore.Event{Action: datastore.ActionAdd, Handle: "handle-1"})).To(Succeed())
+			Expect(notifier.Notify(datastore.Event{Action: datastore.ActionDelete, Handle: "handle-2"})).To(Succeed())
+			Eventually(logger).Should(gbytes.Say("datastore-changed.*delete.*handle-2"))
+
+			Expect(changes).To(Receive())
+			Consistently(changes).ShouldNot(Receive())
+		})
+
+		It("only lets the owner write to the socket", func() {
+			info, err := os.Stat(socketPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+
+		Context("when an event cannot be decoded", func() {
+			It("logs the error and carries on", func() {
+				conn, err := net.Dial("unixgram", socketPath)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = conn.Write([]byte("banana"))
+				Expect(err).NotTo(HaveOccurred())
+				conn.Close()
+
+				Eventually(logger).Should(gbytes.Say("decode-event"))
+				Consistently(changes).ShouldNot(Receive())
+
+				Expect(notifier.Notify(datastore.Event{Action: datastore.ActionAdd, Handle: "some-handle"})).To(Succeed())
+				Eventually(changes).Should(Receive())
+			})
+		})
+	})
+
+	It("removes the socket when it stops", func() {
+		process := ifrit.Invoke(listener)
+		Expect(socketPath).To(BeAnExistingFile())
+
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		Expect(socketPath).NotTo(BeAnExistingFile())
+	})
+
+	Context("when a previous listener left its socket behind", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Dir(socketPath), 0700)).To(Succeed())
+			Expect(ioutil.WriteFile(socketPath, []byte{}, 0600)).To(Succeed())
+		})
+
+		It("replaces it", func() {
+			process := ifrit.Invoke(listener)
+			defer func() {
+				process.Signal(os.Interrupt)
+				Eventually(process.Wait()).Should(Receive(BeNil()))
+			}()
+
+			Expect(notifier.Notify(datastore.Event{Action: datastore.ActionAdd, Handle: "some-handle"})).To(Succeed())
+			Eventually(changes).Should(Receive())
+		})
+	})
+
+	Context("when nothing is listening", func() {
+		It("does not fail to notify", func() {
+			Expect(notifier.Notify(datastore.Event{Action: datastore.ActionAdd, Handle: "some-handle"})).To(Succeed())
+		})
+
+		It("does not fail to notify through a socket left behind", func() {
+			Expect(os.MkdirAll(filepath.Dir(socketPath), 0700)).To(Succeed())
+			conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conn.Close()).To(Succeed())
+			Expect(socketPath).To(BeAnExistingFile())
+
+			Expect(notifier.Notify(datastore.Event{Action: datastore.ActionDelete, Handle: "some-handle"})).To(Succeed())
+		})
+	})
+})
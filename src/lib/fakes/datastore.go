@@ -7,11 +7,11 @@ import (
 )
 
 type Datastore struct {
-	AddStub        func(handle, ip string, metadata map[string]interface{}) error
+	AddStub        func(handle string, ips []string, metadata map[string]interface{}) error
 	addMutex       sync.RWMutex
 	addArgsForCall []struct {
 		handle   string
-		ip       string
+		ips      []string
 		metadata map[string]interface{}
 	}
 	addReturns struct {
@@ -48,18 +48,23 @@ type Datastore struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *Datastore) Add(handle string, ip string, metadata map[string]interface{}) error {
+func (fake *Datastore) Add(handle string, ips []string, metadata map[string]interface{}) error {
+	var ipsCopy []string
+	if ips != nil {
+		ipsCopy = make([]string, len(ips))
+		copy(ipsCopy, ips)
+	}
 	fake.addMutex.Lock()
 	ret, specificReturn := fake.addReturnsOnCall[len(fake.addArgsForCall)]
 	fake.addArgsForCall = append(fake.addArgsForCall, struct {
 		handle   string
-		ip       string
+		ips      []string
 		metadata map[string]interface{}
-	}{handle, ip, metadata})
-	fake.recordInvocation("Add", []interface{}{handle, ip, metadata})
+	}{handle, ipsCopy, metadata})
+	fake.recordInvocation("Add", []interface{}{handle, ipsCopy, metadata})
 	fake.addMutex.Unlock()
 	if fake.AddStub != nil {
-		return fake.AddStub(handle, ip, metadata)
+		return fake.AddStub(handle, ips, metadata)
 	}
 	if specificReturn {
 		return ret.result1
@@ -73,10 +78,10 @@ func (fake *Datastore) AddCallCount() int {
 	return len(fake.addArgsForCall)
 }
 
-func (fake *Datastore) AddArgsForCall(i int) (string, string, map[string]interface{}) {
+func (fake *Datastore) AddArgsForCall(i int) (string, []string, map[string]interface{}) {
 	fake.addMutex.RLock()
 	defer fake.addMutex.RUnlock()
-	return fake.addArgsForCall[i].handle, fake.addArgsForCall[i].ip, fake.addArgsForCall[i].metadata
+	return fake.addArgsForCall[i].handle, fake.addArgsForCall[i].ips, fake.addArgsForCall[i].metadata
 }
 
 func (fake *Datastore) AddReturns(result1 error) {
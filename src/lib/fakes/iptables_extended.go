@@ -49,6 +49,19 @@ type IPTablesAdapter struct {
 		result1 []string
 		result2 error
 	}
+	ListChainsStub        func(table string) ([]string, error)
+	listChainsMutex       sync.RWMutex
+	listChainsArgsForCall []struct {
+		table string
+	}
+	listChainsReturns struct {
+		result1 []string
+		result2 error
+	}
+	listChainsReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
 	NewChainStub        func(table, chain string) error
 	newChainMutex       sync.RWMutex
 	newChainArgsForCall []struct {
@@ -112,6 +125,20 @@ type IPTablesAdapter struct {
 	bulkAppendReturnsOnCall map[int]struct {
 		result1 error
 	}
+	BulkReplaceStub        func(table, chain string, keep func(string) bool, rulespec ...rules.IPTablesRule) error
+	bulkReplaceMutex       sync.RWMutex
+	bulkReplaceArgsForCall []struct {
+		table    string
+		chain    string
+		keep     func(string) bool
+		rulespec []rules.IPTablesRule
+	}
+	bulkReplaceReturns struct {
+		result1 error
+	}
+	bulkReplaceReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -271,6 +298,57 @@ func (fake *IPTablesAdapter) ListReturnsOnCall(i int, result1 []string, result2
 	}{result1, result2}
 }
 
+func (fake *IPTablesAdapter) ListChains(table string) ([]string, error) {
+	fake.listChainsMutex.Lock()
+	ret, specificReturn := fake.listChainsReturnsOnCall[len(fake.listChainsArgsForCall)]
+	fake.listChainsArgsForCall = append(fake.listChainsArgsForCall, struct {
+		table string
+	}{table})
+	fake.recordInvocation("ListChains", []interface{}{table})
+	fake.listChainsMutex.Unlock()
+	if fake.ListChainsStub != nil {
+		return fake.ListChainsStub(table)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.listChainsReturns.result1, fake.listChainsReturns.result2
+}
+
+func (fake *IPTablesAdapter) ListChainsCallCount() int {
+	fake.listChainsMutex.RLock()
+	defer fake.listChainsMutex.RUnlock()
+	return len(fake.listChainsArgsForCall)
+}
+
+func (fake *IPTablesAdapter) ListChainsArgsForCall(i int) string {
+	fake.listChainsMutex.RLock()
+	defer fake.listChainsMutex.RUnlock()
+	return fake.listChainsArgsForCall[i].table
+}
+
+func (fake *IPTablesAdapter) ListChainsReturns(result1 []string, result2 error) {
+	fake.ListChainsStub = nil
+	fake.listChainsReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *IPTablesAdapter) ListChainsReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.ListChainsStub = nil
+	if fake.listChainsReturnsOnCall == nil {
+		fake.listChainsReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.listChainsReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *IPTablesAdapter) NewChain(table string, chain string) error {
 	fake.newChainMutex.Lock()
 	ret, specificReturn := fake.newChainReturnsOnCall[len(fake.newChainArgsForCall)]
@@ -519,6 +597,57 @@ func (fake *IPTablesAdapter) BulkAppendReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *IPTablesAdapter) BulkReplace(table string, chain string, keep func(string) bool, rulespec ...rules.IPTablesRule) error {
+	fake.bulkReplaceMutex.Lock()
+	ret, specificReturn := fake.bulkReplaceReturnsOnCall[len(fake.bulkReplaceArgsForCall)]
+	fake.bulkReplaceArgsForCall = append(fake.bulkReplaceArgsForCall, struct {
+		table    string
+		chain    string
+		keep     func(string) bool
+		rulespec []rules.IPTablesRule
+	}{table, chain, keep, rulespec})
+	fake.recordInvocation("BulkReplace", []interface{}{table, chain, keep, rulespec})
+	fake.bulkReplaceMutex.Unlock()
+	if fake.BulkReplaceStub != nil {
+		return fake.BulkReplaceStub(table, chain, keep, rulespec...)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.bulkReplaceReturns.result1
+}
+
+func (fake *IPTablesAdapter) BulkReplaceCallCount() int {
+	fake.bulkReplaceMutex.RLock()
+	defer fake.bulkReplaceMutex.RUnlock()
+	return len(fake.bulkReplaceArgsForCall)
+}
+
+func (fake *IPTablesAdapter) BulkReplaceArgsForCall(i int) (string, string, func(string) bool, []rules.IPTablesRule) {
+	fake.bulkReplaceMutex.RLock()
+	defer fake.bulkReplaceMutex.RUnlock()
+	return fake.bulkReplaceArgsForCall[i].table, fake.bulkReplaceArgsForCall[i].chain, fake.bulkReplaceArgsForCall[i].keep, fake.bulkReplaceArgsForCall[i].rulespec
+}
+
+func (fake *IPTablesAdapter) BulkReplaceReturns(result1 error) {
+	fake.BulkReplaceStub = nil
+	fake.bulkReplaceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *IPTablesAdapter) BulkReplaceReturnsOnCall(i int, result1 error) {
+	fake.BulkReplaceStub = nil
+	if fake.bulkReplaceReturnsOnCall == nil {
+		fake.bulkReplaceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.bulkReplaceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *IPTablesAdapter) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -528,6 +657,8 @@ func (fake *IPTablesAdapter) Invocations() map[string][][]interface{} {
 	defer fake.deleteMutex.RUnlock()
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
+	fake.listChainsMutex.RLock()
+	defer fake.listChainsMutex.RUnlock()
 	fake.newChainMutex.RLock()
 	defer fake.newChainMutex.RUnlock()
 	fake.clearChainMutex.RLock()
@@ -538,6 +669,8 @@ func (fake *IPTablesAdapter) Invocations() map[string][][]interface{} {
 	defer fake.bulkInsertMutex.RUnlock()
 	fake.bulkAppendMutex.RLock()
 	defer fake.bulkAppendMutex.RUnlock()
+	fake.bulkReplaceMutex.RLock()
+	defer fake.bulkReplaceMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
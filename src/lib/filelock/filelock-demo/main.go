@@ -23,7 +23,7 @@ func mainWithError() error {
 		return fmt.Errorf("usage: %s <file_to_lock>", os.Args[0])
 	}
 
-	locker := filelock.NewLocker(os.Args[1])
+	locker := filelock.NewLockerWithOptions(os.Args[1], filelock.Options{RecordHolder: true})
 
 	startTime := time.Now()
 	fmt.Fprintf(os.Stderr, "waiting to acquire lock on %s...", os.Args[1])
@@ -1,18 +1,200 @@
 package filelock
 
-import "os"
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
 
 //go:generate counterfeiter -o ../fakes/file_locker.go --fake-name FileLocker . FileLocker
 type FileLocker interface {
 	Open() (LockedFile, error)
 }
 
+// Options tune how a locker waits for its lock. The zero Options wait forever
+// and record nothing.
+type Options struct {
+	// Timeout bounds the wait for the lock. Open gives up with a
+	// *TimeoutError after it. Zero waits forever.
+	Timeout time.Duration
+
+	// RecordHolder writes the process holding the lock to HolderFileFor the
+	// path, so that a process timing out can tell who is holding it up.
+	RecordHolder bool
+
+	// OnWait is called with the time each Open waited for the lock. It may
+	// be nil.
+	OnWait func(time.Duration)
+}
+
+// retryInterval is how often a locker with a Timeout retries the lock.
+const retryInterval = 10 * time.Millisecond
+
 type locker struct {
-	path string
+	path    string
+	options Options
 }
 
 func NewLocker(path string) FileLocker {
-	return &locker{path}
+	return &locker{path: path}
+}
+
+func NewLockerWithOptions(path string, options Options) FileLocker {
+	return &locker{path: path, options: options}
+}
+
+// Open will open and lock a file.  It blocks until the lock is acquired, or
+// until the Timeout of the locker's Options runs out.
+// If the file does not yet exist, it creates the file, and any missing
+// directories above it in the path.  To release the lock, Close the file.
+// The holder of the lock may replace the file with a rename, in which case
+// Open locks the new file instead.
+func (l *locker) Open() (LockedFile, error) {
+	start := time.Now()
+	deadline := start.Add(l.options.Timeout)
+
+	dir := filepath.Dir(l.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	var file *os.File
+	for {
+		const flags = os.O_RDWR | os.O_CREATE
+		var err error
+		file, err = os.OpenFile(l.path, flags, 0600)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := l.lock(file, deadline); err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		replaced, err := replacedSinceOpen(file, l.path)
+		if err != nil {
+			file.Close()
+			return nil, err // not tested
+		}
+		if !replaced {
+			break
+		}
+		file.Close()
+	}
+
+	if l.options.OnWait != nil {
+		l.options.OnWait(time.Since(start))
+	}
+
+	locked := &lockedFile{file: file}
+	if l.options.RecordHolder {
+		locked.holderPath = HolderFileFor(l.path)
+		// the holder only helps diagnose timeouts, so failing to record it
+		// must not fail the lock
+		writeHolder(locked.holderPath)
+	}
+	return locked, nil
+}
+
+func (l *locker) lock(file *os.File, deadline time.Time) error {
+	if l.options.Timeout == 0 {
+		return lockFile(file)
+	}
+
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			timeoutErr := &TimeoutError{Path: l.path, Timeout: l.options.Timeout}
+			if holder, err := ReadHolder(HolderFileFor(l.path)); err == nil {
+				timeoutErr.Holder = holder
+			}
+			return timeoutErr
+		}
+		time.Sleep(retryInterval)
+	}
+}
+
+// replacedSinceOpen reports whether path no longer names the open file, e.g.
+// because the previous holder of the lock renamed a new file over it. The lock
+// of the open file then no longer guards path.
+func replacedSinceOpen(file *os.File, path string) (bool, error) {
+	opened, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	current, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(opened, current), nil
+}
+
+// TimeoutError is returned by Open when the lock could not be acquired
+// within the Timeout of the locker's Options.
+type TimeoutError struct {
+	Path    string
+	Timeout time.Duration
+	// Holder is nil when the holder of the lock did not record itself.
+	Holder *Holder
+}
+
+func (e *TimeoutError) Error() string {
+	msg := fmt.Sprintf("timed out after %s waiting for lock on %s", e.Timeout, e.Path)
+	if e.Holder == nil {
+		return msg
+	}
+	return fmt.Sprintf("%s: held by %s (pid %d) since %s",
+		msg, e.Holder.ProcessName, e.Holder.PID, e.Holder.AcquiredAt.Format(time.RFC3339))
+}
+
+// Holder describes the process holding a lock.
+type Holder struct {
+	PID         int       `json:"pid"`
+	ProcessName string    `json:"process_name"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+}
+
+// HolderFileFor returns the file next to a lock file in which the holder of
+// the lock is recorded.
+func HolderFileFor(path string) string {
+	return path + ".holder"
+}
+
+func ReadHolder(path string) (*Holder, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	holder := &Holder{}
+	if err := json.Unmarshal(data, holder); err != nil {
+		return nil, fmt.Errorf("decode holder: %s", err)
+	}
+	return holder, nil
+}
+
+func writeHolder(path string) error {
+	data, err := json.Marshal(Holder{
+		PID:         os.Getpid(),
+		ProcessName: filepath.Base(os.Args[0]),
+		AcquiredAt:  time.Now(),
+	})
+	if err != nil {
+		return err // not tested
+	}
+	return ioutil.WriteFile(path, data, 0600)
 }
 
 //go:generate counterfeiter -o ../fakes/locked_file.go --fake-name LockedFile . LockedFile
@@ -26,6 +208,17 @@ type LockedFile interface {
 
 type lockedFile struct {
 	file *os.File
+	// holderPath is empty when the holder is not recorded
+	holderPath string
+}
+
+// Close releases the lock. The recorded holder is removed while the lock is
+// still held, so that it cannot remove the record of the next holder.
+func (f *lockedFile) Close() error {
+	if f.holderPath != "" {
+		os.Remove(f.holderPath)
+	}
+	return f.unlockAndClose()
 }
 
 func (f *lockedFile) Read(b []byte) (int, error) {
@@ -2,9 +2,12 @@ package filelock_test
 
 import (
 	"os/exec"
+	"time"
 
 	"lib/filelock"
 
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -132,6 +135,52 @@ var _ = Describe("Locking using a file", func() {
 		}, 5 /* max seconds allowed for this spec */)
 	})
 
+	Context("when the holder of the lock replaces the file with a rename", func() {
+		It("locks the new file once the lock of the old one is released", func(done Done) {
+			firstFileHandle, err := filelock.NewLocker(path).Open()
+			Expect(err).NotTo(HaveOccurred())
+
+			lockAcquiredChan := make(chan filelock.LockedFile)
+			go func() {
+				defer GinkgoRecover()
+
+				secondFileHandle, err := filelock.NewLocker(path).Open()
+				Expect(err).NotTo(HaveOccurred())
+
+				lockAcquiredChan <- secondFileHandle
+			}()
+			Consistently(lockAcquiredChan).ShouldNot(Receive())
+
+			By("renaming a new file over the locked one")
+			newPath := path + ".new"
+			Expect(ioutil.WriteFile(newPath, []byte("the new data"), 0600)).To(Succeed())
+			Expect(os.Rename(newPath, path)).To(Succeed())
+			Expect(firstFileHandle.Close()).To(Succeed())
+
+			var secondFileHandle filelock.LockedFile
+			Eventually(lockAcquiredChan).Should(Receive(&secondFileHandle))
+			Expect(ioutil.ReadAll(secondFileHandle)).To(Equal([]byte("the new data")))
+
+			By("holding the lock of the new file")
+			thirdLockedChan := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+
+				thirdFileHandle, err := filelock.NewLocker(path).Open()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(thirdFileHandle.Close()).To(Succeed())
+
+				close(thirdLockedChan)
+			}()
+			Consistently(thirdLockedChan).ShouldNot(BeClosed())
+
+			Expect(secondFileHandle.Close()).To(Succeed())
+			Eventually(thirdLockedChan).Should(BeClosed())
+
+			close(done)
+		}, 5 /* max seconds allowed for this spec */)
+	})
+
 	Context("when the file is locked from a separate OS process", func() {
 		It("blocks the second file open until after the other process has released the lock", func(done Done) {
 			cmd := exec.Command(pathToBinary, path)
@@ -179,5 +228,124 @@ var _ = Describe("Locking using a file", func() {
 			Expect(file.Close()).To(Succeed())
 			close(done)
 		}, 5 /* max seconds allowed for this spec */)
+
+		Context("when the locker has a timeout", func() {
+			var (
+				session    *gexec.Session
+				stdinPipe  io.WriteCloser
+				cmdProcess *os.Process
+			)
+
+			BeforeEach(func() {
+				cmd := exec.Command(pathToBinary, path)
+				var err error
+				stdinPipe, err = cmd.StdinPipe()
+				Expect(err).NotTo(HaveOccurred())
+
+				session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+				Expect(err).NotTo(HaveOccurred())
+				cmdProcess = cmd.Process
+				Eventually(session.Err).Should(gbytes.Say("done after"))
+			})
+
+			AfterEach(func() {
+				stdinPipe.Close()
+				Eventually(session).Should(gexec.Exit(0))
+			})
+
+			It("gives up with a timeout error naming the holder of the lock", func() {
+				locker := filelock.NewLockerWithOptions(path, filelock.Options{Timeout: 100 * time.Millisecond})
+
+				start := time.Now()
+				_, err := locker.Open()
+				Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+
+				timeoutErr, ok := err.(*filelock.TimeoutError)
+				Expect(ok).To(BeTrue())
+				Expect(timeoutErr.Path).To(Equal(path))
+				Expect(timeoutErr.Timeout).To(Equal(100 * time.Millisecond))
+				Expect(timeoutErr.Holder).NotTo(BeNil())
+				Expect(timeoutErr.Holder.PID).To(Equal(cmdProcess.Pid))
+				Expect(timeoutErr.Holder.ProcessName).To(Equal("filelock-demo"))
+				Expect(timeoutErr.Holder.AcquiredAt).To(BeTemporally("<", start))
+
+				Expect(err.Error()).To(ContainSubstring("timed out after 100ms waiting for lock on " + path))
+				Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("held by filelock-demo (pid %d) since", cmdProcess.Pid)))
+			})
+
+			It("acquires the lock when it is released in time", func() {
+				locker := filelock.NewLockerWithOptions(path, filelock.Options{Timeout: 5 * time.Second})
+
+				go func() {
+					time.Sleep(200 * time.Millisecond)
+					stdinPipe.Close()
+				}()
+
+				file, err := locker.Open()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(file.Close()).To(Succeed())
+			})
+		})
+	})
+
+	Describe("recording the holder", func() {
+		It("records the holder while the lock is held", func() {
+			locker := filelock.NewLockerWithOptions(path, filelock.Options{RecordHolder: true})
+
+			file, err := locker.Open()
+			Expect(err).NotTo(HaveOccurred())
+
+			holder, err := filelock.ReadHolder(filelock.HolderFileFor(path))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(holder.PID).To(Equal(os.Getpid()))
+			Expect(holder.AcquiredAt).To(BeTemporally("~", time.Now(), time.Second))
+
+			Expect(file.Close()).To(Succeed())
+			Expect(filelock.HolderFileFor(path)).NotTo(BeAnExistingFile())
+		})
+
+		It("does not record the holder by default", func() {
+			file, err := filelock.NewLocker(path).Open()
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+
+			Expect(filelock.HolderFileFor(path)).NotTo(BeAnExistingFile())
+		})
+
+		Context("when the holder did not record itself", func() {
+			It("times out without a holder", func() {
+				file, err := filelock.NewLocker(path).Open()
+				Expect(err).NotTo(HaveOccurred())
+				defer file.Close()
+
+				_, err = filelock.NewLockerWithOptions(path, filelock.Options{Timeout: 50 * time.Millisecond}).Open()
+				Expect(err).To(MatchError("timed out after 50ms waiting for lock on " + path))
+			})
+		})
+	})
+
+	Describe("OnWait", func() {
+		It("reports how long each open waited for the lock", func() {
+			first, err := filelock.NewLocker(path).Open()
+			Expect(err).NotTo(HaveOccurred())
+
+			waits := make(chan time.Duration, 1)
+			locker := filelock.NewLockerWithOptions(path, filelock.Options{
+				OnWait: func(d time.Duration) { waits <- d },
+			})
+
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				first.Close()
+			}()
+
+			file, err := locker.Open()
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+
+			var wait time.Duration
+			Expect(waits).To(Receive(&wait))
+			Expect(wait).To(BeNumerically(">=", 100*time.Millisecond))
+		})
 	})
 })
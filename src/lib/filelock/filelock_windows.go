@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"math"
 	"os"
-	"path/filepath"
 	"syscall"
 	"unsafe"
 
@@ -17,33 +16,27 @@ var (
 	unlockFileEx = kernel32.NewProc("UnlockFileEx")
 )
 
-// Open will open and lock a file.  It blocks until the lock is acquired.
-// If the file does not yet exist, it creates the file, and any missing
-// directories above it in the path.  To release the lock, Close the file.
-func (l *locker) Open() (LockedFile, error) {
-	dir := filepath.Dir(l.path)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return nil, err
-	}
-	const flags = os.O_RDWR | os.O_CREATE
-	file, err := os.OpenFile(l.path, flags, 0600)
+func lockFile(file *os.File) error {
+	h, err := handle(file)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
+	_, err = lockFileWithFlags(h, LOCKFILE_EXCLUSIVE_LOCK)
+	return err
+}
+
+// tryLockFile returns false when another open file holds the lock.
+func tryLockFile(file *os.File) (bool, error) {
 	h, err := handle(file)
 	if err != nil {
-		return nil, err
-	}
-
-	if err := lockFile(h); err != nil {
-		return nil, err
+		return false, err
 	}
 
-	return &lockedFile{file}, nil
+	return lockFileWithFlags(h, LOCKFILE_EXCLUSIVE_LOCK|LOCKFILE_FAIL_IMMEDIATELY)
 }
 
-func (f *lockedFile) Close() error {
+func (f *lockedFile) unlockAndClose() error {
 	h, err := handle(f.file)
 	if err != nil {
 		return err
@@ -65,7 +58,12 @@ func handle(f *os.File) (syscall.Handle, error) {
 	return h, nil
 }
 
-const LOCKFILE_EXCLUSIVE_LOCK = 2
+const (
+	LOCKFILE_FAIL_IMMEDIATELY = 1
+	LOCKFILE_EXCLUSIVE_LOCK   = 2
+
+	errorLockViolation = syscall.Errno(33)
+)
 
 type overlapped struct {
 	internal     uintptr
@@ -74,25 +72,29 @@ type overlapped struct {
 	handle       windows.Handle
 }
 
-func lockFile(h syscall.Handle) error {
+// lockFileWithFlags returns false when the lock is held elsewhere and flags
+// include LOCKFILE_FAIL_IMMEDIATELY.
+func lockFileWithFlags(h syscall.Handle, flags int) (bool, error) {
 	if err := lockFileEx.Find(); err != nil {
-		return err
+		return false, err
 	}
 
 	event, err := windows.CreateEvent(nil, 0, 0, nil)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer windows.CloseHandle(event)
 	o := &overlapped{handle: event}
 
-	flags := LOCKFILE_EXCLUSIVE_LOCK
 	r0, _, err := syscall.Syscall6(lockFileEx.Addr(), 6, uintptr(h), uintptr(flags), 0, math.MaxInt32, math.MaxInt32, uintptr(unsafe.Pointer(o)))
 	if int32(r0) == 0 {
-		return fmt.Errorf("error locking file: %s", err.Error())
+		if err == errorLockViolation {
+			return false, nil
+		}
+		return false, fmt.Errorf("error locking file: %s", err.Error())
 	}
 
-	return nil
+	return true, nil
 }
 
 func unlockFile(h syscall.Handle) error {
@@ -44,6 +44,31 @@ func (c *InternalClient) GetPoliciesByID(ids ...string) ([]models.Policy, error)
 	return policies.Policies, nil
 }
 
+func (c *InternalClient) GetEgressPoliciesByID(ids ...string) ([]models.EgressPolicy, error) {
+	var policies struct {
+		EgressPolicies []models.EgressPolicy `json:"egress_policies"`
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("ids cannot be empty")
+	}
+	err := c.JsonClient.Do("GET", "/networking/v0/internal/egress_policies?id="+strings.Join(ids, ","), nil, &policies, "")
+	if err != nil {
+		return nil, err
+	}
+	return policies.EgressPolicies, nil
+}
+
+func (c *InternalClient) GetTags() ([]models.Tag, error) {
+	var tags struct {
+		Tags []models.Tag `json:"tags"`
+	}
+	err := c.JsonClient.Do("GET", "/networking/v0/internal/tags", nil, &tags, "")
+	if err != nil {
+		return nil, err
+	}
+	return tags.Tags, nil
+}
+
 func (c *InternalClient) HealthCheck() (bool, error) {
 	var healthcheck struct {
 		Healthcheck bool `json:"healthcheck"`
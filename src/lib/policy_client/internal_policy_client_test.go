@@ -135,6 +135,86 @@ var _ = Describe("InternalClient", func() {
 		})
 	})
 
+	Describe("GetEgressPoliciesByID", func() {
+		BeforeEach(func() {
+			jsonClient.DoStub = func(method, route string, reqData, respData interface{}, token string) error {
+				respBytes := []byte(`{ "egress_policies": [ {"source": { "id": "some-space-guid", "type": "space" }, "destination": { "protocol": "tcp", "cidr": "10.0.0.0/8", "ports": { "start": 80, "end": 443 } } } ] }`)
+				json.Unmarshal(respBytes, respData)
+				return nil
+			}
+		})
+
+		It("does the right json http client request", func() {
+			policies, err := client.GetEgressPoliciesByID("some-app-guid", "some-space-guid")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(jsonClient.DoCallCount()).To(Equal(1))
+			method, route, reqData, _, token := jsonClient.DoArgsForCall(0)
+			Expect(method).To(Equal("GET"))
+			Expect(route).To(Equal("/networking/v0/internal/egress_policies?id=some-app-guid,some-space-guid"))
+			Expect(reqData).To(BeNil())
+			Expect(token).To(BeEmpty())
+
+			Expect(policies).To(Equal([]models.EgressPolicy{{
+				Source:      models.EgressSource{ID: "some-space-guid", Type: "space"},
+				Destination: models.EgressDestination{Protocol: "tcp", CIDR: "10.0.0.0/8", Ports: models.Ports{Start: 80, End: 443}},
+			}}))
+		})
+
+		Context("when the json client fails", func() {
+			BeforeEach(func() {
+				jsonClient.DoReturns(errors.New("banana"))
+			})
+			It("returns the error", func() {
+				_, err := client.GetEgressPoliciesByID("foo")
+				Expect(err).To(MatchError("banana"))
+			})
+		})
+
+		Context("when ids is empty", func() {
+			It("returns an error and does not call the json http client", func() {
+				policies, err := client.GetEgressPoliciesByID()
+				Expect(err).To(MatchError("ids cannot be empty"))
+				Expect(policies).To(BeNil())
+				Expect(jsonClient.DoCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("GetTags", func() {
+		BeforeEach(func() {
+			jsonClient.DoStub = func(method, route string, reqData, respData interface{}, token string) error {
+				respBytes := []byte(`{ "tags": [ { "id": "some-app-guid", "tag": "0001" } ] }`)
+				json.Unmarshal(respBytes, respData)
+				return nil
+			}
+		})
+
+		It("does the right json http client request", func() {
+			tags, err := client.GetTags()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(jsonClient.DoCallCount()).To(Equal(1))
+			method, route, reqData, _, token := jsonClient.DoArgsForCall(0)
+			Expect(method).To(Equal("GET"))
+			Expect(route).To(Equal("/networking/v0/internal/tags"))
+			Expect(reqData).To(BeNil())
+			Expect(token).To(BeEmpty())
+
+			Expect(tags).To(Equal([]models.Tag{{ID: "some-app-guid", Tag: "0001"}}))
+		})
+
+		Context("when the json client fails", func() {
+			BeforeEach(func() {
+				jsonClient.DoReturns(errors.New("banana"))
+			})
+			It("returns the error", func() {
+				_, err := client.GetTags()
+				Expect(err).To(MatchError("banana"))
+			})
+		})
+	})
+
 	Describe("HealthCheck", func() {
 		BeforeEach(func() {
 			jsonClient.DoStub = func(method, route string, reqData, respData interface{}, token string) error {
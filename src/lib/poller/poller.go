@@ -12,20 +12,32 @@ type Poller struct {
 	PollInterval time.Duration
 
 	SingleCycleFunc func() error
+
+	// Trigger runs TriggeredCycleFunc between polls, without delaying the
+	// next poll. It may be nil.
+	Trigger            <-chan struct{}
+	TriggeredCycleFunc func() error
 }
 
 func (m *Poller) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	close(ready)
 
+	timer := time.NewTimer(m.PollInterval)
+	defer timer.Stop()
+
 	for {
 		select {
 		case <-signals:
 			return nil
-		case <-time.After(m.PollInterval):
+		case <-m.Trigger:
+			if err := m.TriggeredCycleFunc(); err != nil {
+				m.Logger.Error("triggered-cycle", err)
+			}
+		case <-timer.C:
 			if err := m.SingleCycleFunc(); err != nil {
 				m.Logger.Error("poll-cycle", err)
-				continue
 			}
+			timer.Reset(m.PollInterval)
 		}
 	}
 }
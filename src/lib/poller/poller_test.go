@@ -81,5 +81,75 @@ var _ = Describe("Poller", func() {
 				Eventually(retChan).Should(Receive(nil))
 			})
 		})
+
+		Context("when a cycle is triggered", func() {
+			var (
+				trigger        chan struct{}
+				triggeredCount uint64
+			)
+
+			BeforeEach(func() {
+				trigger = make(chan struct{})
+				triggeredCount = 0
+
+				p.PollInterval = time.Hour
+				p.Trigger = trigger
+				p.TriggeredCycleFunc = func() error {
+					atomic.AddUint64(&triggeredCount, 1)
+					return errors.New("kiwi")
+				}
+			})
+
+			It("calls the triggered cycle func without waiting for the poll interval", func() {
+				go func() {
+					retChan <- p.Run(signals, ready)
+				}()
+
+				Eventually(ready).Should(BeClosed())
+
+				trigger <- struct{}{}
+				trigger <- struct{}{}
+				Eventually(func() uint64 {
+					return atomic.LoadUint64(&triggeredCount)
+				}).Should(Equal(uint64(2)))
+				Expect(atomic.LoadUint64(&cycleCount)).To(Equal(uint64(0)))
+
+				Eventually(logger).Should(gbytes.Say("triggered-cycle.*kiwi"))
+
+				signals <- os.Interrupt
+				Eventually(retChan).Should(Receive(nil))
+			})
+		})
+
+		Context("when cycles are triggered more often than the poll interval", func() {
+			BeforeEach(func() {
+				trigger := make(chan struct{})
+				p.Trigger = trigger
+				p.TriggeredCycleFunc = func() error { return nil }
+
+				go func() {
+					for {
+						select {
+						case trigger <- struct{}{}:
+						case <-time.After(time.Second):
+							return
+						}
+					}
+				}()
+			})
+
+			It("still polls", func() {
+				go func() {
+					retChan <- p.Run(signals, ready)
+				}()
+
+				Eventually(func() uint64 {
+					return atomic.LoadUint64(&cycleCount)
+				}).Should(BeNumerically(">", 0))
+
+				signals <- os.Interrupt
+				Eventually(retChan).Should(Receive(nil))
+			})
+		})
 	})
 })
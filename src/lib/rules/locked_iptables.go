@@ -13,6 +13,7 @@ type iptables interface {
 	AppendUnique(table, chain string, rulespec ...string) error
 	Delete(table, chain string, rulespec ...string) error
 	List(table, chain string) ([]string, error)
+	ListChains(table string) ([]string, error)
 	NewChain(table, chain string) error
 	ClearChain(table, chain string) error
 	DeleteChain(table, chain string) error
@@ -23,11 +24,13 @@ type IPTablesAdapter interface {
 	Exists(table, chain string, rulespec IPTablesRule) (bool, error)
 	Delete(table, chain string, rulespec IPTablesRule) error
 	List(table, chain string) ([]string, error)
+	ListChains(table string) ([]string, error)
 	NewChain(table, chain string) error
 	ClearChain(table, chain string) error
 	DeleteChain(table, chain string) error
 	BulkInsert(table, chain string, pos int, rulespec ...IPTablesRule) error
 	BulkAppend(table, chain string, rulespec ...IPTablesRule) error
+	BulkReplace(table, chain string, keep func(rule string) bool, rulespec ...IPTablesRule) error
 }
 
 //go:generate counterfeiter -o ../fakes/locker.go --fake-name Locker . locker
@@ -41,15 +44,22 @@ type restorer interface {
 	Restore(ruleState string) error
 }
 
-type Restorer struct{}
+// Restorer runs iptables-restore, or ip6tables-restore for the IPv6 tables.
+type Restorer struct {
+	IPv6 bool
+}
 
 func (r *Restorer) Restore(input string) error {
-	cmd := exec.Command("iptables-restore", "--noflush")
+	binary := "iptables-restore"
+	if r.IPv6 {
+		binary = "ip6tables-restore"
+	}
+	cmd := exec.Command(binary, "--noflush")
 	cmd.Stdin = strings.NewReader(input)
 
 	bytes, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("iptables-restore error: %s combined output: %s", err, string(bytes))
+		return fmt.Errorf("%s error: %s combined output: %s", binary, err, string(bytes))
 	}
 	return nil
 }
@@ -78,23 +88,34 @@ func (l *LockedIPTables) Exists(table, chain string, rulespec IPTablesRule) (boo
 }
 
 func (l *LockedIPTables) bulkAction(table, prefix string, rulespec ...IPTablesRule) error {
+	return l.restore(table, nil, prefix, rulespec...)
+}
+
+func (l *LockedIPTables) restore(table string, preamble []string, prefix string, rulespec ...IPTablesRule) error {
 	if err := l.Locker.Lock(); err != nil {
 		return fmt.Errorf("lock: %s", err)
 	}
 
+	err := l.restoreLocked(table, preamble, prefix, rulespec...)
+	if err != nil {
+		return handleIPTablesError(err, l.Locker.Unlock())
+	}
+
+	return l.Locker.Unlock()
+}
+
+func (l *LockedIPTables) restoreLocked(table string, preamble []string, prefix string, rulespec ...IPTablesRule) error {
 	input := []string{fmt.Sprintf("*%s\n", table)}
+	for _, p := range preamble {
+		input = append(input, p+"\n")
+	}
 	for _, r := range rulespec {
 		tmp := fmt.Sprintf("%s %s\n", prefix, strings.Join(r, " "))
 		input = append(input, tmp)
 	}
 	input = append(input, "COMMIT\n")
 
-	err := l.Restorer.Restore(strings.Join(input, ""))
-	if err != nil {
-		return handleIPTablesError(err, l.Locker.Unlock())
-	}
-
-	return l.Locker.Unlock()
+	return l.Restorer.Restore(strings.Join(input, ""))
 }
 
 func (l *LockedIPTables) BulkInsert(table, chain string, pos int, rulespec ...IPTablesRule) error {
@@ -105,6 +126,36 @@ func (l *LockedIPTables) BulkAppend(table, chain string, rulespec ...IPTablesRul
 	return l.bulkAction(table, fmt.Sprintf("-A %s", chain), rulespec...)
 }
 
+// BulkReplace flushes the chain and appends the rules in a single
+// iptables-restore, so packets never traverse a partially written chain.
+// The existing rules for which keep returns true are put back ahead of the
+// rules. They are listed under the same lock as the restore, so that a rule
+// added by another process in between cannot be lost.
+func (l *LockedIPTables) BulkReplace(table, chain string, keep func(rule string) bool, rulespec ...IPTablesRule) error {
+	if err := l.Locker.Lock(); err != nil {
+		return fmt.Errorf("lock: %s", err)
+	}
+
+	existing, err := l.IPTables.List(table, chain)
+	if err != nil {
+		return handleIPTablesError(err, l.Locker.Unlock())
+	}
+
+	preamble := []string{fmt.Sprintf("-F %s", chain)}
+	for _, rule := range existing {
+		if strings.HasPrefix(rule, "-A ") && keep(rule) {
+			preamble = append(preamble, rule)
+		}
+	}
+
+	err = l.restoreLocked(table, preamble, fmt.Sprintf("-A %s", chain), rulespec...)
+	if err != nil {
+		return handleIPTablesError(err, l.Locker.Unlock())
+	}
+
+	return l.Locker.Unlock()
+}
+
 func (l *LockedIPTables) Delete(table, chain string, rulespec IPTablesRule) error {
 	if err := l.Locker.Lock(); err != nil {
 		return fmt.Errorf("lock: %s", err)
@@ -131,6 +182,19 @@ func (l *LockedIPTables) List(table, chain string) ([]string, error) {
 	return ret, l.Locker.Unlock()
 }
 
+func (l *LockedIPTables) ListChains(table string) ([]string, error) {
+	if err := l.Locker.Lock(); err != nil {
+		return nil, fmt.Errorf("lock: %s", err)
+	}
+
+	ret, err := l.IPTables.ListChains(table)
+	if err != nil {
+		return nil, handleIPTablesError(err, l.Locker.Unlock())
+	}
+
+	return ret, l.Locker.Unlock()
+}
+
 func (l *LockedIPTables) NewChain(table, chain string) error {
 	return l.chainExec(table, chain, l.IPTables.NewChain)
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"lib/fakes"
 	"lib/rules"
+	"strings"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -157,6 +158,84 @@ var _ = Describe("LockedIptables", func() {
 		})
 	})
 
+	Describe("BulkReplace", func() {
+		var ruleSet []rules.IPTablesRule
+		BeforeEach(func() {
+			ruleSet = []rules.IPTablesRule{
+				rules.NewMarkSetRule("1.2.3.4", "A", "a-guid"),
+				rules.NewMarkSetRule("2.2.2.2", "B", "b-guid"),
+			}
+		})
+
+		var keep func(string) bool
+
+		BeforeEach(func() {
+			keep = func(rule string) bool {
+				return strings.Contains(rule, "-j keep-")
+			}
+			ipt.ListReturns([]string{
+				"-N some-chain",
+				"-A some-chain -j keep-me",
+				"-A some-chain -j drop-me",
+			}, nil)
+		})
+
+		It("flushes the chain and appends the kept rules and the rules in one restore", func() {
+			err := lockedIPT.BulkReplace("some-table", "some-chain", keep, ruleSet...)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ipt.ListCallCount()).To(Equal(1))
+			table, chain := ipt.ListArgsForCall(0)
+			Expect(table).To(Equal("some-table"))
+			Expect(chain).To(Equal("some-chain"))
+
+			Expect(lock.LockCallCount()).To(Equal(1))
+			Expect(lock.UnlockCallCount()).To(Equal(1))
+			Expect(restorer.RestoreCallCount()).To(Equal(1))
+			restoreInput := restorer.RestoreArgsForCall(0)
+			Expect(restoreInput).To(Equal("*some-table\n" +
+				"-F some-chain\n" +
+				"-A some-chain -j keep-me\n" +
+				"-A some-chain --source 1.2.3.4 --jump MARK --set-xmark 0xA -m comment --comment src:a-guid\n" +
+				"-A some-chain --source 2.2.2.2 --jump MARK --set-xmark 0xB -m comment --comment src:b-guid\n" +
+				"COMMIT\n"))
+		})
+
+		Context("when the lock fails", func() {
+			BeforeEach(func() {
+				lock.LockReturns(errors.New("banana"))
+			})
+			It("should return an error", func() {
+				err := lockedIPT.BulkReplace("some-table", "some-chain", keep, ruleSet...)
+				Expect(err).To(MatchError("lock: banana"))
+				Expect(ipt.ListCallCount()).To(Equal(0))
+				Expect(restorer.RestoreCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when listing the chain fails", func() {
+			BeforeEach(func() {
+				ipt.ListReturns(nil, errors.New("apple"))
+			})
+			It("unlocks and returns an error without restoring", func() {
+				err := lockedIPT.BulkReplace("some-table", "some-chain", keep, ruleSet...)
+				Expect(err).To(MatchError("iptables call: apple and unlock: <nil>"))
+				Expect(lock.UnlockCallCount()).To(Equal(1))
+				Expect(restorer.RestoreCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the restorer fails", func() {
+			BeforeEach(func() {
+				restorer.RestoreReturns(fmt.Errorf("banana"))
+			})
+			It("should return an error", func() {
+				err := lockedIPT.BulkReplace("some-table", "some-chain", keep, ruleSet...)
+				Expect(err).To(MatchError("iptables call: banana and unlock: <nil>"))
+			})
+		})
+	})
+
 	Describe("Exists", func() {
 		BeforeEach(func() {
 			ipt.ExistsReturns(true, nil)
@@ -302,6 +381,42 @@ var _ = Describe("LockedIptables", func() {
 		})
 	})
 
+	Describe("ListChains", func() {
+		BeforeEach(func() {
+			ipt.ListChainsReturns([]string{"INPUT", "some-chain"}, nil)
+		})
+		It("locks and passes the correct parameters to the iptables library", func() {
+			chains, err := lockedIPT.ListChains("some-table")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chains).To(Equal([]string{"INPUT", "some-chain"}))
+
+			Expect(lock.LockCallCount()).To(Equal(1))
+			Expect(lock.UnlockCallCount()).To(Equal(1))
+			Expect(ipt.ListChainsCallCount()).To(Equal(1))
+			Expect(ipt.ListChainsArgsForCall(0)).To(Equal("some-table"))
+		})
+
+		Context("when locking fails", func() {
+			BeforeEach(func() {
+				lock.LockReturns(errors.New("banana"))
+			})
+			It("returns an error", func() {
+				_, err := lockedIPT.ListChains("some-table")
+				Expect(err).To(MatchError("lock: banana"))
+			})
+		})
+
+		Context("when iptables call fails and unlock succeeds", func() {
+			BeforeEach(func() {
+				ipt.ListChainsReturns(nil, errors.New("banana"))
+			})
+			It("returns an error", func() {
+				_, err := lockedIPT.ListChains("some-table")
+				Expect(err).To(MatchError("iptables call: banana and unlock: <nil>"))
+			})
+		})
+	})
+
 	Describe("NewChain", func() {
 		It("locks and passes the correct parameters to the iptables library", func() {
 			err := lockedIPT.NewChain("some-table", "some-chain")
@@ -20,6 +20,11 @@ func (l *IPTablesLocker) Lock() error {
 	l.f, err = l.FileLocker.Open()
 	if err != nil {
 		l.Mutex.Unlock()
+		// the timeout already names the lock file and its holder, and
+		// callers may want to tell it apart from other errors
+		if timeoutErr, ok := err.(*filelock.TimeoutError); ok {
+			return timeoutErr
+		}
 		return fmt.Errorf("open lock file: %s", err)
 	}
 	return nil
@@ -3,8 +3,10 @@ package rules_test
 import (
 	"fmt"
 	"lib/fakes"
+	"lib/filelock"
 	"lib/rules"
 	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -39,5 +41,23 @@ var _ = Describe("Locker", func() {
 				Expect(err).To(MatchError("open lock file: banana"))
 			})
 		})
+		Context("when fileLocker times out", func() {
+			var timeoutErr *filelock.TimeoutError
+
+			BeforeEach(func() {
+				timeoutErr = &filelock.TimeoutError{Path: "some-lock-file", Timeout: time.Second}
+				flock.OpenReturns(nil, timeoutErr)
+			})
+			It("returns the timeout error as it is", func() {
+				err := locker.Lock()
+				Expect(err).To(BeIdenticalTo(timeoutErr))
+			})
+			It("releases the mutex", func() {
+				locker.Lock()
+
+				flock.OpenReturns(&fakes.LockedFile{}, nil)
+				Expect(locker.Lock()).To(Succeed())
+			})
+		})
 	})
 })
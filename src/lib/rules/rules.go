@@ -2,6 +2,7 @@ package rules
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 )
@@ -15,24 +16,33 @@ func AppendComment(rule IPTablesRule, comment string) IPTablesRule {
 	)
 }
 
-func NewPortForwardingRule(hostPort, containerPort int, hostIP, containerIP string) IPTablesRule {
+// NewPortForwardingRule forwards the host ports from hostPort to hostPortEnd
+// to the same containerPort.
+func NewPortForwardingRule(protocol string, hostPort, hostPortEnd, containerPort int, hostIP, containerIP string) IPTablesRule {
 	return IPTablesRule{
-		"-d", hostIP, "-p", "tcp",
-		"-m", "tcp", "--dport", fmt.Sprintf("%d", hostPort),
+		"-d", hostIP, "-p", protocol,
+		"-m", protocol, "--dport", portRange(hostPort, hostPortEnd),
 		"--jump", "DNAT",
 		"--to-destination", fmt.Sprintf("%s:%d", containerIP, containerPort),
 	}
 }
 
-func NewIngressMarkRule(hostInterface string, hostPort int, hostIP, tag string) IPTablesRule {
+func NewIngressMarkRule(hostInterface, protocol string, hostPort, hostPortEnd int, hostIP, tag string) IPTablesRule {
 	return IPTablesRule{
-		"-i", hostInterface, "-d", hostIP, "-p", "tcp",
-		"-m", "tcp", "--dport", fmt.Sprintf("%d", hostPort),
+		"-i", hostInterface, "-d", hostIP, "-p", protocol,
+		"-m", protocol, "--dport", portRange(hostPort, hostPortEnd),
 		"--jump", "MARK",
 		"--set-mark", fmt.Sprintf("0x%s", tag),
 	}
 }
 
+func portRange(start, end int) string {
+	if end <= start {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d:%d", start, end)
+}
+
 func NewMarkAllowRule(destinationIP, protocol string, port int, tag string, sourceAppGUID, destinationAppGUID string) IPTablesRule {
 	return AppendComment(IPTablesRule{
 		"-d", destinationIP,
@@ -54,6 +64,54 @@ func NewMarkAllowLogRule(destinationIP, protocol string, port int, tag string, d
 		trimAndPad(fmt.Sprintf("OK_%s_%s", tag, destinationAppGUID))}
 }
 
+func NewMarkAllowICMPRule(destinationIP string, icmpType, icmpCode *int, tag string, sourceAppGUID, destinationAppGUID string) IPTablesRule {
+	rule := append(IPTablesRule{"-d", destinationIP}, icmpMatch(icmpType, icmpCode)...)
+	return AppendComment(append(rule,
+		"-m", "mark", "--mark", fmt.Sprintf("0x%s", tag),
+		"--jump", "ACCEPT",
+	), fmt.Sprintf("src:%s_dst:%s", sourceAppGUID, destinationAppGUID))
+}
+
+func NewMarkAllowICMPLogRule(destinationIP string, icmpType, icmpCode *int, tag string, destinationAppGUID string) IPTablesRule {
+	rule := append(IPTablesRule{"-d", destinationIP}, icmpMatch(icmpType, icmpCode)...)
+	return append(rule,
+		"-m", "mark", "--mark", fmt.Sprintf("0x%s", tag),
+		"-m", "conntrack", "--ctstate", "INVALID,NEW,UNTRACKED",
+		"--jump", "LOG", "--log-prefix",
+		trimAndPad(fmt.Sprintf("OK_%s_%s", tag, destinationAppGUID)))
+}
+
+func NewMarkAllowAllRule(destinationIP, tag string, sourceAppGUID, destinationAppGUID string) IPTablesRule {
+	return AppendComment(IPTablesRule{
+		"-d", destinationIP,
+		"-m", "mark", "--mark", fmt.Sprintf("0x%s", tag),
+		"--jump", "ACCEPT",
+	}, fmt.Sprintf("src:%s_dst:%s", sourceAppGUID, destinationAppGUID))
+}
+
+func NewMarkAllowAllLogRule(destinationIP, tag string, destinationAppGUID string) IPTablesRule {
+	return IPTablesRule{
+		"-d", destinationIP,
+		"-m", "mark", "--mark", fmt.Sprintf("0x%s", tag),
+		"-m", "conntrack", "--ctstate", "INVALID,NEW,UNTRACKED",
+		"--jump", "LOG", "--log-prefix",
+		trimAndPad(fmt.Sprintf("OK_%s_%s", tag, destinationAppGUID))}
+}
+
+// icmpMatch matches every ICMP type when icmpType is nil, and every code of
+// the type when icmpCode is nil.
+func icmpMatch(icmpType, icmpCode *int) IPTablesRule {
+	match := IPTablesRule{"-p", "icmp"}
+	if icmpType == nil {
+		return match
+	}
+	icmpTypeSpec := strconv.Itoa(*icmpType)
+	if icmpCode != nil {
+		icmpTypeSpec = fmt.Sprintf("%d/%d", *icmpType, *icmpCode)
+	}
+	return append(match, "-m", "icmp", "--icmp-type", icmpTypeSpec)
+}
+
 func NewMarkSetRule(sourceIP, tag, appGUID string) IPTablesRule {
 	return AppendComment(IPTablesRule{
 		"--source", sourceIP,
@@ -121,6 +179,23 @@ func NewNetOutWithPortsRule(startIP, endIP string, startPort, endPort int, proto
 	}
 }
 
+// NewEgressRule allows traffic to cidr for the protocol. Ports only apply to
+// tcp and udp, where 0-0 allows every port; icmpType and icmpCode only apply to
+// icmp.
+func NewEgressRule(cidr, protocol string, startPort, endPort int, icmpType, icmpCode *int) IPTablesRule {
+	rule := IPTablesRule{"-d", cidr}
+	switch protocol {
+	case "tcp", "udp":
+		rule = append(rule, "-p", protocol)
+		if startPort != 0 || endPort != 0 {
+			rule = append(rule, "-m", protocol, "--destination-port", fmt.Sprintf("%d:%d", startPort, endPort))
+		}
+	case "icmp":
+		rule = append(rule, icmpMatch(icmpType, icmpCode)...)
+	}
+	return append(rule, "--jump", "ACCEPT")
+}
+
 func NewNetOutICMPRule(startIP, endIP string, icmpType, icmpCode int) IPTablesRule {
 	return IPTablesRule{
 		"-m", "iprange",
@@ -265,6 +340,38 @@ func NewNetOutDefaultRejectRule() IPTablesRule {
 	}
 }
 
+// IsIPv6 reports whether ip, an address or a CIDR, belongs in the ip6tables
+// tables.
+func IsIPv6(ip string) bool {
+	if parsed, _, err := net.ParseCIDR(ip); err == nil {
+		return parsed.To4() == nil
+	}
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() == nil
+}
+
+// ToIPv6 rewrites the ICMP matches and reject types of a rule built for
+// iptables so that ip6tables accepts it. ICMP types and codes are kept as they
+// are, so they must already be ICMPv6 ones; the policy server rejects egress
+// policies to IPv6 destinations with other types.
+func ToIPv6(rule IPTablesRule) IPTablesRule {
+	converted := make(IPTablesRule, len(rule))
+	for i, field := range rule {
+		switch {
+		case field == "icmp" && i > 0 && rule[i-1] == "-p":
+			field = "icmpv6"
+		case field == "icmp" && i > 0 && rule[i-1] == "-m":
+			field = "icmp6"
+		case field == "--icmp-type":
+			field = "--icmpv6-type"
+		case field == "icmp-port-unreachable":
+			field = "icmp6-port-unreachable"
+		}
+		converted[i] = field
+	}
+	return converted
+}
+
 func trimAndPad(name string) string {
 	if len(name) > 28 {
 		name = name[:28]
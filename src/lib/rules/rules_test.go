@@ -40,6 +40,117 @@ var _ = Describe("Rules", func() {
 		})
 	})
 
+	Describe("NewPortForwardingRule", func() {
+		It("forwards a single host port", func() {
+			rule := rules.NewPortForwardingRule("udp", 1111, 1111, 2222, "1.2.3.4", "5.6.7.8")
+			Expect(rule).To(Equal(rules.IPTablesRule{
+				"-d", "1.2.3.4", "-p", "udp",
+				"-m", "udp", "--dport", "1111",
+				"--jump", "DNAT",
+				"--to-destination", "5.6.7.8:2222",
+			}))
+		})
+
+		Context("when given a range of host ports", func() {
+			It("matches the whole range", func() {
+				rule := rules.NewPortForwardingRule("tcp", 1111, 1115, 2222, "1.2.3.4", "5.6.7.8")
+				Expect(rule).To(gomegamatchers.ContainSequence(rules.IPTablesRule{"-m", "tcp", "--dport", "1111:1115"}))
+			})
+		})
+	})
+
+	Describe("NewMarkAllowICMPRule", func() {
+		var icmpType, icmpCode int
+
+		BeforeEach(func() {
+			icmpType, icmpCode = 8, 0
+		})
+
+		It("matches the icmp type and code", func() {
+			rule := rules.NewMarkAllowICMPRule("10.255.1.2", &icmpType, &icmpCode, "AB", "some-src", "some-dst")
+			Expect(rule).To(Equal(rules.IPTablesRule{
+				"-d", "10.255.1.2",
+				"-p", "icmp",
+				"-m", "icmp", "--icmp-type", "8/0",
+				"-m", "mark", "--mark", "0xAB",
+				"--jump", "ACCEPT",
+				"-m", "comment", "--comment", "src:some-src_dst:some-dst",
+			}))
+		})
+
+		Context("when no code is given", func() {
+			It("matches every code of the icmp type", func() {
+				rule := rules.NewMarkAllowICMPRule("10.255.1.2", &icmpType, nil, "AB", "some-src", "some-dst")
+				Expect(rule).To(gomegamatchers.ContainSequence(rules.IPTablesRule{"-m", "icmp", "--icmp-type", "8", "-m", "mark"}))
+			})
+		})
+
+		Context("when no type is given", func() {
+			It("matches every icmp type", func() {
+				rule := rules.NewMarkAllowICMPRule("10.255.1.2", nil, nil, "AB", "some-src", "some-dst")
+				Expect(rule).To(gomegamatchers.ContainSequence(rules.IPTablesRule{"-p", "icmp", "-m", "mark"}))
+				Expect(rule).NotTo(ContainElement("--icmp-type"))
+			})
+		})
+	})
+
+	Describe("NewMarkAllowAllRule", func() {
+		It("does not match on protocol or port", func() {
+			rule := rules.NewMarkAllowAllRule("10.255.1.2", "AB", "some-src", "some-dst")
+			Expect(rule).To(Equal(rules.IPTablesRule{
+				"-d", "10.255.1.2",
+				"-m", "mark", "--mark", "0xAB",
+				"--jump", "ACCEPT",
+				"-m", "comment", "--comment", "src:some-src_dst:some-dst",
+			}))
+		})
+	})
+
+	Describe("NewMarkAllowAllLogRule", func() {
+		It("logs new connections for any protocol", func() {
+			rule := rules.NewMarkAllowAllLogRule("10.255.1.2", "AB", "some-dst")
+			Expect(rule).To(Equal(rules.IPTablesRule{
+				"-d", "10.255.1.2",
+				"-m", "mark", "--mark", "0xAB",
+				"-m", "conntrack", "--ctstate", "INVALID,NEW,UNTRACKED",
+				"--jump", "LOG", "--log-prefix", `"OK_AB_some-dst "`,
+			}))
+		})
+	})
+
+	Describe("NewEgressRule", func() {
+		It("matches tcp and udp port ranges", func() {
+			rule := rules.NewEgressRule("10.0.0.0/8", "udp", 53, 54, nil, nil)
+			Expect(rule).To(Equal(rules.IPTablesRule{
+				"-d", "10.0.0.0/8",
+				"-p", "udp",
+				"-m", "udp", "--destination-port", "53:54",
+				"--jump", "ACCEPT",
+			}))
+		})
+
+		It("matches every port when no ports are given", func() {
+			rule := rules.NewEgressRule("10.0.0.0/8", "tcp", 0, 0, nil, nil)
+			Expect(rule).To(Equal(rules.IPTablesRule{"-d", "10.0.0.0/8", "-p", "tcp", "--jump", "ACCEPT"}))
+		})
+
+		It("matches icmp types", func() {
+			icmpType := 8
+			rule := rules.NewEgressRule("10.0.0.1/32", "icmp", 0, 0, &icmpType, nil)
+			Expect(rule).To(Equal(rules.IPTablesRule{
+				"-d", "10.0.0.1/32",
+				"-p", "icmp",
+				"-m", "icmp", "--icmp-type", "8",
+				"--jump", "ACCEPT",
+			}))
+		})
+
+		It("matches every protocol for all", func() {
+			rule := rules.NewEgressRule("0.0.0.0/0", "all", 0, 0, nil, nil)
+			Expect(rule).To(Equal(rules.IPTablesRule{"-d", "0.0.0.0/0", "--jump", "ACCEPT"}))
+		})
+	})
+
 	Describe("NewNetOutDefaultLogRule", func() {
 		Context("when the log prefix is greater than 28 characters", func() {
 			It("shortens the log-prefix to 28 characters and adds a space", func() {
@@ -73,4 +184,45 @@ var _ = Describe("Rules", func() {
 			})
 		})
 	})
+
+	Describe("ToIPv6", func() {
+		It("rewrites icmp matches for ip6tables", func() {
+			icmpType, icmpCode := 128, 0
+			rule := rules.NewEgressRule("2001:db8::/32", "icmp", 0, 0, &icmpType, &icmpCode)
+			Expect(rules.ToIPv6(rule)).To(Equal(rules.IPTablesRule{
+				"-d", "2001:db8::/32",
+				"-p", "icmpv6", "-m", "icmp6", "--icmpv6-type", "128/0",
+				"--jump", "ACCEPT",
+			}))
+		})
+
+		It("rewrites the reject type for ip6tables", func() {
+			Expect(rules.ToIPv6(rules.NewNetOutDefaultRejectRule())).To(Equal(rules.IPTablesRule{
+				"--jump", "REJECT",
+				"--reject-with", "icmp6-port-unreachable",
+			}))
+		})
+
+		It("does not modify the original rule", func() {
+			rule := rules.NewNetOutDefaultRejectRule()
+			rules.ToIPv6(rule)
+			Expect(rule).To(Equal(rules.NewNetOutDefaultRejectRule()))
+		})
+
+		It("leaves other rules alone", func() {
+			rule := rules.NewNetOutRule("2001:db8::1", "2001:db8::ff")
+			Expect(rules.ToIPv6(rule)).To(Equal(rule))
+		})
+	})
+
+	Describe("IsIPv6", func() {
+		It("recognizes IPv6 addresses and CIDRs", func() {
+			Expect(rules.IsIPv6("2001:db8::1")).To(BeTrue())
+			Expect(rules.IsIPv6("2001:db8::/32")).To(BeTrue())
+			Expect(rules.IsIPv6("10.255.1.2")).To(BeFalse())
+			Expect(rules.IsIPv6("10.0.0.0/8")).To(BeFalse())
+			Expect(rules.IsIPv6("::ffff:10.255.1.2")).To(BeFalse())
+			Expect(rules.IsIPv6("not-an-ip")).To(BeFalse())
+		})
+	})
 })
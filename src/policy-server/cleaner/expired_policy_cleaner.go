@@ -0,0 +1,45 @@
+package cleaner
+
+import (
+	"fmt"
+	"policy-server/models"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/expired_store.go --fake-name ExpiredStore . expiredStore
+type expiredStore interface {
+	DeleteExpired(time.Time) ([]models.Policy, error)
+}
+
+// ExpiredPolicyCleaner deletes policies whose expires_at has passed. Expired
+// policies are already left out of the internal API, so this only has to
+// keep the database tidy and need not run often.
+type ExpiredPolicyCleaner struct {
+	Logger lager.Logger
+	Store  expiredStore
+	Clock  clock
+}
+
+func (c *ExpiredPolicyCleaner) DeleteExpiredPolicies() ([]models.Policy, error) {
+	expired, err := c.Store.DeleteExpired(c.Clock.Now())
+	if err != nil {
+		c.Logger.Error("store-delete-expired-policies-failed", err)
+		return nil, fmt.Errorf("database write failed: %s", err)
+	}
+	if len(expired) == 0 {
+		return expired, nil
+	}
+
+	c.Logger.Info("deleted-expired-policies", lager.Data{
+		"total_policies":   len(expired),
+		"expired_policies": expired,
+	})
+	return expired, nil
+}
+
+func (c *ExpiredPolicyCleaner) DeleteExpiredPoliciesWrapper() error {
+	_, err := c.DeleteExpiredPolicies()
+	return err
+}
@@ -0,0 +1,84 @@
+package cleaner_test
+
+import (
+	"errors"
+	"policy-server/cleaner"
+	"policy-server/cleaner/fakes"
+	"policy-server/models"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("ExpiredPolicyCleaner", func() {
+	var (
+		expiredPolicyCleaner *cleaner.ExpiredPolicyCleaner
+		fakeStore            *fakes.ExpiredStore
+		fakeClock            *fakes.Clock
+		logger               *lagertest.TestLogger
+		now                  time.Time
+		past                 time.Time
+		expiredPolicies      []models.Policy
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+		past = now.Add(-time.Minute)
+
+		expiredPolicies = []models.Policy{{
+			Source:      models.Source{ID: "some-guid"},
+			Destination: models.Destination{ID: "some-other-guid", Protocol: "tcp", Port: 9090},
+			ExpiresAt:   &past,
+		}}
+
+		fakeStore = &fakes.ExpiredStore{}
+		fakeStore.DeleteExpiredReturns(expiredPolicies, nil)
+		fakeClock = &fakes.Clock{}
+		fakeClock.NowReturns(now)
+		logger = lagertest.NewTestLogger("test")
+
+		expiredPolicyCleaner = &cleaner.ExpiredPolicyCleaner{
+			Logger: logger,
+			Store:  fakeStore,
+			Clock:  fakeClock,
+		}
+	})
+
+	It("deletes the policies that expired at or before now", func() {
+		deleted, err := expiredPolicyCleaner.DeleteExpiredPolicies()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal(expiredPolicies))
+
+		Expect(fakeStore.DeleteExpiredCallCount()).To(Equal(1))
+		Expect(fakeStore.DeleteExpiredArgsForCall(0)).To(Equal(now))
+		Expect(logger).To(gbytes.Say("deleted-expired-policies.*total_policies\":1"))
+	})
+
+	Context("when no policies have expired", func() {
+		BeforeEach(func() {
+			fakeStore.DeleteExpiredReturns([]models.Policy{}, nil)
+		})
+
+		It("does not log", func() {
+			deleted, err := expiredPolicyCleaner.DeleteExpiredPolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(BeEmpty())
+			Expect(logger.Logs()).To(BeEmpty())
+		})
+	})
+
+	Context("when deleting the policies fails", func() {
+		BeforeEach(func() {
+			fakeStore.DeleteExpiredReturns(nil, errors.New("banana"))
+		})
+
+		It("returns a meaningful error", func() {
+			err := expiredPolicyCleaner.DeleteExpiredPoliciesWrapper()
+			Expect(err).To(MatchError("database write failed: banana"))
+			Expect(logger).To(gbytes.Say("store-delete-expired-policies-failed"))
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"policy-server/models"
+	"sync"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -30,55 +31,154 @@ type contextAdapter interface {
 	WithTimeout(context.Context, time.Duration) (context.Context, context.CancelFunc)
 }
 
+//go:generate counterfeiter -o fakes/clock.go --fake-name Clock . clock
+type clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (_ SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+type missingApp struct {
+	runs  int
+	since time.Time
+}
+
+// PolicyCleaner only deletes the policies of an app once it has been missing
+// from Cloud Controller on GracePeriodRuns consecutive runs and for at least
+// GracePeriod, so that a single inconsistent response cannot wipe valid policies.
 type PolicyCleaner struct {
 	Logger                lager.Logger
 	Store                 store
 	UAAClient             uaaClient
 	CCClient              ccClient
+	Clock                 clock
 	CCAppRequestChunkSize int
 	RequestTimeout        time.Duration
+	DryRun                bool
+	GracePeriodRuns       int
+	GracePeriod           time.Duration
+
+	mutex       sync.Mutex
+	missingApps map[string]missingApp
+	lastReport  models.CleanupReport
+}
+
+// DeleteStalePolicies is a run of the periodic cleanup: it counts the run
+// for every missing app.
+func (p *PolicyCleaner) DeleteStalePolicies() (models.CleanupReport, error) {
+	return p.deleteStalePolicies(true)
 }
 
-func (p *PolicyCleaner) DeleteStalePolicies() ([]models.Policy, error) {
+// DeleteStalePoliciesOnDemand is a cleanup triggered through the API. It only
+// deletes the policies of apps that the periodic runs already found past the
+// grace period, and does not count as a run itself, so that triggering it
+// repeatedly cannot bypass the grace period.
+func (p *PolicyCleaner) DeleteStalePoliciesOnDemand() (models.CleanupReport, error) {
+	return p.deleteStalePolicies(false)
+}
+
+func (p *PolicyCleaner) deleteStalePolicies(recordRun bool) (models.CleanupReport, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	policies, err := p.Store.All()
 	if err != nil {
 		p.Logger.Error("store-list-policies-failed", err)
-		return nil, fmt.Errorf("database read failed: %s", err)
+		return models.CleanupReport{}, fmt.Errorf("database read failed: %s", err)
 	}
 	token, err := p.UAAClient.GetToken()
 	if err != nil {
 		p.Logger.Error("get-uaa-token-failed", err)
-		return nil, fmt.Errorf("get UAA token failed: %s", err)
+		return models.CleanupReport{}, fmt.Errorf("get UAA token failed: %s", err)
 	}
 
-	stalePolicies := []models.Policy{}
+	report := models.CleanupReport{
+		DryRun:      p.DryRun,
+		Policies:    []models.Policy{},
+		StaleApps:   []models.StaleApp{},
+		PendingApps: []models.StaleApp{},
+	}
+	now := p.Clock.Now()
 
 	appGUIDs := policyAppGUIDs(policies)
+	if recordRun {
+		p.forgetUnreferencedApps(appGUIDs)
+	}
 	appGUIDchunks := getChunks(appGUIDs, p.CCAppRequestChunkSize)
 
 	for _, appGUIDchunk := range appGUIDchunks {
 		liveAppGUIDs, err := p.CCClient.GetLiveAppGUIDs(token, appGUIDchunk)
 		if err != nil {
 			p.Logger.Error("cc-get-app-guids-failed", err)
-			return nil, fmt.Errorf("get app guids from Cloud-Controller failed: %s", err)
+			return models.CleanupReport{}, fmt.Errorf("get app guids from Cloud-Controller failed: %s", err)
+		}
+
+		staleAppGUIDs := make(map[string]struct{})
+		for _, guid := range appGUIDchunk {
+			if _, ok := liveAppGUIDs[guid]; ok {
+				if recordRun {
+					delete(p.missingApps, guid)
+				}
+				continue
+			}
+
+			app := p.missingApp(guid, now)
+			if recordRun {
+				app = p.recordMissing(guid, now)
+			}
+			staleApp := models.StaleApp{
+				ID:           guid,
+				MissingRuns:  app.runs,
+				MissingSince: app.since,
+			}
+			reason := fmt.Sprintf("app not found in Cloud-Controller on %d consecutive runs since %s", app.runs, app.since.UTC().Format(time.RFC3339))
+			if p.pastGracePeriod(app, now) {
+				staleApp.Reason = reason
+				report.StaleApps = append(report.StaleApps, staleApp)
+				staleAppGUIDs[guid] = struct{}{}
+			} else {
+				staleApp.Reason = reason + ", within grace period"
+				report.PendingApps = append(report.PendingApps, staleApp)
+			}
 		}
 
-		staleAppGUIDs := getStaleAppGUIDs(liveAppGUIDs, appGUIDchunk)
 		toDelete := getStalePolicies(policies, staleAppGUIDs)
-		stalePolicies = append(stalePolicies, toDelete...)
+		report.Policies = append(report.Policies, toDelete...)
+
+		if p.DryRun {
+			p.Logger.Info("dry-run-stale-policies", lager.Data{
+				"total_policies": len(report.Policies),
+				"stale_policies": report.Policies,
+				"stale_apps":     report.StaleApps,
+			})
+			continue
+		}
 
 		p.Logger.Info("deleting stale policies:", lager.Data{
-			"total_policies": len(stalePolicies),
-			"stale_policies": stalePolicies,
+			"total_policies": len(report.Policies),
+			"stale_policies": report.Policies,
 		})
 		err = p.Store.Delete(toDelete)
 		if err != nil {
 			p.Logger.Error("store-delete-policies-failed", err)
-			return nil, fmt.Errorf("database write failed: %s", err)
+			return models.CleanupReport{}, fmt.Errorf("database write failed: %s", err)
 		}
 	}
 
-	return stalePolicies, nil
+	if len(report.PendingApps) > 0 {
+		p.Logger.Info("stale-apps-within-grace-period", lager.Data{
+			"pending_apps": report.PendingApps,
+		})
+	}
+
+	if recordRun {
+		p.lastReport = report
+	}
+	return report, nil
 }
 
 func (p *PolicyCleaner) DeleteStalePoliciesWrapper() error {
@@ -86,14 +186,58 @@ func (p *PolicyCleaner) DeleteStalePoliciesWrapper() error {
 	return err
 }
 
-func getStaleAppGUIDs(liveAppGUIDs map[string]struct{}, appGUIDs []string) map[string]struct{} {
-	staleAppGUIDs := make(map[string]struct{})
+// Reset forgets the missing apps and the last report. It is called when the
+// instance becomes the leader, since the runs of the previous leaders were
+// not counted here.
+func (p *PolicyCleaner) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.missingApps = nil
+	p.lastReport = models.CleanupReport{}
+}
+
+func (p *PolicyCleaner) LastReport() models.CleanupReport {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.lastReport
+}
+
+// missingApp is what the periodic runs recorded about a missing app so far.
+func (p *PolicyCleaner) missingApp(guid string, now time.Time) missingApp {
+	app, ok := p.missingApps[guid]
+	if !ok {
+		app.since = now
+	}
+	return app
+}
+
+func (p *PolicyCleaner) recordMissing(guid string, now time.Time) missingApp {
+	if p.missingApps == nil {
+		p.missingApps = make(map[string]missingApp)
+	}
+	app, ok := p.missingApps[guid]
+	if !ok {
+		app.since = now
+	}
+	app.runs++
+	p.missingApps[guid] = app
+	return app
+}
+
+func (p *PolicyCleaner) forgetUnreferencedApps(appGUIDs []string) {
+	referenced := make(map[string]struct{})
 	for _, guid := range appGUIDs {
-		if _, ok := liveAppGUIDs[guid]; !ok {
-			staleAppGUIDs[guid] = struct{}{}
+		referenced[guid] = struct{}{}
+	}
+	for guid := range p.missingApps {
+		if _, ok := referenced[guid]; !ok {
+			delete(p.missingApps, guid)
 		}
 	}
-	return staleAppGUIDs
+}
+
+func (p *PolicyCleaner) pastGracePeriod(app missingApp, now time.Time) bool {
+	return app.runs >= p.GracePeriodRuns && now.Sub(app.since) >= p.GracePeriod
 }
 
 func getStalePolicies(policyList []models.Policy, staleAppGUIDs map[string]struct{}) []models.Policy {
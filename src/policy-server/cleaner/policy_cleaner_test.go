@@ -19,6 +19,7 @@ var _ = Describe("PolicyCleaner", func() {
 		fakeStore     *fakes.Store
 		fakeUAAClient *fakes.UAAClient
 		fakeCCClient  *fakes.CCClient
+		fakeClock     *fakes.Clock
 		logger        *lagertest.TestLogger
 		allPolicies   []models.Policy
 	)
@@ -53,6 +54,7 @@ var _ = Describe("PolicyCleaner", func() {
 		fakeStore = &fakes.Store{}
 		fakeUAAClient = &fakes.UAAClient{}
 		fakeCCClient = &fakes.CCClient{}
+		fakeClock = &fakes.Clock{}
 		logger = lagertest.NewTestLogger("test")
 
 		policyCleaner = &cleaner.PolicyCleaner{
@@ -60,10 +62,12 @@ var _ = Describe("PolicyCleaner", func() {
 			Store:          fakeStore,
 			UAAClient:      fakeUAAClient,
 			CCClient:       fakeCCClient,
+			Clock:          fakeClock,
 			RequestTimeout: 5 * time.Second,
 		}
 
 		fakeUAAClient.GetTokenReturns("valid-token", nil)
+		fakeClock.NowReturns(time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC))
 		fakeStore.AllReturns(allPolicies, nil)
 		fakeCCClient.GetLiveAppGUIDsStub = func(token string, appGUIDs []string) (map[string]struct{}, error) {
 			liveGUIDs := make(map[string]struct{})
@@ -77,7 +81,7 @@ var _ = Describe("PolicyCleaner", func() {
 	})
 
 	It("Deletes policies that reference apps that do not exist", func() {
-		policies, err := policyCleaner.DeleteStalePolicies()
+		report, err := policyCleaner.DeleteStalePolicies()
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(fakeStore.AllCallCount()).To(Equal(1))
@@ -93,7 +97,162 @@ var _ = Describe("PolicyCleaner", func() {
 		Expect(fakeStore.DeleteArgsForCall(0)).To(Equal(stalePolicies))
 
 		Expect(logger).To(gbytes.Say("deleting stale policies:.*policies.*dead-guid.*dead-guid.*total_policies\":2"))
-		Expect(policies).To(Equal(stalePolicies))
+		Expect(report.Policies).To(Equal(stalePolicies))
+		Expect(report.DryRun).To(BeFalse())
+		Expect(report.StaleApps).To(Equal([]models.StaleApp{{
+			ID:           "dead-guid",
+			MissingRuns:  1,
+			MissingSince: time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC),
+			Reason:       "app not found in Cloud-Controller on 1 consecutive runs since 2017-06-01T12:00:00Z",
+		}}))
+		Expect(report.PendingApps).To(BeEmpty())
+		Expect(policyCleaner.LastReport()).To(Equal(report))
+	})
+
+	Context("when a grace period in runs is configured", func() {
+		BeforeEach(func() {
+			policyCleaner.GracePeriodRuns = 2
+		})
+
+		It("only deletes policies once the app has been missing on that many consecutive runs", func() {
+			report, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(BeEmpty())
+			Expect(report.PendingApps).To(Equal([]models.StaleApp{{
+				ID:           "dead-guid",
+				MissingRuns:  1,
+				MissingSince: time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC),
+				Reason:       "app not found in Cloud-Controller on 1 consecutive runs since 2017-06-01T12:00:00Z, within grace period",
+			}}))
+			Expect(fakeStore.DeleteArgsForCall(0)).To(BeEmpty())
+			Expect(logger).To(gbytes.Say("stale-apps-within-grace-period.*dead-guid"))
+
+			report, err = policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(Equal(allPolicies[1:]))
+			Expect(report.StaleApps).To(HaveLen(1))
+			Expect(report.StaleApps[0].MissingRuns).To(Equal(2))
+			Expect(report.PendingApps).To(BeEmpty())
+			Expect(fakeStore.DeleteArgsForCall(1)).To(Equal(allPolicies[1:]))
+		})
+
+		It("starts counting again when the app shows up between runs", func() {
+			_, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeCCClient.GetLiveAppGUIDsStub = nil
+			fakeCCClient.GetLiveAppGUIDsReturns(map[string]struct{}{
+				"live-guid": struct{}{},
+				"dead-guid": struct{}{},
+			}, nil)
+			_, err = policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+
+			fakeCCClient.GetLiveAppGUIDsReturns(map[string]struct{}{
+				"live-guid": struct{}{},
+			}, nil)
+			report, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(BeEmpty())
+			Expect(report.PendingApps).To(HaveLen(1))
+			Expect(report.PendingApps[0].MissingRuns).To(Equal(1))
+		})
+	})
+
+	Context("when the cleaner is reset", func() {
+		BeforeEach(func() {
+			policyCleaner.GracePeriodRuns = 2
+		})
+
+		It("starts counting the runs again and forgets the last report", func() {
+			_, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+
+			policyCleaner.Reset()
+			Expect(policyCleaner.LastReport()).To(Equal(models.CleanupReport{}))
+
+			report, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(BeEmpty())
+			Expect(report.PendingApps).To(HaveLen(1))
+			Expect(report.PendingApps[0].MissingRuns).To(Equal(1))
+		})
+	})
+
+	Context("when the cleanup is triggered on demand", func() {
+		BeforeEach(func() {
+			policyCleaner.GracePeriodRuns = 2
+		})
+
+		It("does not count as a run, so the grace period cannot be bypassed", func() {
+			for i := 0; i < 3; i++ {
+				report, err := policyCleaner.DeleteStalePoliciesOnDemand()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Policies).To(BeEmpty())
+				Expect(report.PendingApps).To(HaveLen(1))
+				Expect(report.PendingApps[0].MissingRuns).To(Equal(0))
+			}
+
+			report, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(BeEmpty())
+			Expect(report.PendingApps[0].MissingRuns).To(Equal(1))
+		})
+
+		It("deletes the policies of apps that the runs found past the grace period", func() {
+			_, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			lastReport := policyCleaner.LastReport()
+
+			report, err := policyCleaner.DeleteStalePoliciesOnDemand()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(Equal(allPolicies[1:]))
+			Expect(report.StaleApps[0].MissingRuns).To(Equal(2))
+			Expect(policyCleaner.LastReport()).To(Equal(lastReport))
+		})
+	})
+
+	Context("when a grace period duration is configured", func() {
+		BeforeEach(func() {
+			policyCleaner.GracePeriod = 10 * time.Minute
+		})
+
+		It("only deletes policies once the app has been missing for that long", func() {
+			report, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(BeEmpty())
+			Expect(report.PendingApps).To(HaveLen(1))
+
+			fakeClock.NowReturns(time.Date(2017, 6, 1, 12, 5, 0, 0, time.UTC))
+			report, err = policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(BeEmpty())
+
+			fakeClock.NowReturns(time.Date(2017, 6, 1, 12, 10, 0, 0, time.UTC))
+			report, err = policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Policies).To(Equal(allPolicies[1:]))
+			Expect(report.StaleApps[0].MissingSince).To(Equal(time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)))
+		})
+	})
+
+	Context("when dry run is enabled", func() {
+		BeforeEach(func() {
+			policyCleaner.DryRun = true
+		})
+
+		It("reports the stale policies without deleting them", func() {
+			report, err := policyCleaner.DeleteStalePolicies()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeStore.DeleteCallCount()).To(Equal(0))
+			Expect(report.DryRun).To(BeTrue())
+			Expect(report.Policies).To(Equal(allPolicies[1:]))
+			Expect(report.StaleApps).To(HaveLen(1))
+			Expect(logger).To(gbytes.Say("dry-run-stale-policies.*dead-guid.*total_policies\":2"))
+		})
 	})
 
 	Context("when there are more apps with policies than the CC chunk size", func() {
@@ -103,12 +262,13 @@ var _ = Describe("PolicyCleaner", func() {
 				Store:                 fakeStore,
 				UAAClient:             fakeUAAClient,
 				CCClient:              fakeCCClient,
+				Clock:                 fakeClock,
 				CCAppRequestChunkSize: 1,
 				RequestTimeout:        time.Duration(5) * time.Second,
 			}
 		})
 		It("Calls the CC server multiple times to check which policies to delete", func() {
-			policies, err := policyCleaner.DeleteStalePolicies()
+			report, err := policyCleaner.DeleteStalePolicies()
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fakeStore.AllCallCount()).To(Equal(1))
@@ -134,7 +294,7 @@ var _ = Describe("PolicyCleaner", func() {
 			Expect(deleted).To(ConsistOf(stalePolicies, []models.Policy{}))
 
 			Expect(logger).To(gbytes.Say("deleting stale policies:.*policies.*dead-guid.*dead-guid.*total_policies\":2"))
-			Expect(policies).To(ConsistOf(stalePolicies[0], stalePolicies[1]))
+			Expect(report.Policies).To(ConsistOf(stalePolicies[0], stalePolicies[1]))
 		})
 	})
 
@@ -144,9 +304,9 @@ var _ = Describe("PolicyCleaner", func() {
 		})
 
 		It("returns a meaningful error", func() {
-			policies, err := policyCleaner.DeleteStalePolicies()
+			report, err := policyCleaner.DeleteStalePolicies()
 			Expect(err).To(MatchError("database read failed: potato"))
-			Expect(policies).To(BeNil())
+			Expect(report).To(Equal(models.CleanupReport{}))
 		})
 
 		It("logs the error", func() {
@@ -161,9 +321,9 @@ var _ = Describe("PolicyCleaner", func() {
 		})
 
 		It("returns a meaningful error", func() {
-			policies, err := policyCleaner.DeleteStalePolicies()
+			report, err := policyCleaner.DeleteStalePolicies()
 			Expect(err).To(MatchError("get UAA token failed: potato"))
-			Expect(policies).To(BeNil())
+			Expect(report).To(Equal(models.CleanupReport{}))
 		})
 
 		It("logs the full error", func() {
@@ -178,9 +338,9 @@ var _ = Describe("PolicyCleaner", func() {
 		})
 
 		It("returns a meaningful error", func() {
-			policies, err := policyCleaner.DeleteStalePolicies()
+			report, err := policyCleaner.DeleteStalePolicies()
 			Expect(err).To(MatchError("get app guids from Cloud-Controller failed: potato"))
-			Expect(policies).To(BeNil())
+			Expect(report).To(Equal(models.CleanupReport{}))
 		})
 
 		It("logs the full error", func() {
@@ -195,9 +355,9 @@ var _ = Describe("PolicyCleaner", func() {
 		})
 
 		It("returns a meaningful error", func() {
-			policies, err := policyCleaner.DeleteStalePolicies()
+			report, err := policyCleaner.DeleteStalePolicies()
 			Expect(err).To(MatchError("database write failed: potato"))
-			Expect(policies).To(BeNil())
+			Expect(report).To(Equal(models.CleanupReport{}))
 		})
 
 		It("logs the full error", func() {
@@ -18,6 +18,7 @@ import (
 	"policy-server/cleaner"
 	"policy-server/config"
 	"policy-server/handlers"
+	"policy-server/leader"
 	"policy-server/server_metrics"
 	"policy-server/store"
 	"policy-server/uaa_client"
@@ -41,8 +42,14 @@ import (
 )
 
 const (
-	dropsondeOrigin = "policy-server"
-	emitInterval    = 30 * time.Second
+	dropsondeOrigin              = "policy-server"
+	emitInterval                 = 30 * time.Second
+	leaderLockName               = "policy-server"
+	defaultLeaderLeaseDuration   = 15 * time.Second
+	storeMaxAttempts             = 3
+	storeRetryBackoff            = 100 * time.Millisecond
+	defaultReadinessTimeout      = 5 * time.Second
+	expiredPolicyCleanupInterval = time.Minute
 )
 
 var (
@@ -119,15 +126,20 @@ func main() {
 		connection, err := retriableConnector.GetConnectionPool(conf.Database)
 		channel <- dbConnection{connection, err}
 	}()
+	// wait for as long as the connector is allowed to keep retrying, so that a
+	// database in the middle of a failover does not prevent startup
+	connectTimeout := retriableConnector.RetryInterval*time.Duration(retriableConnector.MaxRetries) +
+		time.Duration(conf.Database.Timeout)*time.Second
 	var connectionResult dbConnection
 	select {
 	case connectionResult = <-channel:
-	case <-time.After(5 * time.Second):
+	case <-time.After(connectTimeout):
 		log.Fatalf("%s.policy-server: db connection timeout", logPrefix)
 	}
 	if connectionResult.Err != nil {
 		log.Fatalf("%s.policy-server: db connect: %s", logPrefix, connectionResult.Err) // not tested
 	}
+	configureConnectionPool(connectionResult.ConnectionPool, conf)
 
 	timeout := time.Duration(conf.Database.Timeout) * time.Second
 	timeout = timeout - time.Duration(500)*time.Millisecond
@@ -148,8 +160,16 @@ func main() {
 		Logger: logger.Session("time-metric-emitter"),
 	}
 
+	retryingStore := &store.RetryWrapper{
+		Store:       dataStore,
+		Logger:      logger.Session("store"),
+		MaxAttempts: storeMaxAttempts,
+		Backoff:     storeRetryBackoff,
+		Sleeper:     store.SleeperFunc(time.Sleep),
+	}
+
 	wrappedStore := &store.MetricsWrapper{
-		Store:         dataStore,
+		Store:         retryingStore,
 		MetricsSender: metricsSender,
 	}
 
@@ -170,8 +190,19 @@ func main() {
 		CCClient:  ccClient,
 	}
 
+	quotaStore := &store.QuotaRetryWrapper{
+		QuotaStore:  store.NewQuotaStore(connectionResult.ConnectionPool),
+		Logger:      logger.Session("quota-store"),
+		MaxAttempts: storeMaxAttempts,
+		Backoff:     storeRetryBackoff,
+		Sleeper:     store.SleeperFunc(time.Sleep),
+	}
+
 	quotaGuard := &handlers.QuotaGuard{
 		Store:       wrappedStore,
+		QuotaStore:  quotaStore,
+		UAAClient:   uaaClient,
+		CCClient:    ccClient,
 		MaxPolicies: conf.MaxPolicies,
 	}
 
@@ -207,16 +238,37 @@ func main() {
 	}
 
 	policyCleaner := &cleaner.PolicyCleaner{
-		Logger:         logger.Session("policy-cleaner"),
-		Store:          wrappedStore,
-		UAAClient:      uaaClient,
-		CCClient:       ccClient,
-		RequestTimeout: time.Duration(5) * time.Second,
+		Logger:          logger.Session("policy-cleaner"),
+		Store:           wrappedStore,
+		UAAClient:       uaaClient,
+		CCClient:        ccClient,
+		Clock:           cleaner.SystemClock{},
+		RequestTimeout:  time.Duration(5) * time.Second,
+		DryRun:          conf.CleanupDryRun,
+		GracePeriodRuns: conf.CleanupGracePeriodRuns,
+		GracePeriod:     time.Duration(conf.CleanupGracePeriod) * time.Second,
+	}
+
+	leaderLock, err := store.NewLeaderLock(connectionResult.ConnectionPool, leaderLockName)
+	if err != nil {
+		log.Fatalf("%s.policy-server: failed to construct leader lock: %s", logPrefix, err)
+	}
+	retryingLeaderLock := &store.LeaderLockRetryWrapper{
+		LeaderLock:  leaderLock,
+		Logger:      logger.Session("leader-lock"),
+		MaxAttempts: storeMaxAttempts,
+		Backoff:     storeRetryBackoff,
+		Sleeper:     store.SleeperFunc(time.Sleep),
 	}
+	elector := initElector(logger, conf, retryingLeaderLock)
+	// the missing apps counted during an earlier leadership would leave out
+	// the runs of the other leaders since
+	elector.OnElected = policyCleaner.Reset
 
 	policiesCleanupHandler := &handlers.PoliciesCleanup{
 		Marshaler:     marshal.MarshalFunc(json.Marshal),
 		PolicyCleaner: policyCleaner,
+		Leadership:    elector,
 		ErrorResponse: errorResponse,
 	}
 
@@ -226,6 +278,62 @@ func main() {
 		ErrorResponse: errorResponse,
 	}
 
+	internalTagsIndexHandler := &handlers.TagsIndexInternal{
+		Store:         wrappedStore,
+		Marshaler:     marshal.MarshalFunc(json.Marshal),
+		ErrorResponse: errorResponse,
+	}
+
+	quotasIndexHandler := &handlers.QuotasIndex{
+		QuotaStore:    quotaStore,
+		Marshaler:     marshal.MarshalFunc(json.Marshal),
+		ErrorResponse: errorResponse,
+	}
+
+	createQuotasHandler := &handlers.QuotasCreate{
+		QuotaStore:    quotaStore,
+		Unmarshaler:   unmarshaler,
+		ErrorResponse: errorResponse,
+	}
+
+	deleteQuotasHandler := &handlers.QuotasDelete{
+		QuotaStore:    quotaStore,
+		Unmarshaler:   unmarshaler,
+		ErrorResponse: errorResponse,
+	}
+
+	egressStore := &store.EgressRetryWrapper{
+		EgressStore: store.NewEgressStore(connectionResult.ConnectionPool),
+		Logger:      logger.Session("egress-store"),
+		MaxAttempts: storeMaxAttempts,
+		Backoff:     storeRetryBackoff,
+		Sleeper:     store.SleeperFunc(time.Sleep),
+	}
+
+	egressPoliciesIndexHandler := &handlers.EgressPoliciesIndex{
+		EgressStore:   egressStore,
+		Marshaler:     marshal.MarshalFunc(json.Marshal),
+		ErrorResponse: errorResponse,
+	}
+
+	createEgressPoliciesHandler := &handlers.EgressPoliciesCreate{
+		EgressStore:   egressStore,
+		Unmarshaler:   unmarshaler,
+		ErrorResponse: errorResponse,
+	}
+
+	deleteEgressPoliciesHandler := &handlers.EgressPoliciesDelete{
+		EgressStore:   egressStore,
+		Unmarshaler:   unmarshaler,
+		ErrorResponse: errorResponse,
+	}
+
+	internalEgressPoliciesHandler := &handlers.EgressPoliciesIndexInternal{
+		EgressStore:   egressStore,
+		Marshaler:     marshal.MarshalFunc(json.Marshal),
+		ErrorResponse: errorResponse,
+	}
+
 	internalPoliciesHandler := &handlers.PoliciesIndexInternal{
 		Logger:        logger.Session("policies-index-internal"),
 		Store:         wrappedStore,
@@ -233,8 +341,18 @@ func main() {
 		ErrorResponse: errorResponse,
 	}
 
+	readinessTimeout := time.Duration(conf.Database.Timeout) * time.Second
+	if readinessTimeout <= 0 {
+		readinessTimeout = defaultReadinessTimeout
+	}
+	readinessHandler := &handlers.Readiness{
+		Store:   wrappedStore,
+		Timeout: readinessTimeout,
+	}
+
 	healthHandler := &handlers.Health{
 		Store:         wrappedStore,
+		Leadership:    elector,
 		ErrorResponse: errorResponse,
 	}
 
@@ -276,12 +394,26 @@ func main() {
 	externalHandlers := rata.Handlers{
 		"uptime":          metricsWrap("Uptime", logWrap(uptimeHandler)),
 		"health":          metricsWrap("Health", logWrap(healthHandler)),
+		"ready":           metricsWrap("Ready", logWrap(readinessHandler)),
 		"create_policies": metricsWrap("CreatePolicies", middleware.LogWrap(logger, authWrite(createPolicyHandler))),
 		"delete_policies": metricsWrap("DeletePolicies", middleware.LogWrap(logger, authWrite(deletePolicyHandler))),
 		"policies_index":  metricsWrap("PoliciesIndex", middleware.LogWrap(logger, authWrite(policiesIndexHandler))),
 		"cleanup":         metricsWrap("Cleanup", middleware.LogWrap(logger, authAdmin(policiesCleanupHandler))),
 		"tags_index":      metricsWrap("TagsIndex", middleware.LogWrap(logger, authAdmin(tagsIndexHandler))),
 		"whoami":          metricsWrap("WhoAmI", middleware.LogWrap(logger, authAdmin(whoamiHandler))),
+		"quotas_index":    metricsWrap("QuotasIndex", middleware.LogWrap(logger, authAdmin(quotasIndexHandler))),
+		"create_quotas":   metricsWrap("CreateQuotas", middleware.LogWrap(logger, authAdmin(createQuotasHandler))),
+		"delete_quotas":   metricsWrap("DeleteQuotas", middleware.LogWrap(logger, authAdmin(deleteQuotasHandler))),
+
+		"egress_policies_index":  metricsWrap("EgressPoliciesIndex", middleware.LogWrap(logger, authAdmin(egressPoliciesIndexHandler))),
+		"create_egress_policies": metricsWrap("CreateEgressPolicies", middleware.LogWrap(logger, authAdmin(createEgressPoliciesHandler))),
+		"delete_egress_policies": metricsWrap("DeleteEgressPolicies", middleware.LogWrap(logger, authAdmin(deleteEgressPoliciesHandler))),
+	}
+
+	internalHandlers := rata.Handlers{
+		"internal_policies":        metricsWrap("InternalPolicies", logWrap(internalPoliciesHandler)),
+		"internal_egress_policies": metricsWrap("InternalEgressPolicies", logWrap(internalEgressPoliciesHandler)),
+		"internal_tags":            metricsWrap("InternalTags", logWrap(internalTagsIndexHandler)),
 	}
 
 	err = dropsonde.Initialize(conf.MetronAddress, dropsondeOrigin)
@@ -289,17 +421,31 @@ func main() {
 		log.Fatalf("%s.policy-server: initializing dropsonde: %s", logPrefix, err)
 	}
 
-	metricsEmitter := initMetricsEmitter(logger, wrappedStore)
+	metricsEmitter := initMetricsEmitter(logger, wrappedStore, policyCleaner, elector)
 	externalServer := initExternalServer(conf, externalHandlers)
-	internalServer := initInternalServer(conf, metricsWrap("InternalPolicies", logWrap(internalPoliciesHandler)))
-	poller := initPoller(logger, conf, policyCleaner)
+	internalServer := initInternalServer(conf, internalHandlers)
+	poller := initPoller(logger, conf, elector.LeaderOnly(policyCleaner.DeleteStalePoliciesWrapper))
+	expiredPolicyCleaner := &cleaner.ExpiredPolicyCleaner{
+		Logger: logger.Session("expired-policy-cleaner"),
+		Store:  wrappedStore,
+		Clock:  cleaner.SystemClock{},
+	}
+	expiredPoller := &poller.Poller{
+		Logger:          logger.Session("expired-policy-cleaner-poller"),
+		PollInterval:    expiredPolicyCleanupInterval,
+		SingleCycleFunc: elector.LeaderOnly(expiredPolicyCleaner.DeleteExpiredPoliciesWrapper),
+	}
 	debugServer := debugserver.Runner(fmt.Sprintf("%s:%d", conf.DebugServerHost, conf.DebugServerPort), reconfigurableSink)
 
+	// the leader is elected before the servers start, so that a cleanup
+	// request is not refused while the election is pending
 	members := grouper.Members{
 		{"metrics_emitter", metricsEmitter},
+		{"leader-elector", elector},
 		{"http_server", externalServer},
 		{"internal_http_server", internalServer},
 		{"policy-cleaner-poller", poller},
+		{"expired-policy-cleaner-poller", expiredPoller},
 		{"debug-server", debugServer},
 	}
 
@@ -343,31 +489,67 @@ func initLoggerSink(logger lager.Logger, level string) *lager.ReconfigurableSink
 	return lager.NewReconfigurableSink(w, logLevel)
 }
 
-func initMetricsEmitter(logger lager.Logger, wrappedStore *store.MetricsWrapper) *metrics.MetricsEmitter {
-	totalPoliciesSource := server_metrics.NewTotalPoliciesSource(wrappedStore)
+func configureConnectionPool(connectionPool *sqlx.DB, conf *config.Config) {
+	if conf.MaxOpenConnections > 0 {
+		connectionPool.SetMaxOpenConns(conf.MaxOpenConnections)
+	}
+	if conf.MaxIdleConnections > 0 {
+		connectionPool.SetMaxIdleConns(conf.MaxIdleConnections)
+	}
+	if conf.ConnectionsMaxLifetimeSeconds > 0 {
+		connectionPool.SetConnMaxLifetime(time.Duration(conf.ConnectionsMaxLifetimeSeconds) * time.Second)
+	}
+}
+
+func initMetricsEmitter(logger lager.Logger, wrappedStore *store.MetricsWrapper, policyCleaner *cleaner.PolicyCleaner, elector *leader.Elector) *metrics.MetricsEmitter {
+	// the cleanup reports are only kept by the leader, which runs the cleanup,
+	// and the policies would be counted once per instance
+	totalPoliciesSource := server_metrics.LeaderOnly(elector, server_metrics.NewTotalPoliciesSource(wrappedStore))
+	stalePoliciesSource := server_metrics.LeaderOnly(elector, server_metrics.NewStalePoliciesSource(policyCleaner))
+	pendingStaleAppsSource := server_metrics.LeaderOnly(elector, server_metrics.NewPendingStaleAppsSource(policyCleaner))
 	uptimeSource := metrics.NewUptimeSource()
-	return metrics.NewMetricsEmitter(logger, emitInterval, uptimeSource, totalPoliciesSource)
+	return metrics.NewMetricsEmitter(logger, emitInterval, uptimeSource, totalPoliciesSource, stalePoliciesSource, pendingStaleAppsSource)
 }
 
-func initPoller(logger lager.Logger, conf *config.Config, policyCleaner *cleaner.PolicyCleaner) ifrit.Runner {
+func initPoller(logger lager.Logger, conf *config.Config, singleCycleFunc func() error) ifrit.Runner {
 	pollInterval := time.Duration(conf.CleanupInterval) * time.Second
 
 	return &poller.Poller{
 		Logger:          logger.Session("policy-cleaner-poller"),
 		PollInterval:    pollInterval,
-		SingleCycleFunc: policyCleaner.DeleteStalePoliciesWrapper,
+		SingleCycleFunc: singleCycleFunc,
 	}
 }
 
-func initInternalServer(conf *config.Config, internalPoliciesHandler http.Handler) ifrit.Runner {
+func initElector(logger lager.Logger, conf *config.Config, leaderLock store.LeaderLock) *leader.Elector {
+	leaseDuration := defaultLeaderLeaseDuration
+	if conf.LeaderLeaseDuration > 0 {
+		leaseDuration = time.Duration(conf.LeaderLeaseDuration) * time.Second
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown" // untested
+	}
+
+	return &leader.Elector{
+		Logger:        logger.Session("leader-elector"),
+		Lock:          leaderLock,
+		Clock:         cleaner.SystemClock{},
+		Owner:         fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		LeaseDuration: leaseDuration,
+		RenewInterval: leaseDuration / 3,
+	}
+}
+
+func initInternalServer(conf *config.Config, internalHandlers rata.Handlers) ifrit.Runner {
 	routes := rata.Routes{
 		{Name: "internal_policies", Method: "GET", Path: "/networking/v0/internal/policies"},
-	}
-	handlers := rata.Handlers{
-		"internal_policies": internalPoliciesHandler,
+		{Name: "internal_egress_policies", Method: "GET", Path: "/networking/v0/internal/egress_policies"},
+		{Name: "internal_tags", Method: "GET", Path: "/networking/v0/internal/tags"},
 	}
 
-	router, err := rata.NewRouter(routes, handlers)
+	router, err := rata.NewRouter(routes, internalHandlers)
 	if err != nil {
 		log.Fatalf("%s.policy-server: unable to create rata Router: %s", logPrefix, err) // not tested
 	}
@@ -387,12 +569,19 @@ func initExternalServer(conf *config.Config, externalHandlers rata.Handlers) ifr
 		{Name: "uptime", Method: "GET", Path: "/"},
 		{Name: "uptime", Method: "GET", Path: "/networking"},
 		{Name: "health", Method: "GET", Path: "/health"},
+		{Name: "ready", Method: "GET", Path: "/ready"},
 		{Name: "whoami", Method: "GET", Path: "/networking/v0/external/whoami"},
 		{Name: "create_policies", Method: "POST", Path: "/networking/v0/external/policies"},
 		{Name: "delete_policies", Method: "POST", Path: "/networking/v0/external/policies/delete"},
 		{Name: "policies_index", Method: "GET", Path: "/networking/v0/external/policies"},
 		{Name: "cleanup", Method: "POST", Path: "/networking/v0/external/policies/cleanup"},
 		{Name: "tags_index", Method: "GET", Path: "/networking/v0/external/tags"},
+		{Name: "quotas_index", Method: "GET", Path: "/networking/v0/external/quotas"},
+		{Name: "create_quotas", Method: "POST", Path: "/networking/v0/external/quotas"},
+		{Name: "delete_quotas", Method: "POST", Path: "/networking/v0/external/quotas/delete"},
+		{Name: "egress_policies_index", Method: "GET", Path: "/networking/v0/external/egress_policies"},
+		{Name: "create_egress_policies", Method: "POST", Path: "/networking/v0/external/egress_policies"},
+		{Name: "delete_egress_policies", Method: "POST", Path: "/networking/v0/external/egress_policies/delete"},
 	}
 
 	externalRouter, err := rata.NewRouter(routes, externalHandlers)
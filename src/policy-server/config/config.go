@@ -36,6 +36,13 @@ type Config struct {
 	RequestTimeout                  int       `json:"request_timeout" validate:"min=1"`
 	MaxPolicies                     int       `json:"max_policies" validate:"min=1"`
 	EnableSpaceDeveloperSelfService bool      `json:"enable_space_developer_self_service"`
+	CleanupDryRun                   bool      `json:"cleanup_dry_run"`
+	CleanupGracePeriodRuns          int       `json:"cleanup_grace_period_runs" validate:"min=0"`
+	CleanupGracePeriod              int       `json:"cleanup_grace_period" validate:"min=0"`
+	LeaderLeaseDuration             int       `json:"leader_lease_duration" validate:"min=0"`
+	MaxOpenConnections              int       `json:"max_open_connections" validate:"min=0"`
+	MaxIdleConnections              int       `json:"max_idle_connections" validate:"min=0"`
+	ConnectionsMaxLifetimeSeconds   int       `json:"connections_max_lifetime_seconds" validate:"min=0"`
 }
 
 func (c *Config) Validate() error {
@@ -58,7 +58,14 @@ var _ = Describe("Config", func() {
 					"cleanup_interval": 2,
 					"request_timeout": 5,
 					"max_policies": 3,
-					"enable_space_developer_self_service": true
+					"enable_space_developer_self_service": true,
+					"cleanup_dry_run": true,
+					"cleanup_grace_period_runs": 3,
+					"cleanup_grace_period": 600,
+					"leader_lease_duration": 20,
+					"max_open_connections": 50,
+					"max_idle_connections": 5,
+					"connections_max_lifetime_seconds": 3600
 				}`)
 				c, err := config.New(file.Name())
 				Expect(err).NotTo(HaveOccurred())
@@ -92,6 +99,13 @@ var _ = Describe("Config", func() {
 				Expect(c.RequestTimeout).To(Equal(5))
 				Expect(c.MaxPolicies).To(Equal(3))
 				Expect(c.EnableSpaceDeveloperSelfService).To(BeTrue())
+				Expect(c.CleanupDryRun).To(BeTrue())
+				Expect(c.CleanupGracePeriodRuns).To(Equal(3))
+				Expect(c.CleanupGracePeriod).To(Equal(600))
+				Expect(c.LeaderLeaseDuration).To(Equal(20))
+				Expect(c.MaxOpenConnections).To(Equal(50))
+				Expect(c.MaxIdleConnections).To(Equal(5))
+				Expect(c.ConnectionsMaxLifetimeSeconds).To(Equal(3600))
 			})
 		})
 
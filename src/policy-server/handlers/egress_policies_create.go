@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	"code.cloudfoundry.org/cf-networking-helpers/marshal"
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/egress_store.go --fake-name EgressStore . egressStore
+type egressStore interface {
+	Create([]models.EgressPolicy) error
+	Delete([]models.EgressPolicy) error
+	All() ([]models.EgressPolicy, error)
+	BySourceGuids([]string) ([]models.EgressPolicy, error)
+}
+
+type EgressPoliciesCreate struct {
+	EgressStore   egressStore
+	Unmarshaler   marshal.Unmarshaler
+	ErrorResponse errorResponse
+}
+
+func (h *EgressPoliciesCreate) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request, tokenData uaa_client.CheckTokenResponse) {
+	logger = logger.Session("create-egress-policies")
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		logger.Error("failed-reading-request-body", err)
+		h.ErrorResponse.BadRequest(w, err, "egress-policies-create", "failed reading request body")
+		return
+	}
+
+	var payload struct {
+		EgressPolicies []models.EgressPolicy `json:"egress_policies"`
+	}
+	err = h.Unmarshaler.Unmarshal(bodyBytes, &payload)
+	if err != nil {
+		logger.Error("failed-unmarshalling-payload", err)
+		h.ErrorResponse.BadRequest(w, err, "egress-policies-create", "invalid values passed to API")
+		return
+	}
+
+	err = validateEgressPolicies(payload.EgressPolicies)
+	if err != nil {
+		logger.Error("failed-validating-egress-policies", err)
+		h.ErrorResponse.BadRequest(w, err, "egress-policies-create", err.Error())
+		return
+	}
+
+	err = h.EgressStore.Create(payload.EgressPolicies)
+	if err != nil {
+		logger.Error("failed-creating-in-database", err)
+		h.ErrorResponse.InternalServerError(w, err, "egress-policies-create", "database create failed")
+		return
+	}
+
+	logger.Info("created-egress-policies", lager.Data{"egress_policies": payload.EgressPolicies, "userName": tokenData.UserName})
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
+
+func validateEgressPolicies(policies []models.EgressPolicy) error {
+	if len(policies) == 0 {
+		return errors.New("missing egress policies")
+	}
+
+	for _, policy := range policies {
+		if policy.Source.ID == "" {
+			return errors.New("missing source id")
+		}
+		if policy.Source.Type != models.EgressSourceTypeApp && policy.Source.Type != models.EgressSourceTypeSpace {
+			return errors.New("invalid source type, specify either app or space")
+		}
+
+		ip, _, err := net.ParseCIDR(policy.Destination.CIDR)
+		if err != nil {
+			return fmt.Errorf("invalid destination cidr %q", policy.Destination.CIDR)
+		}
+
+		err = validateEgressDestination(policy.Destination)
+		if err != nil {
+			return err
+		}
+
+		if ip.To4() == nil {
+			err = validateICMPv6(policy.Destination.ICMPType)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateEgressDestination(destination models.EgressDestination) error {
+	ports := destination.Ports
+	hasPorts := ports.Start != 0 || ports.End != 0
+
+	switch destination.Protocol {
+	case models.ProtocolTCP, models.ProtocolUDP:
+		if destination.ICMPType != nil || destination.ICMPCode != nil {
+			return fmt.Errorf("icmp type and code may not be specified for protocol %s", destination.Protocol)
+		}
+		if hasPorts && (ports.Start < 1 || ports.End > 65535 || ports.Start > ports.End) {
+			return fmt.Errorf("invalid destination port range %d-%d, must be within 1-65535 with start not after end", ports.Start, ports.End)
+		}
+	case models.ProtocolICMP:
+		if hasPorts {
+			return errors.New("ports may not be specified for protocol icmp")
+		}
+		return validateICMP(destination.ICMPType, destination.ICMPCode)
+	case models.ProtocolAll:
+		if hasPorts {
+			return errors.New("ports may not be specified for protocol all")
+		}
+		if destination.ICMPType != nil || destination.ICMPCode != nil {
+			return errors.New("icmp type and code may not be specified for protocol all")
+		}
+	default:
+		return errors.New("invalid destination protocol, specify one of udp, tcp, icmp or all")
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
+	"code.cloudfoundry.org/lager"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EgressPoliciesCreate", func() {
+	var (
+		requestJSON       string
+		request           *http.Request
+		handler           *handlers.EgressPoliciesCreate
+		resp              *httptest.ResponseRecorder
+		fakeEgressStore   *fakes.EgressStore
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		fakeUnmarshaler   *hfakes.Unmarshaler
+		tokenData         uaa_client.CheckTokenResponse
+	)
+
+	BeforeEach(func() {
+		requestJSON = `{"egress_policies": [
+			{ "source": { "id": "some-app-guid", "type": "app" }, "destination": { "protocol": "tcp", "cidr": "10.0.0.0/8", "ports": { "start": 8080, "end": 8090 } } },
+			{ "source": { "id": "some-space-guid", "type": "space" }, "destination": { "protocol": "icmp", "cidr": "10.0.0.1/32", "icmp_type": 8 } }
+		]}`
+
+		fakeEgressStore = &fakes.EgressStore{}
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		fakeUnmarshaler = &hfakes.Unmarshaler{}
+		fakeUnmarshaler.UnmarshalStub = json.Unmarshal
+		logger = lagertest.NewTestLogger("test")
+		handler = &handlers.EgressPoliciesCreate{
+			EgressStore:   fakeEgressStore,
+			Unmarshaler:   fakeUnmarshaler,
+			ErrorResponse: fakeErrorResponse,
+		}
+		tokenData = uaa_client.CheckTokenResponse{
+			Scope:    []string{"network.admin"},
+			UserName: "some_user",
+		}
+		resp = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		request, err = http.NewRequest("POST", "/networking/v0/external/egress_policies", bytes.NewBuffer([]byte(requestJSON)))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("persists the egress policies", func() {
+		handler.ServeHTTP(logger, resp, request, tokenData)
+
+		echoRequest := 8
+		Expect(fakeEgressStore.CreateCallCount()).To(Equal(1))
+		Expect(fakeEgressStore.CreateArgsForCall(0)).To(Equal([]models.EgressPolicy{
+			{
+				Source:      models.EgressSource{ID: "some-app-guid", Type: "app"},
+				Destination: models.EgressDestination{Protocol: "tcp", CIDR: "10.0.0.0/8", Ports: models.Ports{Start: 8080, End: 8090}},
+			},
+			{
+				Source:      models.EgressSource{ID: "some-space-guid", Type: "space"},
+				Destination: models.EgressDestination{Protocol: "icmp", CIDR: "10.0.0.1/32", ICMPType: &echoRequest},
+			},
+		}))
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON("{}"))
+	})
+
+	It("logs the egress policies with username", func() {
+		handler.ServeHTTP(logger, resp, request, tokenData)
+
+		Expect(logger.Logs()).To(HaveLen(1))
+		Expect(logger.Logs()[0]).To(SatisfyAll(
+			LogsWith(lager.INFO, "test.create-egress-policies.created-egress-policies"),
+			HaveLogData(HaveKeyWithValue("userName", "some_user")),
+		))
+	})
+
+	Context("when a policy has an IPv6 cidr", func() {
+		BeforeEach(func() {
+			requestJSON = `{"egress_policies": [
+				{ "source": { "id": "some-app-guid", "type": "app" }, "destination": { "protocol": "all", "cidr": "fd00::/8" } }
+			]}`
+		})
+
+		It("persists the egress policy", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeEgressStore.CreateCallCount()).To(Equal(1))
+			Expect(fakeEgressStore.CreateArgsForCall(0)).To(Equal([]models.EgressPolicy{
+				{
+					Source:      models.EgressSource{ID: "some-app-guid", Type: "app"},
+					Destination: models.EgressDestination{Protocol: "all", CIDR: "fd00::/8"},
+				},
+			}))
+			Expect(resp.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("when a policy has an IPv6 cidr and an ICMPv6 type", func() {
+		BeforeEach(func() {
+			requestJSON = `{"egress_policies": [
+				{ "source": { "id": "some-app-guid", "type": "app" }, "destination": { "protocol": "icmp", "cidr": "fd00::1/128", "icmp_type": 128 } }
+			]}`
+		})
+
+		It("persists the egress policy", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			echoRequest := 128
+			Expect(fakeEgressStore.CreateCallCount()).To(Equal(1))
+			Expect(fakeEgressStore.CreateArgsForCall(0)).To(Equal([]models.EgressPolicy{
+				{
+					Source:      models.EgressSource{ID: "some-app-guid", Type: "app"},
+					Destination: models.EgressDestination{Protocol: "icmp", CIDR: "fd00::1/128", ICMPType: &echoRequest},
+				},
+			}))
+			Expect(resp.Code).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("when the payload cannot be unmarshaled", func() {
+		BeforeEach(func() {
+			fakeUnmarshaler.UnmarshalReturns(errors.New("banana"))
+			fakeUnmarshaler.UnmarshalStub = nil
+		})
+
+		It("calls the bad request handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.BadRequestArgsForCall(0)
+			Expect(err).To(MatchError("banana"))
+			Expect(message).To(Equal("egress-policies-create"))
+			Expect(description).To(Equal("invalid values passed to API"))
+		})
+	})
+
+	DescribeTable("when the egress policies are invalid",
+		func(body, expectedError string) {
+			requestJSON = body
+			request, _ = http.NewRequest("POST", "/networking/v0/external/egress_policies", bytes.NewBuffer([]byte(requestJSON)))
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeEgressStore.CreateCallCount()).To(Equal(0))
+			Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.BadRequestArgsForCall(0)
+			Expect(err).To(MatchError(expectedError))
+			Expect(message).To(Equal("egress-policies-create"))
+			Expect(description).To(Equal(expectedError))
+		},
+		Entry("no policies", `{"egress_policies": []}`, "missing egress policies"),
+		Entry("missing source id", `{"egress_policies": [{"source": {"type": "app"}, "destination": {"protocol": "all", "cidr": "10.0.0.0/8"}}]}`, "missing source id"),
+		Entry("bad source type", `{"egress_policies": [{"source": {"id": "some-guid", "type": "org"}, "destination": {"protocol": "all", "cidr": "10.0.0.0/8"}}]}`, "invalid source type, specify either app or space"),
+		Entry("bad cidr", `{"egress_policies": [{"source": {"id": "some-guid", "type": "app"}, "destination": {"protocol": "all", "cidr": "10.0.0.0"}}]}`, `invalid destination cidr "10.0.0.0"`),
+		Entry("bad protocol", `{"egress_policies": [{"source": {"id": "some-guid", "type": "app"}, "destination": {"protocol": "sctp", "cidr": "10.0.0.0/8"}}]}`, "invalid destination protocol, specify one of udp, tcp, icmp or all"),
+		Entry("reversed port range", `{"egress_policies": [{"source": {"id": "some-guid", "type": "app"}, "destination": {"protocol": "tcp", "cidr": "10.0.0.0/8", "ports": {"start": 90, "end": 80}}}]}`, "invalid destination port range 90-80, must be within 1-65535 with start not after end"),
+		Entry("ports for icmp", `{"egress_policies": [{"source": {"id": "some-guid", "type": "app"}, "destination": {"protocol": "icmp", "cidr": "10.0.0.0/8", "ports": {"start": 80, "end": 80}}}]}`, "ports may not be specified for protocol icmp"),
+		Entry("icmp code without type", `{"egress_policies": [{"source": {"id": "some-guid", "type": "app"}, "destination": {"protocol": "icmp", "cidr": "10.0.0.0/8", "icmp_code": 0}}]}`, "icmp code may not be specified without an icmp type"),
+		Entry("icmp type for all", `{"egress_policies": [{"source": {"id": "some-guid", "type": "app"}, "destination": {"protocol": "all", "cidr": "10.0.0.0/8", "icmp_type": 8}}]}`, "icmp type and code may not be specified for protocol all"),
+		Entry("icmpv4 type for an ipv6 cidr", `{"egress_policies": [{"source": {"id": "some-guid", "type": "app"}, "destination": {"protocol": "icmp", "cidr": "fd00::/64", "icmp_type": 8}}]}`, "invalid icmp type 8 for an ipv6 cidr, must be an icmpv6 type 1-4 or 128-255"),
+	)
+
+	Context("when the store fails", func() {
+		BeforeEach(func() {
+			fakeEgressStore.CreateReturns(errors.New("banana"))
+		})
+
+		It("calls the internal server error handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(err).To(MatchError("banana"))
+			Expect(message).To(Equal("egress-policies-create"))
+			Expect(description).To(Equal("database create failed"))
+		})
+	})
+})
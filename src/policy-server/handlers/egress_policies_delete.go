@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	"code.cloudfoundry.org/cf-networking-helpers/marshal"
+	"code.cloudfoundry.org/lager"
+)
+
+type EgressPoliciesDelete struct {
+	EgressStore   egressStore
+	Unmarshaler   marshal.Unmarshaler
+	ErrorResponse errorResponse
+}
+
+func (h *EgressPoliciesDelete) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request, tokenData uaa_client.CheckTokenResponse) {
+	logger = logger.Session("delete-egress-policies")
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		logger.Error("failed-reading-request-body", err)
+		h.ErrorResponse.BadRequest(w, err, "delete-egress-policies", "invalid request body")
+		return
+	}
+
+	var payload struct {
+		EgressPolicies []models.EgressPolicy `json:"egress_policies"`
+	}
+	err = h.Unmarshaler.Unmarshal(bodyBytes, &payload)
+	if err != nil {
+		logger.Error("failed-unmarshalling-payload", err)
+		h.ErrorResponse.BadRequest(w, err, "delete-egress-policies", "invalid values passed to API")
+		return
+	}
+
+	err = validateEgressPolicies(payload.EgressPolicies)
+	if err != nil {
+		logger.Error("failed-validating-egress-policies", err)
+		h.ErrorResponse.BadRequest(w, err, "delete-egress-policies", err.Error())
+		return
+	}
+
+	err = h.EgressStore.Delete(payload.EgressPolicies)
+	if err != nil {
+		logger.Error("failed-deleting-in-database", err)
+		h.ErrorResponse.InternalServerError(w, err, "delete-egress-policies", "database delete failed")
+		return
+	}
+
+	logger.Info("deleted-egress-policies", lager.Data{"egress_policies": payload.EgressPolicies, "userName": tokenData.UserName})
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{}`))
+}
@@ -0,0 +1,110 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
+	"code.cloudfoundry.org/lager"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EgressPoliciesDelete", func() {
+	var (
+		requestJSON       string
+		request           *http.Request
+		handler           *handlers.EgressPoliciesDelete
+		resp              *httptest.ResponseRecorder
+		fakeEgressStore   *fakes.EgressStore
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		fakeUnmarshaler   *hfakes.Unmarshaler
+		tokenData         uaa_client.CheckTokenResponse
+	)
+
+	BeforeEach(func() {
+		requestJSON = `{"egress_policies": [
+			{ "source": { "id": "some-space-guid", "type": "space" }, "destination": { "protocol": "all", "cidr": "0.0.0.0/0" } }
+		]}`
+		var err error
+		request, err = http.NewRequest("POST", "/networking/v0/external/egress_policies/delete", bytes.NewBuffer([]byte(requestJSON)))
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeEgressStore = &fakes.EgressStore{}
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		fakeUnmarshaler = &hfakes.Unmarshaler{}
+		fakeUnmarshaler.UnmarshalStub = json.Unmarshal
+		logger = lagertest.NewTestLogger("test")
+		handler = &handlers.EgressPoliciesDelete{
+			EgressStore:   fakeEgressStore,
+			Unmarshaler:   fakeUnmarshaler,
+			ErrorResponse: fakeErrorResponse,
+		}
+		tokenData = uaa_client.CheckTokenResponse{
+			Scope:    []string{"network.admin"},
+			UserName: "some_user",
+		}
+		resp = httptest.NewRecorder()
+	})
+
+	It("removes the egress policies", func() {
+		handler.ServeHTTP(logger, resp, request, tokenData)
+
+		Expect(fakeEgressStore.DeleteCallCount()).To(Equal(1))
+		Expect(fakeEgressStore.DeleteArgsForCall(0)).To(Equal([]models.EgressPolicy{{
+			Source:      models.EgressSource{ID: "some-space-guid", Type: "space"},
+			Destination: models.EgressDestination{Protocol: "all", CIDR: "0.0.0.0/0"},
+		}}))
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON("{}"))
+
+		Expect(logger.Logs()).To(HaveLen(1))
+		Expect(logger.Logs()[0]).To(SatisfyAll(
+			LogsWith(lager.INFO, "test.delete-egress-policies.deleted-egress-policies"),
+			HaveLogData(HaveKeyWithValue("userName", "some_user")),
+		))
+	})
+
+	Context("when the egress policies are invalid", func() {
+		BeforeEach(func() {
+			request, _ = http.NewRequest("POST", "/networking/v0/external/egress_policies/delete", bytes.NewBuffer([]byte(`{"egress_policies": [{"source": {"type": "space"}}]}`)))
+		})
+
+		It("calls the bad request handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeEgressStore.DeleteCallCount()).To(Equal(0))
+			Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.BadRequestArgsForCall(0)
+			Expect(err).To(MatchError("missing source id"))
+			Expect(message).To(Equal("delete-egress-policies"))
+			Expect(description).To(Equal("missing source id"))
+		})
+	})
+
+	Context("when the store fails", func() {
+		BeforeEach(func() {
+			fakeEgressStore.DeleteReturns(errors.New("banana"))
+		})
+
+		It("calls the internal server error handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(err).To(MatchError("banana"))
+			Expect(message).To(Equal("delete-egress-policies"))
+			Expect(description).To(Equal("database delete failed"))
+		})
+	})
+})
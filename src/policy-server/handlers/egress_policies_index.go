@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	"code.cloudfoundry.org/cf-networking-helpers/marshal"
+	"code.cloudfoundry.org/lager"
+)
+
+type EgressPoliciesIndex struct {
+	EgressStore   egressStore
+	Marshaler     marshal.Marshaler
+	ErrorResponse errorResponse
+}
+
+func (h *EgressPoliciesIndex) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request, _ uaa_client.CheckTokenResponse) {
+	logger = logger.Session("index-egress-policies")
+	policies, err := h.EgressStore.All()
+	if err != nil {
+		logger.Error("failed-reading-database", err)
+		h.ErrorResponse.InternalServerError(w, err, "egress-policies-index", "database read failed")
+		return
+	}
+
+	policiesResponse := struct {
+		EgressPolicies []models.EgressPolicy `json:"egress_policies"`
+	}{policies}
+	responseBytes, err := h.Marshaler.Marshal(policiesResponse)
+	if err != nil {
+		logger.Error("failed-marshalling-egress-policies", err)
+		h.ErrorResponse.InternalServerError(w, err, "egress-policies-index", "database marshalling failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseBytes)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"policy-server/models"
+
+	"code.cloudfoundry.org/cf-networking-helpers/marshal"
+	"code.cloudfoundry.org/lager"
+)
+
+type EgressPoliciesIndexInternal struct {
+	EgressStore   egressStore
+	Marshaler     marshal.Marshaler
+	ErrorResponse errorResponse
+}
+
+// ServeHTTP returns the egress policies whose source is one of the app or
+// space guids given in the id query parameter, or every egress policy when
+// there is none.
+func (h *EgressPoliciesIndexInternal) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request) {
+	logger = logger.Session("index-egress-policies-internal")
+
+	ids := parseIds(req.URL.Query())
+
+	var policies []models.EgressPolicy
+	var err error
+	if len(ids) == 0 {
+		policies, err = h.EgressStore.All()
+	} else {
+		policies, err = h.EgressStore.BySourceGuids(ids)
+	}
+	if err != nil {
+		logger.Error("failed-reading-database", err)
+		h.ErrorResponse.InternalServerError(w, err, "egress-policies-index-internal", "database read failed")
+		return
+	}
+
+	policiesResponse := struct {
+		EgressPolicies []models.EgressPolicy `json:"egress_policies"`
+	}{policies}
+	bytes, err := h.Marshaler.Marshal(policiesResponse)
+	if err != nil {
+		logger.Error("failed-marshalling-egress-policies", err)
+		h.ErrorResponse.InternalServerError(w, err, "egress-policies-index-internal", "database marshalling failed")
+		return
+	}
+
+	w.Write(bytes)
+}
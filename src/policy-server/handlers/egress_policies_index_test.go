@@ -0,0 +1,137 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Egress policies index handlers", func() {
+	var (
+		resp              *httptest.ResponseRecorder
+		fakeEgressStore   *fakes.EgressStore
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		marshaler         *hfakes.Marshaler
+	)
+
+	BeforeEach(func() {
+		marshaler = &hfakes.Marshaler{}
+		marshaler.MarshalStub = json.Marshal
+
+		fakeEgressStore = &fakes.EgressStore{}
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		policies := []models.EgressPolicy{{
+			Source:      models.EgressSource{ID: "some-app-guid", Type: "app"},
+			Destination: models.EgressDestination{Protocol: "tcp", CIDR: "10.0.0.0/8", Ports: models.Ports{Start: 443, End: 443}},
+		}}
+		fakeEgressStore.AllReturns(policies, nil)
+		fakeEgressStore.BySourceGuidsReturns(policies, nil)
+		logger = lagertest.NewTestLogger("test")
+		resp = httptest.NewRecorder()
+	})
+
+	expectedResponseJSON := `{"egress_policies": [
+		{ "source": { "id": "some-app-guid", "type": "app" }, "destination": { "protocol": "tcp", "cidr": "10.0.0.0/8", "ports": { "start": 443, "end": 443 } } }
+	]}`
+
+	Describe("EgressPoliciesIndex", func() {
+		var handler *handlers.EgressPoliciesIndex
+
+		BeforeEach(func() {
+			handler = &handlers.EgressPoliciesIndex{
+				EgressStore:   fakeEgressStore,
+				Marshaler:     marshaler,
+				ErrorResponse: fakeErrorResponse,
+			}
+		})
+
+		It("returns all the egress policies", func() {
+			request, err := http.NewRequest("GET", "/networking/v0/external/egress_policies", nil)
+			Expect(err).NotTo(HaveOccurred())
+			handler.ServeHTTP(logger, resp, request, uaa_client.CheckTokenResponse{})
+
+			Expect(fakeEgressStore.AllCallCount()).To(Equal(1))
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(MatchJSON(expectedResponseJSON))
+		})
+
+		Context("when the store throws an error", func() {
+			BeforeEach(func() {
+				fakeEgressStore.AllReturns(nil, errors.New("banana"))
+			})
+
+			It("calls the internal server error handler", func() {
+				request, _ := http.NewRequest("GET", "/networking/v0/external/egress_policies", nil)
+				handler.ServeHTTP(logger, resp, request, uaa_client.CheckTokenResponse{})
+
+				Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+				_, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+				Expect(err).To(MatchError("banana"))
+				Expect(message).To(Equal("egress-policies-index"))
+				Expect(description).To(Equal("database read failed"))
+			})
+		})
+	})
+
+	Describe("EgressPoliciesIndexInternal", func() {
+		var handler *handlers.EgressPoliciesIndexInternal
+
+		BeforeEach(func() {
+			handler = &handlers.EgressPoliciesIndexInternal{
+				EgressStore:   fakeEgressStore,
+				Marshaler:     marshaler,
+				ErrorResponse: fakeErrorResponse,
+			}
+		})
+
+		It("returns the egress policies of the requested apps and spaces", func() {
+			request, err := http.NewRequest("GET", "/networking/v0/internal/egress_policies?id=some-app-guid,some-space-guid", nil)
+			Expect(err).NotTo(HaveOccurred())
+			handler.ServeHTTP(logger, resp, request)
+
+			Expect(fakeEgressStore.BySourceGuidsCallCount()).To(Equal(1))
+			Expect(fakeEgressStore.BySourceGuidsArgsForCall(0)).To(Equal([]string{"some-app-guid", "some-space-guid"}))
+			Expect(fakeEgressStore.AllCallCount()).To(Equal(0))
+			Expect(resp.Body).To(MatchJSON(expectedResponseJSON))
+		})
+
+		It("returns all the egress policies when no ids are given", func() {
+			request, err := http.NewRequest("GET", "/networking/v0/internal/egress_policies", nil)
+			Expect(err).NotTo(HaveOccurred())
+			handler.ServeHTTP(logger, resp, request)
+
+			Expect(fakeEgressStore.AllCallCount()).To(Equal(1))
+			Expect(fakeEgressStore.BySourceGuidsCallCount()).To(Equal(0))
+			Expect(resp.Body).To(MatchJSON(expectedResponseJSON))
+		})
+
+		Context("when the store throws an error", func() {
+			BeforeEach(func() {
+				fakeEgressStore.BySourceGuidsReturns(nil, errors.New("banana"))
+			})
+
+			It("calls the internal server error handler", func() {
+				request, _ := http.NewRequest("GET", "/networking/v0/internal/egress_policies?id=some-app-guid", nil)
+				handler.ServeHTTP(logger, resp, request)
+
+				Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+				_, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+				Expect(err).To(MatchError("banana"))
+				Expect(message).To(Equal("egress-policies-index-internal"))
+				Expect(description).To(Equal("database read failed"))
+			})
+		})
+	})
+})
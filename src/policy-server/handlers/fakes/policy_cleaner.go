@@ -7,60 +7,60 @@ import (
 )
 
 type PolicyCleaner struct {
-	DeleteStalePoliciesStub        func() ([]models.Policy, error)
-	deleteStalePoliciesMutex       sync.RWMutex
-	deleteStalePoliciesArgsForCall []struct{}
-	deleteStalePoliciesReturns     struct {
-		result1 []models.Policy
+	DeleteStalePoliciesOnDemandStub        func() (models.CleanupReport, error)
+	deleteStalePoliciesOnDemandMutex       sync.RWMutex
+	deleteStalePoliciesOnDemandArgsForCall []struct{}
+	deleteStalePoliciesOnDemandReturns     struct {
+		result1 models.CleanupReport
 		result2 error
 	}
-	deleteStalePoliciesReturnsOnCall map[int]struct {
-		result1 []models.Policy
+	deleteStalePoliciesOnDemandReturnsOnCall map[int]struct {
+		result1 models.CleanupReport
 		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *PolicyCleaner) DeleteStalePolicies() ([]models.Policy, error) {
-	fake.deleteStalePoliciesMutex.Lock()
-	ret, specificReturn := fake.deleteStalePoliciesReturnsOnCall[len(fake.deleteStalePoliciesArgsForCall)]
-	fake.deleteStalePoliciesArgsForCall = append(fake.deleteStalePoliciesArgsForCall, struct{}{})
-	fake.recordInvocation("DeleteStalePolicies", []interface{}{})
-	fake.deleteStalePoliciesMutex.Unlock()
-	if fake.DeleteStalePoliciesStub != nil {
-		return fake.DeleteStalePoliciesStub()
+func (fake *PolicyCleaner) DeleteStalePoliciesOnDemand() (models.CleanupReport, error) {
+	fake.deleteStalePoliciesOnDemandMutex.Lock()
+	ret, specificReturn := fake.deleteStalePoliciesOnDemandReturnsOnCall[len(fake.deleteStalePoliciesOnDemandArgsForCall)]
+	fake.deleteStalePoliciesOnDemandArgsForCall = append(fake.deleteStalePoliciesOnDemandArgsForCall, struct{}{})
+	fake.recordInvocation("DeleteStalePoliciesOnDemand", []interface{}{})
+	fake.deleteStalePoliciesOnDemandMutex.Unlock()
+	if fake.DeleteStalePoliciesOnDemandStub != nil {
+		return fake.DeleteStalePoliciesOnDemandStub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.deleteStalePoliciesReturns.result1, fake.deleteStalePoliciesReturns.result2
+	return fake.deleteStalePoliciesOnDemandReturns.result1, fake.deleteStalePoliciesOnDemandReturns.result2
 }
 
-func (fake *PolicyCleaner) DeleteStalePoliciesCallCount() int {
-	fake.deleteStalePoliciesMutex.RLock()
-	defer fake.deleteStalePoliciesMutex.RUnlock()
-	return len(fake.deleteStalePoliciesArgsForCall)
+func (fake *PolicyCleaner) DeleteStalePoliciesOnDemandCallCount() int {
+	fake.deleteStalePoliciesOnDemandMutex.RLock()
+	defer fake.deleteStalePoliciesOnDemandMutex.RUnlock()
+	return len(fake.deleteStalePoliciesOnDemandArgsForCall)
 }
 
-func (fake *PolicyCleaner) DeleteStalePoliciesReturns(result1 []models.Policy, result2 error) {
-	fake.DeleteStalePoliciesStub = nil
-	fake.deleteStalePoliciesReturns = struct {
-		result1 []models.Policy
+func (fake *PolicyCleaner) DeleteStalePoliciesOnDemandReturns(result1 models.CleanupReport, result2 error) {
+	fake.DeleteStalePoliciesOnDemandStub = nil
+	fake.deleteStalePoliciesOnDemandReturns = struct {
+		result1 models.CleanupReport
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *PolicyCleaner) DeleteStalePoliciesReturnsOnCall(i int, result1 []models.Policy, result2 error) {
-	fake.DeleteStalePoliciesStub = nil
-	if fake.deleteStalePoliciesReturnsOnCall == nil {
-		fake.deleteStalePoliciesReturnsOnCall = make(map[int]struct {
-			result1 []models.Policy
+func (fake *PolicyCleaner) DeleteStalePoliciesOnDemandReturnsOnCall(i int, result1 models.CleanupReport, result2 error) {
+	fake.DeleteStalePoliciesOnDemandStub = nil
+	if fake.deleteStalePoliciesOnDemandReturnsOnCall == nil {
+		fake.deleteStalePoliciesOnDemandReturnsOnCall = make(map[int]struct {
+			result1 models.CleanupReport
 			result2 error
 		})
 	}
-	fake.deleteStalePoliciesReturnsOnCall[i] = struct {
-		result1 []models.Policy
+	fake.deleteStalePoliciesOnDemandReturnsOnCall[i] = struct {
+		result1 models.CleanupReport
 		result2 error
 	}{result1, result2}
 }
@@ -68,8 +68,8 @@ func (fake *PolicyCleaner) DeleteStalePoliciesReturnsOnCall(i int, result1 []mod
 func (fake *PolicyCleaner) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.deleteStalePoliciesMutex.RLock()
-	defer fake.deleteStalePoliciesMutex.RUnlock()
+	fake.deleteStalePoliciesOnDemandMutex.RLock()
+	defer fake.deleteStalePoliciesOnDemandMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
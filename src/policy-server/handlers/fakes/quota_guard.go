@@ -2,31 +2,32 @@
 package fakes
 
 import (
+	"policy-server/handlers"
 	"policy-server/models"
 	"policy-server/uaa_client"
 	"sync"
 )
 
 type QuotaGuard struct {
-	CheckAccessStub        func(policies []models.Policy, tokenData uaa_client.CheckTokenResponse) (bool, error)
+	CheckAccessStub        func(policies []models.Policy, tokenData uaa_client.CheckTokenResponse) (*handlers.QuotaViolation, error)
 	checkAccessMutex       sync.RWMutex
 	checkAccessArgsForCall []struct {
 		policies  []models.Policy
 		tokenData uaa_client.CheckTokenResponse
 	}
 	checkAccessReturns struct {
-		result1 bool
+		result1 *handlers.QuotaViolation
 		result2 error
 	}
 	checkAccessReturnsOnCall map[int]struct {
-		result1 bool
+		result1 *handlers.QuotaViolation
 		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *QuotaGuard) CheckAccess(policies []models.Policy, tokenData uaa_client.CheckTokenResponse) (bool, error) {
+func (fake *QuotaGuard) CheckAccess(policies []models.Policy, tokenData uaa_client.CheckTokenResponse) (*handlers.QuotaViolation, error) {
 	var policiesCopy []models.Policy
 	if policies != nil {
 		policiesCopy = make([]models.Policy, len(policies))
@@ -61,24 +62,24 @@ func (fake *QuotaGuard) CheckAccessArgsForCall(i int) ([]models.Policy, uaa_clie
 	return fake.checkAccessArgsForCall[i].policies, fake.checkAccessArgsForCall[i].tokenData
 }
 
-func (fake *QuotaGuard) CheckAccessReturns(result1 bool, result2 error) {
+func (fake *QuotaGuard) CheckAccessReturns(result1 *handlers.QuotaViolation, result2 error) {
 	fake.CheckAccessStub = nil
 	fake.checkAccessReturns = struct {
-		result1 bool
+		result1 *handlers.QuotaViolation
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *QuotaGuard) CheckAccessReturnsOnCall(i int, result1 bool, result2 error) {
+func (fake *QuotaGuard) CheckAccessReturnsOnCall(i int, result1 *handlers.QuotaViolation, result2 error) {
 	fake.CheckAccessStub = nil
 	if fake.checkAccessReturnsOnCall == nil {
 		fake.checkAccessReturnsOnCall = make(map[int]struct {
-			result1 bool
+			result1 *handlers.QuotaViolation
 			result2 error
 		})
 	}
 	fake.checkAccessReturnsOnCall[i] = struct {
-		result1 bool
+		result1 *handlers.QuotaViolation
 		result2 error
 	}{result1, result2}
 }
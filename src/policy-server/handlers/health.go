@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 
 	"code.cloudfoundry.org/lager"
 )
 
+//go:generate counterfeiter -o fakes/leadership.go --fake-name Leadership . leadership
+type leadership interface {
+	IsLeader() bool
+}
+
 type Health struct {
 	Store         store
+	Leadership    leadership
 	ErrorResponse errorResponse
 }
 
@@ -19,4 +26,9 @@ func (h *Health) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http
 		h.ErrorResponse.InternalServerError(w, err, "health", "check database failed")
 		return
 	}
+
+	if h.Leadership != nil {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(fmt.Sprintf(`{"leader": %t}`, h.Leadership.IsLeader())))
+	}
 }
@@ -45,6 +45,29 @@ var _ = Describe("Health handler", func() {
 		handler.ServeHTTP(logger, resp, request)
 		Expect(fakeStore.CheckDatabaseCallCount()).To(Equal(1))
 		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(BeEmpty())
+	})
+
+	Context("when leadership is configured", func() {
+		var fakeLeadership *fakes.Leadership
+
+		BeforeEach(func() {
+			fakeLeadership = &fakes.Leadership{}
+			handler.Leadership = fakeLeadership
+		})
+
+		It("reports whether this instance is the leader", func() {
+			fakeLeadership.IsLeaderReturns(true)
+			handler.ServeHTTP(logger, resp, request)
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(MatchJSON(`{"leader": true}`))
+		})
+
+		It("reports when this instance is not the leader", func() {
+			handler.ServeHTTP(logger, resp, request)
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body.String()).To(MatchJSON(`{"leader": false}`))
+		})
 	})
 
 	Context("when the database returns an error", func() {
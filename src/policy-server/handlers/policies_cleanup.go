@@ -11,7 +11,7 @@ import (
 
 //go:generate counterfeiter -o fakes/policy_cleaner.go --fake-name PolicyCleaner . policyCleaner
 type policyCleaner interface {
-	DeleteStalePolicies() ([]models.Policy, error)
+	DeleteStalePoliciesOnDemand() (models.CleanupReport, error)
 }
 
 //go:generate counterfeiter -o fakes/error_response.go --fake-name ErrorResponse . errorResponse
@@ -22,15 +22,26 @@ type errorResponse interface {
 	Unauthorized(http.ResponseWriter, error, string, string)
 }
 
+// PoliciesCleanup triggers a cleanup on the leader, which keeps track of the
+// missing apps. The other instances answer 503 so that the request can be
+// retried against the leader.
 type PoliciesCleanup struct {
 	Marshaler     marshal.Marshaler
 	PolicyCleaner policyCleaner
+	Leadership    leadership
 	ErrorResponse errorResponse
 }
 
 func (h *PoliciesCleanup) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request, tokenData uaa_client.CheckTokenResponse) {
 	logger = logger.Session("cleanup-policies")
-	policies, err := h.PolicyCleaner.DeleteStalePolicies()
+	if h.Leadership != nil && !h.Leadership.IsLeader() {
+		logger.Info("not-leader")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error": "policies cleanup only runs on the leader, retry against another instance"}`))
+		return
+	}
+
+	report, err := h.PolicyCleaner.DeleteStalePoliciesOnDemand()
 	if err != nil {
 		logger.Error("failed-deleting-stale-policies", err)
 		h.ErrorResponse.InternalServerError(w, err, "policies-cleanup", "policies cleanup failed")
@@ -38,9 +49,12 @@ func (h *PoliciesCleanup) ServeHTTP(logger lager.Logger, w http.ResponseWriter,
 	}
 
 	policyCleanup := struct {
-		TotalPolicies int             `json:"total_policies"`
-		Policies      []models.Policy `json:"policies"`
-	}{len(policies), policies}
+		TotalPolicies int               `json:"total_policies"`
+		Policies      []models.Policy   `json:"policies"`
+		DryRun        bool              `json:"dry_run"`
+		StaleApps     []models.StaleApp `json:"stale_apps"`
+		PendingApps   []models.StaleApp `json:"pending_apps"`
+	}{len(report.Policies), report.Policies, report.DryRun, report.StaleApps, report.PendingApps}
 	for i, _ := range policyCleanup.Policies {
 		policyCleanup.Policies[i].Source.Tag = ""
 		policyCleanup.Policies[i].Destination.Tag = ""
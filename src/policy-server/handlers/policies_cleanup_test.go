@@ -9,6 +9,7 @@ import (
 	"policy-server/handlers/fakes"
 	"policy-server/models"
 	"policy-server/uaa_client"
+	"time"
 
 	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
 	"code.cloudfoundry.org/lager"
@@ -27,7 +28,9 @@ var _ = Describe("PoliciesCleanup", func() {
 		fakePolicyCleaner *fakes.PolicyCleaner
 		fakeMarshaler     *hfakes.Marshaler
 		fakeErrorResponse *fakes.ErrorResponse
+		fakeLeadership    *fakes.Leadership
 		policies          []models.Policy
+		report            models.CleanupReport
 		tokenData         uaa_client.CheckTokenResponse
 	)
 
@@ -48,10 +51,13 @@ var _ = Describe("PoliciesCleanup", func() {
 		fakeMarshaler.MarshalStub = json.Marshal
 		fakePolicyCleaner = &fakes.PolicyCleaner{}
 		fakeErrorResponse = &fakes.ErrorResponse{}
+		fakeLeadership = &fakes.Leadership{}
+		fakeLeadership.IsLeaderReturns(true)
 
 		handler = &handlers.PoliciesCleanup{
 			Marshaler:     fakeMarshaler,
 			PolicyCleaner: fakePolicyCleaner,
+			Leadership:    fakeLeadership,
 			ErrorResponse: fakeErrorResponse,
 		}
 
@@ -60,7 +66,17 @@ var _ = Describe("PoliciesCleanup", func() {
 			UserName: "some_user",
 		}
 
-		fakePolicyCleaner.DeleteStalePoliciesReturns(policies, nil)
+		report = models.CleanupReport{
+			Policies: policies,
+			StaleApps: []models.StaleApp{{
+				ID:           "dead-guid",
+				MissingRuns:  2,
+				MissingSince: time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC),
+				Reason:       "app not found in Cloud-Controller on 2 consecutive runs since 2017-06-01T12:00:00Z",
+			}},
+			PendingApps: []models.StaleApp{},
+		}
+		fakePolicyCleaner.DeleteStalePoliciesOnDemandReturns(report, nil)
 		resp = httptest.NewRecorder()
 		request, _ = http.NewRequest("POST", "/networking/v0/external/policies/cleanup", nil)
 	})
@@ -68,7 +84,7 @@ var _ = Describe("PoliciesCleanup", func() {
 	It("Cleans up stale policies for deleted apps", func() {
 		handler.ServeHTTP(logger, resp, request, tokenData)
 
-		Expect(fakePolicyCleaner.DeleteStalePoliciesCallCount()).To(Equal(1))
+		Expect(fakePolicyCleaner.DeleteStalePoliciesOnDemandCallCount()).To(Equal(1))
 		Expect(fakeMarshaler.MarshalCallCount()).To(Equal(1))
 
 		for i, _ := range policies {
@@ -76,9 +92,12 @@ var _ = Describe("PoliciesCleanup", func() {
 			policies[i].Destination.Tag = ""
 		}
 		deletedPolicies := struct {
-			TotalPolicies int             `json:"total_policies"`
-			Policies      []models.Policy `json:"policies"`
-		}{1, policies}
+			TotalPolicies int               `json:"total_policies"`
+			Policies      []models.Policy   `json:"policies"`
+			DryRun        bool              `json:"dry_run"`
+			StaleApps     []models.StaleApp `json:"stale_apps"`
+			PendingApps   []models.StaleApp `json:"pending_apps"`
+		}{1, policies, false, report.StaleApps, report.PendingApps}
 
 		Expect(fakeMarshaler.MarshalArgsForCall(0)).To(Equal(deletedPolicies))
 
@@ -100,14 +119,62 @@ var _ = Describe("PoliciesCleanup", func() {
 					}
 				}
 			}
-			]
+			],
+			"dry_run": false,
+			"stale_apps": [
+				{
+					"id": "dead-guid",
+					"missing_runs": 2,
+					"missing_since": "2017-06-01T12:00:00Z",
+					"reason": "app not found in Cloud-Controller on 2 consecutive runs since 2017-06-01T12:00:00Z"
+				}
+			],
+			"pending_apps": []
 		}
 			`))
 	})
 
+	Context("when the instance is not the leader", func() {
+		BeforeEach(func() {
+			fakeLeadership.IsLeaderReturns(false)
+		})
+
+		It("responds with 503 without cleaning up", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakePolicyCleaner.DeleteStalePoliciesOnDemandCallCount()).To(Equal(0))
+			Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(resp.Body.String()).To(MatchJSON(`{"error": "policies cleanup only runs on the leader, retry against another instance"}`))
+		})
+	})
+
+	Context("when the cleaner is in dry run mode", func() {
+		BeforeEach(func() {
+			report.DryRun = true
+			report.PendingApps = []models.StaleApp{{
+				ID:          "missing-guid",
+				MissingRuns: 1,
+				Reason:      "app not found in Cloud-Controller on 1 consecutive runs since 2017-06-01T12:00:00Z, within grace period",
+			}}
+			fakePolicyCleaner.DeleteStalePoliciesOnDemandReturns(report, nil)
+		})
+
+		It("reports what would be removed and why", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			var body map[string]interface{}
+			Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+			Expect(body).To(HaveKeyWithValue("dry_run", true))
+			Expect(body).To(HaveKeyWithValue("total_policies", BeNumerically("==", 1)))
+			Expect(body["stale_apps"]).To(HaveLen(1))
+			Expect(body["pending_apps"]).To(ConsistOf(HaveKeyWithValue("id", "missing-guid")))
+		})
+	})
+
 	Context("When deleting the policies fails", func() {
 		BeforeEach(func() {
-			fakePolicyCleaner.DeleteStalePoliciesReturns(nil, errors.New("potato"))
+			fakePolicyCleaner.DeleteStalePoliciesOnDemandReturns(models.CleanupReport{}, errors.New("potato"))
 		})
 
 		It("calls the internal server error handler", func() {
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"policy-server/models"
@@ -18,7 +19,7 @@ type policyGuard interface {
 
 //go:generate counterfeiter -o fakes/quota_guard.go --fake-name QuotaGuard . quotaGuard
 type quotaGuard interface {
-	CheckAccess(policies []models.Policy, tokenData uaa_client.CheckTokenResponse) (bool, error)
+	CheckAccess(policies []models.Policy, tokenData uaa_client.CheckTokenResponse) (*QuotaViolation, error)
 }
 
 type PoliciesCreate struct {
@@ -69,14 +70,14 @@ func (h *PoliciesCreate) ServeHTTP(logger lager.Logger, w http.ResponseWriter, r
 		return
 	}
 
-	authorized, err = h.QuotaGuard.CheckAccess(payload.Policies, tokenData)
+	violation, err := h.QuotaGuard.CheckAccess(payload.Policies, tokenData)
 	if err != nil {
 		logger.Error("failed-checking-quota", err)
 		h.ErrorResponse.InternalServerError(w, err, "policies-create", "check quota failed")
 		return
 	}
-	if !authorized {
-		err := errors.New("policy quota exceeded")
+	if violation != nil {
+		err := fmt.Errorf("policy quota exceeded: %s", violation)
 		logger.Error("quota-exceeded", err)
 		h.ErrorResponse.Forbidden(w, err, "policies-create", err.Error())
 		return
@@ -91,7 +91,7 @@ var _ = Describe("PoliciesCreate", func() {
 			UserName: "some_user",
 		}
 		fakePolicyGuard.CheckAccessReturns(true, nil)
-		fakeQuotaGuard.CheckAccessReturns(true, nil)
+		fakeQuotaGuard.CheckAccessReturns(nil, nil)
 		resp = httptest.NewRecorder()
 	})
 	It("persists a new policy rule", func() {
@@ -214,9 +214,15 @@ var _ = Describe("PoliciesCreate", func() {
 		})
 	})
 
-	Context("when the quota guard returns false", func() {
+	Context("when the quota guard returns a violation", func() {
 		BeforeEach(func() {
-			fakeQuotaGuard.CheckAccessReturns(false, nil)
+			fakeQuotaGuard.CheckAccessReturns(&handlers.QuotaViolation{
+				AppGUID: "some-app-guid",
+				Quota:   "max_policies",
+				Scope:   "space",
+				GUID:    "some-space-guid",
+				Limit:   2,
+			}, nil)
 		})
 
 		It("calls the forbidden handler", func() {
@@ -226,9 +232,9 @@ var _ = Describe("PoliciesCreate", func() {
 
 			w, err, message, description := fakeErrorResponse.ForbiddenArgsForCall(0)
 			Expect(w).To(Equal(resp))
-			Expect(err).To(MatchError("policy quota exceeded"))
+			Expect(err).To(MatchError("policy quota exceeded: app some-app-guid exceeds max_policies of 2 for space some-space-guid"))
 			Expect(message).To(Equal("policies-create"))
-			Expect(description).To(Equal("policy quota exceeded"))
+			Expect(description).To(Equal("policy quota exceeded: app some-app-guid exceeds max_policies of 2 for space some-space-guid"))
 
 			By("logging the error")
 			Expect(logger.Logs()).To(HaveLen(1))
@@ -236,7 +242,7 @@ var _ = Describe("PoliciesCreate", func() {
 				LogsWith(lager.ERROR, "test.create-policies.quota-exceeded"),
 				HaveLogData(SatisfyAll(
 					HaveLen(2),
-					HaveKeyWithValue("error", "policy quota exceeded"),
+					HaveKeyWithValue("error", "policy quota exceeded: app some-app-guid exceeds max_policies of 2 for space some-space-guid"),
 					HaveKeyWithValue("session", "1"),
 				)),
 			))
@@ -303,7 +309,7 @@ var _ = Describe("PoliciesCreate", func() {
 
 	Context("when the quota guard returns an error", func() {
 		BeforeEach(func() {
-			fakeQuotaGuard.CheckAccessReturns(false, errors.New("banana"))
+			fakeQuotaGuard.CheckAccessReturns(nil, errors.New("banana"))
 		})
 
 		It("calls the internal server error handler", func() {
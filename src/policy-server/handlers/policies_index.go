@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
 	"policy-server/models"
 	"policy-server/uaa_client"
+	"strings"
 
 	"code.cloudfoundry.org/cf-networking-helpers/marshal"
 	"code.cloudfoundry.org/lager"
@@ -25,9 +28,14 @@ func (h *PoliciesIndex) ServeHTTP(logger lager.Logger, w http.ResponseWriter, re
 	logger = logger.Session("index-policies")
 	queryValues := req.URL.Query()
 	ids := parseIds(queryValues)
+	selectors, err := parseLabelSelectors(queryValues)
+	if err != nil {
+		logger.Error("failed-parsing-label-selectors", err)
+		h.ErrorResponse.BadRequest(w, err, "policies-index", err.Error())
+		return
+	}
 
 	var policies []models.Policy
-	var err error
 	if len(ids) == 0 {
 		policies, err = h.Store.All()
 	} else {
@@ -40,6 +48,8 @@ func (h *PoliciesIndex) ServeHTTP(logger lager.Logger, w http.ResponseWriter, re
 		return
 	}
 
+	policies = filterByLabels(policies, selectors)
+
 	policies, err = h.PolicyFilter.FilterPolicies(policies, userToken)
 	if err != nil {
 		logger.Error("failed-filtering-policies", err)
@@ -66,3 +76,51 @@ func (h *PoliciesIndex) ServeHTTP(logger lager.Logger, w http.ResponseWriter, re
 	w.WriteHeader(http.StatusOK)
 	w.Write(bytes)
 }
+
+type labelSelector struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// parseLabelSelectors reads every label query parameter, each either
+// key=value to match a label value or key to match any policy with the label.
+func parseLabelSelectors(queryValues url.Values) ([]labelSelector, error) {
+	var selectors []labelSelector
+	for _, raw := range queryValues["label"] {
+		parts := strings.SplitN(raw, "=", 2)
+		if parts[0] == "" {
+			return nil, fmt.Errorf("invalid label selector %q", raw)
+		}
+		selector := labelSelector{Key: parts[0]}
+		if len(parts) == 2 {
+			selector.Value = parts[1]
+			selector.HasValue = true
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+func filterByLabels(policies []models.Policy, selectors []labelSelector) []models.Policy {
+	if len(selectors) == 0 {
+		return policies
+	}
+	filtered := []models.Policy{}
+	for _, policy := range policies {
+		if matchesLabels(policy, selectors) {
+			filtered = append(filtered, policy)
+		}
+	}
+	return filtered
+}
+
+func matchesLabels(policy models.Policy, selectors []labelSelector) bool {
+	for _, selector := range selectors {
+		value, ok := policy.Labels[selector.Key]
+		if !ok || (selector.HasValue && value != selector.Value) {
+			return false
+		}
+	}
+	return true
+}
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"policy-server/models"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/cf-networking-helpers/marshal"
 	"code.cloudfoundry.org/lager"
@@ -47,6 +48,21 @@ func (h *PoliciesIndexInternal) ServeHTTP(logger lager.Logger, w http.ResponseWr
 		return
 	}
 
+	// agents only need the connectivity of policies that are still in effect,
+	// not their metadata
+	now := time.Now()
+	activePolicies := []models.Policy{}
+	for _, policy := range policies {
+		if policy.Expired(now) {
+			continue
+		}
+		policy.Description = ""
+		policy.Labels = nil
+		policy.ExpiresAt = nil
+		activePolicies = append(activePolicies, policy)
+	}
+	policies = activePolicies
+
 	policyResponse := struct {
 		Policies []models.Policy `json:"policies"`
 	}{policies}
@@ -8,6 +8,7 @@ import (
 	"policy-server/handlers"
 	"policy-server/handlers/fakes"
 	"policy-server/models"
+	"time"
 
 	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
 	"code.cloudfoundry.org/lager"
@@ -144,6 +145,54 @@ var _ = Describe("PoliciesIndexInternal", func() {
 		})
 	})
 
+	Context("when the policies have a description, labels and an expiry", func() {
+		var past, future time.Time
+
+		BeforeEach(func() {
+			past = time.Now().Add(-time.Hour)
+			future = time.Now().Add(time.Hour)
+
+			fakeStore.AllReturns([]models.Policy{{
+				Source: models.Source{ID: "some-app-guid"},
+				Destination: models.Destination{
+					ID:       "some-other-app-guid",
+					Protocol: "tcp",
+					Port:     8080,
+				},
+				Description: "some description",
+				Labels:      map[string]string{"team": "some-team"},
+				ExpiresAt:   &future,
+			}, {
+				Source: models.Source{ID: "another-app-guid"},
+				Destination: models.Destination{
+					ID:       "some-other-app-guid",
+					Protocol: "tcp",
+					Port:     1234,
+				},
+				ExpiresAt: &past,
+			}}, nil)
+		})
+
+		It("omits them and the expired policies from the response", func() {
+			request, err := http.NewRequest("GET", "/networking/v0/internal/policies", nil)
+			Expect(err).NotTo(HaveOccurred())
+			handler.ServeHTTP(logger, resp, request)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(MatchJSON(`{"policies": [
+				{
+					"source": { "id": "some-app-guid" },
+					"destination": {
+						"id": "some-other-app-guid",
+						"protocol": "tcp",
+						"port": 8080,
+						"ports": { "start": 8080, "end": 8080 }
+					}
+				}
+			]}`))
+		})
+	})
+
 	Context("when the store throws an error", func() {
 		var request *http.Request
 
@@ -9,6 +9,7 @@ import (
 	"policy-server/handlers/fakes"
 	"policy-server/models"
 	"policy-server/uaa_client"
+	"time"
 
 	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
 	"code.cloudfoundry.org/lager"
@@ -186,6 +187,100 @@ var _ = Describe("Policies index handler", func() {
 		})
 	})
 
+	Context("when a policy has an expiry", func() {
+		BeforeEach(func() {
+			expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+			filteredPolicies[0].ExpiresAt = &expiresAt
+		})
+
+		It("includes it in the response", func() {
+			handler.ServeHTTP(logger, resp, request, token)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(MatchJSON(`{
+				"total_policies": 1,
+				"policies": [
+				{
+					"source": { "id": "some-app-guid" },
+					"destination": {
+						"id": "some-other-app-guid",
+						"protocol": "tcp",
+						"port": 8080,
+						"ports": { "start": 8080, "end": 8080 }
+					},
+					"expires_at": "2030-01-02T03:04:05Z"
+				}
+			]}`))
+		})
+	})
+
+	Context("when label selectors are provided as query parameters", func() {
+		BeforeEach(func() {
+			allPolicies[0].Description = "some description"
+			allPolicies[0].Labels = map[string]string{"team": "payments", "env": "prod"}
+			allPolicies[1].Labels = map[string]string{"team": "payments", "env": "dev"}
+			allPolicies[2].Labels = map[string]string{"team": "search"}
+			fakePolicyFilter.FilterPoliciesStub = func(policies []models.Policy, userToken uaa_client.CheckTokenResponse) ([]models.Policy, error) {
+				return policies, nil
+			}
+		})
+
+		It("only returns policies with all of the matching labels", func() {
+			var err error
+			request, err = http.NewRequest("GET", "/networking/v0/external/policies?label=team=payments&label=env=prod", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			handler.ServeHTTP(logger, resp, request, token)
+
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(resp.Body).To(MatchJSON(`{
+				"total_policies": 1,
+				"policies": [
+				{
+					"source": { "id": "some-app-guid" },
+					"destination": {
+						"id": "some-other-app-guid",
+						"protocol": "tcp",
+						"port": 8080,
+						"ports": { "start": 8080, "end": 8080 }
+					},
+					"description": "some description",
+					"labels": { "team": "payments", "env": "prod" }
+				}
+			]}`))
+		})
+
+		It("matches on the presence of a label when no value is given", func() {
+			var err error
+			request, err = http.NewRequest("GET", "/networking/v0/external/policies?label=env", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			handler.ServeHTTP(logger, resp, request, token)
+
+			policies, _ := fakePolicyFilter.FilterPoliciesArgsForCall(0)
+			Expect(policies).To(HaveLen(2))
+			Expect(policies[0].Source.ID).To(Equal("some-app-guid"))
+			Expect(policies[1].Source.ID).To(Equal("another-app-guid"))
+		})
+
+		Context("when a label selector is invalid", func() {
+			It("calls the bad request handler", func() {
+				var err error
+				request, err = http.NewRequest("GET", "/networking/v0/external/policies?label==payments", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				handler.ServeHTTP(logger, resp, request, token)
+
+				Expect(fakeStore.AllCallCount()).To(Equal(0))
+				Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+				_, err, message, description := fakeErrorResponse.BadRequestArgsForCall(0)
+				Expect(err).To(MatchError(`invalid label selector "=payments"`))
+				Expect(message).To(Equal("policies-index"))
+				Expect(description).To(Equal(`invalid label selector "=payments"`))
+			})
+		})
+	})
+
 	Context("when the store throws an error", func() {
 		BeforeEach(func() {
 			fakeStore.AllReturns(nil, errors.New("banana"))
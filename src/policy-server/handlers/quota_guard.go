@@ -6,30 +6,169 @@ import (
 	"policy-server/uaa_client"
 )
 
+//go:generate counterfeiter -o fakes/quota_store.go --fake-name QuotaStore . quotaStore
+type quotaStore interface {
+	Upsert([]models.Quota) error
+	Delete([]models.Quota) error
+	All() ([]models.Quota, error)
+}
+
+const (
+	quotaMaxPolicies              = "max_policies"
+	quotaMaxSourcesPerDestination = "max_sources_per_destination"
+	quotaScopeGlobal              = "global"
+)
+
+type QuotaViolation struct {
+	AppGUID string
+	Quota   string
+	Scope   string
+	GUID    string
+	Limit   int
+}
+
+func (v *QuotaViolation) String() string {
+	if v.Scope == quotaScopeGlobal {
+		return fmt.Sprintf("app %s exceeds global %s of %d", v.AppGUID, v.Quota, v.Limit)
+	}
+	return fmt.Sprintf("app %s exceeds %s of %d for %s %s", v.AppGUID, v.Quota, v.Limit, v.Scope, v.GUID)
+}
+
 type QuotaGuard struct {
 	Store       store
+	QuotaStore  quotaStore
+	UAAClient   uaaClient
+	CCClient    ccClient
 	MaxPolicies int
 }
 
-func (g *QuotaGuard) CheckAccess(policies []models.Policy, userToken uaa_client.CheckTokenResponse) (bool, error) {
+type quotaLimit struct {
+	Scope string
+	GUID  string
+	Limit int
+}
+
+type appLimits struct {
+	MaxPolicies              quotaLimit
+	MaxSourcesPerDestination quotaLimit
+}
+
+func (g *QuotaGuard) CheckAccess(policies []models.Policy, userToken uaa_client.CheckTokenResponse) (*QuotaViolation, error) {
 	for _, scope := range userToken.Scope {
 		if scope == "network.admin" {
-			return true, nil
+			return nil, nil
 		}
 	}
 	appGuids := uniqueAppGUIDs(policies)
+	limits, err := g.resolveLimits(appGuids)
+	if err != nil {
+		return nil, err
+	}
+
 	toAddSourceCounts := sourceCounts(policies, appGuids)
-	sourcePolicies, err := g.Store.ByGuids(appGuids, []string{})
+	currentPolicies, err := g.Store.ByGuids(appGuids, appGuids)
 	if err != nil {
-		return false, fmt.Errorf("getting policies: %s", err)
+		return nil, fmt.Errorf("getting policies: %s", err)
 	}
-	currentAppCounts := sourceCounts(sourcePolicies, appGuids)
+	currentAppCounts := sourceCounts(currentPolicies, appGuids)
 	for _, appGuid := range appGuids {
-		if currentAppCounts[appGuid]+toAddSourceCounts[appGuid] > g.MaxPolicies {
-			return false, nil
+		limit := limits[appGuid].MaxPolicies
+		if currentAppCounts[appGuid]+toAddSourceCounts[appGuid] > limit.Limit {
+			return violation(appGuid, quotaMaxPolicies, limit), nil
 		}
 	}
-	return true, nil
+
+	destinationSources := sourcesByDestination(append(currentPolicies, policies...))
+	for _, policy := range policies {
+		appGuid := policy.Destination.ID
+		limit := limits[appGuid].MaxSourcesPerDestination
+		if limit.Limit > 0 && len(destinationSources[appGuid]) > limit.Limit {
+			return violation(appGuid, quotaMaxSourcesPerDestination, limit), nil
+		}
+	}
+	return nil, nil
+}
+
+func (g *QuotaGuard) resolveLimits(appGuids []string) (map[string]appLimits, error) {
+	global := quotaLimit{Scope: quotaScopeGlobal, Limit: g.MaxPolicies}
+	limits := make(map[string]appLimits)
+	for _, appGuid := range appGuids {
+		limits[appGuid] = appLimits{MaxPolicies: global}
+	}
+
+	quotas, err := g.QuotaStore.All()
+	if err != nil {
+		return nil, fmt.Errorf("getting quotas: %s", err)
+	}
+	if len(quotas) == 0 {
+		return limits, nil
+	}
+	quotasByScope := make(map[string]models.Quota)
+	for _, quota := range quotas {
+		quotasByScope[quota.Scope+"/"+quota.GUID] = quota
+	}
+
+	token, err := g.UAAClient.GetToken()
+	if err != nil {
+		return nil, fmt.Errorf("getting token: %s", err)
+	}
+	appSpaces, err := g.CCClient.GetAppSpaces(token, appGuids)
+	if err != nil {
+		return nil, fmt.Errorf("getting app spaces: %s", err)
+	}
+
+	spaceOrgs := make(map[string]string)
+	for _, spaceGuid := range appSpaces {
+		if _, ok := spaceOrgs[spaceGuid]; ok {
+			continue
+		}
+		space, err := g.CCClient.GetSpace(token, spaceGuid)
+		if err != nil {
+			return nil, fmt.Errorf("getting space with guid %s: %s", spaceGuid, err)
+		}
+		if space == nil {
+			spaceOrgs[spaceGuid] = ""
+			continue
+		}
+		spaceOrgs[spaceGuid] = space.OrgGUID
+	}
+
+	for appGuid, spaceGuid := range appSpaces {
+		if _, ok := limits[appGuid]; !ok {
+			continue
+		}
+		appLimit := limits[appGuid]
+		// the org quota applies first so that a space quota, being more specific, overrides it
+		orgQuota, ok := quotasByScope[models.QuotaScopeOrg+"/"+spaceOrgs[spaceGuid]]
+		if ok {
+			applyQuota(&appLimit, orgQuota)
+		}
+		spaceQuota, ok := quotasByScope[models.QuotaScopeSpace+"/"+spaceGuid]
+		if ok {
+			applyQuota(&appLimit, spaceQuota)
+		}
+		limits[appGuid] = appLimit
+	}
+	return limits, nil
+}
+
+func applyQuota(limits *appLimits, quota models.Quota) {
+	if quota.MaxPolicies > 0 {
+		limits.MaxPolicies = quotaLimit{Scope: quota.Scope, GUID: quota.GUID, Limit: quota.MaxPolicies}
+	}
+	if quota.MaxSourcesPerDestination > 0 {
+		limits.MaxSourcesPerDestination = quotaLimit{Scope: quota.Scope, GUID: quota.GUID, Limit: quota.MaxSourcesPerDestination}
+	}
+}
+
+func violation(appGuid, quota string, limit quotaLimit) *QuotaViolation {
+	return &QuotaViolation{
+		AppGUID: appGuid,
+		Quota:   quota,
+		Scope:   limit.Scope,
+		GUID:    limit.GUID,
+		Limit:   limit.Limit,
+	}
 }
 
 func sourceCounts(policies []models.Policy, knownAppGuids []string) map[string]int {
@@ -42,3 +181,14 @@ func sourceCounts(policies []models.Policy, knownAppGuids []string) map[string]i
 	}
 	return set
 }
+
+func sourcesByDestination(policies []models.Policy) map[string]map[string]struct{} {
+	var set = make(map[string]map[string]struct{})
+	for _, policy := range policies {
+		if _, ok := set[policy.Destination.ID]; !ok {
+			set[policy.Destination.ID] = make(map[string]struct{})
+		}
+		set[policy.Destination.ID][policy.Source.ID] = struct{}{}
+	}
+	return set
+}
@@ -3,8 +3,8 @@ package handlers_test
 import (
 	"errors"
 	"policy-server/handlers"
+	"policy-server/handlers/fakes"
 	"policy-server/models"
-	"policy-server/store/fakes"
 	"policy-server/uaa_client"
 
 	. "github.com/onsi/ginkgo"
@@ -13,15 +13,24 @@ import (
 
 var _ = Describe("QuotaGuard", func() {
 	var (
-		quotaGuard *handlers.QuotaGuard
-		fakeStore  *fakes.Store
-		policies   []models.Policy
-		tokenData  uaa_client.CheckTokenResponse
+		quotaGuard     *handlers.QuotaGuard
+		fakeStore      *fakes.Store
+		fakeQuotaStore *fakes.QuotaStore
+		fakeUAAClient  *fakes.UAAClient
+		fakeCCClient   *fakes.CCClient
+		policies       []models.Policy
+		tokenData      uaa_client.CheckTokenResponse
 	)
 	BeforeEach(func() {
 		fakeStore = &fakes.Store{}
+		fakeQuotaStore = &fakes.QuotaStore{}
+		fakeUAAClient = &fakes.UAAClient{}
+		fakeCCClient = &fakes.CCClient{}
 		quotaGuard = &handlers.QuotaGuard{
 			Store:       fakeStore,
+			QuotaStore:  fakeQuotaStore,
+			UAAClient:   fakeUAAClient,
+			CCClient:    fakeCCClient,
 			MaxPolicies: 2,
 		}
 		tokenData = uaa_client.CheckTokenResponse{
@@ -44,14 +53,33 @@ var _ = Describe("QuotaGuard", func() {
 			},
 		}
 		fakeStore.ByGuidsReturns([]models.Policy{}, nil)
+		fakeQuotaStore.AllReturns([]models.Quota{}, nil)
+		fakeUAAClient.GetTokenReturns("policy-server-token", nil)
+		fakeCCClient.GetAppSpacesReturns(map[string]string{
+			"some-app-guid":       "some-space-guid",
+			"some-other-app-guid": "some-space-guid",
+			"some-other-guid":     "some-space-guid",
+			"yet-another-guid":    "another-space-guid",
+		}, nil)
+		fakeCCClient.GetSpaceStub = func(token, spaceGUID string) (*models.Space, error) {
+			return &models.Space{Name: spaceGUID + "-name", OrgGUID: "some-org-guid"}, nil
+		}
 	})
 	Context("when the user is not an admin", func() {
 		Context("when the additional policies do not exceed the quota", func() {
 			It("allows policy creation", func() {
-				authorized, err := quotaGuard.CheckAccess(policies, tokenData)
+				violation, err := quotaGuard.CheckAccess(policies, tokenData)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(violation).To(BeNil())
+			})
+
+			It("does not look up spaces when no quotas are configured", func() {
+				_, err := quotaGuard.CheckAccess(policies, tokenData)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(authorized).To(BeTrue())
+				Expect(fakeUAAClient.GetTokenCallCount()).To(Equal(0))
+				Expect(fakeCCClient.GetAppSpacesCallCount()).To(Equal(0))
 			})
 		})
 		Context("when the additional policies exceed the quota", func() {
@@ -68,10 +96,104 @@ var _ = Describe("QuotaGuard", func() {
 				}, nil)
 			})
 			It("does not allow policy creation", func() {
-				authorized, err := quotaGuard.CheckAccess(policies, tokenData)
+				violation, err := quotaGuard.CheckAccess(policies, tokenData)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(violation).To(Equal(&handlers.QuotaViolation{
+					AppGUID: "some-other-app-guid",
+					Quota:   "max_policies",
+					Scope:   "global",
+					Limit:   2,
+				}))
+				Expect(violation.String()).To(Equal("app some-other-app-guid exceeds global max_policies of 2"))
+			})
+		})
+		Context("when an org quota is set", func() {
+			BeforeEach(func() {
+				fakeQuotaStore.AllReturns([]models.Quota{
+					{Scope: "org", GUID: "some-org-guid", MaxPolicies: 1},
+				}, nil)
+			})
+			It("enforces the org quota instead of the global one", func() {
+				violation, err := quotaGuard.CheckAccess(policies, tokenData)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(violation).To(Equal(&handlers.QuotaViolation{
+					AppGUID: "some-app-guid",
+					Quota:   "max_policies",
+					Scope:   "org",
+					GUID:    "some-org-guid",
+					Limit:   1,
+				}))
+				Expect(violation.String()).To(Equal("app some-app-guid exceeds max_policies of 1 for org some-org-guid"))
+			})
+
+			It("resolves the app spaces and orgs with the policy server token", func() {
+				_, err := quotaGuard.CheckAccess(policies, tokenData)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeCCClient.GetAppSpacesCallCount()).To(Equal(1))
+				token, appGUIDs := fakeCCClient.GetAppSpacesArgsForCall(0)
+				Expect(token).To(Equal("policy-server-token"))
+				Expect(appGUIDs).To(ConsistOf("some-app-guid", "some-other-app-guid", "some-other-guid", "yet-another-guid"))
+
+				Expect(fakeCCClient.GetSpaceCallCount()).To(Equal(2))
+			})
+
+			Context("when a space quota is also set", func() {
+				BeforeEach(func() {
+					fakeQuotaStore.AllReturns([]models.Quota{
+						{Scope: "org", GUID: "some-org-guid", MaxPolicies: 1},
+						{Scope: "space", GUID: "some-space-guid", MaxPolicies: 5},
+					}, nil)
+				})
+				It("enforces the most specific quota", func() {
+					violation, err := quotaGuard.CheckAccess(policies, tokenData)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(violation).To(BeNil())
+				})
+			})
+		})
+		Context("when a destination quota is set", func() {
+			BeforeEach(func() {
+				fakeQuotaStore.AllReturns([]models.Quota{
+					{Scope: "space", GUID: "another-space-guid", MaxSourcesPerDestination: 1},
+				}, nil)
+			})
+			It("limits how many apps may target a destination", func() {
+				violation, err := quotaGuard.CheckAccess(policies, tokenData)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(authorized).To(BeFalse())
+				Expect(violation).To(Equal(&handlers.QuotaViolation{
+					AppGUID: "yet-another-guid",
+					Quota:   "max_sources_per_destination",
+					Scope:   "space",
+					GUID:    "another-space-guid",
+					Limit:   1,
+				}))
+			})
+
+			Context("when existing policies already target the destination", func() {
+				BeforeEach(func() {
+					fakeQuotaStore.AllReturns([]models.Quota{
+						{Scope: "space", GUID: "some-space-guid", MaxSourcesPerDestination: 1},
+					}, nil)
+					fakeStore.ByGuidsReturns([]models.Policy{
+						{
+							Source:      models.Source{ID: "a-different-app-guid"},
+							Destination: models.Destination{ID: "some-other-guid"},
+						},
+					}, nil)
+				})
+				It("counts them towards the quota", func() {
+					violation, err := quotaGuard.CheckAccess(policies, tokenData)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(violation).NotTo(BeNil())
+					Expect(violation.AppGUID).To(Equal("some-other-guid"))
+					Expect(violation.Quota).To(Equal("max_sources_per_destination"))
+				})
 			})
 		})
 		Context("when getting the policies by guid fails", func() {
@@ -82,7 +204,50 @@ var _ = Describe("QuotaGuard", func() {
 				_, err := quotaGuard.CheckAccess(policies, tokenData)
 				Expect(err).To(MatchError("getting policies: banana"))
 			})
-
+		})
+		Context("when getting the quotas fails", func() {
+			BeforeEach(func() {
+				fakeQuotaStore.AllReturns(nil, errors.New("banana"))
+			})
+			It("returns an error", func() {
+				_, err := quotaGuard.CheckAccess(policies, tokenData)
+				Expect(err).To(MatchError("getting quotas: banana"))
+			})
+		})
+		Context("when quotas exist", func() {
+			BeforeEach(func() {
+				fakeQuotaStore.AllReturns([]models.Quota{
+					{Scope: "org", GUID: "some-org-guid", MaxPolicies: 10},
+				}, nil)
+			})
+			Context("when getting the token fails", func() {
+				BeforeEach(func() {
+					fakeUAAClient.GetTokenReturns("", errors.New("banana"))
+				})
+				It("returns an error", func() {
+					_, err := quotaGuard.CheckAccess(policies, tokenData)
+					Expect(err).To(MatchError("getting token: banana"))
+				})
+			})
+			Context("when getting the app spaces fails", func() {
+				BeforeEach(func() {
+					fakeCCClient.GetAppSpacesReturns(nil, errors.New("banana"))
+				})
+				It("returns an error", func() {
+					_, err := quotaGuard.CheckAccess(policies, tokenData)
+					Expect(err).To(MatchError("getting app spaces: banana"))
+				})
+			})
+			Context("when getting a space fails", func() {
+				BeforeEach(func() {
+					fakeCCClient.GetSpaceStub = nil
+					fakeCCClient.GetSpaceReturns(nil, errors.New("banana"))
+				})
+				It("returns an error", func() {
+					_, err := quotaGuard.CheckAccess(policies, tokenData)
+					Expect(err).To(MatchError(ContainSubstring("banana")))
+				})
+			})
 		})
 	})
 	Context("when the user is an admin", func() {
@@ -104,10 +269,10 @@ var _ = Describe("QuotaGuard", func() {
 			}, nil)
 		})
 		It("allows policy creation beyond the max policies", func() {
-			authorized, err := quotaGuard.CheckAccess(policies, tokenData)
+			violation, err := quotaGuard.CheckAccess(policies, tokenData)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(authorized).To(BeTrue())
+			Expect(violation).To(BeNil())
 		})
 	})
 })
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	"code.cloudfoundry.org/cf-networking-helpers/marshal"
+	"code.cloudfoundry.org/lager"
+)
+
+type QuotasCreate struct {
+	QuotaStore    quotaStore
+	Unmarshaler   marshal.Unmarshaler
+	ErrorResponse errorResponse
+}
+
+func (h *QuotasCreate) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request, tokenData uaa_client.CheckTokenResponse) {
+	logger = logger.Session("create-quotas")
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		logger.Error("failed-reading-request-body", err)
+		h.ErrorResponse.BadRequest(w, err, "quotas-create", "failed reading request body")
+		return
+	}
+
+	var payload struct {
+		Quotas []models.Quota `json:"quotas"`
+	}
+	err = h.Unmarshaler.Unmarshal(bodyBytes, &payload)
+	if err != nil {
+		logger.Error("failed-unmarshalling-payload", err)
+		h.ErrorResponse.BadRequest(w, err, "quotas-create", "invalid values passed to API")
+		return
+	}
+
+	err = validateQuotas(payload.Quotas)
+	if err != nil {
+		logger.Error("failed-validating-quotas", err)
+		h.ErrorResponse.BadRequest(w, err, "quotas-create", err.Error())
+		return
+	}
+
+	err = h.QuotaStore.Upsert(payload.Quotas)
+	if err != nil {
+		logger.Error("failed-creating-in-database", err)
+		h.ErrorResponse.InternalServerError(w, err, "quotas-create", "database create failed")
+		return
+	}
+
+	logger.Info("created-quotas", lager.Data{"quotas": payload.Quotas, "userName": tokenData.UserName})
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("{}"))
+}
+
+func validateQuotas(quotas []models.Quota) error {
+	if len(quotas) == 0 {
+		return errors.New("missing quotas")
+	}
+
+	for _, quota := range quotas {
+		if quota.Scope != models.QuotaScopeOrg && quota.Scope != models.QuotaScopeSpace {
+			return errors.New("invalid quota scope, specify either org or space")
+		}
+		if quota.GUID == "" {
+			return errors.New("missing quota guid")
+		}
+		if quota.MaxPolicies < 0 {
+			return fmt.Errorf("invalid max_policies value %d, must not be negative", quota.MaxPolicies)
+		}
+		if quota.MaxSourcesPerDestination < 0 {
+			return fmt.Errorf("invalid max_sources_per_destination value %d, must not be negative", quota.MaxSourcesPerDestination)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
+	"code.cloudfoundry.org/lager"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QuotasCreate", func() {
+	var (
+		requestJSON       string
+		request           *http.Request
+		handler           *handlers.QuotasCreate
+		resp              *httptest.ResponseRecorder
+		fakeQuotaStore    *fakes.QuotaStore
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		fakeUnmarshaler   *hfakes.Unmarshaler
+		tokenData         uaa_client.CheckTokenResponse
+	)
+
+	BeforeEach(func() {
+		requestJSON = `{"quotas": [
+			{ "scope": "org", "guid": "some-org-guid", "max_policies": 10 },
+			{ "scope": "space", "guid": "some-space-guid", "max_sources_per_destination": 3 }
+		]}`
+
+		fakeQuotaStore = &fakes.QuotaStore{}
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		fakeUnmarshaler = &hfakes.Unmarshaler{}
+		fakeUnmarshaler.UnmarshalStub = json.Unmarshal
+		logger = lagertest.NewTestLogger("test")
+		handler = &handlers.QuotasCreate{
+			QuotaStore:    fakeQuotaStore,
+			Unmarshaler:   fakeUnmarshaler,
+			ErrorResponse: fakeErrorResponse,
+		}
+		tokenData = uaa_client.CheckTokenResponse{
+			Scope:    []string{"network.admin"},
+			UserName: "some_user",
+		}
+		resp = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		request, err = http.NewRequest("POST", "/networking/v0/external/quotas", bytes.NewBuffer([]byte(requestJSON)))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("persists the quotas", func() {
+		handler.ServeHTTP(logger, resp, request, tokenData)
+
+		Expect(fakeQuotaStore.UpsertCallCount()).To(Equal(1))
+		Expect(fakeQuotaStore.UpsertArgsForCall(0)).To(Equal([]models.Quota{
+			{Scope: "org", GUID: "some-org-guid", MaxPolicies: 10},
+			{Scope: "space", GUID: "some-space-guid", MaxSourcesPerDestination: 3},
+		}))
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON("{}"))
+	})
+
+	It("logs the quotas with username", func() {
+		handler.ServeHTTP(logger, resp, request, tokenData)
+
+		Expect(logger.Logs()).To(HaveLen(1))
+		Expect(logger.Logs()[0]).To(SatisfyAll(
+			LogsWith(lager.INFO, "test.create-quotas.created-quotas"),
+			HaveLogData(HaveKeyWithValue("userName", "some_user")),
+		))
+	})
+
+	Context("when the payload cannot be unmarshaled", func() {
+		BeforeEach(func() {
+			fakeUnmarshaler.UnmarshalReturns(errors.New("banana"))
+			fakeUnmarshaler.UnmarshalStub = nil
+		})
+
+		It("calls the bad request handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.BadRequestArgsForCall(0)
+			Expect(err).To(MatchError("banana"))
+			Expect(message).To(Equal("quotas-create"))
+			Expect(description).To(Equal("invalid values passed to API"))
+		})
+	})
+
+	DescribeTable("when the quotas are invalid",
+		func(body, expectedError string) {
+			requestJSON = body
+			request, _ = http.NewRequest("POST", "/networking/v0/external/quotas", bytes.NewBuffer([]byte(requestJSON)))
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeQuotaStore.UpsertCallCount()).To(Equal(0))
+			Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.BadRequestArgsForCall(0)
+			Expect(err).To(MatchError(expectedError))
+			Expect(message).To(Equal("quotas-create"))
+			Expect(description).To(Equal(expectedError))
+		},
+		Entry("no quotas", `{"quotas": []}`, "missing quotas"),
+		Entry("bad scope", `{"quotas": [{"scope": "foundation", "guid": "some-guid"}]}`, "invalid quota scope, specify either org or space"),
+		Entry("missing guid", `{"quotas": [{"scope": "org"}]}`, "missing quota guid"),
+		Entry("negative max policies", `{"quotas": [{"scope": "org", "guid": "some-guid", "max_policies": -1}]}`, "invalid max_policies value -1, must not be negative"),
+		Entry("negative max sources", `{"quotas": [{"scope": "org", "guid": "some-guid", "max_sources_per_destination": -2}]}`, "invalid max_sources_per_destination value -2, must not be negative"),
+	)
+
+	Context("when the store fails", func() {
+		BeforeEach(func() {
+			fakeQuotaStore.UpsertReturns(errors.New("banana"))
+		})
+
+		It("calls the internal server error handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(err).To(MatchError("banana"))
+			Expect(message).To(Equal("quotas-create"))
+			Expect(description).To(Equal("database create failed"))
+		})
+	})
+})
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	"code.cloudfoundry.org/cf-networking-helpers/marshal"
+	"code.cloudfoundry.org/lager"
+)
+
+type QuotasDelete struct {
+	QuotaStore    quotaStore
+	Unmarshaler   marshal.Unmarshaler
+	ErrorResponse errorResponse
+}
+
+func (h *QuotasDelete) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request, tokenData uaa_client.CheckTokenResponse) {
+	logger = logger.Session("delete-quotas")
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		logger.Error("failed-reading-request-body", err)
+		h.ErrorResponse.BadRequest(w, err, "delete-quotas", "invalid request body")
+		return
+	}
+
+	var payload struct {
+		Quotas []models.Quota `json:"quotas"`
+	}
+	err = h.Unmarshaler.Unmarshal(bodyBytes, &payload)
+	if err != nil {
+		logger.Error("failed-unmarshalling-payload", err)
+		h.ErrorResponse.BadRequest(w, err, "delete-quotas", "invalid values passed to API")
+		return
+	}
+
+	err = validateQuotas(payload.Quotas)
+	if err != nil {
+		logger.Error("failed-validating-quotas", err)
+		h.ErrorResponse.BadRequest(w, err, "delete-quotas", err.Error())
+		return
+	}
+
+	err = h.QuotaStore.Delete(payload.Quotas)
+	if err != nil {
+		logger.Error("failed-deleting-in-database", err)
+		h.ErrorResponse.InternalServerError(w, err, "delete-quotas", "database delete failed")
+		return
+	}
+
+	logger.Info("deleted-quotas", lager.Data{"quotas": payload.Quotas, "userName": tokenData.UserName})
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{}`))
+}
@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
+	"code.cloudfoundry.org/lager"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QuotasDelete", func() {
+	var (
+		requestJSON       string
+		request           *http.Request
+		handler           *handlers.QuotasDelete
+		resp              *httptest.ResponseRecorder
+		fakeQuotaStore    *fakes.QuotaStore
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		fakeUnmarshaler   *hfakes.Unmarshaler
+		tokenData         uaa_client.CheckTokenResponse
+	)
+
+	BeforeEach(func() {
+		requestJSON = `{"quotas": [
+			{ "scope": "space", "guid": "some-space-guid" }
+		]}`
+		var err error
+		request, err = http.NewRequest("POST", "/networking/v0/external/quotas/delete", bytes.NewBuffer([]byte(requestJSON)))
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeQuotaStore = &fakes.QuotaStore{}
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		fakeUnmarshaler = &hfakes.Unmarshaler{}
+		fakeUnmarshaler.UnmarshalStub = json.Unmarshal
+		logger = lagertest.NewTestLogger("test")
+		handler = &handlers.QuotasDelete{
+			QuotaStore:    fakeQuotaStore,
+			Unmarshaler:   fakeUnmarshaler,
+			ErrorResponse: fakeErrorResponse,
+		}
+		tokenData = uaa_client.CheckTokenResponse{
+			Scope:    []string{"network.admin"},
+			UserName: "some_user",
+		}
+		resp = httptest.NewRecorder()
+	})
+
+	It("removes the quotas", func() {
+		handler.ServeHTTP(logger, resp, request, tokenData)
+
+		Expect(fakeQuotaStore.DeleteCallCount()).To(Equal(1))
+		Expect(fakeQuotaStore.DeleteArgsForCall(0)).To(Equal([]models.Quota{
+			{Scope: "space", GUID: "some-space-guid"},
+		}))
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON("{}"))
+
+		Expect(logger.Logs()).To(HaveLen(1))
+		Expect(logger.Logs()[0]).To(SatisfyAll(
+			LogsWith(lager.INFO, "test.delete-quotas.deleted-quotas"),
+			HaveLogData(HaveKeyWithValue("userName", "some_user")),
+		))
+	})
+
+	Context("when the quotas are invalid", func() {
+		BeforeEach(func() {
+			request, _ = http.NewRequest("POST", "/networking/v0/external/quotas/delete", bytes.NewBuffer([]byte(`{"quotas": [{"scope": "space"}]}`)))
+		})
+
+		It("calls the bad request handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeQuotaStore.DeleteCallCount()).To(Equal(0))
+			Expect(fakeErrorResponse.BadRequestCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.BadRequestArgsForCall(0)
+			Expect(err).To(MatchError("missing quota guid"))
+			Expect(message).To(Equal("delete-quotas"))
+			Expect(description).To(Equal("missing quota guid"))
+		})
+	})
+
+	Context("when the store fails", func() {
+		BeforeEach(func() {
+			fakeQuotaStore.DeleteReturns(errors.New("banana"))
+		})
+
+		It("calls the internal server error handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(err).To(MatchError("banana"))
+			Expect(message).To(Equal("delete-quotas"))
+			Expect(description).To(Equal("database delete failed"))
+		})
+	})
+})
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	"code.cloudfoundry.org/cf-networking-helpers/marshal"
+	"code.cloudfoundry.org/lager"
+)
+
+type QuotasIndex struct {
+	QuotaStore    quotaStore
+	Marshaler     marshal.Marshaler
+	ErrorResponse errorResponse
+}
+
+func (h *QuotasIndex) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request, _ uaa_client.CheckTokenResponse) {
+	logger = logger.Session("index-quotas")
+	quotas, err := h.QuotaStore.All()
+	if err != nil {
+		logger.Error("failed-reading-database", err)
+		h.ErrorResponse.InternalServerError(w, err, "quotas-index", "database read failed")
+		return
+	}
+
+	quotasResponse := struct {
+		Quotas []models.Quota `json:"quotas"`
+	}{quotas}
+	responseBytes, err := h.Marshaler.Marshal(quotasResponse)
+	if err != nil {
+		logger.Error("failed-marshalling-quotas", err)
+		h.ErrorResponse.InternalServerError(w, err, "quotas-index", "database marshalling failed")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseBytes)
+}
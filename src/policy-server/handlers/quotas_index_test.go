@@ -0,0 +1,116 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/models"
+	"policy-server/uaa_client"
+
+	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
+	"code.cloudfoundry.org/lager"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Quotas index handler", func() {
+	var (
+		request           *http.Request
+		handler           *handlers.QuotasIndex
+		resp              *httptest.ResponseRecorder
+		fakeQuotaStore    *fakes.QuotaStore
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		marshaler         *hfakes.Marshaler
+		tokenData         uaa_client.CheckTokenResponse
+	)
+
+	BeforeEach(func() {
+		var err error
+		request, err = http.NewRequest("GET", "/networking/v0/external/quotas", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		marshaler = &hfakes.Marshaler{}
+		marshaler.MarshalStub = json.Marshal
+
+		fakeQuotaStore = &fakes.QuotaStore{}
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		fakeQuotaStore.AllReturns([]models.Quota{
+			{Scope: "org", GUID: "some-org-guid", MaxPolicies: 10},
+			{Scope: "space", GUID: "some-space-guid", MaxPolicies: 5, MaxSourcesPerDestination: 3},
+		}, nil)
+		logger = lagertest.NewTestLogger("test")
+		handler = &handlers.QuotasIndex{
+			QuotaStore:    fakeQuotaStore,
+			Marshaler:     marshaler,
+			ErrorResponse: fakeErrorResponse,
+		}
+		resp = httptest.NewRecorder()
+		tokenData = uaa_client.CheckTokenResponse{}
+	})
+
+	It("returns all the quotas", func() {
+		expectedResponseJSON := `{"quotas": [
+			{ "scope": "org", "guid": "some-org-guid", "max_policies": 10, "max_sources_per_destination": 0 },
+			{ "scope": "space", "guid": "some-space-guid", "max_policies": 5, "max_sources_per_destination": 3 }
+        ]}`
+		handler.ServeHTTP(logger, resp, request, tokenData)
+
+		Expect(fakeQuotaStore.AllCallCount()).To(Equal(1))
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body).To(MatchJSON(expectedResponseJSON))
+	})
+
+	Context("when the store throws an error", func() {
+		BeforeEach(func() {
+			fakeQuotaStore.AllReturns(nil, errors.New("banana"))
+		})
+
+		It("calls the internal server error handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+
+			w, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(w).To(Equal(resp))
+			Expect(err).To(MatchError("banana"))
+			Expect(message).To(Equal("quotas-index"))
+			Expect(description).To(Equal("database read failed"))
+
+			By("logging the error")
+			Expect(logger.Logs()).To(HaveLen(1))
+			Expect(logger.Logs()[0]).To(SatisfyAll(
+				LogsWith(lager.ERROR, "test.index-quotas.failed-reading-database"),
+				HaveLogData(SatisfyAll(
+					HaveLen(2),
+					HaveKeyWithValue("error", "banana"),
+					HaveKeyWithValue("session", "1"),
+				)),
+			))
+		})
+	})
+
+	Context("when the quotas cannot be marshaled", func() {
+		BeforeEach(func() {
+			marshaler.MarshalStub = func(interface{}) ([]byte, error) {
+				return nil, errors.New("grapes")
+			}
+		})
+
+		It("calls the internal server error handler", func() {
+			handler.ServeHTTP(logger, resp, request, tokenData)
+
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+
+			_, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(err).To(MatchError("grapes"))
+			Expect(message).To(Equal("quotas-index"))
+			Expect(description).To(Equal("database marshalling failed"))
+		})
+	})
+})
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// Readiness reports whether the policy server can currently serve requests.
+// Unlike Health it answers 503 rather than 500, and gives up on the database
+// after Timeout so a hung connection during failover marks the instance
+// unready instead of stalling the health checker.
+type Readiness struct {
+	Store   store
+	Timeout time.Duration
+}
+
+func (h *Readiness) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request) {
+	logger = logger.Session("readiness")
+
+	result := make(chan error, 1)
+	go func() {
+		result <- h.Store.CheckDatabase()
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-time.After(h.Timeout):
+		err = errors.New("timed out checking database")
+	}
+	if err != nil {
+		logger.Error("database-unreachable", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"ready": false, "error": "database unreachable"}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ready": true}`))
+}
@@ -0,0 +1,89 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Readiness handler", func() {
+	var (
+		handler   *handlers.Readiness
+		request   *http.Request
+		fakeStore *fakes.Store
+		resp      *httptest.ResponseRecorder
+		logger    *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		var err error
+		request, err = http.NewRequest("GET", "/ready", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeStore = &fakes.Store{}
+
+		handler = &handlers.Readiness{
+			Store:   fakeStore,
+			Timeout: 100 * time.Millisecond,
+		}
+		resp = httptest.NewRecorder()
+
+		logger = lagertest.NewTestLogger("test-logger")
+	})
+
+	It("checks the database is up and returns a 200", func() {
+		handler.ServeHTTP(logger, resp, request)
+		Expect(fakeStore.CheckDatabaseCallCount()).To(Equal(1))
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body.String()).To(MatchJSON(`{"ready": true}`))
+	})
+
+	Context("when the database returns an error", func() {
+		BeforeEach(func() {
+			fakeStore.CheckDatabaseReturns(errors.New("pineapple"))
+		})
+
+		It("returns a 503 and logs the error", func() {
+			handler.ServeHTTP(logger, resp, request)
+			Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(resp.Body.String()).To(MatchJSON(`{"ready": false, "error": "database unreachable"}`))
+
+			Expect(logger.Logs()).To(HaveLen(1))
+			Expect(logger.Logs()[0]).To(SatisfyAll(
+				LogsWith(lager.ERROR, "test-logger.readiness.database-unreachable"),
+				HaveLogData(HaveKeyWithValue("error", "pineapple")),
+			))
+		})
+	})
+
+	Context("when the database check hangs", func() {
+		var unblock chan struct{}
+
+		BeforeEach(func() {
+			unblock = make(chan struct{})
+			fakeStore.CheckDatabaseStub = func() error {
+				<-unblock
+				return nil
+			}
+		})
+
+		AfterEach(func() {
+			close(unblock)
+		})
+
+		It("returns a 503 after the timeout", func() {
+			handler.ServeHTTP(logger, resp, request)
+			Expect(resp.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(logger.Logs()[0].Data).To(HaveKeyWithValue("error", "timed out checking database"))
+		})
+	})
+})
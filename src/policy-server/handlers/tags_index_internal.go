@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"policy-server/models"
+
+	"code.cloudfoundry.org/cf-networking-helpers/marshal"
+	"code.cloudfoundry.org/lager"
+)
+
+type TagsIndexInternal struct {
+	Store         store
+	Marshaler     marshal.Marshaler
+	ErrorResponse errorResponse
+}
+
+// ServeHTTP returns the tags of every app and policy group, so that the
+// marks on packets can be resolved to apps on the cells.
+func (h *TagsIndexInternal) ServeHTTP(logger lager.Logger, w http.ResponseWriter, req *http.Request) {
+	logger = logger.Session("index-tags-internal")
+	tags, err := h.Store.Tags()
+	if err != nil {
+		logger.Error("failed-reading-database", err)
+		h.ErrorResponse.InternalServerError(w, err, "tags-index-internal", "database read failed")
+		return
+	}
+
+	tagsResponse := struct {
+		Tags []models.Tag `json:"tags"`
+	}{tags}
+	responseBytes, err := h.Marshaler.Marshal(tagsResponse)
+	if err != nil {
+		logger.Error("failed-marshalling-tags", err)
+		h.ErrorResponse.InternalServerError(w, err, "tags-index-internal", "database marshalling failed")
+		return
+	}
+
+	w.Write(responseBytes)
+}
@@ -0,0 +1,102 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"policy-server/handlers"
+	"policy-server/handlers/fakes"
+	"policy-server/models"
+
+	hfakes "code.cloudfoundry.org/cf-networking-helpers/fakes"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tags index handler (internal)", func() {
+	var (
+		request           *http.Request
+		handler           *handlers.TagsIndexInternal
+		resp              *httptest.ResponseRecorder
+		fakeStore         *fakes.Store
+		fakeErrorResponse *fakes.ErrorResponse
+		logger            *lagertest.TestLogger
+		marshaler         *hfakes.Marshaler
+	)
+
+	BeforeEach(func() {
+		var err error
+		request, err = http.NewRequest("GET", "/networking/v0/internal/tags", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		marshaler = &hfakes.Marshaler{}
+		marshaler.MarshalStub = json.Marshal
+
+		fakeStore = &fakes.Store{}
+		fakeStore.TagsReturns([]models.Tag{
+			{ID: "some-app-guid", Tag: "0001"},
+			{ID: "some-other-app-guid", Tag: "0002"},
+		}, nil)
+		fakeErrorResponse = &fakes.ErrorResponse{}
+		logger = lagertest.NewTestLogger("test")
+		handler = &handlers.TagsIndexInternal{
+			Store:         fakeStore,
+			Marshaler:     marshaler,
+			ErrorResponse: fakeErrorResponse,
+		}
+		resp = httptest.NewRecorder()
+	})
+
+	It("returns all the tags", func() {
+		handler.ServeHTTP(logger, resp, request)
+
+		Expect(fakeStore.TagsCallCount()).To(Equal(1))
+		Expect(resp.Code).To(Equal(http.StatusOK))
+		Expect(resp.Body).To(MatchJSON(`{"tags": [
+			{ "id": "some-app-guid", "tag": "0001" },
+			{ "id": "some-other-app-guid", "tag": "0002" }
+		]}`))
+	})
+
+	Context("when the store throws an error", func() {
+		BeforeEach(func() {
+			fakeStore.TagsReturns(nil, errors.New("banana"))
+		})
+
+		It("calls the internal server error handler", func() {
+			handler.ServeHTTP(logger, resp, request)
+
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+			w, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(w).To(Equal(resp))
+			Expect(err).To(MatchError("banana"))
+			Expect(message).To(Equal("tags-index-internal"))
+			Expect(description).To(Equal("database read failed"))
+
+			Expect(logger.Logs()).To(HaveLen(1))
+			Expect(logger.Logs()[0]).To(LogsWith(lager.ERROR, "test.index-tags-internal.failed-reading-database"))
+		})
+	})
+
+	Context("when the tags cannot be marshaled", func() {
+		BeforeEach(func() {
+			marshaler.MarshalStub = func(interface{}) ([]byte, error) {
+				return nil, errors.New("grapes")
+			}
+		})
+
+		It("calls the internal server error handler", func() {
+			handler.ServeHTTP(logger, resp, request)
+
+			Expect(fakeErrorResponse.InternalServerErrorCallCount()).To(Equal(1))
+			_, err, message, description := fakeErrorResponse.InternalServerErrorArgsForCall(0)
+			Expect(err).To(MatchError("grapes"))
+			Expect(message).To(Equal("tags-index-internal"))
+			Expect(description).To(Equal("database marshalling failed"))
+		})
+	})
+})
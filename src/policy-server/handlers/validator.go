@@ -4,8 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"policy-server/models"
+	"regexp"
+	"time"
 )
 
+const (
+	maxDescriptionLength = 255
+	maxLabels            = 16
+	maxLabelValueLength  = 255
+)
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]{0,61}[A-Za-z0-9])?$`)
+
 //go:generate counterfeiter -o fakes/validator.go --fake-name Validator . validator
 type validator interface {
 	ValidatePolicies(policies []models.Policy) error
@@ -25,19 +35,98 @@ func (v *Validator) ValidatePolicies(policies []models.Policy) error {
 		if policy.Destination.ID == "" {
 			return errors.New("missing destination id")
 		}
-		if policy.Destination.Protocol != "udp" && policy.Destination.Protocol != "tcp" {
-			return errors.New("invalid destination protocol, specify either udp or tcp")
-		}
-		if policy.Destination.Ports.Start != policy.Destination.Ports.End {
-			return fmt.Errorf("invalid destination port range %d-%d, start and end must be same", policy.Destination.Ports.Start, policy.Destination.Ports.End)
-		}
-		if policy.Destination.Port < 1 || policy.Destination.Port > 65535 {
-			return fmt.Errorf("invalid destination port value %d, must be 1-65535", policy.Destination.Port)
+
+		err := validateDestination(policy.Destination)
+		if err != nil {
+			return err
 		}
 
 		if policy.Source.Tag != "" || policy.Destination.Tag != "" {
 			return errors.New("tags may not be specified")
 		}
+
+		err = validateMetadata(policy)
+		if err != nil {
+			return err
+		}
+
+		if policy.ExpiresAt != nil && !policy.ExpiresAt.After(time.Now()) {
+			return fmt.Errorf("invalid expires_at %s, must be in the future", policy.ExpiresAt.UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+func validateDestination(destination models.Destination) error {
+	switch destination.Protocol {
+	case models.ProtocolTCP, models.ProtocolUDP:
+		if destination.ICMPType != nil || destination.ICMPCode != nil {
+			return fmt.Errorf("icmp type and code may not be specified for protocol %s", destination.Protocol)
+		}
+		if destination.Ports.Start != destination.Ports.End {
+			return fmt.Errorf("invalid destination port range %d-%d, start and end must be same", destination.Ports.Start, destination.Ports.End)
+		}
+		if destination.Port < 1 || destination.Port > 65535 {
+			return fmt.Errorf("invalid destination port value %d, must be 1-65535", destination.Port)
+		}
+	case models.ProtocolICMP:
+		if destination.Port != 0 || destination.Ports.Start != 0 || destination.Ports.End != 0 {
+			return errors.New("ports may not be specified for protocol icmp")
+		}
+		return validateICMP(destination.ICMPType, destination.ICMPCode)
+	case models.ProtocolAll:
+		if destination.Port != 0 || destination.Ports.Start != 0 || destination.Ports.End != 0 {
+			return errors.New("ports may not be specified for protocol all")
+		}
+		if destination.ICMPType != nil || destination.ICMPCode != nil {
+			return errors.New("icmp type and code may not be specified for protocol all")
+		}
+	default:
+		return errors.New("invalid destination protocol, specify one of udp, tcp, icmp or all")
+	}
+	return nil
+}
+
+func validateICMP(icmpType, icmpCode *int) error {
+	if icmpCode != nil && icmpType == nil {
+		return errors.New("icmp code may not be specified without an icmp type")
+	}
+	if icmpType != nil && (*icmpType < 0 || *icmpType > 255) {
+		return fmt.Errorf("invalid icmp type %d, must be 0-255", *icmpType)
+	}
+	if icmpCode != nil && (*icmpCode < 0 || *icmpCode > 255) {
+		return fmt.Errorf("invalid icmp code %d, must be 0-255", *icmpCode)
+	}
+	return nil
+}
+
+// validateICMPv6 rejects the types that are not ICMPv6 ones, such as the
+// ICMPv4 echo request 8, as the agents match the type of IPv6 destinations
+// against ICMPv6 packets unchanged.
+func validateICMPv6(icmpType *int) error {
+	if icmpType == nil {
+		return nil
+	}
+	if *icmpType < 1 || (*icmpType > 4 && *icmpType < 128) {
+		return fmt.Errorf("invalid icmp type %d for an ipv6 cidr, must be an icmpv6 type 1-4 or 128-255", *icmpType)
+	}
+	return nil
+}
+
+func validateMetadata(policy models.Policy) error {
+	if len(policy.Description) > maxDescriptionLength {
+		return fmt.Errorf("invalid description, must be at most %d characters", maxDescriptionLength)
+	}
+	if len(policy.Labels) > maxLabels {
+		return fmt.Errorf("too many labels, at most %d may be specified", maxLabels)
+	}
+	for key, value := range policy.Labels {
+		if !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid label key %q, must be 1-63 alphanumeric characters, '-', '_' or '.'", key)
+		}
+		if len(value) > maxLabelValueLength {
+			return fmt.Errorf("invalid value for label %q, must be at most %d characters", key, maxLabelValueLength)
+		}
 	}
 	return nil
 }
@@ -1,8 +1,11 @@
 package handlers_test
 
 import (
+	"fmt"
 	"policy-server/handlers"
 	"policy-server/models"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -92,7 +95,7 @@ var _ = Describe("Validator", func() {
 				}
 
 				err := validator.ValidatePolicies(policies)
-				Expect(err).To(MatchError("invalid destination protocol, specify either udp or tcp"))
+				Expect(err).To(MatchError("invalid destination protocol, specify one of udp, tcp, icmp or all"))
 			})
 		})
 
@@ -143,6 +146,96 @@ var _ = Describe("Validator", func() {
 			})
 		})
 
+		Context("when the destination protocol is icmp", func() {
+			var policies []models.Policy
+			var icmpType, icmpCode int
+
+			BeforeEach(func() {
+				icmpType, icmpCode = 8, 0
+				policies = []models.Policy{{
+					Source: models.Source{ID: "foo"},
+					Destination: models.Destination{
+						ID:       "bar",
+						Protocol: "icmp",
+						ICMPType: &icmpType,
+						ICMPCode: &icmpCode,
+					},
+				}}
+			})
+
+			It("accepts an icmp type and code", func() {
+				Expect(validator.ValidatePolicies(policies)).To(Succeed())
+			})
+
+			It("accepts a policy for any icmp type", func() {
+				policies[0].Destination.ICMPType = nil
+				policies[0].Destination.ICMPCode = nil
+				Expect(validator.ValidatePolicies(policies)).To(Succeed())
+			})
+
+			It("rejects ports", func() {
+				policies[0].Destination.Port = 8080
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("ports may not be specified for protocol icmp"))
+			})
+
+			It("rejects a code without a type", func() {
+				policies[0].Destination.ICMPType = nil
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("icmp code may not be specified without an icmp type"))
+			})
+
+			It("rejects out of range types and codes", func() {
+				icmpType = 256
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("invalid icmp type 256, must be 0-255"))
+
+				icmpType, icmpCode = 8, -1
+				err = validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("invalid icmp code -1, must be 0-255"))
+			})
+		})
+
+		Context("when the destination protocol is all", func() {
+			var policies []models.Policy
+
+			BeforeEach(func() {
+				policies = []models.Policy{{
+					Source:      models.Source{ID: "foo"},
+					Destination: models.Destination{ID: "bar", Protocol: "all"},
+				}}
+			})
+
+			It("accepts the policy", func() {
+				Expect(validator.ValidatePolicies(policies)).To(Succeed())
+			})
+
+			It("rejects ports", func() {
+				policies[0].Destination.Ports = models.Ports{Start: 8080, End: 8080}
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("ports may not be specified for protocol all"))
+			})
+
+			It("rejects an icmp type", func() {
+				icmpType := 8
+				policies[0].Destination.ICMPType = &icmpType
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("icmp type and code may not be specified for protocol all"))
+			})
+		})
+
+		Context("when an icmp type is given for a tcp destination", func() {
+			It("returns a useful error", func() {
+				icmpType := 8
+				policies := []models.Policy{{
+					Source:      models.Source{ID: "foo"},
+					Destination: models.Destination{ID: "bar", Protocol: "tcp", Port: 8080, ICMPType: &icmpType},
+				}}
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("icmp type and code may not be specified for protocol tcp"))
+			})
+		})
+
 		Context("when a tag is supplied", func() {
 			It("returns a useful error", func() {
 				policies := []models.Policy{
@@ -164,5 +257,81 @@ var _ = Describe("Validator", func() {
 				Expect(err).To(MatchError("tags may not be specified"))
 			})
 		})
+
+		Context("when an expiry is supplied", func() {
+			var policies []models.Policy
+
+			BeforeEach(func() {
+				policies = []models.Policy{{
+					Source:      models.Source{ID: "foo"},
+					Destination: models.Destination{ID: "bar", Protocol: "tcp", Port: 42},
+				}}
+			})
+
+			It("accepts an expiry in the future", func() {
+				expiresAt := time.Now().Add(time.Hour)
+				policies[0].ExpiresAt = &expiresAt
+				Expect(validator.ValidatePolicies(policies)).To(Succeed())
+			})
+
+			It("rejects an expiry in the past", func() {
+				expiresAt := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+				policies[0].ExpiresAt = &expiresAt
+				err := validator.ValidatePolicies(policies)
+				Expect(err).To(MatchError("invalid expires_at 2001-02-03T04:05:06Z, must be in the future"))
+			})
+		})
+
+		Context("when a description and labels are supplied", func() {
+			var policies []models.Policy
+
+			BeforeEach(func() {
+				policies = []models.Policy{{
+					Source:      models.Source{ID: "foo"},
+					Destination: models.Destination{ID: "bar", Protocol: "tcp", Port: 42},
+					Description: "lets the frontend reach the payments api",
+					Labels:      map[string]string{"team": "payments", "ticket.id": "NET-123"},
+				}}
+			})
+
+			It("accepts them", func() {
+				Expect(validator.ValidatePolicies(policies)).To(Succeed())
+			})
+
+			Context("when the description is too long", func() {
+				It("returns a useful error", func() {
+					policies[0].Description = strings.Repeat("a", 256)
+					err := validator.ValidatePolicies(policies)
+					Expect(err).To(MatchError("invalid description, must be at most 255 characters"))
+				})
+			})
+
+			Context("when a label key is invalid", func() {
+				It("returns a useful error", func() {
+					policies[0].Labels = map[string]string{"not valid": "value"}
+					err := validator.ValidatePolicies(policies)
+					Expect(err).To(MatchError(`invalid label key "not valid", must be 1-63 alphanumeric characters, '-', '_' or '.'`))
+				})
+			})
+
+			Context("when a label value is too long", func() {
+				It("returns a useful error", func() {
+					policies[0].Labels = map[string]string{"team": strings.Repeat("a", 256)}
+					err := validator.ValidatePolicies(policies)
+					Expect(err).To(MatchError(`invalid value for label "team", must be at most 255 characters`))
+				})
+			})
+
+			Context("when there are too many labels", func() {
+				It("returns a useful error", func() {
+					policies[0].Labels = map[string]string{}
+					for i := 0; i < 17; i++ {
+						policies[0].Labels[fmt.Sprintf("label-%d", i)] = "value"
+					}
+					err := validator.ValidatePolicies(policies)
+					Expect(err).To(MatchError("too many labels, at most 16 may be specified"))
+				})
+			})
+		})
 	})
 })
@@ -645,7 +645,7 @@ var _ = Describe("External API", func() {
 				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
 				responseString, err := ioutil.ReadAll(resp.Body)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(responseString).To(MatchJSON(`{ "error": "policies-create: invalid destination protocol, specify either udp or tcp" }`))
+				Expect(responseString).To(MatchJSON(`{ "error": "policies-create: invalid destination protocol, specify one of udp, tcp, icmp or all" }`))
 			})
 		})
 		Context("when the port is invalid", func() {
@@ -986,5 +986,39 @@ var _ = Describe("External API", func() {
 
 			Expect(resp.StatusCode).To(Equal(http.StatusOK))
 		})
+
+		It("returns 200 from the readiness endpoint when the database is reachable", func() {
+			resp := helpers.MakeAndDoRequest(
+				"GET",
+				fmt.Sprintf("http://%s:%d/ready", conf.ListenHost, conf.ListenPort),
+				nil,
+			)
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			responseBytes, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(responseBytes).To(MatchJSON(`{"ready": true}`))
+		})
+
+		It("reports exactly one of the servers as leader", func() {
+			leaders := 0
+			for _, c := range policyServerConfs {
+				resp := helpers.MakeAndDoRequest(
+					"GET",
+					fmt.Sprintf("http://%s:%d/health", c.ListenHost, c.ListenPort),
+					nil,
+				)
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				var health struct {
+					Leader bool `json:"leader"`
+				}
+				Expect(json.NewDecoder(resp.Body).Decode(&health)).To(Succeed())
+				if health.Leader {
+					leaders++
+				}
+			}
+			Expect(leaders).To(Equal(1))
+		})
 	})
 })
@@ -98,6 +98,34 @@ var _ = Describe("Internal API", func() {
 		`))
 	})
 
+	It("lists the tags of the apps with policies", func() {
+		body := strings.NewReader(`{ "policies": [
+				 {"source": { "id": "app1" }, "destination": { "id": "app2", "protocol": "tcp", "port": 8080 } }
+				 ]}
+				`)
+
+		_ = helpers.MakeAndDoRequest(
+			"POST",
+			fmt.Sprintf("http://%s:%d/networking/v0/external/policies", conf.ListenHost, conf.ListenPort),
+			body,
+		)
+
+		resp := helpers.MakeAndDoHTTPSRequest(
+			"GET",
+			fmt.Sprintf("https://%s:%d/networking/v0/internal/tags", conf.ListenHost, conf.InternalListenPort),
+			nil,
+			tlsConfig,
+		)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		responseString, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responseString).To(MatchJSON(`{ "tags": [
+				{ "id": "app1", "tag": "0001" },
+				{ "id": "app2", "tag": "0002" }
+			]}
+		`))
+	})
+
 	It("emits metrics about durations", func() {
 		resp := helpers.MakeAndDoHTTPSRequest(
 			"GET",
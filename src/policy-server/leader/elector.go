@@ -0,0 +1,97 @@
+package leader
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/leader_lock.go --fake-name LeaderLock . leaderLock
+type leaderLock interface {
+	TryAcquire(owner string, now time.Time, ttl time.Duration) (bool, error)
+	Release(owner string) error
+}
+
+//go:generate counterfeiter -o fakes/clock.go --fake-name Clock . clock
+type clock interface {
+	Now() time.Time
+}
+
+// Elector holds a lease on a lock row shared by all policy-server instances
+// and renews it every RenewInterval. Singleton jobs wrapped with LeaderOnly
+// only run on the instance currently holding the lease. OnElected, if set,
+// runs each time the instance becomes the leader, before any of them.
+type Elector struct {
+	Logger        lager.Logger
+	Lock          leaderLock
+	Clock         clock
+	Owner         string
+	LeaseDuration time.Duration
+	RenewInterval time.Duration
+	OnElected     func()
+
+	leader int32
+}
+
+func (e *Elector) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	e.attempt()
+	close(ready)
+
+	for {
+		select {
+		case <-signals:
+			if e.IsLeader() {
+				e.setLeader(false)
+				if err := e.Lock.Release(e.Owner); err != nil {
+					e.Logger.Error("release-lock-failed", err)
+				}
+			}
+			return nil
+		case <-time.After(e.RenewInterval):
+			e.attempt()
+		}
+	}
+}
+
+func (e *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+func (e *Elector) LeaderOnly(singleCycleFunc func() error) func() error {
+	return func() error {
+		if !e.IsLeader() {
+			e.Logger.Debug("skipping-cycle-not-leader")
+			return nil
+		}
+		return singleCycleFunc()
+	}
+}
+
+func (e *Elector) attempt() {
+	acquired, err := e.Lock.TryAcquire(e.Owner, e.Clock.Now(), e.LeaseDuration)
+	if err != nil {
+		e.Logger.Error("acquire-lock-failed", err)
+		acquired = false
+	}
+
+	wasLeader := e.IsLeader()
+	if acquired && !wasLeader {
+		if e.OnElected != nil {
+			e.OnElected()
+		}
+		e.Logger.Info("became-leader", lager.Data{"owner": e.Owner})
+	} else if !acquired && wasLeader {
+		e.Logger.Info("lost-leadership", lager.Data{"owner": e.Owner})
+	}
+	e.setLeader(acquired)
+}
+
+func (e *Elector) setLeader(leader bool) {
+	var value int32
+	if leader {
+		value = 1
+	}
+	atomic.StoreInt32(&e.leader, value)
+}
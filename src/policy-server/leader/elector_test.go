@@ -0,0 +1,148 @@
+package leader_test
+
+import (
+	"errors"
+	"os"
+	"policy-server/leader"
+	"policy-server/leader/fakes"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Elector", func() {
+	var (
+		elector   *leader.Elector
+		fakeLock  *fakes.LeaderLock
+		fakeClock *fakes.Clock
+		logger    *lagertest.TestLogger
+		now       time.Time
+	)
+
+	BeforeEach(func() {
+		fakeLock = &fakes.LeaderLock{}
+		fakeClock = &fakes.Clock{}
+		logger = lagertest.NewTestLogger("test")
+		now = time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+		fakeClock.NowReturns(now)
+
+		elector = &leader.Elector{
+			Logger:        logger,
+			Lock:          fakeLock,
+			Clock:         fakeClock,
+			Owner:         "some-instance",
+			LeaseDuration: 15 * time.Second,
+			RenewInterval: 10 * time.Millisecond,
+		}
+	})
+
+	Context("when the lock is acquired", func() {
+		BeforeEach(func() {
+			fakeLock.TryAcquireReturns(true, nil)
+		})
+
+		It("becomes leader before signalling ready and keeps renewing the lease", func() {
+			process := ifrit.Invoke(elector)
+
+			Expect(elector.IsLeader()).To(BeTrue())
+			owner, acquiredAt, ttl := fakeLock.TryAcquireArgsForCall(0)
+			Expect(owner).To(Equal("some-instance"))
+			Expect(acquiredAt).To(Equal(now))
+			Expect(ttl).To(Equal(15 * time.Second))
+			Expect(logger).To(gbytes.Say("became-leader.*some-instance"))
+
+			Eventually(fakeLock.TryAcquireCallCount).Should(BeNumerically(">", 2))
+
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+
+			Expect(fakeLock.ReleaseCallCount()).To(Equal(1))
+			Expect(fakeLock.ReleaseArgsForCall(0)).To(Equal("some-instance"))
+			Expect(elector.IsLeader()).To(BeFalse())
+		})
+
+		It("runs leader only cycles", func() {
+			process := ifrit.Invoke(elector)
+			defer process.Signal(os.Interrupt)
+
+			cycleErr := errors.New("banana")
+			calls := 0
+			err := elector.LeaderOnly(func() error {
+				calls++
+				return cycleErr
+			})()
+			Expect(err).To(Equal(cycleErr))
+			Expect(calls).To(Equal(1))
+		})
+	})
+
+	Context("when another instance holds the lock", func() {
+		BeforeEach(func() {
+			fakeLock.TryAcquireReturns(false, nil)
+		})
+
+		It("is not leader and does not release the lock on exit", func() {
+			process := ifrit.Invoke(elector)
+			Expect(elector.IsLeader()).To(BeFalse())
+
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+			Expect(fakeLock.ReleaseCallCount()).To(Equal(0))
+		})
+
+		It("skips leader only cycles", func() {
+			process := ifrit.Invoke(elector)
+			defer process.Signal(os.Interrupt)
+
+			calls := 0
+			err := elector.LeaderOnly(func() error {
+				calls++
+				return nil
+			})()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(calls).To(Equal(0))
+		})
+	})
+
+	Context("when the instance becomes the leader again", func() {
+		var elections chan bool
+
+		BeforeEach(func() {
+			fakeLock.TryAcquireReturns(true, nil)
+			fakeLock.TryAcquireReturnsOnCall(1, false, nil)
+			elections = make(chan bool, 10)
+			elector.OnElected = func() {
+				elections <- elector.IsLeader()
+			}
+		})
+
+		It("runs OnElected on each election, before it becomes the leader", func() {
+			process := ifrit.Invoke(elector)
+			defer process.Signal(os.Interrupt)
+
+			Eventually(elections).Should(Receive(BeFalse()))
+			Eventually(elections).Should(Receive(BeFalse()))
+			Eventually(fakeLock.TryAcquireCallCount).Should(BeNumerically(">", 4))
+			Consistently(elections).ShouldNot(Receive())
+		})
+	})
+
+	Context("when the leader can no longer renew the lease", func() {
+		BeforeEach(func() {
+			fakeLock.TryAcquireReturns(true, nil)
+			fakeLock.TryAcquireReturnsOnCall(1, false, errors.New("banana"))
+		})
+
+		It("steps down and logs the error", func() {
+			process := ifrit.Invoke(elector)
+			defer process.Signal(os.Interrupt)
+
+			Eventually(logger).Should(gbytes.Say("acquire-lock-failed.*banana"))
+			Eventually(logger).Should(gbytes.Say("lost-leadership"))
+		})
+	})
+})
@@ -3,11 +3,20 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"time"
 )
 
 type Policy struct {
-	Source      Source      `json:"source"`
-	Destination Destination `json:"destination"`
+	Source      Source            `json:"source"`
+	Destination Destination       `json:"destination"`
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the policy has an expiry at or before now.
+func (p Policy) Expired(now time.Time) bool {
+	return p.ExpiresAt != nil && !p.ExpiresAt.After(now)
 }
 
 type Source struct {
@@ -15,12 +24,24 @@ type Source struct {
 	Tag string `json:"tag,omitempty"`
 }
 
+const (
+	ProtocolTCP  = "tcp"
+	ProtocolUDP  = "udp"
+	ProtocolICMP = "icmp"
+	ProtocolAll  = "all"
+)
+
+// Destination ports only apply to tcp and udp. ICMP destinations may instead
+// narrow the policy to an ICMP type, and to a code within that type; leaving
+// them unset allows every type or code.
 type Destination struct {
 	ID       string `json:"id"`
 	Tag      string `json:"tag,omitempty"`
 	Protocol string `json:"protocol"`
 	Port     int    `json:"port,omitempty"`
 	Ports    Ports  `json:"ports"`
+	ICMPType *int   `json:"icmp_type,omitempty"`
+	ICMPCode *int   `json:"icmp_code,omitempty"`
 }
 
 type Ports struct {
@@ -33,6 +54,57 @@ type Tag struct {
 	Tag string `json:"tag"`
 }
 
+const (
+	QuotaScopeOrg   = "org"
+	QuotaScopeSpace = "space"
+)
+
+type Quota struct {
+	Scope                    string `json:"scope"`
+	GUID                     string `json:"guid"`
+	MaxPolicies              int    `json:"max_policies"`
+	MaxSourcesPerDestination int    `json:"max_sources_per_destination"`
+}
+
+type StaleApp struct {
+	ID           string    `json:"id"`
+	MissingRuns  int       `json:"missing_runs"`
+	MissingSince time.Time `json:"missing_since"`
+	Reason       string    `json:"reason"`
+}
+
+type CleanupReport struct {
+	DryRun      bool
+	Policies    []Policy
+	StaleApps   []StaleApp
+	PendingApps []StaleApp
+}
+
+const (
+	EgressSourceTypeApp   = "app"
+	EgressSourceTypeSpace = "space"
+)
+
+type EgressPolicy struct {
+	Source      EgressSource      `json:"source"`
+	Destination EgressDestination `json:"destination"`
+}
+
+type EgressSource struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// EgressDestination ports are a range for tcp and udp, and allow every port
+// when both are 0.
+type EgressDestination struct {
+	Protocol string `json:"protocol"`
+	CIDR     string `json:"cidr"`
+	Ports    Ports  `json:"ports"`
+	ICMPType *int   `json:"icmp_type,omitempty"`
+	ICMPCode *int   `json:"icmp_code,omitempty"`
+}
+
 type Space struct {
 	Name    string `json:name`
 	OrgGUID string `json:organization_guid`
@@ -44,6 +116,8 @@ type destination struct {
 	Protocol string `json:"protocol"`
 	Port     int    `json:"port,omitempty"`
 	Ports    Ports  `json:"ports"`
+	ICMPType *int   `json:"icmp_type,omitempty"`
+	ICMPCode *int   `json:"icmp_code,omitempty"`
 }
 
 func fixPorts(d *Destination) error {
@@ -71,6 +145,8 @@ func (d Destination) MarshalJSON() ([]byte, error) {
 		Protocol: d.Protocol,
 		Port:     d.Port,
 		Ports:    d.Ports,
+		ICMPType: d.ICMPType,
+		ICMPCode: d.ICMPCode,
 	}
 
 	return json.Marshal(dest) // error not tested
@@ -89,6 +165,8 @@ func (d *Destination) UnmarshalJSON(input []byte) error {
 	d.Protocol = dest.Protocol
 	d.Port = dest.Port
 	d.Ports = dest.Ports
+	d.ICMPType = dest.ICMPType
+	d.ICMPCode = dest.ICMPCode
 
 	err = fixPorts(d)
 	if err != nil {
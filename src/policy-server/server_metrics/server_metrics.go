@@ -11,6 +11,30 @@ type store interface {
 	All() ([]models.Policy, error)
 }
 
+//go:generate counterfeiter -o fakes/cleanup_reporter.go --fake-name CleanupReporter . cleanupReporter
+type cleanupReporter interface {
+	LastReport() models.CleanupReport
+}
+
+//go:generate counterfeiter -o fakes/leadership.go --fake-name Leadership . leadership
+type leadership interface {
+	IsLeader() bool
+}
+
+// LeaderOnly reports source on the leader only, and 0 on the other instances,
+// so that the sum across instances is the value of the source. The other
+// instances do not call the source at all.
+func LeaderOnly(leadership leadership, source metrics.MetricSource) metrics.MetricSource {
+	getter := source.Getter
+	source.Getter = func() (float64, error) {
+		if !leadership.IsLeader() {
+			return 0, nil
+		}
+		return getter()
+	}
+	return source
+}
+
 func NewTotalPoliciesSource(lister store) metrics.MetricSource {
 	return metrics.MetricSource{
 		Name: "totalPolicies",
@@ -21,3 +45,23 @@ func NewTotalPoliciesSource(lister store) metrics.MetricSource {
 		},
 	}
 }
+
+func NewStalePoliciesSource(reporter cleanupReporter) metrics.MetricSource {
+	return metrics.MetricSource{
+		Name: "stalePolicies",
+		Unit: "",
+		Getter: func() (float64, error) {
+			return float64(len(reporter.LastReport().Policies)), nil
+		},
+	}
+}
+
+func NewPendingStaleAppsSource(reporter cleanupReporter) metrics.MetricSource {
+	return metrics.MetricSource{
+		Name: "pendingStaleApps",
+		Unit: "",
+		Getter: func() (float64, error) {
+			return float64(len(reporter.LastReport().PendingApps)), nil
+		},
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"policy-server/server_metrics"
 	"policy-server/server_metrics/fakes"
 
+	"code.cloudfoundry.org/cf-networking-helpers/metrics"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -50,3 +52,78 @@ var _ = Describe("NewTotalPoliciesSource", func() {
 		})
 	})
 })
+
+var _ = Describe("Cleanup report sources", func() {
+	var fakeReporter *fakes.CleanupReporter
+
+	BeforeEach(func() {
+		fakeReporter = &fakes.CleanupReporter{}
+		fakeReporter.LastReportReturns(models.CleanupReport{
+			Policies:    []models.Policy{{}, {}, {}},
+			StaleApps:   []models.StaleApp{{ID: "some-app-guid"}},
+			PendingApps: []models.StaleApp{{ID: "another-app-guid"}, {ID: "yet-another-app-guid"}},
+		})
+	})
+
+	Describe("NewStalePoliciesSource", func() {
+		It("returns the number of stale policies found by the last cleanup", func() {
+			source := server_metrics.NewStalePoliciesSource(fakeReporter)
+			Expect(source.Name).To(Equal("stalePolicies"))
+
+			value, err := source.Getter()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal(3.0))
+		})
+	})
+
+	Describe("NewPendingStaleAppsSource", func() {
+		It("returns the number of missing apps still within the grace period", func() {
+			source := server_metrics.NewPendingStaleAppsSource(fakeReporter)
+			Expect(source.Name).To(Equal("pendingStaleApps"))
+
+			value, err := source.Getter()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal(2.0))
+		})
+	})
+})
+
+var _ = Describe("LeaderOnly", func() {
+	var (
+		fakeLeadership *fakes.Leadership
+		fakeDataStore  *fakes.Store
+		source         metrics.MetricSource
+	)
+
+	BeforeEach(func() {
+		fakeLeadership = &fakes.Leadership{}
+		fakeDataStore = &fakes.Store{}
+		fakeDataStore.AllReturns([]models.Policy{{}, {}}, nil)
+		source = server_metrics.LeaderOnly(fakeLeadership, server_metrics.NewTotalPoliciesSource(fakeDataStore))
+	})
+
+	It("keeps the name of the source", func() {
+		Expect(source.Name).To(Equal("totalPolicies"))
+	})
+
+	Context("when the instance is the leader", func() {
+		BeforeEach(func() {
+			fakeLeadership.IsLeaderReturns(true)
+		})
+
+		It("returns the value of the source", func() {
+			value, err := source.Getter()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal(2.0))
+		})
+	})
+
+	Context("when the instance is not the leader", func() {
+		It("returns 0 without calling the source", func() {
+			value, err := source.Getter()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal(0.0))
+			Expect(fakeDataStore.AllCallCount()).To(Equal(0))
+		})
+	})
+})
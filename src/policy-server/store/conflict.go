@@ -0,0 +1,58 @@
+package store
+
+import (
+	"math/rand"
+	"policy-server/store/helpers"
+	"strings"
+	"time"
+)
+
+const (
+	conflictMaxAttempts = 5
+	conflictBackoff     = 20 * time.Millisecond
+)
+
+// conflictErrors are the messages, per dialect, of errors caused by another
+// transaction touching the same rows concurrently: deadlocks, lock wait
+// timeouts, serialization failures and unique constraint violations from
+// racing inserts. Retrying the whole transaction resolves them.
+var conflictErrors = map[string][]string{
+	helpers.MySQL: []string{
+		"Error 1213:",
+		"Error 1205:",
+		"Error 1062:",
+	},
+	helpers.Postgres: []string{
+		"deadlock detected",
+		"could not serialize access",
+		"duplicate key value violates unique constraint",
+	},
+}
+
+func isConflictError(err error, dialect string) bool {
+	message := err.Error()
+	for _, conflict := range conflictErrors[dialect] {
+		if strings.Contains(message, conflict) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryOnConflict calls transaction until it succeeds, fails with an error
+// that is not a conflict, or conflictMaxAttempts is reached. Between attempts
+// it sleeps a random duration up to an exponentially growing bound, so that
+// transactions that collided once are unlikely to collide again.
+func (s *store) retryOnConflict(transaction func() error) error {
+	var err error
+	for attempt := 0; attempt < s.conflictMaxAttempts; attempt++ {
+		if attempt > 0 {
+			s.sleeper.Sleep(time.Duration(rand.Int63n(int64(s.conflictBackoff << uint(attempt)))))
+		}
+		err = transaction()
+		if err == nil || !isConflictError(err, s.conn.DriverName()) {
+			return err
+		}
+	}
+	return err
+}
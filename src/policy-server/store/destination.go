@@ -2,36 +2,40 @@ package store
 
 //go:generate counterfeiter -o fakes/destination_repo.go --fake-name DestinationRepo . DestinationRepo
 type DestinationRepo interface {
-	Create(Transaction, int, int, string) (int, error)
+	Create(Transaction, int, int, string, int, int) (int, error)
 	Delete(Transaction, int) error
-	GetID(Transaction, int, int, string) (int, error)
+	GetID(Transaction, int, int, string, int, int) (int, error)
 	CountWhereGroupID(Transaction, int) (int, error)
 }
 
 type Destination struct {
 }
 
-func (d *Destination) Create(tx Transaction, destination_group_id int, port int, protocol string) (int, error) {
+func (d *Destination) Create(tx Transaction, destination_group_id int, port int, protocol string, icmp_type int, icmp_code int) (int, error) {
 	_, err := tx.Exec(tx.Rebind(`
-		INSERT INTO destinations (group_id, port, protocol)
-		SELECT ?, ?, ?
+		INSERT INTO destinations (group_id, port, protocol, icmp_type, icmp_code)
+		SELECT ?, ?, ?, ?, ?
 		WHERE
 		NOT EXISTS (
 			SELECT *
 			FROM destinations
-			WHERE group_id = ? AND port = ? AND protocol = ?
+			WHERE group_id = ? AND port = ? AND protocol = ? AND icmp_type = ? AND icmp_code = ?
 		)`),
 		destination_group_id,
 		port,
 		protocol,
+		icmp_type,
+		icmp_code,
 		destination_group_id,
 		port,
 		protocol,
+		icmp_type,
+		icmp_code,
 	)
 	if err != nil {
 		return -1, err
 	}
-	id, err := d.GetID(tx, destination_group_id, port, protocol)
+	id, err := d.GetID(tx, destination_group_id, port, protocol, icmp_type, icmp_code)
 	return id, err
 }
 
@@ -43,14 +47,16 @@ func (d *Destination) Delete(tx Transaction, id int) error {
 	return err
 }
 
-func (d *Destination) GetID(tx Transaction, destination_group_id int, port int, protocol string) (int, error) {
+func (d *Destination) GetID(tx Transaction, destination_group_id int, port int, protocol string, icmp_type int, icmp_code int) (int, error) {
 	var id int
 	err := tx.QueryRow(tx.Rebind(`
 		SELECT id FROM destinations
-		WHERE group_id = ? AND port = ? AND protocol = ? FOR UPDATE`),
+		WHERE group_id = ? AND port = ? AND protocol = ? AND icmp_type = ? AND icmp_code = ? FOR UPDATE`),
 		destination_group_id,
 		port,
 		protocol,
+		icmp_type,
+		icmp_code,
 	).Scan(&id)
 	return id, err
 }
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"policy-server/models"
+	"policy-server/store/helpers"
+)
+
+//go:generate counterfeiter -o fakes/egress_store.go --fake-name EgressStore . EgressStore
+type EgressStore interface {
+	Create([]models.EgressPolicy) error
+	Delete([]models.EgressPolicy) error
+	All() ([]models.EgressPolicy, error)
+	BySourceGuids([]string) ([]models.EgressPolicy, error)
+}
+
+type egressStore struct {
+	conn db
+}
+
+// NewEgressStore expects the egress_policies table to have been created by New.
+func NewEgressStore(dbConnectionPool db) EgressStore {
+	return &egressStore{
+		conn: dbConnectionPool,
+	}
+}
+
+func (e *egressStore) Create(policies []models.EgressPolicy) error {
+	tx, err := e.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %s", err)
+	}
+
+	// a single statement per policy, so that concurrent creates of the same
+	// policy leave it be rather than racing on the unique key
+	query := insertEgressPolicyQuery(e.conn.DriverName())
+	for _, policy := range policies {
+		_, err = tx.Exec(tx.Rebind(query), egressPolicyColumns(policy)...)
+		if err != nil {
+			return rollback(tx, fmt.Errorf("creating egress policy: %s", err))
+		}
+	}
+
+	return commit(tx)
+}
+
+func insertEgressPolicyQuery(driverName string) string {
+	if driverName == helpers.Postgres {
+		return `INSERT INTO egress_policies
+			(source_guid, source_type, protocol, cidr, start_port, end_port, icmp_type, icmp_code)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT DO NOTHING`
+	}
+	return `INSERT INTO egress_policies
+		(source_guid, source_type, protocol, cidr, start_port, end_port, icmp_type, icmp_code)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = id`
+}
+
+func (e *egressStore) Delete(policies []models.EgressPolicy) error {
+	tx, err := e.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %s", err)
+	}
+
+	for _, policy := range policies {
+		_, err = tx.Exec(
+			tx.Rebind(`DELETE FROM egress_policies
+			WHERE source_guid = ? AND source_type = ? AND protocol = ? AND cidr = ?
+			AND start_port = ? AND end_port = ? AND icmp_type = ? AND icmp_code = ?`),
+			egressPolicyColumns(policy)...,
+		)
+		if err != nil {
+			return rollback(tx, fmt.Errorf("deleting egress policy: %s", err))
+		}
+	}
+
+	return commit(tx)
+}
+
+func (e *egressStore) All() ([]models.EgressPolicy, error) {
+	return e.egressPoliciesQuery(`
+		SELECT source_guid, source_type, protocol, cidr, start_port, end_port, icmp_type, icmp_code
+		FROM egress_policies
+		ORDER BY id;`)
+}
+
+func (e *egressStore) BySourceGuids(guids []string) ([]models.EgressPolicy, error) {
+	if len(guids) == 0 {
+		return []models.EgressPolicy{}, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT source_guid, source_type, protocol, cidr, start_port, end_port, icmp_type, icmp_code
+		FROM egress_policies
+		WHERE source_guid IN (%s)
+		ORDER BY id;`, helpers.QuestionMarks(len(guids)))
+
+	bindings := make([]interface{}, len(guids))
+	for i, guid := range guids {
+		bindings[i] = guid
+	}
+
+	return e.egressPoliciesQuery(helpers.RebindForSQLDialect(query, e.conn.DriverName()), bindings...)
+}
+
+func (e *egressStore) egressPoliciesQuery(query string, args ...interface{}) ([]models.EgressPolicy, error) {
+	policies := []models.EgressPolicy{}
+
+	rows, err := e.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing egress policies: %s", err)
+	}
+
+	defer rows.Close() // untested
+	for rows.Next() {
+		var policy models.EgressPolicy
+		var icmpType, icmpCode sql.NullInt64
+		err = rows.Scan(
+			&policy.Source.ID,
+			&policy.Source.Type,
+			&policy.Destination.Protocol,
+			&policy.Destination.CIDR,
+			&policy.Destination.Ports.Start,
+			&policy.Destination.Ports.End,
+			&icmpType,
+			&icmpCode,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("listing egress policies: %s", err)
+		}
+		policy.Destination.ICMPType, policy.Destination.ICMPCode = icmpFields(icmpType, icmpCode)
+		policies = append(policies, policy)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("listing egress policies, getting next row: %s", err) // untested
+	}
+
+	return policies, nil
+}
+
+func egressPolicyColumns(policy models.EgressPolicy) []interface{} {
+	icmpType, icmpCode := icmpColumns(policy.Destination.ICMPType, policy.Destination.ICMPCode)
+	return []interface{}{
+		policy.Source.ID,
+		policy.Source.Type,
+		policy.Destination.Protocol,
+		policy.Destination.CIDR,
+		policy.Destination.Ports.Start,
+		policy.Destination.Ports.End,
+		icmpType,
+		icmpCode,
+	}
+}
@@ -0,0 +1,154 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"policy-server/models"
+	"policy-server/store"
+	"policy-server/store/fakes"
+	"time"
+
+	"code.cloudfoundry.org/cf-networking-helpers/db"
+	"code.cloudfoundry.org/cf-networking-helpers/testsupport"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EgressStore", func() {
+	var (
+		egressStore store.EgressStore
+		dbConf      db.Config
+		realDb      *sqlx.DB
+
+		appPolicy   models.EgressPolicy
+		spacePolicy models.EgressPolicy
+		icmpPolicy  models.EgressPolicy
+	)
+
+	BeforeEach(func() {
+		dbConf = testsupport.GetDBConfig()
+		dbConf.DatabaseName = fmt.Sprintf("test_node_%d", GinkgoParallelNode())
+
+		testsupport.CreateDatabase(dbConf)
+
+		var err error
+		realDb, err = db.GetConnectionPool(dbConf)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = store.New(realDb, &store.Group{}, &store.Destination{}, &store.Policy{}, 1, 2*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+
+		egressStore = store.NewEgressStore(realDb)
+
+		echoRequest := 8
+		appPolicy = models.EgressPolicy{
+			Source:      models.EgressSource{ID: "some-app-guid", Type: "app"},
+			Destination: models.EgressDestination{Protocol: "tcp", CIDR: "10.0.0.0/8", Ports: models.Ports{Start: 8080, End: 8090}},
+		}
+		spacePolicy = models.EgressPolicy{
+			Source:      models.EgressSource{ID: "some-space-guid", Type: "space"},
+			Destination: models.EgressDestination{Protocol: "all", CIDR: "0.0.0.0/0"},
+		}
+		icmpPolicy = models.EgressPolicy{
+			Source:      models.EgressSource{ID: "some-app-guid", Type: "app"},
+			Destination: models.EgressDestination{Protocol: "icmp", CIDR: "10.0.0.1/32", ICMPType: &echoRequest},
+		}
+	})
+
+	AfterEach(func() {
+		if realDb != nil {
+			Expect(realDb.Close()).To(Succeed())
+		}
+		testsupport.RemoveDatabase(dbConf)
+	})
+
+	Describe("Create", func() {
+		It("saves the egress policies", func() {
+			err := egressStore.Create([]models.EgressPolicy{appPolicy, spacePolicy, icmpPolicy})
+			Expect(err).NotTo(HaveOccurred())
+
+			policies, err := egressStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(Equal([]models.EgressPolicy{appPolicy, spacePolicy, icmpPolicy}))
+		})
+
+		It("does not duplicate an existing egress policy", func() {
+			err := egressStore.Create([]models.EgressPolicy{appPolicy, icmpPolicy})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = egressStore.Create([]models.EgressPolicy{appPolicy, icmpPolicy})
+			Expect(err).NotTo(HaveOccurred())
+
+			policies, err := egressStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(HaveLen(2))
+		})
+
+		It("does not fail when the same egress policy is created concurrently", func() {
+			errs := make(chan error, 10)
+			for i := 0; i < 10; i++ {
+				go func() {
+					errs <- egressStore.Create([]models.EgressPolicy{appPolicy, icmpPolicy})
+				}()
+			}
+			for i := 0; i < 10; i++ {
+				Expect(<-errs).NotTo(HaveOccurred())
+			}
+
+			policies, err := egressStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(ConsistOf(appPolicy, icmpPolicy))
+		})
+
+		Context("when a transaction cannot be started", func() {
+			It("returns an error", func() {
+				mockDb := &fakes.Db{}
+				mockDb.BeginxReturns(nil, errors.New("some-error"))
+
+				err := store.NewEgressStore(mockDb).Create([]models.EgressPolicy{appPolicy})
+				Expect(err).To(MatchError("begin transaction: some-error"))
+			})
+		})
+	})
+
+	Describe("BySourceGuids", func() {
+		BeforeEach(func() {
+			err := egressStore.Create([]models.EgressPolicy{appPolicy, spacePolicy, icmpPolicy})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the egress policies of the given apps and spaces", func() {
+			policies, err := egressStore.BySourceGuids([]string{"some-space-guid"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(Equal([]models.EgressPolicy{spacePolicy}))
+
+			policies, err = egressStore.BySourceGuids([]string{"some-app-guid", "some-space-guid"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(Equal([]models.EgressPolicy{appPolicy, spacePolicy, icmpPolicy}))
+		})
+
+		It("returns no egress policies when no guids are given", func() {
+			policies, err := egressStore.BySourceGuids(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(BeEmpty())
+		})
+	})
+
+	Describe("Delete", func() {
+		BeforeEach(func() {
+			err := egressStore.Create([]models.EgressPolicy{appPolicy, spacePolicy, icmpPolicy})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("deletes only the matching egress policies", func() {
+			err := egressStore.Delete([]models.EgressPolicy{icmpPolicy, spacePolicy})
+			Expect(err).NotTo(HaveOccurred())
+
+			policies, err := egressStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policies).To(Equal([]models.EgressPolicy{appPolicy}))
+		})
+	})
+})
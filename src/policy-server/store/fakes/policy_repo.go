@@ -4,6 +4,7 @@ package fakes
 import (
 	"policy-server/store"
 	"sync"
+	"time"
 )
 
 type PolicyRepo struct {
@@ -20,6 +21,51 @@ type PolicyRepo struct {
 	createReturnsOnCall map[int]struct {
 		result1 error
 	}
+	SetMetadataStub        func(store.Transaction, int, int, string, map[string]string) error
+	setMetadataMutex       sync.RWMutex
+	setMetadataArgsForCall []struct {
+		arg1 store.Transaction
+		arg2 int
+		arg3 int
+		arg4 string
+		arg5 map[string]string
+	}
+	setMetadataReturns struct {
+		result1 error
+	}
+	setMetadataReturnsOnCall map[int]struct {
+		result1 error
+	}
+	SetExpiryStub        func(store.Transaction, int, int, *time.Time) error
+	setExpiryMutex       sync.RWMutex
+	setExpiryArgsForCall []struct {
+		arg1 store.Transaction
+		arg2 int
+		arg3 int
+		arg4 *time.Time
+	}
+	setExpiryReturns struct {
+		result1 error
+	}
+	setExpiryReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteExpiryIfExpiredStub        func(store.Transaction, int, int, time.Time) (bool, error)
+	deleteExpiryIfExpiredMutex       sync.RWMutex
+	deleteExpiryIfExpiredArgsForCall []struct {
+		arg1 store.Transaction
+		arg2 int
+		arg3 int
+		arg4 time.Time
+	}
+	deleteExpiryIfExpiredReturns struct {
+		result1 bool
+		result2 error
+	}
+	deleteExpiryIfExpiredReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	DeleteStub        func(store.Transaction, int, int) error
 	deleteMutex       sync.RWMutex
 	deleteArgsForCall []struct {
@@ -115,6 +161,163 @@ func (fake *PolicyRepo) CreateReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *PolicyRepo) SetMetadata(arg1 store.Transaction, arg2 int, arg3 int, arg4 string, arg5 map[string]string) error {
+	fake.setMetadataMutex.Lock()
+	ret, specificReturn := fake.setMetadataReturnsOnCall[len(fake.setMetadataArgsForCall)]
+	fake.setMetadataArgsForCall = append(fake.setMetadataArgsForCall, struct {
+		arg1 store.Transaction
+		arg2 int
+		arg3 int
+		arg4 string
+		arg5 map[string]string
+	}{arg1, arg2, arg3, arg4, arg5})
+	fake.recordInvocation("SetMetadata", []interface{}{arg1, arg2, arg3, arg4, arg5})
+	fake.setMetadataMutex.Unlock()
+	if fake.SetMetadataStub != nil {
+		return fake.SetMetadataStub(arg1, arg2, arg3, arg4, arg5)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.setMetadataReturns.result1
+}
+
+func (fake *PolicyRepo) SetMetadataCallCount() int {
+	fake.setMetadataMutex.RLock()
+	defer fake.setMetadataMutex.RUnlock()
+	return len(fake.setMetadataArgsForCall)
+}
+
+func (fake *PolicyRepo) SetMetadataArgsForCall(i int) (store.Transaction, int, int, string, map[string]string) {
+	fake.setMetadataMutex.RLock()
+	defer fake.setMetadataMutex.RUnlock()
+	return fake.setMetadataArgsForCall[i].arg1, fake.setMetadataArgsForCall[i].arg2, fake.setMetadataArgsForCall[i].arg3, fake.setMetadataArgsForCall[i].arg4, fake.setMetadataArgsForCall[i].arg5
+}
+
+func (fake *PolicyRepo) SetMetadataReturns(result1 error) {
+	fake.SetMetadataStub = nil
+	fake.setMetadataReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *PolicyRepo) SetMetadataReturnsOnCall(i int, result1 error) {
+	fake.SetMetadataStub = nil
+	if fake.setMetadataReturnsOnCall == nil {
+		fake.setMetadataReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.setMetadataReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *PolicyRepo) SetExpiry(arg1 store.Transaction, arg2 int, arg3 int, arg4 *time.Time) error {
+	fake.setExpiryMutex.Lock()
+	ret, specificReturn := fake.setExpiryReturnsOnCall[len(fake.setExpiryArgsForCall)]
+	fake.setExpiryArgsForCall = append(fake.setExpiryArgsForCall, struct {
+		arg1 store.Transaction
+		arg2 int
+		arg3 int
+		arg4 *time.Time
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("SetExpiry", []interface{}{arg1, arg2, arg3, arg4})
+	fake.setExpiryMutex.Unlock()
+	if fake.SetExpiryStub != nil {
+		return fake.SetExpiryStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.setExpiryReturns.result1
+}
+
+func (fake *PolicyRepo) SetExpiryCallCount() int {
+	fake.setExpiryMutex.RLock()
+	defer fake.setExpiryMutex.RUnlock()
+	return len(fake.setExpiryArgsForCall)
+}
+
+func (fake *PolicyRepo) SetExpiryArgsForCall(i int) (store.Transaction, int, int, *time.Time) {
+	fake.setExpiryMutex.RLock()
+	defer fake.setExpiryMutex.RUnlock()
+	return fake.setExpiryArgsForCall[i].arg1, fake.setExpiryArgsForCall[i].arg2, fake.setExpiryArgsForCall[i].arg3, fake.setExpiryArgsForCall[i].arg4
+}
+
+func (fake *PolicyRepo) SetExpiryReturns(result1 error) {
+	fake.SetExpiryStub = nil
+	fake.setExpiryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *PolicyRepo) SetExpiryReturnsOnCall(i int, result1 error) {
+	fake.SetExpiryStub = nil
+	if fake.setExpiryReturnsOnCall == nil {
+		fake.setExpiryReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.setExpiryReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *PolicyRepo) DeleteExpiryIfExpired(arg1 store.Transaction, arg2 int, arg3 int, arg4 time.Time) (bool, error) {
+	fake.deleteExpiryIfExpiredMutex.Lock()
+	ret, specificReturn := fake.deleteExpiryIfExpiredReturnsOnCall[len(fake.deleteExpiryIfExpiredArgsForCall)]
+	fake.deleteExpiryIfExpiredArgsForCall = append(fake.deleteExpiryIfExpiredArgsForCall, struct {
+		arg1 store.Transaction
+		arg2 int
+		arg3 int
+		arg4 time.Time
+	}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("DeleteExpiryIfExpired", []interface{}{arg1, arg2, arg3, arg4})
+	fake.deleteExpiryIfExpiredMutex.Unlock()
+	if fake.DeleteExpiryIfExpiredStub != nil {
+		return fake.DeleteExpiryIfExpiredStub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.deleteExpiryIfExpiredReturns.result1, fake.deleteExpiryIfExpiredReturns.result2
+}
+
+func (fake *PolicyRepo) DeleteExpiryIfExpiredCallCount() int {
+	fake.deleteExpiryIfExpiredMutex.RLock()
+	defer fake.deleteExpiryIfExpiredMutex.RUnlock()
+	return len(fake.deleteExpiryIfExpiredArgsForCall)
+}
+
+func (fake *PolicyRepo) DeleteExpiryIfExpiredArgsForCall(i int) (store.Transaction, int, int, time.Time) {
+	fake.deleteExpiryIfExpiredMutex.RLock()
+	defer fake.deleteExpiryIfExpiredMutex.RUnlock()
+	return fake.deleteExpiryIfExpiredArgsForCall[i].arg1, fake.deleteExpiryIfExpiredArgsForCall[i].arg2, fake.deleteExpiryIfExpiredArgsForCall[i].arg3, fake.deleteExpiryIfExpiredArgsForCall[i].arg4
+}
+
+func (fake *PolicyRepo) DeleteExpiryIfExpiredReturns(result1 bool, result2 error) {
+	fake.DeleteExpiryIfExpiredStub = nil
+	fake.deleteExpiryIfExpiredReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *PolicyRepo) DeleteExpiryIfExpiredReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.DeleteExpiryIfExpiredStub = nil
+	if fake.deleteExpiryIfExpiredReturnsOnCall == nil {
+		fake.deleteExpiryIfExpiredReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.deleteExpiryIfExpiredReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *PolicyRepo) Delete(arg1 store.Transaction, arg2 int, arg3 int) error {
 	fake.deleteMutex.Lock()
 	ret, specificReturn := fake.deleteReturnsOnCall[len(fake.deleteArgsForCall)]
@@ -274,6 +477,12 @@ func (fake *PolicyRepo) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.createMutex.RLock()
 	defer fake.createMutex.RUnlock()
+	fake.setMetadataMutex.RLock()
+	defer fake.setMetadataMutex.RUnlock()
+	fake.setExpiryMutex.RLock()
+	defer fake.setExpiryMutex.RUnlock()
+	fake.deleteExpiryIfExpiredMutex.RLock()
+	defer fake.deleteExpiryIfExpiredMutex.RUnlock()
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
 	fake.countWhereGroupIDMutex.RLock()
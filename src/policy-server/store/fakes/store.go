@@ -5,6 +5,7 @@ import (
 	"policy-server/models"
 	"policy-server/store"
 	"sync"
+	"time"
 )
 
 type Store struct {
@@ -41,6 +42,19 @@ type Store struct {
 	deleteReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DeleteExpiredStub        func(time.Time) ([]models.Policy, error)
+	deleteExpiredMutex       sync.RWMutex
+	deleteExpiredArgsForCall []struct {
+		arg1 time.Time
+	}
+	deleteExpiredReturns struct {
+		result1 []models.Policy
+		result2 error
+	}
+	deleteExpiredReturnsOnCall map[int]struct {
+		result1 []models.Policy
+		result2 error
+	}
 	TagsStub        func() ([]models.Tag, error)
 	tagsMutex       sync.RWMutex
 	tagsArgsForCall []struct{}
@@ -228,6 +242,57 @@ func (fake *Store) DeleteReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *Store) DeleteExpired(arg1 time.Time) ([]models.Policy, error) {
+	fake.deleteExpiredMutex.Lock()
+	ret, specificReturn := fake.deleteExpiredReturnsOnCall[len(fake.deleteExpiredArgsForCall)]
+	fake.deleteExpiredArgsForCall = append(fake.deleteExpiredArgsForCall, struct {
+		arg1 time.Time
+	}{arg1})
+	fake.recordInvocation("DeleteExpired", []interface{}{arg1})
+	fake.deleteExpiredMutex.Unlock()
+	if fake.DeleteExpiredStub != nil {
+		return fake.DeleteExpiredStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.deleteExpiredReturns.result1, fake.deleteExpiredReturns.result2
+}
+
+func (fake *Store) DeleteExpiredCallCount() int {
+	fake.deleteExpiredMutex.RLock()
+	defer fake.deleteExpiredMutex.RUnlock()
+	return len(fake.deleteExpiredArgsForCall)
+}
+
+func (fake *Store) DeleteExpiredArgsForCall(i int) time.Time {
+	fake.deleteExpiredMutex.RLock()
+	defer fake.deleteExpiredMutex.RUnlock()
+	return fake.deleteExpiredArgsForCall[i].arg1
+}
+
+func (fake *Store) DeleteExpiredReturns(result1 []models.Policy, result2 error) {
+	fake.DeleteExpiredStub = nil
+	fake.deleteExpiredReturns = struct {
+		result1 []models.Policy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *Store) DeleteExpiredReturnsOnCall(i int, result1 []models.Policy, result2 error) {
+	fake.DeleteExpiredStub = nil
+	if fake.deleteExpiredReturnsOnCall == nil {
+		fake.deleteExpiredReturnsOnCall = make(map[int]struct {
+			result1 []models.Policy
+			result2 error
+		})
+	}
+	fake.deleteExpiredReturnsOnCall[i] = struct {
+		result1 []models.Policy
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *Store) Tags() ([]models.Tag, error) {
 	fake.tagsMutex.Lock()
 	ret, specificReturn := fake.tagsReturnsOnCall[len(fake.tagsArgsForCall)]
@@ -382,6 +447,8 @@ func (fake *Store) Invocations() map[string][][]interface{} {
 	defer fake.allMutex.RUnlock()
 	fake.deleteMutex.RLock()
 	defer fake.deleteMutex.RUnlock()
+	fake.deleteExpiredMutex.RLock()
+	defer fake.deleteExpiredMutex.RUnlock()
 	fake.tagsMutex.RLock()
 	defer fake.tagsMutex.RUnlock()
 	fake.byGuidsMutex.RLock()
@@ -0,0 +1,33 @@
+package store
+
+import "database/sql"
+
+// icmpAny is stored for an unset ICMP type or code, so that the unique
+// constraints on destinations and egress_policies also cover those rows.
+const icmpAny = -1
+
+func icmpColumns(icmpType, icmpCode *int) (int, int) {
+	typeColumn, codeColumn := icmpAny, icmpAny
+	if icmpType != nil {
+		typeColumn = *icmpType
+	}
+	if icmpCode != nil {
+		codeColumn = *icmpCode
+	}
+	return typeColumn, codeColumn
+}
+
+// icmpFields reverses icmpColumns. NULL is read as unset, as for rows written
+// before the columns existed.
+func icmpFields(typeColumn, codeColumn sql.NullInt64) (*int, *int) {
+	var icmpType, icmpCode *int
+	if typeColumn.Valid && typeColumn.Int64 != icmpAny {
+		t := int(typeColumn.Int64)
+		icmpType = &t
+	}
+	if codeColumn.Valid && codeColumn.Int64 != icmpAny {
+		c := int(codeColumn.Int64)
+		icmpCode = &c
+	}
+	return icmpType, icmpCode
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"fmt"
+	"policy-server/store/helpers"
+	"time"
+)
+
+//go:generate counterfeiter -o fakes/leader_lock.go --fake-name LeaderLock . LeaderLock
+type LeaderLock interface {
+	TryAcquire(owner string, now time.Time, ttl time.Duration) (bool, error)
+	Release(owner string) error
+}
+
+type leaderLock struct {
+	conn db
+	name string
+}
+
+// NewLeaderLock expects the leader_locks table to have been created by New.
+// The lease is held by whichever owner last updated the row before it expired,
+// so instances must keep their clocks roughly in sync relative to the ttl.
+func NewLeaderLock(dbConnectionPool db, name string) (LeaderLock, error) {
+	_, err := dbConnectionPool.Exec(
+		helpers.RebindForSQLDialect(`
+		INSERT INTO leader_locks (name, owner, expires_at)
+		SELECT ?, '', 0
+		WHERE
+		NOT EXISTS (
+			SELECT *
+			FROM leader_locks
+			WHERE name = ?
+		)`, dbConnectionPool.DriverName()),
+		name,
+		name,
+	)
+	if err != nil {
+		var count int
+		countErr := dbConnectionPool.QueryRow(
+			helpers.RebindForSQLDialect(`SELECT COUNT(*) FROM leader_locks WHERE name = ?`, dbConnectionPool.DriverName()),
+			name,
+		).Scan(&count)
+		if countErr != nil || count != 1 {
+			return nil, fmt.Errorf("creating leader lock: %s", err)
+		}
+	}
+
+	return &leaderLock{
+		conn: dbConnectionPool,
+		name: name,
+	}, nil
+}
+
+func (l *leaderLock) TryAcquire(owner string, now time.Time, ttl time.Duration) (bool, error) {
+	result, err := l.conn.Exec(
+		helpers.RebindForSQLDialect(`
+		UPDATE leader_locks SET owner = ?, expires_at = ?
+		WHERE name = ? AND (owner = ? OR expires_at < ?)`, l.conn.DriverName()),
+		owner,
+		now.Add(ttl).UnixNano(),
+		l.name,
+		owner,
+		now.UnixNano(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("updating leader lock: %s", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("updating leader lock: %s", err) // untested
+	}
+	return rowsAffected == 1, nil
+}
+
+func (l *leaderLock) Release(owner string) error {
+	_, err := l.conn.Exec(
+		helpers.RebindForSQLDialect(`UPDATE leader_locks SET owner = '', expires_at = 0 WHERE name = ? AND owner = ?`, l.conn.DriverName()),
+		l.name,
+		owner,
+	)
+	if err != nil {
+		return fmt.Errorf("releasing leader lock: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package store_test
+
+import (
+	"fmt"
+	"policy-server/store"
+	"time"
+
+	"code.cloudfoundry.org/cf-networking-helpers/db"
+	"code.cloudfoundry.org/cf-networking-helpers/testsupport"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LeaderLock", func() {
+	var (
+		lock   store.LeaderLock
+		dbConf db.Config
+		realDb *sqlx.DB
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		dbConf = testsupport.GetDBConfig()
+		dbConf.DatabaseName = fmt.Sprintf("test_node_%d", GinkgoParallelNode())
+
+		testsupport.CreateDatabase(dbConf)
+
+		var err error
+		realDb, err = db.GetConnectionPool(dbConf)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = store.New(realDb, &store.Group{}, &store.Destination{}, &store.Policy{}, 1, 2*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+
+		lock, err = store.NewLeaderLock(realDb, "some-lock")
+		Expect(err).NotTo(HaveOccurred())
+
+		now = time.Now()
+	})
+
+	AfterEach(func() {
+		if realDb != nil {
+			Expect(realDb.Close()).To(Succeed())
+		}
+		testsupport.RemoveDatabase(dbConf)
+	})
+
+	Describe("NewLeaderLock", func() {
+		It("can be called more than once for the same lock", func() {
+			_, err := store.NewLeaderLock(realDb, "some-lock")
+			Expect(err).NotTo(HaveOccurred())
+
+			var count int
+			err = realDb.QueryRow(`SELECT COUNT(*) FROM leader_locks`).Scan(&count)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+	})
+
+	Describe("TryAcquire", func() {
+		It("acquires an unheld lock", func() {
+			acquired, err := lock.TryAcquire("instance-a", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+		})
+
+		It("renews a lock held by the same owner", func() {
+			_, err := lock.TryAcquire("instance-a", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			acquired, err := lock.TryAcquire("instance-a", now.Add(5*time.Second), 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+		})
+
+		It("does not acquire a lock held by another owner", func() {
+			_, err := lock.TryAcquire("instance-a", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			acquired, err := lock.TryAcquire("instance-b", now.Add(5*time.Second), 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+		})
+
+		It("acquires a lock whose lease has expired", func() {
+			_, err := lock.TryAcquire("instance-a", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			acquired, err := lock.TryAcquire("instance-b", now.Add(11*time.Second), 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+
+			acquired, err = lock.TryAcquire("instance-a", now.Add(12*time.Second), 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+		})
+
+		It("keeps locks with different names independent", func() {
+			otherLock, err := store.NewLeaderLock(realDb, "other-lock")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = lock.TryAcquire("instance-a", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			acquired, err := otherLock.TryAcquire("instance-b", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+		})
+	})
+
+	Describe("Release", func() {
+		It("lets another owner acquire the lock immediately", func() {
+			_, err := lock.TryAcquire("instance-a", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lock.Release("instance-a")).To(Succeed())
+
+			acquired, err := lock.TryAcquire("instance-b", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+		})
+
+		It("does not release a lock held by another owner", func() {
+			_, err := lock.TryAcquire("instance-a", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lock.Release("instance-b")).To(Succeed())
+
+			acquired, err := lock.TryAcquire("instance-b", now, 10*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeFalse())
+		})
+	})
+})
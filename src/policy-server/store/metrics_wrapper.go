@@ -55,6 +55,19 @@ func (mw *MetricsWrapper) Delete(policies []models.Policy) error {
 	return err
 }
 
+func (mw *MetricsWrapper) DeleteExpired(now time.Time) ([]models.Policy, error) {
+	startTime := time.Now()
+	policies, err := mw.Store.DeleteExpired(now)
+	deleteExpiredTimeDuration := time.Now().Sub(startTime)
+	if err != nil {
+		mw.MetricsSender.IncrementCounter("StoreDeleteExpiredError")
+		mw.MetricsSender.SendDuration("StoreDeleteExpiredErrorTime", deleteExpiredTimeDuration)
+	} else {
+		mw.MetricsSender.SendDuration("StoreDeleteExpiredSuccessTime", deleteExpiredTimeDuration)
+	}
+	return policies, err
+}
+
 func (mw *MetricsWrapper) Tags() ([]models.Tag, error) {
 	startTime := time.Now()
 	tags, err := mw.Store.Tags()
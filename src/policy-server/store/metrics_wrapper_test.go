@@ -5,6 +5,7 @@ import (
 	"policy-server/models"
 	"policy-server/store"
 	"policy-server/store/fakes"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -237,6 +238,50 @@ var _ = Describe("MetricsWrapper", func() {
 		})
 	})
 
+	Describe("DeleteExpired", func() {
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Now()
+			fakeStore.DeleteExpiredReturns(policies, nil)
+		})
+
+		It("calls DeleteExpired on the Store", func() {
+			deleted, err := metricsWrapper.DeleteExpired(now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(Equal(policies))
+
+			Expect(fakeStore.DeleteExpiredCallCount()).To(Equal(1))
+			Expect(fakeStore.DeleteExpiredArgsForCall(0)).To(Equal(now))
+		})
+
+		It("emits a metric", func() {
+			_, err := metricsWrapper.DeleteExpired(now)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeMetricsSender.SendDurationCallCount()).To(Equal(1))
+			name, _ := fakeMetricsSender.SendDurationArgsForCall(0)
+			Expect(name).To(Equal("StoreDeleteExpiredSuccessTime"))
+		})
+
+		Context("when there is an error", func() {
+			BeforeEach(func() {
+				fakeStore.DeleteExpiredReturns(nil, errors.New("banana"))
+			})
+			It("emits an error metric", func() {
+				_, err := metricsWrapper.DeleteExpired(now)
+				Expect(err).To(MatchError("banana"))
+
+				Expect(fakeMetricsSender.IncrementCounterCallCount()).To(Equal(1))
+				Expect(fakeMetricsSender.IncrementCounterArgsForCall(0)).To(Equal("StoreDeleteExpiredError"))
+
+				Expect(fakeMetricsSender.SendDurationCallCount()).To(Equal(1))
+				name, _ := fakeMetricsSender.SendDurationArgsForCall(0)
+				Expect(name).To(Equal("StoreDeleteExpiredErrorTime"))
+			})
+		})
+	})
+
 	Describe("Tags", func() {
 		BeforeEach(func() {
 			fakeStore.TagsReturns(tags, nil)
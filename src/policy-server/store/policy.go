@@ -1,8 +1,16 @@
 package store
 
+import (
+	"encoding/json"
+	"time"
+)
+
 //go:generate counterfeiter -o fakes/policy_repo.go --fake-name PolicyRepo . PolicyRepo
 type PolicyRepo interface {
 	Create(Transaction, int, int) error
+	SetMetadata(Transaction, int, int, string, map[string]string) error
+	SetExpiry(Transaction, int, int, *time.Time) error
+	DeleteExpiryIfExpired(Transaction, int, int, time.Time) (bool, error)
 	Delete(Transaction, int, int) error
 	CountWhereGroupID(Transaction, int) (int, error)
 	CountWhereDestinationID(Transaction, int) (int, error)
@@ -29,8 +37,127 @@ func (p *Policy) Create(tx Transaction, source_group_id int, destination_id int)
 	return err
 }
 
+// SetMetadata replaces the description and labels of an existing policy. An
+// empty description with no labels removes the metadata.
+func (p *Policy) SetMetadata(tx Transaction, source_group_id int, destination_id int, description string, labels map[string]string) error {
+	err := p.deleteMetadata(tx, source_group_id, destination_id)
+	if err != nil {
+		return err
+	}
+
+	if description == "" && len(labels) == 0 {
+		return nil
+	}
+
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return err // untested
+	}
+
+	_, err = tx.Exec(tx.Rebind(`
+		INSERT INTO policy_metadata (policy_id, description, labels)
+		SELECT id, ?, ?
+		FROM policies
+		WHERE group_id = ? AND destination_id = ?`),
+		description,
+		string(labelsJSON),
+		source_group_id,
+		destination_id,
+	)
+	return err
+}
+
+// SetExpiry replaces the expiry of an existing policy. The expiry is stored
+// with a resolution of seconds. A nil expiresAt removes the expiry.
+func (p *Policy) SetExpiry(tx Transaction, source_group_id int, destination_id int, expiresAt *time.Time) error {
+	err := p.deleteExpiry(tx, source_group_id, destination_id)
+	if err != nil {
+		return err
+	}
+
+	if expiresAt == nil {
+		return nil
+	}
+
+	_, err = tx.Exec(tx.Rebind(`
+		INSERT INTO policy_expirations (policy_id, expires_at)
+		SELECT id, ?
+		FROM policies
+		WHERE group_id = ? AND destination_id = ?`),
+		expiresAt.Unix(),
+		source_group_id,
+		destination_id,
+	)
+	return err
+}
+
+// DeleteExpiryIfExpired removes the expiry of a policy only if it is at or
+// before now, and reports whether it did. A policy whose expiry was extended
+// or removed since it was read is left alone.
+func (p *Policy) DeleteExpiryIfExpired(tx Transaction, source_group_id int, destination_id int, now time.Time) (bool, error) {
+	result, err := tx.Exec(tx.Rebind(`
+		DELETE FROM policy_expirations
+		WHERE expires_at <= ?
+		AND policy_id IN (
+			SELECT id
+			FROM policies
+			WHERE group_id = ? AND destination_id = ?
+		)`),
+		now.Unix(),
+		source_group_id,
+		destination_id,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (p *Policy) deleteExpiry(tx Transaction, source_group_id int, destination_id int) error {
+	_, err := tx.Exec(tx.Rebind(`
+		DELETE FROM policy_expirations
+		WHERE policy_id IN (
+			SELECT id
+			FROM policies
+			WHERE group_id = ? AND destination_id = ?
+		)`),
+		source_group_id,
+		destination_id,
+	)
+	return err
+}
+
+func (p *Policy) deleteMetadata(tx Transaction, source_group_id int, destination_id int) error {
+	_, err := tx.Exec(tx.Rebind(`
+		DELETE FROM policy_metadata
+		WHERE policy_id IN (
+			SELECT id
+			FROM policies
+			WHERE group_id = ? AND destination_id = ?
+		)`),
+		source_group_id,
+		destination_id,
+	)
+	return err
+}
+
 func (p *Policy) Delete(tx Transaction, source_group_id int, destination_id int) error {
-	_, err := tx.Exec(tx.Rebind(`DELETE FROM policies WHERE group_id = ? AND destination_id = ?`),
+	err := p.deleteMetadata(tx, source_group_id, destination_id)
+	if err != nil {
+		return err
+	}
+
+	err = p.deleteExpiry(tx, source_group_id, destination_id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(tx.Rebind(`DELETE FROM policies WHERE group_id = ? AND destination_id = ?`),
 		source_group_id,
 		destination_id,
 	)
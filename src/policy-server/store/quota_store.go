@@ -0,0 +1,113 @@
+package store
+
+import (
+	"fmt"
+	"policy-server/models"
+	"policy-server/store/helpers"
+)
+
+//go:generate counterfeiter -o fakes/quota_store.go --fake-name QuotaStore . QuotaStore
+type QuotaStore interface {
+	Upsert([]models.Quota) error
+	Delete([]models.Quota) error
+	All() ([]models.Quota, error)
+}
+
+type quotaStore struct {
+	conn db
+}
+
+// NewQuotaStore expects the quotas table to have been created by New.
+func NewQuotaStore(dbConnectionPool db) QuotaStore {
+	return &quotaStore{
+		conn: dbConnectionPool,
+	}
+}
+
+func (q *quotaStore) Upsert(quotas []models.Quota) error {
+	tx, err := q.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %s", err)
+	}
+
+	// a single statement per quota, so that concurrent creates of the same
+	// quota update it rather than racing on the unique key
+	query := upsertQuotaQuery(q.conn.DriverName())
+	for _, quota := range quotas {
+		_, err = tx.Exec(
+			tx.Rebind(query),
+			quota.Scope,
+			quota.GUID,
+			quota.MaxPolicies,
+			quota.MaxSourcesPerDestination,
+		)
+		if err != nil {
+			return rollback(tx, fmt.Errorf("upserting quota: %s", err))
+		}
+	}
+
+	return commit(tx)
+}
+
+func upsertQuotaQuery(driverName string) string {
+	if driverName == helpers.Postgres {
+		return `INSERT INTO quotas (scope, guid, max_policies, max_sources_per_destination) VALUES (?, ?, ?, ?)
+			ON CONFLICT (scope, guid) DO UPDATE SET
+			max_policies = EXCLUDED.max_policies, max_sources_per_destination = EXCLUDED.max_sources_per_destination`
+	}
+	return `INSERT INTO quotas (scope, guid, max_policies, max_sources_per_destination) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+		max_policies = VALUES(max_policies), max_sources_per_destination = VALUES(max_sources_per_destination)`
+}
+
+func (q *quotaStore) Delete(quotas []models.Quota) error {
+	tx, err := q.conn.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %s", err)
+	}
+
+	for _, quota := range quotas {
+		_, err = tx.Exec(
+			tx.Rebind(`DELETE FROM quotas WHERE scope = ? AND guid = ?`),
+			quota.Scope,
+			quota.GUID,
+		)
+		if err != nil {
+			return rollback(tx, fmt.Errorf("deleting quota: %s", err))
+		}
+	}
+
+	return commit(tx)
+}
+
+func (q *quotaStore) All() ([]models.Quota, error) {
+	return q.quotasQuery(`
+		SELECT scope, guid, max_policies, max_sources_per_destination
+		FROM quotas
+		ORDER BY id;`)
+}
+
+func (q *quotaStore) quotasQuery(query string) ([]models.Quota, error) {
+	quotas := []models.Quota{}
+
+	rows, err := q.conn.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("listing quotas: %s", err)
+	}
+
+	defer rows.Close() // untested
+	for rows.Next() {
+		var quota models.Quota
+		err = rows.Scan(&quota.Scope, &quota.GUID, &quota.MaxPolicies, &quota.MaxSourcesPerDestination)
+		if err != nil {
+			return nil, fmt.Errorf("listing quotas: %s", err)
+		}
+		quotas = append(quotas, quota)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("listing quotas, getting next row: %s", err) // untested
+	}
+
+	return quotas, nil
+}
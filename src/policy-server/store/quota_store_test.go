@@ -0,0 +1,129 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"policy-server/models"
+	"policy-server/store"
+	"policy-server/store/fakes"
+	"time"
+
+	"code.cloudfoundry.org/cf-networking-helpers/db"
+	"code.cloudfoundry.org/cf-networking-helpers/testsupport"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QuotaStore", func() {
+	var (
+		quotaStore store.QuotaStore
+		dbConf     db.Config
+		realDb     *sqlx.DB
+	)
+
+	BeforeEach(func() {
+		dbConf = testsupport.GetDBConfig()
+		dbConf.DatabaseName = fmt.Sprintf("test_node_%d", GinkgoParallelNode())
+
+		testsupport.CreateDatabase(dbConf)
+
+		var err error
+		realDb, err = db.GetConnectionPool(dbConf)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = store.New(realDb, &store.Group{}, &store.Destination{}, &store.Policy{}, 1, 2*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+
+		quotaStore = store.NewQuotaStore(realDb)
+	})
+
+	AfterEach(func() {
+		if realDb != nil {
+			Expect(realDb.Close()).To(Succeed())
+		}
+		testsupport.RemoveDatabase(dbConf)
+	})
+
+	Describe("Upsert", func() {
+		It("saves the quotas", func() {
+			err := quotaStore.Upsert([]models.Quota{
+				{Scope: "org", GUID: "some-org-guid", MaxPolicies: 10},
+				{Scope: "space", GUID: "some-space-guid", MaxPolicies: 5, MaxSourcesPerDestination: 3},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			quotas, err := quotaStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quotas).To(Equal([]models.Quota{
+				{Scope: "org", GUID: "some-org-guid", MaxPolicies: 10},
+				{Scope: "space", GUID: "some-space-guid", MaxPolicies: 5, MaxSourcesPerDestination: 3},
+			}))
+		})
+
+		It("replaces an existing quota for the same scope and guid", func() {
+			err := quotaStore.Upsert([]models.Quota{{Scope: "org", GUID: "some-org-guid", MaxPolicies: 10}})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = quotaStore.Upsert([]models.Quota{{Scope: "org", GUID: "some-org-guid", MaxPolicies: 20, MaxSourcesPerDestination: 2}})
+			Expect(err).NotTo(HaveOccurred())
+
+			quotas, err := quotaStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quotas).To(Equal([]models.Quota{
+				{Scope: "org", GUID: "some-org-guid", MaxPolicies: 20, MaxSourcesPerDestination: 2},
+			}))
+		})
+
+		It("does not fail when the same quota is created concurrently", func() {
+			errs := make(chan error, 10)
+			for i := 0; i < 10; i++ {
+				go func(maxPolicies int) {
+					errs <- quotaStore.Upsert([]models.Quota{{Scope: "org", GUID: "some-new-org-guid", MaxPolicies: maxPolicies}})
+				}(i + 1)
+			}
+			for i := 0; i < 10; i++ {
+				Expect(<-errs).NotTo(HaveOccurred())
+			}
+
+			quotas, err := quotaStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quotas).To(HaveLen(1))
+		})
+
+		Context("when a transaction cannot be started", func() {
+			It("returns an error", func() {
+				mockDb := &fakes.Db{}
+				mockDb.BeginxReturns(nil, errors.New("some-error"))
+
+				err := store.NewQuotaStore(mockDb).Upsert([]models.Quota{{Scope: "org", GUID: "some-org-guid"}})
+				Expect(err).To(MatchError("begin transaction: some-error"))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		BeforeEach(func() {
+			err := quotaStore.Upsert([]models.Quota{
+				{Scope: "org", GUID: "some-org-guid", MaxPolicies: 10},
+				{Scope: "space", GUID: "some-space-guid", MaxPolicies: 5},
+			})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("deletes the quotas matching scope and guid", func() {
+			err := quotaStore.Delete([]models.Quota{
+				{Scope: "space", GUID: "some-space-guid"},
+				{Scope: "space", GUID: "some-org-guid"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			quotas, err := quotaStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(quotas).To(Equal([]models.Quota{
+				{Scope: "org", GUID: "some-org-guid", MaxPolicies: 10},
+			}))
+		})
+	})
+})
@@ -0,0 +1,258 @@
+package store
+
+import (
+	"policy-server/models"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/sleeper.go --fake-name Sleeper . sleeper
+type sleeper interface {
+	Sleep(time.Duration)
+}
+
+type SleeperFunc func(time.Duration)
+
+func (f SleeperFunc) Sleep(d time.Duration) {
+	f(d)
+}
+
+// transientErrors are substrings of driver errors seen while a database is
+// failing over. Each store call runs in its own transaction and is idempotent,
+// so the whole call is safe to repeat once the pool hands out a new connection.
+var transientErrors = []string{
+	"bad connection",
+	"invalid connection",
+	"connection refused",
+	"connection reset by peer",
+	"broken pipe",
+	"i/o timeout",
+	"server has gone away",
+	"read-only",
+	"the database system is starting up",
+	"the database system is shutting down",
+	"terminating connection due to administrator command",
+}
+
+func isTransientError(err error) bool {
+	message := err.Error()
+	if strings.HasSuffix(message, "EOF") {
+		return true
+	}
+	for _, transient := range transientErrors {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryWrapper retries calls to Store that fail with a transient database
+// error, doubling Backoff between each of at most MaxAttempts attempts.
+// CheckDatabase is never retried so health checks report the current state.
+type RetryWrapper struct {
+	Store       Store
+	Logger      lager.Logger
+	MaxAttempts int
+	Backoff     time.Duration
+	Sleeper     sleeper
+}
+
+// withRetry calls f until it succeeds, fails with an error that is not
+// transient, or has been called maxAttempts times, doubling backoff in between.
+func withRetry(logger lager.Logger, maxAttempts int, backoff time.Duration, sleeper sleeper, action string, f func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = f()
+		if err == nil || attempt >= maxAttempts || !isTransientError(err) {
+			return err
+		}
+		logger.Info("retrying-transient-error", lager.Data{
+			"action":  action,
+			"attempt": attempt,
+			"backoff": backoff.String(),
+			"error":   err.Error(),
+		})
+		sleeper.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (rw *RetryWrapper) retry(action string, f func() error) error {
+	return withRetry(rw.Logger, rw.MaxAttempts, rw.Backoff, rw.Sleeper, action, f)
+}
+
+func (rw *RetryWrapper) Create(policies []models.Policy) error {
+	return rw.retry("create", func() error {
+		return rw.Store.Create(policies)
+	})
+}
+
+func (rw *RetryWrapper) All() ([]models.Policy, error) {
+	var policies []models.Policy
+	err := rw.retry("all", func() error {
+		var err error
+		policies, err = rw.Store.All()
+		return err
+	})
+	return policies, err
+}
+
+func (rw *RetryWrapper) Delete(policies []models.Policy) error {
+	return rw.retry("delete", func() error {
+		return rw.Store.Delete(policies)
+	})
+}
+
+func (rw *RetryWrapper) DeleteExpired(now time.Time) ([]models.Policy, error) {
+	var policies []models.Policy
+	err := rw.retry("delete-expired", func() error {
+		var err error
+		policies, err = rw.Store.DeleteExpired(now)
+		return err
+	})
+	return policies, err
+}
+
+func (rw *RetryWrapper) Tags() ([]models.Tag, error) {
+	var tags []models.Tag
+	err := rw.retry("tags", func() error {
+		var err error
+		tags, err = rw.Store.Tags()
+		return err
+	})
+	return tags, err
+}
+
+func (rw *RetryWrapper) ByGuids(srcGuids, dstGuids []string) ([]models.Policy, error) {
+	var policies []models.Policy
+	err := rw.retry("by-guids", func() error {
+		var err error
+		policies, err = rw.Store.ByGuids(srcGuids, dstGuids)
+		return err
+	})
+	return policies, err
+}
+
+func (rw *RetryWrapper) CheckDatabase() error {
+	return rw.Store.CheckDatabase()
+}
+
+// QuotaRetryWrapper retries calls to QuotaStore like RetryWrapper does for Store.
+type QuotaRetryWrapper struct {
+	QuotaStore  QuotaStore
+	Logger      lager.Logger
+	MaxAttempts int
+	Backoff     time.Duration
+	Sleeper     sleeper
+}
+
+func (rw *QuotaRetryWrapper) retry(action string, f func() error) error {
+	return withRetry(rw.Logger, rw.MaxAttempts, rw.Backoff, rw.Sleeper, action, f)
+}
+
+func (rw *QuotaRetryWrapper) Upsert(quotas []models.Quota) error {
+	return rw.retry("upsert-quotas", func() error {
+		return rw.QuotaStore.Upsert(quotas)
+	})
+}
+
+func (rw *QuotaRetryWrapper) Delete(quotas []models.Quota) error {
+	return rw.retry("delete-quotas", func() error {
+		return rw.QuotaStore.Delete(quotas)
+	})
+}
+
+func (rw *QuotaRetryWrapper) All() ([]models.Quota, error) {
+	var quotas []models.Quota
+	err := rw.retry("all-quotas", func() error {
+		var err error
+		quotas, err = rw.QuotaStore.All()
+		return err
+	})
+	return quotas, err
+}
+
+// EgressRetryWrapper retries calls to EgressStore like RetryWrapper does for Store.
+type EgressRetryWrapper struct {
+	EgressStore EgressStore
+	Logger      lager.Logger
+	MaxAttempts int
+	Backoff     time.Duration
+	Sleeper     sleeper
+}
+
+func (rw *EgressRetryWrapper) retry(action string, f func() error) error {
+	return withRetry(rw.Logger, rw.MaxAttempts, rw.Backoff, rw.Sleeper, action, f)
+}
+
+func (rw *EgressRetryWrapper) Create(policies []models.EgressPolicy) error {
+	return rw.retry("create-egress", func() error {
+		return rw.EgressStore.Create(policies)
+	})
+}
+
+func (rw *EgressRetryWrapper) Delete(policies []models.EgressPolicy) error {
+	return rw.retry("delete-egress", func() error {
+		return rw.EgressStore.Delete(policies)
+	})
+}
+
+func (rw *EgressRetryWrapper) All() ([]models.EgressPolicy, error) {
+	var policies []models.EgressPolicy
+	err := rw.retry("all-egress", func() error {
+		var err error
+		policies, err = rw.EgressStore.All()
+		return err
+	})
+	return policies, err
+}
+
+func (rw *EgressRetryWrapper) BySourceGuids(guids []string) ([]models.EgressPolicy, error) {
+	var policies []models.EgressPolicy
+	err := rw.retry("egress-by-source-guids", func() error {
+		var err error
+		policies, err = rw.EgressStore.BySourceGuids(guids)
+		return err
+	})
+	return policies, err
+}
+
+// LeaderLockRetryWrapper retries calls to LeaderLock like RetryWrapper does
+// for Store. Repeating TryAcquire is safe because the owner may always renew
+// a lease it already holds.
+type LeaderLockRetryWrapper struct {
+	LeaderLock  LeaderLock
+	Logger      lager.Logger
+	MaxAttempts int
+	Backoff     time.Duration
+	Sleeper     sleeper
+}
+
+func (rw *LeaderLockRetryWrapper) retry(action string, f func() error) error {
+	return withRetry(rw.Logger, rw.MaxAttempts, rw.Backoff, rw.Sleeper, action, f)
+}
+
+func (rw *LeaderLockRetryWrapper) TryAcquire(owner string, now time.Time, ttl time.Duration) (bool, error) {
+	// each attempt moves now on by the backoff slept before it, so a retry
+	// after a renewal whose reply was lost still changes the row: MySQL does
+	// not count rows updated to the values they already hold as affected
+	var acquired bool
+	backoff := rw.Backoff
+	err := rw.retry("try-acquire-leader-lock", func() error {
+		var err error
+		acquired, err = rw.LeaderLock.TryAcquire(owner, now, ttl)
+		now = now.Add(backoff)
+		backoff *= 2
+		return err
+	})
+	return acquired, err
+}
+
+func (rw *LeaderLockRetryWrapper) Release(owner string) error {
+	return rw.retry("release-leader-lock", func() error {
+		return rw.LeaderLock.Release(owner)
+	})
+}
@@ -0,0 +1,335 @@
+package store_test
+
+import (
+	"errors"
+	"policy-server/models"
+	"policy-server/store"
+	"policy-server/store/fakes"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("RetryWrapper", func() {
+	var (
+		retryWrapper *store.RetryWrapper
+		fakeStore    *fakes.Store
+		fakeSleeper  *fakes.Sleeper
+		logger       *lagertest.TestLogger
+		policies     []models.Policy
+	)
+
+	BeforeEach(func() {
+		fakeStore = &fakes.Store{}
+		fakeSleeper = &fakes.Sleeper{}
+		logger = lagertest.NewTestLogger("test")
+		retryWrapper = &store.RetryWrapper{
+			Store:       fakeStore,
+			Logger:      logger,
+			MaxAttempts: 3,
+			Backoff:     100 * time.Millisecond,
+			Sleeper:     fakeSleeper,
+		}
+		policies = []models.Policy{{
+			Source:      models.Source{ID: "some-app-guid"},
+			Destination: models.Destination{ID: "some-other-app-guid", Protocol: "tcp", Port: 8080},
+		}}
+	})
+
+	Describe("Create", func() {
+		It("calls Create on the Store", func() {
+			Expect(retryWrapper.Create(policies)).To(Succeed())
+			Expect(fakeStore.CreateCallCount()).To(Equal(1))
+			Expect(fakeStore.CreateArgsForCall(0)).To(Equal(policies))
+			Expect(fakeSleeper.SleepCallCount()).To(Equal(0))
+		})
+
+		Context("when the store fails with a transient error", func() {
+			BeforeEach(func() {
+				fakeStore.CreateReturnsOnCall(0, errors.New("begin transaction: driver: bad connection"))
+				fakeStore.CreateReturnsOnCall(1, errors.New("commit transaction: unexpected EOF"))
+				fakeStore.CreateReturnsOnCall(2, nil)
+			})
+
+			It("retries with exponential backoff", func() {
+				Expect(retryWrapper.Create(policies)).To(Succeed())
+				Expect(fakeStore.CreateCallCount()).To(Equal(3))
+				Expect(fakeSleeper.SleepCallCount()).To(Equal(2))
+				Expect(fakeSleeper.SleepArgsForCall(0)).To(Equal(100 * time.Millisecond))
+				Expect(fakeSleeper.SleepArgsForCall(1)).To(Equal(200 * time.Millisecond))
+				Expect(logger).To(gbytes.Say("retrying-transient-error.*create.*bad connection"))
+			})
+		})
+
+		Context("when the store keeps failing with a transient error", func() {
+			BeforeEach(func() {
+				fakeStore.CreateReturns(errors.New("creating group: dial tcp: connection refused"))
+			})
+
+			It("gives up after MaxAttempts and returns the last error", func() {
+				err := retryWrapper.Create(policies)
+				Expect(err).To(MatchError("creating group: dial tcp: connection refused"))
+				Expect(fakeStore.CreateCallCount()).To(Equal(3))
+				Expect(fakeSleeper.SleepCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when the store fails with another error", func() {
+			BeforeEach(func() {
+				fakeStore.CreateReturns(errors.New("banana"))
+			})
+
+			It("does not retry", func() {
+				Expect(retryWrapper.Create(policies)).To(MatchError("banana"))
+				Expect(fakeStore.CreateCallCount()).To(Equal(1))
+				Expect(fakeSleeper.SleepCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		It("retries transient errors", func() {
+			fakeStore.DeleteReturnsOnCall(0, errors.New("Error 1290: The MySQL server is running with the --read-only option"))
+			Expect(retryWrapper.Delete(policies)).To(Succeed())
+			Expect(fakeStore.DeleteCallCount()).To(Equal(2))
+			Expect(fakeStore.DeleteArgsForCall(1)).To(Equal(policies))
+		})
+	})
+
+	Describe("DeleteExpired", func() {
+		It("retries transient errors and returns the deleted policies", func() {
+			now := time.Now()
+			fakeStore.DeleteExpiredReturnsOnCall(0, nil, errors.New("driver: bad connection"))
+			fakeStore.DeleteExpiredReturnsOnCall(1, policies, nil)
+			result, err := retryWrapper.DeleteExpired(now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(policies))
+			Expect(fakeStore.DeleteExpiredCallCount()).To(Equal(2))
+			Expect(fakeStore.DeleteExpiredArgsForCall(1)).To(Equal(now))
+		})
+	})
+
+	Describe("All", func() {
+		It("retries transient errors and returns the policies", func() {
+			fakeStore.AllReturnsOnCall(0, nil, errors.New("listing all: pq: the database system is shutting down"))
+			fakeStore.AllReturnsOnCall(1, policies, nil)
+			result, err := retryWrapper.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(policies))
+			Expect(fakeStore.AllCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("Tags", func() {
+		It("retries transient errors and returns the tags", func() {
+			tags := []models.Tag{{ID: "some-app-guid", Tag: "0001"}}
+			fakeStore.TagsReturnsOnCall(0, nil, errors.New("listing tags: write: broken pipe"))
+			fakeStore.TagsReturnsOnCall(1, tags, nil)
+			result, err := retryWrapper.Tags()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(tags))
+		})
+	})
+
+	Describe("ByGuids", func() {
+		It("retries transient errors and returns the policies", func() {
+			fakeStore.ByGuidsReturnsOnCall(0, nil, errors.New("listing all: invalid connection"))
+			fakeStore.ByGuidsReturnsOnCall(1, policies, nil)
+			result, err := retryWrapper.ByGuids([]string{"some-app-guid"}, []string{"some-other-app-guid"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(policies))
+
+			srcGuids, dstGuids := fakeStore.ByGuidsArgsForCall(1)
+			Expect(srcGuids).To(Equal([]string{"some-app-guid"}))
+			Expect(dstGuids).To(Equal([]string{"some-other-app-guid"}))
+		})
+	})
+
+	Describe("CheckDatabase", func() {
+		It("does not retry", func() {
+			fakeStore.CheckDatabaseReturns(errors.New("driver: bad connection"))
+			Expect(retryWrapper.CheckDatabase()).To(MatchError("driver: bad connection"))
+			Expect(fakeStore.CheckDatabaseCallCount()).To(Equal(1))
+		})
+	})
+})
+
+var _ = Describe("QuotaRetryWrapper", func() {
+	var (
+		retryWrapper   *store.QuotaRetryWrapper
+		fakeQuotaStore *fakes.QuotaStore
+		fakeSleeper    *fakes.Sleeper
+		quotas         []models.Quota
+	)
+
+	BeforeEach(func() {
+		fakeQuotaStore = &fakes.QuotaStore{}
+		fakeSleeper = &fakes.Sleeper{}
+		retryWrapper = &store.QuotaRetryWrapper{
+			QuotaStore:  fakeQuotaStore,
+			Logger:      lagertest.NewTestLogger("test"),
+			MaxAttempts: 3,
+			Backoff:     100 * time.Millisecond,
+			Sleeper:     fakeSleeper,
+		}
+		quotas = []models.Quota{{Scope: "space", GUID: "some-space-guid", MaxPolicies: 10}}
+	})
+
+	It("retries transient errors on Upsert", func() {
+		fakeQuotaStore.UpsertReturnsOnCall(0, errors.New("upserting quota: driver: bad connection"))
+		Expect(retryWrapper.Upsert(quotas)).To(Succeed())
+		Expect(fakeQuotaStore.UpsertCallCount()).To(Equal(2))
+		Expect(fakeQuotaStore.UpsertArgsForCall(1)).To(Equal(quotas))
+		Expect(fakeSleeper.SleepCallCount()).To(Equal(1))
+	})
+
+	It("retries transient errors on Delete", func() {
+		fakeQuotaStore.DeleteReturnsOnCall(0, errors.New("commit transaction: unexpected EOF"))
+		Expect(retryWrapper.Delete(quotas)).To(Succeed())
+		Expect(fakeQuotaStore.DeleteCallCount()).To(Equal(2))
+	})
+
+	It("retries transient errors on All and returns the quotas", func() {
+		fakeQuotaStore.AllReturnsOnCall(0, nil, errors.New("listing quotas: invalid connection"))
+		fakeQuotaStore.AllReturnsOnCall(1, quotas, nil)
+		result, err := retryWrapper.All()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(quotas))
+	})
+
+	It("does not retry other errors", func() {
+		fakeQuotaStore.UpsertReturns(errors.New("banana"))
+		Expect(retryWrapper.Upsert(quotas)).To(MatchError("banana"))
+		Expect(fakeQuotaStore.UpsertCallCount()).To(Equal(1))
+		Expect(fakeSleeper.SleepCallCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("EgressRetryWrapper", func() {
+	var (
+		retryWrapper    *store.EgressRetryWrapper
+		fakeEgressStore *fakes.EgressStore
+		fakeSleeper     *fakes.Sleeper
+		policies        []models.EgressPolicy
+	)
+
+	BeforeEach(func() {
+		fakeEgressStore = &fakes.EgressStore{}
+		fakeSleeper = &fakes.Sleeper{}
+		retryWrapper = &store.EgressRetryWrapper{
+			EgressStore: fakeEgressStore,
+			Logger:      lagertest.NewTestLogger("test"),
+			MaxAttempts: 3,
+			Backoff:     100 * time.Millisecond,
+			Sleeper:     fakeSleeper,
+		}
+		policies = []models.EgressPolicy{{
+			Source:      models.EgressSource{ID: "some-app-guid"},
+			Destination: models.EgressDestination{Protocol: "tcp", CIDR: "10.0.0.0/8"},
+		}}
+	})
+
+	It("retries transient errors on Create", func() {
+		fakeEgressStore.CreateReturnsOnCall(0, errors.New("begin transaction: dial tcp: connection refused"))
+		fakeEgressStore.CreateReturnsOnCall(1, errors.New("inserting egress policy: Error 1290: read-only"))
+		Expect(retryWrapper.Create(policies)).To(Succeed())
+		Expect(fakeEgressStore.CreateCallCount()).To(Equal(3))
+		Expect(fakeEgressStore.CreateArgsForCall(2)).To(Equal(policies))
+		Expect(fakeSleeper.SleepArgsForCall(1)).To(Equal(200 * time.Millisecond))
+	})
+
+	It("retries transient errors on Delete", func() {
+		fakeEgressStore.DeleteReturnsOnCall(0, errors.New("driver: bad connection"))
+		Expect(retryWrapper.Delete(policies)).To(Succeed())
+		Expect(fakeEgressStore.DeleteCallCount()).To(Equal(2))
+	})
+
+	It("retries transient errors on All and returns the policies", func() {
+		fakeEgressStore.AllReturnsOnCall(0, nil, errors.New("write: broken pipe"))
+		fakeEgressStore.AllReturnsOnCall(1, policies, nil)
+		result, err := retryWrapper.All()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(policies))
+	})
+
+	It("retries transient errors on BySourceGuids and returns the policies", func() {
+		fakeEgressStore.BySourceGuidsReturnsOnCall(0, nil, errors.New("pq: the database system is starting up"))
+		fakeEgressStore.BySourceGuidsReturnsOnCall(1, policies, nil)
+		result, err := retryWrapper.BySourceGuids([]string{"some-app-guid"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(policies))
+		Expect(fakeEgressStore.BySourceGuidsArgsForCall(1)).To(Equal([]string{"some-app-guid"}))
+	})
+
+	It("gives up after MaxAttempts", func() {
+		fakeEgressStore.CreateReturns(errors.New("driver: bad connection"))
+		Expect(retryWrapper.Create(policies)).To(MatchError("driver: bad connection"))
+		Expect(fakeEgressStore.CreateCallCount()).To(Equal(3))
+	})
+})
+
+var _ = Describe("LeaderLockRetryWrapper", func() {
+	var (
+		retryWrapper   *store.LeaderLockRetryWrapper
+		fakeLeaderLock *fakes.LeaderLock
+		fakeSleeper    *fakes.Sleeper
+	)
+
+	BeforeEach(func() {
+		fakeLeaderLock = &fakes.LeaderLock{}
+		fakeSleeper = &fakes.Sleeper{}
+		retryWrapper = &store.LeaderLockRetryWrapper{
+			LeaderLock:  fakeLeaderLock,
+			Logger:      lagertest.NewTestLogger("test"),
+			MaxAttempts: 3,
+			Backoff:     100 * time.Millisecond,
+			Sleeper:     fakeSleeper,
+		}
+	})
+
+	Describe("TryAcquire", func() {
+		It("retries transient errors and returns whether the lock was acquired", func() {
+			now := time.Unix(1000, 0)
+			fakeLeaderLock.TryAcquireReturnsOnCall(0, false, errors.New("updating leader lock: driver: bad connection"))
+			fakeLeaderLock.TryAcquireReturnsOnCall(1, true, nil)
+
+			acquired, err := retryWrapper.TryAcquire("some-owner", now, 15*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(acquired).To(BeTrue())
+			Expect(fakeLeaderLock.TryAcquireCallCount()).To(Equal(2))
+
+			owner, _, ttl := fakeLeaderLock.TryAcquireArgsForCall(1)
+			Expect(owner).To(Equal("some-owner"))
+			Expect(ttl).To(Equal(15 * time.Second))
+		})
+
+		It("moves now on by the backoff slept before each attempt", func() {
+			now := time.Unix(1000, 0)
+			fakeLeaderLock.TryAcquireReturnsOnCall(0, false, errors.New("driver: bad connection"))
+			fakeLeaderLock.TryAcquireReturnsOnCall(1, false, errors.New("driver: bad connection"))
+			fakeLeaderLock.TryAcquireReturnsOnCall(2, true, nil)
+
+			_, err := retryWrapper.TryAcquire("some-owner", now, 15*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, firstNow, _ := fakeLeaderLock.TryAcquireArgsForCall(0)
+			_, secondNow, _ := fakeLeaderLock.TryAcquireArgsForCall(1)
+			_, thirdNow, _ := fakeLeaderLock.TryAcquireArgsForCall(2)
+			Expect(firstNow).To(Equal(now))
+			Expect(secondNow).To(Equal(now.Add(100 * time.Millisecond)))
+			Expect(thirdNow).To(Equal(now.Add(300 * time.Millisecond)))
+		})
+	})
+
+	Describe("Release", func() {
+		It("retries transient errors", func() {
+			fakeLeaderLock.ReleaseReturnsOnCall(0, errors.New("releasing leader lock: i/o timeout"))
+			Expect(retryWrapper.Release("some-owner")).To(Succeed())
+			Expect(fakeLeaderLock.ReleaseCallCount()).To(Equal(2))
+			Expect(fakeLeaderLock.ReleaseArgsForCall(1)).To(Equal("some-owner"))
+		})
+	})
+})
@@ -3,6 +3,7 @@ package store
 import (
 	"bytes"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -27,7 +28,9 @@ var schemas = map[string][]string{
 		group_id int REFERENCES groups(id),
 		port int,
 		protocol varchar(255),
-		UNIQUE (group_id, port, protocol),
+		icmp_type int,
+		icmp_code int,
+		UNIQUE (group_id, port, protocol, icmp_type, icmp_code),
 		PRIMARY KEY (id)
 	);`,
 		`CREATE TABLE IF NOT EXISTS policies (
@@ -36,6 +39,45 @@ var schemas = map[string][]string{
 		destination_id int REFERENCES destinations(id),
 		UNIQUE (group_id, destination_id),
 		PRIMARY KEY (id)
+	);`,
+		`CREATE TABLE IF NOT EXISTS quotas (
+		id int NOT NULL AUTO_INCREMENT,
+		scope varchar(255),
+		guid varchar(255),
+		max_policies int,
+		max_sources_per_destination int,
+		UNIQUE (scope, guid),
+		PRIMARY KEY (id)
+	);`,
+		`CREATE TABLE IF NOT EXISTS policy_metadata (
+		policy_id int NOT NULL REFERENCES policies(id),
+		description text,
+		labels text,
+		PRIMARY KEY (policy_id)
+	);`,
+		`CREATE TABLE IF NOT EXISTS policy_expirations (
+		policy_id int NOT NULL REFERENCES policies(id),
+		expires_at bigint NOT NULL,
+		PRIMARY KEY (policy_id)
+	);`,
+		`CREATE TABLE IF NOT EXISTS leader_locks (
+		name varchar(255) NOT NULL,
+		owner varchar(255),
+		expires_at bigint,
+		PRIMARY KEY (name)
+	);`,
+		`CREATE TABLE IF NOT EXISTS egress_policies (
+		id int NOT NULL AUTO_INCREMENT,
+		source_type varchar(32),
+		source_guid varchar(255),
+		protocol varchar(32),
+		cidr varchar(64),
+		start_port int,
+		end_port int,
+		icmp_type int,
+		icmp_code int,
+		UNIQUE (source_guid, source_type, protocol, cidr, start_port, end_port, icmp_type, icmp_code),
+		PRIMARY KEY (id)
 	);`,
 	},
 	"postgres": []string{
@@ -49,7 +91,9 @@ var schemas = map[string][]string{
 		group_id int REFERENCES groups(id),
 		port int,
 		protocol text,
-		UNIQUE (group_id, port, protocol)
+		icmp_type int,
+		icmp_code int,
+		UNIQUE (group_id, port, protocol, icmp_type, icmp_code)
 	);`,
 		`CREATE TABLE IF NOT EXISTS policies (
 		id SERIAL PRIMARY KEY,
@@ -57,14 +101,76 @@ var schemas = map[string][]string{
 		destination_id int REFERENCES destinations(id),
 		UNIQUE (group_id, destination_id)
 	);`,
+		`CREATE TABLE IF NOT EXISTS quotas (
+		id SERIAL PRIMARY KEY,
+		scope text,
+		guid text,
+		max_policies int,
+		max_sources_per_destination int,
+		UNIQUE (scope, guid)
+	);`,
+		`CREATE TABLE IF NOT EXISTS policy_metadata (
+		policy_id int PRIMARY KEY REFERENCES policies(id),
+		description text,
+		labels text
+	);`,
+		`CREATE TABLE IF NOT EXISTS policy_expirations (
+		policy_id int PRIMARY KEY REFERENCES policies(id),
+		expires_at bigint NOT NULL
+	);`,
+		`CREATE TABLE IF NOT EXISTS leader_locks (
+		name text PRIMARY KEY,
+		owner text,
+		expires_at bigint
+	);`,
+		`CREATE TABLE IF NOT EXISTS egress_policies (
+		id SERIAL PRIMARY KEY,
+		source_type text,
+		source_guid text,
+		protocol text,
+		cidr text,
+		start_port int,
+		end_port int,
+		icmp_type int,
+		icmp_code int,
+		UNIQUE (source_guid, source_type, protocol, cidr, start_port, end_port, icmp_type, icmp_code)
+	);`,
+	},
+}
+
+// icmpMigrations add the icmp_type and icmp_code columns to a destinations
+// table created before ICMP policies were supported, and widen its unique key
+// to include them.
+var icmpMigrations = map[string][]string{
+	helpers.MySQL: []string{
+		`ALTER TABLE destinations
+		ADD COLUMN icmp_type int,
+		ADD COLUMN icmp_code int,
+		DROP INDEX group_id,
+		ADD UNIQUE (group_id, port, protocol, icmp_type, icmp_code);`,
+		`UPDATE destinations SET icmp_type = -1, icmp_code = -1 WHERE icmp_type IS NULL;`,
+	},
+	helpers.Postgres: []string{
+		`ALTER TABLE destinations
+		ADD COLUMN icmp_type int,
+		ADD COLUMN icmp_code int,
+		DROP CONSTRAINT destinations_group_id_port_protocol_key,
+		ADD UNIQUE (group_id, port, protocol, icmp_type, icmp_code);`,
+		`UPDATE destinations SET icmp_type = -1, icmp_code = -1 WHERE icmp_type IS NULL;`,
 	},
 }
 
+var currentSchema = map[string]string{
+	helpers.MySQL:    "DATABASE()",
+	helpers.Postgres: "current_schema()",
+}
+
 //go:generate counterfeiter -o fakes/store.go --fake-name Store . Store
 type Store interface {
 	Create([]models.Policy) error
 	All() ([]models.Policy, error)
 	Delete([]models.Policy) error
+	DeleteExpired(time.Time) ([]models.Policy, error)
 	Tags() ([]models.Tag, error)
 	ByGuids([]string, []string) ([]models.Policy, error)
 	CheckDatabase() error
@@ -93,12 +199,15 @@ type Transaction interface {
 var RecordNotFoundError = errors.New("record not found")
 
 type store struct {
-	conn        db
-	group       GroupRepo
-	destination DestinationRepo
-	policy      PolicyRepo
-	tagLength   int
-	timeout     time.Duration
+	conn                db
+	group               GroupRepo
+	destination         DestinationRepo
+	policy              PolicyRepo
+	tagLength           int
+	timeout             time.Duration
+	conflictMaxAttempts int
+	conflictBackoff     time.Duration
+	sleeper             sleeper
 }
 
 const MAX_TAG_LENGTH = 3
@@ -124,12 +233,15 @@ func New(dbConnectionPool db, g GroupRepo, d DestinationRepo, p PolicyRepo, tl i
 	}
 
 	return &store{
-		conn:        dbConnectionPool,
-		group:       g,
-		destination: d,
-		policy:      p,
-		tagLength:   tl,
-		timeout:     t,
+		conn:                dbConnectionPool,
+		group:               g,
+		destination:         d,
+		policy:              p,
+		tagLength:           tl,
+		timeout:             t,
+		conflictMaxAttempts: conflictMaxAttempts,
+		conflictBackoff:     conflictBackoff,
+		sleeper:             SleeperFunc(time.Sleep),
 	}, nil
 }
 
@@ -155,6 +267,12 @@ func (s *store) CheckDatabase() error {
 }
 
 func (s *store) Create(policies []models.Policy) error {
+	return s.retryOnConflict(func() error {
+		return s.create(policies)
+	})
+}
+
+func (s *store) create(policies []models.Policy) error {
 	tx, err := s.conn.Beginx()
 	if err != nil {
 		return fmt.Errorf("begin transaction: %s", err)
@@ -171,7 +289,8 @@ func (s *store) Create(policies []models.Policy) error {
 			return rollback(tx, fmt.Errorf("creating group: %s", err))
 		}
 
-		destination_id, err := s.destination.Create(tx, destination_group_id, policy.Destination.Port, policy.Destination.Protocol)
+		icmpType, icmpCode := icmpColumns(policy.Destination.ICMPType, policy.Destination.ICMPCode)
+		destination_id, err := s.destination.Create(tx, destination_group_id, policy.Destination.Port, policy.Destination.Protocol, icmpType, icmpCode)
 		if err != nil {
 			return rollback(tx, fmt.Errorf("creating destination: %s", err))
 		}
@@ -180,76 +299,175 @@ func (s *store) Create(policies []models.Policy) error {
 		if err != nil {
 			return rollback(tx, fmt.Errorf("creating policy: %s", err))
 		}
+
+		err = s.policy.SetMetadata(tx, source_group_id, destination_id, policy.Description, policy.Labels)
+		if err != nil {
+			return rollback(tx, fmt.Errorf("setting policy metadata: %s", err))
+		}
+
+		err = s.policy.SetExpiry(tx, source_group_id, destination_id, policy.ExpiresAt)
+		if err != nil {
+			return rollback(tx, fmt.Errorf("setting policy expiry: %s", err))
+		}
 	}
 
 	return commit(tx)
 }
 
 func (s *store) Delete(policies []models.Policy) error {
+	return s.retryOnConflict(func() error {
+		return s.delete(policies)
+	})
+}
+
+func (s *store) delete(policies []models.Policy) error {
 	tx, err := s.conn.Beginx()
 	if err != nil {
 		return fmt.Errorf("begin transaction: %s", err)
 	}
 
 	for _, p := range policies {
-		sourceGroupID, err := s.group.GetID(tx, p.Source.ID)
+		_, err = s.deletePolicy(tx, p, nil)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				continue
-			} else {
-				return rollback(tx, fmt.Errorf("getting source id: %s", err))
-			}
+			return rollback(tx, err)
 		}
+	}
+	return commit(tx)
+}
+
+// DeleteExpired deletes the policies whose expiry is at or before now and
+// returns them. The expiry is checked again as each policy is deleted, so a
+// policy that was re-created with a later expiry in the meantime is kept.
+func (s *store) DeleteExpired(now time.Time) ([]models.Policy, error) {
+	candidates, err := s.policiesQuery(`
+		select
+			src_grp.guid,
+			src_grp.id,
+			dst_grp.guid,
+			dst_grp.id,
+			destinations.port,
+			destinations.protocol,
+			destinations.icmp_type,
+			destinations.icmp_code,
+			policy_metadata.description,
+			policy_metadata.labels,
+			policy_expirations.expires_at
+		from policies
+		left outer join groups as src_grp on (policies.group_id = src_grp.id)
+		left outer join destinations on (destinations.id = policies.destination_id)
+		left outer join groups as dst_grp on (destinations.group_id = dst_grp.id)
+		left outer join policy_metadata on (policy_metadata.policy_id = policies.id)
+		inner join policy_expirations on (policy_expirations.policy_id = policies.id)
+		where policy_expirations.expires_at <= ?;`, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	var deleted []models.Policy
+	err = s.retryOnConflict(func() error {
+		var err error
+		deleted, err = s.deleteExpired(candidates, now)
+		return err
+	})
+	return deleted, err
+}
+
+func (s *store) deleteExpired(policies []models.Policy, now time.Time) ([]models.Policy, error) {
+	tx, err := s.conn.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %s", err)
+	}
 
-		destGroupID, err := s.group.GetID(tx, p.Destination.ID)
+	deleted := []models.Policy{}
+	for _, p := range policies {
+		ok, err := s.deletePolicy(tx, p, &now)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				continue
-			} else {
-				return rollback(tx, fmt.Errorf("getting destination group id: %s", err))
-			}
+			return nil, rollback(tx, err)
 		}
+		if ok {
+			deleted = append(deleted, p)
+		}
+	}
 
-		destID, err := s.destination.GetID(tx, destGroupID, p.Destination.Port, p.Destination.Protocol)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				continue
-			} else {
-				return rollback(tx, fmt.Errorf("getting destination id: %s", err))
-			}
+	err = commit(tx)
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// deletePolicy deletes a policy along with any destination and group rows
+// that are no longer referenced, and reports whether the policy was found.
+// When expiredAt is set the policy is only deleted if its expiry is at or
+// before it.
+func (s *store) deletePolicy(tx Transaction, p models.Policy, expiredAt *time.Time) (bool, error) {
+	sourceGroupID, err := s.group.GetID(tx, p.Source.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
 		}
+		return false, fmt.Errorf("getting source id: %s", err)
+	}
 
-		err = s.policy.Delete(tx, sourceGroupID, destID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				continue
-			} else {
-				return rollback(tx, fmt.Errorf("deleting policy: %s", err))
-			}
+	destGroupID, err := s.group.GetID(tx, p.Destination.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
 		}
+		return false, fmt.Errorf("getting destination group id: %s", err)
+	}
+
+	icmpType, icmpCode := icmpColumns(p.Destination.ICMPType, p.Destination.ICMPCode)
+	destID, err := s.destination.GetID(tx, destGroupID, p.Destination.Port, p.Destination.Protocol, icmpType, icmpCode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("getting destination id: %s", err)
+	}
 
-		destIDCount, err := s.policy.CountWhereDestinationID(tx, destID)
+	if expiredAt != nil {
+		expired, err := s.policy.DeleteExpiryIfExpired(tx, sourceGroupID, destID, *expiredAt)
 		if err != nil {
-			return rollback(tx, fmt.Errorf("counting destination id: %s", err))
+			return false, fmt.Errorf("deleting policy expiry: %s", err)
 		}
-		if destIDCount == 0 {
-			err = s.destination.Delete(tx, destID)
-			if err != nil {
-				return rollback(tx, fmt.Errorf("deleting destination: %s", err))
-			}
+		if !expired {
+			return false, nil
 		}
+	}
 
-		err = s.deleteGroupRowIfLast(tx, sourceGroupID)
-		if err != nil {
-			return rollback(tx, fmt.Errorf("deleting group row: %s", err))
+	err = s.policy.Delete(tx, sourceGroupID, destID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
 		}
+		return false, fmt.Errorf("deleting policy: %s", err)
+	}
 
-		err = s.deleteGroupRowIfLast(tx, destGroupID)
+	destIDCount, err := s.policy.CountWhereDestinationID(tx, destID)
+	if err != nil {
+		return false, fmt.Errorf("counting destination id: %s", err)
+	}
+	if destIDCount == 0 {
+		err = s.destination.Delete(tx, destID)
 		if err != nil {
-			return rollback(tx, fmt.Errorf("deleting group row: %s", err))
+			return false, fmt.Errorf("deleting destination: %s", err)
 		}
 	}
-	return commit(tx)
+
+	err = s.deleteGroupRowIfLast(tx, sourceGroupID)
+	if err != nil {
+		return false, fmt.Errorf("deleting group row: %s", err)
+	}
+
+	err = s.deleteGroupRowIfLast(tx, destGroupID)
+	if err != nil {
+		return false, fmt.Errorf("deleting group row: %s", err)
+	}
+	return true, nil
 }
 
 func (s *store) deleteGroupRowIfLast(tx Transaction, group_id int) error {
@@ -286,12 +504,23 @@ func (s *store) policiesQuery(query string, args ...interface{}) ([]models.Polic
 	for rows.Next() {
 		var source_id, destination_id, protocol string
 		var port, source_tag, destination_tag int
-		err = rows.Scan(&source_id, &source_tag, &destination_id, &destination_tag, &port, &protocol)
+		var icmpType, icmpCode sql.NullInt64
+		var description, labelsJSON sql.NullString
+		var expiresAt sql.NullInt64
+		err = rows.Scan(&source_id, &source_tag, &destination_id, &destination_tag, &port, &protocol, &icmpType, &icmpCode, &description, &labelsJSON, &expiresAt)
 		if err != nil {
 			return nil, fmt.Errorf("listing all: %s", err)
 		}
 
-		policies = append(policies, models.Policy{
+		var labels map[string]string
+		if labelsJSON.Valid && labelsJSON.String != "" {
+			err = json.Unmarshal([]byte(labelsJSON.String), &labels)
+			if err != nil {
+				return nil, fmt.Errorf("listing all, parsing labels: %s", err)
+			}
+		}
+
+		policy := models.Policy{
 			Source: models.Source{
 				ID:  source_id,
 				Tag: s.tagIntToString(source_tag),
@@ -299,10 +528,20 @@ func (s *store) policiesQuery(query string, args ...interface{}) ([]models.Polic
 			Destination: models.Destination{
 				ID:       destination_id,
 				Tag:      s.tagIntToString(destination_tag),
-				Protocol: protocol,
 				Port:     port,
+				Protocol: protocol,
 			},
-		})
+			Description: description.String,
+			Labels:      labels,
+		}
+
+		policy.Destination.ICMPType, policy.Destination.ICMPCode = icmpFields(icmpType, icmpCode)
+
+		if expiresAt.Valid {
+			expiry := time.Unix(expiresAt.Int64, 0).UTC()
+			policy.ExpiresAt = &expiry
+		}
+		policies = append(policies, policy)
 	}
 	err = rows.Err()
 	if err != nil {
@@ -334,11 +573,18 @@ func (s *store) ByGuids(srcGuids, destGuids []string) ([]models.Policy, error) {
 			dst_grp.guid,
 			dst_grp.id,
 			destinations.port,
-			destinations.protocol
+			destinations.protocol,
+			destinations.icmp_type,
+			destinations.icmp_code,
+			policy_metadata.description,
+			policy_metadata.labels,
+			policy_expirations.expires_at
 		from policies
 		left outer join groups as src_grp on (policies.group_id = src_grp.id)
 		left outer join destinations on (destinations.id = policies.destination_id)
-		left outer join groups as dst_grp on (destinations.group_id = dst_grp.id)`
+		left outer join groups as dst_grp on (destinations.group_id = dst_grp.id)
+		left outer join policy_metadata on (policy_metadata.policy_id = policies.id)
+		left outer join policy_expirations on (policy_expirations.policy_id = policies.id)`
 
 	if len(wheres) > 0 {
 		query += " where " + strings.Join(wheres, " OR ")
@@ -365,11 +611,18 @@ func (s *store) All() ([]models.Policy, error) {
 			dst_grp.guid,
 			dst_grp.id,
 			destinations.port,
-			destinations.protocol
+			destinations.protocol,
+			destinations.icmp_type,
+			destinations.icmp_code,
+			policy_metadata.description,
+			policy_metadata.labels,
+			policy_expirations.expires_at
 		from policies
 		left outer join groups as src_grp on (policies.group_id = src_grp.id)
 		left outer join destinations on (destinations.id = policies.destination_id)
-		left outer join groups as dst_grp on (destinations.group_id = dst_grp.id);`)
+		left outer join groups as dst_grp on (destinations.group_id = dst_grp.id)
+		left outer join policy_metadata on (policy_metadata.policy_id = policies.id)
+		left outer join policy_expirations on (policy_expirations.policy_id = policies.id);`)
 }
 
 func (s *store) Tags() ([]models.Tag, error) {
@@ -424,6 +677,34 @@ func setupTables(dbConnectionPool db) error {
 			return err
 		}
 	}
+	return migrateICMPColumns(dbConnectionPool)
+}
+
+func migrateICMPColumns(dbConnectionPool db) error {
+	driverName := dbConnectionPool.DriverName()
+	row := dbConnectionPool.QueryRow(fmt.Sprintf(`
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = %s AND table_name = 'destinations' AND column_name = 'icmp_type'`,
+		currentSchema[driverName],
+	))
+	if row == nil {
+		return nil
+	}
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, migration := range icmpMigrations[driverName] {
+		_, err = dbConnectionPool.Exec(migration)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
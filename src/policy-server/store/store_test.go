@@ -8,6 +8,7 @@ import (
 	"policy-server/store"
 	"policy-server/store/fakes"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -118,6 +119,42 @@ var _ = Describe("Store", func() {
 		})
 	})
 
+	Describe("many goroutines creating and deleting overlapping policies", func() {
+		It("retries conflicting transactions so that no caller sees an error", func() {
+			dataStore, err := store.New(realDb, group, destination, policy, 2, 2*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			nWorkers := 20
+			nPoliciesPerWorker := 10
+			errs := make(chan error, 2*nWorkers*nPoliciesPerWorker)
+
+			var wg sync.WaitGroup
+			for w := 0; w < nWorkers; w++ {
+				wg.Add(1)
+				go func(worker int) {
+					defer wg.Done()
+					for i := 0; i < nPoliciesPerWorker; i++ {
+						// every worker shares its destinations with the others, and
+						// pairs of workers share sources, so the transactions race
+						// for the same group and destination rows
+						p := models.Policy{
+							Source:      models.Source{ID: fmt.Sprintf("some-source-%d", worker/2)},
+							Destination: models.Destination{ID: fmt.Sprintf("some-destination-%d", i), Protocol: "tcp", Port: 8080},
+						}
+						errs <- dataStore.Create([]models.Policy{p})
+						errs <- dataStore.Delete([]models.Policy{p})
+					}
+				}(w)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	})
+
 	Describe("New", func() {
 		BeforeEach(func() {
 			var err error
@@ -133,6 +170,37 @@ var _ = Describe("Store", func() {
 				})
 			})
 
+			Context("when the destinations table predates icmp policies", func() {
+				BeforeEach(func() {
+					_, err := realDb.Exec(`DROP TABLE destinations CASCADE`)
+					Expect(err).NotTo(HaveOccurred())
+					_, err = realDb.Exec(`CREATE TABLE destinations (
+						id SERIAL PRIMARY KEY,
+						group_id int,
+						port int,
+						protocol varchar(255),
+						UNIQUE (group_id, port, protocol)
+					)`)
+					Expect(err).NotTo(HaveOccurred())
+					_, err = realDb.Exec(`INSERT INTO destinations (group_id, port, protocol) VALUES (1, 8080, 'tcp')`)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("adds the icmp columns and keeps the existing destinations", func() {
+					_, err := store.New(realDb, group, destination, policy, 2, 2*time.Second)
+					Expect(err).NotTo(HaveOccurred())
+
+					var icmpType, icmpCode int
+					err = realDb.QueryRow(`SELECT icmp_type, icmp_code FROM destinations WHERE port = 8080`).Scan(&icmpType, &icmpCode)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(icmpType).To(Equal(-1))
+					Expect(icmpCode).To(Equal(-1))
+
+					_, err = realDb.Exec(`INSERT INTO destinations (group_id, port, protocol, icmp_type, icmp_code) VALUES (1, 0, 'icmp', 8, 0), (1, 0, 'icmp', 0, 0)`)
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
 			Context("when the db operation fails", func() {
 				BeforeEach(func() {
 					mockDb.ExecReturns(nil, errors.New("some error"))
@@ -265,6 +333,208 @@ var _ = Describe("Store", func() {
 			})
 		})
 
+		Context("when policies have a description and labels", func() {
+			var policies []models.Policy
+
+			BeforeEach(func() {
+				policies = []models.Policy{{
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "tcp", Port: 8080},
+					Description: "some description",
+					Labels:      map[string]string{"team": "some-team"},
+				}, {
+					Source:      models.Source{ID: "another-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "udp", Port: 1234},
+				}}
+
+				err := dataStore.Create(policies)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("saves them with the policies", func() {
+				p, err := dataStore.All()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(HaveLen(2))
+				bySource := map[string]models.Policy{}
+				for _, policy := range p {
+					bySource[policy.Source.ID] = policy
+				}
+				Expect(bySource["some-app-guid"].Description).To(Equal("some description"))
+				Expect(bySource["some-app-guid"].Labels).To(Equal(map[string]string{"team": "some-team"}))
+				Expect(bySource["another-app-guid"].Description).To(BeEmpty())
+				Expect(bySource["another-app-guid"].Labels).To(BeNil())
+
+				p, err = dataStore.ByGuids([]string{"some-app-guid"}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(HaveLen(1))
+				Expect(p[0].Labels).To(Equal(map[string]string{"team": "some-team"}))
+			})
+
+			It("replaces them when the policy is created again with new metadata", func() {
+				policies[0].Description = "new description"
+				policies[0].Labels = map[string]string{"owner": "someone"}
+				err := dataStore.Create(policies[:1])
+				Expect(err).NotTo(HaveOccurred())
+
+				p, err := dataStore.ByGuids([]string{"some-app-guid"}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(HaveLen(1))
+				Expect(p[0].Description).To(Equal("new description"))
+				Expect(p[0].Labels).To(Equal(map[string]string{"owner": "someone"}))
+			})
+
+			It("clears them when the policy is created again without metadata", func() {
+				policies[0].Description = ""
+				policies[0].Labels = nil
+				err := dataStore.Create(policies[:1])
+				Expect(err).NotTo(HaveOccurred())
+
+				p, err := dataStore.ByGuids([]string{"some-app-guid"}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(HaveLen(1))
+				Expect(p[0].Description).To(BeEmpty())
+				Expect(p[0].Labels).To(BeNil())
+
+				var count int
+				err = realDb.QueryRow(`SELECT COUNT(*) FROM policy_metadata`).Scan(&count)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(0))
+			})
+
+			It("removes them when the policy is deleted", func() {
+				err := dataStore.Delete(policies[:1])
+				Expect(err).NotTo(HaveOccurred())
+
+				var count int
+				err = realDb.QueryRow(`SELECT COUNT(*) FROM policy_metadata`).Scan(&count)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(0))
+			})
+		})
+
+		Context("when policies are for icmp or all protocols", func() {
+			var policies []models.Policy
+
+			BeforeEach(func() {
+				echoRequest, codeZero, destUnreachable := 8, 0, 3
+				policies = []models.Policy{{
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "icmp", ICMPType: &echoRequest, ICMPCode: &codeZero},
+				}, {
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "icmp", ICMPType: &destUnreachable},
+				}, {
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "icmp"},
+				}, {
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "all"},
+				}}
+
+				err := dataStore.Create(policies)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("saves the icmp type and code with each destination", func() {
+				p, err := dataStore.All()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(HaveLen(4))
+
+				var destinations []models.Destination
+				for _, policy := range p {
+					policy.Destination.Tag = ""
+					destinations = append(destinations, policy.Destination)
+				}
+				for _, policy := range policies {
+					Expect(destinations).To(ContainElement(policy.Destination))
+				}
+			})
+
+			It("stores the protocol name and the icmp type and code in their own columns", func() {
+				var protocols []string
+				rows, err := realDb.Query(`SELECT protocol FROM destinations WHERE icmp_type = 8 AND icmp_code = 0`)
+				Expect(err).NotTo(HaveOccurred())
+				defer rows.Close()
+				for rows.Next() {
+					var protocol string
+					Expect(rows.Scan(&protocol)).To(Succeed())
+					protocols = append(protocols, protocol)
+				}
+				Expect(protocols).To(Equal([]string{"icmp"}))
+			})
+
+			It("deletes only the matching icmp destination", func() {
+				err := dataStore.Delete(policies[1:2])
+				Expect(err).NotTo(HaveOccurred())
+
+				p, err := dataStore.All()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(HaveLen(3))
+				for _, policy := range p {
+					if policy.Destination.ICMPType != nil {
+						Expect(*policy.Destination.ICMPType).To(Equal(8))
+					}
+				}
+			})
+		})
+
+		Context("when a policy has an expiry", func() {
+			var policies []models.Policy
+			var expiresAt time.Time
+
+			BeforeEach(func() {
+				expiresAt = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+				policies = []models.Policy{{
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "tcp", Port: 8080},
+					ExpiresAt:   &expiresAt,
+				}}
+
+				err := dataStore.Create(policies)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("saves it with the policy", func() {
+				p, err := dataStore.All()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(HaveLen(1))
+				Expect(p[0].ExpiresAt).NotTo(BeNil())
+				Expect(p[0].ExpiresAt.Equal(expiresAt)).To(BeTrue())
+			})
+
+			It("replaces it when the policy is created again with a new expiry", func() {
+				later := expiresAt.Add(time.Hour)
+				policies[0].ExpiresAt = &later
+				err := dataStore.Create(policies)
+				Expect(err).NotTo(HaveOccurred())
+
+				p, err := dataStore.All()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p[0].ExpiresAt.Equal(later)).To(BeTrue())
+			})
+
+			It("removes it when the policy is created again without an expiry", func() {
+				policies[0].ExpiresAt = nil
+				err := dataStore.Create(policies)
+				Expect(err).NotTo(HaveOccurred())
+
+				p, err := dataStore.All()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).To(HaveLen(1))
+				Expect(p[0].ExpiresAt).To(BeNil())
+			})
+
+			It("removes it when the policy is deleted", func() {
+				err := dataStore.Delete(policies)
+				Expect(err).NotTo(HaveOccurred())
+
+				var count int
+				err = realDb.QueryRow(`SELECT COUNT(*) FROM policy_expirations`).Scan(&count)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(0))
+			})
+		})
+
 		Context("when there are no tags left to allocate", func() {
 			BeforeEach(func() {
 				policies := []models.Policy{}
@@ -429,6 +699,58 @@ var _ = Describe("Store", func() {
 			})
 		})
 
+		Context("when a transaction conflicts with a concurrent one", func() {
+			var fakeGroup *fakes.GroupRepo
+			var conflictErr error
+			var err error
+
+			BeforeEach(func() {
+				conflictErr = errors.New("Error 1213: Deadlock found when trying to get lock; try restarting transaction")
+				if realDb.DriverName() == "postgres" {
+					conflictErr = errors.New("pq: deadlock detected")
+				}
+
+				fakeGroup = &fakes.GroupRepo{}
+				fakeGroup.CreateStub = func(tx store.Transaction, guid string) (int, error) {
+					if fakeGroup.CreateCallCount() == 1 {
+						return -1, conflictErr
+					}
+					return group.Create(tx, guid)
+				}
+
+				dataStore, err = store.New(realDb, fakeGroup, destination, policy, 2, 2*time.Second)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("retries the transaction", func() {
+				err = dataStore.Create([]models.Policy{{
+					Source:      models.Source{ID: "some-app-guid"},
+					Destination: models.Destination{ID: "some-other-app-guid", Protocol: "tcp", Port: 8080},
+				}})
+				Expect(err).NotTo(HaveOccurred())
+
+				policies, err := dataStore.All()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(policies).To(HaveLen(1))
+			})
+
+			Context("when the conflict persists", func() {
+				BeforeEach(func() {
+					fakeGroup.CreateStub = nil
+					fakeGroup.CreateReturns(-1, conflictErr)
+				})
+
+				It("gives up and returns the error", func() {
+					err = dataStore.Create([]models.Policy{{
+						Source:      models.Source{ID: "some-app-guid"},
+						Destination: models.Destination{ID: "some-other-app-guid", Protocol: "tcp", Port: 8080},
+					}})
+					Expect(err).To(MatchError(ContainSubstring(conflictErr.Error())))
+					Expect(fakeGroup.CreateCallCount()).To(Equal(5))
+				})
+			})
+		})
+
 		Context("when a Destination create record fails", func() {
 			var fakeDestination *fakes.DestinationRepo
 			var err error
@@ -727,6 +1049,69 @@ var _ = Describe("Store", func() {
 		})
 	})
 
+	Describe("DeleteExpired", func() {
+		var (
+			now      time.Time
+			policies []models.Policy
+		)
+
+		BeforeEach(func() {
+			var err error
+			dataStore, err = store.New(realDb, group, destination, policy, 2, 1*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+
+			now = time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+			past := now.Add(-time.Minute)
+			future := now.Add(time.Minute)
+			policies = []models.Policy{{
+				Source:      models.Source{ID: "some-app-guid"},
+				Destination: models.Destination{ID: "some-other-app-guid", Protocol: "tcp", Port: 8080},
+			}, {
+				Source:      models.Source{ID: "some-app-guid"},
+				Destination: models.Destination{ID: "some-other-app-guid", Protocol: "tcp", Port: 9090},
+				ExpiresAt:   &past,
+			}, {
+				Source:      models.Source{ID: "some-app-guid"},
+				Destination: models.Destination{ID: "some-other-app-guid", Protocol: "tcp", Port: 9999},
+				ExpiresAt:   &now,
+			}, {
+				Source:      models.Source{ID: "some-app-guid"},
+				Destination: models.Destination{ID: "some-other-app-guid", Protocol: "udp", Port: 9090},
+				ExpiresAt:   &future,
+			}}
+			err = dataStore.Create(policies)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("deletes only the policies that expired at or before now", func() {
+			deleted, err := dataStore.DeleteExpired(now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deleted).To(HaveLen(2))
+			Expect([]int{deleted[0].Destination.Port, deleted[1].Destination.Port}).To(ConsistOf(9090, 9999))
+
+			p, err := dataStore.All()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(p).To(HaveLen(2))
+			for _, policy := range p {
+				Expect(policy.Expired(now)).To(BeFalse())
+			}
+
+			var count int
+			err = realDb.QueryRow(`SELECT COUNT(*) FROM policy_expirations`).Scan(&count)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(1))
+		})
+
+		Context("when nothing has expired", func() {
+			It("deletes nothing", func() {
+				deleted, err := dataStore.DeleteExpired(now.Add(-time.Hour))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(deleted).To(BeEmpty())
+				Expect(dataStore.All()).To(HaveLen(4))
+			})
+		})
+	})
+
 	Describe("Tags", func() {
 		BeforeEach(func() {
 			var err error
@@ -1042,7 +1427,7 @@ var _ = Describe("Store", func() {
 			Context("when getting the destination id fails", func() {
 				Context("when the error is because the destination does not exist", func() {
 					BeforeEach(func() {
-						fakeDestination.GetIDStub = func(store.Transaction, int, int, string) (int, error) {
+						fakeDestination.GetIDStub = func(store.Transaction, int, int, string, int, int) (int, error) {
 							if fakeDestination.GetIDCallCount() == 1 {
 								return -1, sql.ErrNoRows
 							}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"cni-wrapper-plugin/legacynet"
 	"flag"
 	"fmt"
+	"garden-external-networker/port_allocator"
 	"lib/datastore"
 	"lib/filelock"
 	"lib/policy_client"
@@ -20,10 +22,13 @@ import (
 	"vxlan-policy-agent/enforcer"
 	"vxlan-policy-agent/handlers"
 	"vxlan-policy-agent/planner"
+	"vxlan-policy-agent/reconciler"
 
 	"code.cloudfoundry.org/cf-networking-helpers/metrics"
 	"code.cloudfoundry.org/cf-networking-helpers/mutualtls"
 	"code.cloudfoundry.org/debugserver"
+	"code.cloudfoundry.org/garden/client"
+	"code.cloudfoundry.org/garden/client/connection"
 	"code.cloudfoundry.org/lager"
 	"github.com/cloudfoundry/dropsonde"
 	"github.com/coreos/go-iptables/iptables"
@@ -34,8 +39,9 @@ import (
 )
 
 const (
-	dropsondeOrigin = "vxlan-policy-agent"
-	emitInterval    = 30 * time.Second
+	dropsondeOrigin        = "vxlan-policy-agent"
+	emitInterval           = 30 * time.Second
+	metricIPTablesLockWait = "iptablesLockWaitTime"
 )
 
 var (
@@ -95,9 +101,9 @@ func main() {
 		die(logger, "policy-client-get-policies", err)
 	}
 
-	store := &datastore.Store{
-		Serializer: &serial.Serial{},
-		Locker:     filelock.NewLocker(conf.Datastore),
+	store, err := datastore.New(conf.DatastoreBackend, conf.Datastore, os.Stderr)
+	if err != nil {
+		die(logger, "datastore-new", err)
 	}
 
 	ipt, err := iptables.New()
@@ -105,9 +111,19 @@ func main() {
 		die(logger, "iptables-new", err)
 	}
 
+	metricsSender := &metrics.MetricsSender{
+		Logger: logger.Session("time-metric-emitter"),
+	}
+
 	iptLocker := &rules.IPTablesLocker{
-		FileLocker: filelock.NewLocker(conf.IPTablesLockFile),
-		Mutex:      &sync.Mutex{},
+		FileLocker: filelock.NewLockerWithOptions(conf.IPTablesLockFile, filelock.Options{
+			Timeout:      time.Duration(conf.IPTablesLockTimeoutMs) * time.Millisecond,
+			RecordHolder: true,
+			OnWait: func(wait time.Duration) {
+				metricsSender.SendDuration(metricIPTablesLockWait, wait)
+			},
+		}),
+		Mutex: &sync.Mutex{},
 	}
 	restorer := &rules.Restorer{}
 	lockedIPTables := &rules.LockedIPTables{
@@ -116,10 +132,6 @@ func main() {
 		Restorer: restorer,
 	}
 
-	metricsSender := &metrics.MetricsSender{
-		Logger: logger.Session("time-metric-emitter"),
-	}
-
 	iptablesLoggingState := &planner.LoggingState{}
 	if conf.IPTablesLogging {
 		iptablesLoggingState.Enable()
@@ -139,6 +151,14 @@ func main() {
 		LoggingState: iptablesLoggingState,
 	}
 
+	egressPlanner := &planner.EgressPlanner{
+		Datastore:     store,
+		PolicyClient:  policyClient,
+		Logger:        logger.Session("egress-rules-updater"),
+		MetricsSender: metricsSender,
+		ChainNamer:    &legacynet.ChainNamer{MaxLength: 28},
+	}
+
 	timestamper := &enforcer.Timestamper{}
 	ruleEnforcer := enforcer.NewEnforcer(
 		logger.Session("rules-enforcer"),
@@ -146,25 +166,107 @@ func main() {
 		lockedIPTables,
 	)
 
+	// cells without ip6tables only enforce the chains of IPv4 containers
+	var lockedIP6Tables rules.IPTablesAdapter
+	ip6t, err := iptables.NewWithProtocol(iptables.ProtocolIPv6)
+	if err != nil {
+		logger.Info("ip6tables-unavailable", lager.Data{"error": err.Error()})
+	} else {
+		lockedIP6Tables = &rules.LockedIPTables{
+			IPTables: ip6t,
+			Locker:   iptLocker,
+			Restorer: &rules.Restorer{IPv6: true},
+		}
+		ruleEnforcer.IP6Tables = lockedIP6Tables
+		dynamicPlanner.IPv6 = true
+	}
+
 	err = dropsonde.Initialize(conf.MetronAddress, dropsondeOrigin)
 	if err != nil {
 		log.Fatalf("%s: initializing dropsonde: %s", logPrefix, err)
 	}
 
-	uptimeSource := metrics.NewUptimeSource()
-	metricsEmitter := metrics.NewMetricsEmitter(logger, emitInterval, uptimeSource)
+	metricSources := []metrics.MetricSource{
+		metrics.NewUptimeSource(),
+	}
+	// only the JSON datastore is recovered from a backup
+	if jsonStore, ok := store.(*datastore.Store); ok {
+		metricSources = append(metricSources, metrics.MetricSource{
+			// counts recoveries by every process sharing the datastore
+			Name: "datastoreRecoveries",
+			Unit: "",
+			Getter: func() (float64, error) {
+				recoveries, err := jsonStore.Recoveries()
+				return float64(recoveries), err
+			},
+		})
+	}
 
-	policyPoller := &poller.Poller{
-		Logger:       logger,
-		PollInterval: pollInterval,
-		SingleCycleFunc: (&converger.SinglePollCycle{
-			Planners: []converger.Planner{
-				dynamicPlanner,
+	portAllocator := &port_allocator.PortAllocator{
+		// the agent only releases ports, and reports on the range the
+		// garden-external-networker records in its state file
+		Tracker:    &port_allocator.Tracker{},
+		Serializer: &serial.Serial{},
+		Locker:     filelock.NewLocker(conf.NetworkerStateFile),
+	}
+	if conf.NetworkerStateFile != "" {
+		metricSources = append(metricSources, metrics.MetricSource{
+			// the share of the range acquired by containers
+			Name: "portPoolUtilisation",
+			Unit: "percent",
+			Getter: func() (float64, error) {
+				status, err := portAllocator.Status()
+				if err != nil {
+					return 0, err
+				}
+				if status.Capacity == 0 {
+					// no port has been allocated since the range was recorded
+					return 0, nil
+				}
+				return float64(status.Acquired) * 100 / float64(status.Capacity), nil
 			},
-			Enforcer:      ruleEnforcer,
-			MetricsSender: metricsSender,
-			Logger:        logger,
-		}).DoCycle,
+		})
+	}
+
+	var stateReconciler *reconciler.Reconciler
+	if conf.ReconcileInterval > 0 {
+		stateReconciler = &reconciler.Reconciler{
+			Logger:        logger.Session("reconciler"),
+			GardenClient:  client.New(connection.New(conf.GardenProtocol, conf.GardenAddress)),
+			Datastore:     store,
+			PortAllocator: portAllocator,
+			IPTables:      lockedIPTables,
+			IP6Tables:     lockedIP6Tables,
+			ChainNamer:    &legacynet.ChainNamer{MaxLength: 28},
+			VTEPName:      conf.VTEPName,
+			GraceRuns:     conf.ReconcileGraceRuns,
+		}
+		metricSources = append(metricSources,
+			reconciler.NewLeakedDatastoreEntriesSource(stateReconciler),
+			reconciler.NewLeakedPortAllocationsSource(stateReconciler),
+			reconciler.NewLeakedIPTablesChainsSource(stateReconciler),
+			reconciler.NewLeakedMasqueradeRulesSource(stateReconciler),
+			reconciler.NewPendingLeaksSource(stateReconciler),
+		)
+	}
+
+	metricsEmitter := metrics.NewMetricsEmitter(logger, emitInterval, metricSources...)
+
+	singlePollCycle := &converger.SinglePollCycle{
+		Planners: []converger.Planner{
+			dynamicPlanner,
+		},
+		MultiChainPlanners: []converger.MultiChainPlanner{
+			egressPlanner,
+		},
+		Enforcer:      ruleEnforcer,
+		MetricsSender: metricsSender,
+		Logger:        logger,
+	}
+	policyPoller := &poller.Poller{
+		Logger:          logger,
+		PollInterval:    pollInterval,
+		SingleCycleFunc: singlePollCycle.DoCycle,
 	}
 
 	debugServerAddress := fmt.Sprintf("%s:%d", conf.DebugServerHost, conf.DebugServerPort)
@@ -174,6 +276,25 @@ func main() {
 		{"policy_poller", policyPoller},
 		{"debug-server", debugServer},
 	}
+	if stateReconciler != nil {
+		members = append(members, grouper.Member{"reconciler", &poller.Poller{
+			Logger:          logger,
+			PollInterval:    time.Duration(conf.ReconcileInterval) * time.Second,
+			SingleCycleFunc: stateReconciler.ReconcileWrapper,
+		}})
+	}
+	if conf.DatastoreNotifySocket != "" {
+		// the CNI plugin notifies of new and deleted containers, so that
+		// their rules do not wait for the next poll
+		datastoreChanges := make(chan struct{}, 1)
+		policyPoller.Trigger = datastoreChanges
+		policyPoller.TriggeredCycleFunc = singlePollCycle.DoChangesCycle
+		members = append(members, grouper.Member{"datastore_listener", &datastore.Listener{
+			SocketPath: conf.DatastoreNotifySocket,
+			Changes:    datastoreChanges,
+			Logger:     logger.Session("datastore-listener"),
+		}})
+	}
 
 	monitor := ifrit.Invoke(sigmon.New(grouper.NewOrdered(os.Interrupt, members)))
 	logger.Info("starting")
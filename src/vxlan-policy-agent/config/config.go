@@ -2,33 +2,69 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"lib/datastore"
 	"os"
 
 	"gopkg.in/validator.v2"
 )
 
 type VxlanPolicyAgent struct {
-	PollInterval         int    `json:"poll_interval" validate:"nonzero"`
-	Datastore            string `json:"cni_datastore_path" validate:"nonzero"`
-	PolicyServerURL      string `json:"policy_server_url" validate:"min=1"`
-	VNI                  int    `json:"vni" validate:"nonzero"`
-	MetronAddress        string `json:"metron_address" validate:"nonzero"`
-	ServerCACertFile     string `json:"ca_cert_file" validate:"nonzero"`
-	ClientCertFile       string `json:"client_cert_file" validate:"nonzero"`
-	ClientKeyFile        string `json:"client_key_file" validate:"nonzero"`
-	ClientTimeoutSeconds int    `json:"client_timeout_seconds" validate:"nonzero"`
-	IPTablesLockFile     string `json:"iptables_lock_file" validate:"nonzero"`
-	DebugServerHost      string `json:"debug_server_host" validate:"nonzero"`
-	DebugServerPort      int    `json:"debug_server_port" validate:"nonzero"`
-	LogLevel             string `json:"log_level"`
-	LogPrefix            string `json:"log_prefix" validate:"nonzero"`
-	IPTablesLogging      bool   `json:"iptables_c2c_logging"`
+	PollInterval          int    `json:"poll_interval" validate:"nonzero"`
+	Datastore             string `json:"cni_datastore_path" validate:"nonzero"`
+	DatastoreBackend      string `json:"cni_datastore_backend"`
+	PolicyServerURL       string `json:"policy_server_url" validate:"min=1"`
+	VNI                   int    `json:"vni" validate:"nonzero"`
+	MetronAddress         string `json:"metron_address" validate:"nonzero"`
+	ServerCACertFile      string `json:"ca_cert_file" validate:"nonzero"`
+	ClientCertFile        string `json:"client_cert_file" validate:"nonzero"`
+	ClientKeyFile         string `json:"client_key_file" validate:"nonzero"`
+	ClientTimeoutSeconds  int    `json:"client_timeout_seconds" validate:"nonzero"`
+	IPTablesLockFile      string `json:"iptables_lock_file" validate:"nonzero"`
+	IPTablesLockTimeoutMs int    `json:"iptables_lock_timeout_ms" validate:"min=0"`
+	DebugServerHost       string `json:"debug_server_host" validate:"nonzero"`
+	DebugServerPort       int    `json:"debug_server_port" validate:"nonzero"`
+	LogLevel              string `json:"log_level"`
+	LogPrefix             string `json:"log_prefix" validate:"nonzero"`
+	IPTablesLogging       bool   `json:"iptables_c2c_logging"`
+	GardenProtocol        string `json:"garden_protocol"`
+	GardenAddress         string `json:"garden_address"`
+	NetworkerStateFile    string `json:"external_networker_state_file"`
+	ReconcileInterval     int    `json:"reconcile_interval"`
+	ReconcileGraceRuns    int    `json:"reconcile_grace_runs"`
+	VTEPName              string `json:"vtep_name"`
+	DatastoreNotifySocket string `json:"datastore_notify_socket"`
 }
 
 func (c *VxlanPolicyAgent) Validate() error {
-	return validator.Validate(c)
+	if err := validator.Validate(c); err != nil {
+		return err
+	}
+
+	if !datastore.ValidBackend(c.DatastoreBackend) {
+		return fmt.Errorf("invalid cni_datastore_backend: %s", c.DatastoreBackend)
+	}
+
+	// the reconciler is disabled when it has no interval
+	if c.ReconcileInterval == 0 {
+		return nil
+	}
+	if c.GardenProtocol == "" || c.GardenAddress == "" {
+		return errors.New("reconciler requires garden_protocol and garden_address")
+	}
+	if c.NetworkerStateFile == "" {
+		return errors.New("reconciler requires external_networker_state_file")
+	}
+	if c.VTEPName == "" {
+		return errors.New("reconciler requires vtep_name")
+	}
+	// with a single run, containers still being created would be torn down
+	if c.ReconcileGraceRuns < 2 {
+		return errors.New("reconciler requires reconcile_grace_runs of at least 2")
+	}
+	return nil
 }
 
 func New(configFilePath string) (*VxlanPolicyAgent, error) {
@@ -29,6 +29,7 @@ var _ = Describe("Config", func() {
 				file.WriteString(`{
 					"poll_interval": 1234,
 					"cni_datastore_path": "/some/datastore/path",
+					"cni_datastore_backend": "bolt",
 					"policy_server_url": "https://some-url:1234",
 					"vni": 42,
 					"metron_address": "http://1.2.3.4:1234",
@@ -36,17 +37,26 @@ var _ = Describe("Config", func() {
 					"client_cert_file": "/some/client/cert/file",
 					"client_key_file": "/some/client/key/file",
 					"iptables_lock_file":  "/var/vcap/data/lock",
+					"iptables_lock_timeout_ms": 30000,
 					"debug_server_host": "http://5.6.7.8",
 					"debug_server_port": 5678,
 					"log_level": "debug",
 					"log_prefix": "cfnetworking",
 					"iptables_c2c_logging": true,
-					"client_timeout_seconds":5
+					"client_timeout_seconds":5,
+					"garden_protocol": "unix",
+					"garden_address": "/some/garden.sock",
+					"external_networker_state_file": "/some/state/file",
+					"reconcile_interval": 60,
+					"reconcile_grace_runs": 3,
+					"vtep_name": "some-vtep",
+					"datastore_notify_socket": "/some/datastore.sock"
 				}`)
 				c, err := config.New(file.Name())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(c.PollInterval).To(Equal(1234))
 				Expect(c.Datastore).To(Equal("/some/datastore/path"))
+				Expect(c.DatastoreBackend).To(Equal("bolt"))
 				Expect(c.PolicyServerURL).To(Equal("https://some-url:1234"))
 				Expect(c.VNI).To(Equal(42))
 				Expect(c.MetronAddress).To(Equal("http://1.2.3.4:1234"))
@@ -54,12 +64,20 @@ var _ = Describe("Config", func() {
 				Expect(c.ClientCertFile).To(Equal("/some/client/cert/file"))
 				Expect(c.ClientKeyFile).To(Equal("/some/client/key/file"))
 				Expect(c.IPTablesLockFile).To(Equal("/var/vcap/data/lock"))
+				Expect(c.IPTablesLockTimeoutMs).To(Equal(30000))
 				Expect(c.DebugServerHost).To(Equal("http://5.6.7.8"))
 				Expect(c.DebugServerPort).To(Equal(5678))
 				Expect(c.LogLevel).To(Equal("debug"))
 				Expect(c.LogPrefix).To(Equal("cfnetworking"))
 				Expect(c.IPTablesLogging).To(Equal(true))
 				Expect(c.ClientTimeoutSeconds).To(Equal(5))
+				Expect(c.GardenProtocol).To(Equal("unix"))
+				Expect(c.GardenAddress).To(Equal("/some/garden.sock"))
+				Expect(c.NetworkerStateFile).To(Equal("/some/state/file"))
+				Expect(c.DatastoreNotifySocket).To(Equal("/some/datastore.sock"))
+				Expect(c.ReconcileInterval).To(Equal(60))
+				Expect(c.ReconcileGraceRuns).To(Equal(3))
+				Expect(c.VTEPName).To(Equal("some-vtep"))
 			})
 		})
 
@@ -122,5 +140,96 @@ var _ = Describe("Config", func() {
 			Entry("missing log prefix", "log_prefix", "LogPrefix: zero value"),
 			Entry("missing client timeout", "client_timeout_seconds", "ClientTimeoutSeconds: zero value"),
 		)
+
+		DescribeTable("when the reconciler is enabled and is missing a member",
+			func(missingFlag, errorMsg string) {
+				allData := map[string]interface{}{
+					"poll_interval":                 1234,
+					"cni_datastore_path":            "/some/datastore/path",
+					"policy_server_url":             "https://some-url:1234",
+					"vni":                           42,
+					"metron_address":                "http://1.2.3.4:1234",
+					"ca_cert_file":                  "/some/ca/file",
+					"client_cert_file":              "/some/client/cert/file",
+					"client_key_file":               "/some/client/key/file",
+					"iptables_lock_file":            "/var/vcap/data/lock",
+					"debug_server_host":             "http://5.6.7.8",
+					"debug_server_port":             5678,
+					"log_prefix":                    "cfnetworking",
+					"client_timeout_seconds":        5,
+					"garden_protocol":               "unix",
+					"garden_address":                "/some/garden.sock",
+					"external_networker_state_file": "/some/state/file",
+					"reconcile_interval":            60,
+					"reconcile_grace_runs":          2,
+					"vtep_name":                     "some-vtep",
+				}
+				delete(allData, missingFlag)
+				Expect(json.NewEncoder(file).Encode(allData)).To(Succeed())
+
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError(fmt.Sprintf("invalid config: %s", errorMsg)))
+			},
+			Entry("missing garden protocol", "garden_protocol", "reconciler requires garden_protocol and garden_address"),
+			Entry("missing garden address", "garden_address", "reconciler requires garden_protocol and garden_address"),
+			Entry("missing state file", "external_networker_state_file", "reconciler requires external_networker_state_file"),
+			Entry("missing vtep name", "vtep_name", "reconciler requires vtep_name"),
+			Entry("missing grace runs", "reconcile_grace_runs", "reconciler requires reconcile_grace_runs of at least 2"),
+		)
+
+		Context("when the reconciler is enabled with a single grace run", func() {
+			It("returns an error", func() {
+				allData := map[string]interface{}{
+					"poll_interval":                 1234,
+					"cni_datastore_path":            "/some/datastore/path",
+					"policy_server_url":             "https://some-url:1234",
+					"vni":                           42,
+					"metron_address":                "http://1.2.3.4:1234",
+					"ca_cert_file":                  "/some/ca/file",
+					"client_cert_file":              "/some/client/cert/file",
+					"client_key_file":               "/some/client/key/file",
+					"iptables_lock_file":            "/var/vcap/data/lock",
+					"debug_server_host":             "http://5.6.7.8",
+					"debug_server_port":             5678,
+					"log_prefix":                    "cfnetworking",
+					"client_timeout_seconds":        5,
+					"garden_protocol":               "unix",
+					"garden_address":                "/some/garden.sock",
+					"external_networker_state_file": "/some/state/file",
+					"reconcile_interval":            60,
+					"reconcile_grace_runs":          1,
+					"vtep_name":                     "some-vtep",
+				}
+				Expect(json.NewEncoder(file).Encode(allData)).To(Succeed())
+
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError("invalid config: reconciler requires reconcile_grace_runs of at least 2"))
+			})
+		})
+
+		Context("when the datastore backend is unknown", func() {
+			It("returns an error", func() {
+				allData := map[string]interface{}{
+					"poll_interval":          1234,
+					"cni_datastore_path":     "/some/datastore/path",
+					"cni_datastore_backend":  "banana",
+					"policy_server_url":      "https://some-url:1234",
+					"vni":                    42,
+					"metron_address":         "http://1.2.3.4:1234",
+					"ca_cert_file":           "/some/ca/file",
+					"client_cert_file":       "/some/client/cert/file",
+					"client_key_file":        "/some/client/key/file",
+					"iptables_lock_file":     "/var/vcap/data/lock",
+					"debug_server_host":      "http://5.6.7.8",
+					"debug_server_port":      5678,
+					"log_prefix":             "cfnetworking",
+					"client_timeout_seconds": 5,
+				}
+				Expect(json.NewEncoder(file).Encode(allData)).To(Succeed())
+
+				_, err = config.New(file.Name())
+				Expect(err).To(MatchError("invalid config: invalid cni_datastore_backend: banana"))
+			})
+		})
 	})
 })
@@ -9,13 +9,35 @@ import (
 )
 
 //go:generate counterfeiter -o fakes/planner.go --fake-name Planner . Planner
+
+// Planner plans rules for a fixed set of chains, such as one chain per IP
+// family. A chain planned without rules is enforced empty.
 type Planner interface {
-	GetRulesAndChain() (enforcer.RulesWithChain, error)
+	GetRulesAndChains() ([]enforcer.RulesWithChain, error)
+}
+
+//go:generate counterfeiter -o fakes/changes_planner.go --fake-name ChangesPlanner . ChangesPlanner
+
+// ChangesPlanner is a Planner that can plan again after the containers on the
+// cell changed without fetching the policies it already has.
+type ChangesPlanner interface {
+	GetRulesAndChains() ([]enforcer.RulesWithChain, error)
+	GetRulesAndChainsForChanges() ([]enforcer.RulesWithChain, error)
+}
+
+//go:generate counterfeiter -o fakes/multi_chain_planner.go --fake-name MultiChainPlanner . MultiChainPlanner
+
+// MultiChainPlanner plans rules for a varying set of chains, such as one chain
+// per container. A chain that is no longer planned, or that is planned without
+// rules, is removed.
+type MultiChainPlanner interface {
+	GetRulesAndChains() ([]enforcer.RulesWithChain, error)
 }
 
 //go:generate counterfeiter -o fakes/rule_enforcer.go --fake-name RuleEnforcer . ruleEnforcer
 type ruleEnforcer interface {
 	EnforceRulesAndChain(enforcer.RulesWithChain) error
+	RemoveChain(enforcer.Chain) error
 }
 
 //go:generate counterfeiter -o fakes/metrics_sender.go --fake-name MetricsSender . metricsSender
@@ -24,17 +46,36 @@ type metricsSender interface {
 }
 
 type SinglePollCycle struct {
-	Planners      []Planner
-	Enforcer      ruleEnforcer
-	MetricsSender metricsSender
-	Logger        lager.Logger
-	ruleSets      map[enforcer.Chain]enforcer.RulesWithChain
+	Planners           []Planner
+	MultiChainPlanners []MultiChainPlanner
+	Enforcer           ruleEnforcer
+	MetricsSender      metricsSender
+	Logger             lager.Logger
+	ruleSets           map[enforcer.Chain]enforcer.RulesWithChain
+	multiChains        map[enforcer.Chain]struct{}
 }
 
 const metricEnforceDuration = "iptablesEnforceTime"
 const metricPollDuration = "totalPollTime"
 
 func (m *SinglePollCycle) DoCycle() error {
+	return m.doCycle(func(p Planner) ([]enforcer.RulesWithChain, error) {
+		return p.GetRulesAndChains()
+	})
+}
+
+// DoChangesCycle is a cycle for when the containers on the cell changed. The
+// ChangesPlanners only fetch the policies of groups that are new to them.
+func (m *SinglePollCycle) DoChangesCycle() error {
+	return m.doCycle(func(p Planner) ([]enforcer.RulesWithChain, error) {
+		if changesPlanner, ok := p.(ChangesPlanner); ok {
+			return changesPlanner.GetRulesAndChainsForChanges()
+		}
+		return p.GetRulesAndChains()
+	})
+}
+
+func (m *SinglePollCycle) doCycle(getRulesAndChains func(Planner) ([]enforcer.RulesWithChain, error)) error {
 	if m.ruleSets == nil {
 		m.ruleSets = make(map[enforcer.Chain]enforcer.RulesWithChain)
 	}
@@ -42,14 +83,17 @@ func (m *SinglePollCycle) DoCycle() error {
 	pollStartTime := time.Now()
 	var enforceDuration time.Duration
 	for _, p := range m.Planners {
-		ruleSet, err := p.GetRulesAndChain()
+		ruleSets, err := getRulesAndChains(p)
 		if err != nil {
 			return fmt.Errorf("get-rules: %s", err)
 		}
 		enforceStartTime := time.Now()
 
-		oldRuleSet := m.ruleSets[ruleSet.Chain]
-		if !ruleSet.Equals(oldRuleSet) {
+		for _, ruleSet := range ruleSets {
+			oldRuleSet := m.ruleSets[ruleSet.Chain]
+			if ruleSet.Equals(oldRuleSet) {
+				continue
+			}
 			m.Logger.Debug("poll-cycle", lager.Data{
 				"message":       "updating iptables rules",
 				"num old rules": len(oldRuleSet.Rules),
@@ -67,6 +111,56 @@ func (m *SinglePollCycle) DoCycle() error {
 		enforceDuration += time.Now().Sub(enforceStartTime)
 	}
 
+	multiChains := make(map[enforcer.Chain]struct{})
+	removed := make(map[enforcer.Chain]struct{})
+	for _, p := range m.MultiChainPlanners {
+		ruleSets, err := p.GetRulesAndChains()
+		if err != nil {
+			return fmt.Errorf("get-rules: %s", err)
+		}
+		enforceStartTime := time.Now()
+
+		for _, ruleSet := range ruleSets {
+			if len(ruleSet.Rules) == 0 {
+				removed[ruleSet.Chain] = struct{}{}
+				continue
+			}
+			multiChains[ruleSet.Chain] = struct{}{}
+			oldRuleSet := m.ruleSets[ruleSet.Chain]
+			if ruleSet.Equals(oldRuleSet) {
+				continue
+			}
+			err = m.Enforcer.EnforceRulesAndChain(ruleSet)
+			if err != nil {
+				// the parent chain may have been removed since the rules were
+				// planned, so carry on with the other chains and retry next cycle
+				m.Logger.Error("enforce-chain", err, lager.Data{"chain": ruleSet.Chain})
+				continue
+			}
+			m.ruleSets[ruleSet.Chain] = ruleSet
+		}
+
+		enforceDuration += time.Now().Sub(enforceStartTime)
+	}
+	for chain := range m.multiChains {
+		if _, ok := multiChains[chain]; !ok {
+			removed[chain] = struct{}{}
+		}
+	}
+
+	enforceStartTime := time.Now()
+	for chain := range removed {
+		delete(m.ruleSets, chain)
+		err := m.Enforcer.RemoveChain(chain)
+		if err != nil {
+			// keep track of the chain so that removing it is retried next cycle
+			m.Logger.Error("remove-chain", err, lager.Data{"chain": chain})
+			multiChains[chain] = struct{}{}
+		}
+	}
+	enforceDuration += time.Now().Sub(enforceStartTime)
+	m.multiChains = multiChains
+
 	pollDuration := time.Now().Sub(pollStartTime)
 	m.MetricsSender.SendDuration(metricEnforceDuration, enforceDuration)
 	m.MetricsSender.SendDuration(metricPollDuration, pollDuration)
@@ -69,17 +69,17 @@ var _ = Describe("Single Poll Cycle", func() {
 				},
 			}
 
-			fakeLocalPlanner.GetRulesAndChainReturns(localRulesWithChain, nil)
-			fakeRemotePlanner.GetRulesAndChainReturns(remoteRulesWithChain, nil)
-			fakePolicyPlanner.GetRulesAndChainReturns(policyRulesWithChain, nil)
+			fakeLocalPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{localRulesWithChain}, nil)
+			fakeRemotePlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{remoteRulesWithChain}, nil)
+			fakePolicyPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{policyRulesWithChain}, nil)
 		})
 
 		It("enforces local,remote and policy rules on configured interval", func() {
 			err := p.DoCycle()
 			Expect(err).NotTo(HaveOccurred())
-			Expect(fakeLocalPlanner.GetRulesAndChainCallCount()).To(Equal(1))
-			Expect(fakeRemotePlanner.GetRulesAndChainCallCount()).To(Equal(1))
-			Expect(fakePolicyPlanner.GetRulesAndChainCallCount()).To(Equal(1))
+			Expect(fakeLocalPlanner.GetRulesAndChainsCallCount()).To(Equal(1))
+			Expect(fakeRemotePlanner.GetRulesAndChainsCallCount()).To(Equal(1))
+			Expect(fakePolicyPlanner.GetRulesAndChainsCallCount()).To(Equal(1))
 			Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(3))
 
 			rws := fakeEnforcer.EnforceRulesAndChainArgsForCall(0)
@@ -110,9 +110,9 @@ var _ = Describe("Single Poll Cycle", func() {
 			It("does not re-write the ip tables rules", func() {
 				err := p.DoCycle()
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeLocalPlanner.GetRulesAndChainCallCount()).To(Equal(2))
-				Expect(fakeRemotePlanner.GetRulesAndChainCallCount()).To(Equal(2))
-				Expect(fakePolicyPlanner.GetRulesAndChainCallCount()).To(Equal(2))
+				Expect(fakeLocalPlanner.GetRulesAndChainsCallCount()).To(Equal(2))
+				Expect(fakeRemotePlanner.GetRulesAndChainsCallCount()).To(Equal(2))
+				Expect(fakePolicyPlanner.GetRulesAndChainsCallCount()).To(Equal(2))
 
 				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(3))
 			})
@@ -124,15 +124,15 @@ var _ = Describe("Single Poll Cycle", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(3))
 				localRulesWithChain.Rules = []rules.IPTablesRule{[]string{"new-rule"}}
-				fakeLocalPlanner.GetRulesAndChainReturns(localRulesWithChain, nil)
+				fakeLocalPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{localRulesWithChain}, nil)
 			})
 
 			It("re-writes the ip tables rules for that chain", func() {
 				err := p.DoCycle()
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeLocalPlanner.GetRulesAndChainCallCount()).To(Equal(2))
-				Expect(fakeRemotePlanner.GetRulesAndChainCallCount()).To(Equal(2))
-				Expect(fakePolicyPlanner.GetRulesAndChainCallCount()).To(Equal(2))
+				Expect(fakeLocalPlanner.GetRulesAndChainsCallCount()).To(Equal(2))
+				Expect(fakeRemotePlanner.GetRulesAndChainsCallCount()).To(Equal(2))
+				Expect(fakePolicyPlanner.GetRulesAndChainsCallCount()).To(Equal(2))
 
 				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(4))
 			})
@@ -150,15 +150,15 @@ var _ = Describe("Single Poll Cycle", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(3))
 				localRulesWithChain.Rules = []rules.IPTablesRule{}
-				fakeLocalPlanner.GetRulesAndChainReturns(localRulesWithChain, nil)
+				fakeLocalPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{localRulesWithChain}, nil)
 			})
 
 			It("re-writes the ip tables rules for that chain", func() {
 				err := p.DoCycle()
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeLocalPlanner.GetRulesAndChainCallCount()).To(Equal(2))
-				Expect(fakeRemotePlanner.GetRulesAndChainCallCount()).To(Equal(2))
-				Expect(fakePolicyPlanner.GetRulesAndChainCallCount()).To(Equal(2))
+				Expect(fakeLocalPlanner.GetRulesAndChainsCallCount()).To(Equal(2))
+				Expect(fakeRemotePlanner.GetRulesAndChainsCallCount()).To(Equal(2))
+				Expect(fakePolicyPlanner.GetRulesAndChainsCallCount()).To(Equal(2))
 
 				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(4))
 			})
@@ -167,7 +167,7 @@ var _ = Describe("Single Poll Cycle", func() {
 		Context("when a new empty chain is created", func() {
 			BeforeEach(func() {
 				localRulesWithChain.Rules = []rules.IPTablesRule{}
-				fakeLocalPlanner.GetRulesAndChainReturns(localRulesWithChain, nil)
+				fakeLocalPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{localRulesWithChain}, nil)
 			})
 
 			It("enforces the rules for that chain", func() {
@@ -179,9 +179,38 @@ var _ = Describe("Single Poll Cycle", func() {
 			})
 		})
 
+		Context("when a planner plans several chains", func() {
+			var ipv6RulesWithChain enforcer.RulesWithChain
+
+			BeforeEach(func() {
+				ipv6RulesWithChain = policyRulesWithChain
+				ipv6RulesWithChain.Chain.IPv6 = true
+				ipv6RulesWithChain.Rules = []rules.IPTablesRule{}
+				fakePolicyPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{policyRulesWithChain, ipv6RulesWithChain}, nil)
+			})
+
+			It("enforces each of them, even without rules", func() {
+				Expect(p.DoCycle()).To(Succeed())
+
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(4))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(2)).To(Equal(policyRulesWithChain))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(3)).To(Equal(ipv6RulesWithChain))
+			})
+
+			It("only re-writes the chains that changed", func() {
+				Expect(p.DoCycle()).To(Succeed())
+				ipv6RulesWithChain.Rules = []rules.IPTablesRule{[]string{"ipv6-rule"}}
+				fakePolicyPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{policyRulesWithChain, ipv6RulesWithChain}, nil)
+
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(5))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(4)).To(Equal(ipv6RulesWithChain))
+			})
+		})
+
 		Context("when the local planner errors", func() {
 			BeforeEach(func() {
-				fakeLocalPlanner.GetRulesAndChainReturns(policyRulesWithChain, errors.New("eggplant"))
+				fakeLocalPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{policyRulesWithChain}, errors.New("eggplant"))
 			})
 
 			It("logs the error and returns", func() {
@@ -195,7 +224,7 @@ var _ = Describe("Single Poll Cycle", func() {
 
 		Context("when the remote planner errors", func() {
 			BeforeEach(func() {
-				fakeRemotePlanner.GetRulesAndChainReturns(policyRulesWithChain, errors.New("eggplant"))
+				fakeRemotePlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{policyRulesWithChain}, errors.New("eggplant"))
 			})
 
 			It("logs the error and returns", func() {
@@ -209,7 +238,7 @@ var _ = Describe("Single Poll Cycle", func() {
 
 		Context("when the policy planner errors", func() {
 			BeforeEach(func() {
-				fakePolicyPlanner.GetRulesAndChainReturns(policyRulesWithChain, errors.New("eggplant"))
+				fakePolicyPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{policyRulesWithChain}, errors.New("eggplant"))
 			})
 
 			It("logs the error and returns", func() {
@@ -233,5 +262,161 @@ var _ = Describe("Single Poll Cycle", func() {
 				Expect(metricsSender.SendDurationCallCount()).To(Equal(0))
 			})
 		})
+
+		Context("when there is a multi chain planner", func() {
+			var (
+				fakeMultiChainPlanner *fakes.MultiChainPlanner
+				firstRulesWithChain   enforcer.RulesWithChain
+				secondRulesWithChain  enforcer.RulesWithChain
+			)
+
+			BeforeEach(func() {
+				fakeMultiChainPlanner = &fakes.MultiChainPlanner{}
+				p.Planners = nil
+				p.MultiChainPlanners = []converger.MultiChainPlanner{fakeMultiChainPlanner}
+
+				firstRulesWithChain = enforcer.RulesWithChain{
+					Rules: []rules.IPTablesRule{[]string{"first-rule"}},
+					Chain: enforcer.Chain{Table: "filter", ParentChain: "netout--first", Prefix: "egress--first"},
+				}
+				secondRulesWithChain = enforcer.RulesWithChain{
+					Rules: []rules.IPTablesRule{[]string{"second-rule"}},
+					Chain: enforcer.Chain{Table: "filter", ParentChain: "netout--second", Prefix: "egress--second"},
+				}
+				fakeMultiChainPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{firstRulesWithChain, secondRulesWithChain}, nil)
+			})
+
+			It("enforces every chain, and only changed chains after that", func() {
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(2))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(0)).To(Equal(firstRulesWithChain))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(1)).To(Equal(secondRulesWithChain))
+
+				secondRulesWithChain.Rules = []rules.IPTablesRule{[]string{"second-rule"}, []string{"another-rule"}}
+				fakeMultiChainPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{firstRulesWithChain, secondRulesWithChain}, nil)
+
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(3))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(2)).To(Equal(secondRulesWithChain))
+			})
+
+			It("removes chains that are no longer planned", func() {
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.RemoveChainCallCount()).To(Equal(0))
+
+				fakeMultiChainPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{secondRulesWithChain}, nil)
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(2))
+				Expect(fakeEnforcer.RemoveChainCallCount()).To(Equal(1))
+				Expect(fakeEnforcer.RemoveChainArgsForCall(0)).To(Equal(firstRulesWithChain.Chain))
+
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.RemoveChainCallCount()).To(Equal(1))
+
+				fakeMultiChainPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{firstRulesWithChain, secondRulesWithChain}, nil)
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(3))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(2)).To(Equal(firstRulesWithChain))
+			})
+
+			It("removes chains that are planned without rules instead of enforcing them", func() {
+				secondRulesWithChain.Rules = []rules.IPTablesRule{}
+				fakeMultiChainPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{firstRulesWithChain, secondRulesWithChain}, nil)
+
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(1))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(0)).To(Equal(firstRulesWithChain))
+				Expect(fakeEnforcer.RemoveChainCallCount()).To(Equal(1))
+				Expect(fakeEnforcer.RemoveChainArgsForCall(0)).To(Equal(secondRulesWithChain.Chain))
+			})
+
+			Context("when removing a chain fails", func() {
+				BeforeEach(func() {
+					fakeEnforcer.RemoveChainReturnsOnCall(0, errors.New("eggplant"))
+				})
+
+				It("logs the error and retries on the next cycle", func() {
+					Expect(p.DoCycle()).To(Succeed())
+
+					fakeMultiChainPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{secondRulesWithChain}, nil)
+					Expect(p.DoCycle()).To(Succeed())
+					Expect(fakeEnforcer.RemoveChainCallCount()).To(Equal(1))
+					Expect(logger).To(gbytes.Say("remove-chain.*eggplant"))
+
+					Expect(p.DoCycle()).To(Succeed())
+					Expect(fakeEnforcer.RemoveChainCallCount()).To(Equal(2))
+					Expect(fakeEnforcer.RemoveChainArgsForCall(1)).To(Equal(firstRulesWithChain.Chain))
+
+					Expect(p.DoCycle()).To(Succeed())
+					Expect(fakeEnforcer.RemoveChainCallCount()).To(Equal(2))
+				})
+			})
+
+			Context("when enforcing one chain fails", func() {
+				BeforeEach(func() {
+					fakeEnforcer.EnforceRulesAndChainReturnsOnCall(0, errors.New("eggplant"))
+				})
+
+				It("logs the error, enforces the other chains and retries on the next cycle", func() {
+					Expect(p.DoCycle()).To(Succeed())
+					Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(2))
+					Expect(logger).To(gbytes.Say("enforce-chain.*eggplant"))
+
+					Expect(p.DoCycle()).To(Succeed())
+					Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(3))
+					Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(2)).To(Equal(firstRulesWithChain))
+				})
+			})
+
+			Context("when the multi chain planner errors", func() {
+				BeforeEach(func() {
+					fakeMultiChainPlanner.GetRulesAndChainsReturns(nil, errors.New("eggplant"))
+				})
+
+				It("returns the error", func() {
+					Expect(p.DoCycle()).To(MatchError("get-rules: eggplant"))
+					Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when the containers changed", func() {
+			var fakeChangesPlanner *fakes.ChangesPlanner
+
+			BeforeEach(func() {
+				fakeChangesPlanner = &fakes.ChangesPlanner{}
+				fakeChangesPlanner.GetRulesAndChainsForChangesReturns([]enforcer.RulesWithChain{policyRulesWithChain}, nil)
+				p.Planners = []converger.Planner{fakeLocalPlanner, fakeChangesPlanner}
+			})
+
+			It("plans the changes with the planners that can, and fully with the others", func() {
+				Expect(p.DoChangesCycle()).To(Succeed())
+
+				Expect(fakeChangesPlanner.GetRulesAndChainsForChangesCallCount()).To(Equal(1))
+				Expect(fakeChangesPlanner.GetRulesAndChainsCallCount()).To(Equal(0))
+				Expect(fakeLocalPlanner.GetRulesAndChainsCallCount()).To(Equal(1))
+
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(2))
+				Expect(fakeEnforcer.EnforceRulesAndChainArgsForCall(1)).To(Equal(policyRulesWithChain))
+			})
+
+			It("shares the enforced rules with the polls", func() {
+				Expect(p.DoChangesCycle()).To(Succeed())
+
+				fakeChangesPlanner.GetRulesAndChainsReturns([]enforcer.RulesWithChain{policyRulesWithChain}, nil)
+				Expect(p.DoCycle()).To(Succeed())
+				Expect(fakeEnforcer.EnforceRulesAndChainCallCount()).To(Equal(2))
+			})
+
+			Context("when planning the changes fails", func() {
+				BeforeEach(func() {
+					fakeChangesPlanner.GetRulesAndChainsForChangesReturns(nil, errors.New("eggplant"))
+				})
+
+				It("returns the error", func() {
+					Expect(p.DoChangesCycle()).To(MatchError("get-rules: eggplant"))
+				})
+			})
+		})
 	})
 })
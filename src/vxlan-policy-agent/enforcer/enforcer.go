@@ -1,8 +1,10 @@
 package enforcer
 
 import (
+	"errors"
 	"fmt"
 	"lib/rules"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -23,7 +25,10 @@ type TimeStamper interface {
 }
 
 type Enforcer struct {
-	Logger      lager.Logger
+	Logger lager.Logger
+	// IP6Tables enforces the chains of IPv6 containers. It is nil on cells
+	// without ip6tables.
+	IP6Tables   rules.IPTablesAdapter
 	timestamper TimeStamper
 	iptables    rules.IPTablesAdapter
 }
@@ -40,6 +45,7 @@ type Chain struct {
 	Table       string
 	ParentChain string
 	Prefix      string
+	IPv6        bool
 }
 
 type RulesWithChain struct {
@@ -75,31 +81,70 @@ func (e *Enforcer) EnforceRulesAndChain(rulesAndChain RulesWithChain) error {
 }
 
 func (e *Enforcer) EnforceOnChain(c Chain, rules []rules.IPTablesRule) error {
+	if c.IPv6 {
+		if e.IP6Tables == nil {
+			return errors.New("ip6tables unavailable")
+		}
+		return e.enforce(e.IP6Tables, c.Table, c.ParentChain, c.Prefix, rules...)
+	}
 	return e.Enforce(c.Table, c.ParentChain, c.Prefix, rules...)
 }
 
+// RemoveChain deletes every chain enforced for c along with its jump, for a
+// chain that is no longer planned. There is nothing to remove once the
+// parent chain is gone.
+func (e *Enforcer) RemoveChain(c Chain) error {
+	ipt := e.iptables
+	if c.IPv6 {
+		if e.IP6Tables == nil {
+			return errors.New("ip6tables unavailable")
+		}
+		ipt = e.IP6Tables
+	}
+
+	chains, err := ipt.ListChains(c.Table)
+	if err != nil {
+		return fmt.Errorf("listing chains: %s", err)
+	}
+	for _, chain := range chains {
+		if chain == c.ParentChain {
+			return e.cleanupOldRules(ipt, c.Table, c.ParentChain, c.Prefix, math.MaxInt64)
+		}
+	}
+	return nil
+}
+
 func (e *Enforcer) Enforce(table, parentChain, chainPrefix string, rulespec ...rules.IPTablesRule) error {
+	return e.enforce(e.iptables, table, parentChain, chainPrefix, rulespec...)
+}
+
+func (e *Enforcer) enforce(ipt rules.IPTablesAdapter, table, parentChain, chainPrefix string, rulespec ...rules.IPTablesRule) error {
 	newTime := e.timestamper.CurrentTime()
 	chain := fmt.Sprintf("%s%d", chainPrefix, newTime)
 
-	err := e.iptables.NewChain(table, chain)
+	err := ipt.NewChain(table, chain)
 	if err != nil {
 		e.Logger.Error("create-chain", err)
 		return fmt.Errorf("creating chain: %s", err)
 	}
 
-	err = e.iptables.BulkInsert(table, parentChain, 1, rules.IPTablesRule{"-j", chain})
+	err = ipt.BulkInsert(table, parentChain, 1, rules.IPTablesRule{"-j", chain})
 	if err != nil {
 		e.Logger.Error("insert-chain", err)
+		// the parent chain may be gone, e.g. the netout chain of a deleted
+		// container, so do not leave the new chain behind
+		if deleteErr := ipt.DeleteChain(table, chain); deleteErr != nil {
+			e.Logger.Error("delete-uninserted-chain", deleteErr) // untested
+		}
 		return fmt.Errorf("inserting chain: %s", err)
 	}
 
-	err = e.iptables.BulkAppend(table, chain, rulespec...)
+	err = ipt.BulkAppend(table, chain, rulespec...)
 	if err != nil {
 		return fmt.Errorf("bulk appending: %s", err)
 	}
 
-	err = e.cleanupOldRules(table, parentChain, chainPrefix, int(newTime))
+	err = e.cleanupOldRules(ipt, table, parentChain, chainPrefix, int64(newTime))
 	if err != nil {
 		e.Logger.Error("cleanup-rules", err)
 		return err
@@ -108,8 +153,8 @@ func (e *Enforcer) Enforce(table, parentChain, chainPrefix string, rulespec ...r
 	return nil
 }
 
-func (e *Enforcer) cleanupOldRules(table, parentChain, chainPrefix string, newTime int) error {
-	chainList, err := e.iptables.List(table, parentChain)
+func (e *Enforcer) cleanupOldRules(ipt rules.IPTablesAdapter, table, parentChain, chainPrefix string, newTime int64) error {
+	chainList, err := ipt.List(table, parentChain)
 	if err != nil {
 		return fmt.Errorf("listing forward rules: %s", err)
 	}
@@ -119,13 +164,13 @@ func (e *Enforcer) cleanupOldRules(table, parentChain, chainPrefix string, newTi
 		timeStampedChain := string(re.Find([]byte(c)))
 
 		if timeStampedChain != "" {
-			oldTime, err := strconv.Atoi(strings.TrimPrefix(timeStampedChain, chainPrefix))
+			oldTime, err := strconv.ParseInt(strings.TrimPrefix(timeStampedChain, chainPrefix), 10, 64)
 			if err != nil {
 				return err // not tested
 			}
 
 			if oldTime < newTime {
-				err = e.cleanupOldChain(table, parentChain, timeStampedChain)
+				err = e.cleanupOldChain(ipt, table, parentChain, timeStampedChain)
 				if err != nil {
 					return err
 				}
@@ -136,18 +181,18 @@ func (e *Enforcer) cleanupOldRules(table, parentChain, chainPrefix string, newTi
 	return nil
 }
 
-func (e *Enforcer) cleanupOldChain(table, parentChain, timeStampedChain string) error {
-	err := e.iptables.Delete(table, parentChain, rules.IPTablesRule{"-j", timeStampedChain})
+func (e *Enforcer) cleanupOldChain(ipt rules.IPTablesAdapter, table, parentChain, timeStampedChain string) error {
+	err := ipt.Delete(table, parentChain, rules.IPTablesRule{"-j", timeStampedChain})
 	if err != nil {
 		return fmt.Errorf("cleanup old chain: %s", err)
 	}
 
-	err = e.iptables.ClearChain(table, timeStampedChain)
+	err = ipt.ClearChain(table, timeStampedChain)
 	if err != nil {
 		return fmt.Errorf("cleanup old chain: %s", err)
 	}
 
-	err = e.iptables.DeleteChain(table, timeStampedChain)
+	err = ipt.DeleteChain(table, timeStampedChain)
 	if err != nil {
 		return fmt.Errorf("cleanup old chain: %s", err)
 	}
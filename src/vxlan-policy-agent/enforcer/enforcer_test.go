@@ -120,6 +120,15 @@ var _ = Describe("Enforcer", func() {
 
 				Expect(logger).To(gbytes.Say("insert-chain.*banana"))
 			})
+
+			It("deletes the new chain", func() {
+				ruleEnforcer.Enforce("some-table", "some-chain", "foo", []rules.IPTablesRule{fakeRule}...)
+
+				Expect(iptables.DeleteChainCallCount()).To(Equal(1))
+				table, chain := iptables.DeleteChainArgsForCall(0)
+				Expect(table).To(Equal("some-table"))
+				Expect(chain).To(Equal("foo42"))
+			})
 		})
 
 		Context("when there are errors cleaning up old rules", func() {
@@ -160,6 +169,139 @@ var _ = Describe("Enforcer", func() {
 			})
 		})
 	})
+	Describe("EnforceOnChain", func() {
+		var (
+			iptables     *libfakes.IPTablesAdapter
+			ip6tables    *libfakes.IPTablesAdapter
+			timestamper  *fakes.TimeStamper
+			ruleEnforcer *enforcer.Enforcer
+			chain        enforcer.Chain
+		)
+
+		BeforeEach(func() {
+			timestamper = &fakes.TimeStamper{}
+			timestamper.CurrentTimeReturns(42)
+			iptables = &libfakes.IPTablesAdapter{}
+			ip6tables = &libfakes.IPTablesAdapter{}
+
+			ruleEnforcer = enforcer.NewEnforcer(lagertest.NewTestLogger("test"), timestamper, iptables)
+			ruleEnforcer.IP6Tables = ip6tables
+			chain = enforcer.Chain{Table: "some-table", ParentChain: "some-chain", Prefix: "foo"}
+		})
+
+		It("enforces IPv4 chains with iptables", func() {
+			err := ruleEnforcer.EnforceOnChain(chain, []rules.IPTablesRule{{"rule1"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(iptables.NewChainCallCount()).To(Equal(1))
+			Expect(ip6tables.NewChainCallCount()).To(Equal(0))
+		})
+
+		Context("when the chain is IPv6", func() {
+			BeforeEach(func() {
+				chain.IPv6 = true
+			})
+
+			It("enforces the chain with ip6tables", func() {
+				err := ruleEnforcer.EnforceOnChain(chain, []rules.IPTablesRule{{"rule1"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(iptables.NewChainCallCount()).To(Equal(0))
+				Expect(ip6tables.NewChainCallCount()).To(Equal(1))
+				tableName, chainName := ip6tables.NewChainArgsForCall(0)
+				Expect(tableName).To(Equal("some-table"))
+				Expect(chainName).To(Equal("foo42"))
+				Expect(ip6tables.BulkAppendCallCount()).To(Equal(1))
+			})
+
+			Context("when ip6tables is unavailable", func() {
+				BeforeEach(func() {
+					ruleEnforcer.IP6Tables = nil
+				})
+
+				It("returns an error", func() {
+					err := ruleEnforcer.EnforceOnChain(chain, []rules.IPTablesRule{{"rule1"}})
+					Expect(err).To(MatchError("ip6tables unavailable"))
+				})
+			})
+		})
+	})
+
+	Describe("RemoveChain", func() {
+		var (
+			iptables     *libfakes.IPTablesAdapter
+			ip6tables    *libfakes.IPTablesAdapter
+			ruleEnforcer *enforcer.Enforcer
+			chain        enforcer.Chain
+		)
+
+		BeforeEach(func() {
+			iptables = &libfakes.IPTablesAdapter{}
+			ip6tables = &libfakes.IPTablesAdapter{}
+
+			ruleEnforcer = enforcer.NewEnforcer(lagertest.NewTestLogger("test"), &fakes.TimeStamper{}, iptables)
+			ruleEnforcer.IP6Tables = ip6tables
+			chain = enforcer.Chain{Table: "some-table", ParentChain: "some-chain", Prefix: "foo"}
+
+			iptables.ListChainsReturns([]string{"INPUT", "some-chain", "foo1111111111"}, nil)
+			iptables.ListReturns([]string{
+				"-N some-chain",
+				"-A some-chain -j foo1111111111",
+				"-A some-chain -j bar2222222222",
+			}, nil)
+		})
+
+		It("deletes the chains with the prefix and their jumps", func() {
+			Expect(ruleEnforcer.RemoveChain(chain)).To(Succeed())
+
+			Expect(iptables.DeleteCallCount()).To(Equal(1))
+			table, parentChain, rulespec := iptables.DeleteArgsForCall(0)
+			Expect(table).To(Equal("some-table"))
+			Expect(parentChain).To(Equal("some-chain"))
+			Expect(rulespec).To(Equal(rules.IPTablesRule{"-j", "foo1111111111"}))
+
+			Expect(iptables.ClearChainCallCount()).To(Equal(1))
+			Expect(iptables.DeleteChainCallCount()).To(Equal(1))
+			_, deletedChain := iptables.DeleteChainArgsForCall(0)
+			Expect(deletedChain).To(Equal("foo1111111111"))
+			Expect(iptables.NewChainCallCount()).To(Equal(0))
+		})
+
+		Context("when the parent chain no longer exists", func() {
+			BeforeEach(func() {
+				iptables.ListChainsReturns([]string{"INPUT"}, nil)
+			})
+
+			It("does nothing", func() {
+				Expect(ruleEnforcer.RemoveChain(chain)).To(Succeed())
+				Expect(iptables.ListCallCount()).To(Equal(0))
+				Expect(iptables.DeleteChainCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the chain is IPv6", func() {
+			BeforeEach(func() {
+				chain.IPv6 = true
+			})
+
+			It("removes it with ip6tables", func() {
+				Expect(ruleEnforcer.RemoveChain(chain)).To(Succeed())
+				Expect(iptables.ListChainsCallCount()).To(Equal(0))
+				Expect(ip6tables.ListChainsCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when listing the chains fails", func() {
+			BeforeEach(func() {
+				iptables.ListChainsReturns(nil, errors.New("banana"))
+			})
+
+			It("returns an error", func() {
+				Expect(ruleEnforcer.RemoveChain(chain)).To(MatchError("listing chains: banana"))
+			})
+		})
+	})
+
 	Describe("RulesWithChain", func() {
 		Describe("Equals", func() {
 			var ruleSet, otherRuleSet enforcer.RulesWithChain
@@ -193,6 +193,7 @@ var _ = Describe("VXLAN Policy Agent", func() {
 				Eventually(gatherMetricNames, "5s").Should(HaveKey("totalPollTime"))
 				Eventually(gatherMetricNames, "5s").Should(HaveKey("containerMetadataTime"))
 				Eventually(gatherMetricNames, "5s").Should(HaveKey("policyServerPollTime"))
+				Eventually(gatherMetricNames, "5s").Should(HaveKey("iptablesLockWaitTime"))
 			})
 
 			It("has a log level thats configurable at runtime", func() {
@@ -0,0 +1,178 @@
+package planner
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"lib/rules"
+	"policy-server/models"
+	"sort"
+	"time"
+	"vxlan-policy-agent/enforcer"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// egressChainPrefix starts the name of every chain the egress planner
+// attaches to a netout chain. The enforcer appends a 10 digit timestamp,
+// which leaves room for 10 hex characters identifying the container.
+const egressChainPrefix = "egress--"
+
+//go:generate counterfeiter -o fakes/egress_policy_client.go --fake-name EgressPolicyClient . egressPolicyClient
+type egressPolicyClient interface {
+	GetEgressPoliciesByID(ids ...string) ([]models.EgressPolicy, error)
+}
+
+type chainNamer interface {
+	Prefix(prefix, body string) string
+}
+
+// EgressPlanner plans, for every container with egress policies, a chain
+// allowing the egress policies of its app and space, jumped to from the
+// container's netout chain. ChainNamer must name the netout chain the way
+// cni-wrapper-plugin does.
+//
+// Only its first plan includes the containers without egress policies, with
+// no rules, so that the chains left from before the agent restarted are
+// removed.
+type EgressPlanner struct {
+	Logger        lager.Logger
+	Datastore     dstore
+	PolicyClient  egressPolicyClient
+	MetricsSender metricsSender
+	ChainNamer    chainNamer
+
+	planned bool
+}
+
+type egressContainer struct {
+	appID   string
+	spaceID string
+	ipv4    bool
+	ipv6    bool
+}
+
+const metricEgressPolicyServerPoll = "egressPolicyServerPollTime"
+
+func (p *EgressPlanner) GetRulesAndChains() ([]enforcer.RulesWithChain, error) {
+	allContainers, err := p.Datastore.ReadAll()
+	if err != nil {
+		p.Logger.Error("datastore", err)
+		return nil, err
+	}
+
+	containers := map[string]egressContainer{}
+	handles := []string{}
+	sourceIDs := map[string]struct{}{}
+	for _, container := range allContainers {
+		if container.Metadata == nil {
+			continue
+		}
+		appID, _ := container.Metadata["policy_group_id"].(string)
+		spaceID, _ := container.Metadata["space_id"].(string)
+		if appID == "" && spaceID == "" {
+			continue
+		}
+		egress := egressContainer{appID: appID, spaceID: spaceID}
+		for _, ip := range container.AllIPs() {
+			if rules.IsIPv6(ip) {
+				egress.ipv6 = true
+			} else {
+				egress.ipv4 = true
+			}
+		}
+		containers[container.Handle] = egress
+		handles = append(handles, container.Handle)
+		if appID != "" {
+			sourceIDs[appID] = struct{}{}
+		}
+		if spaceID != "" {
+			sourceIDs[spaceID] = struct{}{}
+		}
+	}
+	if len(containers) == 0 {
+		return []enforcer.RulesWithChain{}, nil
+	}
+
+	ids := []string{}
+	for id := range sourceIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	policyServerStartRequestTime := time.Now()
+	policies, err := p.PolicyClient.GetEgressPoliciesByID(ids...)
+	if err != nil {
+		p.Logger.Error("policy-client-get-egress-policies", err)
+		return nil, err
+	}
+	p.MetricsSender.SendDuration(metricEgressPolicyServerPoll, time.Now().Sub(policyServerStartRequestTime))
+
+	policiesBySource := map[models.EgressSource][]models.EgressPolicy{}
+	for _, policy := range policies {
+		policiesBySource[policy.Source] = append(policiesBySource[policy.Source], policy)
+	}
+
+	sort.Strings(handles)
+	ruleSets := []enforcer.RulesWithChain{}
+	for _, handle := range handles {
+		container := containers[handle]
+		containerPolicies := []models.EgressPolicy{}
+		containerPolicies = append(containerPolicies, policiesBySource[models.EgressSource{ID: container.appID, Type: models.EgressSourceTypeApp}]...)
+		containerPolicies = append(containerPolicies, policiesBySource[models.EgressSource{ID: container.spaceID, Type: models.EgressSourceTypeSpace}]...)
+
+		// the netout chain of each IP family of the container only gets the
+		// policies with a destination in that family
+		for _, ipv6 := range []bool{false, true} {
+			if (ipv6 && !container.ipv6) || (!ipv6 && !container.ipv4) {
+				continue
+			}
+
+			egressRules := egressRulesFor(containerPolicies, ipv6)
+			if len(egressRules) == 0 && p.planned {
+				continue
+			}
+
+			ruleSets = append(ruleSets, enforcer.RulesWithChain{
+				Chain: enforcer.Chain{
+					Table:       "filter",
+					ParentChain: p.ChainNamer.Prefix("netout", handle),
+					Prefix:      EgressChainPrefixFor(handle),
+					IPv6:        ipv6,
+				},
+				Rules: egressRules,
+			})
+		}
+	}
+
+	p.planned = true
+	return ruleSets, nil
+}
+
+func egressRulesFor(policies []models.EgressPolicy, ipv6 bool) []rules.IPTablesRule {
+	egressRules := []rules.IPTablesRule{}
+	for _, policy := range policies {
+		if rules.IsIPv6(policy.Destination.CIDR) != ipv6 {
+			continue
+		}
+
+		rule := rules.NewEgressRule(
+			policy.Destination.CIDR,
+			policy.Destination.Protocol,
+			policy.Destination.Ports.Start,
+			policy.Destination.Ports.End,
+			policy.Destination.ICMPType,
+			policy.Destination.ICMPCode,
+		)
+		if ipv6 {
+			rule = rules.ToIPv6(rule)
+		}
+		egressRules = append(egressRules, rule)
+	}
+	return egressRules
+}
+
+// EgressChainPrefixFor hashes the handle, since container handles may share
+// their leading characters.
+func EgressChainPrefixFor(handle string) string {
+	return fmt.Sprintf("%s%x", egressChainPrefix, sha1.Sum([]byte(handle)))[:len(egressChainPrefix)+10]
+}
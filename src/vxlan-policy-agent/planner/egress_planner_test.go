@@ -0,0 +1,252 @@
+package planner_test
+
+import (
+	"cni-wrapper-plugin/legacynet"
+	"errors"
+	"lib/datastore"
+	libfakes "lib/fakes"
+	"lib/rules"
+	"policy-server/models"
+	"vxlan-policy-agent/enforcer"
+	"vxlan-policy-agent/planner"
+	"vxlan-policy-agent/planner/fakes"
+
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EgressPlanner", func() {
+	var (
+		egressPlanner *planner.EgressPlanner
+		policyClient  *fakes.EgressPolicyClient
+		store         *libfakes.Datastore
+		metricsSender *fakes.MetricsSender
+		logger        *lagertest.TestLogger
+		data          map[string]datastore.Container
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		policyClient = &fakes.EgressPolicyClient{}
+		metricsSender = &fakes.MetricsSender{}
+		store = &libfakes.Datastore{}
+
+		data = map[string]datastore.Container{
+			"container-id-1": {
+				Handle: "container-id-1",
+				IP:     "10.255.1.2",
+				Metadata: map[string]interface{}{
+					"policy_group_id": "some-app-guid",
+					"space_id":        "some-space-guid",
+				},
+			},
+			"container-id-2": {
+				Handle: "container-id-2",
+				IP:     "10.255.1.3",
+				Metadata: map[string]interface{}{
+					"policy_group_id": "some-other-app-guid",
+				},
+			},
+			"container-id-3": {
+				Handle: "container-id-3",
+				IP:     "10.255.1.4",
+			},
+		}
+		store.ReadAllReturns(data, nil)
+
+		policyClient.GetEgressPoliciesByIDReturns([]models.EgressPolicy{
+			{
+				Source:      models.EgressSource{ID: "some-space-guid", Type: "space"},
+				Destination: models.EgressDestination{Protocol: "all", CIDR: "10.0.0.0/8"},
+			},
+			{
+				Source:      models.EgressSource{ID: "some-app-guid", Type: "app"},
+				Destination: models.EgressDestination{Protocol: "tcp", CIDR: "192.168.0.0/16", Ports: models.Ports{Start: 443, End: 443}},
+			},
+		}, nil)
+
+		egressPlanner = &planner.EgressPlanner{
+			Logger:        logger,
+			Datastore:     store,
+			PolicyClient:  policyClient,
+			MetricsSender: metricsSender,
+			ChainNamer:    &legacynet.ChainNamer{MaxLength: 28},
+		}
+	})
+
+	It("gets the egress policies of every app and space on the cell", func() {
+		_, err := egressPlanner.GetRulesAndChains()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(policyClient.GetEgressPoliciesByIDCallCount()).To(Equal(1))
+		Expect(policyClient.GetEgressPoliciesByIDArgsForCall(0)).To(Equal([]string{
+			"some-app-guid", "some-other-app-guid", "some-space-guid",
+		}))
+	})
+
+	It("plans a chain for each container with app or space egress policies", func() {
+		_, err := egressPlanner.GetRulesAndChains()
+		Expect(err).NotTo(HaveOccurred())
+
+		rulesWithChains, err := egressPlanner.GetRulesAndChains()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(rulesWithChains).To(Equal([]enforcer.RulesWithChain{
+			{
+				Chain: enforcer.Chain{
+					Table:       "filter",
+					ParentChain: "netout--container-id-1",
+					Prefix:      planner.EgressChainPrefixFor("container-id-1"),
+				},
+				Rules: []rules.IPTablesRule{
+					{"-d", "192.168.0.0/16", "-p", "tcp", "-m", "tcp", "--destination-port", "443:443", "--jump", "ACCEPT"},
+					{"-d", "10.0.0.0/8", "--jump", "ACCEPT"},
+				},
+			},
+		}))
+	})
+
+	It("plans the containers without egress policies with no rules the first time only", func() {
+		emptyChain := enforcer.RulesWithChain{
+			Chain: enforcer.Chain{
+				Table:       "filter",
+				ParentChain: "netout--container-id-2",
+				Prefix:      planner.EgressChainPrefixFor("container-id-2"),
+			},
+			Rules: []rules.IPTablesRule{},
+		}
+
+		rulesWithChains, err := egressPlanner.GetRulesAndChains()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rulesWithChains).To(HaveLen(2))
+		Expect(rulesWithChains).To(ContainElement(emptyChain))
+
+		rulesWithChains, err = egressPlanner.GetRulesAndChains()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rulesWithChains).To(HaveLen(1))
+		Expect(rulesWithChains).NotTo(ContainElement(emptyChain))
+	})
+
+	It("names the netout chain like cni-wrapper-plugin", func() {
+		data["container-id-1"] = datastore.Container{
+			Handle: "some-very-long-container-handle",
+			IP:     "10.255.1.2",
+			Metadata: map[string]interface{}{
+				"policy_group_id": "some-app-guid",
+			},
+		}
+
+		rulesWithChains, err := egressPlanner.GetRulesAndChains()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rulesWithChains).To(ContainElement(WithTransform(func(r enforcer.RulesWithChain) string {
+			return r.Chain.ParentChain
+		}, Equal("netout--some-very-long-conta"))))
+	})
+
+	Context("when a container is dual-stack", func() {
+		BeforeEach(func() {
+			data["container-id-2"] = datastore.Container{
+				Handle: "container-id-2",
+				IP:     "10.255.1.3",
+				IPs:    []string{"10.255.1.3", "fd00::3"},
+				Metadata: map[string]interface{}{
+					"policy_group_id": "some-other-app-guid",
+				},
+			}
+
+			policyClient.GetEgressPoliciesByIDReturns([]models.EgressPolicy{
+				{
+					Source:      models.EgressSource{ID: "some-other-app-guid", Type: "app"},
+					Destination: models.EgressDestination{Protocol: "all", CIDR: "10.0.0.0/8"},
+				},
+				{
+					Source:      models.EgressSource{ID: "some-other-app-guid", Type: "app"},
+					Destination: models.EgressDestination{Protocol: "icmp", CIDR: "fd00::/8"},
+				},
+			}, nil)
+		})
+
+		It("plans an ip6tables chain with the IPv6 policies", func() {
+			rulesWithChains, err := egressPlanner.GetRulesAndChains()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(rulesWithChains).To(ContainElement(enforcer.RulesWithChain{
+				Chain: enforcer.Chain{
+					Table:       "filter",
+					ParentChain: "netout--container-id-2",
+					Prefix:      planner.EgressChainPrefixFor("container-id-2"),
+				},
+				Rules: []rules.IPTablesRule{
+					{"-d", "10.0.0.0/8", "--jump", "ACCEPT"},
+				},
+			}))
+			Expect(rulesWithChains).To(ContainElement(enforcer.RulesWithChain{
+				Chain: enforcer.Chain{
+					Table:       "filter",
+					ParentChain: "netout--container-id-2",
+					Prefix:      planner.EgressChainPrefixFor("container-id-2"),
+					IPv6:        true,
+				},
+				Rules: []rules.IPTablesRule{
+					{"-d", "fd00::/8", "-p", "icmpv6", "--jump", "ACCEPT"},
+				},
+			}))
+		})
+	})
+
+	It("emits the policy server poll time", func() {
+		_, err := egressPlanner.GetRulesAndChains()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(metricsSender.SendDurationCallCount()).To(Equal(1))
+		name, _ := metricsSender.SendDurationArgsForCall(0)
+		Expect(name).To(Equal("egressPolicyServerPollTime"))
+	})
+
+	Describe("chain names", func() {
+		It("leaves room for the enforcer timestamp in the egress chain prefix", func() {
+			prefix := planner.EgressChainPrefixFor("some-very-long-container-handle")
+			Expect(prefix).To(HavePrefix("egress--"))
+			Expect(len(prefix) + 10).To(BeNumerically("<=", 28))
+			Expect(planner.EgressChainPrefixFor("some-handle-1")).NotTo(Equal(planner.EgressChainPrefixFor("some-handle-2")))
+		})
+	})
+
+	Context("when there are no containers with metadata", func() {
+		BeforeEach(func() {
+			store.ReadAllReturns(map[string]datastore.Container{}, nil)
+		})
+
+		It("plans no chains and does not call the policy server", func() {
+			rulesWithChains, err := egressPlanner.GetRulesAndChains()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rulesWithChains).To(BeEmpty())
+			Expect(policyClient.GetEgressPoliciesByIDCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when reading the datastore fails", func() {
+		BeforeEach(func() {
+			store.ReadAllReturns(nil, errors.New("banana"))
+		})
+
+		It("returns the error", func() {
+			_, err := egressPlanner.GetRulesAndChains()
+			Expect(err).To(MatchError("banana"))
+		})
+	})
+
+	Context("when getting egress policies fails", func() {
+		BeforeEach(func() {
+			policyClient.GetEgressPoliciesByIDReturns(nil, errors.New("kiwi"))
+		})
+
+		It("logs and returns the error", func() {
+			_, err := egressPlanner.GetRulesAndChains()
+			Expect(err).To(MatchError("kiwi"))
+			Expect(logger.Logs()[0].Message).To(Equal("test.policy-client-get-egress-policies"))
+		})
+	})
+})
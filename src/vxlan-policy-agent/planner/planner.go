@@ -39,6 +39,14 @@ type VxlanPolicyPlanner struct {
 	MetricsSender metricsSender
 	Chain         enforcer.Chain
 	LoggingState  loggingStateGetter
+	// IPv6 plans the IPv6 addresses of the containers on a second chain,
+	// like Chain but enforced with ip6tables. Without it, as on cells
+	// without ip6tables, those addresses are left out.
+	IPv6 bool
+
+	// the policies of the last fetch and the groups they were fetched for
+	policies     []models.Policy
+	policyGroups map[string]struct{}
 }
 
 type Container struct {
@@ -62,17 +70,31 @@ func (p *VxlanPolicyPlanner) getContainersMap(allContainers map[string]datastore
 			p.Logger.Debug("container-metadata-policy-group-id", lager.Data{"container_handle": container.Handle, "message": message})
 			continue
 		}
-		containers[groupID] = append(containers[groupID], container.IP)
+		containers[groupID] = append(containers[groupID], container.AllIPs()...)
 	}
 	return containers, nil
 }
 
-func (p *VxlanPolicyPlanner) GetRulesAndChain() (enforcer.RulesWithChain, error) {
+// GetRulesAndChains plans the rules of Chain and, with IPv6, of its IPv6
+// counterpart.
+func (p *VxlanPolicyPlanner) GetRulesAndChains() ([]enforcer.RulesWithChain, error) {
+	return p.getRulesAndChains(false)
+}
+
+// GetRulesAndChainsForChanges plans for the containers on the cell after they
+// changed, only fetching the policies of groups that had no containers on the
+// cell at the last fetch. The policies of the other groups are refreshed by
+// the next GetRulesAndChains.
+func (p *VxlanPolicyPlanner) GetRulesAndChainsForChanges() ([]enforcer.RulesWithChain, error) {
+	return p.getRulesAndChains(true)
+}
+
+func (p *VxlanPolicyPlanner) getRulesAndChains(changesOnly bool) ([]enforcer.RulesWithChain, error) {
 	containerMetadataStartTime := time.Now()
 	containerMetadata, err := p.Datastore.ReadAll()
 	if err != nil {
 		p.Logger.Error("datastore", err)
-		return enforcer.RulesWithChain{}, err
+		return nil, err
 	}
 
 	containers, err := p.getContainersMap(containerMetadata)
@@ -84,85 +106,207 @@ func (p *VxlanPolicyPlanner) GetRulesAndChain() (enforcer.RulesWithChain, error)
 	}
 	if err != nil {
 		p.Logger.Error("container-info", err)
-		return enforcer.RulesWithChain{}, err
+		return nil, err
 	}
 	containerMetadataDuration := time.Now().Sub(containerMetadataStartTime)
 	p.Logger.Debug("got-containers", lager.Data{"containers": containers})
 
 	policyServerStartRequestTime := time.Now()
 	var policies []models.Policy
-	if len(groupIDs) > 0 {
-		policies, err = p.PolicyClient.GetPoliciesByID(groupIDs...)
-		if err != nil {
-			p.Logger.Error("policy-client-get-policies", err)
-			return enforcer.RulesWithChain{}, err
-		}
+	if changesOnly && p.policyGroups != nil {
+		policies, err = p.fetchNewGroupPolicies(groupIDs)
+	} else {
+		policies, err = p.fetchPolicies(groupIDs)
+	}
+	if err != nil {
+		p.Logger.Error("policy-client-get-policies", err)
+		return nil, err
 	}
 
 	policyServerPollDuration := time.Now().Sub(policyServerStartRequestTime)
 	p.MetricsSender.SendDuration(metricContainerMetadata, containerMetadataDuration)
 	p.MetricsSender.SendDuration(metricPolicyServerPoll, policyServerPollDuration)
 
+	policySlice := models.PolicySlice(append([]models.Policy{}, policies...))
+	sort.Sort(policySlice)
+
+	// each IP family of the containers is planned on a chain of its own
+	ruleSets := []enforcer.RulesWithChain{p.rulesWithChain(containers, policySlice, false)}
+	if p.IPv6 {
+		ruleSets = append(ruleSets, p.rulesWithChain(containers, policySlice, true))
+	}
+	return ruleSets, nil
+}
+
+func (p *VxlanPolicyPlanner) rulesWithChain(containers map[string][]string, policies []models.Policy, ipv6 bool) enforcer.RulesWithChain {
 	marksRuleset := []rules.IPTablesRule{}
 	markedSourceIPs := make(map[string]struct{})
 	filterRuleset := []rules.IPTablesRule{}
 
 	iptablesLoggingEnabled := p.LoggingState.IsEnabled()
-	policySlice := models.PolicySlice(policies)
-	sort.Sort(policySlice)
-	for _, policy := range policySlice {
-		srcContainerIPs, srcOk := containers[policy.Source.ID]
-		dstContainerIPs, dstOk := containers[policy.Destination.ID]
-
-		if dstOk {
-			// there are some containers on this host that are dests for the policy
-			ips := sort.StringSlice(dstContainerIPs)
-			sort.Sort(ips)
-			for _, dstContainerIP := range ips {
-				if iptablesLoggingEnabled {
-					filterRuleset = append(
-						filterRuleset,
-						rules.NewMarkAllowLogRule(
-							dstContainerIP,
-							policy.Destination.Protocol,
-							policy.Destination.Port,
-							policy.Source.Tag,
-							policy.Destination.ID,
-						),
-					)
-				}
-				filterRuleset = append(
-					filterRuleset,
-					rules.NewMarkAllowRule(
-						dstContainerIP,
-						policy.Destination.Protocol,
-						policy.Destination.Port,
-						policy.Source.Tag,
-						policy.Source.ID,
-						policy.Destination.ID,
-					),
-				)
+	for _, policy := range policies {
+		srcContainerIPs := ipsOfFamily(containers[policy.Source.ID], ipv6)
+		dstContainerIPs := ipsOfFamily(containers[policy.Destination.ID], ipv6)
+
+		// the ICMP types of policies are ICMPv4 ones, which ip6tables
+		// would read as different ICMPv6 types
+		if ipv6 && policy.Destination.Protocol == models.ProtocolICMP && policy.Destination.ICMPType != nil {
+			dstContainerIPs = nil
+		}
+
+		// the containers on this host that are dests for the policy
+		for _, dstContainerIP := range dstContainerIPs {
+			if iptablesLoggingEnabled {
+				filterRuleset = append(filterRuleset, markAllowLogRule(dstContainerIP, policy))
 			}
+			filterRuleset = append(filterRuleset, markAllowRule(dstContainerIP, policy))
 		}
 
-		if srcOk {
-			// there are some containers on this host that are sources for the policy
-			ips := sort.StringSlice(srcContainerIPs)
-			sort.Sort(ips)
-			for _, srcContainerIP := range ips {
-				_, added := markedSourceIPs[srcContainerIP]
-				if !added {
-					rule := rules.NewMarkSetRule(srcContainerIP, policy.Source.Tag, policy.Source.ID)
-					marksRuleset = append(marksRuleset, rule)
-					markedSourceIPs[srcContainerIP] = struct{}{}
-				}
+		// set tags on the packets of the containers on this host that are
+		// sources for the policy
+		for _, srcContainerIP := range srcContainerIPs {
+			_, added := markedSourceIPs[srcContainerIP]
+			if !added {
+				rule := rules.NewMarkSetRule(srcContainerIP, policy.Source.Tag, policy.Source.ID)
+				marksRuleset = append(marksRuleset, rule)
+				markedSourceIPs[srcContainerIP] = struct{}{}
 			}
 		}
 	}
 	ruleset := append(marksRuleset, filterRuleset...)
-	p.Logger.Debug("generated-rules", lager.Data{"rules": ruleset})
+	if ipv6 {
+		for i, rule := range ruleset {
+			ruleset[i] = rules.ToIPv6(rule)
+		}
+	}
+
+	chain := p.Chain
+	chain.IPv6 = ipv6
+	p.Logger.Debug("generated-rules", lager.Data{"chain": chain, "rules": ruleset})
 	return enforcer.RulesWithChain{
-		Chain: p.Chain,
+		Chain: chain,
 		Rules: ruleset,
-	}, nil
+	}
+}
+
+// ipsOfFamily returns the IPv4 or the IPv6 addresses among ips, sorted.
+func ipsOfFamily(ips []string, ipv6 bool) []string {
+	family := []string{}
+	for _, ip := range ips {
+		if rules.IsIPv6(ip) == ipv6 {
+			family = append(family, ip)
+		}
+	}
+	sort.Strings(family)
+	return family
+}
+
+func (p *VxlanPolicyPlanner) fetchPolicies(groupIDs []string) ([]models.Policy, error) {
+	var policies []models.Policy
+	if len(groupIDs) > 0 {
+		var err error
+		policies, err = p.PolicyClient.GetPoliciesByID(groupIDs...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.policies = policies
+	p.policyGroups = make(map[string]struct{})
+	for _, groupID := range groupIDs {
+		p.policyGroups[groupID] = struct{}{}
+	}
+	return policies, nil
+}
+
+// fetchNewGroupPolicies adds the policies of the groups missing from the last
+// fetch to its policies. Those policies that involve a group of the last fetch
+// were already part of it.
+func (p *VxlanPolicyPlanner) fetchNewGroupPolicies(groupIDs []string) ([]models.Policy, error) {
+	var newGroupIDs []string
+	for _, groupID := range groupIDs {
+		if _, ok := p.policyGroups[groupID]; !ok {
+			newGroupIDs = append(newGroupIDs, groupID)
+		}
+	}
+	if len(newGroupIDs) == 0 {
+		return p.policies, nil
+	}
+
+	fetched, err := p.PolicyClient.GetPoliciesByID(newGroupIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := append([]models.Policy{}, p.policies...)
+	for _, policy := range fetched {
+		_, srcFetched := p.policyGroups[policy.Source.ID]
+		_, dstFetched := p.policyGroups[policy.Destination.ID]
+		if !srcFetched && !dstFetched {
+			policies = append(policies, policy)
+		}
+	}
+
+	p.policies = policies
+	for _, groupID := range newGroupIDs {
+		p.policyGroups[groupID] = struct{}{}
+	}
+	return policies, nil
+}
+
+func markAllowRule(dstContainerIP string, policy models.Policy) rules.IPTablesRule {
+	switch policy.Destination.Protocol {
+	case models.ProtocolICMP:
+		return rules.NewMarkAllowICMPRule(
+			dstContainerIP,
+			policy.Destination.ICMPType,
+			policy.Destination.ICMPCode,
+			policy.Source.Tag,
+			policy.Source.ID,
+			policy.Destination.ID,
+		)
+	case models.ProtocolAll:
+		return rules.NewMarkAllowAllRule(
+			dstContainerIP,
+			policy.Source.Tag,
+			policy.Source.ID,
+			policy.Destination.ID,
+		)
+	default:
+		return rules.NewMarkAllowRule(
+			dstContainerIP,
+			policy.Destination.Protocol,
+			policy.Destination.Port,
+			policy.Source.Tag,
+			policy.Source.ID,
+			policy.Destination.ID,
+		)
+	}
+}
+
+func markAllowLogRule(dstContainerIP string, policy models.Policy) rules.IPTablesRule {
+	switch policy.Destination.Protocol {
+	case models.ProtocolICMP:
+		return rules.NewMarkAllowICMPLogRule(
+			dstContainerIP,
+			policy.Destination.ICMPType,
+			policy.Destination.ICMPCode,
+			policy.Source.Tag,
+			policy.Destination.ID,
+		)
+	case models.ProtocolAll:
+		return rules.NewMarkAllowAllLogRule(
+			dstContainerIP,
+			policy.Source.Tag,
+			policy.Destination.ID,
+		)
+	default:
+		return rules.NewMarkAllowLogRule(
+			dstContainerIP,
+			policy.Destination.Protocol,
+			policy.Destination.Port,
+			policy.Source.Tag,
+			policy.Destination.ID,
+		)
+	}
 }
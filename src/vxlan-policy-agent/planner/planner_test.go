@@ -31,6 +31,15 @@ var _ = Describe("Planner", func() {
 		loggingStateGetter   *fakes.LoggingStateGetter
 	)
 
+	// onlyRulesWithChain is the IPv4 chain, the only one planned without IPv6
+	onlyRulesWithChain := func(ruleSets []enforcer.RulesWithChain, err error) (enforcer.RulesWithChain, error) {
+		if err != nil {
+			return enforcer.RulesWithChain{}, err
+		}
+		Expect(ruleSets).To(HaveLen(1))
+		return ruleSets[0], nil
+	}
+
 	BeforeEach(func() {
 		logger = lagertest.NewTestLogger("test")
 		policyClient = &fakes.PolicyClient{}
@@ -115,16 +124,16 @@ var _ = Describe("Planner", func() {
 		}
 	})
 
-	Describe("GetRulesAndChain", func() {
+	Describe("GetRulesAndChains", func() {
 		It("gets every container's properties from the datastore", func() {
-			_, err := policyPlanner.GetRulesAndChain()
+			_, err := policyPlanner.GetRulesAndChains()
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(store.ReadAllCallCount()).To(Equal(1))
 		})
 
 		It("gets policies from the policy server", func() {
-			_, err := policyPlanner.GetRulesAndChain()
+			_, err := policyPlanner.GetRulesAndChains()
 			Expect(err).NotTo(HaveOccurred())
 
 			By("filtering by ID when calling the internal policy server")
@@ -137,7 +146,7 @@ var _ = Describe("Planner", func() {
 				loggingStateGetter.IsEnabledReturns(false)
 			})
 			It("returns all the rules but no logging rules", func() {
-				rulesWithChain, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(rulesWithChain.Chain).To(Equal(chain))
 
@@ -179,7 +188,7 @@ var _ = Describe("Planner", func() {
 				loggingStateGetter.IsEnabledReturns(true)
 			})
 			It("returns all the rules including logging rules", func() {
-				rulesWithChain, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(rulesWithChain.Chain).To(Equal(chain))
 
@@ -238,7 +247,7 @@ var _ = Describe("Planner", func() {
 		})
 
 		It("returns all mark set rules before any mark filter rules", func() {
-			rulesWithChain, err := policyPlanner.GetRulesAndChain()
+			rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 			Expect(err).NotTo(HaveOccurred())
 			Expect(rulesWithChain.Rules).To(HaveLen(4))
 			Expect(rulesWithChain.Rules[0]).To(ContainElement("--set-xmark"))
@@ -248,7 +257,7 @@ var _ = Describe("Planner", func() {
 		})
 
 		It("emits time metrics", func() {
-			_, err := policyPlanner.GetRulesAndChain()
+			_, err := policyPlanner.GetRulesAndChains()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(metricsSender.SendDurationCallCount()).To(Equal(2))
 			name, _ := metricsSender.SendDurationArgsForCall(0)
@@ -266,10 +275,10 @@ var _ = Describe("Planner", func() {
 			})
 
 			It("the order of the rules is not affected", func() {
-				rulesWithChain, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 				policyClient.GetPoliciesByIDReturns(reversed, nil)
-				rulesWithChain2, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain2, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(rulesWithChain).To(Equal(rulesWithChain2))
@@ -307,7 +316,7 @@ var _ = Describe("Planner", func() {
 			})
 
 			It("writes only one set mark rule", func() {
-				rulesWithChain, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(rulesWithChain.Rules).To(HaveLen(3))
 				Expect(rulesWithChain.Rules[0]).To(ContainElement("--set-xmark"))
@@ -316,6 +325,74 @@ var _ = Describe("Planner", func() {
 			})
 		})
 
+		Context("when policies are for icmp or all protocols", func() {
+			BeforeEach(func() {
+				echoRequest := 8
+				policyServerResponse = []models.Policy{
+					{
+						Source: models.Source{
+							ID:  "some-app-guid",
+							Tag: "AA",
+						},
+						Destination: models.Destination{
+							ID:       "some-other-app-guid",
+							Protocol: "icmp",
+							ICMPType: &echoRequest,
+						},
+					},
+					{
+						Source: models.Source{
+							ID:  "another-app-guid",
+							Tag: "BB",
+						},
+						Destination: models.Destination{
+							ID:       "some-other-app-guid",
+							Protocol: "all",
+						},
+					},
+				}
+				policyClient.GetPoliciesByIDReturns(policyServerResponse, nil)
+				loggingStateGetter.IsEnabledReturns(true)
+			})
+
+			It("writes icmp and protocol-less rules", func() {
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rulesWithChain.Rules).To(gomegamatchers.ContainSequence([]rules.IPTablesRule{
+					{
+						"-d", "10.255.1.3",
+						"-p", "icmp",
+						"-m", "icmp", "--icmp-type", "8",
+						"-m", "mark", "--mark", "0xAA",
+						"-m", "conntrack", "--ctstate", "INVALID,NEW,UNTRACKED",
+						"--jump", "LOG", "--log-prefix", `"OK_AA_some-other-app-guid "`,
+					},
+					{
+						"-d", "10.255.1.3",
+						"-p", "icmp",
+						"-m", "icmp", "--icmp-type", "8",
+						"-m", "mark", "--mark", "0xAA",
+						"--jump", "ACCEPT",
+						"-m", "comment", "--comment", "src:some-app-guid_dst:some-other-app-guid",
+					},
+				}))
+				Expect(rulesWithChain.Rules).To(gomegamatchers.ContainSequence([]rules.IPTablesRule{
+					{
+						"-d", "10.255.1.3",
+						"-m", "mark", "--mark", "0xBB",
+						"-m", "conntrack", "--ctstate", "INVALID,NEW,UNTRACKED",
+						"--jump", "LOG", "--log-prefix", `"OK_BB_some-other-app-guid "`,
+					},
+					{
+						"-d", "10.255.1.3",
+						"-m", "mark", "--mark", "0xBB",
+						"--jump", "ACCEPT",
+						"-m", "comment", "--comment", "src:another-app-guid_dst:some-other-app-guid",
+					},
+				}))
+			})
+		})
+
 		Context("when there are multiple containers for an app on the cell", func() {
 			BeforeEach(func() {
 				data = make(map[string]datastore.Container)
@@ -352,7 +429,7 @@ var _ = Describe("Planner", func() {
 			})
 
 			It("the order of the rules is not affected", func() {
-				rulesWithChain, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(rulesWithChain.Rules).To(HaveLen(8))
 				Expect(rulesWithChain.Rules[0]).To(ContainElement("10.255.1.2"))
@@ -367,7 +444,7 @@ var _ = Describe("Planner", func() {
 				policyClient.GetPoliciesByIDReturns([]models.Policy{}, nil)
 			})
 			It("returns an chain with no rules", func() {
-				rulesWithChain, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(policyClient.GetPoliciesByIDCallCount()).To(Equal(1))
 
@@ -383,7 +460,7 @@ var _ = Describe("Planner", func() {
 			})
 
 			It("does not call the policy client", func() {
-				rulesWithChain, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(policyClient.GetPoliciesByIDCallCount()).To(Equal(0))
 
@@ -404,7 +481,7 @@ var _ = Describe("Planner", func() {
 			})
 
 			It("logs an error for that container and returns rules for other containers", func() {
-				rulesWithChain, err := policyPlanner.GetRulesAndChain()
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
 				Expect(err).NotTo(HaveOccurred())
 				Expect(logger).To(gbytes.Say("container-metadata-policy-group-id.*container-id-fruit.*Container.*metadata.*policy_group_id.*CloudController.*restage"))
 
@@ -413,13 +490,148 @@ var _ = Describe("Planner", func() {
 			})
 		})
 
+		Context("when containers have IPv6 addresses", func() {
+			BeforeEach(func() {
+				data["container-id-1"] = datastore.Container{
+					Handle: "container-id-1",
+					IP:     "10.255.1.2",
+					IPs:    []string{"10.255.1.2", "fd00::2"},
+					Metadata: map[string]interface{}{
+						"policy_group_id": "some-app-guid",
+					},
+				}
+				data["container-id-2"] = datastore.Container{
+					Handle: "container-id-2",
+					IP:     "fd00::3",
+					IPs:    []string{"fd00::3"},
+					Metadata: map[string]interface{}{
+						"policy_group_id": "some-other-app-guid",
+					},
+				}
+			})
+
+			It("leaves them out of the IPv4 chain", func() {
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(rulesWithChain.Chain).To(Equal(chain))
+				Expect(rulesWithChain.Rules).To(Equal([]rules.IPTablesRule{
+					{
+						"--source", "10.255.1.2",
+						"--jump", "MARK", "--set-xmark", "0xAA",
+						"-m", "comment", "--comment", "src:some-app-guid",
+					},
+				}))
+			})
+
+			Context("when IPv6 is planned", func() {
+				var ipv6Chain enforcer.Chain
+
+				BeforeEach(func() {
+					policyPlanner.IPv6 = true
+					ipv6Chain = chain
+					ipv6Chain.IPv6 = true
+				})
+
+				It("plans the IPv6 addresses of dual-stack and IPv6-only containers on an IPv6 chain", func() {
+					rulesWithChains, err := policyPlanner.GetRulesAndChains()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(rulesWithChains).To(HaveLen(2))
+
+					Expect(rulesWithChains[0].Chain).To(Equal(chain))
+					Expect(rulesWithChains[0].Rules).To(HaveLen(1))
+					Expect(rulesWithChains[0].Rules[0]).To(ContainElement("10.255.1.2"))
+
+					Expect(rulesWithChains[1].Chain).To(Equal(ipv6Chain))
+					Expect(rulesWithChains[1].Rules).To(ConsistOf([]rules.IPTablesRule{
+						{
+							"-d", "fd00::3",
+							"-p", "udp",
+							"--dport", "5555",
+							"-m", "mark", "--mark", "0xBB",
+							"--jump", "ACCEPT",
+							"-m", "comment", "--comment", "src:another-app-guid_dst:some-other-app-guid",
+						},
+						{
+							"-d", "fd00::3",
+							"-p", "tcp",
+							"--dport", "1234",
+							"-m", "mark", "--mark", "0xAA",
+							"--jump", "ACCEPT",
+							"-m", "comment", "--comment", "src:some-app-guid_dst:some-other-app-guid",
+						},
+						{
+							"--source", "fd00::2",
+							"--jump", "MARK", "--set-xmark", "0xAA",
+							"-m", "comment", "--comment", "src:some-app-guid",
+						},
+						{
+							"--source", "fd00::3",
+							"--jump", "MARK", "--set-xmark", "0xCC",
+							"-m", "comment", "--comment", "src:some-other-app-guid",
+						},
+					}))
+				})
+
+				Context("when policies are for icmp", func() {
+					BeforeEach(func() {
+						echoRequest := 8
+						policyClient.GetPoliciesByIDReturns([]models.Policy{
+							{
+								Source: models.Source{
+									ID:  "some-app-guid",
+									Tag: "AA",
+								},
+								Destination: models.Destination{
+									ID:       "some-other-app-guid",
+									Protocol: "icmp",
+									ICMPType: &echoRequest,
+								},
+							},
+							{
+								Source: models.Source{
+									ID:  "another-app-guid",
+									Tag: "BB",
+								},
+								Destination: models.Destination{
+									ID:       "some-other-app-guid",
+									Protocol: "icmp",
+								},
+							},
+						}, nil)
+					})
+
+					It("allows icmpv6 for the policies without an ICMP type only", func() {
+						rulesWithChains, err := policyPlanner.GetRulesAndChains()
+						Expect(err).NotTo(HaveOccurred())
+						Expect(rulesWithChains).To(HaveLen(2))
+
+						Expect(rulesWithChains[1].Rules).To(ConsistOf([]rules.IPTablesRule{
+							{
+								"--source", "fd00::2",
+								"--jump", "MARK", "--set-xmark", "0xAA",
+								"-m", "comment", "--comment", "src:some-app-guid",
+							},
+							{
+								"-d", "fd00::3",
+								"-p", "icmpv6",
+								"-m", "mark", "--mark", "0xBB",
+								"--jump", "ACCEPT",
+								"-m", "comment", "--comment", "src:another-app-guid_dst:some-other-app-guid",
+							},
+						}))
+					})
+				})
+			})
+		})
+
 		Context("when getting containers from datastore fails", func() {
 			BeforeEach(func() {
 				store.ReadAllReturns(nil, errors.New("banana"))
 			})
 
 			It("logs and returns the error", func() {
-				_, err := policyPlanner.GetRulesAndChain()
+				_, err := policyPlanner.GetRulesAndChains()
 				Expect(err).To(MatchError("banana"))
 				Expect(logger).To(gbytes.Say("datastore.*banana"))
 			})
@@ -431,10 +643,127 @@ var _ = Describe("Planner", func() {
 			})
 
 			It("logs and returns the error", func() {
-				_, err := policyPlanner.GetRulesAndChain()
+				_, err := policyPlanner.GetRulesAndChains()
 				Expect(err).To(MatchError("kiwi"))
 				Expect(logger).To(gbytes.Say("policy-client-get-policies.*kiwi"))
 			})
 		})
 	})
+
+	Describe("GetRulesAndChainsForChanges", func() {
+		rulesWithPort := func(ruleSet []rules.IPTablesRule, port string) int {
+			count := 0
+			for _, rule := range ruleSet {
+				for _, arg := range rule {
+					if arg == port {
+						count++
+						break
+					}
+				}
+			}
+			return count
+		}
+
+		Context("before the policies were fetched", func() {
+			It("fetches the policies of every group", func() {
+				rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChainsForChanges())
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(policyClient.GetPoliciesByIDCallCount()).To(Equal(1))
+				Expect(policyClient.GetPoliciesByIDArgsForCall(0)).To(ConsistOf("some-app-guid", "some-other-app-guid"))
+
+				fullRulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(rulesWithChain).To(Equal(fullRulesWithChain))
+			})
+		})
+
+		Context("after the policies were fetched", func() {
+			BeforeEach(func() {
+				_, err := policyPlanner.GetRulesAndChains()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(policyClient.GetPoliciesByIDCallCount()).To(Equal(1))
+			})
+
+			Context("when only containers of known groups changed", func() {
+				BeforeEach(func() {
+					delete(data, "container-id-2")
+				})
+
+				It("plans with the fetched policies", func() {
+					rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChainsForChanges())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(policyClient.GetPoliciesByIDCallCount()).To(Equal(1))
+
+					fullRulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChains())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(rulesWithChain).To(Equal(fullRulesWithChain))
+				})
+			})
+
+			Context("when a container of a new group was added", func() {
+				BeforeEach(func() {
+					data["container-id-4"] = datastore.Container{
+						Handle: "container-id-4",
+						IP:     "10.255.1.6",
+						Metadata: map[string]interface{}{
+							"policy_group_id": "another-app-guid",
+						},
+					}
+
+					policyClient.GetPoliciesByIDReturns([]models.Policy{
+						policyServerResponse[1],
+						{
+							Source: models.Source{
+								ID:  "another-app-guid",
+								Tag: "BB",
+							},
+							Destination: models.Destination{
+								ID:       "another-app-guid",
+								Port:     8080,
+								Protocol: "tcp",
+							},
+						},
+					}, nil)
+				})
+
+				It("only fetches the policies of the new group", func() {
+					rulesWithChain, err := onlyRulesWithChain(policyPlanner.GetRulesAndChainsForChanges())
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(policyClient.GetPoliciesByIDCallCount()).To(Equal(2))
+					Expect(policyClient.GetPoliciesByIDArgsForCall(1)).To(ConsistOf("another-app-guid"))
+
+					Expect(rulesWithPort(rulesWithChain.Rules, "8080")).To(Equal(1))
+					Expect(rulesWithPort(rulesWithChain.Rules, "5555")).To(Equal(1))
+					Expect(rulesWithPort(rulesWithChain.Rules, "1234")).To(Equal(1))
+				})
+
+				It("reuses the policies of the new group afterwards", func() {
+					_, err := policyPlanner.GetRulesAndChainsForChanges()
+					Expect(err).NotTo(HaveOccurred())
+					_, err = policyPlanner.GetRulesAndChainsForChanges()
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(policyClient.GetPoliciesByIDCallCount()).To(Equal(2))
+				})
+
+				Context("when getting the policies fails", func() {
+					BeforeEach(func() {
+						policyClient.GetPoliciesByIDReturns(nil, errors.New("kiwi"))
+					})
+
+					It("logs and returns the error, and fetches them again next time", func() {
+						_, err := policyPlanner.GetRulesAndChainsForChanges()
+						Expect(err).To(MatchError("kiwi"))
+						Expect(logger).To(gbytes.Say("policy-client-get-policies.*kiwi"))
+
+						_, err = policyPlanner.GetRulesAndChainsForChanges()
+						Expect(err).To(HaveOccurred())
+						Expect(policyClient.GetPoliciesByIDCallCount()).To(Equal(3))
+					})
+				})
+			})
+		})
+	})
 })
@@ -0,0 +1,38 @@
+package reconciler
+
+import "code.cloudfoundry.org/cf-networking-helpers/metrics"
+
+//go:generate counterfeiter -o fakes/leak_reporter.go --fake-name LeakReporter . leakReporter
+type leakReporter interface {
+	LastReport() Report
+}
+
+func NewLeakedDatastoreEntriesSource(reporter leakReporter) metrics.MetricSource {
+	return leakSource("leakedDatastoreEntries", reporter, func(r Report) []string { return r.DatastoreEntries })
+}
+
+func NewLeakedPortAllocationsSource(reporter leakReporter) metrics.MetricSource {
+	return leakSource("leakedPortAllocations", reporter, func(r Report) []string { return r.PortAllocations })
+}
+
+func NewLeakedIPTablesChainsSource(reporter leakReporter) metrics.MetricSource {
+	return leakSource("leakedIPTablesChains", reporter, func(r Report) []string { return r.Chains })
+}
+
+func NewLeakedMasqueradeRulesSource(reporter leakReporter) metrics.MetricSource {
+	return leakSource("leakedMasqueradeRules", reporter, func(r Report) []string { return r.MasqueradeRules })
+}
+
+func NewPendingLeaksSource(reporter leakReporter) metrics.MetricSource {
+	return leakSource("pendingLeakedContainerState", reporter, func(r Report) []string { return r.Pending })
+}
+
+func leakSource(name string, reporter leakReporter, leaks func(Report) []string) metrics.MetricSource {
+	return metrics.MetricSource{
+		Name: name,
+		Unit: "",
+		Getter: func() (float64, error) {
+			return float64(len(leaks(reporter.LastReport()))), nil
+		},
+	}
+}
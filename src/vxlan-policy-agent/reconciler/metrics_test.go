@@ -0,0 +1,49 @@
+package reconciler_test
+
+import (
+	"vxlan-policy-agent/reconciler"
+	"vxlan-policy-agent/reconciler/fakes"
+
+	"code.cloudfoundry.org/cf-networking-helpers/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+	var reporter *fakes.LeakReporter
+
+	BeforeEach(func() {
+		reporter = &fakes.LeakReporter{}
+		reporter.LastReportReturns(reconciler.Report{
+			DatastoreEntries: []string{"a"},
+			PortAllocations:  []string{"a", "b"},
+			Chains:           []string{"a", "b", "c"},
+			MasqueradeRules:  []string{"a", "b", "c", "d"},
+			Pending:          []string{"a", "b", "c", "d", "e"},
+		})
+	})
+
+	It("counts each kind of leak in the last report", func() {
+		values := map[string]float64{}
+		for _, source := range []metrics.MetricSource{
+			reconciler.NewLeakedDatastoreEntriesSource(reporter),
+			reconciler.NewLeakedPortAllocationsSource(reporter),
+			reconciler.NewLeakedIPTablesChainsSource(reporter),
+			reconciler.NewLeakedMasqueradeRulesSource(reporter),
+			reconciler.NewPendingLeaksSource(reporter),
+		} {
+			value, err := source.Getter()
+			Expect(err).NotTo(HaveOccurred())
+			values[source.Name] = value
+		}
+
+		Expect(values).To(Equal(map[string]float64{
+			"leakedDatastoreEntries":      1,
+			"leakedPortAllocations":       2,
+			"leakedIPTablesChains":        3,
+			"leakedMasqueradeRules":       4,
+			"pendingLeakedContainerState": 5,
+		}))
+	})
+})
@@ -0,0 +1,385 @@
+package reconciler
+
+import (
+	"fmt"
+	"lib/datastore"
+	"lib/rules"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"vxlan-policy-agent/planner"
+
+	"code.cloudfoundry.org/garden"
+	"code.cloudfoundry.org/lager"
+)
+
+//go:generate counterfeiter -o fakes/garden_client.go --fake-name GardenClient . gardenClient
+type gardenClient interface {
+	Containers(garden.Properties) ([]garden.Container, error)
+}
+
+//go:generate counterfeiter -o fakes/port_allocator.go --fake-name PortAllocator . portAllocator
+type portAllocator interface {
+	AllocatedHandles() ([]string, error)
+	ReleaseAllPorts(handle string) error
+}
+
+type chainNamer interface {
+	Prefix(prefix, body string) string
+	Postfix(body, suffix string) (string, error)
+}
+
+// containerChainPrefixes are the prefixes cni-wrapper-plugin names the chains
+// of a container with, see legacynet.
+var containerChainPrefixes = []string{"netin", "netout", "input", "overlay"}
+
+const egressChainPrefix = "egress--"
+const netOutLogSuffix = "log"
+
+// parentChains are the built-in chains that jump to container chains.
+var parentChains = map[string][]string{
+	"filter": {"FORWARD", "INPUT"},
+	"nat":    {"PREROUTING"},
+	"mangle": {"PREROUTING"},
+}
+
+var chainTables = []string{"filter", "nat", "mangle"}
+
+// ipFamily is the iptables of one IP family. Its label prefixes the chains it
+// reports, so that the chains of both families can be told apart.
+type ipFamily struct {
+	label    string
+	iptables rules.IPTablesAdapter
+}
+
+type Report struct {
+	DatastoreEntries []string
+	PortAllocations  []string
+	Chains           []string
+	MasqueradeRules  []string
+	Pending          []string
+}
+
+// Reconciler removes the state cni-wrapper-plugin and garden-external-networker
+// leave behind when a container is not cleaned up completely. State is only
+// removed once it has been orphaned on GraceRuns consecutive runs, so that
+// containers that are still being created are not torn down.
+type Reconciler struct {
+	Logger        lager.Logger
+	GardenClient  gardenClient
+	Datastore     datastore.Datastore
+	PortAllocator portAllocator
+	IPTables      rules.IPTablesAdapter
+	// IP6Tables holds the chains and IP masquerade rules of IPv6 containers.
+	// It is nil on cells without ip6tables.
+	IP6Tables  rules.IPTablesAdapter
+	ChainNamer chainNamer
+	// VTEPName is the device the IP masquerade rules of containers exclude,
+	// see rules.NewDefaultEgressRule.
+	VTEPName  string
+	GraceRuns int
+
+	mutex      sync.Mutex
+	leakRuns   map[string]int
+	lastReport Report
+}
+
+func (r *Reconciler) Reconcile() (Report, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	containers, err := r.GardenClient.Containers(garden.Properties{})
+	if err != nil {
+		r.Logger.Error("garden-list-containers-failed", err)
+		return Report{}, fmt.Errorf("list garden containers: %s", err)
+	}
+	liveHandles := make(map[string]struct{})
+	for _, container := range containers {
+		liveHandles[container.Handle()] = struct{}{}
+	}
+
+	entries, err := r.Datastore.ReadAll()
+	if err != nil {
+		r.Logger.Error("datastore-read-failed", err)
+		return Report{}, fmt.Errorf("read datastore: %s", err)
+	}
+
+	allocatedHandles, err := r.PortAllocator.AllocatedHandles()
+	if err != nil {
+		r.Logger.Error("list-port-allocations-failed", err)
+		return Report{}, fmt.Errorf("list port allocations: %s", err)
+	}
+
+	report := Report{
+		DatastoreEntries: []string{},
+		PortAllocations:  []string{},
+		Chains:           []string{},
+		MasqueradeRules:  []string{},
+		Pending:          []string{},
+	}
+	orphans := make(map[string]struct{})
+
+	liveIPs := make(map[string]struct{})
+	for _, handle := range sortedKeys(entries) {
+		if _, ok := liveHandles[handle]; ok {
+			for _, ip := range entries[handle].AllIPs() {
+				liveIPs[ip] = struct{}{}
+			}
+			continue
+		}
+		if !r.leaked("datastore:"+handle, orphans, &report) {
+			continue
+		}
+		report.DatastoreEntries = append(report.DatastoreEntries, handle)
+		if _, err := r.Datastore.Delete(handle); err != nil {
+			r.Logger.Error("datastore-delete-failed", err, lager.Data{"handle": handle})
+		}
+	}
+
+	for _, handle := range allocatedHandles {
+		if _, ok := liveHandles[handle]; ok {
+			continue
+		}
+		if !r.leaked("ports:"+handle, orphans, &report) {
+			continue
+		}
+		report.PortAllocations = append(report.PortAllocations, handle)
+		if err := r.PortAllocator.ReleaseAllPorts(handle); err != nil {
+			r.Logger.Error("release-ports-failed", err, lager.Data{"handle": handle})
+		}
+	}
+
+	ownedChains, err := r.ownedChains(liveHandles)
+	if err != nil {
+		return Report{}, err // not tested
+	}
+	for _, family := range r.ipFamilies() {
+		r.reconcileChains(family, ownedChains, liveHandles, orphans, &report)
+		r.reconcileMasqueradeRules(family, liveIPs, orphans, &report)
+	}
+
+	// forget state that was cleaned up or came back
+	for key := range r.leakRuns {
+		if _, ok := orphans[key]; !ok {
+			delete(r.leakRuns, key)
+		}
+	}
+
+	if len(report.DatastoreEntries)+len(report.PortAllocations)+len(report.Chains)+len(report.MasqueradeRules) > 0 {
+		r.Logger.Info("cleaned-up-leaked-container-state", lager.Data{
+			"datastore_entries": report.DatastoreEntries,
+			"port_allocations":  report.PortAllocations,
+			"chains":            report.Chains,
+			"masquerade_rules":  report.MasqueradeRules,
+		})
+	}
+	if len(report.Pending) > 0 {
+		r.Logger.Info("leaked-container-state-within-grace-runs", lager.Data{
+			"pending": report.Pending,
+		})
+	}
+
+	r.lastReport = report
+	return report, nil
+}
+
+func (r *Reconciler) ReconcileWrapper() error {
+	_, err := r.Reconcile()
+	return err
+}
+
+func (r *Reconciler) LastReport() Report {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.lastReport
+}
+
+func (r *Reconciler) ipFamilies() []ipFamily {
+	families := []ipFamily{{label: "", iptables: r.IPTables}}
+	if r.IP6Tables != nil {
+		families = append(families, ipFamily{label: "ip6:", iptables: r.IP6Tables})
+	}
+	return families
+}
+
+func (r *Reconciler) reconcileChains(family ipFamily, ownedChains, liveHandles, orphans map[string]struct{}, report *Report) {
+	for _, table := range chainTables {
+		chains, err := family.iptables.ListChains(table)
+		if err != nil {
+			r.Logger.Error("list-chains-failed", err, lager.Data{"table": family.label + table})
+			continue
+		}
+
+		leakedChains := []string{}
+		for _, chain := range chains {
+			if !isContainerChain(chain) || ownedByLiveContainer(chain, ownedChains, liveHandles) {
+				continue
+			}
+			if !r.leaked("chain:"+family.label+table+"/"+chain, orphans, report) {
+				continue
+			}
+			report.Chains = append(report.Chains, family.label+table+"/"+chain)
+			leakedChains = append(leakedChains, chain)
+		}
+		r.deleteChains(family, table, leakedChains)
+	}
+}
+
+func (r *Reconciler) reconcileMasqueradeRules(family ipFamily, liveIPs, orphans map[string]struct{}, report *Report) {
+	masqRules, err := family.iptables.List("nat", "POSTROUTING")
+	if err != nil {
+		r.Logger.Error("list-masquerade-rules-failed", err, lager.Data{"table": family.label + "nat"})
+		return
+	}
+	for _, rule := range masqRules {
+		ip, ok := masqueradeSource(rule, r.VTEPName)
+		if !ok {
+			continue
+		}
+		if _, ok := liveIPs[ip]; ok {
+			continue
+		}
+		if !r.leaked("masq:"+ip, orphans, report) {
+			continue
+		}
+		report.MasqueradeRules = append(report.MasqueradeRules, ip)
+		err := family.iptables.Delete("nat", "POSTROUTING", rules.IPTablesRule(strings.Fields(rule)[2:]))
+		if err != nil {
+			r.Logger.Error("delete-masquerade-rule-failed", err, lager.Data{"ip": ip})
+		}
+	}
+}
+
+// leaked records that the state identified by key is orphaned and reports
+// whether it has been orphaned for long enough to be cleaned up.
+func (r *Reconciler) leaked(key string, orphans map[string]struct{}, report *Report) bool {
+	if r.leakRuns == nil {
+		r.leakRuns = make(map[string]int)
+	}
+	r.leakRuns[key]++
+	if r.leakRuns[key] < r.GraceRuns {
+		orphans[key] = struct{}{}
+		report.Pending = append(report.Pending, key)
+		return false
+	}
+	return true
+}
+
+func (r *Reconciler) ownedChains(liveHandles map[string]struct{}) (map[string]struct{}, error) {
+	owned := make(map[string]struct{})
+	for handle := range liveHandles {
+		for _, prefix := range containerChainPrefixes {
+			owned[r.ChainNamer.Prefix(prefix, handle)] = struct{}{}
+		}
+		logChain, err := r.ChainNamer.Postfix(r.ChainNamer.Prefix("netout", handle), netOutLogSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("getting chain name: %s", err) // not tested
+		}
+		owned[logChain] = struct{}{}
+	}
+	return owned, nil
+}
+
+func (r *Reconciler) deleteChains(family ipFamily, table string, chains []string) {
+	if len(chains) == 0 {
+		return
+	}
+	leaked := make(map[string]struct{})
+	for _, chain := range chains {
+		leaked[chain] = struct{}{}
+	}
+
+	for _, parent := range parentChains[table] {
+		parentRules, err := family.iptables.List(table, parent)
+		if err != nil {
+			r.Logger.Error("list-rules-failed", err, lager.Data{"table": table, "chain": parent})
+			continue
+		}
+		for _, rule := range parentRules {
+			if _, ok := leaked[jumpTarget(rule)]; !ok {
+				continue
+			}
+			err := family.iptables.Delete(table, parent, rules.IPTablesRule(strings.Fields(rule)[2:]))
+			if err != nil {
+				r.Logger.Error("delete-jump-failed", err, lager.Data{"table": table, "rule": rule})
+			}
+		}
+	}
+
+	// chains of the same container jump to each other, so empty all of them
+	// before deleting any
+	for _, chain := range chains {
+		if err := family.iptables.ClearChain(table, chain); err != nil {
+			r.Logger.Error("clear-chain-failed", err, lager.Data{"table": table, "chain": chain})
+		}
+	}
+	for _, chain := range chains {
+		if err := family.iptables.DeleteChain(table, chain); err != nil {
+			r.Logger.Error("delete-chain-failed", err, lager.Data{"table": table, "chain": chain})
+		}
+	}
+}
+
+func isContainerChain(chain string) bool {
+	if strings.HasPrefix(chain, egressChainPrefix) {
+		return true
+	}
+	for _, prefix := range containerChainPrefixes {
+		if strings.HasPrefix(chain, prefix+"--") {
+			return true
+		}
+	}
+	return false
+}
+
+func ownedByLiveContainer(chain string, ownedChains, liveHandles map[string]struct{}) bool {
+	if _, ok := ownedChains[chain]; ok {
+		return true
+	}
+	if strings.HasPrefix(chain, egressChainPrefix) {
+		for handle := range liveHandles {
+			if strings.HasPrefix(chain, planner.EgressChainPrefixFor(handle)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jumpTarget(rule string) string {
+	fields := strings.Fields(rule)
+	for i := 0; i < len(fields)-1; i++ {
+		if fields[i] == "-j" || fields[i] == "-g" {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
+// masqueradeSource returns the container IP of a rule created by
+// rules.NewDefaultEgressRule for vtepName, as listed by iptables or ip6tables.
+// Other masquerade rules of a single address are left alone.
+func masqueradeSource(rule, vtepName string) (string, bool) {
+	fields := strings.Fields(rule)
+	if len(fields) != 9 || fields[0] != "-A" || fields[2] != "-s" || fields[4] != "!" || fields[5] != "-o" || fields[6] != vtepName || fields[8] != "MASQUERADE" {
+		return "", false
+	}
+	ip, ipNet, err := net.ParseCIDR(fields[3])
+	if err != nil {
+		return "", false
+	}
+	if ones, bits := ipNet.Mask.Size(); ones != bits {
+		return "", false
+	}
+	return ip.String(), true
+}
+
+func sortedKeys(entries map[string]datastore.Container) []string {
+	keys := []string{}
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,386 @@
+package reconciler_test
+
+import (
+	"cni-wrapper-plugin/legacynet"
+	"errors"
+	"lib/datastore"
+	libfakes "lib/fakes"
+	"lib/rules"
+	"vxlan-policy-agent/planner"
+	"vxlan-policy-agent/reconciler"
+	"vxlan-policy-agent/reconciler/fakes"
+
+	"code.cloudfoundry.org/garden"
+	"code.cloudfoundry.org/garden/gardenfakes"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reconciler", func() {
+	var (
+		r             *reconciler.Reconciler
+		logger        *lagertest.TestLogger
+		gardenClient  *fakes.GardenClient
+		store         *libfakes.Datastore
+		portAllocator *fakes.PortAllocator
+		ipt           *libfakes.IPTablesAdapter
+		deadEgress    string
+		chains        map[string][]string
+		ruleLists     map[string][]string
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		gardenClient = &fakes.GardenClient{}
+		store = &libfakes.Datastore{}
+		portAllocator = &fakes.PortAllocator{}
+		ipt = &libfakes.IPTablesAdapter{}
+
+		liveContainer := &gardenfakes.FakeContainer{}
+		liveContainer.HandleReturns("live-handle")
+		gardenClient.ContainersReturns([]garden.Container{liveContainer}, nil)
+
+		store.ReadAllReturns(map[string]datastore.Container{
+			"live-handle": {Handle: "live-handle", IP: "10.255.1.2"},
+			"dead-handle": {Handle: "dead-handle", IP: "10.255.1.3"},
+		}, nil)
+		portAllocator.AllocatedHandlesReturns([]string{"dead-handle", "live-handle"}, nil)
+
+		deadEgress = planner.EgressChainPrefixFor("dead-handle") + "1500000000"
+		chains = map[string][]string{
+			"filter": {
+				"FORWARD", "INPUT", "vpa--1500000000",
+				"netout--live-handle", "netout--live-handle--log", "input--live-handle", "overlay--live-handle",
+				planner.EgressChainPrefixFor("live-handle") + "1500000000",
+				"netout--dead-handle", "netout--dead-handle--log", deadEgress,
+			},
+			"nat":    {"PREROUTING", "POSTROUTING", "netin--live-handle", "netin--dead-handle"},
+			"mangle": {"PREROUTING", "netin--dead-handle"},
+		}
+		ipt.ListChainsStub = func(table string) ([]string, error) {
+			return chains[table], nil
+		}
+		ruleLists = map[string][]string{
+			"filter/FORWARD": {
+				"-A FORWARD -j vpa--1500000000",
+				"-A FORWARD -s 10.255.1.2/32 -o eth0 -j netout--live-handle",
+				"-A FORWARD -s 10.255.1.3/32 -o eth0 -j netout--dead-handle",
+			},
+			"nat/PREROUTING": {
+				"-A PREROUTING -j netin--live-handle",
+				"-A PREROUTING -j netin--dead-handle",
+			},
+			"mangle/PREROUTING": {
+				"-A PREROUTING -j netin--dead-handle",
+			},
+			"nat/POSTROUTING": {
+				"-A POSTROUTING -s 10.255.0.0/16 ! -d 10.255.0.0/16 -j MASQUERADE",
+				"-A POSTROUTING -s 10.255.1.2/32 ! -o silk-vtep -j MASQUERADE",
+				"-A POSTROUTING -s 10.255.1.3/32 ! -o silk-vtep -j MASQUERADE",
+				"-A POSTROUTING -s 10.255.1.4/32 ! -o some-other-device -j MASQUERADE",
+			},
+		}
+		ipt.ListStub = func(table, chain string) ([]string, error) {
+			return ruleLists[table+"/"+chain], nil
+		}
+
+		r = &reconciler.Reconciler{
+			Logger:        logger,
+			GardenClient:  gardenClient,
+			Datastore:     store,
+			PortAllocator: portAllocator,
+			IPTables:      ipt,
+			ChainNamer:    &legacynet.ChainNamer{MaxLength: 28},
+			VTEPName:      "silk-vtep",
+			GraceRuns:     2,
+		}
+	})
+
+	It("lists the live containers from garden", func() {
+		_, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gardenClient.ContainersCallCount()).To(Equal(1))
+		Expect(gardenClient.ContainersArgsForCall(0)).To(Equal(garden.Properties{}))
+	})
+
+	Context("the first time state is found without a live container", func() {
+		It("reports it as pending and does not clean it up", func() {
+			report, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.DatastoreEntries).To(BeEmpty())
+			Expect(report.PortAllocations).To(BeEmpty())
+			Expect(report.Chains).To(BeEmpty())
+			Expect(report.MasqueradeRules).To(BeEmpty())
+			Expect(report.Pending).To(ConsistOf(
+				"datastore:dead-handle",
+				"ports:dead-handle",
+				"chain:filter/netout--dead-handle",
+				"chain:filter/netout--dead-handle--log",
+				"chain:filter/"+deadEgress,
+				"chain:nat/netin--dead-handle",
+				"chain:mangle/netin--dead-handle",
+				"masq:10.255.1.3",
+			))
+
+			Expect(store.DeleteCallCount()).To(Equal(0))
+			Expect(portAllocator.ReleaseAllPortsCallCount()).To(Equal(0))
+			Expect(ipt.DeleteCallCount()).To(Equal(0))
+			Expect(ipt.ClearChainCallCount()).To(Equal(0))
+			Expect(ipt.DeleteChainCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the state is still orphaned after the grace runs", func() {
+		BeforeEach(func() {
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("reports the leaks", func() {
+			report, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report).To(Equal(reconciler.Report{
+				DatastoreEntries: []string{"dead-handle"},
+				PortAllocations:  []string{"dead-handle"},
+				Chains: []string{
+					"filter/netout--dead-handle",
+					"filter/netout--dead-handle--log",
+					"filter/" + deadEgress,
+					"nat/netin--dead-handle",
+					"mangle/netin--dead-handle",
+				},
+				MasqueradeRules: []string{"10.255.1.3"},
+				Pending:         []string{},
+			}))
+			Expect(r.LastReport()).To(Equal(report))
+		})
+
+		It("deletes the datastore entry and releases the ports", func() {
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(store.DeleteCallCount()).To(Equal(1))
+			Expect(store.DeleteArgsForCall(0)).To(Equal("dead-handle"))
+			Expect(portAllocator.ReleaseAllPortsCallCount()).To(Equal(1))
+			Expect(portAllocator.ReleaseAllPortsArgsForCall(0)).To(Equal("dead-handle"))
+		})
+
+		It("removes the jumps to the chains, then clears and deletes them", func() {
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			type call struct {
+				table, chain string
+				rule         rules.IPTablesRule
+			}
+			deletes := []call{}
+			for i := 0; i < ipt.DeleteCallCount(); i++ {
+				table, chain, rule := ipt.DeleteArgsForCall(i)
+				deletes = append(deletes, call{table, chain, rule})
+			}
+			Expect(deletes).To(Equal([]call{
+				{"filter", "FORWARD", rules.IPTablesRule{"-s", "10.255.1.3/32", "-o", "eth0", "-j", "netout--dead-handle"}},
+				{"nat", "PREROUTING", rules.IPTablesRule{"-j", "netin--dead-handle"}},
+				{"mangle", "PREROUTING", rules.IPTablesRule{"-j", "netin--dead-handle"}},
+				{"nat", "POSTROUTING", rules.IPTablesRule{"-s", "10.255.1.3/32", "!", "-o", "silk-vtep", "-j", "MASQUERADE"}},
+			}))
+
+			cleared := []string{}
+			for i := 0; i < ipt.ClearChainCallCount(); i++ {
+				table, chain := ipt.ClearChainArgsForCall(i)
+				cleared = append(cleared, table+"/"+chain)
+			}
+			deleted := []string{}
+			for i := 0; i < ipt.DeleteChainCallCount(); i++ {
+				table, chain := ipt.DeleteChainArgsForCall(i)
+				deleted = append(deleted, table+"/"+chain)
+			}
+			expected := []string{
+				"filter/netout--dead-handle",
+				"filter/netout--dead-handle--log",
+				"filter/" + deadEgress,
+				"nat/netin--dead-handle",
+				"mangle/netin--dead-handle",
+			}
+			Expect(cleared).To(Equal(expected))
+			Expect(deleted).To(Equal(expected))
+		})
+
+		It("logs what it cleaned up", func() {
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logger.LogMessages()).To(ContainElement("test.cleaned-up-leaked-container-state"))
+		})
+
+		Context("when cleaning up fails", func() {
+			BeforeEach(func() {
+				store.DeleteReturns(datastore.Container{}, errors.New("banana"))
+				portAllocator.ReleaseAllPortsReturns(errors.New("banana"))
+				ipt.DeleteReturns(errors.New("banana"))
+				ipt.ClearChainReturns(errors.New("banana"))
+				ipt.DeleteChainReturns(errors.New("banana"))
+			})
+
+			It("logs the errors and carries on", func() {
+				_, err := r.Reconcile()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(portAllocator.ReleaseAllPortsCallCount()).To(Equal(1))
+				Expect(ipt.DeleteChainCallCount()).To(Equal(5))
+				Expect(logger.LogMessages()).To(ContainElement("test.datastore-delete-failed"))
+				Expect(logger.LogMessages()).To(ContainElement("test.release-ports-failed"))
+				Expect(logger.LogMessages()).To(ContainElement("test.delete-jump-failed"))
+				Expect(logger.LogMessages()).To(ContainElement("test.clear-chain-failed"))
+				Expect(logger.LogMessages()).To(ContainElement("test.delete-chain-failed"))
+				Expect(logger.LogMessages()).To(ContainElement("test.delete-masquerade-rule-failed"))
+			})
+		})
+
+		Context("when listing the chains of a table fails", func() {
+			BeforeEach(func() {
+				ipt.ListChainsStub = func(table string) ([]string, error) {
+					if table == "nat" {
+						return nil, errors.New("banana")
+					}
+					return chains[table], nil
+				}
+			})
+
+			It("logs the error and cleans up the other tables", func() {
+				report, err := r.Reconcile()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Chains).To(ContainElement("mangle/netin--dead-handle"))
+				Expect(report.Chains).NotTo(ContainElement("nat/netin--dead-handle"))
+				Expect(logger.LogMessages()).To(ContainElement("test.list-chains-failed"))
+			})
+		})
+	})
+
+	Context("on a cell with ip6tables", func() {
+		var ip6t *libfakes.IPTablesAdapter
+
+		BeforeEach(func() {
+			store.ReadAllReturns(map[string]datastore.Container{
+				"live-handle": {Handle: "live-handle", IPs: []string{"10.255.1.2", "fd00::2"}},
+				"dead-handle": {Handle: "dead-handle", IPs: []string{"10.255.1.3", "fd00::3"}},
+			}, nil)
+
+			ip6t = &libfakes.IPTablesAdapter{}
+			ip6Chains := map[string][]string{
+				"filter": {"FORWARD", "INPUT", "netout--live-handle", "netout--dead-handle"},
+			}
+			ip6t.ListChainsStub = func(table string) ([]string, error) {
+				return ip6Chains[table], nil
+			}
+			ip6RuleLists := map[string][]string{
+				"filter/FORWARD": {
+					"-A FORWARD -s fd00::2/128 -o eth0 -j netout--live-handle",
+					"-A FORWARD -s fd00::3/128 -o eth0 -j netout--dead-handle",
+				},
+				"nat/POSTROUTING": {
+					"-A POSTROUTING -s fd00::2/128 ! -o silk-vtep -j MASQUERADE",
+					"-A POSTROUTING -s fd00::3/128 ! -o silk-vtep -j MASQUERADE",
+				},
+			}
+			ip6t.ListStub = func(table, chain string) ([]string, error) {
+				return ip6RuleLists[table+"/"+chain], nil
+			}
+			r.IP6Tables = ip6t
+
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("cleans up the leaked chains and masquerade rules of both families", func() {
+			report, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Chains).To(ContainElement("filter/netout--dead-handle"))
+			Expect(report.Chains).To(ContainElement("ip6:filter/netout--dead-handle"))
+			Expect(report.Chains).NotTo(ContainElement("ip6:filter/netout--live-handle"))
+			Expect(report.MasqueradeRules).To(ConsistOf("10.255.1.3", "fd00::3"))
+
+			type call struct {
+				table, chain string
+				rule         rules.IPTablesRule
+			}
+			deletes := []call{}
+			for i := 0; i < ip6t.DeleteCallCount(); i++ {
+				table, chain, rule := ip6t.DeleteArgsForCall(i)
+				deletes = append(deletes, call{table, chain, rule})
+			}
+			Expect(deletes).To(Equal([]call{
+				{"filter", "FORWARD", rules.IPTablesRule{"-s", "fd00::3/128", "-o", "eth0", "-j", "netout--dead-handle"}},
+				{"nat", "POSTROUTING", rules.IPTablesRule{"-s", "fd00::3/128", "!", "-o", "silk-vtep", "-j", "MASQUERADE"}},
+			}))
+			Expect(ip6t.DeleteChainCallCount()).To(Equal(1))
+			table, chain := ip6t.DeleteChainArgsForCall(0)
+			Expect(table + "/" + chain).To(Equal("filter/netout--dead-handle"))
+		})
+	})
+
+	Context("when the container shows up in garden before the grace runs pass", func() {
+		It("does not clean up its state", func() {
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			liveContainer := &gardenfakes.FakeContainer{}
+			liveContainer.HandleReturns("live-handle")
+			deadContainer := &gardenfakes.FakeContainer{}
+			deadContainer.HandleReturns("dead-handle")
+			gardenClient.ContainersReturns([]garden.Container{liveContainer, deadContainer}, nil)
+
+			report, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Pending).To(BeEmpty())
+			Expect(store.DeleteCallCount()).To(Equal(0))
+			Expect(ipt.DeleteChainCallCount()).To(Equal(0))
+
+			By("restarting the grace runs if the container goes away again")
+			gardenClient.ContainersReturns([]garden.Container{liveContainer}, nil)
+			report, err = r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report.Pending).To(ContainElement("datastore:dead-handle"))
+			Expect(store.DeleteCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when listing the garden containers fails", func() {
+		BeforeEach(func() {
+			gardenClient.ContainersReturns(nil, errors.New("banana"))
+		})
+
+		It("returns the error without cleaning anything up", func() {
+			_, err := r.Reconcile()
+			Expect(err).To(MatchError("list garden containers: banana"))
+			Expect(store.ReadAllCallCount()).To(Equal(0))
+			Expect(ipt.ListChainsCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when reading the datastore fails", func() {
+		BeforeEach(func() {
+			store.ReadAllReturns(nil, errors.New("banana"))
+		})
+
+		It("returns the error", func() {
+			err := r.ReconcileWrapper()
+			Expect(err).To(MatchError("read datastore: banana"))
+		})
+	})
+
+	Context("when listing the port allocations fails", func() {
+		BeforeEach(func() {
+			portAllocator.AllocatedHandlesReturns(nil, errors.New("banana"))
+		})
+
+		It("returns the error", func() {
+			_, err := r.Reconcile()
+			Expect(err).To(MatchError("list port allocations: banana"))
+		})
+	})
+})